@@ -0,0 +1,125 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/wire"
+)
+
+// TestAssumeValidSkipsScripts ensures the script checks are only skipped for
+// blocks which extend the main chain up to the assume-valid block.
+func TestAssumeValidSkipsScripts(t *testing.T) {
+	// makeNode returns a block node at the passed height which builds on
+	// the passed parent.  The nonce distinguishes competing blocks.
+	makeNode := func(parent *blockNode, nonce uint64) *blockNode {
+		header := wire.BlockHeader{
+			PrevBlock: *parent.hash,
+			Height:    parent.height + 1,
+			Nonce:     nonce,
+		}
+		hash := header.BlockHash()
+		return newBlockNode(&header, &hash)
+	}
+
+	// Build the main chain genesis -> n1 -> n2 -> n3 along with the
+	// competing side chain block s2 which builds on n1.
+	genesisHeader := wire.BlockHeader{}
+	genesisHash := genesisHeader.BlockHash()
+	genesis := newBlockNode(&genesisHeader, &genesisHash)
+	n1 := makeNode(genesis, 0)
+	n2 := makeNode(n1, 0)
+	n3 := makeNode(n2, 0)
+	s2 := makeNode(n1, 1)
+
+	tests := []struct {
+		name        string
+		mainChain   []*blockNode
+		sideChain   []*blockNode
+		assumeValid *blockNode
+		node        *blockNode
+		flags       BehaviorFlags
+		want        bool
+	}{
+		{
+			name:        "block below unconnected assume-valid block",
+			mainChain:   []*blockNode{genesis},
+			sideChain:   []*blockNode{n2},
+			assumeValid: n2,
+			node:        n1,
+			flags:       BFAssumeValid,
+			want:        true,
+		},
+		{
+			name:        "assume-valid block itself",
+			mainChain:   []*blockNode{genesis, n1},
+			sideChain:   []*blockNode{n2},
+			assumeValid: n2,
+			node:        n2,
+			flags:       BFAssumeValid,
+			want:        true,
+		},
+		{
+			name:        "block after connected assume-valid block",
+			mainChain:   []*blockNode{genesis, n1, n2},
+			assumeValid: n2,
+			node:        n3,
+			flags:       BFAssumeValid,
+			want:        false,
+		},
+		{
+			name:        "side chain block",
+			mainChain:   []*blockNode{genesis, n1, n2},
+			assumeValid: s2,
+			node:        s2,
+			flags:       BFAssumeValid,
+			want:        false,
+		},
+		{
+			name:        "block without flag",
+			mainChain:   []*blockNode{genesis},
+			sideChain:   []*blockNode{n2},
+			assumeValid: n2,
+			node:        n1,
+			flags:       BFNone,
+			want:        false,
+		},
+		{
+			name:      "no assume-valid block configured",
+			mainChain: []*blockNode{genesis},
+			node:      n1,
+			flags:     BFAssumeValid,
+			want:      false,
+		},
+	}
+
+	for _, test := range tests {
+		chain := &BlockChain{index: make(map[chainhash.Hash]*blockNode)}
+		for _, node := range test.mainChain {
+			node.inMainChain = true
+			chain.index[*node.hash] = node
+			chain.bestNode = node
+		}
+		for _, node := range test.sideChain {
+			node.inMainChain = false
+			chain.index[*node.hash] = node
+		}
+		if test.assumeValid != nil {
+			chain.assumeValid = test.assumeValid.hash
+		}
+
+		got, err := chain.assumeValidSkipsScripts(test.node, test.flags)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: unexpected result -- got %v, want %v",
+				test.name, got, test.want)
+		}
+	}
+}
@@ -192,6 +192,7 @@ type BestState struct {
 	NumTxns    uint64          // The number of txns in the block.
 	TotalTxns  uint64          // The total number of txns in the chain.
 	MedianTime time.Time       // Median time as per CalcPastMedianTime.
+	WorkSum    *big.Int        // The total work of the chain up to the block.
 }
 
 // newBestState returns a new best stats instance for the given parameters.
@@ -205,6 +206,7 @@ func newBestState(node *blockNode, blockSize, numTxns, totalTxns uint64,
 		NumTxns:    numTxns,
 		TotalTxns:  totalTxns,
 		MedianTime: medianTime,
+		WorkSum:    new(big.Int).Set(node.workSum),
 	}
 }
 
@@ -225,6 +227,7 @@ type BlockChain struct {
 	sigCache            *txscript.SigCache
 	hashCache           *txscript.HashCache
 	indexManager        IndexManager
+	pruneTarget         uint64
 
 	// The following fields are calculated based upon the provided chain
 	// parameters.  They are also set when the instance is created and
@@ -248,6 +251,13 @@ type BlockChain struct {
 	// runtime.  They are protected by the chain lock.
 	noVerify bool
 
+	// assumeValid is the hash of the block whose ancestors are assumed to
+	// have valid scripts and assumeValidHeight is the height of the most
+	// recent block connected without script validation on that basis.
+	// They are protected by the chain lock.
+	assumeValid       *chainhash.Hash
+	assumeValidHeight uint32
+
 	// These fields are related to the memory block index.  They are
 	// protected by the chain lock.
 	bestNode *blockNode
@@ -310,6 +320,20 @@ func (b *BlockChain) DisableVerify(disable bool) {
 	b.chainLock.Unlock()
 }
 
+// AssumeValid returns the hash of the block whose ancestors are assumed to have
+// valid scripts along with the height of the most recent block that was
+// connected without script validation because of it.  The hash is nil when no
+// assume-valid block is configured and the height is zero when no block has
+// been connected on that basis.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) AssumeValid() (*chainhash.Hash, uint32) {
+	b.chainLock.RLock()
+	hash, height := b.assumeValid, b.assumeValidHeight
+	b.chainLock.RUnlock()
+	return hash, height
+}
+
 // HaveBlock returns whether or not the chain instance has the block represented
 // by the passed hash.  This includes checking the various places a block can
 // be like part of the main chain, on a side chain, or in the orphan pool.
@@ -548,6 +572,39 @@ func (b *BlockChain) getPrevNodeFromBlock(block *provautil.Block) (*blockNode, e
 	return prevBlockNode, err
 }
 
+// getPrevNodeFromHeader returns a block node for the parent of the block with
+// the passed header.  When it is already in the memory block chain, it simply
+// returns it.  Otherwise, it loads the parent from the block database when it
+// is stored there.  The returned node will be nil if the genesis block is
+// passed or the parent is not known.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) getPrevNodeFromHeader(header *wire.BlockHeader) (*blockNode, error) {
+	// Genesis block.
+	prevHash := &header.PrevBlock
+	if prevHash.IsEqual(zeroHash) {
+		return nil, nil
+	}
+
+	// Return the existing previous block node if it's already there.
+	if bn, ok := b.index[*prevHash]; ok {
+		return bn, nil
+	}
+
+	// Dynamically load the previous block from the block database when it
+	// is available.
+	var prevBlockNode *blockNode
+	err := b.db.View(func(dbTx database.Tx) error {
+		exists, err := dbTx.HasBlock(prevHash)
+		if err != nil || !exists {
+			return err
+		}
+		prevBlockNode, err = b.loadBlockNode(dbTx, prevHash)
+		return err
+	})
+	return prevBlockNode, err
+}
+
 // getPrevNodeFromNode returns a block node for the block previous to the
 // passed block node (the passed block node's parent).  When the node is already
 // connected to a parent, it simply returns it.  Otherwise, it loads the
@@ -1014,6 +1071,15 @@ func (b *BlockChain) connectBlock(node *blockNode, block *provautil.Block, utxoV
 			}
 		}
 
+		// Prune the data for the oldest blocks along with their spend
+		// journal entries when the stored blocks exceed the target.
+		if b.pruneTarget != 0 {
+			err := b.pruneBlocks(dbTx, node)
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -1232,12 +1298,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 	// Disconnecting all of the blocks back to the point of the fork also
 	// entails reverting all admin operations that have happened in these
 	// blocks.
-	keyView := NewKeyViewpoint()
-	keyView.SetThreadTips(b.threadTips)
-	keyView.SetLastKeyID(b.lastKeyID)
-	keyView.SetTotalSupply(b.totalSupply)
-	keyView.SetKeys(b.adminKeySets)
-	keyView.SetKeyIDs(b.aspKeyIdMap)
+	keyView := b.bestKeyView()
 	for e := detachNodes.Front(); e != nil; e = e.Next() {
 		n := e.Value.(*blockNode)
 		var block *provautil.Block
@@ -1324,7 +1385,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 		// thus will not be generated.  This is done because the state
 		// is not being immediately written to the database, so it is
 		// not needed.
-		err = b.checkConnectBlock(n, block, utxoView, keyView, nil)
+		err = b.checkConnectBlock(n, block, utxoView, keyView, nil, flags)
 		if err != nil {
 			return err
 		}
@@ -1431,6 +1492,9 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List, flags
 //  - BFDryRun: Prevents the block from being connected and avoids modifying the
 //    state of the memory chain index.  Also, any log messages related to
 //    modifying the state are avoided.
+//  - BFAssumeValid: Skips script validation when an assume-valid block is
+//    configured and the block extends the main chain no further than it.
+//    Blocks connected by a reorganization are always fully validated.
 //
 // This function MUST be called with the chain state lock held (for writes).
 func (b *BlockChain) connectBestChain(node *blockNode, block *provautil.Block, flags BehaviorFlags) (bool, error) {
@@ -1450,15 +1514,10 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *provautil.Block, f
 		// The block can only be connected if:
 		// - it is mined by an active validate key.
 		// - all keyIDs used for outputs are provisioned.
-		keyView := NewKeyViewpoint()
-		keyView.SetThreadTips(b.threadTips)
-		keyView.SetLastKeyID(b.lastKeyID)
-		keyView.SetTotalSupply(b.totalSupply)
-		keyView.SetKeys(b.adminKeySets)
-		keyView.SetKeyIDs(b.aspKeyIdMap)
+		keyView := b.bestKeyView()
 		stxos := make([]spentTxOut, 0, countSpentOutputs(block))
 		if !fastAdd {
-			err := b.checkConnectBlock(node, block, utxoView, keyView, &stxos)
+			err := b.checkConnectBlock(node, block, utxoView, keyView, &stxos, flags)
 			if err != nil {
 				return false, err
 			}
@@ -1678,6 +1737,43 @@ func (b *BlockChain) KeyIDs() btcec.KeyIdMap {
 	return aspKeyIdMap
 }
 
+// AdminState describes the admin state of the main chain at a block.
+type AdminState struct {
+	// BestState is the state of the block the admin state belongs to.
+	BestState *BestState
+
+	// AdminKeySets are the admin key sets that govern the chain.
+	AdminKeySets map[btcec.KeySetType]btcec.PublicKeySet
+
+	// LastKeyID is the last assigned ASP key id.
+	LastKeyID btcec.KeyID
+
+	// ASPKeyIDs is the ASP keyID-to-pubkey mapping.
+	ASPKeyIDs btcec.KeyIdMap
+}
+
+// FetchAdminState returns the admin state of the best chain along with the
+// state of the best block it belongs to.  Unlike calling BestSnapshot,
+// AdminKeySets, LastKeyID, and KeyIDs individually, the returned state is
+// consistent even when a block is connected concurrently.  The returned
+// instance must be treated as immutable since it is shared by all callers.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchAdminState() *AdminState {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	b.stateLock.RLock()
+	state := &AdminState{
+		BestState:    b.stateSnapshot,
+		AdminKeySets: b.adminKeySets,
+		LastKeyID:    b.lastKeyID,
+		ASPKeyIDs:    b.aspKeyIdMap,
+	}
+	b.stateLock.RUnlock()
+	return state
+}
+
 // IndexManager provides a generic interface that the is called when blocks are
 // connected and disconnected to and from the tip of the main chain for the
 // purpose of supporting optional indexes.
@@ -1759,6 +1855,27 @@ type Config struct {
 	// This field can be nil if the caller does not wish to make use of an
 	// index manager.
 	IndexManager IndexManager
+
+	// AssumeValid is the hash of a block whose ancestors are assumed to
+	// have valid scripts.  Script validation is skipped for blocks that
+	// are processed with the BFAssumeValid flag.
+	//
+	// This field can be nil to always validate scripts.
+	AssumeValid *chainhash.Hash
+
+	// PruneTarget is the target size in bytes for the stored block data.
+	// Once it is exceeded, the data for the oldest blocks which are deeper
+	// than the reorganization safe depth is deleted along with their spend
+	// journal entries.
+	//
+	// This field can be zero to keep all blocks.
+	PruneTarget uint64
+
+	// ReindexChainState discards the chain state stored in the database and
+	// rebuilds it by connecting all of the stored blocks again.  The
+	// optional indexes are rebuilt along with it, so the caller must drop
+	// any existing index data beforehand.
+	ReindexChainState bool
 }
 
 // New returns a BlockChain instance using the provided configuration details.
@@ -1801,6 +1918,8 @@ func New(config *Config) (*BlockChain, error) {
 		sigCache:            config.SigCache,
 		hashCache:           config.HashCache,
 		indexManager:        config.IndexManager,
+		pruneTarget:         config.PruneTarget,
+		assumeValid:         config.AssumeValid,
 		blocksPerRetarget:   int32(config.ChainParams.PowAveragingWindow),
 		minMemoryNodes:      int32(config.ChainParams.PowAveragingWindow),
 		bestNode:            nil,
@@ -1815,6 +1934,17 @@ func New(config *Config) (*BlockChain, error) {
 		prevOrphans:         make(map[chainhash.Hash][]*orphanBlock),
 	}
 
+	// Remove the existing chain state when it is to be rebuilt from the
+	// stored blocks.
+	var reindexHashes []chainhash.Hash
+	if config.ReindexChainState {
+		var err error
+		reindexHashes, err = b.resetChainState()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Initialize the chain state from the passed database.  When the db
 	// does not yet contain any chain state, both it and the chain state
 	// will be initialized to contain only the genesis block.
@@ -1830,6 +1960,14 @@ func New(config *Config) (*BlockChain, error) {
 		}
 	}
 
+	// Rebuild the chain state, along with the optional indexes, from the
+	// stored blocks.
+	if config.ReindexChainState {
+		if err := b.rebuildChainState(reindexHashes); err != nil {
+			return nil, err
+		}
+	}
+
 	log.Infof("Chain state (height %d, hash %v, totaltx %d, work %v)",
 		b.bestNode.height, b.bestNode.hash, b.stateSnapshot.TotalTxns,
 		b.bestNode.workSum)
@@ -1369,8 +1369,10 @@ func (b *BlockChain) createChainState() error {
 			return err
 		}
 
-		// Store the genesis block into the database.
-		return dbTx.StoreBlock(genesisBlock)
+		// Store the genesis block into the database if needed.  It is
+		// already there when the chain state is being rebuilt from the
+		// stored blocks.
+		return dbMaybeStoreBlock(dbTx, genesisBlock)
 	})
 	return err
 }
@@ -216,64 +216,136 @@ const (
 	// transaction.
 	ErrInvalidAdminTx
 
-	// ErrInvalidAdminOp indicates an admin transaction contains an invalid
-	// operation according to current chain state.
+	// ErrInvalidAdminOp indicates an admin transaction contains an
+	// operation which is not allowed on its thread or is invalid according
+	// to current chain state.
 	ErrInvalidAdminOp
 
 	// ErrFeeTooHigh indicates a transaction fee exceeds the limit for
 	// fee paid.
 	ErrFeeTooHigh
+
+	// ErrPreviousBlockUnknown indicates that the previous block referenced
+	// by a block header is not known.
+	ErrPreviousBlockUnknown
+
+	// ErrBadSnapshot indicates a utxo set snapshot is malformed or its
+	// contents are not consistent with each other.
+	ErrBadSnapshot
+
+	// ErrUnknownSnapshot indicates a utxo set snapshot does not match any
+	// of the known good snapshots for the network.
+	ErrUnknownSnapshot
+
+	// ErrBadNoticeKeySet indicates a network notice is not signed by one of
+	// the admin key sets which are allowed to sign notices.
+	ErrBadNoticeKeySet
+
+	// ErrNoticeExpired indicates a network notice has expired.
+	ErrNoticeExpired
+
+	// ErrNoticeTimeTooNew indicates the time a network notice was created
+	// is too far in the future.
+	ErrNoticeTimeTooNew
+
+	// ErrBadNoticeSignature indicates a network notice has a signature
+	// which is invalid or was not made by a key of the admin key set.
+	ErrBadNoticeSignature
+
+	// ErrNoticeQuorum indicates a network notice is not signed by enough
+	// distinct keys of the admin key set.
+	ErrNoticeQuorum
+
+	// ErrBadNoticeVersion indicates a network notice is of a version which
+	// is not known.
+	ErrBadNoticeVersion
+
+	// ErrPrevBlockNotBest indicates that the block's previous block is not
+	// the current chain tip.  This is not a block validation rule, but is
+	// required for block proposals submitted via getblocktemplate RPC.
+	ErrPrevBlockNotBest
+
+	// ErrUnknownKeyID indicates a transaction output pays to a keyID which
+	// has never been provisioned.
+	ErrUnknownKeyID
+
+	// ErrKeyIDRevoked indicates a transaction output pays to a keyID which
+	// was provisioned but has since been revoked.
+	ErrKeyIDRevoked
+
+	// ErrThreadOutOfOrder indicates an admin transaction does not continue
+	// the admin thread it spends from, such as spending the tip of another
+	// thread or issuing admin operations without spending a thread tip.
+	ErrThreadOutOfOrder
+
+	// ErrValidateKeyRateLimited indicates a block is signed by a validate
+	// key which has signed too many of the recent blocks.
+	ErrValidateKeyRateLimited
 )
 
 // Map of ErrorCode values back to their constant names for pretty printing.
 var errorCodeStrings = map[ErrorCode]string{
-	ErrDuplicateBlock:       "ErrDuplicateBlock",
-	ErrBlockTooBig:          "ErrBlockTooBig",
-	ErrBlockVersionTooOld:   "ErrBlockVersionTooOld",
-	ErrInvalidTime:          "ErrInvalidTime",
-	ErrTimeTooOld:           "ErrTimeTooOld",
-	ErrTimeTooNew:           "ErrTimeTooNew",
-	ErrDifficultyTooLow:     "ErrDifficultyTooLow",
-	ErrUnexpectedDifficulty: "ErrUnexpectedDifficulty",
-	ErrBadHeight:            "ErrBadHeight",
-	ErrBadBlockSignature:    "ErrBadBlockSignature",
-	ErrHighHash:             "ErrHighHash",
-	ErrBadMerkleRoot:        "ErrBadMerkleRoot",
-	ErrBadCheckpoint:        "ErrBadCheckpoint",
-	ErrForkTooOld:           "ErrForkTooOld",
-	ErrCheckpointTimeTooOld: "ErrCheckpointTimeTooOld",
-	ErrNoTransactions:       "ErrNoTransactions",
-	ErrTooManyTransactions:  "ErrTooManyTransactions",
-	ErrNoTxInputs:           "ErrNoTxInputs",
-	ErrNoTxOutputs:          "ErrNoTxOutputs",
-	ErrTxTooBig:             "ErrTxTooBig",
-	ErrBadTxOutValue:        "ErrBadTxOutValue",
-	ErrDuplicateTxInputs:    "ErrDuplicateTxInputs",
-	ErrBadTxInput:           "ErrBadTxInput",
-	ErrMissingTx:            "ErrMissingTx",
-	ErrUnfinalizedTx:        "ErrUnfinalizedTx",
-	ErrDuplicateTx:          "ErrDuplicateTx",
-	ErrOverwriteTx:          "ErrOverwriteTx",
-	ErrImmatureSpend:        "ErrImmatureSpend",
-	ErrDoubleSpend:          "ErrDoubleSpend",
-	ErrSpendTooHigh:         "ErrSpendTooHigh",
-	ErrBadFees:              "ErrBadFees",
-	ErrTooManySigOps:        "ErrTooManySigOps",
-	ErrFirstTxNotCoinbase:   "ErrFirstTxNotCoinbase",
-	ErrMultipleCoinbases:    "ErrMultipleCoinbases",
-	ErrBadCoinbaseScriptLen: "ErrBadCoinbaseScriptLen",
-	ErrBadCoinbaseValue:     "ErrBadCoinbaseValue",
-	ErrScriptMalformed:      "ErrScriptMalformed",
-	ErrScriptValidation:     "ErrScriptValidation",
-	ErrExcessiveChainShare:  "ErrExcessiveChainShare",
-	ErrExcessiveTrailing:    "ErrExcessiveTrailing",
-	ErrInconsistentBlkSize:  "ErrInconsistentBlkSize",
-	ErrInvalidCoinbase:      "ErrInvalidCoinbase",
-	ErrInvalidTx:            "ErrInvalidTx",
-	ErrInvalidValidateKey:   "ErrInvalidValidateKey",
-	ErrInvalidAdminTx:       "ErrInvalidAdminTx",
-	ErrInvalidAdminOp:       "ErrInvalidAdminOp",
-	ErrFeeTooHigh:           "ErrFeeTooHigh",
+	ErrDuplicateBlock:         "ErrDuplicateBlock",
+	ErrBlockTooBig:            "ErrBlockTooBig",
+	ErrBlockVersionTooOld:     "ErrBlockVersionTooOld",
+	ErrInvalidTime:            "ErrInvalidTime",
+	ErrTimeTooOld:             "ErrTimeTooOld",
+	ErrTimeTooNew:             "ErrTimeTooNew",
+	ErrDifficultyTooLow:       "ErrDifficultyTooLow",
+	ErrUnexpectedDifficulty:   "ErrUnexpectedDifficulty",
+	ErrBadHeight:              "ErrBadHeight",
+	ErrBadBlockSignature:      "ErrBadBlockSignature",
+	ErrHighHash:               "ErrHighHash",
+	ErrBadMerkleRoot:          "ErrBadMerkleRoot",
+	ErrBadCheckpoint:          "ErrBadCheckpoint",
+	ErrForkTooOld:             "ErrForkTooOld",
+	ErrCheckpointTimeTooOld:   "ErrCheckpointTimeTooOld",
+	ErrNoTransactions:         "ErrNoTransactions",
+	ErrTooManyTransactions:    "ErrTooManyTransactions",
+	ErrNoTxInputs:             "ErrNoTxInputs",
+	ErrNoTxOutputs:            "ErrNoTxOutputs",
+	ErrTxTooBig:               "ErrTxTooBig",
+	ErrBadTxOutValue:          "ErrBadTxOutValue",
+	ErrDuplicateTxInputs:      "ErrDuplicateTxInputs",
+	ErrBadTxInput:             "ErrBadTxInput",
+	ErrMissingTx:              "ErrMissingTx",
+	ErrUnfinalizedTx:          "ErrUnfinalizedTx",
+	ErrDuplicateTx:            "ErrDuplicateTx",
+	ErrOverwriteTx:            "ErrOverwriteTx",
+	ErrImmatureSpend:          "ErrImmatureSpend",
+	ErrDoubleSpend:            "ErrDoubleSpend",
+	ErrSpendTooHigh:           "ErrSpendTooHigh",
+	ErrBadFees:                "ErrBadFees",
+	ErrTooManySigOps:          "ErrTooManySigOps",
+	ErrFirstTxNotCoinbase:     "ErrFirstTxNotCoinbase",
+	ErrMultipleCoinbases:      "ErrMultipleCoinbases",
+	ErrBadCoinbaseScriptLen:   "ErrBadCoinbaseScriptLen",
+	ErrBadCoinbaseValue:       "ErrBadCoinbaseValue",
+	ErrScriptMalformed:        "ErrScriptMalformed",
+	ErrScriptValidation:       "ErrScriptValidation",
+	ErrExcessiveChainShare:    "ErrExcessiveChainShare",
+	ErrExcessiveTrailing:      "ErrExcessiveTrailing",
+	ErrInconsistentBlkSize:    "ErrInconsistentBlkSize",
+	ErrInvalidCoinbase:        "ErrInvalidCoinbase",
+	ErrInvalidTx:              "ErrInvalidTx",
+	ErrInvalidValidateKey:     "ErrInvalidValidateKey",
+	ErrInvalidAdminTx:         "ErrInvalidAdminTx",
+	ErrInvalidAdminOp:         "ErrInvalidAdminOp",
+	ErrFeeTooHigh:             "ErrFeeTooHigh",
+	ErrPreviousBlockUnknown:   "ErrPreviousBlockUnknown",
+	ErrBadSnapshot:            "ErrBadSnapshot",
+	ErrUnknownSnapshot:        "ErrUnknownSnapshot",
+	ErrBadNoticeKeySet:        "ErrBadNoticeKeySet",
+	ErrNoticeExpired:          "ErrNoticeExpired",
+	ErrNoticeTimeTooNew:       "ErrNoticeTimeTooNew",
+	ErrBadNoticeSignature:     "ErrBadNoticeSignature",
+	ErrNoticeQuorum:           "ErrNoticeQuorum",
+	ErrBadNoticeVersion:       "ErrBadNoticeVersion",
+	ErrPrevBlockNotBest:       "ErrPrevBlockNotBest",
+	ErrUnknownKeyID:           "ErrUnknownKeyID",
+	ErrKeyIDRevoked:           "ErrKeyIDRevoked",
+	ErrThreadOutOfOrder:       "ErrThreadOutOfOrder",
+	ErrValidateKeyRateLimited: "ErrValidateKeyRateLimited",
 }
 
 // String returns the ErrorCode as a human-readable name.
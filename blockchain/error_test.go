@@ -61,6 +61,20 @@ func TestErrorCodeStringer(t *testing.T) {
 		{blockchain.ErrInconsistentBlkSize, "ErrInconsistentBlkSize"},
 		{blockchain.ErrInvalidValidateKey, "ErrInvalidValidateKey"},
 		{blockchain.ErrFeeTooHigh, "ErrFeeTooHigh"},
+		{blockchain.ErrPreviousBlockUnknown, "ErrPreviousBlockUnknown"},
+		{blockchain.ErrBadSnapshot, "ErrBadSnapshot"},
+		{blockchain.ErrUnknownSnapshot, "ErrUnknownSnapshot"},
+		{blockchain.ErrBadNoticeKeySet, "ErrBadNoticeKeySet"},
+		{blockchain.ErrNoticeExpired, "ErrNoticeExpired"},
+		{blockchain.ErrNoticeTimeTooNew, "ErrNoticeTimeTooNew"},
+		{blockchain.ErrBadNoticeSignature, "ErrBadNoticeSignature"},
+		{blockchain.ErrNoticeQuorum, "ErrNoticeQuorum"},
+		{blockchain.ErrBadNoticeVersion, "ErrBadNoticeVersion"},
+		{blockchain.ErrPrevBlockNotBest, "ErrPrevBlockNotBest"},
+		{blockchain.ErrUnknownKeyID, "ErrUnknownKeyID"},
+		{blockchain.ErrKeyIDRevoked, "ErrKeyIDRevoked"},
+		{blockchain.ErrThreadOutOfOrder, "ErrThreadOutOfOrder"},
+		{blockchain.ErrValidateKeyRateLimited, "ErrValidateKeyRateLimited"},
 		{0xffff, "Unknown ErrorCode (65535)"},
 	}
 
@@ -56,6 +56,14 @@ var (
 		0xe0, 0xbe, 0x63, 0xb3, 0x6b, 0x94, 0xb8, 0x3c, 0x2d, 0x1f,
 		0xd9, 0x77,
 	})
+	// A validate key which is added and then revoked by the tests.
+	validatePrivKey2, _ = btcec.PrivKeyFromBytes(btcec.S256(), []byte{
+		0x7a, 0x2f, 0x91, 0x0c, 0x5e, 0x3b, 0xd4, 0x68, 0x81, 0x16,
+		0xc3, 0x4d, 0x9e, 0x27, 0x50, 0xab, 0x36, 0xf1, 0x0d, 0x8c,
+		0x62, 0xe9, 0x4b, 0x15, 0xa7, 0x73, 0x2c, 0xde, 0x08, 0x95,
+		0xb4, 0x41,
+	})
+	validatePubKey2 = (*btcec.PublicKey)(&validatePrivKey2.PublicKey)
 	// Some keyIDs to make tests easier
 	keyId1 = btcec.KeyID(1)
 	keyId2 = btcec.KeyID(2)
@@ -214,6 +222,9 @@ type testGenerator struct {
 
 	// Common key for any tests which require signed transactions.
 	privKey *btcec.PrivateKey
+
+	// Validate key used to sign the generated blocks.
+	validateKey *btcec.PrivateKey
 }
 
 // makeTestGenerator returns a test generator instance initialized with the
@@ -231,6 +242,7 @@ func makeTestGenerator(params *chaincfg.Params) (testGenerator, error) {
 		tipName:      "genesis",
 		tipHeight:    0,
 		privKey:      privKey2,
+		validateKey:  validatePrivKey,
 	}, nil
 }
 
@@ -666,7 +678,7 @@ func (g *testGenerator) nextBlock(blockName string, spend *spendableOut, mungers
 		block.Header.MerkleRoot = calcMerkleRoot(block.Transactions)
 	}
 	block.Header.Size = uint32(block.SerializeSize())
-	block.Header.Sign(validatePrivKey)
+	block.Header.Sign(g.validateKey)
 
 	// Only solve the block if the nonce wasn't manually changed by a munge
 	// function.
@@ -897,7 +909,7 @@ func Generate(includeLargeReorg bool) (tests [][]TestInstance, err error) {
 	// Try to spend provision thread with root thread
 	issueKeyAddTx := createAdminTx(outs[1], 0, txscript.AdminOpIssueKeyAdd, pubKey1)
 	g.nextBlock("b2", nil, additionalTx(issueKeyAddTx))
-	rejected(blockchain.ErrInvalidAdminTx)
+	rejected(blockchain.ErrThreadOutOfOrder)
 
 	// Provision an ISSUE key in b3 and check its there.
 	g.setTip("b1")
@@ -1151,5 +1163,46 @@ func Generate(includeLargeReorg bool) (tests [][]TestInstance, err error) {
 	g.nextBlock("b31", outs[12], changeCoinbaseValue(1))
 	rejected(blockchain.ErrBadCoinbaseValue)
 
+	// ---------------------------------------------------------------------
+	// Validate key tests.
+	// ---------------------------------------------------------------------
+	//
+	//   ... -> b27(11) -> b32() -> b33() -> b34() -> b35()
+	//
+	// A validate key is added in b32 and signs b33.  Once it is revoked in
+	// b34, blocks signed by it must be rejected.
+	g.setTip("b27")
+	validateKeys := append([]btcec.PublicKey(nil),
+		lastAdminKeySets[btcec.ValidateKeySet]...)
+	provThreadOut = makeSpendableOutForTx(aspKeyIdTx, 0)
+	validateKeyAddTx := createAdminTx(&provThreadOut,
+		provautil.ProvisionThread, txscript.AdminOpValidateKeyAdd,
+		validatePubKey2)
+	provThreadOut = makeSpendableOutForTx(validateKeyAddTx, 0)
+	g.nextBlock("b32", nil, additionalTx(validateKeyAddTx))
+	assertThreadTip(provautil.ProvisionThread, provThreadOut)
+	assertAdminKeys(btcec.ValidateKeySet, append(validateKeys,
+		*validatePubKey2))
+	accepted()
+
+	g.validateKey = validatePrivKey2
+	g.nextBlock("b33", nil)
+	g.validateKey = validatePrivKey
+	accepted()
+
+	validateKeyRevokeTx := createAdminTx(&provThreadOut,
+		provautil.ProvisionThread, txscript.AdminOpValidateKeyRevoke,
+		validatePubKey2)
+	provThreadOut = makeSpendableOutForTx(validateKeyRevokeTx, 0)
+	g.nextBlock("b34", nil, additionalTx(validateKeyRevokeTx))
+	assertThreadTip(provautil.ProvisionThread, provThreadOut)
+	assertAdminKeys(btcec.ValidateKeySet, validateKeys)
+	accepted()
+
+	g.validateKey = validatePrivKey2
+	g.nextBlock("b35", nil)
+	g.validateKey = validatePrivKey
+	rejected(blockchain.ErrInvalidValidateKey)
+
 	return tests, nil
 }
@@ -0,0 +1,243 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"fmt"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/provautil/gcs/builder"
+)
+
+const (
+	// cfIndexName is the human-readable name for the index.
+	cfIndexName = "committed filter index"
+)
+
+var (
+	// cfIndexKey is the key of the committed filter index and the db
+	// bucket used to house the block hash -> filter mappings.
+	cfIndexKey = []byte("cfbyhashidx")
+
+	// cfHeaderIndexBucketName is the name of the db bucket used to house
+	// the block hash -> filter header mappings.
+	cfHeaderIndexBucketName = []byte("cfhbyhashidx")
+)
+
+// -----------------------------------------------------------------------------
+// The committed filter index consists of the basic GCS filter of every block
+// in the main chain along with the filter header, which commits to the filter
+// of the block and the filter headers of all previous blocks.  The filters
+// contain the pubkey hashes and keyIDs paid to, the admin thread scripts, the
+// admin operation keys, and the outpoints spent by each block, so that light
+// clients can find the blocks relevant to them without revealing their keys.
+//
+// There are two buckets used in total.  The first bucket maps the hash of each
+// block to the serialized filter and the second maps the hash of each block to
+// the filter header.
+//
+// The serialized format for keys and values in the filter bucket is:
+//   <hash> = <filter>
+//
+//   Field           Type              Size
+//   hash            chainhash.Hash    32 bytes
+//   filter          []byte            variable (N followed by the filter)
+//
+// The serialized format for keys and values in the filter header bucket is:
+//   <hash> = <filter header>
+//
+//   Field           Type              Size
+//   hash            chainhash.Hash    32 bytes
+//   filter header   chainhash.Hash    32 bytes
+//   -----
+//   Total: 64 bytes
+// -----------------------------------------------------------------------------
+
+// dbFetchFilter uses an existing database transaction to fetch the serialized
+// filter of the passed block.  When there is no entry for the provided hash,
+// nil will be returned.
+func dbFetchFilter(dbTx database.Tx, hash *chainhash.Hash) []byte {
+	serialized := dbTx.Metadata().Bucket(cfIndexKey).Get(hash[:])
+	if serialized == nil {
+		return nil
+	}
+
+	// The returned slice is only valid for the lifetime of the database
+	// transaction, so make a copy.
+	filter := make([]byte, len(serialized))
+	copy(filter, serialized)
+	return filter
+}
+
+// dbFetchFilterHeader uses an existing database transaction to fetch the filter
+// header of the passed block.  When there is no entry for the provided hash,
+// nil will be returned.
+func dbFetchFilterHeader(dbTx database.Tx, hash *chainhash.Hash) *chainhash.Hash {
+	serialized := dbTx.Metadata().Bucket(cfHeaderIndexBucketName).Get(hash[:])
+	if len(serialized) != chainhash.HashSize {
+		return nil
+	}
+
+	var header chainhash.Hash
+	copy(header[:], serialized)
+	return &header
+}
+
+// CfIndex implements a committed filter (cf) by block hash index.  That is to
+// say, it supports querying the basic filter and the filter header of every
+// block of the main chain by the hash of the block.
+type CfIndex struct {
+	db database.DB
+}
+
+// Ensure the CfIndex type implements the Indexer interface.
+var _ Indexer = (*CfIndex)(nil)
+
+// Init initializes the committed filter index.  There is nothing to initialize.
+//
+// This is part of the Indexer interface.
+func (idx *CfIndex) Init() error {
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *CfIndex) Key() []byte {
+	return cfIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *CfIndex) Name() string {
+	return cfIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs to be
+// created for the first time.  It creates the buckets for the filters and the
+// filter headers.
+//
+// This is part of the Indexer interface.
+func (idx *CfIndex) Create(dbTx database.Tx) error {
+	meta := dbTx.Metadata()
+	if _, err := meta.CreateBucket(cfHeaderIndexBucketName); err != nil {
+		return err
+	}
+	_, err := meta.CreateBucket(cfIndexKey)
+	return err
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer builds the filter of the block and
+// adds it along with its filter header, which commits to the filter header of
+// the previous block, to the index.
+//
+// This is part of the Indexer interface.
+func (idx *CfIndex) ConnectBlock(dbTx database.Tx, block *provautil.Block, view *blockchain.UtxoViewpoint) error {
+	filter, err := builder.BuildBasicFilter(block.MsgBlock())
+	if err != nil {
+		return err
+	}
+	serialized, err := filter.NBytes()
+	if err != nil {
+		return err
+	}
+
+	// The filter header of the genesis block commits to an all zero
+	// previous header.
+	var prevHeader chainhash.Hash
+	prevHash := &block.MsgBlock().Header.PrevBlock
+	if *prevHash != (chainhash.Hash{}) {
+		header := dbFetchFilterHeader(dbTx, prevHash)
+		if header == nil {
+			return fmt.Errorf("no filter header for block %v, the "+
+				"parent of block %v", prevHash, block.Hash())
+		}
+		prevHeader = *header
+	}
+	header, err := builder.MakeHeaderForFilter(filter, prevHeader)
+	if err != nil {
+		return err
+	}
+
+	meta := dbTx.Metadata()
+	hash := block.Hash()
+	if err := meta.Bucket(cfIndexKey).Put(hash[:], serialized); err != nil {
+		return err
+	}
+	return meta.Bucket(cfHeaderIndexBucketName).Put(hash[:], header[:])
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the filter and the
+// filter header of the block.
+//
+// This is part of the Indexer interface.
+func (idx *CfIndex) DisconnectBlock(dbTx database.Tx, block *provautil.Block, view *blockchain.UtxoViewpoint) error {
+	meta := dbTx.Metadata()
+	hash := block.Hash()
+	if err := meta.Bucket(cfIndexKey).Delete(hash[:]); err != nil {
+		return err
+	}
+	return meta.Bucket(cfHeaderIndexBucketName).Delete(hash[:])
+}
+
+// FilterByBlockHash returns the serialized basic filter of the block with the
+// passed hash.  The filter starts with the number of its items.  When there is
+// no entry for the provided hash, nil will be returned for the both the filter
+// and the error.
+//
+// This function is safe for concurrent access.
+func (idx *CfIndex) FilterByBlockHash(hash *chainhash.Hash) ([]byte, error) {
+	var filter []byte
+	err := idx.db.View(func(dbTx database.Tx) error {
+		filter = dbFetchFilter(dbTx, hash)
+		return nil
+	})
+	return filter, err
+}
+
+// FilterHeaderByBlockHash returns the filter header of the block with the
+// passed hash.  When there is no entry for the provided hash, nil will be
+// returned for the both the header and the error.
+//
+// This function is safe for concurrent access.
+func (idx *CfIndex) FilterHeaderByBlockHash(hash *chainhash.Hash) (*chainhash.Hash, error) {
+	var header *chainhash.Hash
+	err := idx.db.View(func(dbTx database.Tx) error {
+		header = dbFetchFilterHeader(dbTx, hash)
+		return nil
+	})
+	return header, err
+}
+
+// NewCfIndex returns a new instance of an indexer that is used to create a
+// mapping of the hashes of all blocks in the blockchain to their basic filter
+// and filter header.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewCfIndex(db database.DB) *CfIndex {
+	return &CfIndex{db: db}
+}
+
+// dropCfHeaderIndex drops the filter header bucket of the committed filter
+// index.
+func dropCfHeaderIndex(db database.DB) error {
+	return db.Update(func(dbTx database.Tx) error {
+		return dbTx.Metadata().DeleteBucket(cfHeaderIndexBucketName)
+	})
+}
+
+// DropCfIndex drops the committed filter index from the provided database if it
+// exists.
+func DropCfIndex(db database.DB) error {
+	return dropIndex(db, cfIndexKey, cfIndexName)
+}
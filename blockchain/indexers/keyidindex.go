@@ -0,0 +1,502 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/txscript"
+	"github.com/bitgo/prova/wire"
+)
+
+const (
+	// keyIDIndexName is the human-readable name for the index.
+	keyIDIndexName = "keyid index"
+
+	// addrKeyTypeKeyID is the address type in a key of the keyID index
+	// which represents a keyID.  The keyID occupies the first 4 bytes of
+	// the hash of the key.
+	addrKeyTypeKeyID = 2
+
+	// keyIDUtxoKeySize is the number of bytes a key in the keyID utxo
+	// bucket consumes.  It consists of the 4 byte keyID + 32 byte
+	// transaction hash + 4 byte output index.
+	keyIDUtxoKeySize = btcec.KeyIDSize + chainhash.HashSize + 4
+)
+
+var (
+	// keyIDIndexKey is the key of the keyID index and the db bucket used
+	// to house the keyID -> transactions mappings.
+	keyIDIndexKey = []byte("txbykeyididx")
+
+	// keyIDUtxoBucketName is the name of the db bucket used to house the
+	// unspent outputs which are secured by each keyID.
+	keyIDUtxoBucketName = []byte("utxobykeyididx")
+)
+
+// -----------------------------------------------------------------------------
+// The keyID index maps the keyIDs referenced in the blockchain to a list of all
+// the transactions involving that keyID, and to the unspent outputs secured by
+// that keyID.  A transaction involves a keyID when it creates an output which
+// is secured by the keyID, spends such an output, or provisions or revokes the
+// ASP key with the keyID.  This lets ASP operators enumerate everything that
+// depends on one of their keys across all user addresses, for example to
+// recover the funds or to assess the exposure when a key is revoked.
+//
+// Like the address index, this index requires the transaction index since the
+// spent outputs are needed to catch up old blocks.
+//
+// The transactions are stored with the level-based scheme of the address index
+// in the keyID index bucket.  The keys use the address key format with the
+// addrKeyTypeKeyID type and the little-endian keyID followed by zeros in place
+// of the hash.
+//
+// The serialized key format of the keyID utxo bucket is:
+//
+//   <keyID><tx hash><output index>
+//
+//   Field           Type              Size
+//   keyID           uint32            4 bytes (big-endian)
+//   tx hash         chainhash.Hash    32 bytes
+//   output index    uint32            4 bytes (big-endian)
+//   -----
+//   Total: 40 bytes
+//
+// The keyID and output index are big-endian so the outputs of a keyID are
+// adjacent and ordered in the bucket.  The serialized value format is:
+//
+//   <amount>
+//
+//   Field           Type      Size
+//   amount          int64     8 bytes
+// -----------------------------------------------------------------------------
+
+// keyIDToKey converts the passed keyID to a key of the keyID index.
+func keyIDToKey(keyID btcec.KeyID) [addrKeySize]byte {
+	var result [addrKeySize]byte
+	result[0] = addrKeyTypeKeyID
+	keyID.ToAddressFormat(result[1:])
+	return result
+}
+
+// keyIDUtxoKey returns the key of the passed outpoint in the keyID utxo bucket
+// of the passed keyID.
+func keyIDUtxoKey(keyID btcec.KeyID, outPoint *wire.OutPoint) []byte {
+	key := make([]byte, keyIDUtxoKeySize)
+	binary.BigEndian.PutUint32(key, uint32(keyID))
+	copy(key[btcec.KeyIDSize:], outPoint.Hash[:])
+	binary.BigEndian.PutUint32(key[btcec.KeyIDSize+chainhash.HashSize:],
+		outPoint.Index)
+	return key
+}
+
+// dbPutKeyIDUtxo adds the passed unspent output to the keyID utxo bucket for
+// every passed keyID.
+func dbPutKeyIDUtxo(bucket internalBucket, keyIDs []btcec.KeyID, outPoint *wire.OutPoint, amount int64) error {
+	var serialized [8]byte
+	byteOrder.PutUint64(serialized[:], uint64(amount))
+	for _, keyID := range keyIDs {
+		err := bucket.Put(keyIDUtxoKey(keyID, outPoint), serialized[:])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dbRemoveKeyIDUtxo removes the passed output from the keyID utxo bucket for
+// every passed keyID.
+func dbRemoveKeyIDUtxo(bucket internalBucket, keyIDs []btcec.KeyID, outPoint *wire.OutPoint) error {
+	for _, keyID := range keyIDs {
+		if err := bucket.Delete(keyIDUtxoKey(keyID, outPoint)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractKeyIDs returns the keyIDs the passed public key script is secured by.
+// Nil is returned for scripts which are not Prova scripts.
+func extractKeyIDs(pkScript []byte) []btcec.KeyID {
+	_, keyIDs, err := txscript.ExtractProvaKeys(pkScript)
+	if err != nil {
+		return nil
+	}
+	return keyIDs
+}
+
+// KeyIDUtxo describes an unspent output which is secured by a keyID.
+type KeyIDUtxo struct {
+	OutPoint wire.OutPoint
+	Amount   provautil.Amount
+}
+
+// KeyIDIndex implements a transaction and unspent output by keyID index.  That
+// is to say, it supports querying all transactions that create, spend, or
+// provision outputs and keys with a given keyID, and all unspent outputs that
+// are secured by it.  The returned transactions are ordered according to their
+// order of appearance in the blockchain.
+type KeyIDIndex struct {
+	db database.DB
+}
+
+// Ensure the KeyIDIndex type implements the Indexer interface.
+var _ Indexer = (*KeyIDIndex)(nil)
+
+// Ensure the KeyIDIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*KeyIDIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *KeyIDIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init is only provided to satisfy the Indexer interface as there is nothing to
+// initialize for this index.
+//
+// This is part of the Indexer interface.
+func (idx *KeyIDIndex) Init() error {
+	// Nothing to do.
+	return nil
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *KeyIDIndex) Key() []byte {
+	return keyIDIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *KeyIDIndex) Name() string {
+	return keyIDIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs to be
+// created for the first time.  It creates the buckets for the transactions and
+// the unspent outputs of the keyIDs.
+//
+// This is part of the Indexer interface.
+func (idx *KeyIDIndex) Create(dbTx database.Tx) error {
+	meta := dbTx.Metadata()
+	if _, err := meta.CreateBucket(keyIDUtxoBucketName); err != nil {
+		return err
+	}
+	_, err := meta.CreateBucket(keyIDIndexKey)
+	return err
+}
+
+// indexKeyIDs maps each of the passed keyIDs to the passed transaction using
+// the passed map.
+func indexKeyIDs(data writeIndexData, keyIDs []btcec.KeyID, txIdx int) {
+	for _, keyID := range keyIDs {
+		// Avoid inserting the transaction more than once.  Since the
+		// transactions are indexed serially any duplicates will be
+		// indexed in a row, so checking the most recent entry for the
+		// keyID is enough to detect duplicates.
+		key := keyIDToKey(keyID)
+		indexedTxns := data[key]
+		numTxns := len(indexedTxns)
+		if numTxns > 0 && indexedTxns[numTxns-1] == txIdx {
+			continue
+		}
+		data[key] = append(indexedTxns, txIdx)
+	}
+}
+
+// adminOpKeyIDs returns the keyIDs of the ASP keys which are provisioned or
+// revoked by the passed transaction.
+func adminOpKeyIDs(msgTx *wire.MsgTx) []btcec.KeyID {
+	threadInt, adminOutputs := txscript.GetAdminDetailsMsgTx(msgTx)
+	if provautil.ThreadID(threadInt) != provautil.ProvisionThread {
+		return nil
+	}
+
+	var keyIDs []btcec.KeyID
+	for _, pops := range adminOutputs {
+		if !txscript.IsValidAdminOp(pops, provautil.ProvisionThread) {
+			continue
+		}
+		_, keySetType, _, keyID := txscript.ExtractAdminOpData(pops)
+		if keySetType == btcec.ASPKeySet {
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+	return keyIDs
+}
+
+// indexBlock maps all of the keyIDs involved in the transactions of the passed
+// block to the associated transaction using the passed map.
+func (idx *KeyIDIndex) indexBlock(data writeIndexData, block *provautil.Block, view *blockchain.UtxoViewpoint) {
+	for txIdx, tx := range block.Transactions() {
+		// Coinbases do not reference any inputs.
+		if txIdx != 0 {
+			for _, txIn := range tx.MsgTx().TxIn {
+				// The view should always have the input since
+				// the index contract requires it, however, be
+				// safe and simply ignore any missing entries.
+				origin := &txIn.PreviousOutPoint
+				entry := view.LookupEntry(&origin.Hash)
+				if entry == nil {
+					continue
+				}
+
+				pkScript := entry.PkScriptByIndex(origin.Index)
+				indexKeyIDs(data, extractKeyIDs(pkScript), txIdx)
+			}
+		}
+
+		for _, txOut := range tx.MsgTx().TxOut {
+			indexKeyIDs(data, extractKeyIDs(txOut.PkScript), txIdx)
+		}
+
+		indexKeyIDs(data, adminOpKeyIDs(tx.MsgTx()), txIdx)
+	}
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds a mapping for each keyID the
+// transactions in the block involve, adds the outputs created by the block to
+// the unspent outputs of their keyIDs, and removes the outputs spent by the
+// block.
+//
+// This is part of the Indexer interface.
+func (idx *KeyIDIndex) ConnectBlock(dbTx database.Tx, block *provautil.Block, view *blockchain.UtxoViewpoint) error {
+	// The offset and length of the transactions within the serialized
+	// block.
+	txLocs, err := block.TxLoc()
+	if err != nil {
+		return err
+	}
+
+	// Get the internal block ID associated with the block.
+	blockID, err := dbFetchBlockIDByHash(dbTx, block.Hash())
+	if err != nil {
+		return err
+	}
+
+	// Build all of the keyID to transaction mappings in a local map.
+	keyIDsToTxns := make(writeIndexData)
+	idx.indexBlock(keyIDsToTxns, block, view)
+
+	// Add all of the index entries for each keyID.
+	meta := dbTx.Metadata()
+	keyIDIdxBucket := meta.Bucket(keyIDIndexKey)
+	for key, txIdxs := range keyIDsToTxns {
+		for _, txIdx := range txIdxs {
+			err := dbPutAddrIndexEntry(keyIDIdxBucket, key, blockID,
+				txLocs[txIdx])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Update the unspent outputs in the order of the transactions since
+	// transactions later in the block can spend the outputs of earlier
+	// ones.
+	utxoBucket := meta.Bucket(keyIDUtxoBucketName)
+	for txIdx, tx := range block.Transactions() {
+		if txIdx != 0 {
+			for _, txIn := range tx.MsgTx().TxIn {
+				origin := &txIn.PreviousOutPoint
+				entry := view.LookupEntry(&origin.Hash)
+				if entry == nil {
+					continue
+				}
+
+				keyIDs := extractKeyIDs(entry.PkScriptByIndex(
+					origin.Index))
+				err := dbRemoveKeyIDUtxo(utxoBucket, keyIDs, origin)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for txOutIdx, txOut := range tx.MsgTx().TxOut {
+			outPoint := wire.OutPoint{
+				Hash:  *tx.Hash(),
+				Index: uint32(txOutIdx),
+			}
+			err := dbPutKeyIDUtxo(utxoBucket,
+				extractKeyIDs(txOut.PkScript), &outPoint,
+				txOut.Value)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the keyID mappings
+// each transaction in the block involve, removes the outputs created by the
+// block from the unspent outputs of their keyIDs, and restores the outputs
+// spent by the block.
+//
+// This is part of the Indexer interface.
+func (idx *KeyIDIndex) DisconnectBlock(dbTx database.Tx, block *provautil.Block, view *blockchain.UtxoViewpoint) error {
+	// Build all of the keyID to transaction mappings in a local map.
+	keyIDsToTxns := make(writeIndexData)
+	idx.indexBlock(keyIDsToTxns, block, view)
+
+	// Remove all of the index entries for each keyID.
+	meta := dbTx.Metadata()
+	keyIDIdxBucket := meta.Bucket(keyIDIndexKey)
+	for key, txIdxs := range keyIDsToTxns {
+		err := dbRemoveAddrIndexEntries(keyIDIdxBucket, key, len(txIdxs))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Loop backwards through the transactions so outputs which are both
+	// created and spent by the block end up removed.
+	utxoBucket := meta.Bucket(keyIDUtxoBucketName)
+	transactions := block.Transactions()
+	for txIdx := len(transactions) - 1; txIdx >= 0; txIdx-- {
+		tx := transactions[txIdx]
+		for txOutIdx, txOut := range tx.MsgTx().TxOut {
+			outPoint := wire.OutPoint{
+				Hash:  *tx.Hash(),
+				Index: uint32(txOutIdx),
+			}
+			err := dbRemoveKeyIDUtxo(utxoBucket,
+				extractKeyIDs(txOut.PkScript), &outPoint)
+			if err != nil {
+				return err
+			}
+		}
+
+		if txIdx == 0 {
+			continue
+		}
+		for _, txIn := range tx.MsgTx().TxIn {
+			origin := &txIn.PreviousOutPoint
+			entry := view.LookupEntry(&origin.Hash)
+			if entry == nil {
+				continue
+			}
+
+			keyIDs := extractKeyIDs(entry.PkScriptByIndex(origin.Index))
+			err := dbPutKeyIDUtxo(utxoBucket, keyIDs, origin,
+				entry.AmountByIndex(origin.Index))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// TxRegionsForKeyID returns a slice of block regions which identify each
+// transaction that involves the passed keyID.  The number of entries to skip
+// and the maximum number of entries to return are specified with numToSkip and
+// numRequested, and the reverse flag returns the newest transactions first.
+// The number of entries actually skipped is returned as well.
+//
+// This function is safe for concurrent access.
+func (idx *KeyIDIndex) TxRegionsForKeyID(dbTx database.Tx, keyID btcec.KeyID, numToSkip, numRequested uint32, reverse bool) ([]database.BlockRegion, uint32, error) {
+	// Create closure to lookup the block hash given the ID using the
+	// database transaction.
+	fetchBlockHash := func(id []byte) (*chainhash.Hash, error) {
+		// Deserialize and populate the result.
+		return dbFetchBlockHashBySerializedID(dbTx, id)
+	}
+
+	keyIDIdxBucket := dbTx.Metadata().Bucket(keyIDIndexKey)
+	return dbFetchAddrIndexEntries(keyIDIdxBucket, keyIDToKey(keyID),
+		numToSkip, numRequested, reverse, fetchBlockHash)
+}
+
+// UtxosForKeyID returns the unspent outputs which are secured by the passed
+// keyID, ordered by outpoint.  The number of outputs to skip and the maximum
+// number of outputs to return are specified with numToSkip and numRequested.
+// The total number and value of the unspent outputs of the keyID are returned
+// as well, regardless of the requested range.
+//
+// This function is safe for concurrent access.
+func (idx *KeyIDIndex) UtxosForKeyID(keyID btcec.KeyID, numToSkip, numRequested uint32) ([]KeyIDUtxo, uint32, provautil.Amount, error) {
+	var prefix [btcec.KeyIDSize]byte
+	binary.BigEndian.PutUint32(prefix[:], uint32(keyID))
+
+	var utxos []KeyIDUtxo
+	var count uint32
+	var total provautil.Amount
+	err := idx.db.View(func(dbTx database.Tx) error {
+		cursor := dbTx.Metadata().Bucket(keyIDUtxoBucketName).Cursor()
+		for ok := cursor.Seek(prefix[:]); ok; ok = cursor.Next() {
+			key := cursor.Key()
+			if !bytes.HasPrefix(key, prefix[:]) {
+				break
+			}
+			value := cursor.Value()
+			if len(key) != keyIDUtxoKeySize || len(value) != 8 {
+				return database.Error{
+					ErrorCode: database.ErrCorruption,
+					Description: "malformed keyid utxo " +
+						"index entry",
+				}
+			}
+
+			amount := provautil.Amount(byteOrder.Uint64(value))
+			total += amount
+			count++
+			if count <= numToSkip ||
+				uint32(len(utxos)) >= numRequested {
+
+				continue
+			}
+
+			utxo := KeyIDUtxo{Amount: amount}
+			copy(utxo.OutPoint.Hash[:], key[btcec.KeyIDSize:])
+			utxo.OutPoint.Index = binary.BigEndian.Uint32(
+				key[btcec.KeyIDSize+chainhash.HashSize:])
+			utxos = append(utxos, utxo)
+		}
+		return nil
+	})
+
+	return utxos, count, total, err
+}
+
+// NewKeyIDIndex returns a new instance of an indexer that is used to create a
+// mapping of all keyIDs in the blockchain to the transactions that involve
+// them and to the unspent outputs they secure.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewKeyIDIndex(db database.DB) *KeyIDIndex {
+	return &KeyIDIndex{db: db}
+}
+
+// dropKeyIDUtxoIndex drops the unspent output bucket of the keyID index.
+func dropKeyIDUtxoIndex(db database.DB) error {
+	return db.Update(func(dbTx database.Tx) error {
+		return dbTx.Metadata().DeleteBucket(keyIDUtxoBucketName)
+	})
+}
+
+// DropKeyIDIndex drops the keyID index from the provided database if it
+// exists.
+func DropKeyIDIndex(db database.DB) error {
+	return dropIndex(db, keyIDIndexKey, keyIDIndexName)
+}
@@ -0,0 +1,535 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	_ "github.com/bitgo/prova/database/ffldb"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/txscript"
+	"github.com/bitgo/prova/wire"
+)
+
+// keyIDUtxoBucket provides a mock keyID utxo database bucket by implementing
+// the internalBucket interface.
+type keyIDUtxoBucket struct {
+	entries map[string][]byte
+}
+
+// Get returns the value associated with the key from the mock bucket.
+//
+// This is part of the internalBucket interface.
+func (b *keyIDUtxoBucket) Get(key []byte) []byte {
+	return b.entries[string(key)]
+}
+
+// Put stores the provided key/value pair to the mock bucket.
+//
+// This is part of the internalBucket interface.
+func (b *keyIDUtxoBucket) Put(key []byte, value []byte) error {
+	b.entries[string(key)] = value
+	return nil
+}
+
+// Delete removes the provided key from the mock bucket.
+//
+// This is part of the internalBucket interface.
+func (b *keyIDUtxoBucket) Delete(key []byte) error {
+	delete(b.entries, string(key))
+	return nil
+}
+
+// TestKeyIDIndexKeys ensures the keys of the keyID index serialize as expected
+// and that the unspent outputs of a keyID are adjacent in key order.
+func TestKeyIDIndexKeys(t *testing.T) {
+	t.Parallel()
+
+	key := keyIDToKey(btcec.KeyID(0x01020304))
+	want := [addrKeySize]byte{addrKeyTypeKeyID, 0x04, 0x03, 0x02, 0x01}
+	if key != want {
+		t.Fatalf("keyIDToKey: unexpected key - got %x, want %x", key,
+			want)
+	}
+
+	// Add outputs for several keyIDs in an order which differs from the
+	// expected key order.
+	bucket := &keyIDUtxoBucket{entries: make(map[string][]byte)}
+	hash := chainhash.Hash{0x01}
+	keyIDs := []btcec.KeyID{0x100, 0x2, 0x1ff}
+	for i := uint32(0); i < 3; i++ {
+		outPoint := wire.OutPoint{Hash: hash, Index: 256 - i}
+		err := dbPutKeyIDUtxo(bucket, keyIDs, &outPoint, int64(i+1))
+		if err != nil {
+			t.Fatalf("dbPutKeyIDUtxo: unexpected error: %v", err)
+		}
+	}
+	if len(bucket.entries) != 9 {
+		t.Fatalf("unexpected number of entries - got %d, want 9",
+			len(bucket.entries))
+	}
+
+	// Ensure the entries sort by keyID first and output index second.
+	keys := make([]string, 0, len(bucket.entries))
+	for k := range bucket.entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	wantKeyIDs := []btcec.KeyID{0x2, 0x100, 0x1ff}
+	for i, k := range keys {
+		keyID := wantKeyIDs[i/3]
+		outPoint := wire.OutPoint{Hash: hash, Index: 254 + uint32(i%3)}
+		if !bytes.Equal([]byte(k), keyIDUtxoKey(keyID, &outPoint)) {
+			t.Fatalf("entry #%d: unexpected key %x, want keyID %d "+
+				"output %v", i, k, keyID, outPoint)
+		}
+	}
+
+	// Ensure removing the outputs of a single keyID leaves the others.
+	outPoint := wire.OutPoint{Hash: hash, Index: 255}
+	err := dbRemoveKeyIDUtxo(bucket, []btcec.KeyID{0x100}, &outPoint)
+	if err != nil {
+		t.Fatalf("dbRemoveKeyIDUtxo: unexpected error: %v", err)
+	}
+	if bucket.Get(keyIDUtxoKey(0x100, &outPoint)) != nil {
+		t.Fatal("removed entry still exists")
+	}
+	value := bucket.Get(keyIDUtxoKey(0x2, &outPoint))
+	if len(value) != 8 || byteOrder.Uint64(value) != 2 {
+		t.Fatalf("unexpected value for remaining entry: %x", value)
+	}
+}
+
+// keyIDTestChain houses a chain of blocks along with the utxo views needed to
+// connect and disconnect them which is used to test the keyID index.  Each
+// block has a coinbase which pays to a script secured by keyIDs 1 and 2, and
+// every block after the first one has a transaction which spends the coinbase
+// of the previous block to a script secured by keyIDs 1 and 3.
+type keyIDTestChain struct {
+	blocks []*provautil.Block
+	views  []*blockchain.UtxoViewpoint
+}
+
+// newKeyIDTestChain returns a keyID index test chain with the passed number of
+// blocks.
+func newKeyIDTestChain(t *testing.T, numBlocks int) *keyIDTestChain {
+	newScript := func(keyIDs ...btcec.KeyID) []byte {
+		addr, err := provautil.NewAddressProva(make([]byte, 20), keyIDs,
+			&chaincfg.RegressionNetParams)
+		if err != nil {
+			t.Fatalf("NewAddressProva: unexpected error: %v", err)
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+		}
+		return script
+	}
+	coinbaseScript := newScript(1, 2)
+	spendScript := newScript(1, 3)
+
+	chain := &keyIDTestChain{}
+	var prevCoinbase *provautil.Tx
+	for i := 1; i <= numBlocks; i++ {
+		coinbase := wire.NewMsgTx(1)
+		coinbase.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+			Index: wire.MaxPrevOutIndex,
+		}, []byte{byte(i), byte(i >> 8)}))
+		coinbase.AddTxOut(wire.NewTxOut(int64(i)*100, coinbaseScript))
+		msgBlock := wire.MsgBlock{
+			Header: wire.BlockHeader{
+				Timestamp: time.Unix(int64(i), 0),
+				Height:    uint32(i),
+			},
+			Transactions: []*wire.MsgTx{coinbase},
+		}
+
+		view := blockchain.NewUtxoViewpoint()
+		if prevCoinbase != nil {
+			spend := wire.NewMsgTx(1)
+			spend.AddTxIn(wire.NewTxIn(&wire.OutPoint{
+				Hash: *prevCoinbase.Hash(),
+			}, nil))
+			spend.AddTxOut(wire.NewTxOut(
+				prevCoinbase.MsgTx().TxOut[0].Value, spendScript))
+			msgBlock.Transactions = append(msgBlock.Transactions,
+				spend)
+			view.AddTxOuts(prevCoinbase, uint32(i-1))
+		}
+
+		block := provautil.NewBlock(&msgBlock)
+		chain.blocks = append(chain.blocks, block)
+		chain.views = append(chain.views, view)
+		prevCoinbase = block.Transactions()[0]
+	}
+	return chain
+}
+
+// newKeyIDTestDB returns a new database with the buckets of the keyID index and
+// the block ID index it depends on along with a teardown function the caller
+// should invoke when done testing to clean up.
+func newKeyIDTestDB(t *testing.T) (database.DB, func()) {
+	dbPath, err := ioutil.TempDir("", "keyidindex")
+	if err != nil {
+		t.Fatalf("Failed creating a temporary directory: %v", err)
+	}
+	db, err := database.Create("ffldb", filepath.Join(dbPath, "db"),
+		wire.MainNet)
+	if err != nil {
+		os.RemoveAll(dbPath)
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	teardown := func() {
+		db.Close()
+		os.RemoveAll(dbPath)
+	}
+	err = db.Update(func(dbTx database.Tx) error {
+		if err := NewTxIndex(db).Create(dbTx); err != nil {
+			return err
+		}
+		return NewKeyIDIndex(db).Create(dbTx)
+	})
+	if err != nil {
+		teardown()
+		t.Fatalf("Failed to create index buckets: %v", err)
+	}
+	return db, teardown
+}
+
+// TestKeyIDIndexConnectDisconnect ensures connecting and disconnecting blocks
+// updates the transactions and unspent outputs of the keyIDs involved, and
+// that the transactions are stored in levels which do not exceed their
+// maximum number of entries.
+func TestKeyIDIndexConnectDisconnect(t *testing.T) {
+	const numBlocks = 20
+	chain := newKeyIDTestChain(t, numBlocks)
+	db, teardown := newKeyIDTestDB(t)
+	defer teardown()
+	idx := NewKeyIDIndex(db)
+
+	// wantTxns returns the block regions of the transactions which involve
+	// the passed keyID when the passed number of blocks are connected.
+	wantTxns := func(keyID btcec.KeyID, numBlocks int) []database.BlockRegion {
+		var regions []database.BlockRegion
+		for _, block := range chain.blocks[:numBlocks] {
+			txLocs, err := block.TxLoc()
+			if err != nil {
+				t.Fatalf("TxLoc: unexpected error: %v", err)
+			}
+			for txIdx := range block.Transactions() {
+				// Coinbases pay to keyIDs 1 and 2, while the
+				// other transactions spend from keyIDs 1 and
+				// 2 and pay to keyIDs 1 and 3.
+				if keyID == 3 && txIdx == 0 {
+					continue
+				}
+				regions = append(regions, database.BlockRegion{
+					Hash:   block.Hash(),
+					Offset: uint32(txLocs[txIdx].TxStart),
+					Len:    uint32(txLocs[txIdx].TxLen),
+				})
+			}
+		}
+		return regions
+	}
+
+	// wantUtxos returns the unspent outputs secured by the passed keyID
+	// when the passed number of blocks are connected, ordered by outpoint.
+	wantUtxos := func(keyID btcec.KeyID, numBlocks int) []KeyIDUtxo {
+		var utxos []KeyIDUtxo
+		for i, block := range chain.blocks[:numBlocks] {
+			for txIdx, tx := range block.Transactions() {
+				spent := txIdx == 0 && i != numBlocks-1
+				if spent || (keyID == 2 && txIdx != 0) ||
+					(keyID == 3 && txIdx == 0) {
+
+					continue
+				}
+				utxos = append(utxos, KeyIDUtxo{
+					OutPoint: wire.OutPoint{Hash: *tx.Hash()},
+					Amount: provautil.Amount(
+						tx.MsgTx().TxOut[0].Value),
+				})
+			}
+		}
+		sort.Slice(utxos, func(i, j int) bool {
+			return bytes.Compare(utxos[i].OutPoint.Hash[:],
+				utxos[j].OutPoint.Hash[:]) < 0
+		})
+		return utxos
+	}
+
+	// checkIndex ensures the index reflects the passed number of connected
+	// blocks.
+	checkIndex := func(numBlocks int) {
+		for _, keyID := range []btcec.KeyID{1, 2, 3} {
+			want := wantTxns(keyID, numBlocks)
+			var regions []database.BlockRegion
+			var levelEntries int
+			err := db.View(func(dbTx database.Tx) error {
+				var err error
+				regions, _, err = idx.TxRegionsForKeyID(dbTx,
+					keyID, 0, uint32(len(want)+1), false)
+				if err != nil {
+					return err
+				}
+
+				// Ensure no level exceeds its maximum number
+				// of entries.
+				bucket := dbTx.Metadata().Bucket(keyIDIndexKey)
+				key := keyIDToKey(keyID)
+				for level := uint8(0); level < 8; level++ {
+					levelKey := keyForLevel(key, level)
+					entries := len(bucket.Get(levelKey[:])) /
+						txEntrySize
+					if entries > maxEntriesForLevel(level) {
+						t.Errorf("keyID %d level %d has %d "+
+							"entries", keyID, level,
+							entries)
+					}
+					levelEntries += entries
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("TxRegionsForKeyID: unexpected error: %v",
+					err)
+			}
+			if levelEntries != len(want) {
+				t.Fatalf("%d blocks, keyID %d: unexpected number "+
+					"of level entries - got %d, want %d",
+					numBlocks, keyID, levelEntries, len(want))
+			}
+			if len(regions) != len(want) {
+				t.Fatalf("%d blocks, keyID %d: unexpected number "+
+					"of transactions - got %d, want %d",
+					numBlocks, keyID, len(regions), len(want))
+			}
+			for i := range regions {
+				if *regions[i].Hash != *want[i].Hash ||
+					regions[i].Offset != want[i].Offset ||
+					regions[i].Len != want[i].Len {
+
+					t.Fatalf("%d blocks, keyID %d: unexpected "+
+						"transaction #%d", numBlocks,
+						keyID, i)
+				}
+			}
+
+			wantUtxos := wantUtxos(keyID, numBlocks)
+			var wantTotal provautil.Amount
+			for _, utxo := range wantUtxos {
+				wantTotal += utxo.Amount
+			}
+			utxos, count, total, err := idx.UtxosForKeyID(keyID, 0,
+				uint32(len(wantUtxos)))
+			if err != nil {
+				t.Fatalf("UtxosForKeyID: unexpected error: %v", err)
+			}
+			if count != uint32(len(wantUtxos)) || total != wantTotal {
+				t.Fatalf("%d blocks, keyID %d: unexpected utxo "+
+					"totals - got %d (%v), want %d (%v)",
+					numBlocks, keyID, count, total,
+					len(wantUtxos), wantTotal)
+			}
+			if len(utxos) != len(wantUtxos) {
+				t.Fatalf("%d blocks, keyID %d: unexpected number "+
+					"of utxos - got %d, want %d", numBlocks,
+					keyID, len(utxos), len(wantUtxos))
+			}
+			for i := range utxos {
+				if utxos[i] != wantUtxos[i] {
+					t.Fatalf("%d blocks, keyID %d: unexpected "+
+						"utxo #%d - got %v, want %v",
+						numBlocks, keyID, i, utxos[i],
+						wantUtxos[i])
+				}
+			}
+		}
+	}
+
+	// Connect all of the blocks and ensure the index is updated after
+	// each of them.
+	for i, block := range chain.blocks {
+		err := db.Update(func(dbTx database.Tx) error {
+			err := dbPutBlockIDIndexEntry(dbTx, block.Hash(),
+				uint32(i+1))
+			if err != nil {
+				return err
+			}
+			return idx.ConnectBlock(dbTx, block, chain.views[i])
+		})
+		if err != nil {
+			t.Fatalf("ConnectBlock #%d: unexpected error: %v", i, err)
+		}
+		checkIndex(i + 1)
+	}
+
+	// Disconnect the blocks again and ensure the index is restored to the
+	// state before each of them was connected.
+	for i := len(chain.blocks) - 1; i >= 0; i-- {
+		block := chain.blocks[i]
+		err := db.Update(func(dbTx database.Tx) error {
+			err := idx.DisconnectBlock(dbTx, block, chain.views[i])
+			if err != nil {
+				return err
+			}
+			return dbRemoveBlockIDIndexEntry(dbTx, block.Hash())
+		})
+		if err != nil {
+			t.Fatalf("DisconnectBlock #%d: unexpected error: %v", i,
+				err)
+		}
+		checkIndex(i)
+	}
+}
+
+// TestKeyIDIndexPaging ensures the transactions and unspent outputs of a keyID
+// can be requested in pages.
+func TestKeyIDIndexPaging(t *testing.T) {
+	const numBlocks = 15
+	chain := newKeyIDTestChain(t, numBlocks)
+	db, teardown := newKeyIDTestDB(t)
+	defer teardown()
+	idx := NewKeyIDIndex(db)
+
+	for i, block := range chain.blocks {
+		err := db.Update(func(dbTx database.Tx) error {
+			err := dbPutBlockIDIndexEntry(dbTx, block.Hash(),
+				uint32(i+1))
+			if err != nil {
+				return err
+			}
+			return idx.ConnectBlock(dbTx, block, chain.views[i])
+		})
+		if err != nil {
+			t.Fatalf("ConnectBlock #%d: unexpected error: %v", i, err)
+		}
+	}
+
+	// Fetch all of the unspent outputs of keyID 3, which are the outputs
+	// of the spending transactions, and ensure the pages match them.
+	all, count, total, err := idx.UtxosForKeyID(3, 0, numBlocks)
+	if err != nil {
+		t.Fatalf("UtxosForKeyID: unexpected error: %v", err)
+	}
+	if len(all) != numBlocks-1 || count != numBlocks-1 {
+		t.Fatalf("UtxosForKeyID: unexpected number of utxos - got %d "+
+			"(count %d), want %d", len(all), count, numBlocks-1)
+	}
+	utxoTests := []struct {
+		skip, requested uint32
+		want            []KeyIDUtxo
+	}{
+		{skip: 0, requested: 5, want: all[:5]},
+		{skip: 5, requested: 5, want: all[5:10]},
+		{skip: 10, requested: 5, want: all[10:]},
+		{skip: numBlocks, requested: 5, want: nil},
+		{skip: 3, requested: 0, want: nil},
+	}
+	for i, test := range utxoTests {
+		utxos, pageCount, pageTotal, err := idx.UtxosForKeyID(3,
+			test.skip, test.requested)
+		if err != nil {
+			t.Fatalf("UtxosForKeyID #%d: unexpected error: %v", i, err)
+		}
+		if pageCount != count || pageTotal != total {
+			t.Errorf("UtxosForKeyID #%d: unexpected totals - got %d "+
+				"(%v), want %d (%v)", i, pageCount, pageTotal,
+				count, total)
+		}
+		if len(utxos) != len(test.want) {
+			t.Errorf("UtxosForKeyID #%d: unexpected number of utxos "+
+				"- got %d, want %d", i, len(utxos),
+				len(test.want))
+			continue
+		}
+		for j := range utxos {
+			if utxos[j] != test.want[j] {
+				t.Errorf("UtxosForKeyID #%d: unexpected utxo #%d "+
+					"- got %v, want %v", i, j, utxos[j],
+					test.want[j])
+			}
+		}
+	}
+
+	// Ensure the transactions of keyID 2 can be paged through in both
+	// directions.
+	var allRegions []database.BlockRegion
+	err = db.View(func(dbTx database.Tx) error {
+		var err error
+		allRegions, _, err = idx.TxRegionsForKeyID(dbTx, 2, 0,
+			2*numBlocks, false)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("TxRegionsForKeyID: unexpected error: %v", err)
+	}
+	if len(allRegions) != 2*numBlocks-1 {
+		t.Fatalf("TxRegionsForKeyID: unexpected number of transactions "+
+			"- got %d, want %d", len(allRegions), 2*numBlocks-1)
+	}
+	reversed := make([]database.BlockRegion, len(allRegions))
+	for i := range allRegions {
+		reversed[len(allRegions)-1-i] = allRegions[i]
+	}
+	regionTests := []struct {
+		skip, requested uint32
+		reverse         bool
+		want            []database.BlockRegion
+		wantSkipped     uint32
+	}{
+		{skip: 3, requested: 10, want: allRegions[3:13], wantSkipped: 3},
+		{skip: 20, requested: 10, want: allRegions[20:],
+			wantSkipped: 20},
+		{skip: 3, requested: 10, reverse: true, want: reversed[3:13],
+			wantSkipped: 3},
+		{skip: 40, requested: 10, want: nil, wantSkipped: 29},
+	}
+	for i, test := range regionTests {
+		var regions []database.BlockRegion
+		var skipped uint32
+		err := db.View(func(dbTx database.Tx) error {
+			var err error
+			regions, skipped, err = idx.TxRegionsForKeyID(dbTx, 2,
+				test.skip, test.requested, test.reverse)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("TxRegionsForKeyID #%d: unexpected error: %v", i,
+				err)
+		}
+		if skipped != test.wantSkipped {
+			t.Errorf("TxRegionsForKeyID #%d: unexpected skipped "+
+				"count - got %d, want %d", i, skipped,
+				test.wantSkipped)
+		}
+		if len(regions) != len(test.want) {
+			t.Errorf("TxRegionsForKeyID #%d: unexpected number of "+
+				"transactions - got %d, want %d", i,
+				len(regions), len(test.want))
+			continue
+		}
+		for j := range regions {
+			if *regions[j].Hash != *test.want[j].Hash ||
+				regions[j].Offset != test.want[j].Offset {
+
+				t.Errorf("TxRegionsForKeyID #%d: unexpected "+
+					"transaction #%d", i, j)
+			}
+		}
+	}
+}
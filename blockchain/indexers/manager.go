@@ -620,11 +620,21 @@ func dropIndex(db database.DB, idxKey []byte, idxName string) error {
 		}
 	}
 
-	// Call extra index specific deinitialization for the transaction index.
-	if idxName == txIndexName {
+	// Call extra index specific deinitialization for the indexes which use
+	// more than one bucket.
+	switch idxName {
+	case txIndexName:
 		if err := dropBlockIDIndex(db); err != nil {
 			return err
 		}
+	case cfIndexName:
+		if err := dropCfHeaderIndex(db); err != nil {
+			return err
+		}
+	case keyIDIndexName:
+		if err := dropKeyIDUtxoIndex(db); err != nil {
+			return err
+		}
 	}
 
 	// Remove the index tip, index bucket, and in-progress drop flag now
@@ -468,12 +468,15 @@ func dropBlockIDIndex(db database.DB) error {
 }
 
 // DropTxIndex drops the transaction index from the provided database if it
-// exists.  Since the address index relies on it, the address index will also be
-// dropped when it exists.
+// exists.  Since the address and keyID indexes rely on it, they will also be
+// dropped when they exist.
 func DropTxIndex(db database.DB) error {
 	if err := dropIndex(db, addrIndexKey, addrIndexName); err != nil {
 		return err
 	}
+	if err := dropIndex(db, keyIDIndexKey, keyIDIndexName); err != nil {
+		return err
+	}
 
 	return dropIndex(db, txIndexKey, txIndexName)
 }
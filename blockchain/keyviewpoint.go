@@ -7,7 +7,10 @@ package blockchain
 
 import (
 	"bytes"
+	"fmt"
+
 	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/database"
 	"github.com/bitgo/prova/provautil"
 	"github.com/bitgo/prova/txscript"
 	"github.com/bitgo/prova/wire"
@@ -258,3 +261,118 @@ func NewKeyViewpoint() *KeyViewpoint {
 		aspKeyIdMap:  make(map[btcec.KeyID]*btcec.PublicKey),
 	}
 }
+
+// bestKeyView returns a new key view which represents the admin state at the
+// end of the main chain.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) bestKeyView() *KeyViewpoint {
+	keyView := NewKeyViewpoint()
+	keyView.SetThreadTips(b.threadTips)
+	keyView.SetLastKeyID(b.lastKeyID)
+	keyView.SetTotalSupply(b.totalSupply)
+	keyView.SetKeys(b.adminKeySets)
+	keyView.SetKeyIDs(b.aspKeyIdMap)
+	return keyView
+}
+
+// fetchKeyView returns a key view which represents the historical admin state
+// as of the passed block node, that is, after all admin operations in the block
+// have been applied.  When the node is the end of the main chain, the current
+// admin state is returned.  Otherwise the admin operations of the main chain
+// blocks after the fork point are undone, and the admin operations of any side
+// chain blocks which lead up to the node are replayed on top of it.
+//
+// Since every main chain block after the fork point has to be loaded to undo
+// it, nodes which fork the main chain more than MinBlocksToKeep blocks before
+// its end are rejected with ErrForkTooOld.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) fetchKeyView(node *blockNode) (*KeyViewpoint, error) {
+	keyView := b.bestKeyView()
+	if node == nil || node.hash.IsEqual(b.bestNode.hash) {
+		return keyView, nil
+	}
+
+	// isTooDeep returns whether the passed node is buried too deep in the
+	// main chain for the admin state to be rolled back to it.
+	isTooDeep := func(n *blockNode) bool {
+		return n.height+MinBlocksToKeep < b.bestNode.height
+	}
+	forkTooOld := func() error {
+		str := fmt.Sprintf("block %v forks the main chain more than %d "+
+			"blocks before its end", node.hash, MinBlocksToKeep)
+		return ruleError(ErrForkTooOld, str)
+	}
+
+	// Find the fork point with the main chain while collecting the side
+	// chain nodes which lead up to the passed node.
+	var attachNodes []*blockNode
+	forkNode := node
+	for forkNode != nil && !forkNode.inMainChain {
+		if isTooDeep(forkNode) {
+			return nil, forkTooOld()
+		}
+		attachNodes = append(attachNodes, forkNode)
+
+		var err error
+		forkNode, err = b.getPrevNodeFromNode(forkNode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if forkNode == nil {
+		str := fmt.Sprintf("fetchKeyView: unable to find fork point "+
+			"for block %v", node.hash)
+		return nil, AssertError(str)
+	}
+	if isTooDeep(forkNode) {
+		return nil, forkTooOld()
+	}
+
+	// Collect the main chain nodes after the fork point, starting at the
+	// end of the main chain.
+	var detachNodes []*blockNode
+	for n := b.bestNode; n != nil && n.height > forkNode.height; {
+		detachNodes = append(detachNodes, n)
+
+		var err error
+		n, err = b.getPrevNodeFromNode(n)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := b.db.View(func(dbTx database.Tx) error {
+		// Undo the admin operations of the main chain blocks so the
+		// view represents the state at the fork point.
+		for _, n := range detachNodes {
+			block, err := dbFetchBlockByHash(dbTx, n.hash)
+			if err != nil {
+				return err
+			}
+			err = keyView.disconnectTransactions(block)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Replay the admin operations of the side chain blocks in the
+		// order they were built on top of the fork point.
+		for i := len(attachNodes) - 1; i >= 0; i-- {
+			n := attachNodes[i]
+			block, err := dbFetchBlockByHash(dbTx, n.hash)
+			if err != nil {
+				return err
+			}
+			block.SetHeight(n.height)
+			keyView.connectTransactions(block)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return keyView, nil
+}
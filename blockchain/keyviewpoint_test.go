@@ -0,0 +1,77 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/wire"
+)
+
+// TestFetchKeyViewForkTooOld ensures the admin state is not rolled back for
+// nodes which fork the main chain deeper than MinBlocksToKeep blocks before its
+// end, so no blocks have to be loaded for them.
+func TestFetchKeyViewForkTooOld(t *testing.T) {
+	// newNode returns a node at the passed height which builds on the
+	// passed parent.
+	newNode := func(parent *blockNode, height uint32, nonce uint64) *blockNode {
+		header := wire.BlockHeader{Height: height, Nonce: nonce}
+		if parent != nil {
+			header.PrevBlock = *parent.hash
+		}
+		hash := header.BlockHash()
+		node := newBlockNode(&header, &hash)
+		node.parent = parent
+		return node
+	}
+
+	// Create a main chain which is one block longer than the blocks kept
+	// safe from reorganization.  The chain has no database, so any attempt
+	// to load blocks would fail.
+	var mainNodes []*blockNode
+	var tip *blockNode
+	for height := uint32(0); height <= MinBlocksToKeep+1; height++ {
+		tip = newNode(tip, height, 0)
+		tip.inMainChain = true
+		mainNodes = append(mainNodes, tip)
+	}
+	chain := &BlockChain{
+		chainParams: &chaincfg.RegressionNetParams,
+		bestNode:    tip,
+	}
+
+	tests := []struct {
+		name string
+		node *blockNode
+	}{
+		{
+			name: "main chain node",
+			node: mainNodes[0],
+		},
+		{
+			name: "side chain node",
+			node: newNode(mainNodes[0], 1, 1),
+		},
+		{
+			name: "long side chain node",
+			node: newNode(newNode(mainNodes[0], 1, 1), 2, 1),
+		},
+	}
+	for _, test := range tests {
+		_, err := chain.fetchKeyView(test.node)
+		rerr, ok := err.(RuleError)
+		if !ok || rerr.ErrorCode != ErrForkTooOld {
+			t.Errorf("fetchKeyView (%s): unexpected error - got %v, "+
+				"want %v", test.name, err, ErrForkTooOld)
+		}
+	}
+
+	// The end of the main chain doesn't require any rollback.
+	if _, err := chain.fetchKeyView(tip); err != nil {
+		t.Errorf("fetchKeyView: unexpected error for the best node: %v",
+			err)
+	}
+}
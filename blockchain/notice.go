@@ -0,0 +1,96 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/wire"
+)
+
+// CheckNotice ensures the passed network notice is of a known version, is signed
+// by a quorum of the keys of the root or provision key set as of the end of the
+// main chain, and that it has not expired.  The quorum is defined by the
+// NoticeQuorum chain parameter.  Since the admin key sets change over time, a
+// notice which was valid when it was received may no longer be valid later on.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CheckNotice(notice *wire.MsgNotice) error {
+	if notice.Version != wire.NoticeVersionCurrent {
+		str := fmt.Sprintf("notice version %d is not supported",
+			notice.Version)
+		return ruleError(ErrBadNoticeVersion, str)
+	}
+
+	keySetType := btcec.KeySetType(notice.KeySet)
+	if keySetType != btcec.RootKeySet &&
+		keySetType != btcec.ProvisionKeySet {
+
+		str := fmt.Sprintf("notice is signed by the %v key set which "+
+			"may not sign notices", keySetType)
+		return ruleError(ErrBadNoticeKeySet, str)
+	}
+
+	// Ensure the notice has not expired and was not created too far in
+	// the future.
+	now := b.timeSource.AdjustedTime()
+	if !notice.Expiration.After(now) {
+		str := fmt.Sprintf("notice expired at %v", notice.Expiration)
+		return ruleError(ErrNoticeExpired, str)
+	}
+	maxTimestamp := now.Add(time.Second * MaxTimeOffsetSeconds)
+	if notice.Timestamp.After(maxTimestamp) {
+		str := fmt.Sprintf("notice timestamp of %v is too far in the "+
+			"future", notice.Timestamp)
+		return ruleError(ErrNoticeTimeTooNew, str)
+	}
+
+	// Ensure every signature is made by a distinct key of the key set and
+	// that there are enough of them.
+	keySet := b.AdminKeySets()[keySetType]
+	hash := notice.NoticeHash()
+	signers := make(map[int]struct{}, len(notice.Signatures))
+	for i := range notice.Signatures {
+		sig := &notice.Signatures[i]
+		pubKey, err := btcec.ParsePubKey(sig.PubKey[:], btcec.S256())
+		if err != nil {
+			str := fmt.Sprintf("notice signature %d has an invalid "+
+				"public key: %v", i, err)
+			return ruleError(ErrBadNoticeSignature, str)
+		}
+		pos := keySet.Pos(pubKey)
+		if pos < 0 {
+			str := fmt.Sprintf("notice signature %d is made by key "+
+				"%x which is not in the %v key set", i,
+				sig.PubKey, keySetType)
+			return ruleError(ErrBadNoticeSignature, str)
+		}
+		if _, ok := signers[pos]; ok {
+			str := fmt.Sprintf("notice signature %d is made by key "+
+				"%x which already signed it", i, sig.PubKey)
+			return ruleError(ErrBadNoticeSignature, str)
+		}
+
+		signature, err := btcec.ParseDERSignature(sig.Signature,
+			btcec.S256())
+		if err != nil || !signature.Verify(hash[:], pubKey) {
+			str := fmt.Sprintf("notice signature %d by key %x is "+
+				"invalid", i, sig.PubKey)
+			return ruleError(ErrBadNoticeSignature, str)
+		}
+		signers[pos] = struct{}{}
+	}
+	quorum := b.chainParams.NoticeQuorum
+	if len(signers) < quorum {
+		str := fmt.Sprintf("notice is signed by %d keys of the %v key "+
+			"set instead of at least %d", len(signers), keySetType,
+			quorum)
+		return ruleError(ErrNoticeQuorum, str)
+	}
+
+	return nil
+}
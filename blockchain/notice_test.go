@@ -0,0 +1,143 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/wire"
+)
+
+// TestCheckNotice ensures network notices are only accepted when they are
+// signed by a quorum of the keys of the root or provision key set.
+func TestCheckNotice(t *testing.T) {
+	// Use root and provision key sets with known private keys.
+	var keys []*btcec.PrivateKey
+	for i := byte(1); i <= 4; i++ {
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+			bytes.Repeat([]byte{i}, 32))
+		keys = append(keys, key)
+	}
+	params := chaincfg.RegressionNetParams
+	params.AdminKeySets = btcec.DeepCopy(params.AdminKeySets)
+	params.AdminKeySets[btcec.RootKeySet] = btcec.PublicKeySet{
+		*keys[0].PubKey(), *keys[1].PubKey(), *keys[2].PubKey(),
+	}
+	params.AdminKeySets[btcec.ProvisionKeySet] = btcec.PublicKeySet{
+		*keys[3].PubKey(),
+	}
+	chain, teardownFunc, err := chainSetup("checknotice", &params)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	now := time.Now()
+	newNotice := func(keySet btcec.KeySetType, timestamp,
+		expiration time.Time, signers ...*btcec.PrivateKey) *wire.MsgNotice {
+
+		notice := wire.NewMsgNotice(keySet, timestamp, expiration, 1,
+			"please upgrade")
+		for _, key := range signers {
+			if err := notice.Sign(key); err != nil {
+				t.Fatalf("Sign: unexpected error: %v", err)
+			}
+		}
+		return notice
+	}
+	expiration := now.Add(time.Hour)
+	badSigNotice := newNotice(btcec.RootKeySet, now, expiration, keys[0],
+		keys[1])
+	badSigNotice.Signatures[1].Signature = badSigNotice.Signatures[0].Signature
+	unknownVersionNotice := newNotice(btcec.RootKeySet, now, expiration)
+	unknownVersionNotice.Version = wire.NoticeVersionCurrent + 1
+	for _, key := range keys[:2] {
+		if err := unknownVersionNotice.Sign(key); err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		notice *wire.MsgNotice
+		err    error
+	}{{
+		name: "root quorum",
+		notice: newNotice(btcec.RootKeySet, now, expiration, keys[0],
+			keys[2]),
+	}, {
+		name: "all root keys",
+		notice: newNotice(btcec.RootKeySet, now, expiration, keys[0],
+			keys[1], keys[2]),
+	}, {
+		name:   "single root key",
+		notice: newNotice(btcec.RootKeySet, now, expiration, keys[1]),
+		err:    blockchain.RuleError{ErrorCode: blockchain.ErrNoticeQuorum},
+	}, {
+		name: "duplicate root key",
+		notice: newNotice(btcec.RootKeySet, now, expiration, keys[0],
+			keys[0]),
+		err: blockchain.RuleError{ErrorCode: blockchain.ErrBadNoticeSignature},
+	}, {
+		name: "key from another key set",
+		notice: newNotice(btcec.RootKeySet, now, expiration, keys[0],
+			keys[3]),
+		err: blockchain.RuleError{ErrorCode: blockchain.ErrBadNoticeSignature},
+	}, {
+		name:   "invalid signature",
+		notice: badSigNotice,
+		err:    blockchain.RuleError{ErrorCode: blockchain.ErrBadNoticeSignature},
+	}, {
+		name: "provision key set too small for quorum",
+		notice: newNotice(btcec.ProvisionKeySet, now, expiration,
+			keys[3]),
+		err: blockchain.RuleError{ErrorCode: blockchain.ErrNoticeQuorum},
+	}, {
+		name:   "unknown version",
+		notice: unknownVersionNotice,
+		err:    blockchain.RuleError{ErrorCode: blockchain.ErrBadNoticeVersion},
+	}, {
+		name: "issue key set",
+		notice: newNotice(btcec.IssueKeySet, now, expiration, keys[0],
+			keys[1]),
+		err: blockchain.RuleError{ErrorCode: blockchain.ErrBadNoticeKeySet},
+	}, {
+		name: "expired",
+		notice: newNotice(btcec.RootKeySet, now.Add(-2*time.Hour),
+			now.Add(-time.Hour), keys[0], keys[1]),
+		err: blockchain.RuleError{ErrorCode: blockchain.ErrNoticeExpired},
+	}, {
+		name: "timestamp too far in the future",
+		notice: newNotice(btcec.RootKeySet, now.Add(3*time.Hour),
+			now.Add(4*time.Hour), keys[0], keys[1]),
+		err: blockchain.RuleError{ErrorCode: blockchain.ErrNoticeTimeTooNew},
+	}}
+
+	for _, test := range tests {
+		err := chain.CheckNotice(test.notice)
+		if test.err == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", test.name, err)
+			}
+			continue
+		}
+		rerr, ok := err.(blockchain.RuleError)
+		if !ok {
+			t.Errorf("%s: unexpected error type - got %T, want %T",
+				test.name, err, test.err)
+			continue
+		}
+		if rerr.ErrorCode != test.err.(blockchain.RuleError).ErrorCode {
+			t.Errorf("%s: unexpected error code - got %v, want %v",
+				test.name, rerr.ErrorCode,
+				test.err.(blockchain.RuleError).ErrorCode)
+		}
+	}
+}
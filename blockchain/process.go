@@ -34,6 +34,19 @@ const (
 	// without modifying the current state.
 	BFDryRun
 
+	// BFAssumeValid may be set to indicate the block is known to be an
+	// ancestor of the configured assume-valid block in a header chain that
+	// has already been validated, so the expensive script checks can be
+	// skipped.  It has no effect when no assume-valid block is configured.
+	BFAssumeValid
+
+	// BFNoValidateKeyCheck may be set to indicate the block signature and
+	// the checks which ensure the block is signed by an active validate key
+	// which is not rate limited will not be performed.  This is useful to
+	// check blocks assembled by external block builders before they are
+	// signed.
+	BFNoValidateKeyCheck
+
 	// BFNone is a convenience value to specifically indicate no flags.
 	BFNone BehaviorFlags = 0
 )
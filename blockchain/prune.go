@@ -0,0 +1,79 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"github.com/bitgo/prova/database"
+)
+
+// MinBlocksToKeep is the minimum number of blocks at the end of the main chain
+// whose data is never pruned.  Blocks which are buried deeper than this are
+// considered safe from reorganization, so the spend journal entries needed to
+// disconnect them are no longer needed either.
+const MinBlocksToKeep = 288
+
+// pruneDepth returns the number of blocks at the end of the main chain whose
+// data must be kept when pruning.  Besides the reorganization safe depth, this
+// also covers the most recent blocks which are included in utxo set snapshots.
+func (b *BlockChain) pruneDepth() uint32 {
+	depth := b.snapshotBlockCount()
+	if depth < MinBlocksToKeep {
+		depth = MinBlocksToKeep
+	}
+	return depth
+}
+
+// pruneBlocks deletes the data for the oldest blocks when the stored blocks
+// exceed the configured prune target along with the spend journal entries for
+// them.  The data for the blocks within the prune depth of the passed node,
+// which is the new end of the main chain, is always kept.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) pruneBlocks(dbTx database.Tx, node *blockNode) error {
+	depth := b.pruneDepth()
+	if node.height <= depth {
+		return nil
+	}
+	keepHash, err := dbFetchHashByHeight(dbTx, node.height-depth)
+	if err != nil {
+		return err
+	}
+
+	prunedHashes, err := dbTx.PruneBlocks(b.pruneTarget, keepHash)
+	if err != nil {
+		return err
+	}
+	for i := range prunedHashes {
+		err := dbRemoveSpendJournalEntry(dbTx, &prunedHashes[i])
+		if err != nil {
+			return err
+		}
+	}
+	if len(prunedHashes) > 0 {
+		log.Infof("Pruned %d blocks older than height %d",
+			len(prunedHashes), node.height-depth)
+	}
+
+	return nil
+}
+
+// IsPruned returns whether or not the data for any blocks has been deleted by
+// pruning or was never available because the chain was bootstrapped from a utxo
+// set snapshot.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) IsPruned() (bool, error) {
+	var pruned bool
+	err := b.db.View(func(dbTx database.Tx) error {
+		if dbHasSnapshotBlock(dbTx) {
+			pruned = true
+			return nil
+		}
+		var err error
+		pruned, err = dbTx.BeenPruned()
+		return err
+	})
+	return pruned, err
+}
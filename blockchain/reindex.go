@@ -0,0 +1,152 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+)
+
+// reindexLogInterval is the number of blocks between progress messages while
+// rebuilding the chain state.
+const reindexLogInterval = 10000
+
+// resetChainState removes the chain state from the database so it is rebuilt
+// from the stored blocks.  This includes the main chain block index, the utxo
+// set, the spend journal, the admin key set and the best chain state.  The
+// hashes of the stored blocks are returned in the order they were stored so
+// they can be connected again once the chain state is initialized to the
+// genesis block.
+//
+// The chain state is only removed when all of the stored blocks are available
+// and every one of them was stored after its parent, since it could not be
+// rebuilt otherwise.  That is not the case for pruned databases and databases
+// which were bootstrapped from a utxo set snapshot.
+func (b *BlockChain) resetChainState() ([]chainhash.Hash, error) {
+	var hashes []chainhash.Hash
+	err := b.db.View(func(dbTx database.Tx) error {
+		pruned, err := dbTx.BeenPruned()
+		if err != nil {
+			return err
+		}
+		if pruned {
+			return AssertError("unable to rebuild the chain state " +
+				"of a pruned database")
+		}
+
+		hashes, err = dbTx.StoredBlockHashes()
+		if err != nil {
+			return err
+		}
+
+		stored := make(map[chainhash.Hash]struct{}, len(hashes))
+		for i := range hashes {
+			hash := &hashes[i]
+			stored[*hash] = struct{}{}
+			header, err := dbFetchHeaderByHash(dbTx, hash)
+			if err != nil {
+				return err
+			}
+			if header.PrevBlock.IsEqual(zeroHash) {
+				continue
+			}
+			if _, ok := stored[header.PrevBlock]; !ok {
+				str := fmt.Sprintf("unable to rebuild the chain "+
+					"state since the parent of block %v is "+
+					"not available", hash)
+				return AssertError(str)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Removing the chain state to rebuild it from %d stored "+
+		"blocks", len(hashes))
+	err = b.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		for _, bucketName := range [][]byte{hashIndexBucketName,
+			heightIndexBucketName, spendJournalBucketName,
+			utxoSetBucketName} {
+
+			if meta.Bucket(bucketName) == nil {
+				continue
+			}
+			if err := meta.DeleteBucket(bucketName); err != nil {
+				return err
+			}
+		}
+		if err := meta.Delete(keySetBucketName); err != nil {
+			return err
+		}
+		return meta.Delete(chainStateKeyName)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// rebuildChainState connects the stored blocks identified by the passed hashes
+// in the order they were stored to a chain state which has been initialized to
+// the genesis block.  The blocks go through the same chain selection as when
+// they were first processed, so side chains and reorganizations are handled,
+// and blocks which fail to connect are skipped.
+//
+// No notifications are sent for the blocks since the chain state is rebuilt
+// before the chain instance is handed to the caller.
+func (b *BlockChain) rebuildChainState(hashes []chainhash.Hash) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	notifications := b.notifications
+	b.notifications = nil
+	defer func() {
+		b.notifications = notifications
+	}()
+
+	for i := range hashes {
+		hash := &hashes[i]
+		var block *provautil.Block
+		err := b.db.View(func(dbTx database.Tx) error {
+			var err error
+			block, err = dbFetchBlockByHash(dbTx, hash)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// The genesis block is already connected.
+		if block.MsgBlock().Header.PrevBlock.IsEqual(zeroHash) {
+			continue
+		}
+
+		_, err = b.maybeAcceptBlock(block, BFNone)
+		if err != nil {
+			if _, ok := err.(RuleError); !ok {
+				return err
+			}
+			log.Warnf("Skipping block %v which failed to connect: %v",
+				hash, err)
+		}
+
+		if (i+1)%reindexLogInterval == 0 {
+			log.Infof("Rebuilt chain state from %d of %d blocks "+
+				"(height %d)", i+1, len(hashes),
+				b.bestNode.height)
+		}
+	}
+
+	log.Infof("Rebuilt chain state from %d blocks (height %d, hash %v)",
+		len(hashes), b.bestNode.height, b.bestNode.hash)
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/blockchain/fullblocktests"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/txscript"
+)
+
+// checkChainStateConsistent ensures the chain state stored in the passed
+// database is consistent with the stored blocks.
+func checkChainStateConsistent(t *testing.T, db database.DB) {
+	report, err := blockchain.VerifyChainState(db)
+	if err != nil {
+		t.Fatalf("VerifyChainState: unexpected error: %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Fatalf("VerifyChainState: unexpected problems: %v",
+			report.Problems)
+	}
+	if report.BlocksChecked != report.BestHeight+1 {
+		t.Fatalf("VerifyChainState: checked %d blocks, want %d",
+			report.BlocksChecked, report.BestHeight+1)
+	}
+}
+
+// TestReindexChainState ensures the chain state rebuilt from the stored blocks,
+// which include side chain blocks and blocks that failed to connect, is the
+// same as the chain state they were originally processed into.
+func TestReindexChainState(t *testing.T) {
+	tests, err := fullblocktests.Generate(false)
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	// Create a new database to store the blocks into.
+	dbPath := filepath.Join(os.TempDir(), "reindexchainstate")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create(testDbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	params := chaincfg.RegressionNetParams
+	newChain := func(reindex bool) (*blockchain.BlockChain, error) {
+		return blockchain.New(&blockchain.Config{
+			DB:                db,
+			ChainParams:       &params,
+			TimeSource:        blockchain.NewMedianTime(),
+			SigCache:          txscript.NewSigCache(1000),
+			ReindexChainState: reindex,
+		})
+	}
+	chain, err := newChain(false)
+	if err != nil {
+		t.Fatalf("failed to create chain instance: %v", err)
+	}
+
+	// Process all of the test blocks.  The results are checked by the full
+	// block tests, so they are ignored here.
+	for _, testInstances := range tests {
+		for _, instance := range testInstances {
+			var block *provautil.Block
+			switch item := instance.(type) {
+			case fullblocktests.AcceptedBlock:
+				block = provautil.NewBlock(item.Block)
+			case fullblocktests.RejectedBlock:
+				block = provautil.NewBlock(item.Block)
+			case fullblocktests.OrphanOrRejectedBlock:
+				block = provautil.NewBlock(item.Block)
+			default:
+				continue
+			}
+			_, _, _ = chain.ProcessBlock(block, blockchain.BFNone)
+		}
+	}
+	checkChainStateConsistent(t, db)
+	best := chain.BestSnapshot()
+	totalSupply := chain.TotalSupply()
+	var buf bytes.Buffer
+	info, err := chain.DumpUtxoSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("DumpUtxoSnapshot: %v", err)
+	}
+
+	// Rebuild the chain state and ensure it matches the original one.
+	reindexedChain, err := newChain(true)
+	if err != nil {
+		t.Fatalf("failed to reindex chain state: %v", err)
+	}
+	checkChainStateConsistent(t, db)
+	reindexedBest := reindexedChain.BestSnapshot()
+	if *reindexedBest.Hash != *best.Hash ||
+		reindexedBest.Height != best.Height ||
+		reindexedBest.TotalTxns != best.TotalTxns {
+
+		t.Fatalf("unexpected best state -- got %v (%d), want %v (%d)",
+			reindexedBest.Hash, reindexedBest.Height, best.Hash,
+			best.Height)
+	}
+	if reindexedChain.TotalSupply() != totalSupply {
+		t.Fatalf("unexpected total supply -- got %d, want %d",
+			reindexedChain.TotalSupply(), totalSupply)
+	}
+	buf.Reset()
+	reindexedInfo, err := reindexedChain.DumpUtxoSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("DumpUtxoSnapshot: %v", err)
+	}
+	if reindexedInfo.Hash != info.Hash {
+		t.Fatalf("unexpected utxo set commitment -- got %v, want %v",
+			reindexedInfo.Hash, info.Hash)
+	}
+}
+
+// TestVerifyChainState ensures inconsistencies between the chain state and the
+// stored blocks are reported.
+func TestVerifyChainState(t *testing.T) {
+	tests, err := fullblocktests.Generate(false)
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	// Create a new database to store the blocks into.
+	dbPath := filepath.Join(os.TempDir(), "verifychainstate")
+	_ = os.RemoveAll(dbPath)
+	db, err := database.Create(testDbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("error creating db: %v", err)
+	}
+	defer os.RemoveAll(dbPath)
+	defer db.Close()
+
+	params := chaincfg.RegressionNetParams
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+		SigCache:    txscript.NewSigCache(1000),
+	})
+	if err != nil {
+		t.Fatalf("failed to create chain instance: %v", err)
+	}
+	for _, instance := range tests[0] {
+		item, ok := instance.(fullblocktests.AcceptedBlock)
+		if !ok {
+			continue
+		}
+		block := provautil.NewBlock(item.Block)
+		_, _, err := chain.ProcessBlock(block, blockchain.BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlock: unexpected error: %v", err)
+		}
+	}
+	checkChainStateConsistent(t, db)
+
+	// Remove the hash index entry of the best block and add an invalid
+	// utxo set entry, then ensure both are reported.
+	best := chain.BestSnapshot()
+	err = db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		err := meta.Bucket([]byte("hashidx")).Delete(best.Hash[:])
+		if err != nil {
+			return err
+		}
+		return meta.Bucket([]byte("utxoset")).Put(best.Hash[:],
+			[]byte{0x01})
+	})
+	if err != nil {
+		t.Fatalf("Update: unexpected error: %v", err)
+	}
+	report, err := blockchain.VerifyChainState(db)
+	if err != nil {
+		t.Fatalf("VerifyChainState: unexpected error: %v", err)
+	}
+	if len(report.Problems) != 2 {
+		t.Fatalf("VerifyChainState: unexpected problems: %v",
+			report.Problems)
+	}
+}
@@ -0,0 +1,521 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+const (
+	// snapshotVersion is the current version of the utxo set snapshot
+	// format.
+	snapshotVersion = 1
+
+	// maxSnapshotStateLen is the maximum allowed length of the serialized
+	// best chain state and admin state in a utxo set snapshot.
+	maxSnapshotStateLen = wire.MaxMessagePayload
+
+	// maxSnapshotEntryLen is the maximum allowed length of a serialized
+	// utxo entry in a utxo set snapshot.
+	maxSnapshotEntryLen = wire.MaxBlockPayload
+)
+
+// snapshotBlockKeyName is the name of the db key used to store the hash of the
+// block a chain was bootstrapped at from a utxo set snapshot.  Its presence
+// means the data for the blocks before the snapshot block is not available.
+var snapshotBlockKeyName = []byte("snapshotblock")
+
+// -----------------------------------------------------------------------------
+// A utxo set snapshot holds everything needed to bootstrap the chain state of a
+// new node as of a given block without replaying the blocks before it.
+//
+// The serialized format is:
+//
+//   Field              Type              Size
+//   network            wire.BitcoinNet   4 bytes
+//   version            uint32            4 bytes
+//   best chain state   []byte            variable (see serializeBestChainState)
+//   admin state        []byte            variable (see serializeKeySet)
+//   num headers        VLQ               variable
+//   headers            []BlockHeader     headers of blocks 1 through the best block
+//   num blocks         VLQ               variable
+//   blocks             []MsgBlock        the most recent blocks ending at the best block
+//   num utxo entries   VLQ               variable
+//   utxo entries       []entry           tx hash followed by the serialized utxo entry
+//   commitment         chainhash.Hash    chainhash.HashSize
+//
+// The commitment is the double sha256 of the best chain state, the admin state
+// and all utxo entries in the order they appear in the snapshot.  The headers
+// and blocks are not part of the commitment since they are validated against
+// the best block when the snapshot is loaded.
+// -----------------------------------------------------------------------------
+
+// SnapshotInfo describes a utxo set snapshot that was dumped or loaded.
+type SnapshotInfo struct {
+	Hash       chainhash.Hash // The commitment hash of the snapshot.
+	BlockHash  chainhash.Hash // The hash of the block the snapshot is for.
+	Height     uint32         // The height of the block the snapshot is for.
+	NumUtxos   uint64         // The number of utxo entries in the snapshot.
+	NumHeaders uint64         // The number of block headers in the snapshot.
+}
+
+// snapshotCommitment computes the commitment hash of a utxo set snapshot.
+type snapshotCommitment struct {
+	hasher hash.Hash
+}
+
+// newSnapshotCommitment returns a new snapshot commitment which includes the
+// passed serialized best chain state and admin state.
+func newSnapshotCommitment(serializedState, serializedKeySet []byte) *snapshotCommitment {
+	c := &snapshotCommitment{hasher: sha256.New()}
+	c.hasher.Write(serializedState)
+	c.hasher.Write(serializedKeySet)
+	return c
+}
+
+// addUtxoEntry adds the passed serialized utxo entry to the commitment.
+func (c *snapshotCommitment) addUtxoEntry(txHash, serializedEntry []byte) {
+	var lenBuf [4]byte
+	byteOrder.PutUint32(lenBuf[:], uint32(len(serializedEntry)))
+	c.hasher.Write(txHash)
+	c.hasher.Write(lenBuf[:])
+	c.hasher.Write(serializedEntry)
+}
+
+// hash returns the final commitment hash.
+func (c *snapshotCommitment) hash() chainhash.Hash {
+	return chainhash.HashH(c.hasher.Sum(nil))
+}
+
+// snapshotBlockCount returns the number of most recent blocks which must be
+// included in a snapshot for the chain to be able to validate the blocks which
+// follow it.  This covers the blocks looked at for the difficulty, median
+// time, validate key rate limit and block version calculations.
+func (b *BlockChain) snapshotBlockCount() uint32 {
+	count := uint32(b.chainParams.BlockUpgradeNumToCheck)
+	window := uint32(b.chainParams.PowAveragingWindow + medianTimeBlocks + 1)
+	if window > count {
+		count = window
+	}
+	return count
+}
+
+// DumpUtxoSnapshot writes a snapshot of the utxo set, the admin state and the
+// best chain state along with the header chain and the most recent blocks to
+// the passed writer.  The snapshot is taken from a consistent view of the
+// database, so it is safe to call while blocks are being processed.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DumpUtxoSnapshot(w io.Writer) (*SnapshotInfo, error) {
+	var info SnapshotInfo
+	err := b.db.View(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		serializedState := meta.Get(chainStateKeyName)
+		serializedKeySet := meta.Get(keySetBucketName)
+		if serializedState == nil || serializedKeySet == nil {
+			return AssertError("chain state is not initialized")
+		}
+		state, err := deserializeBestChainState(serializedState)
+		if err != nil {
+			return err
+		}
+		info.BlockHash = state.hash
+		info.Height = state.height
+
+		var prefix [8]byte
+		byteOrder.PutUint32(prefix[0:4], uint32(b.chainParams.Net))
+		byteOrder.PutUint32(prefix[4:8], snapshotVersion)
+		if _, err := w.Write(prefix[:]); err != nil {
+			return err
+		}
+		err = wire.WriteVarBytes(w, 0, serializedState)
+		if err != nil {
+			return err
+		}
+		err = wire.WriteVarBytes(w, 0, serializedKeySet)
+		if err != nil {
+			return err
+		}
+
+		// Write the headers of all blocks after the genesis block.
+		info.NumHeaders = uint64(state.height)
+		err = wire.WriteVarInt(w, 0, info.NumHeaders)
+		if err != nil {
+			return err
+		}
+		for height := uint32(1); height <= state.height; height++ {
+			header, err := dbFetchHeaderByHeight(dbTx, height)
+			if err != nil {
+				return err
+			}
+			if err := header.Serialize(w); err != nil {
+				return err
+			}
+		}
+
+		// Write the most recent blocks ending at the best block.
+		numBlocks := b.snapshotBlockCount()
+		if numBlocks > state.height {
+			numBlocks = state.height
+		}
+		err = wire.WriteVarInt(w, 0, uint64(numBlocks))
+		if err != nil {
+			return err
+		}
+		for height := state.height - numBlocks + 1; height <= state.height; height++ {
+			block, err := dbFetchBlockByHeight(dbTx, height)
+			if err != nil {
+				return err
+			}
+			if err := block.MsgBlock().Serialize(w); err != nil {
+				return err
+			}
+		}
+
+		// Write the utxo set.  The entries are iterated in key order, so
+		// the commitment is deterministic.
+		utxoBucket := meta.Bucket(utxoSetBucketName)
+		err = utxoBucket.ForEach(func(k, v []byte) error {
+			info.NumUtxos++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = wire.WriteVarInt(w, 0, info.NumUtxos)
+		if err != nil {
+			return err
+		}
+		commitment := newSnapshotCommitment(serializedState,
+			serializedKeySet)
+		err = utxoBucket.ForEach(func(k, v []byte) error {
+			commitment.addUtxoEntry(k, v)
+			if _, err := w.Write(k); err != nil {
+				return err
+			}
+			return wire.WriteVarBytes(w, 0, v)
+		})
+		if err != nil {
+			return err
+		}
+
+		info.Hash = commitment.hash()
+		_, err = w.Write(info.Hash[:])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// snapshotError returns a rule error for a malformed or inconsistent utxo set
+// snapshot.
+func snapshotError(format string, args ...interface{}) error {
+	return ruleError(ErrBadSnapshot, fmt.Sprintf(format, args...))
+}
+
+// LoadUtxoSnapshot bootstraps the chain state from a utxo set snapshot read
+// from the passed reader.  The chain must not have any blocks other than the
+// genesis block.  Since the blocks before the snapshot are not available to
+// build the indexes, the index manager, if any, is detached from the chain once
+// the snapshot is loaded.  Callers which need indexes that require the full
+// block history must not load snapshots.
+//
+// The header chain in the snapshot is checked to connect the genesis block to
+// the snapshot block, to have the required proof of work and to be signed by
+// the validate keys the headers name, and the total work and commitment of the
+// snapshot are verified.  Since the snapshot does not contain the history of
+// the admin state, it is not possible to check that those keys were part of
+// the validate key set.  This is covered by the final check which requires
+// the snapshot to match one of the known good snapshots in the chain
+// parameters.  The chain state is only modified when all checks pass.
+//
+// Note that the spend journal is not available for the blocks up to and
+// including the snapshot block, so they can't be disconnected afterwards.  The
+// chain is considered pruned from then on since the data for the blocks before
+// the most recent ones in the snapshot is not available.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) LoadUtxoSnapshot(r io.Reader) (*SnapshotInfo, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	if b.bestNode.height != 0 {
+		return nil, fmt.Errorf("utxo set snapshots can only be loaded " +
+			"into a chain without blocks")
+	}
+
+	var prefix [8]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, err
+	}
+	net := byteOrder.Uint32(prefix[0:4])
+	version := byteOrder.Uint32(prefix[4:8])
+	if wire.BitcoinNet(net) != b.chainParams.Net {
+		return nil, snapshotError("snapshot is for network %v instead "+
+			"of %v", wire.BitcoinNet(net), b.chainParams.Net)
+	}
+	if version != snapshotVersion {
+		return nil, snapshotError("unsupported snapshot version %d",
+			version)
+	}
+
+	serializedState, err := wire.ReadVarBytes(r, 0, maxSnapshotStateLen,
+		"best chain state")
+	if err != nil {
+		return nil, err
+	}
+	state, err := deserializeBestChainState(serializedState)
+	if err != nil {
+		return nil, snapshotError("invalid best chain state: %v", err)
+	}
+	serializedKeySet, err := wire.ReadVarBytes(r, 0, maxSnapshotStateLen,
+		"admin state")
+	if err != nil {
+		return nil, err
+	}
+	_, _, _, _, _, err = deserializeKeySet(serializedKeySet)
+	if err != nil {
+		return nil, snapshotError("invalid admin state: %v", err)
+	}
+
+	var info SnapshotInfo
+	info.BlockHash = state.hash
+	info.Height = state.height
+	err = b.db.Update(func(dbTx database.Tx) error {
+		// Read the header chain and ensure it connects the genesis block
+		// to the snapshot block.  The block index is populated along the
+		// way and the total work is recalculated.
+		numHeaders, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return err
+		}
+		if numHeaders != uint64(state.height) {
+			return snapshotError("snapshot has %d headers for "+
+				"best height %d", numHeaders, state.height)
+		}
+		info.NumHeaders = numHeaders
+		genesisHash, err := dbFetchHashByHeight(dbTx, 0)
+		if err != nil {
+			return err
+		}
+		prevHash := *genesisHash
+		workSum := CalcWork(b.chainParams.GenesisBlock.Header.Bits)
+		for height := uint32(1); height <= state.height; height++ {
+			var header wire.BlockHeader
+			if err := header.Deserialize(r); err != nil {
+				return err
+			}
+			blockHash := header.BlockHash()
+			if header.PrevBlock != prevHash || header.Height != height {
+				return snapshotError("header %v at height %d "+
+					"does not connect to the header chain",
+					blockHash, height)
+			}
+			err := CheckBlockHeaderSanity(&header,
+				b.chainParams.PowLimit, b.timeSource)
+			if err != nil {
+				return err
+			}
+			pubKey, err := btcec.ParsePubKey(
+				header.ValidatingPubKey[:], btcec.S256())
+			if err != nil || !header.Verify(pubKey) {
+				str := fmt.Sprintf("header %v at height %d does "+
+					"not have a valid signature", blockHash,
+					height)
+				return ruleError(ErrBadBlockSignature, str)
+			}
+			err = dbPutBlockIndex(dbTx, &blockHash, height)
+			if err != nil {
+				return err
+			}
+			workSum.Add(workSum, CalcWork(header.Bits))
+			prevHash = blockHash
+		}
+		if prevHash != state.hash {
+			return snapshotError("header chain ends at %v instead "+
+				"of best block %v", prevHash, state.hash)
+		}
+		if workSum.Cmp(state.workSum) != 0 {
+			return snapshotError("best chain state has work sum %v "+
+				"instead of %v", state.workSum, workSum)
+		}
+
+		// Read and store the most recent blocks.  They must be the
+		// blocks of the last headers in the header chain.
+		numBlocks, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return err
+		}
+		wantBlocks := b.snapshotBlockCount()
+		if wantBlocks > state.height {
+			wantBlocks = state.height
+		}
+		if numBlocks != uint64(wantBlocks) {
+			return snapshotError("snapshot has %d blocks instead "+
+				"of %d", numBlocks, wantBlocks)
+		}
+		for height := state.height - wantBlocks + 1; height <= state.height; height++ {
+			var msgBlock wire.MsgBlock
+			if err := msgBlock.Deserialize(r); err != nil {
+				return err
+			}
+			block := provautil.NewBlock(&msgBlock)
+			wantHash, err := dbFetchHashByHeight(dbTx, height)
+			if err != nil {
+				return err
+			}
+			if !block.Hash().IsEqual(wantHash) {
+				return snapshotError("block %v at height %d "+
+					"does not match header %v",
+					block.Hash(), height, wantHash)
+			}
+			err = CheckBlockSanity(block, b.chainParams.PowLimit,
+				b.timeSource)
+			if err != nil {
+				return err
+			}
+			if err := dbTx.StoreBlock(block); err != nil {
+				return err
+			}
+		}
+
+		// Replace the utxo set with the one from the snapshot.
+		meta := dbTx.Metadata()
+		if err := meta.DeleteBucket(utxoSetBucketName); err != nil {
+			return err
+		}
+		utxoBucket, err := meta.CreateBucket(utxoSetBucketName)
+		if err != nil {
+			return err
+		}
+		info.NumUtxos, err = wire.ReadVarInt(r, 0)
+		if err != nil {
+			return err
+		}
+		commitment := newSnapshotCommitment(serializedState,
+			serializedKeySet)
+		for i := uint64(0); i < info.NumUtxos; i++ {
+			var txHash chainhash.Hash
+			if _, err := io.ReadFull(r, txHash[:]); err != nil {
+				return err
+			}
+			serializedEntry, err := wire.ReadVarBytes(r, 0,
+				maxSnapshotEntryLen, "utxo entry")
+			if err != nil {
+				return err
+			}
+			_, err = deserializeUtxoEntry(serializedEntry)
+			if err != nil {
+				return snapshotError("invalid utxo entry for "+
+					"%v: %v", txHash, err)
+			}
+			commitment.addUtxoEntry(txHash[:], serializedEntry)
+			err = utxoBucket.Put(txHash[:], serializedEntry)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Ensure the snapshot matches its commitment and that the
+		// commitment is a known good snapshot for the network.
+		var wantCommitment chainhash.Hash
+		if _, err := io.ReadFull(r, wantCommitment[:]); err != nil {
+			return err
+		}
+		info.Hash = commitment.hash()
+		if info.Hash != wantCommitment {
+			return snapshotError("snapshot commitment %v does not "+
+				"match contents %v", wantCommitment, info.Hash)
+		}
+		if !b.isKnownSnapshot(&info) {
+			str := fmt.Sprintf("snapshot %v at height %d (block %v) "+
+				"is not a known snapshot", info.Hash, info.Height,
+				info.BlockHash)
+			return ruleError(ErrUnknownSnapshot, str)
+		}
+
+		// Finally, store the admin state and best chain state along with
+		// the block the chain was bootstrapped at.
+		if err := meta.Put(keySetBucketName, serializedKeySet); err != nil {
+			return err
+		}
+		err = meta.Put(snapshotBlockKeyName, state.hash[:])
+		if err != nil {
+			return err
+		}
+		return meta.Put(chainStateKeyName, serializedState)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload the in-memory chain state from the database now that it
+	// reflects the snapshot.
+	b.stateLock.Lock()
+	b.bestNode = nil
+	b.index = make(map[chainhash.Hash]*blockNode)
+	b.depNodes = make(map[chainhash.Hash][]*blockNode)
+	err = b.initChainState()
+	b.stateLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if b.indexManager != nil {
+		log.Infof("Optional indexes are no longer maintained since the " +
+			"chain was bootstrapped from a utxo set snapshot")
+		b.indexManager = nil
+	}
+
+	log.Infof("Loaded utxo set snapshot %v (height %d, hash %v, utxos %d)",
+		info.Hash, info.Height, info.BlockHash, info.NumUtxos)
+
+	return &info, nil
+}
+
+// isKnownSnapshot returns whether or not the passed snapshot matches one of
+// the known good snapshots in the chain parameters.
+func (b *BlockChain) isKnownSnapshot(info *SnapshotInfo) bool {
+	for _, snapshot := range b.chainParams.UtxoSnapshots {
+		if snapshot.Height == info.Height &&
+			snapshot.BlockHash.IsEqual(&info.BlockHash) &&
+			snapshot.Hash.IsEqual(&info.Hash) {
+
+			return true
+		}
+	}
+	return false
+}
+
+// dbHasSnapshotBlock returns whether or not the chain stored in the database
+// was bootstrapped from a utxo set snapshot.
+func dbHasSnapshotBlock(dbTx database.Tx) bool {
+	return dbTx.Metadata().Get(snapshotBlockKeyName) != nil
+}
+
+// IsSnapshotBootstrapped returns whether or not the chain stored in the passed
+// database was bootstrapped from a utxo set snapshot, in which case the data
+// for the blocks before the snapshot block is not available.
+//
+// This function is safe for concurrent access.
+func IsSnapshotBootstrapped(db database.DB) (bool, error) {
+	var bootstrapped bool
+	err := db.View(func(dbTx database.Tx) error {
+		bootstrapped = dbHasSnapshotBlock(dbTx)
+		return nil
+	})
+	return bootstrapped, err
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/blockchain/fullblocktests"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+// TestUtxoSnapshot ensures a utxo set snapshot dumped from a chain can be
+// loaded into a new chain only when it matches a known snapshot and that the
+// loaded chain state is the same as the original one.
+func TestUtxoSnapshot(t *testing.T) {
+	tests, err := fullblocktests.Generate(false)
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	// Create a chain with the blocks leading up to the first block which
+	// is not accepted to the main chain.
+	chain, teardownFunc, err := chainSetup("snapshotsrc",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+out:
+	for _, testInstances := range tests {
+		for _, instance := range testInstances {
+			item, ok := instance.(fullblocktests.AcceptedBlock)
+			if !ok || !item.IsMainChain {
+				break out
+			}
+			block := provautil.NewBlock(item.Block)
+			_, _, err := chain.ProcessBlock(block, blockchain.BFNone)
+			if err != nil {
+				teardownFunc()
+				t.Fatalf("ProcessBlock %q: %v", item.Name, err)
+			}
+		}
+	}
+	best := chain.BestSnapshot()
+	totalSupply := chain.TotalSupply()
+
+	// Only one test database can be open at a time, so tear down the
+	// source chain once the snapshot is dumped.
+	var buf bytes.Buffer
+	info, err := chain.DumpUtxoSnapshot(&buf)
+	teardownFunc()
+	if err != nil {
+		t.Fatalf("DumpUtxoSnapshot: %v", err)
+	}
+	if info.BlockHash != *best.Hash || info.Height != best.Height {
+		t.Fatalf("DumpUtxoSnapshot: unexpected block -- got %v (%d), "+
+			"want %v (%d)", info.BlockHash, info.Height, best.Hash,
+			best.Height)
+	}
+	snapshot := buf.Bytes()
+
+	// The snapshot must be rejected when it does not match a known one.
+	unknownChain, teardownFunc, err := chainSetup("snapshotunknown",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	_, err = unknownChain.LoadUtxoSnapshot(bytes.NewReader(snapshot))
+	unknownHeight := unknownChain.BestSnapshot().Height
+	teardownFunc()
+	rerr, ok := err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrUnknownSnapshot {
+		t.Fatalf("LoadUtxoSnapshot: unexpected error -- got %v, want %v",
+			err, blockchain.ErrUnknownSnapshot)
+	}
+	if unknownHeight != 0 {
+		t.Fatalf("LoadUtxoSnapshot: chain state modified by rejected " +
+			"snapshot")
+	}
+
+	// Add the snapshot to the known snapshots and ensure it loads.
+	params := chaincfg.RegressionNetParams
+	params.UtxoSnapshots = []chaincfg.UtxoSnapshot{{
+		Height:    info.Height,
+		BlockHash: &info.BlockHash,
+		Hash:      &info.Hash,
+	}}
+	loadChain, teardownFunc, err := chainSetup("snapshotload", &params)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+
+	// A snapshot with contents which do not match the commitment must be
+	// rejected.
+	corrupt := make([]byte, len(snapshot))
+	copy(corrupt, snapshot)
+	corrupt[len(corrupt)-1] ^= 0xff
+	_, err = loadChain.LoadUtxoSnapshot(bytes.NewReader(corrupt))
+	rerr, ok = err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrBadSnapshot {
+		t.Fatalf("LoadUtxoSnapshot: unexpected error -- got %v, want %v",
+			err, blockchain.ErrBadSnapshot)
+	}
+
+	// A snapshot with a header which is not signed by the validate key it
+	// names must be rejected.
+	r := bytes.NewReader(snapshot[8:])
+	for _, field := range []string{"best chain state", "admin state"} {
+		_, err := wire.ReadVarBytes(r, 0, uint32(len(snapshot)), field)
+		if err != nil {
+			t.Fatalf("ReadVarBytes: %v", err)
+		}
+	}
+	if _, err := wire.ReadVarInt(r, 0); err != nil {
+		t.Fatalf("ReadVarInt: %v", err)
+	}
+	headerOffset := len(snapshot) - r.Len()
+	var header wire.BlockHeader
+	if err := header.Deserialize(r); err != nil {
+		t.Fatalf("Deserialize: %v", err)
+	}
+	// The signature is part of the block hash, so solve the header again
+	// to make sure the signature is the only thing wrong with it.
+	header.Signature[len(header.Signature)/2] ^= 0xff
+	target := blockchain.CompactToBig(header.Bits)
+	for {
+		hash := header.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+		header.Nonce++
+	}
+	var headerBuf bytes.Buffer
+	if err := header.Serialize(&headerBuf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	badSig := make([]byte, len(snapshot))
+	copy(badSig, snapshot)
+	copy(badSig[headerOffset:], headerBuf.Bytes())
+	_, err = loadChain.LoadUtxoSnapshot(bytes.NewReader(badSig))
+	rerr, ok = err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrBadBlockSignature {
+		t.Fatalf("LoadUtxoSnapshot: unexpected error -- got %v, want %v",
+			err, blockchain.ErrBadBlockSignature)
+	}
+
+	pruned, err := loadChain.IsPruned()
+	if err != nil {
+		t.Fatalf("IsPruned: %v", err)
+	}
+	if pruned {
+		t.Fatal("IsPruned: chain without snapshot reported as pruned")
+	}
+	_, err = loadChain.LoadUtxoSnapshot(bytes.NewReader(snapshot))
+	if err != nil {
+		t.Fatalf("LoadUtxoSnapshot: %v", err)
+	}
+
+	// A chain bootstrapped from a snapshot does not have the data for the
+	// blocks before it, so it must be treated as pruned.
+	pruned, err = loadChain.IsPruned()
+	if err != nil {
+		t.Fatalf("IsPruned: %v", err)
+	}
+	if !pruned {
+		t.Fatal("IsPruned: snapshot bootstrapped chain not reported " +
+			"as pruned")
+	}
+	loadedBest := loadChain.BestSnapshot()
+	if *loadedBest.Hash != *best.Hash || loadedBest.Height != best.Height ||
+		loadedBest.TotalTxns != best.TotalTxns {
+		t.Fatalf("LoadUtxoSnapshot: unexpected best state -- got %v "+
+			"(%d), want %v (%d)", loadedBest.Hash, loadedBest.Height,
+			best.Hash, best.Height)
+	}
+	if loadChain.TotalSupply() != totalSupply {
+		t.Fatalf("LoadUtxoSnapshot: unexpected total supply -- got %d, "+
+			"want %d", loadChain.TotalSupply(), totalSupply)
+	}
+
+	// Dumping the loaded chain must result in the same commitment.
+	var loadedBuf bytes.Buffer
+	loadedInfo, err := loadChain.DumpUtxoSnapshot(&loadedBuf)
+	if err != nil {
+		t.Fatalf("DumpUtxoSnapshot: %v", err)
+	}
+	if loadedInfo.Hash != info.Hash {
+		t.Fatalf("DumpUtxoSnapshot: unexpected commitment -- got %v, "+
+			"want %v", loadedInfo.Hash, info.Hash)
+	}
+
+	// A snapshot can't be loaded into a chain which already has blocks.
+	_, err = loadChain.LoadUtxoSnapshot(bytes.NewReader(snapshot))
+	if err == nil {
+		t.Fatalf("LoadUtxoSnapshot: loaded snapshot into chain with " +
+			"blocks")
+	}
+}
@@ -607,6 +607,27 @@ func (b *BlockChain) FetchUtxoView(tx *provautil.Tx) (*UtxoViewpoint, error) {
 	return view, err
 }
 
+// FetchTipUtxoView loads utxo details about the passed transactions from the
+// point of view of the end of the main chain and returns them along with the
+// state of the best block they were loaded at.
+//
+// This function is safe for concurrent access however the returned view is NOT.
+func (b *BlockChain) FetchTipUtxoView(txHashes []*chainhash.Hash) (*UtxoViewpoint, *BestState, error) {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	txNeededSet := make(map[chainhash.Hash]struct{}, len(txHashes))
+	for _, txHash := range txHashes {
+		txNeededSet[*txHash] = struct{}{}
+	}
+
+	view := NewUtxoViewpoint()
+	if err := view.fetchUtxosMain(b.db, txNeededSet); err != nil {
+		return nil, nil, err
+	}
+	return view, b.BestSnapshot(), nil
+}
+
 // FetchUtxoEntry loads and returns the unspent transaction output entry for the
 // passed hash from the point of view of the end of the main chain.
 //
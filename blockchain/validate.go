@@ -14,6 +14,7 @@ import (
 	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
 	"github.com/bitgo/prova/provautil"
 	"github.com/bitgo/prova/txscript"
 	"github.com/bitgo/prova/wire"
@@ -358,7 +359,7 @@ func CheckTransactionSanity(tx *provautil.Tx) error {
 				if !txscript.IsValidAdminOp(adminOpOut, threadId) {
 					str := fmt.Sprintf("admin transaction with invalid admin " +
 						"operation found.")
-					return ruleError(ErrInvalidAdminTx, str)
+					return ruleError(ErrInvalidAdminOp, str)
 				}
 			}
 		}
@@ -547,6 +548,13 @@ func checkBlockHeaderSanity(header *wire.BlockHeader, powLimit *big.Int, timeSou
 	return nil
 }
 
+// CheckBlockHeaderSanity performs some preliminary checks on a block header to
+// ensure it is sane before continuing with processing.  These checks are
+// context free.
+func CheckBlockHeaderSanity(header *wire.BlockHeader, powLimit *big.Int, timeSource MedianTimeSource) error {
+	return checkBlockHeaderSanity(header, powLimit, timeSource, BFNone)
+}
+
 // checkBlockSanity performs some preliminary checks on a block to ensure it is
 // sane before continuing with block processing.  These checks are context free.
 //
@@ -673,6 +681,8 @@ func CheckBlockSanity(block *provautil.Block, powLimit *big.Int, timeSource Medi
 // The flags modify the behavior of this function as follows:
 //  - BFFastAdd: All checks except those involving comparing the header against
 //    the checkpoints are not performed.
+//  - BFNoValidateKeyCheck: The block signature is not verified and the validate
+//    key used to sign the block is not checked.
 //
 // This function MUST be called with the chain state lock held (for writes).
 func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode *blockNode, flags BehaviorFlags) error {
@@ -709,16 +719,6 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 			str = fmt.Sprintf(str, header.Timestamp, medianTime)
 			return ruleError(ErrTimeTooOld, str)
 		}
-
-		// Verify the block's signature by an active validate key.
-		// TODO(prova): confirm that the validating pubkey is valid
-		pubKey, err := btcec.ParsePubKey(header.ValidatingPubKey[:], btcec.S256())
-		if err != nil {
-			return err
-		}
-		if !header.Verify(pubKey) {
-			return ruleError(ErrBadBlockSignature, "unable to validate block signature")
-		}
 	}
 
 	// The height of this block is one more than the referenced previous
@@ -753,8 +753,38 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 		return ruleError(ErrForkTooOld, str)
 	}
 
+	// Verify the block's signature by an active validate key.  This is
+	// done after the checkpoint checks since the historical admin state
+	// of the chain the header builds on has to be loaded for it.
+	if !fastAdd && flags&BFNoValidateKeyCheck != BFNoValidateKeyCheck {
+		pubKey, err := btcec.ParsePubKey(header.ValidatingPubKey[:], btcec.S256())
+		if err != nil {
+			return err
+		}
+		if !header.Verify(pubKey) {
+			return ruleError(ErrBadBlockSignature, "unable to validate block signature")
+		}
+
+		// Ensure the validate key was authorized as of the parent block
+		// and is not rate limited at this position in the chain.
+		err = b.checkHeaderValidateKey(header, pubKey, prevNode)
+		if err != nil {
+			return err
+		}
+	}
+
 	// TODO(prova): clean up / remove
 	if !fastAdd {
+		// Reject version 4 blocks once a majority of the network has
+		// upgraded.  Version 5 blocks enforce compressed public keys.
+		if header.Version < 5 && b.isMajorityVersion(5, prevNode,
+			b.chainParams.BlockRejectNumRequired) {
+
+			str := "new blocks with version %d are no longer valid"
+			str = fmt.Sprintf(str, header.Version)
+			return ruleError(ErrBlockVersionTooOld, str)
+		}
+
 		// Reject version 3 blocks once a majority of the network has
 		// upgraded.  This is part of BIP0065.
 		if header.Version < 4 && b.isMajorityVersion(4, prevNode,
@@ -789,6 +819,72 @@ func (b *BlockChain) checkBlockHeaderContext(header *wire.BlockHeader, prevNode
 	return nil
 }
 
+// checkHeaderValidateKey ensures the validate key which signed the passed
+// header is part of the validate key set in the historical admin state as of
+// the parent block, and that signing the block does not breach the trailing or
+// share rate limits.  Both checks only depend on the header chain and the admin
+// state of the ancestors, so they can be performed before the block body is
+// known.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) checkHeaderValidateKey(header *wire.BlockHeader, pubKey *btcec.PublicKey, prevNode *blockNode) error {
+	keyView, err := b.fetchKeyView(prevNode)
+	if err != nil {
+		return err
+	}
+	validateKeySet := keyView.Keys()[btcec.ValidateKeySet]
+	if len(validateKeySet) > 0 && validateKeySet.Pos(pubKey) == -1 {
+		str := fmt.Sprintf("block signed by validate key %x which is "+
+			"not authorized as of block %v",
+			pubKey.SerializeCompressed(), prevNode.hash)
+		return ruleError(ErrInvalidValidateKey, str)
+	}
+
+	// Create a node for the header which is not added to the memory
+	// chain, so the rate limits can be evaluated from its position.
+	blockHash := header.BlockHash()
+	node := newBlockNode(header, &blockHash)
+	node.parent = prevNode
+	isRateLimited, err := b.isValidateKeyRateLimited(node,
+		header.ValidatingPubKey, false)
+	if err != nil {
+		return err
+	}
+	if isRateLimited {
+		str := fmt.Sprintf("Validate key rate limited %v",
+			header.ValidatingPubKey)
+		return ruleError(ErrValidateKeyRateLimited, str)
+	}
+	return nil
+}
+
+// CheckBlockHeader performs all of the context dependent checks on the passed
+// block header which do not require the block body.  This includes verifying
+// the block signature, and that the validate key which signed it was authorized
+// in the historical admin state as of the parent block and is not rate limited.
+// The parent of the header must already be known to the block chain.
+//
+// This allows headers signed by unauthorized or revoked validate keys to be
+// rejected before their blocks are downloaded.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CheckBlockHeader(header *wire.BlockHeader) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	prevNode, err := b.getPrevNodeFromHeader(header)
+	if err != nil {
+		return err
+	}
+	if prevNode == nil {
+		str := fmt.Sprintf("previous block %v of header is unknown",
+			header.PrevBlock)
+		return ruleError(ErrPreviousBlockUnknown, str)
+	}
+
+	return b.checkBlockHeaderContext(header, prevNode, BFNone)
+}
+
 // checkBlockContext peforms several validation checks on the block which depend
 // on its position within the block chain.
 //
@@ -914,20 +1010,20 @@ func CheckTransactionInputs(tx *provautil.Tx, txHeight uint32, utxoView *UtxoVie
 					"thread transaction %v with input at position "+
 					"%d. Only input #0 may spend an admin threads.",
 					tx.Hash(), originTxHash, txInIndex)
-				return 0, ruleError(ErrInvalidAdminTx, str)
+				return 0, ruleError(ErrThreadOutOfOrder, str)
 			}
 			if !hasAdminOut {
 				str := fmt.Sprintf("transaction %v spends admin output, "+
 					"yet does not continue admin thread. Should have admin "+
 					"output at position 0.", tx.Hash())
-				return 0, ruleError(ErrInvalidAdminTx, str)
+				return 0, ruleError(ErrThreadOutOfOrder, str)
 			}
 			hasAdminIn = true
 			if thisPkScript[0] != originPkScript[0] ||
 				thisPkScript[1] != originPkScript[1] {
 				str := fmt.Sprintf("admin transaction input %v is "+
 					"spending wrong thread.", tx.Hash())
-				return 0, ruleError(ErrInvalidAdminTx, str)
+				return 0, ruleError(ErrThreadOutOfOrder, str)
 			}
 		}
 
@@ -937,7 +1033,7 @@ func CheckTransactionInputs(tx *provautil.Tx, txHeight uint32, utxoView *UtxoVie
 			str := fmt.Sprintf("tried to issue admin operation "+
 				"at transaction %s:%d without spending valid thread.",
 				tx.Hash(), txInIndex)
-			return 0, ruleError(ErrInvalidAdminTx, str)
+			return 0, ruleError(ErrThreadOutOfOrder, str)
 		}
 
 		// Ensure the transaction is not spending coins which have not
@@ -1049,18 +1145,26 @@ func CheckTransactionInputs(tx *provautil.Tx, txHeight uint32, utxoView *UtxoVie
 }
 
 // CheckProvaOutput checks that all keyIDs in the pkScript are known in
-// the chain state.
+// the chain state.  KeyIDs are provisioned in strictly increasing order, so a
+// keyID which is not active but not greater than the last provisioned keyID
+// has been revoked.
 //
 // NOTE: The passed output MUST have already been sanity checked with the
 // CheckTransactionSanity function prior to calling this function.
 func CheckProvaOutput(tx *provautil.Tx, txOutIndex int, keyIDs []btcec.KeyID,
 	keyView *KeyViewpoint) error {
 	for _, keyID := range keyIDs {
-		if keyView.aspKeyIdMap[keyID] == nil {
-			str := fmt.Sprintf("transaction %v output %v has unknown "+
+		if keyView.aspKeyIdMap[keyID] != nil {
+			continue
+		}
+		if keyID <= keyView.LastKeyID() {
+			str := fmt.Sprintf("transaction %v output %v has revoked "+
 				"keyID %v.", tx.Hash(), txOutIndex, keyID)
-			return ruleError(ErrInvalidTx, str)
+			return ruleError(ErrKeyIDRevoked, str)
 		}
+		str := fmt.Sprintf("transaction %v output %v has unknown "+
+			"keyID %v.", tx.Hash(), txOutIndex, keyID)
+		return ruleError(ErrUnknownKeyID, str)
 	}
 	return nil
 }
@@ -1243,6 +1347,45 @@ func (b *BlockChain) isValidateKeyRateLimited(node *blockNode, validatePubKey wi
 	return false, nil
 }
 
+// assumeValidSkipsScripts returns whether the script checks of the passed block
+// node may be skipped because the caller marked it as an ancestor of the
+// configured assume-valid block with the BFAssumeValid flag.  The flag is only
+// honored for blocks which directly extend the main chain, so the blocks of a
+// side chain are always fully validated when a reorganization connects them,
+// and it is ignored for blocks after the assume-valid block once that block is
+// part of the main chain.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) assumeValidSkipsScripts(node *blockNode, flags BehaviorFlags) (bool, error) {
+	if b.assumeValid == nil || flags&BFAssumeValid != BFAssumeValid {
+		return false, nil
+	}
+	if !node.parentHash.IsEqual(b.bestNode.hash) {
+		return false, nil
+	}
+
+	// Look up the assume-valid block in the memory index first and fall
+	// back to the database since old nodes are not kept in memory.
+	if avNode, ok := b.index[*b.assumeValid]; ok {
+		return !avNode.inMainChain || node.height <= avNode.height, nil
+	}
+	var inMainChain bool
+	var avHeight uint32
+	err := b.db.View(func(dbTx database.Tx) error {
+		inMainChain = dbMainChainHasBlock(dbTx, b.assumeValid)
+		if !inMainChain {
+			return nil
+		}
+		var err error
+		avHeight, err = dbFetchHeightByHash(dbTx, b.assumeValid)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return !inMainChain || node.height <= avHeight, nil
+}
+
 // checkConnectBlock performs several checks to confirm connecting the passed
 // block to the chain represented by the passed view does not violate any rules.
 // In addition, the passed view is updated to spend all of the referenced
@@ -1260,7 +1403,7 @@ func (b *BlockChain) isValidateKeyRateLimited(node *blockNode, validatePubKey wi
 // checks performed by this function.
 //
 // This function MUST be called with the chain state lock held (for writes).
-func (b *BlockChain) checkConnectBlock(node *blockNode, block *provautil.Block, utxoView *UtxoViewpoint, keyView *KeyViewpoint, stxos *[]spentTxOut) error {
+func (b *BlockChain) checkConnectBlock(node *blockNode, block *provautil.Block, utxoView *UtxoViewpoint, keyView *KeyViewpoint, stxos *[]spentTxOut, flags BehaviorFlags) error {
 	// If the side chain blocks end up in the database, a call to
 	// CheckBlockSanity should be done here in case a previous version
 	// allowed a block that is no longer valid.  However, since the
@@ -1415,6 +1558,21 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *provautil.Block,
 		runScripts = false
 	}
 
+	// Likewise, don't run scripts when the caller has established that the
+	// block is an ancestor of the configured assume-valid block within a
+	// header chain that has already passed validation.  Every other
+	// consensus check is still performed.
+	skipScripts, err := b.assumeValidSkipsScripts(node, flags)
+	if err != nil {
+		return err
+	}
+	if runScripts && skipScripts {
+		runScripts = false
+		if node.height > b.assumeValidHeight {
+			b.assumeValidHeight = node.height
+		}
+	}
+
 	// Get the previous block node.  This function is used over simply
 	// accessing node.parent directly as it will dynamically create previous
 	// block nodes as needed.  This helps allow only the pieces of the chain
@@ -1444,14 +1602,17 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *provautil.Block,
 
 	// Check that the validate key used to sign the block is represented in
 	// the current admin keyset state.
-	validateKeySet := keyView.Keys()[btcec.ValidateKeySet]
-	pubKey, err := btcec.ParsePubKey(blockHeader.ValidatingPubKey[:], btcec.S256())
-	if err != nil {
-		return err
-	}
-	if len(validateKeySet) > 0 && validateKeySet.Pos(pubKey) == -1 {
-		str := fmt.Sprintf("invalid validate key %v", pubKey.SerializeCompressed())
-		return ruleError(ErrInvalidValidateKey, str)
+	checkValidateKey := flags&BFNoValidateKeyCheck != BFNoValidateKeyCheck
+	if checkValidateKey {
+		validateKeySet := keyView.Keys()[btcec.ValidateKeySet]
+		pubKey, err := btcec.ParsePubKey(blockHeader.ValidatingPubKey[:], btcec.S256())
+		if err != nil {
+			return err
+		}
+		if len(validateKeySet) > 0 && validateKeySet.Pos(pubKey) == -1 {
+			str := fmt.Sprintf("invalid validate key %v", pubKey.SerializeCompressed())
+			return ruleError(ErrInvalidValidateKey, str)
+		}
 	}
 
 	// Enforce CHECKLOCKTIMEVERIFY for block versions 4+ once the majority
@@ -1463,14 +1624,24 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *provautil.Block,
 		scriptFlags |= txscript.ScriptVerifyCheckLockTimeVerify
 	}
 
-	// Check to see if there is a validate key rate limit breach.
-	isRateLimited, err := b.isValidateKeyRateLimited(node, blockHeader.ValidatingPubKey, false)
-	if err != nil {
-		return err
+	// Enforce compressed public keys for block versions 5+ once the
+	// majority of the network has upgraded to the enforcement threshold.
+	if blockHeader.Version >= 5 && b.isMajorityVersion(5, prevNode,
+		b.chainParams.BlockEnforceNumRequired) {
+
+		scriptFlags |= txscript.ScriptVerifyCompressedPubKeys
 	}
-	if isRateLimited {
-		str := fmt.Sprintf("Validate key rate limited %v", blockHeader.ValidatingPubKey)
-		return ruleError(ErrExcessiveTrailing, str)
+
+	// Check to see if there is a validate key rate limit breach.
+	if checkValidateKey {
+		isRateLimited, err := b.isValidateKeyRateLimited(node, blockHeader.ValidatingPubKey, false)
+		if err != nil {
+			return err
+		}
+		if isRateLimited {
+			str := fmt.Sprintf("Validate key rate limited %v", blockHeader.ValidatingPubKey)
+			return ruleError(ErrValidateKeyRateLimited, str)
+		}
 	}
 
 	// Now that the inexpensive checks are done and have passed, verify the
@@ -1520,11 +1691,68 @@ func (b *BlockChain) CheckConnectBlock(block *provautil.Block) error {
 	// state of the chain. The block can only be connected if:
 	// - it is mined by an active validate key.
 	// - all keyIDs used for outputs are provisioned.
-	keyView := NewKeyViewpoint()
-	keyView.SetThreadTips(b.threadTips)
-	keyView.SetLastKeyID(b.lastKeyID)
-	keyView.SetTotalSupply(b.totalSupply)
-	keyView.SetKeys(b.adminKeySets)
-	keyView.SetKeyIDs(b.aspKeyIdMap)
-	return b.checkConnectBlock(newNode, block, utxoView, keyView, nil)
+	keyView := b.bestKeyView()
+	return b.checkConnectBlock(newNode, block, utxoView, keyView, nil,
+		unsignedBlockFlags(&block.MsgBlock().Header))
+}
+
+// unsignedBlockFlags returns BFNoValidateKeyCheck when the passed header has not
+// been signed by a validate key yet so the signature and validate key checks
+// are skipped for block templates which are signed externally.
+func unsignedBlockFlags(header *wire.BlockHeader) BehaviorFlags {
+	if header.ValidatingPubKey == (wire.BlockValidatingPubKey{}) {
+		return BFNoValidateKeyCheck
+	}
+	return BFNone
+}
+
+// CheckConnectBlockTemplate fully validates that connecting the passed block to
+// the main chain does not violate any consensus rules, aside from the proof of
+// work requirement.  The block must connect to the current tip of the main
+// chain.
+//
+// Unlike CheckConnectBlock, the context free sanity checks and the checks which
+// depend on the position of the block within the chain are also performed, so
+// this is suitable for checking block proposals assembled by external block
+// builders.  When the block has not been signed by a validate key yet, the
+// block signature and validate key checks are skipped.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) CheckConnectBlockTemplate(block *provautil.Block) error {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	// Skip the proof of work check as this is just a block template.
+	header := &block.MsgBlock().Header
+	flags := BFNoPoWCheck | unsignedBlockFlags(header)
+
+	// This only checks whether the block can be connected to the tip of the
+	// current chain.
+	prevNode := b.bestNode
+	if !header.PrevBlock.IsEqual(prevNode.hash) {
+		str := fmt.Sprintf("previous block must be the current chain tip "+
+			"%v, instead got %v", prevNode.hash, header.PrevBlock)
+		return ruleError(ErrPrevBlockNotBest, str)
+	}
+
+	err := checkBlockSanity(block, b.chainParams.PowLimit, b.timeSource, flags)
+	if err != nil {
+		return err
+	}
+
+	err = b.checkBlockContext(block, prevNode, flags)
+	if err != nil {
+		return err
+	}
+
+	newNode := newBlockNode(header, block.Hash())
+	newNode.parent = prevNode
+	newNode.workSum.Add(prevNode.workSum, newNode.workSum)
+
+	// Leave the spent txouts entry nil in the state since the information
+	// is not needed and thus extra work can be avoided.
+	utxoView := NewUtxoViewpoint()
+	utxoView.SetBestHash(prevNode.hash)
+	keyView := b.bestKeyView()
+	return b.checkConnectBlock(newNode, block, utxoView, keyView, nil, flags)
 }
@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/blockchain/fullblocktests"
 	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
@@ -97,6 +98,166 @@ func TestCheckConnectBlock(t *testing.T) {
 	}
 }
 
+// TestCheckConnectBlockTemplate tests the CheckConnectBlockTemplate function to
+// ensure blocks which do not build on the current chain tip are rejected.
+func TestCheckConnectBlockTemplate(t *testing.T) {
+	// Create a new database and chain instance to run tests against.
+	chain, teardownFunc, err := chainSetup("checkconnectblocktemplate",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Errorf("Failed to setup chain instance: %v", err)
+		return
+	}
+	defer teardownFunc()
+
+	// The genesis block does not build on the current chain tip since it
+	// is the current chain tip.
+	genesisBlock := chaincfg.MainNetParams.GenesisBlock
+	err = chain.CheckConnectBlockTemplate(provautil.NewBlock(genesisBlock))
+	rerr, ok := err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrPrevBlockNotBest {
+		t.Errorf("CheckConnectBlockTemplate: unexpected error - got "+
+			"%v, want %v", err, blockchain.ErrPrevBlockNotBest)
+	}
+}
+
+// TestCheckBlockHeader tests the CheckBlockHeader function to ensure headers
+// which do not connect to a known block, are signed by a validate key which is
+// not authorized or was revoked, or break the validate key rate limits are
+// rejected.
+func TestCheckBlockHeader(t *testing.T) {
+	// Create a new database and chain instance to run tests against.
+	chain, teardownFunc, err := chainSetup("checkblockheader",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Errorf("Failed to setup chain instance: %v", err)
+		return
+	}
+
+	// A header which builds on an unknown block must be rejected.
+	header := chaincfg.MainNetParams.GenesisBlock.Header
+	header.PrevBlock = chainhash.Hash{0x01}
+	header.Height = 1
+	err = chain.CheckBlockHeader(&header)
+	rerr, ok := err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrPreviousBlockUnknown {
+		t.Errorf("CheckBlockHeader: unexpected error - got %v, want %v",
+			err, blockchain.ErrPreviousBlockUnknown)
+	}
+	teardownFunc()
+
+	tests, err := fullblocktests.Generate(false)
+	if err != nil {
+		t.Fatalf("failed to generate tests: %v", err)
+	}
+
+	// The headers of all accepted blocks must pass the header checks, and
+	// the headers of blocks signed by a validate key which was revoked
+	// must be rejected before the block itself is processed.
+	chain, teardownFunc, err = chainSetup("checkblockheaderrevoked",
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	var numRevoked int
+	for _, testInstances := range tests {
+		for _, instance := range testInstances {
+			var block *wire.MsgBlock
+			switch item := instance.(type) {
+			case fullblocktests.AcceptedBlock:
+				block = item.Block
+				if item.IsOrphan {
+					break
+				}
+				err := chain.CheckBlockHeader(&block.Header)
+				if err != nil {
+					teardownFunc()
+					t.Fatalf("CheckBlockHeader %q: unexpected "+
+						"error: %v", item.Name, err)
+				}
+			case fullblocktests.RejectedBlock:
+				block = item.Block
+				if item.RejectCode != blockchain.ErrInvalidValidateKey {
+					break
+				}
+				numRevoked++
+				err := chain.CheckBlockHeader(&block.Header)
+				rerr, ok := err.(blockchain.RuleError)
+				if !ok || rerr.ErrorCode != item.RejectCode {
+					teardownFunc()
+					t.Fatalf("CheckBlockHeader %q: unexpected "+
+						"error - got %v, want %v", item.Name,
+						err, item.RejectCode)
+				}
+			case fullblocktests.OrphanOrRejectedBlock:
+				block = item.Block
+			default:
+				continue
+			}
+			_, _, _ = chain.ProcessBlock(provautil.NewBlock(block),
+				blockchain.BFNone)
+		}
+	}
+	teardownFunc()
+	if numRevoked == 0 {
+		t.Fatal("no block signed by a revoked validate key was tested")
+	}
+
+	// Create a chain which limits the number of blocks in a row that may
+	// be signed by the same validate key and extend it with blocks which
+	// are all signed by one key, up to that limit.
+	params := chaincfg.RegressionNetParams
+	params.ChainTrailingSigKeyLimit = 3
+	chain, teardownFunc, err = chainSetup("checkblockheaderratelimit",
+		&params)
+	if err != nil {
+		t.Fatalf("Failed to setup chain instance: %v", err)
+	}
+	defer teardownFunc()
+	var blocks []*wire.MsgBlock
+	for _, instance := range tests[0] {
+		item, ok := instance.(fullblocktests.AcceptedBlock)
+		if !ok || len(blocks) == params.ChainTrailingSigKeyLimit {
+			break
+		}
+		blocks = append(blocks, item.Block)
+	}
+	for _, block := range blocks[:len(blocks)-1] {
+		_, _, err := chain.ProcessBlock(provautil.NewBlock(block),
+			blockchain.BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlock: unexpected error: %v", err)
+		}
+	}
+
+	// A header signed by a key which is not in the validate key set must
+	// be rejected.
+	header = blocks[len(blocks)-1].Header
+	unauthorizedKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: unexpected error: %v", err)
+	}
+	if err := header.Sign(unauthorizedKey); err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	err = chain.CheckBlockHeader(&header)
+	rerr, ok = err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrInvalidValidateKey {
+		t.Errorf("CheckBlockHeader: unexpected error - got %v, want %v",
+			err, blockchain.ErrInvalidValidateKey)
+	}
+
+	// The next header signed by the same key as its ancestors exceeds the
+	// trailing limit and must be rejected.
+	header = blocks[len(blocks)-1].Header
+	err = chain.CheckBlockHeader(&header)
+	rerr, ok = err.(blockchain.RuleError)
+	if !ok || rerr.ErrorCode != blockchain.ErrValidateKeyRateLimited {
+		t.Errorf("CheckBlockHeader: unexpected error - got %v, want %v",
+			err, blockchain.ErrValidateKeyRateLimited)
+	}
+}
+
 // TestCheckBlockSanity tests the CheckBlockSanity function to ensure it works
 // as expected.
 func TestCheckBlockSanity(t *testing.T) {
@@ -370,7 +531,7 @@ func TestCheckTransactionSanity(t *testing.T) {
 				LockTime: 0,
 			},
 			isValid: false,
-			code:    blockchain.ErrInvalidAdminTx,
+			code:    blockchain.ErrInvalidAdminOp,
 		},
 		{
 			name: "Admin transaction with invalid operation",
@@ -384,7 +545,7 @@ func TestCheckTransactionSanity(t *testing.T) {
 				LockTime: 0,
 			},
 			isValid: false,
-			code:    blockchain.ErrInvalidAdminTx,
+			code:    blockchain.ErrInvalidAdminOp,
 		},
 	}
 
@@ -566,6 +727,7 @@ func TestCheckTransactionOutputs(t *testing.T) {
 		name         string
 		tx           wire.MsgTx
 		lastKeyID    btcec.KeyID
+		totalSupply  uint64
 		adminKeySets map[btcec.KeySetType]btcec.PublicKeySet
 		aspKeyIdMap  btcec.KeyIdMap
 		isCoinbase   bool
@@ -600,7 +762,23 @@ func TestCheckTransactionOutputs(t *testing.T) {
 				return map[btcec.KeyID]*btcec.PublicKey{keyId1: pubKey}
 			}(),
 			isValid: false,
-			code:    blockchain.ErrInvalidTx,
+			code:    blockchain.ErrUnknownKeyID,
+		},
+		{
+			name: "Spend to Prova with revoked keyID.",
+			tx: wire.MsgTx{
+				Version:  1,
+				TxIn:     []*wire.TxIn{&dummyTxIn},
+				TxOut:    []*wire.TxOut{&provaTxOut},
+				LockTime: 0,
+			},
+			lastKeyID: btcec.KeyID(2),
+			aspKeyIdMap: func() btcec.KeyIdMap {
+				keyId1 := btcec.KeyID(1)
+				return map[btcec.KeyID]*btcec.PublicKey{keyId1: pubKey}
+			}(),
+			isValid: false,
+			code:    blockchain.ErrKeyIDRevoked,
 		},
 		{
 			name: "Add key to empty admin set.",
@@ -729,7 +907,7 @@ func TestCheckTransactionOutputs(t *testing.T) {
 			},
 			lastKeyID: btcec.KeyID(4),
 			isValid:   false,
-			code:      blockchain.ErrInvalidTx,
+			code:      blockchain.ErrKeyIDRevoked,
 		},
 		{
 			name: "provision keyID 2 times in same tx.",
@@ -826,7 +1004,21 @@ func TestCheckTransactionOutputs(t *testing.T) {
 				return map[btcec.KeyID]*btcec.PublicKey{keyId2: pubKey}
 			}(),
 			isValid: false,
-			code:    blockchain.ErrInvalidTx,
+			code:    blockchain.ErrUnknownKeyID,
+		},
+		{
+			name: "Destroy more than the total supply, which is only rejected by the memory pool policy.",
+			tx: wire.MsgTx{
+				Version: 1,
+				TxIn:    []*wire.TxIn{&dummyTxIn, &dummyTxIn},
+				TxOut: []*wire.TxOut{&issueTxOut, {
+					Value:    1001,
+					PkScript: nullScript,
+				}},
+				LockTime: 0,
+			},
+			totalSupply: 1000,
+			isValid:     true,
 		},
 		{
 			name: "Spend to a single null data output",
@@ -857,6 +1049,7 @@ func TestCheckTransactionOutputs(t *testing.T) {
 		keyView := blockchain.NewKeyViewpoint()
 		keyView.SetKeys(test.adminKeySets)
 		keyView.SetLastKeyID(test.lastKeyID)
+		keyView.SetTotalSupply(test.totalSupply)
 		keyView.SetKeyIDs(test.aspKeyIdMap)
 		tx := provautil.NewTx(&test.tx)
 		if test.isCoinbase {
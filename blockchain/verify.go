@@ -0,0 +1,434 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+// ChainStateReport houses the results of verifying the chain state stored in a
+// database against the stored blocks.
+type ChainStateReport struct {
+	// BestHash and BestHeight identify the best block of the stored chain
+	// state.
+	BestHash   chainhash.Hash
+	BestHeight uint32
+
+	// BlocksChecked is the number of main chain blocks whose data was
+	// checked against the chain state.
+	BlocksChecked uint32
+
+	// BlocksUnavailable is the number of main chain blocks whose data is
+	// not stored, either because it was pruned or because the chain state
+	// was loaded from a utxo set snapshot.
+	BlocksUnavailable uint32
+
+	// UtxoEntries is the number of entries in the utxo set.
+	UtxoEntries uint64
+
+	// Problems describes each inconsistency that was found.  The chain
+	// state is consistent with the stored blocks when it is empty.
+	Problems []string
+}
+
+// addProblem records an inconsistency in the report.
+func (r *ChainStateReport) addProblem(format string, args ...interface{}) {
+	r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+}
+
+// isBlockUnavailableErr returns whether or not the passed error is the result
+// of the data for a block not being stored.
+func isBlockUnavailableErr(err error) bool {
+	dbErr, ok := err.(database.Error)
+	return ok && (dbErr.ErrorCode == database.ErrBlockNotFound ||
+		dbErr.ErrorCode == database.ErrBlockPruned)
+}
+
+// VerifyChainState checks that the chain state stored in the passed database is
+// consistent with the stored blocks.  This covers the best chain state, the
+// main chain block index, the spend journal, the utxo set and the admin key set.
+// Inconsistencies are returned in the report, while an error is only returned
+// when the checks could not be performed.
+//
+// The database must not be in use by a chain instance since the chain state is
+// expected to not change while it is being verified.
+func VerifyChainState(db database.DB) (*ChainStateReport, error) {
+	var report ChainStateReport
+	err := db.View(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		serializedState := meta.Get(chainStateKeyName)
+		if serializedState == nil {
+			return AssertError("the database does not contain a " +
+				"chain state")
+		}
+		state, err := deserializeBestChainState(serializedState)
+		if err != nil {
+			return err
+		}
+		report.BestHash = state.hash
+		report.BestHeight = state.height
+
+		for _, bucketName := range [][]byte{hashIndexBucketName,
+			heightIndexBucketName, spendJournalBucketName,
+			utxoSetBucketName} {
+
+			if meta.Bucket(bucketName) == nil {
+				return AssertError(fmt.Sprintf("the database does "+
+					"not contain the %s bucket", bucketName))
+			}
+		}
+
+		mainChain, err := verifyBlockIndex(dbTx, &state, &report)
+		if err != nil {
+			return err
+		}
+		if err := verifySpendJournal(dbTx, mainChain, &report); err != nil {
+			return err
+		}
+		if err := verifyUtxoSet(dbTx, mainChain, &report); err != nil {
+			return err
+		}
+		return verifyKeySet(dbTx, &report)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// verifyBlockIndex checks the main chain block index against the best chain
+// state and the stored block headers, and returns the main chain block hashes
+// mapped to their heights.
+func verifyBlockIndex(dbTx database.Tx, state *bestChainState, report *ChainStateReport) (map[chainhash.Hash]uint32, error) {
+	meta := dbTx.Metadata()
+	hashIndex := meta.Bucket(hashIndexBucketName)
+	heightIndex := meta.Bucket(heightIndexBucketName)
+
+	// Walk the main chain from the genesis block to the best block and
+	// ensure both indexes agree, every header connects to the previous
+	// one, and the work sum matches.  The work sum can't be calculated
+	// when a header is not available.
+	mainChain := make(map[chainhash.Hash]uint32, state.height+1)
+	var prevHash chainhash.Hash
+	workSum := new(big.Int)
+	haveAllHeaders := true
+	for height := uint32(0); height <= state.height; height++ {
+		hash, err := dbFetchHashByHeight(dbTx, height)
+		if err != nil {
+			report.addProblem("height index has no entry for "+
+				"height %d", height)
+			haveAllHeaders = false
+			prevHash = chainhash.Hash{}
+			continue
+		}
+		mainChain[*hash] = height
+
+		hashHeight, err := dbFetchHeightByHash(dbTx, hash)
+		if err != nil {
+			report.addProblem("hash index has no entry for block "+
+				"%v at height %d", hash, height)
+		} else if hashHeight != height {
+			report.addProblem("hash index has height %d for block "+
+				"%v at height %d", hashHeight, hash, height)
+		}
+
+		header, err := dbFetchHeaderByHash(dbTx, hash)
+		switch {
+		case isBlockUnavailableErr(err):
+			haveAllHeaders = false
+		case err != nil:
+			report.addProblem("unable to load header for block %v "+
+				"at height %d: %v", hash, height, err)
+			haveAllHeaders = false
+		default:
+			if header.Height != height {
+				report.addProblem("block %v at height %d has "+
+					"height %d in its header", hash, height,
+					header.Height)
+			}
+			if height > 0 && prevHash != (chainhash.Hash{}) &&
+				header.PrevBlock != prevHash {
+
+				report.addProblem("block %v at height %d does "+
+					"not connect to block %v", hash, height,
+					prevHash)
+			}
+			workSum.Add(workSum, CalcWork(header.Bits))
+		}
+		prevHash = *hash
+	}
+	if prevHash != state.hash {
+		report.addProblem("main chain ends at %v instead of the best "+
+			"block %v", prevHash, state.hash)
+	}
+	if haveAllHeaders && workSum.Cmp(state.workSum) != 0 {
+		report.addProblem("best chain state has work sum %v instead "+
+			"of %v", state.workSum, workSum)
+	}
+
+	// Ensure neither index has entries for blocks which are not in the
+	// main chain.
+	err := hashIndex.ForEach(func(k, v []byte) error {
+		var hash chainhash.Hash
+		copy(hash[:], k)
+		if _, ok := mainChain[hash]; !ok {
+			report.addProblem("hash index has an entry for block "+
+				"%v which is not in the main chain", hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = heightIndex.ForEach(func(k, v []byte) error {
+		height := byteOrder.Uint32(k)
+		if height > state.height {
+			report.addProblem("height index has an entry for height "+
+				"%d above the best height %d", height,
+				state.height)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mainChain, nil
+}
+
+// verifySpendJournal checks that every available main chain block has a spend
+// journal entry which covers all of its spent txouts, and that there are no
+// entries for other blocks.
+//
+// The blocks stored along with a utxo set snapshot were never connected, so
+// they don't have entries.  Entries are therefore only required once the first
+// connected block is found.
+func verifySpendJournal(dbTx database.Tx, mainChain map[chainhash.Hash]uint32, report *ChainStateReport) error {
+	heightHashes := make(map[uint32]chainhash.Hash, len(mainChain))
+	for hash, height := range mainChain {
+		heightHashes[height] = hash
+	}
+
+	spendBucket := dbTx.Metadata().Bucket(spendJournalBucketName)
+	connected := false
+	for height := uint32(0); height <= report.BestHeight; height++ {
+		hash, ok := heightHashes[height]
+		if !ok {
+			continue
+		}
+		block, err := dbFetchBlockByHash(dbTx, &hash)
+		if isBlockUnavailableErr(err) {
+			report.BlocksUnavailable++
+			continue
+		}
+		if err != nil {
+			report.addProblem("unable to load block %v at height "+
+				"%d: %v", hash, height, err)
+			continue
+		}
+		report.BlocksChecked++
+
+		// The genesis block is not connected, so it does not have a
+		// spend journal entry.
+		if height == 0 {
+			continue
+		}
+		serialized := spendBucket.Get(hash[:])
+		if height == 1 || serialized != nil {
+			connected = true
+		}
+
+		// Decode the stxos of all of the non-coinbase transactions.
+		// The version of the containing transaction is only needed to
+		// decompress the txout, so any version works for decoding it.
+		// An empty entry can't be told apart from a missing one, so
+		// only blocks which spend txouts are required to have one.
+		var numStxos int
+		for _, tx := range block.MsgBlock().Transactions[1:] {
+			numStxos += len(tx.TxIn)
+		}
+		if serialized == nil {
+			if numStxos > 0 && connected {
+				report.addProblem("spend journal has no entry "+
+					"for block %v at height %d", hash,
+					height)
+			}
+			continue
+		}
+		offset := 0
+		for i := 0; i < numStxos && err == nil; i++ {
+			var stxo spentTxOut
+			var n int
+			n, err = decodeSpentTxOut(serialized[offset:], &stxo, 1)
+			offset += n
+			if err == nil && stxo.height > height {
+				err = fmt.Errorf("spent txout from height %d",
+					stxo.height)
+			}
+		}
+		if err == nil && offset != len(serialized) {
+			err = fmt.Errorf("%d bytes left after %d spent txouts",
+				len(serialized)-offset, numStxos)
+		}
+		if err != nil {
+			report.addProblem("spend journal entry for block %v at "+
+				"height %d is invalid: %v", hash, height, err)
+		}
+	}
+
+	return spendBucket.ForEach(func(k, v []byte) error {
+		var hash chainhash.Hash
+		copy(hash[:], k)
+		if _, ok := mainChain[hash]; !ok {
+			report.addProblem("spend journal has an entry for "+
+				"block %v which is not in the main chain", hash)
+		}
+		return nil
+	})
+}
+
+// verifyUtxoSet checks that every utxo set entry matches the outputs of the
+// transaction it was created by in the main chain block at its height.
+func verifyUtxoSet(dbTx database.Tx, mainChain map[chainhash.Hash]uint32, report *ChainStateReport) error {
+	heightHashes := make(map[uint32]chainhash.Hash, len(mainChain))
+	for hash, height := range mainChain {
+		heightHashes[height] = hash
+	}
+
+	// Transactions of the most recently loaded block.  Utxo entries are
+	// visited in transaction hash order, so this mostly helps with the
+	// outputs of the same transaction at the end of the main chain.
+	var cachedHeight uint32
+	var cachedTxns map[chainhash.Hash]int
+	var cachedBlock *provautil.Block
+
+	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+	return utxoBucket.ForEach(func(k, v []byte) error {
+		report.UtxoEntries++
+		var txHash chainhash.Hash
+		copy(txHash[:], k)
+		if len(v) == 0 {
+			report.addProblem("utxo set has an entry for fully "+
+				"spent tx %v", txHash)
+			return nil
+		}
+		entry, err := deserializeUtxoEntry(v)
+		if err != nil {
+			report.addProblem("utxo set entry for tx %v is "+
+				"invalid: %v", txHash, err)
+			return nil
+		}
+
+		height := entry.BlockHeight()
+		blockHash, ok := heightHashes[height]
+		if !ok {
+			report.addProblem("utxo set entry for tx %v is from "+
+				"height %d which is not in the main chain",
+				txHash, height)
+			return nil
+		}
+		if cachedBlock == nil || cachedHeight != height {
+			block, err := dbFetchBlockByHash(dbTx, &blockHash)
+			if isBlockUnavailableErr(err) {
+				return nil
+			}
+			if err != nil {
+				report.addProblem("unable to load block %v at "+
+					"height %d: %v", blockHash, height, err)
+				return nil
+			}
+			cachedBlock = block
+			cachedHeight = height
+			cachedTxns = make(map[chainhash.Hash]int)
+			for i, tx := range block.Transactions() {
+				cachedTxns[*tx.Hash()] = i
+			}
+		}
+
+		txIdx, ok := cachedTxns[txHash]
+		if !ok {
+			report.addProblem("utxo set entry for tx %v is not in "+
+				"block %v at height %d", txHash, blockHash,
+				height)
+			return nil
+		}
+		tx := cachedBlock.MsgBlock().Transactions[txIdx]
+		if entry.IsCoinBase() != (txIdx == 0) {
+			report.addProblem("utxo set entry for tx %v has the "+
+				"wrong coinbase flag", txHash)
+		}
+		if entry.Version() != tx.Version {
+			report.addProblem("utxo set entry for tx %v has "+
+				"version %d instead of %d", txHash,
+				entry.Version(), tx.Version)
+		}
+		for outputIndex := range entry.sparseOutputs {
+			if outputIndex >= uint32(len(tx.TxOut)) {
+				report.addProblem("utxo set entry for tx %v has "+
+					"output %d which does not exist", txHash,
+					outputIndex)
+				continue
+			}
+			txOut := tx.TxOut[outputIndex]
+			if entry.AmountByIndex(outputIndex) != txOut.Value ||
+				!bytes.Equal(entry.PkScriptByIndex(outputIndex),
+					txOut.PkScript) {
+
+				report.addProblem("utxo set entry for output "+
+					"%v does not match the transaction",
+					wire.NewOutPoint(&txHash, outputIndex))
+			}
+		}
+		return nil
+	})
+}
+
+// verifyKeySet checks that the admin key set can be loaded and that the admin
+// thread tips are unspent outputs in the utxo set.
+func verifyKeySet(dbTx database.Tx, report *ChainStateReport) error {
+	serializedKeys := dbTx.Metadata().Get(keySetBucketName)
+	if serializedKeys == nil {
+		report.addProblem("the database does not contain the admin " +
+			"key set")
+		return nil
+	}
+	_, aspKeyIDMap, threadTips, lastKeyID, _, err :=
+		deserializeKeySet(serializedKeys)
+	if err != nil {
+		report.addProblem("admin key set is invalid: %v", err)
+		return nil
+	}
+
+	for keyID := range aspKeyIDMap {
+		if keyID > lastKeyID {
+			report.addProblem("admin key set has ASP key id %d "+
+				"above the last key id %d", keyID, lastKeyID)
+		}
+	}
+	for _, threadID := range threadOrder {
+		tip := threadTips[threadID]
+		entry, err := dbFetchUtxoEntry(dbTx, &tip.Hash)
+		if err != nil {
+			report.addProblem("unable to load the utxo set entry "+
+				"for the tip %v of admin thread %d: %v", tip,
+				threadID, err)
+			continue
+		}
+		if entry == nil || entry.IsOutputSpent(tip.Index) {
+			report.addProblem("tip %v of admin thread %d is not "+
+				"in the utxo set", tip, threadID)
+		}
+	}
+
+	return nil
+}
@@ -6,15 +6,20 @@
 package main
 
 import (
+	"bufio"
 	"container/list"
+	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/database"
@@ -40,6 +45,30 @@ const (
 	// maxRequestedTxns is the maximum number of requested transactions
 	// hashes to store in memory.
 	maxRequestedTxns = wire.MaxInvPerMsg
+
+	// blockDownloadWindow is the maximum number of blocks beyond the
+	// current best chain height which are requested from peers during
+	// headers-first synchronization.
+	blockDownloadWindow = 1024
+
+	// maxInFlightBlocksPerPeer is the maximum number of blocks which may
+	// be outstanding from a single peer during headers-first
+	// synchronization.
+	maxInFlightBlocksPerPeer = 16
+
+	// blockStallTimeout is the amount of time a block requested during
+	// headers-first synchronization may remain outstanding before the peer
+	// it was requested from is considered stalled.
+	blockStallTimeout = time.Minute
+
+	// stallSampleInterval is the interval at which the outstanding block
+	// requests are checked for stalled peers.
+	stallSampleInterval = 10 * time.Second
+
+	// maxPendingCmpctBlocks is the maximum number of partially
+	// reconstructed compact blocks which are waiting for their missing
+	// transactions.
+	maxPendingCmpctBlocks = 16
 )
 
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
@@ -57,6 +86,27 @@ type blockMsg struct {
 	peer  *serverPeer
 }
 
+// headersMsg packages a bitcoin headers message and the peer it came from
+// together so the block handler has access to that information.
+type headersMsg struct {
+	headers *wire.MsgHeaders
+	peer    *serverPeer
+}
+
+// cmpctBlockMsg packages a bitcoin cmpctblock message and the peer it came
+// from together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *serverPeer
+}
+
+// blockTxnMsg packages a bitcoin blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnMsg struct {
+	blockTxn *wire.MsgBlockTxn
+	peer     *serverPeer
+}
+
 // invMsg packages a bitcoin inv message and the peer it came from together
 // so the block handler has access to that information.
 type invMsg struct {
@@ -82,6 +132,12 @@ type getSyncPeerMsg struct {
 	reply chan *serverPeer
 }
 
+// getHeaderHeightMsg is a message type to be sent across the message channel
+// for retrieving the height of the best known header.
+type getHeaderHeightMsg struct {
+	reply chan uint32
+}
+
 // processBlockResponse is a response sent to the reply channel of a
 // processBlockMsg.
 type processBlockResponse struct {
@@ -100,6 +156,20 @@ type processBlockMsg struct {
 	reply chan processBlockResponse
 }
 
+// loadSnapshotResponse is a response sent to the reply channel of a
+// loadSnapshotMsg.
+type loadSnapshotResponse struct {
+	info *blockchain.SnapshotInfo
+	err  error
+}
+
+// loadSnapshotMsg is a message type to be sent across the message channel for
+// bootstrapping the chain state from the utxo set snapshot at the given path.
+type loadSnapshotMsg struct {
+	path  string
+	reply chan loadSnapshotResponse
+}
+
 // isCurrentMsg is a message type to be sent across the message channel for
 // requesting whether or not the block manager believes it is synced with
 // the currently connected peers.
@@ -115,6 +185,30 @@ type pauseMsg struct {
 	unpause <-chan struct{}
 }
 
+// headerNode is used as a node in a list of headers that are linked together
+// between the current best chain tip and the most recently accepted header
+// during headers-first synchronization.
+type headerNode struct {
+	height uint32
+	hash   *chainhash.Hash
+}
+
+// inFlightBlock tracks a block which was requested from a peer during
+// headers-first synchronization.
+type inFlightBlock struct {
+	peer      *serverPeer
+	requested time.Time
+}
+
+// pendingCmpctBlock is a compact block which could not be fully reconstructed
+// from the transaction memory pool and is waiting for the missing transactions
+// to be delivered by the peer which announced it.
+type pendingCmpctBlock struct {
+	peer    *serverPeer
+	block   *wire.MsgBlock
+	missing []uint32
+}
+
 // blockManager provides a concurrency safe block manager for handling all
 // incoming blocks.
 type blockManager struct {
@@ -130,6 +224,36 @@ type blockManager struct {
 	msgChan         chan interface{}
 	wg              sync.WaitGroup
 	quit            chan struct{}
+
+	// The following fields are used for headers-first mode.
+	headersFirstMode bool
+	headersSynced    bool
+	headersBlocked   bool
+	headerList       *list.List
+	headerTip        *headerNode
+	inFlightBlocks   map[chainhash.Hash]*inFlightBlock
+	downloadedBlocks map[chainhash.Hash]*blockMsg
+
+	// assumeValidHeight is the height of the assume-valid block once its
+	// header is part of the header list and zero otherwise.  Blocks up to
+	// that height are processed without script validation.
+	assumeValidHeight uint32
+
+	// pendingCmpctBlocks tracks compact blocks which are waiting for
+	// their missing transactions.
+	pendingCmpctBlocks map[chainhash.Hash]*pendingCmpctBlock
+}
+
+// resetHeaderList discards all headers which do not yet have their blocks
+// connected to the chain and sets the header tip to the passed node.  Blocks
+// which are already downloaded or in flight are kept since they are likely to
+// be needed again once the header chain is downloaded anew.
+func (b *blockManager) resetHeaderList(tip *headerNode) {
+	b.headerList.Init()
+	b.headerTip = tip
+	b.headersSynced = false
+	b.headersBlocked = false
+	b.assumeValidHeight = 0
 }
 
 // startSync will choose the best peer among the available candidate peers to
@@ -160,17 +284,22 @@ func (b *blockManager) startSync(peers *list.List) {
 			continue
 		}
 
-		// TODO(davec): Use a better algorithm to choose the best peer.
-		// For now, just pick the first available candidate.
-		bestPeer = sp
+		// Choose the candidate which claims the longest chain.  Blocks
+		// are downloaded from all candidates in headers-first mode, so
+		// the sync peer only needs to provide the headers.
+		if bestPeer == nil || sp.LastBlock() > bestPeer.LastBlock() {
+			bestPeer = sp
+		}
 	}
 
 	// Start syncing from the best peer if one was selected.
 	if bestPeer != nil {
 		// Clear the requestedBlocks if the sync peer changes, otherwise
 		// we may ignore blocks we need that the last sync peer failed
-		// to send.
+		// to send.  The blocks in flight for the previous header list
+		// are forgotten for the same reason.
 		b.requestedBlocks = make(map[chainhash.Hash]struct{})
+		b.inFlightBlocks = make(map[chainhash.Hash]*inFlightBlock)
 
 		locator, err := b.chain.LatestBlockLocator()
 		if err != nil {
@@ -181,9 +310,28 @@ func (b *blockManager) startSync(peers *list.List) {
 
 		bmgrLog.Infof("Syncing to block height %d from peer %v",
 			bestPeer.LastBlock(), bestPeer.Addr())
-		bestPeer.PushGetBlocksMsg(locator, &zeroHash)
+
+		// Download and validate the signed header chain first when the
+		// peer is ahead of us so the blocks can be fetched in parallel
+		// from all of the candidate peers.  The regression test tool
+		// does not serve headers, so it is synced from directly.
+		if !cfg.RegressionTest && bestPeer.LastBlock() > best.Height {
+			b.headersFirstMode = true
+			b.resetHeaderList(&headerNode{
+				height: best.Height,
+				hash:   best.Hash,
+			})
+			bmgrLog.Infof("Downloading headers for blocks %d to %d "+
+				"from peer %s", best.Height+1,
+				bestPeer.LastBlock(), bestPeer.Addr())
+			bestPeer.PushGetHeadersMsg(locator, &zeroHash)
+		} else {
+			b.headersFirstMode = false
+			bestPeer.PushGetBlocksMsg(locator, &zeroHash)
+		}
 		b.syncPeer = bestPeer
 	} else {
+		b.headersFirstMode = false
 		bmgrLog.Warnf("No sync peer candidates available")
 	}
 }
@@ -237,6 +385,12 @@ func (b *blockManager) handleNewPeerMsg(peers *list.List, sp *serverPeer) {
 
 	// Start syncing by choosing the best candidate if needed.
 	b.startSync(peers)
+
+	// Make use of the new peer to download blocks when synchronizing in
+	// headers-first mode.
+	if b.headersFirstMode {
+		b.fetchHeaderBlocks(peers)
+	}
 }
 
 // handleDonePeerMsg deals with peers that have signalled they are done.  It
@@ -266,6 +420,15 @@ func (b *blockManager) handleDonePeerMsg(peers *list.List, sp *serverPeer) {
 	// and request them now to speed things up a little.
 	for k := range sp.requestedBlocks {
 		delete(b.requestedBlocks, k)
+		delete(b.inFlightBlocks, k)
+	}
+
+	// Forget compact blocks which are waiting for transactions from the
+	// quitting peer.
+	for k, pending := range b.pendingCmpctBlocks {
+		if pending.peer == sp {
+			delete(b.pendingCmpctBlocks, k)
+		}
 	}
 
 	// Attempt to find a new peer to sync from if the quitting peer is the
@@ -274,6 +437,12 @@ func (b *blockManager) handleDonePeerMsg(peers *list.List, sp *serverPeer) {
 		b.syncPeer = nil
 		b.startSync(peers)
 	}
+
+	// Request the blocks which were in flight from the quitting peer from
+	// the remaining peers.
+	if b.headersFirstMode {
+		b.fetchHeaderBlocks(peers)
+	}
 }
 
 // handleTxMsg handles transaction messages from all peers.
@@ -300,8 +469,9 @@ func (b *blockManager) handleTxMsg(tmsg *txMsg) {
 	// Process the transaction to include validation, insertion in the
 	// memory pool, orphan handling, etc.
 	allowOrphans := cfg.MaxOrphanTxs > 0
+	origin := mempool.TxOrigin{Peer: mempool.Tag(tmsg.peer.ID())}
 	acceptedTxs, err := b.server.txMemPool.ProcessTransaction(tmsg.tx,
-		allowOrphans, true, mempool.Tag(tmsg.peer.ID()))
+		allowOrphans, true, origin)
 
 	// Remove transaction from request maps. Either the mempool/chain
 	// already knows about it and as such we shouldn't have any more
@@ -361,7 +531,7 @@ func (b *blockManager) current() bool {
 }
 
 // handleBlockMsg handles block messages from all peers.
-func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
+func (b *blockManager) handleBlockMsg(peers *list.List, bmsg *blockMsg) {
 	// If we didn't ask for this block then the peer is misbehaving.
 	blockHash := bmsg.block.Hash()
 	if _, exists := bmsg.peer.requestedBlocks[*blockHash]; !exists {
@@ -378,6 +548,23 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
+	// Blocks requested during headers-first synchronization may arrive in
+	// any order, so they are held until all of their ancestors have been
+	// processed and then processed in header order.
+	if _, exists := b.inFlightBlocks[*blockHash]; exists {
+		delete(b.inFlightBlocks, *blockHash)
+		if b.headersFirstMode {
+			delete(bmsg.peer.requestedBlocks, *blockHash)
+			delete(b.requestedBlocks, *blockHash)
+			b.downloadedBlocks[*blockHash] = bmsg
+			b.processDownloadedBlocks()
+			if b.headersFirstMode {
+				b.fetchHeaderBlocks(peers)
+			}
+			return
+		}
+	}
+
 	behaviorFlags := blockchain.BFNone
 
 	// Remove block from request maps. Either chain will know about it and
@@ -480,6 +667,624 @@ func (b *blockManager) handleBlockMsg(bmsg *blockMsg) {
 	}
 }
 
+// reconstructCmpctBlock reconstructs the block announced by the passed compact
+// block from its prefilled transactions and the passed memory pool
+// transactions.  It returns the block along with the indexes of the
+// transactions which could not be found, whose slots in the block are left
+// empty.  Short IDs which match more than one of the memory pool transactions
+// are ambiguous and treated as missing.
+func reconstructCmpctBlock(msg *wire.MsgCmpctBlock, poolTxns []*wire.MsgTx) (*wire.MsgBlock, []uint32, error) {
+	// Place the prefilled transactions in the block.
+	numTxns := msg.TotalTransactions()
+	txns := make([]*wire.MsgTx, numTxns)
+	for _, ptx := range msg.PrefilledTxs {
+		if ptx.Index >= uint32(numTxns) {
+			return nil, nil, fmt.Errorf("prefilled transaction "+
+				"index %d is out of range", ptx.Index)
+		}
+		txns[ptx.Index] = ptx.Tx
+	}
+
+	// Map the short IDs of the memory pool transactions to the
+	// transactions.
+	k0, k1 := msg.ShortIDKeys()
+	txnsByShortID := make(map[uint64]*wire.MsgTx, len(poolTxns))
+	for _, msgTx := range poolTxns {
+		txHash := msgTx.TxHashWithSig()
+		shortID := wire.ShortTxID(k0, k1, &txHash)
+		if _, exists := txnsByShortID[shortID]; exists {
+			txnsByShortID[shortID] = nil
+			continue
+		}
+		txnsByShortID[shortID] = msgTx
+	}
+
+	// Fill the remaining slots in order from the short IDs.
+	var missing []uint32
+	shortIDs := msg.ShortIDs
+	for i := range txns {
+		if txns[i] != nil {
+			continue
+		}
+		if len(shortIDs) == 0 {
+			break
+		}
+		txns[i] = txnsByShortID[shortIDs[0]]
+		shortIDs = shortIDs[1:]
+		if txns[i] == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+
+	msgBlock := wire.NewMsgBlock(&msg.Header)
+	msgBlock.Transactions = txns
+	return msgBlock, missing, nil
+}
+
+// fill places the passed transactions, which were delivered by a blocktxn
+// message, in the slots of the missing transactions of the block.  It returns
+// false without modifying the block when the number of transactions does not
+// match the number of missing ones.
+func (p *pendingCmpctBlock) fill(txns []*wire.MsgTx) bool {
+	if len(txns) != len(p.missing) {
+		return false
+	}
+	for i, index := range p.missing {
+		p.block.Transactions[index] = txns[i]
+	}
+	return true
+}
+
+// handleCmpctBlockMsg handles cmpctblock messages from all peers.  The block is
+// reconstructed from the transactions in the memory pool.  Any transactions
+// which are not available are requested from the peer via a getblocktxn
+// message, and the full block is requested instead when the compact block can
+// not be used.
+func (b *blockManager) handleCmpctBlockMsg(peers *list.List, cmsg *cmpctBlockMsg) {
+	// Compact blocks are only useful for blocks at the tip of the chain,
+	// so ignore them while syncing.
+	msg := cmsg.cmpctBlock
+	blockHash := msg.BlockHash()
+	if b.headersFirstMode || !b.current() {
+		bmgrLog.Debugf("Ignoring compact block %v from %s while "+
+			"syncing", blockHash, cmsg.peer)
+		return
+	}
+
+	// Ignore compact blocks which are already known or being
+	// reconstructed.
+	haveBlock, err := b.chain.HaveBlock(&blockHash)
+	if err != nil {
+		bmgrLog.Errorf("Failed to check for block %v: %v", blockHash,
+			err)
+		return
+	}
+	if haveBlock || b.pendingCmpctBlocks[blockHash] != nil {
+		return
+	}
+
+	// Request the full block when the compact block does not build on a
+	// known block so it is handled via the regular orphan processing.
+	haveParent, err := b.chain.HaveBlock(&msg.Header.PrevBlock)
+	if err != nil {
+		bmgrLog.Errorf("Failed to check for block %v: %v",
+			msg.Header.PrevBlock, err)
+		return
+	}
+	if !haveParent {
+		b.requestFullBlock(cmsg.peer, &blockHash)
+		return
+	}
+
+	// Ensure the header is valid and signed by an authorized validate key
+	// before spending any effort on reconstructing the block.
+	err = b.chain.CheckBlockHeader(&msg.Header)
+	if err != nil {
+		bmgrLog.Infof("Rejected compact block %v from %s: %v",
+			blockHash, cmsg.peer, err)
+		code, reason := mempool.ErrToRejectErr(err)
+		cmsg.peer.PushRejectMsg(wire.CmdBlock, code, reason,
+			&blockHash, false)
+		return
+	}
+
+	descs := b.server.txMemPool.TxDescs()
+	poolTxns := make([]*wire.MsgTx, 0, len(descs))
+	for _, txDesc := range descs {
+		poolTxns = append(poolTxns, txDesc.Tx.MsgTx())
+	}
+	msgBlock, missing, err := reconstructCmpctBlock(msg, poolTxns)
+	if err != nil {
+		bmgrLog.Warnf("Compact block %v from %s is malformed: %v -- "+
+			"disconnecting", blockHash, cmsg.peer, err)
+		cmsg.peer.Disconnect()
+		return
+	}
+	if len(missing) == 0 {
+		b.processCmpctBlock(peers, cmsg.peer, msgBlock)
+		return
+	}
+
+	// Request the missing transactions from the peer.
+	if len(b.pendingCmpctBlocks) >= maxPendingCmpctBlocks {
+		for k := range b.pendingCmpctBlocks {
+			delete(b.pendingCmpctBlocks, k)
+			break
+		}
+	}
+	b.pendingCmpctBlocks[blockHash] = &pendingCmpctBlock{
+		peer:    cmsg.peer,
+		block:   msgBlock,
+		missing: missing,
+	}
+	bmgrLog.Debugf("Requesting %d of %d transactions of compact block %v "+
+		"from %s", len(missing), len(msgBlock.Transactions), blockHash,
+		cmsg.peer)
+	cmsg.peer.QueueMessage(wire.NewMsgGetBlockTxn(&blockHash, missing), nil)
+}
+
+// handleBlockTxnMsg handles blocktxn messages from all peers.  The delivered
+// transactions complete the reconstruction of a pending compact block.
+func (b *blockManager) handleBlockTxnMsg(peers *list.List, tmsg *blockTxnMsg) {
+	msg := tmsg.blockTxn
+	pending, exists := b.pendingCmpctBlocks[msg.BlockHash]
+	if !exists || pending.peer != tmsg.peer {
+		bmgrLog.Debugf("Ignoring unrequested block transactions for "+
+			"block %v from %s", msg.BlockHash, tmsg.peer)
+		return
+	}
+	delete(b.pendingCmpctBlocks, msg.BlockHash)
+
+	// Fall back to requesting the full block when the peer did not deliver
+	// exactly the requested transactions.
+	if !pending.fill(msg.Transactions) {
+		bmgrLog.Debugf("Peer %s sent %d transactions for block %v "+
+			"when %d were requested", tmsg.peer,
+			len(msg.Transactions), msg.BlockHash,
+			len(pending.missing))
+		b.requestFullBlock(tmsg.peer, &msg.BlockHash)
+		return
+	}
+
+	b.processCmpctBlock(peers, tmsg.peer, pending.block)
+}
+
+// processCmpctBlock processes a block which was reconstructed from a compact
+// block.  Since a short ID may match a different transaction than the one in
+// the block, the full block is requested instead when the reconstructed
+// transactions do not match the merkle root of the header.
+func (b *blockManager) processCmpctBlock(peers *list.List, sp *serverPeer, msgBlock *wire.MsgBlock) {
+	block := provautil.NewBlock(msgBlock)
+	merkles := blockchain.BuildMerkleTreeStore(block.Transactions())
+	if !msgBlock.Header.MerkleRoot.IsEqual(merkles[len(merkles)-1]) {
+		bmgrLog.Debugf("Reconstructed compact block %v does not match "+
+			"its merkle root", block.Hash())
+		b.requestFullBlock(sp, block.Hash())
+		return
+	}
+
+	// The block is processed as if it was requested from the peer.
+	sp.requestedBlocks[*block.Hash()] = struct{}{}
+	b.requestedBlocks[*block.Hash()] = struct{}{}
+	b.handleBlockMsg(peers, &blockMsg{block: block, peer: sp})
+}
+
+// requestFullBlock requests the block with the passed hash from the peer
+// unless there is already a pending request for it.
+func (b *blockManager) requestFullBlock(sp *serverPeer, hash *chainhash.Hash) {
+	if _, exists := b.requestedBlocks[*hash]; exists {
+		return
+	}
+	b.requestedBlocks[*hash] = struct{}{}
+	b.limitMap(b.requestedBlocks, maxRequestedBlocks)
+	sp.requestedBlocks[*hash] = struct{}{}
+
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, hash))
+	sp.QueueMessage(gdmsg, nil)
+}
+
+// processDownloadedBlocks processes the blocks downloaded during headers-first
+// synchronization in the order of the header list, stopping at the first
+// header whose block has not been downloaded yet.  Once every header has had
+// its block connected, either more headers are requested or headers-first mode
+// is exited when the sync peer has no more headers.
+func (b *blockManager) processDownloadedBlocks() {
+	for e := b.headerList.Front(); e != nil; e = b.headerList.Front() {
+		node := e.Value.(*headerNode)
+		bmsg, exists := b.downloadedBlocks[*node.hash]
+		if !exists {
+			break
+		}
+		delete(b.downloadedBlocks, *node.hash)
+
+		// Blocks which are ancestors of the assume-valid block in the
+		// validated header chain do not need their scripts checked.
+		behaviorFlags := blockchain.BFNone
+		if node.height <= b.assumeValidHeight {
+			behaviorFlags |= blockchain.BFAssumeValid
+		}
+		_, isOrphan, err := b.chain.ProcessBlock(bmsg.block,
+			behaviorFlags)
+		if err == nil && isOrphan {
+			err = fmt.Errorf("block %v does not connect to the "+
+				"chain", node.hash)
+		}
+		if err != nil {
+			// The block may already have been processed via
+			// another path, such as the submitblock RPC.
+			if rerr, ok := err.(blockchain.RuleError); ok &&
+				rerr.ErrorCode == blockchain.ErrDuplicateBlock {
+				b.headerList.Remove(e)
+				continue
+			}
+
+			bmgrLog.Infof("Rejected block %v from %s: %v", node.hash,
+				bmsg.peer, err)
+			if dbErr, ok := err.(database.Error); ok &&
+				dbErr.ErrorCode == database.ErrCorruption {
+				panic(dbErr)
+			}
+			code, reason := mempool.ErrToRejectErr(err)
+			bmsg.peer.PushRejectMsg(wire.CmdBlock, code, reason,
+				node.hash, false)
+
+			// A block whose transactions do not match the merkle
+			// root of its header was malleated by the peer which
+			// sent it, so request it again from another peer.
+			// Otherwise the signed header chain itself leads to an
+			// invalid block, so the sync peer which provided it is
+			// not a suitable source to sync from.
+			rerr, ok := err.(blockchain.RuleError)
+			if ok && rerr.ErrorCode == blockchain.ErrBadMerkleRoot {
+				bmsg.peer.Disconnect()
+				return
+			}
+			if b.syncPeer != nil {
+				bmgrLog.Warnf("Sync peer %s provided headers for "+
+					"invalid block %v -- disconnecting",
+					b.syncPeer, node.hash)
+				b.syncPeer.Disconnect()
+			}
+			best := b.chain.BestSnapshot()
+			b.resetHeaderList(&headerNode{
+				height: best.Height,
+				hash:   best.Hash,
+			})
+			return
+		}
+		b.headerList.Remove(e)
+
+		b.progressLogger.LogBlockHeight(bmsg.block)
+		b.rejectedTxns = make(map[chainhash.Hash]struct{})
+		if rpcServer := b.server.rpcServer; rpcServer != nil {
+			rpcServer.gbtWorkState.NotifyBlockConnected(node.hash)
+		}
+	}
+
+	if b.headerList.Len() == 0 {
+		b.handleHeaderListDrained()
+	}
+}
+
+// handleHeaderListDrained is invoked during headers-first synchronization once
+// every header in the header list has had its block connected.  When the
+// header chain was paused at a header signed by a validate key which was not
+// yet known to be authorized, headers are requested again from the new best
+// chain tip so the header can be checked against the updated admin state.
+// When the sync peer has no more headers, headers-first mode is exited and
+// any remaining blocks are requested via the regular inventory based sync.
+func (b *blockManager) handleHeaderListDrained() {
+	// The blocks of all headers in the list are connected, so any block
+	// still marked as in flight belongs to a discarded header list and
+	// would otherwise count against the in flight limit of its peer.
+	b.inFlightBlocks = make(map[chainhash.Hash]*inFlightBlock)
+
+	if b.syncPeer == nil || (!b.headersBlocked && !b.headersSynced) {
+		return
+	}
+
+	best := b.chain.BestSnapshot()
+	locator, err := b.chain.LatestBlockLocator()
+	if err != nil {
+		bmgrLog.Errorf("Failed to get block locator for the latest "+
+			"block: %v", err)
+		return
+	}
+
+	if b.headersBlocked {
+		b.resetHeaderList(&headerNode{height: best.Height, hash: best.Hash})
+		b.syncPeer.PushGetHeadersMsg(locator, &zeroHash)
+		return
+	}
+
+	bmgrLog.Infof("Headers-first synchronization complete at height %d",
+		best.Height)
+	b.headersFirstMode = false
+	b.headerList.Init()
+	b.downloadedBlocks = make(map[chainhash.Hash]*blockMsg)
+	b.syncPeer.PushGetBlocksMsg(locator, &zeroHash)
+}
+
+// handleLoadSnapshotMsg bootstraps the chain state from the utxo set snapshot
+// at the passed path.  Since the best chain jumps ahead to the snapshot block,
+// any headers-first synchronization in progress is restarted from there.
+func (b *blockManager) handleLoadSnapshotMsg(path string) (*blockchain.SnapshotInfo, error) {
+	// The optional indexes which have to be requested explicitly require
+	// all blocks to be available.  The committed filter index is simply no
+	// longer maintained.
+	if cfg.TxIndex || cfg.AddrIndex || cfg.KeyIDIndex {
+		return nil, errors.New("utxo set snapshots can't be loaded " +
+			"with the transaction, address, or keyID index enabled")
+	}
+	info, err := loadUtxoSnapshotFile(b.chain, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.headersFirstMode {
+		best := b.chain.BestSnapshot()
+		b.resetHeaderList(&headerNode{height: best.Height, hash: best.Hash})
+		b.downloadedBlocks = make(map[chainhash.Hash]*blockMsg)
+		if b.syncPeer != nil {
+			locator := blockchain.BlockLocator([]*chainhash.Hash{best.Hash})
+			b.syncPeer.PushGetHeadersMsg(locator, &zeroHash)
+		}
+	}
+
+	return info, nil
+}
+
+// fetchHeaderBlocks requests the blocks for the headers in the header list
+// which are within the download window and not yet downloaded or in flight.
+// The requests are spread over all of the candidate peers which claim to have
+// the blocks, limiting the number of blocks in flight from any single peer.
+func (b *blockManager) fetchHeaderBlocks(peers *list.List) {
+	inFlight := make(map[*serverPeer]int)
+	for _, req := range b.inFlightBlocks {
+		inFlight[req.peer]++
+	}
+
+	best := b.chain.BestSnapshot()
+	maxHeight := best.Height + blockDownloadWindow
+	gdmsgs := make(map[*serverPeer]*wire.MsgGetData)
+	for e := b.headerList.Front(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.height > maxHeight {
+			break
+		}
+		if _, exists := b.inFlightBlocks[*node.hash]; exists {
+			continue
+		}
+		if _, exists := b.downloadedBlocks[*node.hash]; exists {
+			continue
+		}
+
+		// Choose the least busy peer which claims to have the block.
+		// The sync peer provided the header, so it is assumed to have
+		// the block regardless of the height it reported.
+		var sp *serverPeer
+		for pe := peers.Front(); pe != nil; pe = pe.Next() {
+			candidate := pe.Value.(*serverPeer)
+			if inFlight[candidate] >= maxInFlightBlocksPerPeer {
+				continue
+			}
+			if candidate != b.syncPeer &&
+				candidate.LastBlock() < node.height {
+				continue
+			}
+			if sp == nil || inFlight[candidate] < inFlight[sp] {
+				sp = candidate
+			}
+		}
+		if sp == nil {
+			break
+		}
+
+		b.inFlightBlocks[*node.hash] = &inFlightBlock{
+			peer:      sp,
+			requested: time.Now(),
+		}
+		b.requestedBlocks[*node.hash] = struct{}{}
+		sp.requestedBlocks[*node.hash] = struct{}{}
+		inFlight[sp]++
+
+		gdmsg, exists := gdmsgs[sp]
+		if !exists {
+			gdmsg = wire.NewMsgGetData()
+			gdmsgs[sp] = gdmsg
+		}
+		gdmsg.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, node.hash))
+	}
+
+	for sp, gdmsg := range gdmsgs {
+		sp.QueueMessage(gdmsg, nil)
+	}
+}
+
+// checkHeaderProvisional performs the checks which are possible on a header
+// whose parent is itself only known by its header.  The header must follow the
+// header tip, be sane, and be signed by the validate key it claims.  The
+// returned bool indicates whether the validate key is part of the validate key
+// set as of the current best chain.  Since the admin transactions which change
+// the validate key set are only known once their blocks are connected, a key
+// which is not yet authorized does not mean the header is invalid, only that
+// it can not be trusted until the blocks before it are connected.
+func (b *blockManager) checkHeaderProvisional(header *wire.BlockHeader) (bool, error) {
+	if header.Height != b.headerTip.height+1 {
+		return false, fmt.Errorf("header height %d does not follow "+
+			"header tip height %d", header.Height, b.headerTip.height)
+	}
+
+	err := blockchain.CheckBlockHeaderSanity(header,
+		b.server.chainParams.PowLimit, b.server.timeSource)
+	if err != nil {
+		return false, err
+	}
+
+	pubKey, err := btcec.ParsePubKey(header.ValidatingPubKey[:],
+		btcec.S256())
+	if err != nil {
+		return false, err
+	}
+	if !header.Verify(pubKey) {
+		return false, fmt.Errorf("unable to validate signature of "+
+			"header %v", header.BlockHash())
+	}
+
+	validateKeySet := b.chain.AdminKeySets()[btcec.ValidateKeySet]
+	return len(validateKeySet) == 0 || validateKeySet.Pos(pubKey) != -1, nil
+}
+
+// handleHeadersMsg handles headers messages from all peers.  Headers are only
+// accepted from the sync peer during headers-first synchronization.  Each
+// header must extend the header chain and be signed by an authorized validate
+// key.  The validate key of a header whose parent block is known is checked
+// against the admin state as of that block.  The remaining headers can only be
+// checked against the admin state of the current best chain, so the header
+// chain is paused at the first header signed by a key which is not authorized
+// yet until the blocks before it are connected.
+func (b *blockManager) handleHeadersMsg(peers *list.List, hmsg *headersMsg) {
+	// Ignore headers from peers other than the sync peer and when not in
+	// headers-first mode.
+	if !b.headersFirstMode || hmsg.peer != b.syncPeer {
+		bmgrLog.Debugf("Ignoring %d unrequested headers from %s",
+			len(hmsg.headers.Headers), hmsg.peer)
+		return
+	}
+
+	msg := hmsg.headers
+	numHeaders := len(msg.Headers)
+	for _, blockHeader := range msg.Headers {
+		blockHash := blockHeader.BlockHash()
+
+		// Headers which do not extend the header tip must build on a
+		// block which is already known, in which case the header
+		// chain is restarted from that block.
+		if blockHeader.PrevBlock != *b.headerTip.hash {
+			haveParent, err := b.chain.HaveBlock(&blockHeader.PrevBlock)
+			if err != nil || !haveParent || blockHeader.Height == 0 {
+				bmgrLog.Warnf("Received block header %v from %s "+
+					"that does not connect -- disconnecting",
+					blockHash, hmsg.peer)
+				hmsg.peer.Disconnect()
+				return
+			}
+			b.resetHeaderList(&headerNode{
+				height: blockHeader.Height - 1,
+				hash:   &blockHeader.PrevBlock,
+			})
+		}
+
+		// Skip headers of blocks which are already known.
+		if b.headerList.Len() == 0 {
+			haveBlock, err := b.chain.HaveBlock(&blockHash)
+			if err != nil {
+				bmgrLog.Errorf("Failed to check for block %v: %v",
+					blockHash, err)
+				return
+			}
+			if haveBlock {
+				b.headerTip = &headerNode{
+					height: blockHeader.Height,
+					hash:   &blockHash,
+				}
+				continue
+			}
+		}
+
+		// The header can be fully validated when its parent block is
+		// known.  Otherwise only the provisional checks are possible.
+		if b.headerList.Len() == 0 {
+			err := b.chain.CheckBlockHeader(blockHeader)
+			if err != nil {
+				bmgrLog.Warnf("Rejected block header %v from %s: "+
+					"%v -- disconnecting", blockHash,
+					hmsg.peer, err)
+				hmsg.peer.Disconnect()
+				return
+			}
+		} else {
+			authorized, err := b.checkHeaderProvisional(blockHeader)
+			if err != nil {
+				bmgrLog.Warnf("Rejected block header %v from %s: "+
+					"%v -- disconnecting", blockHash,
+					hmsg.peer, err)
+				hmsg.peer.Disconnect()
+				return
+			}
+			if !authorized {
+				bmgrLog.Debugf("Block header %v is signed by a "+
+					"validate key which is not authorized as "+
+					"of the best chain -- waiting for the "+
+					"blocks up to height %d", blockHash,
+					b.headerTip.height)
+				b.headersBlocked = true
+				break
+			}
+		}
+
+		node := &headerNode{height: blockHeader.Height, hash: &blockHash}
+		b.headerList.PushBack(node)
+		b.headerTip = node
+
+		if cfg.assumeValid != nil && blockHash == *cfg.assumeValid {
+			bmgrLog.Infof("Assuming valid scripts for blocks up to "+
+				"height %d (%v)", node.height, node.hash)
+			b.assumeValidHeight = node.height
+		}
+	}
+
+	// Request the next batch of headers when the peer sent the maximum
+	// number of headers, since it likely has more.  Otherwise the peer has
+	// no more headers, so headers-first mode will be exited once all of
+	// the blocks are connected.
+	if !b.headersBlocked {
+		if numHeaders == wire.MaxBlockHeadersPerMsg {
+			locator := blockchain.BlockLocator(
+				[]*chainhash.Hash{b.headerTip.hash})
+			err := hmsg.peer.PushGetHeadersMsg(locator, &zeroHash)
+			if err != nil {
+				bmgrLog.Warnf("Failed to send getheaders message "+
+					"to peer %s: %v", hmsg.peer.Addr(), err)
+				return
+			}
+		} else {
+			b.headersSynced = true
+		}
+	}
+
+	if b.headerList.Len() == 0 {
+		b.handleHeaderListDrained()
+		return
+	}
+	b.fetchHeaderBlocks(peers)
+}
+
+// handleStallSample checks for blocks requested during headers-first
+// synchronization which have been outstanding for too long.  Peers which fail
+// to deliver a block in time are disconnected so the blocks are requested from
+// the remaining peers, which prevents a single slow peer from stalling the
+// download window.
+func (b *blockManager) handleStallSample() {
+	if !b.headersFirstMode {
+		return
+	}
+
+	now := time.Now()
+	stalled := make(map[*serverPeer]struct{})
+	for _, req := range b.inFlightBlocks {
+		if now.Sub(req.requested) > blockStallTimeout {
+			stalled[req.peer] = struct{}{}
+		}
+	}
+	for sp := range stalled {
+		bmgrLog.Infof("Peer %s stalled block download -- disconnecting",
+			sp)
+		sp.Disconnect()
+	}
+}
+
 // haveInventory returns whether or not the inventory represented by the passed
 // inventory vector is known.  This includes checking all of the various places
 // inventory can be when it is in different states such as blocks that are part
@@ -565,6 +1370,11 @@ func (b *blockManager) handleInvMsg(imsg *invMsg) {
 		// for the peer.
 		imsg.peer.AddKnownInventory(iv)
 
+		// Ignore inventory when we're in headers-first mode.
+		if b.headersFirstMode {
+			continue
+		}
+
 		// Request the inventory if we don't already have it.
 		haveInv, err := b.haveInventory(iv)
 		if err != nil {
@@ -698,6 +1508,8 @@ func (b *blockManager) limitMap(m map[chainhash.Hash]struct{}, limit int) {
 // the fetching should proceed.
 func (b *blockManager) blockHandler() {
 	candidatePeers := list.New()
+	stallTicker := time.NewTicker(stallSampleInterval)
+	defer stallTicker.Stop()
 out:
 	for {
 		select {
@@ -711,9 +1523,18 @@ out:
 				msg.peer.txProcessed <- struct{}{}
 
 			case *blockMsg:
-				b.handleBlockMsg(msg)
+				b.handleBlockMsg(candidatePeers, msg)
 				msg.peer.blockProcessed <- struct{}{}
 
+			case *headersMsg:
+				b.handleHeadersMsg(candidatePeers, msg)
+
+			case *cmpctBlockMsg:
+				b.handleCmpctBlockMsg(candidatePeers, msg)
+
+			case *blockTxnMsg:
+				b.handleBlockTxnMsg(candidatePeers, msg)
+
 			case *invMsg:
 				b.handleInvMsg(msg)
 
@@ -723,6 +1544,9 @@ out:
 			case getSyncPeerMsg:
 				msg.reply <- b.syncPeer
 
+			case getHeaderHeightMsg:
+				msg.reply <- b.headerHeight()
+
 			case processBlockMsg:
 				_, isOrphan, err := b.chain.ProcessBlock(
 					msg.block, msg.flags)
@@ -746,6 +1570,13 @@ out:
 					err:      nil,
 				}
 
+			case loadSnapshotMsg:
+				info, err := b.handleLoadSnapshotMsg(msg.path)
+				msg.reply <- loadSnapshotResponse{
+					info: info,
+					err:  err,
+				}
+
 			case isCurrentMsg:
 				msg.reply <- b.current()
 
@@ -758,6 +1589,9 @@ out:
 					"handler: %T", msg)
 			}
 
+		case <-stallTicker.C:
+			b.handleStallSample()
+
 		case <-b.quit:
 			break out
 		}
@@ -788,7 +1622,7 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 
 		// Generate the inventory vector and relay it.
 		iv := wire.NewInvVect(wire.InvTypeBlock, block.Hash())
-		b.server.RelayInventory(iv, block.MsgBlock().Header)
+		b.server.RelayInventory(iv, block)
 
 	// A block has been connected to the main block chain.
 	case blockchain.NTBlockConnected:
@@ -813,6 +1647,15 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 			b.server.AnnounceNewTransactions(acceptedTxs)
 		}
 
+		// Register the block with the fee estimator.  This only fails
+		// when the estimator missed a block, which it recovers from on
+		// its own, so the error is only logged.
+		err := b.server.feeEstimator.RegisterBlock(block)
+		if err != nil {
+			bmgrLog.Debugf("Failed to register block %v with the "+
+				"fee estimator: %v", block.Hash(), err)
+		}
+
 		if r := b.server.rpcServer; r != nil {
 			// Now that this block is in the blockchain we can mark
 			// all the transactions (except the coinbase) as no
@@ -847,6 +1690,13 @@ func (b *blockManager) handleNotifyMsg(notification *blockchain.Notification) {
 			}
 		}
 
+		// Rollback the previous block recorded by the fee estimator.
+		err := b.server.feeEstimator.Rollback(block.Hash())
+		if err != nil {
+			bmgrLog.Debugf("Failed to roll back block %v from the "+
+				"fee estimator: %v", block.Hash(), err)
+		}
+
 		// Notify registered websocket clients.
 		if r := b.server.rpcServer; r != nil {
 			r.ntfnMgr.NotifyBlockDisconnected(block)
@@ -897,6 +1747,42 @@ func (b *blockManager) QueueInv(inv *wire.MsgInv, sp *serverPeer) {
 	b.msgChan <- &invMsg{inv: inv, peer: sp}
 }
 
+// QueueHeaders adds the passed headers message and peer to the block handling
+// queue.
+func (b *blockManager) QueueHeaders(headers *wire.MsgHeaders, sp *serverPeer) {
+	// No channel handling here because peers do not need to block on
+	// headers messages.
+	if atomic.LoadInt32(&b.shutdown) != 0 {
+		return
+	}
+
+	b.msgChan <- &headersMsg{headers: headers, peer: sp}
+}
+
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue.
+func (b *blockManager) QueueCmpctBlock(cmpctBlock *wire.MsgCmpctBlock, sp *serverPeer) {
+	// No channel handling here because peers do not need to block on
+	// cmpctblock messages.
+	if atomic.LoadInt32(&b.shutdown) != 0 {
+		return
+	}
+
+	b.msgChan <- &cmpctBlockMsg{cmpctBlock: cmpctBlock, peer: sp}
+}
+
+// QueueBlockTxn adds the passed blocktxn message and peer to the block
+// handling queue.
+func (b *blockManager) QueueBlockTxn(blockTxn *wire.MsgBlockTxn, sp *serverPeer) {
+	// No channel handling here because peers do not need to block on
+	// blocktxn messages.
+	if atomic.LoadInt32(&b.shutdown) != 0 {
+		return
+	}
+
+	b.msgChan <- &blockTxnMsg{blockTxn: blockTxn, peer: sp}
+}
+
 // DonePeer informs the blockmanager that a peer has disconnected.
 func (b *blockManager) DonePeer(sp *serverPeer) {
 	// Ignore if we are shutting down.
@@ -941,6 +1827,24 @@ func (b *blockManager) SyncPeer() *serverPeer {
 	return <-reply
 }
 
+// headerHeight returns the height of the best known header, which is the tip
+// of the header chain downloaded during headers-first synchronization when it
+// is ahead of the best block chain and the height of the best block otherwise.
+func (b *blockManager) headerHeight() uint32 {
+	height := b.chain.BestSnapshot().Height
+	if b.headerTip != nil && b.headerTip.height > height {
+		height = b.headerTip.height
+	}
+	return height
+}
+
+// HeaderHeight returns the height of the best known header.
+func (b *blockManager) HeaderHeight() uint32 {
+	reply := make(chan uint32)
+	b.msgChan <- getHeaderHeightMsg{reply: reply}
+	return <-reply
+}
+
 // ProcessBlock makes use of ProcessBlock on an internal instance of a block
 // chain.  It is funneled through the block manager since btcchain is not safe
 // for concurrent access.
@@ -951,6 +1855,16 @@ func (b *blockManager) ProcessBlock(block *provautil.Block, flags blockchain.Beh
 	return response.isOrphan, response.err
 }
 
+// LoadUtxoSnapshot bootstraps the chain state from the utxo set snapshot at the
+// passed path.  It is funneled through the block manager since the sync state
+// needs to be reset to the new best chain.
+func (b *blockManager) LoadUtxoSnapshot(path string) (*blockchain.SnapshotInfo, error) {
+	reply := make(chan loadSnapshotResponse, 1)
+	b.msgChan <- loadSnapshotMsg{path: path, reply: reply}
+	response := <-reply
+	return response.info, response.err
+}
+
 // IsCurrent returns whether or not the block manager believes it is synced with
 // the connected peers.
 func (b *blockManager) IsCurrent() bool {
@@ -1026,13 +1940,18 @@ func mergeCheckpoints(defaultCheckpoints, additional []chaincfg.Checkpoint) []ch
 // Use Start to begin processing asynchronous block and inv updates.
 func newBlockManager(s *server, indexManager blockchain.IndexManager) (*blockManager, error) {
 	bm := blockManager{
-		server:          s,
-		rejectedTxns:    make(map[chainhash.Hash]struct{}),
-		requestedTxns:   make(map[chainhash.Hash]struct{}),
-		requestedBlocks: make(map[chainhash.Hash]struct{}),
-		progressLogger:  newBlockProgressLogger("Processed", bmgrLog),
-		msgChan:         make(chan interface{}, cfg.MaxPeers*3),
-		quit:            make(chan struct{}),
+		server:           s,
+		rejectedTxns:     make(map[chainhash.Hash]struct{}),
+		requestedTxns:    make(map[chainhash.Hash]struct{}),
+		requestedBlocks:  make(map[chainhash.Hash]struct{}),
+		progressLogger:   newBlockProgressLogger("Processed", bmgrLog),
+		msgChan:          make(chan interface{}, cfg.MaxPeers*3),
+		quit:             make(chan struct{}),
+		headerList:       list.New(),
+		inFlightBlocks:   make(map[chainhash.Hash]*inFlightBlock),
+		downloadedBlocks: make(map[chainhash.Hash]*blockMsg),
+
+		pendingCmpctBlocks: make(map[chainhash.Hash]*pendingCmpctBlock),
 	}
 
 	// Merge given checkpoints with the default ones unless they are disabled.
@@ -1042,21 +1961,52 @@ func newBlockManager(s *server, indexManager blockchain.IndexManager) (*blockMan
 	// Create a new block chain instance with the appropriate configuration.
 	var err error
 	bm.chain, err = blockchain.New(&blockchain.Config{
-		DB:            s.db,
-		ChainParams:   s.chainParams,
-		Checkpoints:   checkpoints,
-		TimeSource:    s.timeSource,
-		Notifications: bm.handleNotifyMsg,
-		SigCache:      s.sigCache,
-		IndexManager:  indexManager,
+		DB:                s.db,
+		ChainParams:       s.chainParams,
+		Checkpoints:       checkpoints,
+		TimeSource:        s.timeSource,
+		Notifications:     bm.handleNotifyMsg,
+		SigCache:          s.sigCache,
+		IndexManager:      indexManager,
+		AssumeValid:       cfg.assumeValid,
+		PruneTarget:       cfg.Prune * 1024 * 1024,
+		ReindexChainState: cfg.Reindex || cfg.ReindexChainState,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	// Bootstrap the chain state from a utxo set snapshot when requested.
+	// This only applies to a chain which does not have any blocks yet, so
+	// the option is ignored on subsequent starts.
+	if cfg.LoadSnapshot != "" {
+		if bm.chain.BestSnapshot().Height != 0 {
+			bmgrLog.Infof("Chain already has blocks -- ignoring "+
+				"snapshot %s", cfg.LoadSnapshot)
+		} else {
+			_, err := loadUtxoSnapshotFile(bm.chain, cfg.LoadSnapshot)
+			if err != nil {
+				return nil, fmt.Errorf("unable to load snapshot "+
+					"%s: %v", cfg.LoadSnapshot, err)
+			}
+		}
+	}
+
 	return &bm, nil
 }
 
+// loadUtxoSnapshotFile bootstraps the chain state of the passed chain from the
+// utxo set snapshot file at the passed path.
+func loadUtxoSnapshotFile(chain *blockchain.BlockChain, path string) (*blockchain.SnapshotInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return chain.LoadUtxoSnapshot(bufio.NewReader(f))
+}
+
 // removeRegressionDB removes the existing regression test database if running
 // in regression test mode and it already exists.
 func removeRegressionDB(dbPath string) error {
@@ -1156,8 +2106,14 @@ func loadBlockDB() (database.DB, error) {
 	// each run, so remove it now if it already exists.
 	removeRegressionDB(dbPath)
 
+	// Rebuild the index of the stored blocks from the block storage when a
+	// full reindex was requested.
 	btcdLog.Infof("Loading block database from '%s'", dbPath)
-	db, err := database.Open(cfg.DbType, dbPath, activeNetParams.Net)
+	openDB := database.Open
+	if cfg.Reindex {
+		openDB = database.Reindex
+	}
+	db, err := openDB(cfg.DbType, dbPath, activeNetParams.Net)
 	if err != nil {
 		// Return the error if it's not because the database doesn't
 		// exist.
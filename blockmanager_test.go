@@ -0,0 +1,446 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/peer"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+// newTestBlockManager returns a block manager for a new chain which only
+// contains the regression test genesis block along with a teardown function
+// the caller should invoke when done testing to clean up.
+func newTestBlockManager(t *testing.T) (*blockManager, func()) {
+	dbPath, err := ioutil.TempDir("", "blockmanager")
+	if err != nil {
+		t.Fatalf("Failed creating a temporary directory: %v", err)
+	}
+	params := &chaincfg.RegressionNetParams
+	db, err := database.Create(defaultDbType, filepath.Join(dbPath, "db"),
+		params.Net)
+	if err != nil {
+		os.RemoveAll(dbPath)
+		t.Fatalf("Failed to create db: %v", err)
+	}
+	teardown := func() {
+		db.Close()
+		os.RemoveAll(dbPath)
+	}
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: params,
+		TimeSource:  blockchain.NewMedianTime(),
+	})
+	if err != nil {
+		teardown()
+		t.Fatalf("Failed to create chain instance: %v", err)
+	}
+
+	// The sync logic consults the configuration for the regression test
+	// mode, so make sure the defaults are in place.
+	origCfg := cfg
+	cfg = &config{}
+	return &blockManager{
+		chain:            chain,
+		rejectedTxns:     make(map[chainhash.Hash]struct{}),
+		requestedTxns:    make(map[chainhash.Hash]struct{}),
+		requestedBlocks:  make(map[chainhash.Hash]struct{}),
+		progressLogger:   newBlockProgressLogger("Processed", bmgrLog),
+		headerList:       list.New(),
+		inFlightBlocks:   make(map[chainhash.Hash]*inFlightBlock),
+		downloadedBlocks: make(map[chainhash.Hash]*blockMsg),
+
+		pendingCmpctBlocks: make(map[chainhash.Hash]*pendingCmpctBlock),
+	}, func() {
+		cfg = origCfg
+		teardown()
+	}
+}
+
+// newTestSyncPeer returns a server peer which is not connected and claims to
+// have a chain of the passed height.  Messages queued to it are discarded.
+func newTestSyncPeer(t *testing.T, lastBlock uint32) *serverPeer {
+	p, err := peer.NewOutboundPeer(&peer.Config{
+		ChainParams: &chaincfg.RegressionNetParams,
+	}, "127.0.0.1:18444")
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: unexpected error: %v", err)
+	}
+	p.UpdateLastBlockHeight(lastBlock)
+	sp := newServerPeer(nil, false)
+	sp.Peer = p
+	return sp
+}
+
+// TestStartSyncHeadersFirst ensures selecting a sync peer which is ahead of
+// the chain enters headers-first mode from the best chain tip and forgets the
+// blocks which were in flight for the previous sync peer.
+func TestStartSyncHeadersFirst(t *testing.T) {
+	bm, teardown := newTestBlockManager(t)
+	defer teardown()
+
+	oldPeer := newTestSyncPeer(t, 5)
+	staleHash := chainhash.Hash{0x01}
+	bm.inFlightBlocks[staleHash] = &inFlightBlock{
+		peer:      oldPeer,
+		requested: time.Now(),
+	}
+	bm.requestedBlocks[staleHash] = struct{}{}
+
+	peers := list.New()
+	sp := newTestSyncPeer(t, 10)
+	peers.PushBack(sp)
+	bm.startSync(peers)
+
+	best := bm.chain.BestSnapshot()
+	if bm.syncPeer != sp {
+		t.Fatalf("startSync: unexpected sync peer -- got %v, want %v",
+			bm.syncPeer, sp)
+	}
+	if !bm.headersFirstMode {
+		t.Fatal("startSync: headers-first mode not entered")
+	}
+	if bm.headerTip == nil || bm.headerTip.height != best.Height ||
+		*bm.headerTip.hash != *best.Hash {
+
+		t.Fatalf("startSync: unexpected header tip -- got %v, want "+
+			"%v (%d)", bm.headerTip, best.Hash, best.Height)
+	}
+	if len(bm.inFlightBlocks) != 0 {
+		t.Fatalf("startSync: %d blocks still in flight",
+			len(bm.inFlightBlocks))
+	}
+	if len(bm.requestedBlocks) != 0 {
+		t.Fatalf("startSync: %d blocks still requested",
+			len(bm.requestedBlocks))
+	}
+
+	// A peer which is not ahead of the chain is synced from directly.
+	bm.syncPeer = nil
+	peers.Init()
+	peers.PushBack(newTestSyncPeer(t, best.Height))
+	bm.startSync(peers)
+	if bm.headersFirstMode {
+		t.Fatal("startSync: headers-first mode entered for a peer " +
+			"which is not ahead")
+	}
+}
+
+// TestSyncPeerSwitch ensures losing the sync peer during headers-first
+// synchronization switches to the next candidate and clears the blocks which
+// were in flight.
+func TestSyncPeerSwitch(t *testing.T) {
+	bm, teardown := newTestBlockManager(t)
+	defer teardown()
+
+	peers := list.New()
+	sp1 := newTestSyncPeer(t, 20)
+	sp2 := newTestSyncPeer(t, 10)
+	peers.PushBack(sp1)
+	peers.PushBack(sp2)
+	bm.startSync(peers)
+	if bm.syncPeer != sp1 {
+		t.Fatalf("startSync: unexpected sync peer -- got %v, want %v",
+			bm.syncPeer, sp1)
+	}
+
+	hash := chainhash.Hash{0x02}
+	bm.inFlightBlocks[hash] = &inFlightBlock{
+		peer:      sp2,
+		requested: time.Now(),
+	}
+	bm.handleDonePeerMsg(peers, sp1)
+	if bm.syncPeer != sp2 {
+		t.Fatalf("handleDonePeerMsg: unexpected sync peer -- got %v, "+
+			"want %v", bm.syncPeer, sp2)
+	}
+	if !bm.headersFirstMode {
+		t.Fatal("handleDonePeerMsg: headers-first mode not entered")
+	}
+	if len(bm.inFlightBlocks) != 0 {
+		t.Fatalf("handleDonePeerMsg: %d blocks still in flight",
+			len(bm.inFlightBlocks))
+	}
+}
+
+// TestHeaderListDrained ensures the headers-first state transitions once all
+// blocks of the header list are connected.
+func TestHeaderListDrained(t *testing.T) {
+	tests := []struct {
+		name             string
+		headersBlocked   bool
+		headersSynced    bool
+		headersFirstMode bool
+	}{
+		{
+			name:             "more headers available",
+			headersFirstMode: true,
+		},
+		{
+			name:             "header chain blocked",
+			headersBlocked:   true,
+			headersFirstMode: true,
+		},
+		{
+			name:             "header chain synced",
+			headersSynced:    true,
+			headersFirstMode: false,
+		},
+	}
+
+	for _, test := range tests {
+		bm, teardown := newTestBlockManager(t)
+		peers := list.New()
+		sp := newTestSyncPeer(t, 10)
+		peers.PushBack(sp)
+		bm.startSync(peers)
+
+		hash := chainhash.Hash{0x03}
+		bm.inFlightBlocks[hash] = &inFlightBlock{
+			peer:      sp,
+			requested: time.Now(),
+		}
+		bm.headersBlocked = test.headersBlocked
+		bm.headersSynced = test.headersSynced
+		bm.handleHeaderListDrained()
+
+		if bm.headersFirstMode != test.headersFirstMode {
+			t.Errorf("%s: unexpected headers-first mode -- got %v, "+
+				"want %v", test.name, bm.headersFirstMode,
+				test.headersFirstMode)
+		}
+		if bm.headersBlocked {
+			t.Errorf("%s: header chain still blocked", test.name)
+		}
+		if len(bm.inFlightBlocks) != 0 {
+			t.Errorf("%s: %d blocks still in flight", test.name,
+				len(bm.inFlightBlocks))
+		}
+		if bm.headerList.Len() != 0 {
+			t.Errorf("%s: %d headers still in the header list",
+				test.name, bm.headerList.Len())
+		}
+		teardown()
+	}
+}
+
+// TestHeaderHeight ensures the height of the best known header follows the
+// header chain while it is ahead of the best block chain.
+func TestHeaderHeight(t *testing.T) {
+	bm, teardown := newTestBlockManager(t)
+	defer teardown()
+
+	best := bm.chain.BestSnapshot()
+	if height := bm.headerHeight(); height != best.Height {
+		t.Fatalf("headerHeight: unexpected height -- got %d, want %d",
+			height, best.Height)
+	}
+
+	peers := list.New()
+	peers.PushBack(newTestSyncPeer(t, 10))
+	bm.startSync(peers)
+	bm.headerTip = &headerNode{height: best.Height + 5,
+		hash: &chainhash.Hash{0x04}}
+	if height := bm.headerHeight(); height != best.Height+5 {
+		t.Fatalf("headerHeight: unexpected height -- got %d, want %d",
+			height, best.Height+5)
+	}
+}
+
+// newTestCmpctBlock returns a block with a coinbase and the passed number of
+// other transactions along with a compact block for it in which only the
+// coinbase is prefilled.
+func newTestCmpctBlock(numTxns int) (*wire.MsgBlock, *wire.MsgCmpctBlock) {
+	genesis := chaincfg.RegressionNetParams.GenesisBlock
+	block := wire.NewMsgBlock(&genesis.Header)
+	block.AddTransaction(genesis.Transactions[0])
+	for i := 0; i < numTxns; i++ {
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		msgTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: uint32(i)}, nil))
+		msgTx.AddTxOut(wire.NewTxOut(int64(i+1), nil))
+		block.AddTransaction(msgTx)
+	}
+	merkles := blockchain.BuildMerkleTreeStore(
+		provautil.NewBlock(block).Transactions())
+	block.Header.MerkleRoot = *merkles[len(merkles)-1]
+	return block, wire.NewMsgCmpctBlock(block, 0x0123456789abcdef)
+}
+
+// TestReconstructCmpctBlock ensures compact blocks are reconstructed from the
+// memory pool transactions, and that transactions which are not in the pool or
+// whose short IDs are ambiguous are reported as missing.
+func TestReconstructCmpctBlock(t *testing.T) {
+	block, msg := newTestCmpctBlock(3)
+	txns := block.Transactions
+	tests := []struct {
+		name     string
+		poolTxns []*wire.MsgTx
+		missing  []uint32
+	}{
+		{
+			name:     "all transactions in the pool",
+			poolTxns: []*wire.MsgTx{txns[3], txns[1], txns[2]},
+		},
+		{
+			name:     "transaction not in the pool",
+			poolTxns: []*wire.MsgTx{txns[1], txns[3]},
+			missing:  []uint32{2},
+		},
+		{
+			name: "short id collision",
+			poolTxns: []*wire.MsgTx{txns[1], txns[2], txns[2],
+				txns[3]},
+			missing: []uint32{2},
+		},
+		{
+			name:    "empty pool",
+			missing: []uint32{1, 2, 3},
+		},
+	}
+	for _, test := range tests {
+		msgBlock, missing, err := reconstructCmpctBlock(msg,
+			test.poolTxns)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(missing, test.missing) {
+			t.Errorf("%s: unexpected missing transactions -- got "+
+				"%v, want %v", test.name, missing, test.missing)
+			continue
+		}
+		if msgBlock.BlockHash() != block.BlockHash() ||
+			len(msgBlock.Transactions) != len(txns) {
+
+			t.Errorf("%s: unexpected block %v", test.name,
+				msgBlock.BlockHash())
+			continue
+		}
+		for i, msgTx := range msgBlock.Transactions {
+			want := txns[i]
+			for _, index := range test.missing {
+				if index == uint32(i) {
+					want = nil
+				}
+			}
+			if msgTx != want {
+				t.Errorf("%s: unexpected transaction #%d",
+					test.name, i)
+			}
+		}
+	}
+
+	// Prefilled transactions with an index beyond the transactions of the
+	// block are rejected.
+	msg.PrefilledTxs[0].Index = uint32(msg.TotalTransactions())
+	if _, _, err := reconstructCmpctBlock(msg, nil); err == nil {
+		t.Error("reconstructCmpctBlock: did not reject a prefilled " +
+			"transaction index out of range")
+	}
+}
+
+// TestHandleBlockTxn ensures the transactions delivered for a pending compact
+// block complete its reconstruction, and that the full block is requested
+// instead when the wrong transactions are delivered.
+func TestHandleBlockTxn(t *testing.T) {
+	bm, teardown := newTestBlockManager(t)
+	defer teardown()
+
+	block, msg := newTestCmpctBlock(3)
+	blockHash := block.BlockHash()
+	sp := newTestSyncPeer(t, 0)
+	peers := list.New()
+	peers.PushBack(sp)
+
+	// addPending reconstructs the compact block with only the first
+	// transaction in the pool and adds it to the pending compact blocks.
+	addPending := func() *pendingCmpctBlock {
+		msgBlock, missing, err := reconstructCmpctBlock(msg,
+			block.Transactions[1:2])
+		if err != nil {
+			t.Fatalf("reconstructCmpctBlock: unexpected error: %v",
+				err)
+		}
+		pending := &pendingCmpctBlock{
+			peer:    sp,
+			block:   msgBlock,
+			missing: missing,
+		}
+		bm.pendingCmpctBlocks[blockHash] = pending
+		bm.requestedBlocks = make(map[chainhash.Hash]struct{})
+		return pending
+	}
+	isFullBlockRequested := func() bool {
+		_, exists := bm.requestedBlocks[blockHash]
+		return exists
+	}
+
+	// Delivering the missing transactions completes the block.
+	pending := addPending()
+	if !pending.fill(block.Transactions[2:]) {
+		t.Fatal("fill: did not accept the missing transactions")
+	}
+	if !reflect.DeepEqual(pending.block, block) {
+		t.Fatal("fill: reconstructed block does not match the block")
+	}
+
+	// Transactions delivered by a peer which was not asked for them are
+	// ignored.
+	addPending()
+	other := newTestSyncPeer(t, 0)
+	bm.handleBlockTxnMsg(peers, &blockTxnMsg{
+		blockTxn: wire.NewMsgBlockTxn(&blockHash, block.Transactions[2:]),
+		peer:     other,
+	})
+	if bm.pendingCmpctBlocks[blockHash] == nil || isFullBlockRequested() {
+		t.Fatal("handleBlockTxnMsg: handled transactions from a peer " +
+			"which was not asked for them")
+	}
+
+	tests := []struct {
+		name string
+		txns []*wire.MsgTx
+	}{
+		{
+			name: "too few transactions",
+			txns: block.Transactions[2:3],
+		},
+		{
+			name: "too many transactions",
+			txns: block.Transactions,
+		},
+		{
+			name: "wrong transactions",
+			txns: []*wire.MsgTx{block.Transactions[3],
+				block.Transactions[2]},
+		},
+	}
+	for _, test := range tests {
+		addPending()
+		bm.handleBlockTxnMsg(peers, &blockTxnMsg{
+			blockTxn: wire.NewMsgBlockTxn(&blockHash, test.txns),
+			peer:     sp,
+		})
+		if bm.pendingCmpctBlocks[blockHash] != nil {
+			t.Errorf("%s: compact block still pending", test.name)
+		}
+		if !isFullBlockRequested() {
+			t.Errorf("%s: full block not requested", test.name)
+		}
+	}
+}
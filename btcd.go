@@ -106,7 +106,7 @@ func btcdMain(serverChan chan<- *server) error {
 	// Drop indexes and exit if requested.
 	//
 	// NOTE: The order is important here because dropping the tx index also
-	// drops the address index since it relies on it.
+	// drops the address and keyID indexes since they rely on it.
 	if cfg.DropAddrIndex {
 		if err := indexers.DropAddrIndex(db); err != nil {
 			btcdLog.Errorf("%v", err)
@@ -115,6 +115,14 @@ func btcdMain(serverChan chan<- *server) error {
 
 		return nil
 	}
+	if cfg.DropKeyIDIndex {
+		if err := indexers.DropKeyIDIndex(db); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
 	if cfg.DropTxIndex {
 		if err := indexers.DropTxIndex(db); err != nil {
 			btcdLog.Errorf("%v", err)
@@ -123,6 +131,28 @@ func btcdMain(serverChan chan<- *server) error {
 
 		return nil
 	}
+	if cfg.DropCfIndex {
+		if err := indexers.DropCfIndex(db); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
+
+	// The optional indexes are rebuilt along with the chain state when
+	// reindexing, so drop them first.  Dropping the tx index also drops the
+	// address and keyID indexes.
+	if cfg.Reindex || cfg.ReindexChainState {
+		if err := indexers.DropTxIndex(db); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+		if err := indexers.DropCfIndex(db); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+	}
 
 	// Create server and start it.
 	server, err := newServer(cfg.Listeners, db, activeNetParams.Params)
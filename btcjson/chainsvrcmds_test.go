@@ -102,6 +102,32 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"decodescript","params":["00"],"id":1}`,
 			unmarshalled: &btcjson.DecodeScriptCmd{HexScript: "00"},
 		},
+		{
+			name: "estimatefee",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("estimatefee", 6)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewEstimateFeeCmd(6)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimatefee","params":[6],"id":1}`,
+			unmarshalled: &btcjson.EstimateFeeCmd{
+				NumBlocks: 6,
+			},
+		},
+		{
+			name: "estimatesmartfee",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("estimatesmartfee", 6)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewEstimateSmartFeeCmd(6)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"estimatesmartfee","params":[6],"id":1}`,
+			unmarshalled: &btcjson.EstimateSmartFeeCmd{
+				ConfTarget: 6,
+			},
+		},
 		{
 			name: "getaddednodeinfo",
 			newCmd: func() (interface{}, error) {
@@ -328,6 +354,35 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "getcfilter",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getcfilter", "123")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetCFilterCmd("123", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getcfilter","params":["123"],"id":1}`,
+			unmarshalled: &btcjson.GetCFilterCmd{
+				Hash:       "123",
+				FilterType: btcjson.Uint32(0),
+			},
+		},
+		{
+			name: "getcfilter optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getcfilter", "123", 0)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetCFilterCmd("123",
+					btcjson.Uint32(0))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getcfilter","params":["123",0],"id":1}`,
+			unmarshalled: &btcjson.GetCFilterCmd{
+				Hash:       "123",
+				FilterType: btcjson.Uint32(0),
+			},
+		},
 		{
 			name: "getchaintips",
 			newCmd: func() (interface{}, error) {
@@ -510,7 +565,7 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("getrawmempool")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetRawMempoolCmd(nil)
+				return btcjson.NewGetRawMempoolCmd(nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"getrawmempool","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetRawMempoolCmd{
@@ -523,13 +578,28 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("getrawmempool", false)
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewGetRawMempoolCmd(btcjson.Bool(false))
+				return btcjson.NewGetRawMempoolCmd(btcjson.Bool(false), nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"getrawmempool","params":[false],"id":1}`,
 			unmarshalled: &btcjson.GetRawMempoolCmd{
 				Verbose: btcjson.Bool(false),
 			},
 		},
+		{
+			name: "getrawmempool tag",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrawmempool", true, 42)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRawMempoolCmd(btcjson.Bool(true),
+					btcjson.Uint64(42))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getrawmempool","params":[true,42],"id":1}`,
+			unmarshalled: &btcjson.GetRawMempoolCmd{
+				Verbose: btcjson.Bool(true),
+				Tag:     btcjson.Uint64(42),
+			},
+		},
 		{
 			name: "getrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -731,6 +801,17 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "123",
 			},
 		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &btcjson.SaveMempoolCmd{},
+		},
 		{
 			name: "searchrawtransactions",
 			newCmd: func() (interface{}, error) {
@@ -876,12 +957,13 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("sendrawtransaction", "1122")
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSendRawTransactionCmd("1122", nil)
+				return btcjson.NewSendRawTransactionCmd("1122", nil, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"sendrawtransaction","params":["1122"],"id":1}`,
 			unmarshalled: &btcjson.SendRawTransactionCmd{
 				HexTx:         "1122",
 				AllowHighFees: btcjson.Bool(false),
+				Tag:           btcjson.Uint64(0),
 			},
 		},
 		{
@@ -890,12 +972,29 @@ func TestChainSvrCmds(t *testing.T) {
 				return btcjson.NewCmd("sendrawtransaction", "1122", false)
 			},
 			staticCmd: func() interface{} {
-				return btcjson.NewSendRawTransactionCmd("1122", btcjson.Bool(false))
+				return btcjson.NewSendRawTransactionCmd("1122", btcjson.Bool(false), nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"sendrawtransaction","params":["1122",false],"id":1}`,
 			unmarshalled: &btcjson.SendRawTransactionCmd{
 				HexTx:         "1122",
 				AllowHighFees: btcjson.Bool(false),
+				Tag:           btcjson.Uint64(0),
+			},
+		},
+		{
+			name: "sendrawtransaction tag",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("sendrawtransaction", "1122", false, 42)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSendRawTransactionCmd("1122",
+					btcjson.Bool(false), btcjson.Uint64(42))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendrawtransaction","params":["1122",false,42],"id":1}`,
+			unmarshalled: &btcjson.SendRawTransactionCmd{
+				HexTx:         "1122",
+				AllowHighFees: btcjson.Bool(false),
+				Tag:           btcjson.Uint64(42),
 			},
 		},
 		{
@@ -970,6 +1069,56 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "submitblock signed externally",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("submitblock", "112233", `{"validatingpubkey":"0211","signature":"3044"}`)
+			},
+			staticCmd: func() interface{} {
+				options := btcjson.SubmitBlockOptions{
+					ValidatingPubKey: "0211",
+					Signature:        "3044",
+				}
+				return btcjson.NewSubmitBlockCmd("112233", &options)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"submitblock","params":["112233",{"validatingpubkey":"0211","signature":"3044"}],"id":1}`,
+			unmarshalled: &btcjson.SubmitBlockCmd{
+				HexBlock: "112233",
+				Options: &btcjson.SubmitBlockOptions{
+					ValidatingPubKey: "0211",
+					Signature:        "3044",
+				},
+			},
+		},
+		{
+			name: "testmempoolaccept",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("testmempoolaccept", []string{"1122", "3344"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewTestMempoolAcceptCmd([]string{"1122", "3344"}, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122","3344"]],"id":1}`,
+			unmarshalled: &btcjson.TestMempoolAcceptCmd{
+				RawTxs: []string{"1122", "3344"},
+				Tag:    btcjson.Uint64(0),
+			},
+		},
+		{
+			name: "testmempoolaccept tag",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("testmempoolaccept", []string{"1122"}, 42)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewTestMempoolAcceptCmd([]string{"1122"},
+					btcjson.Uint64(42))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"testmempoolaccept","params":[["1122"],42],"id":1}`,
+			unmarshalled: &btcjson.TestMempoolAcceptCmd{
+				RawTxs: []string{"1122"},
+				Tag:    btcjson.Uint64(42),
+			},
+		},
 		{
 			name: "validateaddress",
 			newCmd: func() (interface{}, error) {
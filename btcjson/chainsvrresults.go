@@ -116,16 +116,116 @@ type GetBlockChainInfoResult struct {
 	Difficulty           float64 `json:"difficulty"`
 	VerificationProgress float64 `json:"verificationprogress"`
 	ChainWork            string  `json:"chainwork"`
+	Pruned               bool    `json:"pruned"`
+	AssumeValid          string  `json:"assumevalid,omitempty"`
+	AssumeValidHeight    uint32  `json:"assumevalidheight,omitempty"`
+}
+
+// TxOutSetSnapshotResult models the data returned from the dumptxoutset and
+// loadtxoutset commands.
+type TxOutSetSnapshotResult struct {
+	Path          string `json:"path"`
+	Hash          string `json:"hash"`
+	BestBlockHash string `json:"bestblockhash"`
+	Height        uint32 `json:"height"`
+	NumUtxos      uint64 `json:"numutxos"`
+	NumHeaders    uint64 `json:"numheaders"`
+}
+
+// LoadMempoolResult models the data returned from the loadmempool command.
+type LoadMempoolResult struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+// NoticeResult models a network notice returned from the getnotices command and
+// the notice websocket notification.
+type NoticeResult struct {
+	Hash       string   `json:"hash"`
+	KeySet     string   `json:"keyset"`
+	Timestamp  int64    `json:"timestamp"`
+	Expiration int64    `json:"expiration"`
+	Priority   uint32   `json:"priority"`
+	Message    string   `json:"message"`
+	Signers    []string `json:"signers"`
+}
+
+// EstimateSmartFeeResult models the data returned from the estimatesmartfee
+// command.
+type EstimateSmartFeeResult struct {
+	FeeRate *float64 `json:"feerate,omitempty"`
+	Errors  []string `json:"errors,omitempty"`
+	Blocks  int64    `json:"blocks"`
+}
+
+// KeyIDUtxoResult models an unspent output returned from the listkeyidutxos
+// command.
+type KeyIDUtxoResult struct {
+	TxID          string  `json:"txid"`
+	Vout          uint32  `json:"vout"`
+	Amount        float64 `json:"amount"`
+	Address       string  `json:"address,omitempty"`
+	ScriptPubKey  string  `json:"scriptpubkey"`
+	Confirmations int64   `json:"confirmations"`
+}
+
+// ListKeyIDUtxosResult models the data returned from the listkeyidutxos
+// command.  The total count and value cover all of the unspent outputs which
+// are secured by the keyID, not only the returned page.
+type ListKeyIDUtxosResult struct {
+	KeyID      uint32            `json:"keyid"`
+	TotalCount uint32            `json:"totalcount"`
+	TotalValue float64           `json:"totalvalue"`
+	Utxos      []KeyIDUtxoResult `json:"utxos"`
+}
+
+// RevokeImpactAddressResult models the unspent outputs of a single address
+// which are affected by revoking an ASP key as returned from the
+// getrevokeimpact command.
+type RevokeImpactAddressResult struct {
+	Address       string            `json:"address,omitempty"`
+	ScriptPubKey  string            `json:"scriptpubkey"`
+	RequiredSigs  int32             `json:"requiredsigs"`
+	AvailableKeys int32             `json:"availablekeys"`
+	Spendable     bool              `json:"spendable"`
+	TotalCount    uint32            `json:"totalcount"`
+	TotalValue    float64           `json:"totalvalue"`
+	Utxos         []KeyIDUtxoResult `json:"utxos"`
+}
+
+// RevokeImpactResult models the data returned from the getrevokeimpact
+// command.  The totals cover all of the unspent outputs which are secured by
+// the keyID, while only the requested page of them is listed.
+type RevokeImpactResult struct {
+	KeyID            uint32                      `json:"keyid"`
+	Revoked          bool                        `json:"revoked"`
+	TotalCount       uint32                      `json:"totalcount"`
+	TotalValue       float64                     `json:"totalvalue"`
+	SpendableValue   float64                     `json:"spendablevalue"`
+	UnspendableValue float64                     `json:"unspendablevalue"`
+	Addresses        []RevokeImpactAddressResult `json:"addresses"`
+}
+
+// RecoveryTransactionResult models a transaction returned from the
+// createrecoverytransactions command.
+type RecoveryTransactionResult struct {
+	Address     string  `json:"address,omitempty"`
+	Destination string  `json:"destination"`
+	Hex         string  `json:"hex"`
+	NumInputs   int     `json:"numinputs"`
+	Amount      float64 `json:"amount"`
+	Fee         float64 `json:"fee"`
 }
 
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
-	Data    string  `json:"data"`
-	Hash    string  `json:"hash"`
-	Depends []int64 `json:"depends"`
-	Fee     int64   `json:"fee"`
-	SigOps  int64   `json:"sigops"`
+	Data     string  `json:"data"`
+	Hash     string  `json:"hash"`
+	Depends  []int64 `json:"depends"`
+	Fee      int64   `json:"fee"`
+	SigOps   int64   `json:"sigops"`
+	Required bool    `json:"required,omitempty"`
 }
 
 // GetBlockTemplateResultAux models the coinbaseaux field of the
@@ -134,6 +234,13 @@ type GetBlockTemplateResultAux struct {
 	Flags string `json:"flags"`
 }
 
+// GetBlockTemplateResultValidateKey models the validatekeys field of the
+// getblocktemplate command.
+type GetBlockTemplateResultValidateKey struct {
+	PubKey      string `json:"pubkey"`
+	RateLimited bool   `json:"ratelimited"`
+}
+
 // GetBlockTemplateResult models the data returned from the getblocktemplate
 // command.
 type GetBlockTemplateResult struct {
@@ -152,6 +259,18 @@ type GetBlockTemplateResult struct {
 	CoinbaseValue *int64                     `json:"coinbasevalue,omitempty"`
 	WorkID        string                     `json:"workid,omitempty"`
 
+	// Pending admin transactions which must directly follow the coinbase
+	// of any block built from the template.  Like the transactions, it is
+	// always present, as an empty array when there are none, since the
+	// depends indices of the transactions count the admin transactions
+	// first.
+	AdminTransactions []GetBlockTemplateResultTx `json:"admintransactions"`
+
+	// Prova header fields and the active validate keys along with whether
+	// they are currently rate limited from signing the next block.
+	Size         int64                               `json:"size"`
+	ValidateKeys []GetBlockTemplateResultValidateKey `json:"validatekeys"`
+
 	// Optional long polling from BIP 0022.
 	LongPollID  string `json:"longpollid,omitempty"`
 	LongPollURI string `json:"longpolluri,omitempty"`
@@ -194,8 +313,11 @@ type GetMempoolEntryResult struct {
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
 // command.
 type GetMempoolInfoResult struct {
-	Size  int64 `json:"size"`
-	Bytes int64 `json:"bytes"`
+	Size          int64   `json:"size"`
+	Bytes         int64   `json:"bytes"`
+	MaxMempool    int64   `json:"maxmempool"`
+	MempoolMinFee float64 `json:"mempoolminfee"`
+	MinRelayTxFee float64 `json:"minrelaytxfee"`
 }
 
 // GetNetworkInfoResult models the data returned from the getnetworkinfo
@@ -233,6 +355,8 @@ type GetPeerInfoResult struct {
 	BanScore       int32   `json:"banscore"`
 	FeeFilter      int64   `json:"feefilter"`
 	SyncNode       bool    `json:"syncnode"`
+	AuthIdentity   string  `json:"authidentity,omitempty"`
+	Encrypted      bool    `json:"encrypted"`
 }
 
 // GetRawMempoolVerboseResult models the data returned from the getrawmempool
@@ -246,6 +370,13 @@ type GetRawMempoolVerboseResult struct {
 	StartingPriority float64  `json:"startingpriority"`
 	CurrentPriority  float64  `json:"currentpriority"`
 	Depends          []string `json:"depends"`
+	DescendantCount  int64    `json:"descendantcount"`
+	DescendantSize   int64    `json:"descendantsize"`
+	DescendantFees   float64  `json:"descendantfees"`
+	AncestorCount    int64    `json:"ancestorcount"`
+	AncestorSize     int64    `json:"ancestorsize"`
+	AncestorFees     float64  `json:"ancestorfees"`
+	Tag              uint64   `json:"tag"`
 }
 
 // ScriptPubKeyResult models the scriptPubKey data of a tx script.  It is
@@ -476,6 +607,16 @@ type TxRawDecodeResult struct {
 	Vout     []Vout `json:"vout"`
 }
 
+// TestMempoolAcceptResult models the data returned for each transaction by the
+// testmempoolaccept command.
+type TestMempoolAcceptResult struct {
+	TxID         string   `json:"txid"`
+	Allowed      bool     `json:"allowed"`
+	RejectCode   uint8    `json:"rejectcode,omitempty"`
+	RejectReason string   `json:"rejectreason,omitempty"`
+	Fee          *float64 `json:"fee,omitempty"`
+}
+
 // ValidateAddressChainResult models the data returned by the chain server
 // validateaddress command.
 type ValidateAddressChainResult struct {
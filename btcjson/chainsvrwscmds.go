@@ -41,6 +41,24 @@ func NewStopNotifyBlocksCmd() *StopNotifyBlocksCmd {
 	return &StopNotifyBlocksCmd{}
 }
 
+// NotifyNoticesCmd defines the notifynotices JSON-RPC command.
+type NotifyNoticesCmd struct{}
+
+// NewNotifyNoticesCmd returns a new instance which can be used to issue a
+// notifynotices JSON-RPC command.
+func NewNotifyNoticesCmd() *NotifyNoticesCmd {
+	return &NotifyNoticesCmd{}
+}
+
+// StopNotifyNoticesCmd defines the stopnotifynotices JSON-RPC command.
+type StopNotifyNoticesCmd struct{}
+
+// NewStopNotifyNoticesCmd returns a new instance which can be used to issue a
+// stopnotifynotices JSON-RPC command.
+func NewStopNotifyNoticesCmd() *StopNotifyNoticesCmd {
+	return &StopNotifyNoticesCmd{}
+}
+
 // NotifyNewTransactionsCmd defines the notifynewtransactions JSON-RPC command.
 type NotifyNewTransactionsCmd struct {
 	Verbose *bool `jsonrpcdefault:"false"`
@@ -229,11 +247,13 @@ func init() {
 	MustRegisterCmd("loadtxfilter", (*LoadTxFilterCmd)(nil), flags)
 	MustRegisterCmd("notifyblocks", (*NotifyBlocksCmd)(nil), flags)
 	MustRegisterCmd("notifynewtransactions", (*NotifyNewTransactionsCmd)(nil), flags)
+	MustRegisterCmd("notifynotices", (*NotifyNoticesCmd)(nil), flags)
 	MustRegisterCmd("notifyreceived", (*NotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("notifyspent", (*NotifySpentCmd)(nil), flags)
 	MustRegisterCmd("session", (*SessionCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyblocks", (*StopNotifyBlocksCmd)(nil), flags)
 	MustRegisterCmd("stopnotifynewtransactions", (*StopNotifyNewTransactionsCmd)(nil), flags)
+	MustRegisterCmd("stopnotifynotices", (*StopNotifyNoticesCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyspent", (*StopNotifySpentCmd)(nil), flags)
 	MustRegisterCmd("stopnotifyreceived", (*StopNotifyReceivedCmd)(nil), flags)
 	MustRegisterCmd("rescan", (*RescanCmd)(nil), flags)
@@ -213,6 +213,28 @@ func TestChainSvrWsCmds(t *testing.T) {
 				OutPoints: []btcjson.OutPoint{{Hash: "0000000000000000000000000000000000000000000000000000000000000123", Index: 0}},
 			},
 		},
+		{
+			name: "notifynotices",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("notifynotices")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewNotifyNoticesCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"notifynotices","params":[],"id":1}`,
+			unmarshalled: &btcjson.NotifyNoticesCmd{},
+		},
+		{
+			name: "stopnotifynotices",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("stopnotifynotices")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewStopNotifyNoticesCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"stopnotifynotices","params":[],"id":1}`,
+			unmarshalled: &btcjson.StopNotifyNoticesCmd{},
+		},
 		{
 			name: "rescanblocks",
 			newCmd: func() (interface{}, error) {
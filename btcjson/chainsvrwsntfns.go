@@ -75,6 +75,10 @@ const (
 	// from the chain server that inform a client that a transaction that
 	// matches the loaded filter was accepted by the mempool.
 	RelevantTxAcceptedNtfnMethod = "relevanttxaccepted"
+
+	// NoticeNtfnMethod is the method used for notifications from the chain
+	// server that a new network notice has been received.
+	NoticeNtfnMethod = "notice"
 )
 
 // BlockConnectedNtfn defines the blockconnected JSON-RPC notification.
@@ -249,27 +253,31 @@ func NewRescanProgressNtfn(hash string, height int32, time int64) *RescanProgres
 type TxAcceptedNtfn struct {
 	TxID   string
 	Amount float64
+	Tag    *uint64
 }
 
 // NewTxAcceptedNtfn returns a new instance which can be used to issue a
 // txaccepted JSON-RPC notification.
-func NewTxAcceptedNtfn(txHash string, amount float64) *TxAcceptedNtfn {
+func NewTxAcceptedNtfn(txHash string, amount float64, tag *uint64) *TxAcceptedNtfn {
 	return &TxAcceptedNtfn{
 		TxID:   txHash,
 		Amount: amount,
+		Tag:    tag,
 	}
 }
 
 // TxAcceptedVerboseNtfn defines the txacceptedverbose JSON-RPC notification.
 type TxAcceptedVerboseNtfn struct {
 	RawTx TxRawResult
+	Tag   *uint64
 }
 
 // NewTxAcceptedVerboseNtfn returns a new instance which can be used to issue a
 // txacceptedverbose JSON-RPC notification.
-func NewTxAcceptedVerboseNtfn(rawTx TxRawResult) *TxAcceptedVerboseNtfn {
+func NewTxAcceptedVerboseNtfn(rawTx TxRawResult, tag *uint64) *TxAcceptedVerboseNtfn {
 	return &TxAcceptedVerboseNtfn{
 		RawTx: rawTx,
+		Tag:   tag,
 	}
 }
 
@@ -285,6 +293,19 @@ func NewRelevantTxAcceptedNtfn(txHex string) *RelevantTxAcceptedNtfn {
 	return &RelevantTxAcceptedNtfn{Transaction: txHex}
 }
 
+// NoticeNtfn defines the notice JSON-RPC notification.
+type NoticeNtfn struct {
+	Notice NoticeResult
+}
+
+// NewNoticeNtfn returns a new instance which can be used to issue a notice
+// JSON-RPC notification.
+func NewNoticeNtfn(notice NoticeResult) *NoticeNtfn {
+	return &NoticeNtfn{
+		Notice: notice,
+	}
+}
+
 func init() {
 	// The commands in this file are only usable by websockets and are
 	// notifications.
@@ -301,4 +322,5 @@ func init() {
 	MustRegisterCmd(TxAcceptedNtfnMethod, (*TxAcceptedNtfn)(nil), flags)
 	MustRegisterCmd(TxAcceptedVerboseNtfnMethod, (*TxAcceptedVerboseNtfn)(nil), flags)
 	MustRegisterCmd(RelevantTxAcceptedNtfnMethod, (*RelevantTxAcceptedNtfn)(nil), flags)
+	MustRegisterCmd(NoticeNtfnMethod, (*NoticeNtfn)(nil), flags)
 }
@@ -174,7 +174,7 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				return btcjson.NewCmd("txaccepted", "123", 1.5)
 			},
 			staticNtfn: func() interface{} {
-				return btcjson.NewTxAcceptedNtfn("123", 1.5)
+				return btcjson.NewTxAcceptedNtfn("123", 1.5, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"txaccepted","params":["123",1.5],"id":null}`,
 			unmarshalled: &btcjson.TxAcceptedNtfn{
@@ -182,6 +182,22 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Amount: 1.5,
 			},
 		},
+		{
+			name: "txaccepted tag",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("txaccepted", "123", 1.5, 42)
+			},
+			staticNtfn: func() interface{} {
+				return btcjson.NewTxAcceptedNtfn("123", 1.5,
+					btcjson.Uint64(42))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"txaccepted","params":["123",1.5,42],"id":null}`,
+			unmarshalled: &btcjson.TxAcceptedNtfn{
+				TxID:   "123",
+				Amount: 1.5,
+				Tag:    btcjson.Uint64(42),
+			},
+		},
 		{
 			name: "txacceptedverbose",
 			newNtfn: func() (interface{}, error) {
@@ -197,7 +213,7 @@ func TestChainSvrWsNtfns(t *testing.T) {
 					Vout:          nil,
 					Confirmations: 0,
 				}
-				return btcjson.NewTxAcceptedVerboseNtfn(txResult)
+				return btcjson.NewTxAcceptedVerboseNtfn(txResult, nil)
 			},
 			marshalled: `{"jsonrpc":"1.0","method":"txacceptedverbose","params":[{"hex":"001122","txid":"123","version":1,"locktime":4294967295,"vin":null,"vout":null}],"id":null}`,
 			unmarshalled: &btcjson.TxAcceptedVerboseNtfn{
@@ -225,6 +241,36 @@ func TestChainSvrWsNtfns(t *testing.T) {
 				Transaction: "001122",
 			},
 		},
+		{
+			name: "notice",
+			newNtfn: func() (interface{}, error) {
+				return btcjson.NewCmd("notice", `{"hash":"123","keyset":"ROOT","timestamp":1500000000,"expiration":1500086400,"priority":10,"message":"please upgrade","signers":["02ab","03cd"]}`)
+			},
+			staticNtfn: func() interface{} {
+				notice := btcjson.NoticeResult{
+					Hash:       "123",
+					KeySet:     "ROOT",
+					Timestamp:  1500000000,
+					Expiration: 1500086400,
+					Priority:   10,
+					Message:    "please upgrade",
+					Signers:    []string{"02ab", "03cd"},
+				}
+				return btcjson.NewNoticeNtfn(notice)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"notice","params":[{"hash":"123","keyset":"ROOT","timestamp":1500000000,"expiration":1500086400,"priority":10,"message":"please upgrade","signers":["02ab","03cd"]}],"id":null}`,
+			unmarshalled: &btcjson.NoticeNtfn{
+				Notice: btcjson.NoticeResult{
+					Hash:       "123",
+					KeySet:     "ROOT",
+					Timestamp:  1500000000,
+					Expiration: 1500086400,
+					Priority:   10,
+					Message:    "please upgrade",
+					Signers:    []string{"02ab", "03cd"},
+				},
+			},
+		},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -83,3 +83,16 @@ const (
 	ErrRPCNoWallet      RPCErrorCode = -1
 	ErrRPCUnimplemented RPCErrorCode = -1
 )
+
+// Errors that are specific to Prova.  They identify the rule a transaction
+// violated when it is rejected, so clients can react to the failure without
+// parsing the error message.
+const (
+	ErrRPCUnknownKeyID           RPCErrorCode = -70
+	ErrRPCKeyIDRevoked           RPCErrorCode = -71
+	ErrRPCThreadOutOfOrder       RPCErrorCode = -72
+	ErrRPCSupplyUnderflow        RPCErrorCode = -73
+	ErrRPCInvalidAdminOp         RPCErrorCode = -74
+	ErrRPCValidateKeyRateLimited RPCErrorCode = -75
+	ErrRPCFeeTooHigh             RPCErrorCode = -76
+)
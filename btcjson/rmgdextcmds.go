@@ -24,9 +24,190 @@ func NewSetValidateKeysCmd(privKeys []string) *SetValidateKeysCmd {
 	}
 }
 
+// CreateRecoveryTransactionsCmd defines the createrecoverytransactions JSON-RPC
+// command.  This command is not a standard command, it is an extension for
+// operating prova.
+type CreateRecoveryTransactionsCmd struct {
+	KeyID        uint32
+	Destinations map[string]string `jsonrpcusage:"{\"address\":\"newaddress\",...}"` // Address => new address
+	FeeRate      *float64
+	Skip         *int `jsonrpcdefault:"0"`
+	Count        *int `jsonrpcdefault:"100"`
+}
+
+// NewCreateRecoveryTransactionsCmd returns a new CreateRecoveryTransactionsCmd
+// which can be used to issue a createrecoverytransactions JSON-RPC command.
+// This command is not a standard command.  It is an extension for prova.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewCreateRecoveryTransactionsCmd(keyID uint32, destinations map[string]string, feeRate *float64, skip, count *int) *CreateRecoveryTransactionsCmd {
+	return &CreateRecoveryTransactionsCmd{
+		KeyID:        keyID,
+		Destinations: destinations,
+		FeeRate:      feeRate,
+		Skip:         skip,
+		Count:        count,
+	}
+}
+
+// DumpTxOutSetCmd defines the dumptxoutset JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type DumpTxOutSetCmd struct {
+	Path string
+}
+
+// NewDumpTxOutSetCmd returns a new DumpTxOutSetCmd which can be used to issue
+// a dumptxoutset JSON-RPC command.  This command is not a standard command.
+// It is an extension for prova.
+func NewDumpTxOutSetCmd(path string) *DumpTxOutSetCmd {
+	return &DumpTxOutSetCmd{
+		Path: path,
+	}
+}
+
+// LoadTxOutSetCmd defines the loadtxoutset JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type LoadTxOutSetCmd struct {
+	Path string
+}
+
+// NewLoadTxOutSetCmd returns a new LoadTxOutSetCmd which can be used to issue
+// a loadtxoutset JSON-RPC command.  This command is not a standard command.
+// It is an extension for prova.
+func NewLoadTxOutSetCmd(path string) *LoadTxOutSetCmd {
+	return &LoadTxOutSetCmd{
+		Path: path,
+	}
+}
+
+// GetNoticesCmd defines the getnotices JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type GetNoticesCmd struct{}
+
+// NewGetNoticesCmd returns a new GetNoticesCmd which can be used to issue a
+// getnotices JSON-RPC command.  This command is not a standard command.  It is
+// an extension for prova.
+func NewGetNoticesCmd() *GetNoticesCmd {
+	return &GetNoticesCmd{}
+}
+
+// LoadMempoolCmd defines the loadmempool JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type LoadMempoolCmd struct{}
+
+// NewLoadMempoolCmd returns a new LoadMempoolCmd which can be used to issue a
+// loadmempool JSON-RPC command.  This command is not a standard command.  It
+// is an extension for prova.
+func NewLoadMempoolCmd() *LoadMempoolCmd {
+	return &LoadMempoolCmd{}
+}
+
+// SendNoticeCmd defines the sendnotice JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type SendNoticeCmd struct {
+	HexNotice string
+}
+
+// NewSendNoticeCmd returns a new SendNoticeCmd which can be used to issue a
+// sendnotice JSON-RPC command.  This command is not a standard command.  It is
+// an extension for prova.
+func NewSendNoticeCmd(hexNotice string) *SendNoticeCmd {
+	return &SendNoticeCmd{
+		HexNotice: hexNotice,
+	}
+}
+
+// GetRevokeImpactCmd defines the getrevokeimpact JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type GetRevokeImpactCmd struct {
+	KeyID uint32
+	Skip  *int `jsonrpcdefault:"0"`
+	Count *int `jsonrpcdefault:"100"`
+}
+
+// NewGetRevokeImpactCmd returns a new GetRevokeImpactCmd which can be used to
+// issue a getrevokeimpact JSON-RPC command.  This command is not a standard
+// command.  It is an extension for prova.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewGetRevokeImpactCmd(keyID uint32, skip, count *int) *GetRevokeImpactCmd {
+	return &GetRevokeImpactCmd{
+		KeyID: keyID,
+		Skip:  skip,
+		Count: count,
+	}
+}
+
+// ListKeyIDUtxosCmd defines the listkeyidutxos JSON-RPC command.
+// This command is not a standard command, it is an extension for operating
+// prova.
+type ListKeyIDUtxosCmd struct {
+	KeyID uint32
+	Skip  *int `jsonrpcdefault:"0"`
+	Count *int `jsonrpcdefault:"100"`
+}
+
+// NewListKeyIDUtxosCmd returns a new ListKeyIDUtxosCmd which can be used to
+// issue a listkeyidutxos JSON-RPC command.  This command is not a standard
+// command.  It is an extension for prova.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewListKeyIDUtxosCmd(keyID uint32, skip, count *int) *ListKeyIDUtxosCmd {
+	return &ListKeyIDUtxosCmd{
+		KeyID: keyID,
+		Skip:  skip,
+		Count: count,
+	}
+}
+
+// SearchKeyIDTransactionsCmd defines the searchkeyidtransactions JSON-RPC
+// command.  This command is not a standard command, it is an extension for
+// operating prova.
+type SearchKeyIDTransactionsCmd struct {
+	KeyID   uint32
+	Verbose *int  `jsonrpcdefault:"1"`
+	Skip    *int  `jsonrpcdefault:"0"`
+	Count   *int  `jsonrpcdefault:"100"`
+	Reverse *bool `jsonrpcdefault:"false"`
+}
+
+// NewSearchKeyIDTransactionsCmd returns a new SearchKeyIDTransactionsCmd which
+// can be used to issue a searchkeyidtransactions JSON-RPC command.  This
+// command is not a standard command.  It is an extension for prova.
+//
+// The parameters which are pointers indicate they are optional.  Passing nil
+// for optional parameters will use the default value.
+func NewSearchKeyIDTransactionsCmd(keyID uint32, verbose, skip, count *int, reverse *bool) *SearchKeyIDTransactionsCmd {
+	return &SearchKeyIDTransactionsCmd{
+		KeyID:   keyID,
+		Verbose: verbose,
+		Skip:    skip,
+		Count:   count,
+		Reverse: reverse,
+	}
+}
+
 func init() {
 	// No special flags for commands in this file.
 	flags := UsageFlag(0)
 
+	MustRegisterCmd("createrecoverytransactions", (*CreateRecoveryTransactionsCmd)(nil), flags)
+	MustRegisterCmd("dumptxoutset", (*DumpTxOutSetCmd)(nil), flags)
+	MustRegisterCmd("getnotices", (*GetNoticesCmd)(nil), flags)
+	MustRegisterCmd("getrevokeimpact", (*GetRevokeImpactCmd)(nil), flags)
+	MustRegisterCmd("listkeyidutxos", (*ListKeyIDUtxosCmd)(nil), flags)
+	MustRegisterCmd("loadmempool", (*LoadMempoolCmd)(nil), flags)
+	MustRegisterCmd("loadtxoutset", (*LoadTxOutSetCmd)(nil), flags)
+	MustRegisterCmd("searchkeyidtransactions", (*SearchKeyIDTransactionsCmd)(nil), flags)
+	MustRegisterCmd("sendnotice", (*SendNoticeCmd)(nil), flags)
 	MustRegisterCmd("setvalidatekeys", (*SetValidateKeysCmd)(nil), flags)
 }
@@ -30,6 +30,207 @@ func TestRmgdExtCmds(t *testing.T) {
 		marshalled   string
 		unmarshalled interface{}
 	}{
+		{
+			name: "createrecoverytransactions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("createrecoverytransactions", 2,
+					`{"old":"new"}`)
+			},
+			staticCmd: func() interface{} {
+				destinations := map[string]string{"old": "new"}
+				return btcjson.NewCreateRecoveryTransactionsCmd(2,
+					destinations, nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createrecoverytransactions","params":[2,{"old":"new"}],"id":1}`,
+			unmarshalled: &btcjson.CreateRecoveryTransactionsCmd{
+				KeyID:        2,
+				Destinations: map[string]string{"old": "new"},
+				Skip:         btcjson.Int(0),
+				Count:        btcjson.Int(100),
+			},
+		},
+		{
+			name: "createrecoverytransactions optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("createrecoverytransactions", 2,
+					`{"old":"new"}`, 0.001, 10, 50)
+			},
+			staticCmd: func() interface{} {
+				destinations := map[string]string{"old": "new"}
+				return btcjson.NewCreateRecoveryTransactionsCmd(2,
+					destinations, btcjson.Float64(0.001),
+					btcjson.Int(10), btcjson.Int(50))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createrecoverytransactions","params":[2,{"old":"new"},0.001,10,50],"id":1}`,
+			unmarshalled: &btcjson.CreateRecoveryTransactionsCmd{
+				KeyID:        2,
+				Destinations: map[string]string{"old": "new"},
+				FeeRate:      btcjson.Float64(0.001),
+				Skip:         btcjson.Int(10),
+				Count:        btcjson.Int(50),
+			},
+		},
+		{
+			name: "dumptxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("dumptxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDumpTxOutSetCmd("utxo.dat")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"dumptxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.DumpTxOutSetCmd{
+				Path: "utxo.dat",
+			},
+		},
+		{
+			name: "getnotices",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getnotices")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetNoticesCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getnotices","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetNoticesCmd{},
+		},
+		{
+			name: "getrevokeimpact",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrevokeimpact", 2)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRevokeImpactCmd(2, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getrevokeimpact","params":[2],"id":1}`,
+			unmarshalled: &btcjson.GetRevokeImpactCmd{
+				KeyID: 2,
+				Skip:  btcjson.Int(0),
+				Count: btcjson.Int(100),
+			},
+		},
+		{
+			name: "getrevokeimpact optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getrevokeimpact", 2, 10, 50)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetRevokeImpactCmd(2,
+					btcjson.Int(10), btcjson.Int(50))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getrevokeimpact","params":[2,10,50],"id":1}`,
+			unmarshalled: &btcjson.GetRevokeImpactCmd{
+				KeyID: 2,
+				Skip:  btcjson.Int(10),
+				Count: btcjson.Int(50),
+			},
+		},
+		{
+			name: "listkeyidutxos",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listkeyidutxos", 2)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListKeyIDUtxosCmd(2, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listkeyidutxos","params":[2],"id":1}`,
+			unmarshalled: &btcjson.ListKeyIDUtxosCmd{
+				KeyID: 2,
+				Skip:  btcjson.Int(0),
+				Count: btcjson.Int(100),
+			},
+		},
+		{
+			name: "listkeyidutxos optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("listkeyidutxos", 2, 10, 50)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewListKeyIDUtxosCmd(2, btcjson.Int(10),
+					btcjson.Int(50))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"listkeyidutxos","params":[2,10,50],"id":1}`,
+			unmarshalled: &btcjson.ListKeyIDUtxosCmd{
+				KeyID: 2,
+				Skip:  btcjson.Int(10),
+				Count: btcjson.Int(50),
+			},
+		},
+		{
+			name: "loadmempool",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadmempool")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"loadmempool","params":[],"id":1}`,
+			unmarshalled: &btcjson.LoadMempoolCmd{},
+		},
+		{
+			name: "loadtxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadtxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadTxOutSetCmd("utxo.dat")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadtxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.LoadTxOutSetCmd{
+				Path: "utxo.dat",
+			},
+		},
+		{
+			name: "searchkeyidtransactions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("searchkeyidtransactions", 2)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSearchKeyIDTransactionsCmd(2, nil,
+					nil, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"searchkeyidtransactions","params":[2],"id":1}`,
+			unmarshalled: &btcjson.SearchKeyIDTransactionsCmd{
+				KeyID:   2,
+				Verbose: btcjson.Int(1),
+				Skip:    btcjson.Int(0),
+				Count:   btcjson.Int(100),
+				Reverse: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "searchkeyidtransactions optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("searchkeyidtransactions", 2, 0,
+					5, 10, true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSearchKeyIDTransactionsCmd(2,
+					btcjson.Int(0), btcjson.Int(5), btcjson.Int(10),
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"searchkeyidtransactions","params":[2,0,5,10,true],"id":1}`,
+			unmarshalled: &btcjson.SearchKeyIDTransactionsCmd{
+				KeyID:   2,
+				Verbose: btcjson.Int(0),
+				Skip:    btcjson.Int(5),
+				Count:   btcjson.Int(10),
+				Reverse: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "sendnotice",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("sendnotice", "001122")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSendNoticeCmd("001122")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"sendnotice","params":["001122"],"id":1}`,
+			unmarshalled: &btcjson.SendNoticeCmd{
+				HexNotice: "001122",
+			},
+		},
 		{
 			name: "setvalidatekeys",
 			newCmd: func() (interface{}, error) {
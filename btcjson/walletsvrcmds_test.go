@@ -103,19 +103,6 @@ func TestWalletSvrCmds(t *testing.T) {
 				Passphrase: "pass",
 			},
 		},
-		{
-			name: "estimatefee",
-			newCmd: func() (interface{}, error) {
-				return btcjson.NewCmd("estimatefee", 6)
-			},
-			staticCmd: func() interface{} {
-				return btcjson.NewEstimateFeeCmd(6)
-			},
-			marshalled: `{"jsonrpc":"1.0","method":"estimatefee","params":[6],"id":1}`,
-			unmarshalled: &btcjson.EstimateFeeCmd{
-				NumBlocks: 6,
-			},
-		},
 		{
 			name: "estimatepriority",
 			newCmd: func() (interface{}, error) {
@@ -52,6 +52,16 @@ type Checkpoint struct {
 	Hash   *chainhash.Hash
 }
 
+// UtxoSnapshot identifies a known good snapshot of the utxo set and admin
+// state as of a given block.  Snapshots matching one of these entries may be
+// loaded to bootstrap the chain state of a new node without replaying every
+// block before it.
+type UtxoSnapshot struct {
+	Height    uint32
+	BlockHash *chainhash.Hash
+	Hash      *chainhash.Hash
+}
+
 // DNSSeed identifies a DNS seed.
 type DNSSeed struct {
 	// Host defines the hostname of the seed.
@@ -117,6 +127,16 @@ type Params struct {
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// AssumeValid is the hash of a block whose ancestors are assumed to
+	// have valid scripts.  Nodes skip script validation for those blocks
+	// during initial sync unless overridden.  It is nil when the network
+	// does not define one.
+	AssumeValid *chainhash.Hash
+
+	// UtxoSnapshots are the known good utxo set snapshots which may be
+	// loaded to bootstrap the chain state.
+	UtxoSnapshots []UtxoSnapshot
+
 	// Enforce current block version once network has
 	// upgraded.  This is part of BIP0034.
 	BlockEnforceNumRequired uint64
@@ -160,6 +180,10 @@ type Params struct {
 
 	// Maximum fee allowed in a single transaction, in atoms.
 	MaximumFeeAmount int64
+
+	// Number of distinct keys of the root or provision key set which must
+	// sign a network notice.
+	NoticeQuorum int
 }
 
 // MaxActualTimespan returns a timespan with the down-dampening factor applied.
@@ -237,6 +261,12 @@ var MainNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{},
 
+	// Assume-valid block.
+	AssumeValid: nil,
+
+	// Known good utxo set snapshots.
+	UtxoSnapshots: nil,
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 75% (750 / 1000)
@@ -279,6 +309,9 @@ var MainNetParams = Params{
 
 	// Maximum fee allowed in a single transaction, in atoms.
 	MaximumFeeAmount: 5000000,
+
+	// Number of distinct admin keys which must sign a network notice.
+	NoticeQuorum: 2,
 }
 
 // RegressionNetParams defines the network parameters for the regression test
@@ -359,6 +392,9 @@ var RegressionNetParams = Params{
 
 	// Maximum fee allowed in a single transaction, in atoms.
 	MaximumFeeAmount: 5000000,
+
+	// Number of distinct admin keys which must sign a network notice.
+	NoticeQuorum: 2,
 }
 
 // TestNetParams defines the network parameters for the test network.
@@ -409,6 +445,12 @@ var TestNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: []Checkpoint{},
 
+	// Assume-valid block.
+	AssumeValid: nil,
+
+	// Known good utxo set snapshots.
+	UtxoSnapshots: nil,
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 51% (51 / 100)
@@ -451,6 +493,9 @@ var TestNetParams = Params{
 
 	// Maximum fee allowed in a single transaction, in atoms.
 	MaximumFeeAmount: 5000000,
+
+	// Number of distinct admin keys which must sign a network notice.
+	NoticeQuorum: 2,
 }
 
 // SimNetParams defines the network parameters for the simulation test Bitcoin
@@ -479,6 +524,12 @@ var SimNetParams = Params{
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints: nil,
 
+	// Assume-valid block.
+	AssumeValid: nil,
+
+	// Known good utxo set snapshots.
+	UtxoSnapshots: nil,
+
 	// Enforce current block version once majority of the network has
 	// upgraded.
 	// 51% (51 / 100)
@@ -520,6 +571,9 @@ var SimNetParams = Params{
 
 	// Maximum fee allowed in a single transaction, in atoms.
 	MaximumFeeAmount: 5000000,
+
+	// Number of distinct admin keys which must sign a network notice.
+	NoticeQuorum: 2,
 }
 
 var (
@@ -9,6 +9,7 @@ import (
 	"bufio"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -21,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/connmgr"
@@ -55,10 +57,12 @@ const (
 	defaultGenerate              = false
 	defaultMaxOrphanTransactions = 100
 	defaultMaxOrphanTxSize       = mempool.MaxStandardTxSize
+	defaultMaxMempool            = 300
 	defaultSigCacheMaxSize       = 100000
 	sampleConfigFilename         = "sample-prova.conf"
 	defaultTxIndex               = false
 	defaultAddrIndex             = false
+	minPruneTarget               = 1024
 )
 
 var (
@@ -100,6 +104,10 @@ type config struct {
 	DisableBanning       bool          `long:"nobanning" description:"Disable banning of misbehaving peers"`
 	BanDuration          time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	BanThreshold         uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
+	NodeKey              string        `long:"nodekey" default-mask:"-" description:"Hex-encoded private key this node proves control of to peers during the handshake -- Peers which require authentication only accept admin keys and ASP keys"`
+	AuthPeers            bool          `long:"authpeers" description:"Only connect to and accept connections from peers which prove control of an admin key or an ASP key -- Implies --requireencryption"`
+	NoEncryption         bool          `long:"noencryption" description:"Disable encryption of connections to peers which support it"`
+	RequireEncryption    bool          `long:"requireencryption" description:"Only connect to and accept connections from peers which encrypt the connection"`
 	RPCUser              string        `short:"u" long:"rpcuser" description:"Username for RPC connections"`
 	RPCPass              string        `short:"P" long:"rpcpass" default-mask:"-" description:"Password for RPC connections"`
 	RPCLimitUser         string        `long:"rpclimituser" description:"Username for limited RPC connections"`
@@ -127,6 +135,7 @@ type config struct {
 	RegressionTest       bool          `long:"regtest" description:"Use the regression test network"`
 	SimNet               bool          `long:"simnet" description:"Use the simulation test network"`
 	AddCheckpoints       []string      `long:"addcheckpoint" description:"Add a custom checkpoint.  Format: '<height>:<hash>'"`
+	AssumeValid          string        `long:"assumevalid" description:"Skip script validation for ancestors of the block with this hash during initial sync -- Use 0 to validate all scripts (default: network specific)"`
 	DbType               string        `long:"dbtype" description:"Database backend to use for the Block Chain"`
 	Profile              string        `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65536"`
 	CPUProfile           string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
@@ -135,26 +144,41 @@ type config struct {
 	MinRelayTxFee        float64       `long:"minrelaytxfee" description:"The minimum transaction fee in RMG/kB to be considered a non-zero fee."`
 	FreeTxRelayLimit     float64       `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
 	RelayPriority        bool          `long:"relaypriority" description:"Require free or low-fee transactions to have high priority for relaying"`
+	FreeRelayTags        []uint64      `long:"freerelaytag" description:"Accept free or low-fee transactions submitted via RPC by admin users with the specified tag regardless of their size and priority -- May be specified multiple times"`
 	MaxOrphanTxs         int           `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
+	MaxMempool           uint64        `long:"maxmempool" description:"Maximum total size of the transactions in the memory pool in MB -- The transactions with the lowest fee rates are evicted when it is exceeded (0 = unlimited)"`
 	Generate             bool          `long:"generate" description:"Generate (mine) blocks using the CPU"`
 	MiningAddrs          []string      `long:"miningaddr" description:"Add the specified payment address to the list of addresses to use for generated blocks -- At least one address is required if the generate option is set"`
 	BlockMinSize         uint32        `long:"blockminsize" description:"Mininum block size in bytes to be used when creating a block"`
 	BlockMaxSize         uint32        `long:"blockmaxsize" description:"Maximum block size in bytes to be used when creating a block"`
 	BlockPrioritySize    uint32        `long:"blockprioritysize" description:"Size in bytes for high-priority/low-fee transactions when creating a block"`
 	NoPeerBloomFilters   bool          `long:"nopeerbloomfilters" description:"Disable bloom filtering support"`
+	StateQueries         bool          `long:"statequeries" description:"Serve utxo and admin state queries of light clients"`
+	NoCFilters           bool          `long:"nocfilters" description:"Disable the committed filter index and serving committed filters to light clients"`
+	AcceptReplacement    bool          `long:"acceptreplacement" description:"Accept transactions which replace transactions in the mempool that signal replacement through the Replace-By-Fee (RBF) policy, such as to raise their fee or to cancel them by paying their inputs back to the sender"`
+	NoPersistMempool     bool          `long:"nopersistmempool" description:"Do not save the transaction memory pool on shutdown and reload it on start up"`
 	SigCacheMaxSize      uint          `long:"sigcachemaxsize" description:"The maximum number of entries in the signature verification cache"`
 	BlocksOnly           bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
 	TxIndex              bool          `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
 	DropTxIndex          bool          `long:"droptxindex" description:"Deletes the hash-based transaction index from the database on start up and then exits."`
 	AddrIndex            bool          `long:"addrindex" description:"Maintain a full address-based transaction index which makes the searchrawtransactions RPC available"`
 	DropAddrIndex        bool          `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up and then exits."`
+	KeyIDIndex           bool          `long:"keyidindex" description:"Maintain a keyID-based transaction and unspent output index which makes the listkeyidutxos and searchkeyidtransactions RPCs available"`
+	DropKeyIDIndex       bool          `long:"dropkeyidindex" description:"Deletes the keyID-based index from the database on start up and then exits."`
+	DropCfIndex          bool          `long:"dropcfindex" description:"Deletes the committed filter index from the database on start up and then exits."`
+	LoadSnapshot         string        `long:"loadsnapshot" description:"Bootstrap the chain state from the given utxo set snapshot file created with the dumptxoutset RPC when the chain has no blocks yet"`
+	Reindex              bool          `long:"reindex" description:"Rebuild the block index from the stored block files and then the chain state and optional indexes from the blocks on start up"`
+	ReindexChainState    bool          `long:"reindex-chainstate" description:"Rebuild the chain state and optional indexes from the stored blocks on start up"`
+	Prune                uint64        `long:"prune" description:"Delete the oldest blocks once the stored blocks exceed the given size in MiB -- The node no longer serves the full block history and the optional indexes may not be used (0 = disabled, minimum 1024)"`
 	RelayNonStd          bool          `long:"relaynonstd" description:"Relay non-standard transactions regardless of the default settings for the active network."`
 	RejectNonStd         bool          `long:"rejectnonstd" description:"Reject non-standard transactions regardless of the default settings for the active network."`
 	lookup               func(string) ([]net.IP, error)
 	oniondial            func(string, string, time.Duration) (net.Conn, error)
 	dial                 func(string, string, time.Duration) (net.Conn, error)
 	addCheckpoints       []chaincfg.Checkpoint
+	assumeValid          *chainhash.Hash
 	miningAddrs          []provautil.Address
+	nodeKey              *btcec.PrivateKey
 	minRelayTxFee        provautil.Amount
 }
 
@@ -408,6 +432,7 @@ func loadConfig() (*config, []string, error) {
 		BlockMaxSize:         defaultBlockMaxSize,
 		BlockPrioritySize:    mempool.DefaultBlockPrioritySize,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
+		MaxMempool:           defaultMaxMempool,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
 		Generate:             defaultGenerate,
 		TxIndex:              defaultTxIndex,
@@ -747,6 +772,84 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// --keyidindex and --dropkeyidindex do not mix.
+	if cfg.KeyIDIndex && cfg.DropKeyIDIndex {
+		err := fmt.Errorf("%s: the --keyidindex and --dropkeyidindex "+
+			"options may not be activated at the same time",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --dropcfindex requires the committed filter index to be disabled.
+	if !cfg.NoCFilters && cfg.DropCfIndex {
+		err := fmt.Errorf("%s: the --dropcfindex option requires the "+
+			"--nocfilters option", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	if cfg.LoadSnapshot != "" {
+		cfg.LoadSnapshot = cleanAndExpandPath(cfg.LoadSnapshot)
+	}
+
+	// --loadsnapshot does not mix with the optional indexes since the
+	// blocks before the snapshot are not available to build them.  The
+	// committed filter index is enabled by default, so it is disabled
+	// instead.
+	if cfg.LoadSnapshot != "" && (cfg.TxIndex || cfg.AddrIndex ||
+		cfg.KeyIDIndex) {
+		err := fmt.Errorf("%s: the --loadsnapshot option may not be "+
+			"activated at the same time as the --txindex, "+
+			"--addrindex, or --keyidindex options", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.LoadSnapshot != "" {
+		cfg.NoCFilters = true
+	}
+
+	// --reindex and --reindex-chainstate do not mix with --loadsnapshot
+	// since the chain state is rebuilt from the stored blocks.
+	if cfg.LoadSnapshot != "" && (cfg.Reindex || cfg.ReindexChainState) {
+		err := fmt.Errorf("%s: the --loadsnapshot option may not be "+
+			"activated at the same time as the --reindex or "+
+			"--reindex-chainstate options", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Ensure the prune target leaves room for the block files which hold
+	// the blocks that are never pruned.
+	if cfg.Prune != 0 && cfg.Prune < minPruneTarget {
+		str := "%s: The prune option may not be less than %d MiB " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, minPruneTarget, cfg.Prune)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// --prune does not mix with the optional indexes since they need all
+	// of the blocks to be available.  The committed filter index is
+	// enabled by default, so it is disabled instead.
+	if cfg.Prune != 0 && (cfg.TxIndex || cfg.AddrIndex ||
+		cfg.KeyIDIndex) {
+		err := fmt.Errorf("%s: the --prune option may not be "+
+			"activated at the same time as the --txindex, "+
+			"--addrindex, or --keyidindex options", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+	if cfg.Prune != 0 {
+		cfg.NoCFilters = true
+	}
+
 	// --addrindex and --droptxindex do not mix.
 	if cfg.AddrIndex && cfg.DropTxIndex {
 		err := fmt.Errorf("%s: the --addrindex and --droptxindex "+
@@ -759,6 +862,18 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// --keyidindex and --droptxindex do not mix.
+	if cfg.KeyIDIndex && cfg.DropTxIndex {
+		err := fmt.Errorf("%s: the --keyidindex and --droptxindex "+
+			"options may not be activated at the same time "+
+			"because the keyID index relies on the transaction "+
+			"index",
+			funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Check mining addresses are valid and saved parsed versions.
 	cfg.miningAddrs = make([]provautil.Address, 0, len(cfg.MiningAddrs))
 	for _, strAddr := range cfg.MiningAddrs {
@@ -780,6 +895,45 @@ func loadConfig() (*config, []string, error) {
 		cfg.miningAddrs = append(cfg.miningAddrs, addr)
 	}
 
+	// Encryption can't be both disabled and required.
+	if cfg.NoEncryption && cfg.RequireEncryption {
+		str := "%s: noencryption and requireencryption cannot be used " +
+			"together -- choose only one"
+		err := fmt.Errorf(str, funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// Authentication over a plaintext connection could be relayed by a
+	// man-in-the-middle, so authenticated peers must encrypt the connection.
+	if cfg.AuthPeers {
+		if cfg.NoEncryption {
+			str := "%s: authpeers and noencryption cannot be used " +
+				"together -- authenticated peers must encrypt the " +
+				"connection"
+			err := fmt.Errorf(str, funcName)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.RequireEncryption = true
+	}
+
+	// Check the node key is valid and save the parsed version.
+	if cfg.NodeKey != "" {
+		keyBytes, err := hex.DecodeString(cfg.NodeKey)
+		if err != nil || len(keyBytes) != btcec.PrivKeyBytesLen {
+			str := "%s: the node key must be a hex-encoded %d byte " +
+				"private key"
+			err := fmt.Errorf(str, funcName, btcec.PrivKeyBytesLen)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+		cfg.nodeKey, _ = btcec.PrivKeyFromBytes(btcec.S256(), keyBytes)
+	}
+
 	// Ensure there is at least one mining address when the generate flag is
 	// set.
 	if cfg.Generate && len(cfg.MiningAddrs) == 0 {
@@ -857,6 +1011,22 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Parse the assume-valid block hash, falling back to the default for
+	// the active network when it is not specified.
+	cfg.assumeValid = activeNetParams.AssumeValid
+	if cfg.AssumeValid == "0" {
+		cfg.assumeValid = nil
+	} else if cfg.AssumeValid != "" {
+		cfg.assumeValid, err = chainhash.NewHashFromStr(cfg.AssumeValid)
+		if err != nil {
+			str := "%s: Error parsing assumevalid hash: %v"
+			err := fmt.Errorf(str, funcName, err)
+			fmt.Fprintln(os.Stderr, err)
+			fmt.Fprintln(os.Stderr, usageMessage)
+			return nil, nil, err
+		}
+	}
+
 	// Tor stream isolation requires either proxy or onion proxy to be set.
 	if cfg.TorIsolation && cfg.Proxy == "" && cfg.OnionProxy == "" {
 		str := "%s: Tor stream isolation requires either proxy or " +
@@ -90,6 +90,11 @@ func realMain() error {
 	parser.AddCommand("fetchblockregion",
 		"Fetch the specified block region from the database", "",
 		&blockRegionCfg)
+	parser.AddCommand("verify",
+		"Verify the chain state is consistent with the stored blocks",
+		"Verify the best chain state, main chain block index, spend "+
+			"journal, utxo set and admin key set are consistent "+
+			"with the stored blocks.", &verifyCfg)
 
 	// Parse command line and invoke the Execute function for the specified
 	// command.
@@ -0,0 +1,58 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitgo/prova/blockchain"
+)
+
+// verifyCmd defines the configuration options for the verify command.
+type verifyCmd struct{}
+
+var (
+	// verifyCfg defines the configuration options for the command.
+	verifyCfg = verifyCmd{}
+)
+
+// Execute is the main entry point for the command.  It's invoked by the parser.
+func (cmd *verifyCmd) Execute(args []string) error {
+	// Setup the global config options and ensure they are valid.
+	if err := setupGlobalConfig(); err != nil {
+		return err
+	}
+
+	// Load the block database.
+	db, err := loadBlockDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	log.Infof("Verifying the chain state against the stored blocks...")
+	startTime := time.Now()
+	report, err := blockchain.VerifyChainState(db)
+	if err != nil {
+		return err
+	}
+	log.Infof("Verified chain state at height %d (hash %v) in %v",
+		report.BestHeight, report.BestHash, time.Since(startTime))
+	log.Infof("Checked %d blocks and %d utxo set entries (%d blocks not "+
+		"available)", report.BlocksChecked, report.UtxoEntries,
+		report.BlocksUnavailable)
+
+	for _, problem := range report.Problems {
+		log.Error(problem)
+	}
+	if len(report.Problems) != 0 {
+		return fmt.Errorf("found %d inconsistencies -- restart the "+
+			"node with --reindex-chainstate to rebuild the chain "+
+			"state", len(report.Problems))
+	}
+	log.Info("The chain state is consistent with the stored blocks")
+	return nil
+}
@@ -28,6 +28,13 @@ type Driver struct {
 	// ErrDbDoesNotExist if the database has not already been created.
 	Open func(args ...interface{}) (DB, error)
 
+	// Reindex is the function that will be invoked with all user-specified
+	// arguments to open the database after rebuilding the index of the
+	// stored blocks from the underlying block storage.  This function must
+	// return ErrDbDoesNotExist if the database has not already been
+	// created.
+	Reindex func(args ...interface{}) (DB, error)
+
 	// UseLogger uses a specified Logger to output package logging info.
 	UseLogger func(logger btclog.Logger)
 }
@@ -88,3 +95,22 @@ func Open(dbType string, args ...interface{}) (DB, error) {
 
 	return drv.Open(args...)
 }
+
+// Reindex opens an existing database for the specified type after rebuilding
+// the index of the stored blocks from the underlying block storage.  Any stored
+// block data which fails the integrity checks is discarded along with all of
+// the block data stored after it.  The rest of the metadata is not modified, so
+// it is the responsibility of the caller to rebuild any data which depends on
+// the stored blocks.  The arguments are specific to the database type driver.
+// See the documentation for the database driver for further details.
+//
+// ErrDbUnknownType will be returned if the the database type is not registered.
+func Reindex(dbType string, args ...interface{}) (DB, error) {
+	drv, exists := drivers[dbType]
+	if !exists {
+		str := fmt.Sprintf("driver %q is not registered", dbType)
+		return nil, makeError(ErrDbUnknownType, str, nil)
+	}
+
+	return drv.Reindex(args...)
+}
@@ -169,6 +169,12 @@ type blockStore struct {
 	// new blocks are written to.
 	writeCursor *writeCursor
 
+	// firstFileNum is the number of the oldest block file that has not
+	// been pruned.  All block files before it have been deleted.  It is
+	// protected by pruneMtx.
+	pruneMtx     sync.RWMutex
+	firstFileNum uint32
+
 	// These functions are set to openFile, openWriteFile, and deleteFile by
 	// default, but are exposed here to allow the whitebox tests to replace
 	// them when working with mock files.
@@ -501,12 +507,18 @@ func (s *blockStore) writeBlock(rawBlock []byte) (blockLocation, error) {
 // and closing files as necessary to stay within the maximum allowed open files
 // limit.
 //
-// Returns ErrDriverSpecific if the data fails to read for any reason and
-// ErrCorruption if the checksum of the read data doesn't match the checksum
-// read from the file.
+// Returns ErrBlockPruned if the block file has been pruned, ErrDriverSpecific
+// if the data fails to read for any reason and ErrCorruption if the checksum of
+// the read data doesn't match the checksum read from the file.
 //
 // Format: <network><block length><serialized block><checksum>
 func (s *blockStore) readBlock(hash *chainhash.Hash, loc blockLocation) ([]byte, error) {
+	// Ensure the file which housed the block has not been pruned.
+	if s.isPruned(loc.blockFileNum) {
+		str := fmt.Sprintf("block %s has been pruned", hash)
+		return nil, makeDbErr(database.ErrBlockPruned, str, nil)
+	}
+
 	// Get the referenced block file handle opening the file as needed.  The
 	// function also handles closing files as needed to avoid going over the
 	// max allowed open files.
@@ -561,8 +573,16 @@ func (s *blockStore) readBlock(hash *chainhash.Hash, loc blockLocation) ([]byte,
 // closing files as necessary to stay within the maximum allowed open files
 // limit.
 //
-// Returns ErrDriverSpecific if the data fails to read for any reason.
+// Returns ErrBlockPruned if the block file has been pruned and
+// ErrDriverSpecific if the data fails to read for any reason.
 func (s *blockStore) readBlockRegion(loc blockLocation, offset, numBytes uint32) ([]byte, error) {
+	// Ensure the file which housed the block has not been pruned.
+	if s.isPruned(loc.blockFileNum) {
+		str := fmt.Sprintf("block file %d has been pruned",
+			loc.blockFileNum)
+		return nil, makeDbErr(database.ErrBlockPruned, str, nil)
+	}
+
 	// Get the referenced block file handle opening the file as needed.  The
 	// function also handles closing files as needed to avoid going over the
 	// max allowed open files.
@@ -588,6 +608,75 @@ func (s *blockStore) readBlockRegion(loc blockLocation, offset, numBytes uint32)
 	return serializedData, nil
 }
 
+// isPruned returns whether or not the block file with the passed number has
+// been deleted by pruning.
+func (s *blockStore) isPruned(fileNum uint32) bool {
+	s.pruneMtx.RLock()
+	pruned := fileNum < s.firstFileNum
+	s.pruneMtx.RUnlock()
+	return pruned
+}
+
+// pruneState returns the number of the oldest block file that has not been
+// pruned along with the approximate total size of the stored block files.  The
+// size assumes all files before the current write file are full which is close
+// enough for the purposes of pruning since files are only rotated once a block
+// no longer fits.
+func (s *blockStore) pruneState() (uint32, uint64) {
+	s.pruneMtx.RLock()
+	firstFileNum := s.firstFileNum
+	s.pruneMtx.RUnlock()
+
+	wc := s.writeCursor
+	wc.RLock()
+	size := uint64(wc.curFileNum-firstFileNum)*uint64(s.maxBlockFileSize) +
+		uint64(wc.curOffset)
+	wc.RUnlock()
+
+	return firstFileNum, size
+}
+
+// pruneFiles closes and deletes all block files before the provided file
+// number.  The file associated with the current write cursor must not be
+// before the provided file number.
+//
+// The oldest remaining block file is updated even when a file fails to delete
+// so that the files which were deleted are properly reported as pruned.
+func (s *blockStore) pruneFiles(pruneTo uint32) error {
+	s.pruneMtx.Lock()
+	defer s.pruneMtx.Unlock()
+
+	// Close any of the files to be deleted which are open under the write
+	// lock for the file in case any readers are currently reading from it
+	// so it's not closed out from under them.
+	s.obfMutex.Lock()
+	s.lruMutex.Lock()
+	for fileNum := s.firstFileNum; fileNum < pruneTo; fileNum++ {
+		blockFile, ok := s.openBlockFiles[fileNum]
+		if !ok {
+			continue
+		}
+		blockFile.Lock()
+		_ = blockFile.file.Close()
+		blockFile.Unlock()
+
+		s.openBlocksLRU.Remove(s.fileNumToLRUElem[fileNum])
+		delete(s.openBlockFiles, fileNum)
+		delete(s.fileNumToLRUElem, fileNum)
+	}
+	s.lruMutex.Unlock()
+	s.obfMutex.Unlock()
+
+	for ; s.firstFileNum < pruneTo; s.firstFileNum++ {
+		if err := s.deleteFileFunc(s.firstFileNum); err != nil {
+			return err
+		}
+		log.Debugf("Pruned block file %d", s.firstFileNum)
+	}
+
+	return nil
+}
+
 // syncBlocks performs a file system sync on the flat file associated with the
 // store's current write cursor.  It is safe to call even when there is not a
 // current write file in which case it will have no effect.
@@ -719,10 +808,27 @@ func (s *blockStore) handleRollback(oldBlockFileNum, oldBlockOffset uint32) {
 // current write cursor which is also stored in the metadata.  Thus, it is used
 // to detect unexpected shutdowns in the middle of writes so the block files
 // can be reconciled.
-func scanBlockFiles(dbPath string) (int, uint32) {
+//
+// The oldest block files might have been deleted by pruning, so the number of
+// the oldest file found is returned as well.
+func scanBlockFiles(dbPath string) (int, int, uint32) {
+	// The file names are zero padded and the matches are sorted, so the
+	// first one which parses is the oldest file.
+	firstFile := 0
+	fileNames, _ := filepath.Glob(filepath.Join(dbPath, "*.fdb"))
+	for _, fileName := range fileNames {
+		var fileNum int
+		_, err := fmt.Sscanf(filepath.Base(fileName),
+			blockFilenameTemplate, &fileNum)
+		if err == nil {
+			firstFile = fileNum
+			break
+		}
+	}
+
 	lastFile := -1
 	fileLen := uint32(0)
-	for i := 0; ; i++ {
+	for i := firstFile; ; i++ {
 		filePath := blockFilePath(dbPath, uint32(i))
 		st, err := os.Stat(filePath)
 		if err != nil {
@@ -733,9 +839,9 @@ func scanBlockFiles(dbPath string) (int, uint32) {
 		fileLen = uint32(st.Size())
 	}
 
-	log.Tracef("Scan found latest block file #%d with length %d", lastFile,
-		fileLen)
-	return lastFile, fileLen
+	log.Tracef("Scan found block files #%d through #%d with length %d",
+		firstFile, lastFile, fileLen)
+	return firstFile, lastFile, fileLen
 }
 
 // newBlockStore returns a new block store with the current block file number
@@ -744,8 +850,9 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 	// Look for the end of the latest block to file to determine what the
 	// write cursor position is from the viewpoing of the block files on
 	// disk.
-	fileNum, fileOff := scanBlockFiles(basePath)
+	firstFileNum, fileNum, fileOff := scanBlockFiles(basePath)
 	if fileNum == -1 {
+		firstFileNum = 0
 		fileNum = 0
 		fileOff = 0
 	}
@@ -757,6 +864,7 @@ func newBlockStore(basePath string, network wire.BitcoinNet) *blockStore {
 		openBlockFiles:   make(map[uint32]*lockableFile),
 		openBlocksLRU:    list.New(),
 		fileNumToLRUElem: make(map[uint32]*list.Element),
+		firstFileNum:     uint32(firstFileNum),
 
 		writeCursor: &writeCursor{
 			curFile:    &lockableFile{},
@@ -967,6 +967,9 @@ type transaction struct {
 	pendingBlocks    map[chainhash.Hash]int
 	pendingBlockData []pendingBlock
 
+	// Block files before this file number need to be deleted on commit.
+	pendingPruneTo uint32
+
 	// Keys that need to be stored or deleted on commit.
 	pendingKeys   *treap.Mutable
 	pendingRemove *treap.Mutable
@@ -1338,6 +1341,7 @@ func (tx *transaction) FetchBlockHeaders(hashes []chainhash.Hash) ([][]byte, err
 //
 // Returns the following errors as required by the interface contract:
 //   - ErrBlockNotFound if the requested block hash does not exist
+//   - ErrBlockPruned if the data for the requested block has been pruned
 //   - ErrTxClosed if the transaction has already been closed
 //   - ErrCorruption if the database has somehow become corrupted
 //
@@ -1385,6 +1389,8 @@ func (tx *transaction) FetchBlock(hash *chainhash.Hash) ([]byte, error) {
 //
 // Returns the following errors as required by the interface contract:
 //   - ErrBlockNotFound if any of the requested block hashed do not exist
+//   - ErrBlockPruned if the data for any of the requested blocks has been
+//     pruned
 //   - ErrTxClosed if the transaction has already been closed
 //   - ErrCorruption if the database has somehow become corrupted
 //
@@ -1461,6 +1467,7 @@ func (tx *transaction) fetchPendingRegion(region *database.BlockRegion) ([]byte,
 //
 // Returns the following errors as required by the interface contract:
 //   - ErrBlockNotFound if the requested block hash does not exist
+//   - ErrBlockPruned if the data for the requested block has been pruned
 //   - ErrBlockRegionInvalid if the region exceeds the bounds of the associated
 //     block
 //   - ErrTxClosed if the transaction has already been closed
@@ -1534,6 +1541,8 @@ func (tx *transaction) FetchBlockRegion(region *database.BlockRegion) ([]byte, e
 //
 // Returns the following errors as required by the interface contract:
 //   - ErrBlockNotFound if any of the request block hashes do not exist
+//   - ErrBlockPruned if the data for any of the requested blocks has been
+//     pruned
 //   - ErrBlockRegionInvalid if one or more region exceed the bounds of the
 //     associated block
 //   - ErrTxClosed if the transaction has already been closed
@@ -1628,6 +1637,166 @@ func (tx *transaction) FetchBlockRegions(regions []database.BlockRegion) ([][]by
 	return blockRegions, nil
 }
 
+// PruneBlocks deletes the oldest block files until the total size of the block
+// files is at or below the provided target size in bytes.  The file which
+// houses the block identified by keepHash and any files after it are never
+// deleted, so the target size might not be reached.  The hashes of the blocks
+// which were housed in the deleted files are returned.
+//
+// The entries for the pruned blocks are intentionally kept in the block index
+// so their headers are still available and attempts to fetch their data can
+// be reported as ErrBlockPruned as opposed to ErrBlockNotFound.
+//
+// The block files are only deleted once the transaction has been committed, so
+// nothing is deleted when the transaction is rolled back.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrBlockNotFound if the block identified by keepHash does not exist
+//   - ErrTxNotWritable if attempted against a read-only transaction
+//   - ErrTxClosed if the transaction has already been closed
+//
+// In addition, returns ErrDriverSpecific if any failures occur when deleting
+// the block files.
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) PruneBlocks(targetSize uint64, keepHash *chainhash.Hash) ([]chainhash.Hash, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Ensure the transaction is writable.
+	if !tx.writable {
+		str := "prune blocks requires a writable database transaction"
+		return nil, makeDbErr(database.ErrTxNotWritable, str, nil)
+	}
+
+	// Nothing to do when the block to keep is pending to be written on
+	// commit since it will be written to the current write file.
+	if _, exists := tx.pendingBlocks[*keepHash]; exists {
+		return nil, nil
+	}
+	blockRow, err := tx.fetchBlockRow(keepHash)
+	if err != nil {
+		return nil, err
+	}
+	keepFileNum := deserializeBlockLoc(blockRow).blockFileNum
+
+	// Determine which of the oldest files need to be deleted in order to
+	// reach the target size.
+	store := tx.db.store
+	firstFileNum, size := tx.pruneState()
+	pruneTo := firstFileNum
+	for pruneTo < keepFileNum && size > targetSize {
+		size -= uint64(store.maxBlockFileSize)
+		pruneTo++
+	}
+	if pruneTo == firstFileNum {
+		return nil, nil
+	}
+
+	// Gather the hashes of all blocks housed in the files to be deleted.
+	var prunedHashes []chainhash.Hash
+	err = tx.blockIdxBucket.ForEach(func(k, v []byte) error {
+		fileNum := deserializeBlockLoc(v).blockFileNum
+		if fileNum >= firstFileNum && fileNum < pruneTo {
+			var hash chainhash.Hash
+			copy(hash[:], k)
+			prunedHashes = append(prunedHashes, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tx.pendingPruneTo = pruneTo
+	log.Debugf("Pruning %d blocks housed in block files %d through %d",
+		len(prunedHashes), firstFileNum, pruneTo-1)
+
+	return prunedHashes, nil
+}
+
+// pruneState returns the number of the oldest block file which is not deleted
+// when the transaction is committed along with the total size of the block
+// files after it.
+func (tx *transaction) pruneState() (uint32, uint64) {
+	store := tx.db.store
+	firstFileNum, size := store.pruneState()
+	if tx.pendingPruneTo > firstFileNum {
+		size -= uint64(tx.pendingPruneTo-firstFileNum) *
+			uint64(store.maxBlockFileSize)
+		firstFileNum = tx.pendingPruneTo
+	}
+	return firstFileNum, size
+}
+
+// StoredBlockHashes returns the hashes of all blocks whose data is available in
+// the database in the order they were stored.  Blocks which are pending to be
+// written on commit are included after all of the blocks in the block files.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) StoredBlockHashes() ([]chainhash.Hash, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return nil, err
+	}
+
+	// Gather the locations of all blocks which have not been pruned and
+	// sort them by file and offset which is the order they were stored.
+	firstFileNum, _ := tx.pruneState()
+	var hashes []chainhash.Hash
+	var locations []bulkFetchData
+	err := tx.blockIdxBucket.ForEach(func(k, v []byte) error {
+		location := deserializeBlockLoc(v)
+		if location.blockFileNum < firstFileNum {
+			return nil
+		}
+
+		var hash chainhash.Hash
+		copy(hash[:], k)
+		locations = append(locations, bulkFetchData{&location,
+			len(hashes)})
+		hashes = append(hashes, hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(bulkFetchDataSorter(locations))
+
+	storedHashes := make([]chainhash.Hash, 0, len(hashes)+
+		len(tx.pendingBlockData))
+	for i := range locations {
+		storedHashes = append(storedHashes, hashes[locations[i].replyIndex])
+	}
+	for _, blockData := range tx.pendingBlockData {
+		storedHashes = append(storedHashes, *blockData.hash)
+	}
+
+	return storedHashes, nil
+}
+
+// BeenPruned returns whether or not any block files have been deleted from the
+// database by PruneBlocks.
+//
+// Returns the following errors as required by the interface contract:
+//   - ErrTxClosed if the transaction has already been closed
+//
+// This function is part of the database.Tx interface implementation.
+func (tx *transaction) BeenPruned() (bool, error) {
+	// Ensure transaction state is valid.
+	if err := tx.checkClosed(); err != nil {
+		return false, err
+	}
+
+	firstFileNum, _ := tx.pruneState()
+	return firstFileNum > 0, nil
+}
+
 // close marks the transaction closed then releases any pending data, the
 // underlying snapshot, the transaction read lock, and the write lock when the
 // transaction is writable.
@@ -1637,6 +1806,7 @@ func (tx *transaction) close() {
 	// Clear pending blocks that would have been written on commit.
 	tx.pendingBlocks = nil
 	tx.pendingBlockData = nil
+	tx.pendingPruneTo = 0
 
 	// Clear pending keys that would have been written or deleted on commit.
 	tx.pendingKeys = nil
@@ -1725,7 +1895,20 @@ func (tx *transaction) writePendingAndCommit() error {
 
 	// Atomically update the database cache.  The cache automatically
 	// handles flushing to the underlying persistent storage database.
-	return tx.db.cache.commitTx(tx)
+	if err := tx.db.cache.commitTx(tx); err != nil {
+		return err
+	}
+
+	// Delete the block files which were pruned now that the transaction
+	// is committed.  The oldest remaining block file is only advanced past
+	// the files which were actually deleted, so any which fail to delete
+	// are pruned again later.
+	if tx.pendingPruneTo > 0 {
+		if err := tx.db.store.pruneFiles(tx.pendingPruneTo); err != nil {
+			log.Warnf("Failed to delete pruned block files: %v", err)
+		}
+	}
+	return nil
 }
 
 // Commit commits all changes that have been made to the root metadata bucket
@@ -2041,7 +2224,9 @@ func initDB(ldb *leveldb.DB) error {
 
 // openDB opens the database at the provided path.  database.ErrDbDoesNotExist
 // is returned if the database doesn't exist and the create flag is not set.
-func openDB(dbPath string, network wire.BitcoinNet, create bool) (database.DB, error) {
+// When the reindex flag is set, the block index is rebuilt from the flat block
+// files instead of reconciling the metadata with them.
+func openDB(dbPath string, network wire.BitcoinNet, create, reindex bool) (database.DB, error) {
 	// Error if the database doesn't exist and the create flag is not set.
 	metadataDbPath := filepath.Join(dbPath, metadataDbName)
 	dbExists := fileExists(metadataDbPath)
@@ -2079,6 +2264,12 @@ func openDB(dbPath string, network wire.BitcoinNet, create bool) (database.DB, e
 	cache := newDbCache(ldb, store, defaultCacheSize, defaultFlushSecs)
 	pdb := &db{store: store, cache: cache}
 
+	// Rebuild the block index from the block files when requested instead
+	// of reconciling them with the metadata.
+	if reindex {
+		return reindexBlocks(pdb)
+	}
+
 	// Perform any reconciliation needed between the block and metadata as
 	// well as database initialization, if needed.
 	return reconcileDB(pdb, create)
@@ -50,7 +50,19 @@ func openDBDriver(args ...interface{}) (database.DB, error) {
 		return nil, err
 	}
 
-	return openDB(dbPath, network, false)
+	return openDB(dbPath, network, false, false)
+}
+
+// reindexDBDriver is the callback provided during driver registration that
+// opens an existing database for use after rebuilding the block index from the
+// flat block files.
+func reindexDBDriver(args ...interface{}) (database.DB, error) {
+	dbPath, network, err := parseArgs("Reindex", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDB(dbPath, network, false, true)
 }
 
 // createDBDriver is the callback provided during driver registration that
@@ -61,7 +73,7 @@ func createDBDriver(args ...interface{}) (database.DB, error) {
 		return nil, err
 	}
 
-	return openDB(dbPath, network, true)
+	return openDB(dbPath, network, true, false)
 }
 
 // useLogger is the callback provided during driver registration that sets the
@@ -76,6 +88,7 @@ func init() {
 		DbType:    dbType,
 		Create:    createDBDriver,
 		Open:      openDBDriver,
+		Reindex:   reindexDBDriver,
 		UseLogger: useLogger,
 	}
 	if err := database.RegisterDriver(driver); err != nil {
@@ -0,0 +1,205 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// This file is part of the ffldb package rather than the ffldb_test package as
+// it provides whitebox testing.
+
+package ffldb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+// makePruneTestBlocks returns the requested number of unique blocks which are
+// large enough that each of them fills most of a 1KiB block file.
+func makePruneTestBlocks(numBlocks int) []*provautil.Block {
+	blocks := make([]*provautil.Block, 0, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, make([]byte, 300)))
+		tx.AddTxOut(wire.NewTxOut(0, nil))
+		msgBlock := wire.MsgBlock{
+			Header: wire.BlockHeader{
+				Timestamp: time.Unix(int64(i), 0),
+				Height:    uint32(i),
+				Nonce:     uint64(i),
+			},
+			Transactions: []*wire.MsgTx{tx},
+		}
+		blocks = append(blocks, provautil.NewBlock(&msgBlock))
+	}
+	return blocks
+}
+
+// TestPruneBlocks ensures pruning deletes the oldest block files, reports the
+// blocks they housed as pruned, and that the pruned state survives reopening
+// the database.
+func TestPruneBlocks(t *testing.T) {
+	// Create a new database to run tests against.
+	dbPath := filepath.Join(os.TempDir(), "ffldb-pruneblocks")
+	_ = os.RemoveAll(dbPath)
+	idb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer os.RemoveAll(dbPath)
+
+	// Change the maximum file size to a small value to force multiple flat
+	// files.
+	idb.(*db).store.maxBlockFileSize = 1024 // 1KiB
+
+	blocks := makePruneTestBlocks(10)
+	for _, block := range blocks {
+		err := idb.Update(func(tx database.Tx) error {
+			return tx.StoreBlock(block)
+		})
+		if err != nil {
+			idb.Close()
+			t.Fatalf("StoreBlock: unexpected error: %v", err)
+		}
+	}
+
+	// Pruning requires a writable transaction.
+	keepHash := blocks[5].Hash()
+	err = idb.View(func(tx database.Tx) error {
+		_, err := tx.PruneBlocks(0, keepHash)
+		return err
+	})
+	if !checkDbError(t, "PruneBlocks", err, database.ErrTxNotWritable) {
+		idb.Close()
+		return
+	}
+
+	// Block files must not be deleted when the transaction is rolled back.
+	errRollback := errors.New("rollback")
+	err = idb.Update(func(tx database.Tx) error {
+		if _, err := tx.PruneBlocks(0, keepHash); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		idb.Close()
+		t.Fatalf("PruneBlocks: unexpected error: %v", err)
+	}
+	err = idb.View(func(tx database.Tx) error {
+		beenPruned, err := tx.BeenPruned()
+		if err != nil {
+			return err
+		}
+		if beenPruned {
+			return errors.New("database reported as pruned")
+		}
+		_, err = tx.FetchBlock(blocks[0].Hash())
+		return err
+	})
+	if err != nil {
+		idb.Close()
+		t.Fatalf("PruneBlocks: rolled back prune: %v", err)
+	}
+
+	var pruned []chainhash.Hash
+	err = idb.Update(func(tx database.Tx) error {
+		var err error
+		pruned, err = tx.PruneBlocks(0, keepHash)
+		return err
+	})
+	if err != nil {
+		idb.Close()
+		t.Fatalf("PruneBlocks: unexpected error: %v", err)
+	}
+	if len(pruned) == 0 || len(pruned) > 5 {
+		idb.Close()
+		t.Fatalf("PruneBlocks: unexpected number of pruned blocks %d",
+			len(pruned))
+	}
+	prunedSet := make(map[chainhash.Hash]struct{}, len(pruned))
+	for _, hash := range pruned {
+		prunedSet[hash] = struct{}{}
+	}
+
+	// checkPruned ensures the first blocks are reported as pruned while
+	// still being known and the remaining blocks are still available.
+	checkPruned := func(db database.DB) bool {
+		err := db.View(func(tx database.Tx) error {
+			beenPruned, err := tx.BeenPruned()
+			if err != nil {
+				return err
+			}
+			if !beenPruned {
+				t.Errorf("BeenPruned: database not reported as " +
+					"pruned")
+			}
+
+			for i, block := range blocks {
+				hash := block.Hash()
+				if _, err := tx.FetchBlockHeader(hash); err != nil {
+					t.Errorf("FetchBlockHeader #%d: unexpected "+
+						"error: %v", i, err)
+				}
+				_, err := tx.FetchBlock(hash)
+				if _, ok := prunedSet[*hash]; ok != (i < len(pruned)) {
+					t.Errorf("PruneBlocks #%d: unexpected pruned "+
+						"state %v", i, ok)
+				}
+				if i < len(pruned) {
+					checkDbError(t, "FetchBlock", err,
+						database.ErrBlockPruned)
+					continue
+				}
+				if err != nil {
+					t.Errorf("FetchBlock #%d: unexpected "+
+						"error: %v", i, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("View: unexpected error: %v", err)
+			return false
+		}
+		return !t.Failed()
+	}
+	if !checkPruned(idb) {
+		idb.Close()
+		return
+	}
+
+	// Reopen the database and ensure the pruned state is loaded and new
+	// blocks can still be stored.
+	if err := idb.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	idb, err = database.Open(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to open test database (%s) %v", dbType, err)
+	}
+	defer idb.Close()
+	if !checkPruned(idb) {
+		return
+	}
+	extraBlock := makePruneTestBlocks(11)[10]
+	err = idb.Update(func(tx database.Tx) error {
+		return tx.StoreBlock(extraBlock)
+	})
+	if err != nil {
+		t.Fatalf("StoreBlock: unexpected error: %v", err)
+	}
+	err = idb.View(func(tx database.Tx) error {
+		_, err := tx.FetchBlock(extraBlock.Hash())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("FetchBlock: unexpected error: %v", err)
+	}
+}
@@ -6,10 +6,14 @@
 package ffldb
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/crc32"
+	"os"
 
 	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/wire"
 )
 
 // The serialized write cursor location format is:
@@ -116,3 +120,169 @@ func reconcileDB(pdb *db, create bool) (database.DB, error) {
 
 	return pdb, nil
 }
+
+// indexBlockFile adds all of the blocks in the flat block file with the passed
+// number to the block index of the passed transaction.  It returns the offset
+// just after the last block record which passed all integrity checks and
+// whether or not the entire file passed them.  A missing file is treated as an
+// empty file which did not pass them.
+//
+// Format: <network><block length><serialized block><checksum>
+func indexBlockFile(tx *transaction, fileNum uint32) (uint32, bool, error) {
+	store := tx.db.store
+	file, err := os.Open(blockFilePath(store.basePath, fileNum))
+	if err != nil {
+		return 0, false, nil
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return 0, false, nil
+	}
+	fileSize := uint32(fi.Size())
+
+	var offset uint32
+	var recordHdr [8]byte
+	for offset < fileSize {
+		// Ensure the record is for the current network and that the
+		// full record is contained in the file.
+		if fileSize-offset < uint32(len(recordHdr))+4 {
+			return offset, false, nil
+		}
+		_, err := file.ReadAt(recordHdr[:], int64(offset))
+		if err != nil {
+			return offset, false, nil
+		}
+		if byteOrder.Uint32(recordHdr[0:4]) != uint32(store.network) {
+			return offset, false, nil
+		}
+		blockLen := byteOrder.Uint32(recordHdr[4:8])
+		fullLen := blockLen + 12
+		if blockLen < blockHdrSize || fullLen < blockLen ||
+			fullLen > fileSize-offset {
+
+			return offset, false, nil
+		}
+
+		// Read the full record and ensure the checksum matches.
+		record := make([]byte, fullLen)
+		if _, err := file.ReadAt(record, int64(offset)); err != nil {
+			return offset, false, nil
+		}
+		serializedChecksum := binary.BigEndian.Uint32(record[fullLen-4:])
+		calculatedChecksum := crc32.Checksum(record[:fullLen-4],
+			castagnoli)
+		if serializedChecksum != calculatedChecksum {
+			return offset, false, nil
+		}
+
+		// Add a record in the block index for the block which includes
+		// its header.
+		blockHdr := record[8 : 8+blockHdrSize]
+		var header wire.BlockHeader
+		err = header.Deserialize(bytes.NewReader(blockHdr))
+		if err != nil {
+			return offset, false, nil
+		}
+		blockHash := header.BlockHash()
+		location := blockLocation{
+			blockFileNum: fileNum,
+			fileOffset:   offset,
+			blockLen:     fullLen,
+		}
+		blockRow := serializeBlockRow(location, blockHdr)
+		err = tx.blockIdxBucket.Put(blockHash[:], blockRow)
+		if err != nil {
+			return offset, false, err
+		}
+
+		offset += fullLen
+	}
+
+	return offset, true, nil
+}
+
+// reindexBlocks rebuilds the block index from the flat block files on disk
+// rather than reconciling the metadata with them.  The index is rebuilt from
+// all block records up to the first one that fails the integrity checks.  That
+// record, along with everything stored after it, is removed since it can't be
+// trusted.  This makes it possible to recover from block files which were left
+// inconsistent with the metadata by an unclean shutdown.
+//
+// The rest of the metadata is not modified, so it is the responsibility of
+// higher layers to rebuild any data which depends on the stored blocks.
+func reindexBlocks(pdb *db) (database.DB, error) {
+	// The blocks in pruned block files are no longer available to rebuild
+	// their index entries.
+	store := pdb.store
+	if firstFileNum, _ := store.pruneState(); firstFileNum != 0 {
+		_ = pdb.Close()
+		str := "unable to reindex the blocks of a pruned database"
+		return nil, makeDbErr(database.ErrBlockPruned, str, nil)
+	}
+
+	log.Info("Reindexing block files...")
+
+	// Remove all existing entries from the block index.
+	err := pdb.Update(func(dbTx database.Tx) error {
+		blockIdxBucket := dbTx.(*transaction).blockIdxBucket
+		var keys [][]byte
+		err := blockIdxBucket.ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := blockIdxBucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = pdb.Close()
+		return nil, err
+	}
+
+	// Index the blocks in each of the block files one file at a time to
+	// limit the size of the transactions.
+	store.writeCursor.RLock()
+	lastFileNum := store.writeCursor.curFileNum
+	store.writeCursor.RUnlock()
+	var endFileNum, endOffset uint32
+	for fileNum := uint32(0); fileNum <= lastFileNum; fileNum++ {
+		var complete bool
+		err := pdb.Update(func(dbTx database.Tx) error {
+			var err error
+			endOffset, complete, err = indexBlockFile(
+				dbTx.(*transaction), fileNum)
+			return err
+		})
+		if err != nil {
+			_ = pdb.Close()
+			return nil, err
+		}
+		endFileNum = fileNum
+		if !complete {
+			break
+		}
+	}
+
+	// Remove all block data after the last block which passed the
+	// integrity checks and update the write cursor accordingly.
+	store.handleRollback(endFileNum, endOffset)
+	err = pdb.Update(func(dbTx database.Tx) error {
+		writeRow := serializeWriteRow(endFileNum, endOffset)
+		return dbTx.Metadata().Put(writeLocKeyName, writeRow)
+	})
+	if err != nil {
+		_ = pdb.Close()
+		return nil, err
+	}
+
+	log.Infof("Reindexed block files up to file %d, offset %d", endFileNum,
+		endOffset)
+	return pdb, nil
+}
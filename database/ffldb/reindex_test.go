@@ -0,0 +1,138 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// This file is part of the ffldb package rather than the ffldb_test package as
+// it provides whitebox testing.
+
+package ffldb
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/database"
+)
+
+// TestReindexBlocks ensures the block index is rebuilt from the flat block
+// files and that a corrupt block record is removed along with all of the block
+// data stored after it.
+func TestReindexBlocks(t *testing.T) {
+	// Create a new database to run tests against.
+	dbPath := filepath.Join(os.TempDir(), "ffldb-reindexblocks")
+	_ = os.RemoveAll(dbPath)
+	idb, err := database.Create(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Failed to create test database (%s) %v", dbType, err)
+	}
+	defer os.RemoveAll(dbPath)
+
+	// Change the maximum file size to a small value to force multiple flat
+	// files.
+	idb.(*db).store.maxBlockFileSize = 1024 // 1KiB
+
+	blocks := makePruneTestBlocks(6)
+	var locations []blockLocation
+	for _, block := range blocks {
+		err := idb.Update(func(tx database.Tx) error {
+			return tx.StoreBlock(block)
+		})
+		if err != nil {
+			idb.Close()
+			t.Fatalf("StoreBlock: unexpected error: %v", err)
+		}
+		err = idb.View(func(tx database.Tx) error {
+			blockRow, err := tx.(*transaction).fetchBlockRow(
+				block.Hash())
+			if err != nil {
+				return err
+			}
+			locations = append(locations, deserializeBlockLoc(blockRow))
+			return nil
+		})
+		if err != nil {
+			idb.Close()
+			t.Fatalf("fetchBlockRow: unexpected error: %v", err)
+		}
+	}
+	if err := idb.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+
+	// Corrupt the data of the fourth block.
+	corruptLoc := locations[3]
+	filePath := blockFilePath(dbPath, corruptLoc.blockFileNum)
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("OpenFile: unexpected error: %v", err)
+	}
+	_, err = file.WriteAt([]byte{0xff, 0xff}, int64(corruptLoc.fileOffset+
+		corruptLoc.blockLen/2))
+	file.Close()
+	if err != nil {
+		t.Fatalf("WriteAt: unexpected error: %v", err)
+	}
+
+	idb, err = database.Reindex(dbType, dbPath, blockDataNet)
+	if err != nil {
+		t.Fatalf("Reindex: unexpected error: %v", err)
+	}
+	defer idb.Close()
+
+	// Only the blocks before the corrupt one must remain.
+	wantHashes := []chainhash.Hash{*blocks[0].Hash(), *blocks[1].Hash(),
+		*blocks[2].Hash()}
+	err = idb.View(func(tx database.Tx) error {
+		hashes, err := tx.StoredBlockHashes()
+		if err != nil {
+			return err
+		}
+		if len(hashes) != len(wantHashes) {
+			t.Errorf("StoredBlockHashes: unexpected number of "+
+				"hashes - got %d, want %d", len(hashes),
+				len(wantHashes))
+			return nil
+		}
+		for i := range hashes {
+			if hashes[i] != wantHashes[i] {
+				t.Errorf("StoredBlockHashes #%d: unexpected "+
+					"hash - got %v, want %v", i, hashes[i],
+					wantHashes[i])
+			}
+		}
+
+		for i, block := range blocks {
+			_, err := tx.FetchBlock(block.Hash())
+			if i < len(wantHashes) {
+				if err != nil {
+					t.Errorf("FetchBlock #%d: unexpected "+
+						"error: %v", i, err)
+				}
+				continue
+			}
+			checkDbError(t, "FetchBlock", err,
+				database.ErrBlockNotFound)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: unexpected error: %v", err)
+	}
+
+	// The removed blocks must be able to be stored again.
+	err = idb.Update(func(tx database.Tx) error {
+		return tx.StoreBlock(blocks[3])
+	})
+	if err != nil {
+		t.Fatalf("StoreBlock: unexpected error: %v", err)
+	}
+	err = idb.View(func(tx database.Tx) error {
+		_, err := tx.FetchBlock(blocks[3].Hash())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("FetchBlock: unexpected error: %v", err)
+	}
+}
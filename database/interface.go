@@ -307,6 +307,8 @@ type Tx interface {
 	// The interface contract guarantees at least the following errors will
 	// be returned (other implementation-specific errors are possible):
 	//   - ErrBlockNotFound if the requested block hash does not exist
+	//   - ErrBlockPruned if the data for the requested block has been
+	//     pruned
 	//   - ErrTxClosed if the transaction has already been closed
 	//   - ErrCorruption if the database has somehow become corrupted
 	//
@@ -325,6 +327,8 @@ type Tx interface {
 	// be returned (other implementation-specific errors are possible):
 	//   - ErrBlockNotFound if the any of the requested block hashes do not
 	//     exist
+	//   - ErrBlockPruned if the data for any of the requested blocks has
+	//     been pruned
 	//   - ErrTxClosed if the transaction has already been closed
 	//   - ErrCorruption if the database has somehow become corrupted
 	//
@@ -350,6 +354,8 @@ type Tx interface {
 	// The interface contract guarantees at least the following errors will
 	// be returned (other implementation-specific errors are possible):
 	//   - ErrBlockNotFound if the requested block hash does not exist
+	//   - ErrBlockPruned if the data for the requested block has been
+	//     pruned
 	//   - ErrBlockRegionInvalid if the region exceeds the bounds of the
 	//     associated block
 	//   - ErrTxClosed if the transaction has already been closed
@@ -378,6 +384,8 @@ type Tx interface {
 	// be returned (other implementation-specific errors are possible):
 	//   - ErrBlockNotFound if any of the requested block hashed do not
 	//     exist
+	//   - ErrBlockPruned if the data for any of the requested blocks has
+	//     been pruned
 	//   - ErrBlockRegionInvalid if one or more region exceed the bounds of
 	//     the associated block
 	//   - ErrTxClosed if the transaction has already been closed
@@ -390,6 +398,50 @@ type Tx interface {
 	// implementations.
 	FetchBlockRegions(regions []BlockRegion) ([][]byte, error)
 
+	// PruneBlocks deletes the oldest stored block data until the total
+	// size of the stored blocks is at or below the provided target size in
+	// bytes.  The data for the block identified by keepHash and any blocks
+	// stored after it is never deleted, so the target size might not be
+	// reached.  The hashes of the blocks whose data was deleted are
+	// returned.
+	//
+	// Pruned blocks are still known to the database, so their headers are
+	// still available and HasBlock still reports them, however attempting
+	// to fetch their data will result in ErrBlockPruned.
+	//
+	// The block data is only deleted once the transaction is committed, so
+	// nothing is deleted when the transaction is rolled back.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrBlockNotFound if the block identified by keepHash does not
+	//     exist
+	//   - ErrTxNotWritable if attempted against a read-only transaction
+	//   - ErrTxClosed if the transaction has already been closed
+	//
+	// Other errors are possible depending on the implementation.
+	PruneBlocks(targetSize uint64, keepHash *chainhash.Hash) ([]chainhash.Hash, error)
+
+	// StoredBlockHashes returns the hashes of all blocks whose data is
+	// available in the database in the order they were stored.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxClosed if the transaction has already been closed
+	//
+	// Other errors are possible depending on the implementation.
+	StoredBlockHashes() ([]chainhash.Hash, error)
+
+	// BeenPruned returns whether or not the data for any blocks has been
+	// deleted from the database by PruneBlocks.
+	//
+	// The interface contract guarantees at least the following errors will
+	// be returned (other implementation-specific errors are possible):
+	//   - ErrTxClosed if the transaction has already been closed
+	//
+	// Other errors are possible depending on the implementation.
+	BeenPruned() (bool, error)
+
 	// ******************************************************************
 	// Methods related to both atomic metadata storage and block storage.
 	// ******************************************************************
@@ -40,6 +40,17 @@ Application Options:
                             banning misbehaving peers.
       --banduration=        How long to ban misbehaving peers.  Valid time units
                             are {s, m, h}.  Minimum 1 second (24h0m0s)
+      --nodekey=            Hex-encoded private key this node proves control of
+                            to peers during the handshake -- Peers which
+                            require authentication only accept admin keys and
+                            ASP keys
+      --authpeers           Only connect to and accept connections from peers
+                            which prove control of an admin key or an ASP key
+                            -- Implies --requireencryption
+      --noencryption        Disable encryption of connections to peers which
+                            support it
+      --requireencryption   Only connect to and accept connections from peers
+                            which encrypt the connection
   -u, --rpcuser=            Username for RPC connections
   -P, --rpcpass=            Password for RPC connections
       --rpclimituser=       Username for limited RPC connections
@@ -76,6 +87,22 @@ Application Options:
       --regtest             Use the regression test network
       --simnet              Use the simulation test network
       --addcheckpoint=      Add a custom checkpoint.  Format: '<height>:<hash>'
+      --assumevalid=        Skip script validation for ancestors of the block
+                            with this hash during initial sync -- Use 0 to
+                            validate all scripts (default: network specific)
+      --loadsnapshot=       Bootstrap the chain state from the given utxo set
+                            snapshot file created with the dumptxoutset RPC
+                            when the chain has no blocks yet
+      --reindex             Rebuild the block index from the stored block files
+                            and then the chain state and optional indexes from
+                            the blocks on start up
+      --reindex-chainstate  Rebuild the chain state and optional indexes from
+                            the stored blocks on start up
+      --prune=              Delete the oldest blocks once the stored blocks
+                            exceed the given size in MiB -- The node no longer
+                            serves the full block history and the optional
+                            indexes may not be used (0 = disabled, minimum
+                            1024)
       --nocheckpoints       Disable built-in checkpoints.  Don't do this unless
                             you know what you're doing.
       --dbtype=             Database backend to use for the Block Chain (ffldb)
@@ -95,6 +122,10 @@ Application Options:
                             minute (15)
       --relaypriority       Require free or low-fee transactions to have
                             high priority for relaying
+      --freerelaytag=       Accept free or low-fee transactions submitted via
+                            RPC by admin users with the specified tag
+                            regardless of their size and priority -- May be
+                            specified multiple times
       --maxorphantx=        Max number of orphan transactions to keep in memory
                             (100)
       --generate            Generate (mine) blocks using the CPU
@@ -109,6 +140,15 @@ Application Options:
       --blockprioritysize=  Size in bytes for high-priority/low-fee transactions
                             when creating a block (50000)
       --nopeerbloomfilters  Disable bloom filtering support.
+      --statequeries        Serve utxo and admin state queries of light
+                            clients.
+      --nocfilters          Disable the committed filter index and serving
+                            committed filters to light clients.
+      --acceptreplacement   Accept transactions which replace transactions in
+                            the mempool that signal replacement through the
+                            Replace-By-Fee (RBF) policy, such as to raise their
+                            fee or to cancel them by paying their inputs back
+                            to the sender
       --sigcachemaxsize=    The maximum number of entries in the signature
                             verification cache.
       --blocksonly          Do not accept transactions from remote peers.
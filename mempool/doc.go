@@ -40,6 +40,10 @@ be an exhaustive list.
    - Reject non-fully-spent duplicate transactions
    - Reject coinbase transactions
    - Reject double spends (both from the chain and other transactions in pool)
+   - Optional replacement of transactions in the pool which signal it by
+     replacements which pay higher fees.  There is no separate cancellation,
+     a transaction is cancelled by replacing it with one which pays its
+     inputs back to the sender
    - Reject invalid transactions according to the network consensus rules
    - Full script execution and validation with signature cache support
    - Individual transaction query support
@@ -56,13 +60,29 @@ be an exhaustive list.
    - Max signature operations per transaction
    - Max orphan transaction size
    - Max number of orphan transactions allowed
+   - Option to accept replacement transactions
+   - Max total size of the pool with eviction of the transactions with the
+     lowest fee rates and a dynamic minimum relay fee raised by evictions
+   - Max number and total size of the unconfirmed ancestors and descendants
+     of a transaction
+   - Exemption of admin transactions, which are free by design, from the fee
+     and priority based limits and from eviction
+   - Per-tag policy overrides for transactions submitted locally by trusted
+     clients, such as accepting free transactions regardless of their size
+     and priority
  - Additional metadata tracking for each transaction
    - Timestamp when the transaction was added to the pool
    - Most recent block height when the transaction was added to the pool
    - The fee the transaction pays
    - The starting priority for the transaction
+   - The number, total size, and total fees of the unconfirmed ancestors and
+     descendants of the transaction
+   - The tag the transaction was submitted with, such as a business
+     identifier provided by the submitter
  - Manual control of transaction removal
    - Recursive removal of all dependent transactions
+ - Testing whether transactions, including dependent packages of transactions,
+   would be accepted without adding them to the pool
 
 Errors
 
@@ -93,6 +93,22 @@ func extractRejectCode(err error) (wire.RejectCode, bool) {
 		case blockchain.ErrForkTooOld:
 			code = wire.RejectCheckpoint
 
+		// Rejected due to a Prova specific rule.
+		case blockchain.ErrUnknownKeyID:
+			code = wire.RejectUnknownKeyID
+		case blockchain.ErrKeyIDRevoked:
+			code = wire.RejectKeyIDRevoked
+		case blockchain.ErrThreadOutOfOrder:
+			code = wire.RejectThreadOrder
+		case blockchain.ErrInvalidAdminOp:
+			code = wire.RejectInvalidAdminOp
+		case blockchain.ErrValidateKeyRateLimited:
+			code = wire.RejectRateLimited
+		case blockchain.ErrFeeTooHigh:
+			code = wire.RejectHighFee
+		case blockchain.ErrInvalidAdminTx:
+			code = wire.RejectInvalidAdmin
+
 		// Everything else is due to the block or transaction being invalid.
 		default:
 			code = wire.RejectInvalid
@@ -0,0 +1,761 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/mining"
+	"github.com/bitgo/prova/provautil"
+)
+
+const (
+	// estimateFeeDepth is the maximum number of blocks before a transaction
+	// is confirmed that we want to track.
+	estimateFeeDepth = 25
+
+	// estimateFeeBinSize is the number of txs stored in each bin.
+	estimateFeeBinSize = 100
+
+	// estimateFeeMaxReplacements is the max number of replacements that
+	// can be made by the txs found in a given block.
+	estimateFeeMaxReplacements = 10
+
+	// DefaultEstimateFeeMaxRollback is the default number of rollbacks
+	// allowed by the fee estimator for orphaned blocks.
+	DefaultEstimateFeeMaxRollback = 2
+
+	// DefaultEstimateFeeMinRegisteredBlocks is the default minimum
+	// number of blocks which must be observed by the fee estimator before
+	// it will provide fee estimations.
+	DefaultEstimateFeeMinRegisteredBlocks = 3
+
+	// EstimateFeeMaxTarget is the maximum number of blocks from now for
+	// which the fee estimator provides estimates.
+	EstimateFeeMaxTarget = estimateFeeDepth
+
+	// estimateFeeSaveVersion is the version of the serialized fee
+	// estimator state.  When the serialization format changes, the state
+	// of a previous version is not upgraded.  Instead, fee estimation just
+	// starts over.
+	estimateFeeSaveVersion = 1
+
+	bytesPerKb = 1000
+)
+
+var (
+	// EstimateFeeDatabaseKey is the key that we use to store the fee
+	// estimator state in the database.
+	EstimateFeeDatabaseKey = []byte("estimatefee")
+)
+
+// AtomsPerByte is a fee rate with units of atoms per byte.
+type AtomsPerByte float64
+
+// RMGPerKilobyte is a fee rate with units of RMG per kilobyte.
+type RMGPerKilobyte float64
+
+// NewAtomsPerByte returns the fee rate of a transaction of the given size in
+// bytes which pays the given fee.
+func NewAtomsPerByte(fee provautil.Amount, size uint32) AtomsPerByte {
+	return AtomsPerByte(float64(fee) / float64(size))
+}
+
+// ToRMGPerKb returns the fee rate converted to RMG per kilobyte.
+func (rate AtomsPerByte) ToRMGPerKb() RMGPerKilobyte {
+	return RMGPerKilobyte(float64(rate) * bytesPerKb /
+		provautil.AtomsPerGram)
+}
+
+// Fee returns the fee of a transaction of the given size in bytes which pays
+// the fee rate.
+func (rate AtomsPerByte) Fee(size uint32) provautil.Amount {
+	return provautil.Amount(float64(rate) * float64(size))
+}
+
+// writeElements writes the passed fixed size elements to the passed writer in
+// big endian.
+func writeElements(w io.Writer, elements ...interface{}) error {
+	for _, element := range elements {
+		if err := binary.Write(w, binary.BigEndian, element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readElements reads the passed fixed size elements from the passed reader in
+// big endian.
+func readElements(r io.Reader, elements ...interface{}) error {
+	for _, element := range elements {
+		if err := binary.Read(r, binary.BigEndian, element); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observedTransaction represents an observed transaction and some additional
+// data required for the fee estimation algorithm.
+type observedTransaction struct {
+	// hash is the hash of the transaction.
+	hash chainhash.Hash
+
+	// feeRate is the fee per byte of the transaction in atoms.
+	feeRate AtomsPerByte
+
+	// observed is the block height when the transaction was observed.
+	observed uint32
+
+	// mined is the height of the block in which the transaction was
+	// mined.  It is mining.UnminedHeight while the transaction is not yet
+	// mined.
+	mined uint32
+}
+
+// serialize writes the observed transaction to the passed writer.
+func (o *observedTransaction) serialize(w io.Writer) error {
+	return writeElements(w, o.hash, o.feeRate, o.observed, o.mined)
+}
+
+// deserializeObservedTransaction reads an observed transaction which was
+// written with serialize from the passed reader.
+func deserializeObservedTransaction(r io.Reader) (*observedTransaction, error) {
+	var o observedTransaction
+	err := readElements(r, &o.hash, &o.feeRate, &o.observed, &o.mined)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// registeredBlock has the hash of a block and the list of transactions it mined
+// which were dropped from the bins to make room for them.  It is used if
+// Rollback is called to reverse the effect of registering a block.
+type registeredBlock struct {
+	hash         chainhash.Hash
+	transactions []*observedTransaction
+}
+
+// serialize writes the registered block to the passed writer, referring to the
+// transactions by their index in the passed map.
+func (rb *registeredBlock) serialize(w io.Writer, txs map[*observedTransaction]uint32) error {
+	if err := writeElements(w, rb.hash); err != nil {
+		return err
+	}
+	indexes := make([]uint32, 0, len(rb.transactions)+1)
+	indexes = append(indexes, uint32(len(rb.transactions)))
+	for _, o := range rb.transactions {
+		indexes = append(indexes, txs[o])
+	}
+	return binary.Write(w, binary.BigEndian, indexes)
+}
+
+// deserializeRegisteredBlock reads a registered block which was written with
+// serialize from the passed reader, resolving the transactions with the passed
+// map.
+func deserializeRegisteredBlock(r io.Reader, txs map[uint32]*observedTransaction) (*registeredBlock, error) {
+	var rb registeredBlock
+	var numTransactions uint32
+	if err := readElements(r, &rb.hash, &numTransactions); err != nil {
+		return nil, err
+	}
+	rb.transactions = make([]*observedTransaction, numTransactions)
+	for i := range rb.transactions {
+		var index uint32
+		if err := binary.Read(r, binary.BigEndian, &index); err != nil {
+			return nil, err
+		}
+		o, ok := txs[index]
+		if !ok {
+			return nil, fmt.Errorf("invalid transaction reference %d",
+				index)
+		}
+		rb.transactions[i] = o
+	}
+	return &rb, nil
+}
+
+// FeeEstimator manages the data necessary to create fee estimations.  It
+// tracks how many blocks the transactions observed in the memory pool take to
+// be mined, binned by the number of blocks, and estimates the fee rate which
+// is needed to be mined within a given number of blocks from the fee rates of
+// the bins.
+//
+// It is safe for concurrent access.
+type FeeEstimator struct {
+	// maxRollback is the maximum number of blocks which can be rolled
+	// back.
+	maxRollback uint32
+
+	// binSize is the maximum number of transactions in each bin.
+	binSize int32
+
+	// maxReplacements is the maximum number of replacements that can be
+	// made in a single bin per block.
+	maxReplacements int32
+
+	// minRegisteredBlocks is the minimum number of blocks that must be
+	// registered with the fee estimator before it will provide answers.
+	minRegisteredBlocks uint32
+
+	// lastKnownHeight is the height of the last registered block.
+	lastKnownHeight uint32
+
+	// numBlocksRegistered is the number of blocks that have been
+	// registered.
+	numBlocksRegistered uint32
+
+	mtx      sync.RWMutex
+	observed map[chainhash.Hash]*observedTransaction
+	bin      [estimateFeeDepth][]*observedTransaction
+
+	// cached houses the estimates until the next block is registered or
+	// rolled back.
+	cached []AtomsPerByte
+
+	// dropped houses the transactions that have been removed from the
+	// bins by the recently registered blocks.  This allows the estimator
+	// to revert in case of an orphaned block.
+	dropped []*registeredBlock
+}
+
+// NewFeeEstimator creates a FeeEstimator for which at most maxRollback blocks
+// can be unregistered and which returns an error unless minRegisteredBlocks
+// have been registered with it.
+func NewFeeEstimator(maxRollback, minRegisteredBlocks uint32) *FeeEstimator {
+	return &FeeEstimator{
+		maxRollback:         maxRollback,
+		minRegisteredBlocks: minRegisteredBlocks,
+		lastKnownHeight:     mining.UnminedHeight,
+		binSize:             estimateFeeBinSize,
+		maxReplacements:     estimateFeeMaxReplacements,
+		observed:            make(map[chainhash.Hash]*observedTransaction),
+		dropped:             make([]*registeredBlock, 0, maxRollback),
+	}
+}
+
+// ObserveTransaction is called when a new transaction is accepted to the memory
+// pool.
+func (ef *FeeEstimator) ObserveTransaction(t *TxDesc) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// If we haven't seen a block yet we don't know when this one arrived,
+	// so we ignore it.
+	if ef.lastKnownHeight == mining.UnminedHeight {
+		return
+	}
+
+	hash := *t.Tx.Hash()
+	if _, ok := ef.observed[hash]; !ok {
+		size := uint32(t.Tx.MsgTx().SerializeSize())
+		ef.observed[hash] = &observedTransaction{
+			hash:     hash,
+			feeRate:  NewAtomsPerByte(provautil.Amount(t.Fee), size),
+			observed: t.Height,
+			mined:    mining.UnminedHeight,
+		}
+	}
+}
+
+// RegisterBlock informs the fee estimator of a new block to take into account.
+// An error is returned when the block does not extend the last registered
+// block.  The estimator continues from the block in that case, but forgets the
+// observed transactions which have not been mined yet since the number of
+// blocks they take to be mined can no longer be determined.
+func (ef *FeeEstimator) RegisterBlock(block *provautil.Block) error {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// The previous estimates are invalid, so delete them.
+	ef.cached = nil
+
+	height := block.Height()
+	if ef.lastKnownHeight != mining.UnminedHeight &&
+		height != ef.lastKnownHeight+1 {
+
+		str := fmt.Sprintf("intermediate block not recorded; current "+
+			"height is %d; new height is %d", ef.lastKnownHeight,
+			height)
+		ef.observed = make(map[chainhash.Hash]*observedTransaction)
+		ef.dropped = ef.dropped[:0]
+		ef.lastKnownHeight = height
+		return errors.New(str)
+	}
+
+	// Update the last known height.
+	ef.lastKnownHeight = height
+	ef.numBlocksRegistered++
+
+	// Count the number of replacements we make per bin so that we don't
+	// replace too many.
+	var replacementCounts [estimateFeeDepth]int
+
+	// Keep track of which txs were dropped in case of an orphan block.
+	dropped := &registeredBlock{hash: *block.Hash()}
+
+	// Go through the txs in the block in a random order so the
+	// replacement limit does not favor the transactions at the start of
+	// the block.
+	transactions := block.Transactions()
+	for _, i := range rand.Perm(len(transactions)) {
+		// Have we observed this tx in the mempool?
+		o, ok := ef.observed[*transactions[i].Hash()]
+		if !ok || o.mined != mining.UnminedHeight {
+			continue
+		}
+
+		// Ignore transactions which took too long to be mined.  This
+		// also avoids an out-of-bounds index should the transaction
+		// have been observed at the current height.
+		if height <= o.observed {
+			continue
+		}
+		blocksToConfirm := height - o.observed - 1
+		if blocksToConfirm >= estimateFeeDepth {
+			continue
+		}
+
+		// Make sure we do not replace too many transactions per block.
+		if replacementCounts[blocksToConfirm] == int(ef.maxReplacements) {
+			continue
+		}
+		replacementCounts[blocksToConfirm]++
+		o.mined = height
+
+		// Replace a random element of a full bin with this tx, taking
+		// care not to drop the transactions we have just added from
+		// this same block.
+		bin := ef.bin[blocksToConfirm]
+		if len(bin) == int(ef.binSize) {
+			l := int(ef.binSize) - replacementCounts[blocksToConfirm] + 1
+			drop := rand.Intn(l)
+			dropped.transactions = append(dropped.transactions,
+				bin[drop])
+
+			bin[drop] = bin[l-1]
+			bin[l-1] = o
+		} else {
+			bin = append(bin, o)
+		}
+		ef.bin[blocksToConfirm] = bin
+	}
+
+	// Forget the unmined txs that have been observed for too long.
+	for hash, o := range ef.observed {
+		if o.mined == mining.UnminedHeight &&
+			height-o.observed >= estimateFeeDepth {
+
+			delete(ef.observed, hash)
+		}
+	}
+
+	// Add dropped list to history.
+	if ef.maxRollback == 0 {
+		return nil
+	}
+	if uint32(len(ef.dropped)) == ef.maxRollback {
+		ef.dropped = append(ef.dropped[1:], dropped)
+	} else {
+		ef.dropped = append(ef.dropped, dropped)
+	}
+
+	return nil
+}
+
+// LastKnownHeight returns the height of the last block which was registered.
+func (ef *FeeEstimator) LastKnownHeight() uint32 {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	return ef.lastKnownHeight
+}
+
+// Rollback unregisters a recently registered block from the FeeEstimator.  This
+// can be used to reverse the effect of an orphaned block on the fee estimator.
+// The maximum number of rollbacks allowed is given by maxRollback.  When the
+// block is not one of the recently registered blocks, the estimator accepts
+// the next registered block at any height instead of rejecting all further
+// blocks.
+//
+// NOTE: Not everything can be rolled back because some transactions are
+// deleted if they have been observed too long ago.  That means the result of
+// Rollback won't always be exactly the same as if the last block had not
+// happened, but it should be close enough.
+func (ef *FeeEstimator) Rollback(hash *chainhash.Hash) error {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// Find this block in the stack of recent registered blocks.
+	var n int
+	for n = 1; n <= len(ef.dropped); n++ {
+		if ef.dropped[len(ef.dropped)-n].hash.IsEqual(hash) {
+			break
+		}
+	}
+	if n > len(ef.dropped) {
+		ef.cached = nil
+		ef.lastKnownHeight = mining.UnminedHeight
+		return errors.New("no such block was recently registered")
+	}
+
+	for i := 0; i < n; i++ {
+		ef.rollback()
+	}
+
+	return nil
+}
+
+// rollback rolls back the effect of the last block in the stack of registered
+// blocks.
+//
+// This function MUST be called with the fee estimator lock held (for writes).
+func (ef *FeeEstimator) rollback() {
+	// The previous estimates are invalid, so delete them.
+	ef.cached = nil
+
+	// Pop the last list of dropped txs from the stack.
+	last := len(ef.dropped) - 1
+	if last == -1 {
+		// Cannot really happen because the exported calling function
+		// only rolls back a block already known to be in the list of
+		// dropped transactions.
+		return
+	}
+	dropped := ef.dropped[last]
+
+	// Put the dropped txs back in place of the txs the block added in each
+	// bin, keeping track of where we are in each bin.
+	var replacementCounters [estimateFeeDepth]int
+	for _, o := range dropped.transactions {
+		// Which bin was this tx in?
+		blocksToConfirm := o.mined - o.observed - 1
+		bin := ef.bin[blocksToConfirm]
+		counter := replacementCounters[blocksToConfirm]
+		for ; counter < len(bin); counter++ {
+			prev := bin[counter]
+			if prev.mined == ef.lastKnownHeight {
+				prev.mined = mining.UnminedHeight
+				bin[counter] = o
+				counter++
+				break
+			}
+		}
+		replacementCounters[blocksToConfirm] = counter
+	}
+
+	// Remove the remaining txs the block added which did not replace any
+	// other when they were entered.
+	for i, j := range replacementCounters {
+		bin := ef.bin[i]
+		for j < len(bin) {
+			prev := bin[j]
+			if prev.mined != ef.lastKnownHeight {
+				j++
+				continue
+			}
+
+			prev.mined = mining.UnminedHeight
+			copy(bin[j:], bin[j+1:])
+			bin[len(bin)-1] = nil
+			bin = bin[:len(bin)-1]
+		}
+		ef.bin[i] = bin
+	}
+
+	ef.dropped = ef.dropped[:last]
+	ef.numBlocksRegistered--
+	ef.lastKnownHeight--
+}
+
+// estimateFeeSet is a set of the fee rates of the txs in the bins sorted by
+// descending fee rate.
+type estimateFeeSet struct {
+	feeRate []AtomsPerByte
+	bin     [estimateFeeDepth]uint32
+}
+
+// Len returns the number of items in the set.  It is part of the
+// sort.Interface implementation.
+func (b *estimateFeeSet) Len() int { return len(b.feeRate) }
+
+// Less returns whether the item with index i should sort before the item with
+// index j.  It is part of the sort.Interface implementation.
+func (b *estimateFeeSet) Less(i, j int) bool {
+	return b.feeRate[i] > b.feeRate[j]
+}
+
+// Swap swaps the items at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (b *estimateFeeSet) Swap(i, j int) {
+	b.feeRate[i], b.feeRate[j] = b.feeRate[j], b.feeRate[i]
+}
+
+// estimateFee returns the estimated fee rate for a transaction to confirm in
+// the given number of blocks from now given the data set we have collected.
+// The txs which confirmed faster than the requested number of blocks are
+// assumed to have the highest fee rates, so the estimate is the median fee
+// rate of the position the txs of the requested bin would occupy.
+func (b *estimateFeeSet) estimateFee(confirmations int) AtomsPerByte {
+	if confirmations <= 0 || confirmations > estimateFeeDepth ||
+		len(b.feeRate) == 0 {
+
+		return 0
+	}
+
+	var min int
+	for i := 0; i < confirmations-1; i++ {
+		min += int(b.bin[i])
+	}
+	max := min + int(b.bin[confirmations-1]) - 1
+	if max < min {
+		max = min
+	}
+	feeIndex := (min + max) / 2
+	if feeIndex >= len(b.feeRate) {
+		feeIndex = len(b.feeRate) - 1
+	}
+
+	return b.feeRate[feeIndex]
+}
+
+// newEstimateFeeSet creates a temporary data structure that can be used to find
+// all fee estimates.
+//
+// This function MUST be called with the fee estimator lock held (for reads).
+func (ef *FeeEstimator) newEstimateFeeSet() *estimateFeeSet {
+	set := &estimateFeeSet{}
+
+	capacity := 0
+	for i, b := range ef.bin {
+		set.bin[i] = uint32(len(b))
+		capacity += len(b)
+	}
+
+	set.feeRate = make([]AtomsPerByte, 0, capacity)
+	for _, b := range ef.bin {
+		for _, o := range b {
+			set.feeRate = append(set.feeRate, o.feeRate)
+		}
+	}
+	sort.Sort(set)
+
+	return set
+}
+
+// estimates returns the set of all fee estimates from 1 to estimateFeeDepth
+// confirmations from now.
+//
+// This function MUST be called with the fee estimator lock held (for reads).
+func (ef *FeeEstimator) estimates() []AtomsPerByte {
+	set := ef.newEstimateFeeSet()
+
+	estimates := make([]AtomsPerByte, estimateFeeDepth)
+	for i := 0; i < estimateFeeDepth; i++ {
+		estimates[i] = set.estimateFee(i + 1)
+	}
+
+	return estimates
+}
+
+// EstimateFee estimates the fee rate needed to have a tx confirmed the given
+// number of blocks from now.
+func (ef *FeeEstimator) EstimateFee(numBlocks uint32) (RMGPerKilobyte, error) {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// If the number of registered blocks is below the minimum, return an
+	// error.
+	if ef.numBlocksRegistered < ef.minRegisteredBlocks {
+		return -1, errors.New("not enough blocks have been observed")
+	}
+
+	if numBlocks == 0 {
+		return -1, errors.New("cannot confirm transaction in zero blocks")
+	}
+
+	if numBlocks > estimateFeeDepth {
+		return -1, fmt.Errorf("can only estimate fees for up to %d "+
+			"blocks from now", estimateFeeDepth)
+	}
+
+	// If there are no cached results, generate them.
+	if ef.cached == nil {
+		ef.cached = ef.estimates()
+	}
+
+	return ef.cached[int(numBlocks)-1].ToRMGPerKb(), nil
+}
+
+// FeeEstimatorState represents a saved FeeEstimator that can be restored with
+// data from an earlier session of the program.
+type FeeEstimatorState []byte
+
+// observedTxSet is a set of txs sorted by hash.  It exists for serialization
+// purposes so that a serialized state always comes out the same.
+type observedTxSet []*observedTransaction
+
+// Len returns the number of items in the set.  It is part of the
+// sort.Interface implementation.
+func (q observedTxSet) Len() int { return len(q) }
+
+// Less returns whether the item with index i should sort before the item with
+// index j.  It is part of the sort.Interface implementation.
+func (q observedTxSet) Less(i, j int) bool {
+	return bytes.Compare(q[i].hash[:], q[j].hash[:]) < 0
+}
+
+// Swap swaps the items at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (q observedTxSet) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+// Save records the current state of the FeeEstimator to a []byte that can be
+// restored later with RestoreFeeEstimator.
+func (ef *FeeEstimator) Save() FeeEstimatorState {
+	ef.mtx.Lock()
+	defer ef.mtx.Unlock()
+
+	// Writes to a bytes.Buffer can't fail, so the errors are ignored.
+	var w bytes.Buffer
+	writeElements(&w, uint32(estimateFeeSaveVersion), ef.maxRollback,
+		ef.binSize, ef.maxReplacements, ef.minRegisteredBlocks,
+		ef.lastKnownHeight, ef.numBlocksRegistered)
+
+	// Put all the observed transactions in a sorted list.
+	ots := make([]*observedTransaction, 0, len(ef.observed))
+	for _, o := range ef.observed {
+		ots = append(ots, o)
+	}
+	sort.Sort(observedTxSet(ots))
+
+	observed := make(map[*observedTransaction]uint32, len(ots))
+	binary.Write(&w, binary.BigEndian, uint32(len(ots)))
+	for i, o := range ots {
+		o.serialize(&w)
+		observed[o] = uint32(i)
+	}
+
+	// Save the bins.
+	for _, list := range ef.bin {
+		indexes := make([]uint32, 0, len(list)+1)
+		indexes = append(indexes, uint32(len(list)))
+		for _, o := range list {
+			indexes = append(indexes, observed[o])
+		}
+		binary.Write(&w, binary.BigEndian, indexes)
+	}
+
+	// Save the dropped transactions.
+	binary.Write(&w, binary.BigEndian, uint32(len(ef.dropped)))
+	for _, registered := range ef.dropped {
+		registered.serialize(&w, observed)
+	}
+
+	return FeeEstimatorState(w.Bytes())
+}
+
+// RestoreFeeEstimator takes a FeeEstimatorState that was previously returned by
+// Save and restores it to a FeeEstimator.
+func RestoreFeeEstimator(data FeeEstimatorState) (*FeeEstimator, error) {
+	r := bytes.NewReader(data)
+
+	// Check the version.
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != estimateFeeSaveVersion {
+		return nil, fmt.Errorf("incorrect version: expected %d found %d",
+			estimateFeeSaveVersion, version)
+	}
+
+	ef := &FeeEstimator{
+		observed: make(map[chainhash.Hash]*observedTransaction),
+	}
+
+	// Read the basic parameters.
+	err := readElements(r, &ef.maxRollback, &ef.binSize,
+		&ef.maxReplacements, &ef.minRegisteredBlocks,
+		&ef.lastKnownHeight, &ef.numBlocksRegistered)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the observed transactions.
+	var numObserved uint32
+	if err := binary.Read(r, binary.BigEndian, &numObserved); err != nil {
+		return nil, err
+	}
+	observed := make(map[uint32]*observedTransaction)
+	for i := uint32(0); i < numObserved; i++ {
+		o, err := deserializeObservedTransaction(r)
+		if err != nil {
+			return nil, err
+		}
+		observed[i] = o
+		ef.observed[o.hash] = o
+	}
+
+	// Read the bins.
+	for i := range ef.bin {
+		var numTransactions uint32
+		err := binary.Read(r, binary.BigEndian, &numTransactions)
+		if err != nil {
+			return nil, err
+		}
+		if numTransactions > uint32(ef.binSize) {
+			return nil, fmt.Errorf("bin %d has %d transactions which "+
+				"exceeds the bin size of %d", i,
+				numTransactions, ef.binSize)
+		}
+		bin := make([]*observedTransaction, numTransactions)
+		for j := range bin {
+			var index uint32
+			err := binary.Read(r, binary.BigEndian, &index)
+			if err != nil {
+				return nil, err
+			}
+			o, ok := observed[index]
+			if !ok {
+				return nil, fmt.Errorf("invalid transaction "+
+					"reference %d", index)
+			}
+			bin[j] = o
+		}
+		ef.bin[i] = bin
+	}
+
+	// Read the dropped transactions.
+	var numDropped uint32
+	if err := binary.Read(r, binary.BigEndian, &numDropped); err != nil {
+		return nil, err
+	}
+	if numDropped > ef.maxRollback {
+		return nil, fmt.Errorf("%d dropped blocks exceeds the maximum "+
+			"rollback of %d", numDropped, ef.maxRollback)
+	}
+	ef.dropped = make([]*registeredBlock, numDropped, ef.maxRollback)
+	for i := range ef.dropped {
+		rb, err := deserializeRegisteredBlock(r, observed)
+		if err != nil {
+			return nil, err
+		}
+		ef.dropped[i] = rb
+	}
+
+	return ef, nil
+}
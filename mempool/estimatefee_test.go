@@ -0,0 +1,155 @@
+// Copyright (c) 2016 The btcsuite developers
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/mining"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+// newTestFeeTx returns a transaction descriptor for a unique transaction which
+// pays the passed fee and was added to the pool at the passed height.
+func newTestFeeTx(id uint32, fee int64, height uint32) *TxDesc {
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxOut(wire.NewTxOut(1, nil))
+	msgTx.LockTime = id
+	return &TxDesc{
+		TxDesc: mining.TxDesc{
+			Tx:     provautil.NewTx(msgTx),
+			Added:  time.Now(),
+			Height: height,
+			Fee:    fee,
+		},
+	}
+}
+
+// newTestFeeBlock returns a block at the passed height which contains the
+// passed transactions.
+func newTestFeeBlock(height uint32, txs ...*TxDesc) *provautil.Block {
+	msgBlock := wire.MsgBlock{
+		Header: wire.BlockHeader{Height: height},
+	}
+	for _, txD := range txs {
+		msgBlock.AddTransaction(txD.Tx.MsgTx())
+	}
+	block := provautil.NewBlock(&msgBlock)
+	block.SetHeight(height)
+	return block
+}
+
+// expectedFeeRate returns the fee rate the estimator computes for the passed
+// transaction.
+func expectedFeeRate(txD *TxDesc) RMGPerKilobyte {
+	size := uint32(txD.Tx.MsgTx().SerializeSize())
+	return NewAtomsPerByte(provautil.Amount(txD.Fee), size).ToRMGPerKb()
+}
+
+// checkFeeEstimate ensures the estimator estimates the passed fee rate for the
+// passed number of blocks.
+func checkFeeEstimate(t *testing.T, ef *FeeEstimator, numBlocks uint32, want RMGPerKilobyte) {
+	got, err := ef.EstimateFee(numBlocks)
+	if err != nil {
+		t.Fatalf("EstimateFee(%d): unexpected error: %v", numBlocks,
+			err)
+	}
+	if got != want {
+		t.Fatalf("EstimateFee(%d): got %v, want %v", numBlocks, got,
+			want)
+	}
+}
+
+// TestFeeEstimator tests the fee estimates are derived from the confirmation
+// delays of the observed transactions and that the state of the estimator can
+// be rolled back, saved, and restored.
+func TestFeeEstimator(t *testing.T) {
+	t.Parallel()
+
+	ef := NewFeeEstimator(DefaultEstimateFeeMaxRollback, 2)
+
+	// Transactions are ignored until the first block is registered and no
+	// estimates are provided until enough blocks are registered.
+	ignored := newTestFeeTx(0, 100000, 0)
+	ef.ObserveTransaction(ignored)
+	if err := ef.RegisterBlock(newTestFeeBlock(1)); err != nil {
+		t.Fatalf("RegisterBlock: unexpected error: %v", err)
+	}
+	if _, err := ef.EstimateFee(1); err == nil {
+		t.Fatal("EstimateFee: did not fail with too few blocks")
+	}
+
+	// Observe a high and a low fee transaction, and mine the high fee one
+	// in the next block and the low fee one in the block after that.
+	high := newTestFeeTx(1, 50000, 1)
+	low := newTestFeeTx(2, 1000, 1)
+	ef.ObserveTransaction(high)
+	ef.ObserveTransaction(low)
+	err := ef.RegisterBlock(newTestFeeBlock(2, ignored, high))
+	if err != nil {
+		t.Fatalf("RegisterBlock: unexpected error: %v", err)
+	}
+	checkFeeEstimate(t, ef, 1, expectedFeeRate(high))
+	if err := ef.RegisterBlock(newTestFeeBlock(3, low)); err != nil {
+		t.Fatalf("RegisterBlock: unexpected error: %v", err)
+	}
+	checkFeeEstimate(t, ef, 1, expectedFeeRate(high))
+	checkFeeEstimate(t, ef, 2, expectedFeeRate(low))
+
+	// Ensure the restored estimator provides the same estimates and
+	// serializes to the same state.
+	state := ef.Save()
+	restored, err := RestoreFeeEstimator(state)
+	if err != nil {
+		t.Fatalf("RestoreFeeEstimator: unexpected error: %v", err)
+	}
+	if !bytes.Equal(restored.Save(), state) {
+		t.Fatal("restored fee estimator state differs")
+	}
+	if restored.LastKnownHeight() != 3 {
+		t.Fatalf("restored height: got %d, want 3",
+			restored.LastKnownHeight())
+	}
+	checkFeeEstimate(t, restored, 2, expectedFeeRate(low))
+
+	// Rolling back the last block forgets that the low fee transaction was
+	// mined, and registering it again restores the estimates.
+	lastBlock := newTestFeeBlock(3, low)
+	if err := ef.Rollback(lastBlock.Hash()); err != nil {
+		t.Fatalf("Rollback: unexpected error: %v", err)
+	}
+	if ef.LastKnownHeight() != 2 {
+		t.Fatalf("rolled back height: got %d, want 2",
+			ef.LastKnownHeight())
+	}
+	checkFeeEstimate(t, ef, 2, expectedFeeRate(high))
+	if err := ef.RegisterBlock(lastBlock); err != nil {
+		t.Fatalf("RegisterBlock: unexpected error: %v", err)
+	}
+	if !bytes.Equal(ef.Save(), state) {
+		t.Fatal("fee estimator state differs after registering the " +
+			"rolled back block again")
+	}
+
+	// A block which does not extend the last registered block is
+	// rejected, but the estimator continues from it.
+	if err := ef.RegisterBlock(newTestFeeBlock(10)); err == nil {
+		t.Fatal("RegisterBlock: did not fail with a missed block")
+	}
+	if err := ef.RegisterBlock(newTestFeeBlock(11)); err != nil {
+		t.Fatalf("RegisterBlock: unexpected error: %v", err)
+	}
+	checkFeeEstimate(t, ef, 2, expectedFeeRate(low))
+
+	// Restoring a state with an unknown version fails.
+	state[3]++
+	if _, err := RestoreFeeEstimator(state); err == nil {
+		t.Fatal("RestoreFeeEstimator: did not fail with a bad version")
+	}
+}
@@ -6,6 +6,7 @@
 package mempool
 
 import (
+	"container/heap"
 	"container/list"
 	"fmt"
 	"math"
@@ -40,13 +41,67 @@ const (
 	// orphanExpireScanInterval is the minimum amount of time in between
 	// scans of the orphan pool to evict expired transactions.
 	orphanExpireScanInterval = time.Minute * 5
+
+	// MaxRBFSequence is the maximum sequence number an input can use to
+	// signal that the transaction spending it can be replaced while it is
+	// in the memory pool.
+	MaxRBFSequence = 0xfffffffd
+
+	// MaxReplacementEvictions is the maximum number of transactions that
+	// can be evicted from the memory pool when accepting a replacement
+	// transaction.
+	MaxReplacementEvictions = 100
+
+	// rollingFeeHalfLife is the time it takes the dynamic minimum relay fee,
+	// which is raised when transactions are evicted from a full pool, to
+	// decay by half.  It decays faster while the pool is mostly empty.
+	rollingFeeHalfLife = time.Hour * 12
+
+	// DefaultMaxPackageCount is the default maximum number of transactions
+	// in the pool a transaction can have as ancestors or descendants,
+	// including itself.
+	DefaultMaxPackageCount = 25
+
+	// DefaultMaxPackageSize is the default maximum total serialized size in
+	// bytes of a transaction along with its ancestors or its descendants in
+	// the pool.
+	DefaultMaxPackageSize = 101000
 )
 
-// Tag represents an identifier to use for tagging orphan transactions.  The
-// caller may choose any scheme it desires, however it is common to use peer IDs
-// so that orphans can be identified by which peer first relayed them.
+// Tag represents an identifier to use for tagging transactions.  The caller may
+// choose any scheme it desires, however it is common to use peer IDs so that
+// orphans can be identified by which peer first relayed them, and business
+// identifiers, such as a customer or batch ID, for transactions submitted
+// locally.
 type Tag uint64
 
+// TagPolicy houses the policy overrides applied to transactions submitted
+// locally with a specific tag.
+type TagPolicy struct {
+	// AllowFree defines whether to accept free or low-fee transactions
+	// with the tag regardless of their size and priority.
+	AllowFree bool
+}
+
+// TxOrigin describes where a transaction processed by the memory pool came
+// from.
+type TxOrigin struct {
+	// Peer is the ID of the peer which relayed the transaction, or zero
+	// for transactions submitted locally.  Orphans are tracked by it so
+	// they can be removed once the peer disconnects.
+	Peer Tag
+
+	// Tag is the business identifier, such as a customer or batch ID, the
+	// transaction was submitted locally with.  It is kept with the
+	// transaction in the pool and when the pool is saved.
+	Tag Tag
+
+	// Trusted defines whether the policy for the tag is applied to the
+	// transaction.  It must only be set for transactions submitted by
+	// trusted clients, such as authenticated admin RPC users.
+	Trusted bool
+}
+
 // Config is a descriptor containing the memory pool configuration.
 type Config struct {
 	// Policy defines the various mempool configuration options related
@@ -103,6 +158,11 @@ type Config struct {
 	// indexing the unconfirmed transactions in the memory pool.
 	// This can be nil if the address index is not enabled.
 	AddrIndex *indexers.AddrIndex
+
+	// FeeEstimator defines the optional fee estimator which is informed of
+	// the transactions accepted to the memory pool.  This can be nil if
+	// fee estimation is not enabled.
+	FeeEstimator *FeeEstimator
 }
 
 // Policy houses the policy (configuration parameters) which is used to
@@ -143,6 +203,35 @@ type Policy struct {
 	// MinRelayTxFee defines the minimum transaction fee in RMG/kB to be
 	// considered a non-zero fee.
 	MinRelayTxFee provautil.Amount
+
+	// AcceptReplacement, if true, accepts transactions which replace
+	// transactions in the mempool that signal replacement using the
+	// Replace-By-Fee (RBF) signaling policy.  Otherwise any transaction
+	// which conflicts with a transaction in the mempool is rejected.
+	AcceptReplacement bool
+
+	// MaxPoolSize is the maximum total serialized size in bytes of the
+	// transactions in the main pool.  The transactions with the lowest fee
+	// rates are evicted when it is exceeded.  A value of zero disables the
+	// limit.
+	MaxPoolSize int64
+
+	// MaxPackageCount is the maximum number of transactions in the pool,
+	// including itself, a transaction can have as ancestors, and that any
+	// of its ancestors can have as descendants.  A value of zero disables
+	// the limit.
+	MaxPackageCount int
+
+	// MaxPackageSize is the maximum total serialized size in bytes of a
+	// transaction along with its ancestors in the pool, and of any of its
+	// ancestors along with their descendants.  A value of zero disables the
+	// limit.
+	MaxPackageSize int64
+
+	// TagPolicies defines the policy overrides for transactions submitted
+	// locally with the tag they are keyed by.  They are only applied to
+	// transactions from trusted origins.
+	TagPolicies map[Tag]TagPolicy
 }
 
 // TxDesc is a descriptor containing a transaction in the mempool along with
@@ -153,6 +242,30 @@ type TxDesc struct {
 	// StartingPriority is the priority of the transaction when it was added
 	// to the pool.
 	StartingPriority float64
+
+	// Tag is the business identifier the transaction was submitted locally
+	// with, or zero for transactions relayed by peers.
+	Tag Tag
+
+	// Trusted defines whether the transaction was submitted from a trusted
+	// origin, so the policy for its tag was applied.
+	Trusted bool
+
+	// DescendantCount is the number of transactions in the pool which
+	// depend on the transaction, directly or indirectly, including itself.
+	DescendantCount int
+
+	// DescendantSize is the total serialized size of the transaction and
+	// its descendants in the pool.
+	DescendantSize int64
+
+	// DescendantFees is the total fee paid by the transaction and its
+	// descendants in the pool.
+	DescendantFees int64
+
+	// evictionIndex is the index of the transaction in the eviction queue
+	// of the pool, or -1 when it is not in the queue.
+	evictionIndex int
 }
 
 // orphanTx is normal transaction that references an ancestor transaction
@@ -160,7 +273,7 @@ type TxDesc struct {
 // to it such as an expiration time to help prevent caching the orphan forever.
 type orphanTx struct {
 	tx         *provautil.Tx
-	tag        Tag
+	origin     TxOrigin
 	expiration time.Time
 }
 
@@ -185,6 +298,21 @@ type TxPool struct {
 	// the scan will only run when an orphan is added to the pool as opposed
 	// to on an unconditional timer.
 	nextExpireScan time.Time
+
+	// totalSize is the total serialized size of the transactions in the
+	// main pool.
+	totalSize int64
+
+	// evictionQueue orders the transactions in the main pool which can be
+	// evicted by their eviction fee rate so the pool size can be limited
+	// without sorting the whole pool.
+	evictionQueue evictionQueue
+
+	// rollingMinFee is the dynamic minimum relay fee in atoms/kB which is
+	// raised above the fee rates of the transactions evicted from a full
+	// pool and decays over time since it was last updated.
+	rollingMinFee        float64
+	lastRollingFeeUpdate time.Time
 }
 
 // Ensure the TxPool type implements the mining.TxSource interface.
@@ -241,15 +369,15 @@ func (mp *TxPool) RemoveOrphan(tx *provautil.Tx) {
 	mp.mtx.Unlock()
 }
 
-// RemoveOrphansByTag removes all orphan transactions tagged with the provided
-// identifier.
+// RemoveOrphansByPeer removes all orphan transactions relayed by the peer with
+// the provided ID.
 //
 // This function is safe for concurrent access.
-func (mp *TxPool) RemoveOrphansByTag(tag Tag) uint64 {
+func (mp *TxPool) RemoveOrphansByPeer(peer Tag) uint64 {
 	var numEvicted uint64
 	mp.mtx.Lock()
 	for _, otx := range mp.orphans {
-		if otx.tag == tag {
+		if otx.origin.Peer == peer {
 			mp.removeOrphan(otx.tx, true)
 			numEvicted++
 		}
@@ -314,7 +442,7 @@ func (mp *TxPool) limitNumOrphans() error {
 // addOrphan adds an orphan transaction to the orphan pool.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) addOrphan(tx *provautil.Tx, tag Tag) {
+func (mp *TxPool) addOrphan(tx *provautil.Tx, origin TxOrigin) {
 	// Nothing to do if no orphans are allowed.
 	if mp.cfg.Policy.MaxOrphanTxs <= 0 {
 		return
@@ -327,7 +455,7 @@ func (mp *TxPool) addOrphan(tx *provautil.Tx, tag Tag) {
 
 	mp.orphans[*tx.Hash()] = &orphanTx{
 		tx:         tx,
-		tag:        tag,
+		origin:     origin,
 		expiration: time.Now().Add(orphanTTL),
 	}
 	for _, txIn := range tx.MsgTx().TxIn {
@@ -345,7 +473,7 @@ func (mp *TxPool) addOrphan(tx *provautil.Tx, tag Tag) {
 // maybeAddOrphan potentially adds an orphan to the orphan pool.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) maybeAddOrphan(tx *provautil.Tx, tag Tag) error {
+func (mp *TxPool) maybeAddOrphan(tx *provautil.Tx, origin TxOrigin) error {
 	// Ignore orphan transactions that are too large.  This helps avoid
 	// a memory exhaustion attack based on sending a lot of really large
 	// orphans.  In the case there is a valid transaction larger than this,
@@ -365,7 +493,7 @@ func (mp *TxPool) maybeAddOrphan(tx *provautil.Tx, tag Tag) error {
 	}
 
 	// Add the orphan if the none of the above disqualified it.
-	mp.addOrphan(tx, tag)
+	mp.addOrphan(tx, origin)
 
 	return nil
 }
@@ -486,6 +614,11 @@ func (mp *TxPool) removeTransaction(tx *provautil.Tx, removeRedeemers bool) {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
 		delete(mp.pool, *txHash)
+		if txDesc.evictionIndex >= 0 {
+			heap.Remove(&mp.evictionQueue, txDesc.evictionIndex)
+		}
+		mp.totalSize -= int64(txDesc.Tx.MsgTx().SerializeSize())
+		mp.removePackageStats(txDesc)
 		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 	}
 }
@@ -526,27 +659,40 @@ func (mp *TxPool) RemoveDoubleSpends(tx *provautil.Tx) {
 
 // addTransaction adds the passed transaction to the memory pool.  It should
 // not be called directly as it doesn't perform any validation.  This is a
-// helper for maybeAcceptTransaction.
+// helper for maybeAcceptTransaction.  The added time is the time the
+// transaction was first accepted, which is only in the past for transactions
+// restored from a saved memory pool.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *provautil.Tx, height uint32, fee int64) *TxDesc {
+func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *provautil.Tx, height uint32, fee int64, origin TxOrigin, added time.Time) *TxDesc {
 	// Add the transaction to the pool and mark the referenced outpoints
 	// as spent by the pool.
 	txD := &TxDesc{
 		TxDesc: mining.TxDesc{
 			Tx:       tx,
-			Added:    time.Now(),
+			Added:    added,
 			Height:   height,
 			Fee:      fee,
 			FeePerKB: fee * 1000 / int64(tx.MsgTx().SerializeSize()),
 		},
 		StartingPriority: mining.CalcPriority(tx.MsgTx(), utxoView, height),
+		Tag:              origin.Tag,
+		Trusted:          origin.Trusted,
+		evictionIndex:    -1,
 	}
 	mp.pool[*tx.Hash()] = txD
+	mp.totalSize += int64(tx.MsgTx().SerializeSize())
 
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+
+	// Update the package statistics of the transaction along with the
+	// transactions in the pool it is related to.
+	mp.addPackageStats(txD)
+	if !isAdminTx(tx) {
+		heap.Push(&mp.evictionQueue, txD)
+	}
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	// Add unconfirmed address index entries associated with the transaction
@@ -555,25 +701,687 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *provaut
 		mp.cfg.AddrIndex.AddUnconfirmedTx(tx, utxoView)
 	}
 
+	// Record this tx for fee estimation if enabled.
+	if mp.cfg.FeeEstimator != nil {
+		mp.cfg.FeeEstimator.ObserveTransaction(txD)
+	}
+
 	return txD
 }
 
+// adjustDescendantStats adds the passed number, size and fees of transactions to
+// the descendant statistics of the passed transaction.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) adjustDescendantStats(txD *TxDesc, count int, size, fees int64) {
+	txD.DescendantCount += count
+	txD.DescendantSize += size
+	txD.DescendantFees += fees
+
+	// The eviction fee rate depends on the descendant statistics, so
+	// restore the order of the eviction queue.
+	if txD.evictionIndex >= 0 {
+		heap.Fix(&mp.evictionQueue, txD.evictionIndex)
+	}
+}
+
+// addPackageStats sets the ancestor and descendant statistics of the passed
+// transaction, which was just added to the pool, and adds it to the descendant
+// statistics of its ancestors.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) addPackageStats(txD *TxDesc) {
+	tx := txD.Tx
+	ancestors := mp.txAncestors(tx, nil)
+
+	// The transaction already has descendants in the pool when it is added
+	// back from a block that has been disconnected during a reorg.  Some
+	// of them can already be descendants of its ancestors through other
+	// inputs, so the statistics of the related transactions are
+	// recalculated instead.  The package limits bound the work.
+	descendants := mp.txDescendants(tx, nil)
+	if len(descendants) > 0 {
+		related := descendants
+		for hash, ancestor := range ancestors {
+			related[hash] = ancestor
+		}
+		related[*tx.Hash()] = tx
+		mp.updatePackageStats(related)
+		return
+	}
+
+	size := int64(tx.MsgTx().SerializeSize())
+	txD.AncestorCount = len(ancestors) + 1
+	txD.AncestorSize = size
+	txD.AncestorFees = txD.Fee
+	txD.DescendantCount = 1
+	txD.DescendantSize = size
+	txD.DescendantFees = txD.Fee
+	for hash := range ancestors {
+		ancestor := mp.pool[hash]
+		txD.AncestorSize += int64(ancestor.Tx.MsgTx().SerializeSize())
+		txD.AncestorFees += ancestor.Fee
+		mp.adjustDescendantStats(ancestor, 1, size, txD.Fee)
+	}
+}
+
+// removePackageStats removes the passed transaction, which was just removed
+// from the pool, from the statistics of the transactions in the pool it is
+// related to.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) removePackageStats(txD *TxDesc) {
+	// The transactions the removed transaction spends and the ones which
+	// spend it are still known.
+	tx := txD.Tx
+	ancestors := mp.txAncestors(tx, nil)
+	descendants := mp.txDescendants(tx, nil)
+
+	// When the transaction has both ancestors and descendants, some of the
+	// descendants can remain descendants of the ancestors through other
+	// inputs, so the statistics of the related transactions are
+	// recalculated instead.  This does not happen for mined transactions,
+	// which don't have ancestors, nor for evicted or replaced transactions,
+	// which have their descendants removed first.
+	if len(ancestors) > 0 && len(descendants) > 0 {
+		for hash, ancestor := range ancestors {
+			descendants[hash] = ancestor
+		}
+		mp.updatePackageStats(descendants)
+		return
+	}
+
+	size := int64(tx.MsgTx().SerializeSize())
+	for hash := range ancestors {
+		mp.adjustDescendantStats(mp.pool[hash], -1, -size, -txD.Fee)
+	}
+	for hash := range descendants {
+		descendant := mp.pool[hash]
+		descendant.AncestorCount--
+		descendant.AncestorSize -= size
+		descendant.AncestorFees -= txD.Fee
+	}
+}
+
+// updatePackageStats recalculates the ancestor and descendant statistics of the
+// passed transactions from the transactions currently in the pool.  Any of the
+// passed transactions which are not in the pool are ignored.  It is only used
+// when the statistics can't be updated incrementally.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updatePackageStats(txns map[chainhash.Hash]*provautil.Tx) {
+	for hash := range txns {
+		txD, ok := mp.pool[hash]
+		if !ok {
+			continue
+		}
+		size := int64(txD.Tx.MsgTx().SerializeSize())
+
+		ancestors := mp.txAncestors(txD.Tx, nil)
+		txD.AncestorCount = len(ancestors) + 1
+		txD.AncestorSize = size
+		txD.AncestorFees = txD.Fee
+		for ancestorHash := range ancestors {
+			ancestor := mp.pool[ancestorHash]
+			txD.AncestorSize += int64(ancestor.Tx.MsgTx().SerializeSize())
+			txD.AncestorFees += ancestor.Fee
+		}
+
+		descendants := mp.txDescendants(txD.Tx, nil)
+		txD.DescendantCount = len(descendants) + 1
+		txD.DescendantSize = size
+		txD.DescendantFees = txD.Fee
+		for descendantHash := range descendants {
+			descendant := mp.pool[descendantHash]
+			txD.DescendantSize +=
+				int64(descendant.Tx.MsgTx().SerializeSize())
+			txD.DescendantFees += descendant.Fee
+		}
+
+		// The eviction fee rate depends on the descendant statistics,
+		// so restore the order of the eviction queue.
+		if txD.evictionIndex >= 0 {
+			heap.Fix(&mp.evictionQueue, txD.evictionIndex)
+		}
+	}
+}
+
+// checkPackageLimits ensures that accepting the passed transaction into the
+// pool would neither give it more ancestors or descendants, nor give any of its
+// ancestors more descendants or any of its descendants more ancestors, than
+// allowed by the package limits of the policy.
+//
+// A transaction which is added back from a block that has been disconnected
+// during a reorg can already have descendants in the pool, which become
+// descendants of its ancestors as well.  Some of them can already be
+// descendants of the ancestors through other inputs, so the limits are applied
+// conservatively in that case.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) checkPackageLimits(tx *provautil.Tx) error {
+	maxCount := mp.cfg.Policy.MaxPackageCount
+	maxSize := mp.cfg.Policy.MaxPackageSize
+	if maxCount <= 0 && maxSize <= 0 {
+		return nil
+	}
+
+	txHash := tx.Hash()
+	txSize := int64(tx.MsgTx().SerializeSize())
+	ancestors := mp.txAncestors(tx, nil)
+	ancestorCount := len(ancestors) + 1
+	if maxCount > 0 && ancestorCount > maxCount {
+		str := fmt.Sprintf("transaction %v has %d ancestors in the "+
+			"pool which exceeds the limit of %d", txHash,
+			ancestorCount, maxCount)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	descendants := mp.txDescendants(tx, nil)
+	descendantCount := len(descendants) + 1
+	descendantSize := txSize
+	for hash := range descendants {
+		descendant := mp.pool[hash]
+		descendantSize += int64(descendant.Tx.MsgTx().SerializeSize())
+	}
+	if maxCount > 0 && descendantCount > maxCount {
+		str := fmt.Sprintf("transaction %v has %d descendants in the "+
+			"pool which exceeds the limit of %d", txHash,
+			descendantCount, maxCount)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+	if maxSize > 0 && descendantSize > maxSize {
+		str := fmt.Sprintf("transaction %v has descendants of %d bytes "+
+			"in the pool which exceeds the limit of %d", txHash,
+			descendantSize, maxSize)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	ancestorSize := txSize
+	for hash := range ancestors {
+		ancestor := mp.pool[hash]
+		ancestorSize += int64(ancestor.Tx.MsgTx().SerializeSize())
+
+		count := ancestor.DescendantCount + descendantCount
+		if maxCount > 0 && count > maxCount {
+			str := fmt.Sprintf("transaction %v would give its "+
+				"ancestor %v %d descendants in the pool which "+
+				"exceeds the limit of %d", txHash, hash, count,
+				maxCount)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+		size := ancestor.DescendantSize + descendantSize
+		if maxSize > 0 && size > maxSize {
+			str := fmt.Sprintf("transaction %v would give its "+
+				"ancestor %v descendants of %d bytes in the "+
+				"pool which exceeds the limit of %d", txHash,
+				hash, size, maxSize)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+	if maxSize > 0 && ancestorSize > maxSize {
+		str := fmt.Sprintf("transaction %v has ancestors of %d bytes "+
+			"in the pool which exceeds the limit of %d", txHash,
+			ancestorSize, maxSize)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	for hash := range descendants {
+		descendant := mp.pool[hash]
+		count := descendant.AncestorCount + ancestorCount
+		if maxCount > 0 && count > maxCount {
+			str := fmt.Sprintf("transaction %v would give its "+
+				"descendant %v %d ancestors in the pool which "+
+				"exceeds the limit of %d", txHash, hash, count,
+				maxCount)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+		size := descendant.AncestorSize + ancestorSize
+		if maxSize > 0 && size > maxSize {
+			str := fmt.Sprintf("transaction %v would give its "+
+				"descendant %v ancestors of %d bytes in the "+
+				"pool which exceeds the limit of %d", txHash,
+				hash, size, maxSize)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
+	return nil
+}
+
 // checkPoolDoubleSpend checks whether or not the passed transaction is
 // attempting to spend coins already spent by other transactions in the pool.
-// Note it does not check for double spends against transactions already in the
-// main chain.
+// If it does, we'll check whether each of those transactions are signaling for
+// replacement.  If just one of them isn't, an error is returned.  Otherwise, a
+// boolean is returned signaling that the transaction is a replacement.  Note it
+// does not check for double spends against transactions already in the main
+// chain.
 //
 // This function MUST be called with the mempool lock held (for reads).
-func (mp *TxPool) checkPoolDoubleSpend(tx *provautil.Tx) error {
+func (mp *TxPool) checkPoolDoubleSpend(tx *provautil.Tx) (bool, error) {
+	var isReplacement bool
 	for _, txIn := range tx.MsgTx().TxIn {
-		if txR, exists := mp.outpoints[txIn.PreviousOutPoint]; exists {
+		conflict, ok := mp.outpoints[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+
+		// Reject the transaction if we don't accept replacement
+		// transactions or if it doesn't signal replacement.
+		if !mp.cfg.Policy.AcceptReplacement ||
+			!mp.signalsReplacement(conflict, nil) {
+
 			str := fmt.Sprintf("output %v already spent by "+
 				"transaction %v in the memory pool",
-				txIn.PreviousOutPoint, txR.Hash())
-			return txRuleError(wire.RejectDuplicate, str)
+				txIn.PreviousOutPoint, conflict.Hash())
+			return false, txRuleError(wire.RejectDuplicate, str)
 		}
+
+		isReplacement = true
 	}
 
+	return isReplacement, nil
+}
+
+// signalsReplacement determines if a transaction is signaling that it can be
+// replaced using the Replace-By-Fee (RBF) policy.  This policy specifies two
+// ways a transaction can signal that it is replaceable:
+//
+// Explicit signaling: A transaction is considered to have opted in to allowing
+// replacement of itself if any of its inputs have a sequence number less than
+// 0xfffffffe.
+//
+// Inherited signaling: Transactions that don't explicitly signal
+// replaceability are replaceable under this policy for as long as any one of
+// their ancestors signals replaceability and remains unconfirmed.
+//
+// The cache is optional and serves as an optimization to avoid visiting
+// transactions we've already determined don't signal replacement.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) signalsReplacement(tx *provautil.Tx, cache map[chainhash.Hash]struct{}) bool {
+	// If a cache was not provided, we'll initialize one now to use for the
+	// recursive calls.
+	if cache == nil {
+		cache = make(map[chainhash.Hash]struct{})
+	}
+
+	for _, txIn := range tx.MsgTx().TxIn {
+		if txIn.Sequence <= MaxRBFSequence {
+			return true
+		}
+
+		hash := txIn.PreviousOutPoint.Hash
+		unconfirmedAncestorTx, ok := mp.pool[hash]
+		if !ok {
+			continue
+		}
+
+		// If we've already determined the transaction doesn't signal
+		// replacement, we can avoid recursing.
+		if _, ok := cache[hash]; ok {
+			continue
+		}
+
+		if mp.signalsReplacement(unconfirmedAncestorTx.Tx, cache) {
+			return true
+		}
+
+		// Since the transaction doesn't signal replacement, we'll cache
+		// its result to ensure we don't attempt to determine so again.
+		cache[hash] = struct{}{}
+	}
+
+	return false
+}
+
+// txAncestors returns all of the unconfirmed ancestors of the given
+// transaction.  Given transactions A, B, and C where C spends B and B spends A,
+// A and B are considered ancestors of C.
+//
+// The cache is optional and serves as an optimization to avoid visiting
+// transactions we've already determined ancestors of.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txAncestors(tx *provautil.Tx, cache map[chainhash.Hash]*provautil.Tx) map[chainhash.Hash]*provautil.Tx {
+	// If a cache was not provided, we'll initialize one now to use for the
+	// recursive calls.
+	if cache == nil {
+		cache = make(map[chainhash.Hash]*provautil.Tx)
+	}
+
+	for _, txIn := range tx.MsgTx().TxIn {
+		parent, ok := mp.pool[txIn.PreviousOutPoint.Hash]
+		if !ok {
+			continue
+		}
+		if _, ok := cache[*parent.Tx.Hash()]; ok {
+			continue
+		}
+		cache[*parent.Tx.Hash()] = parent.Tx
+		mp.txAncestors(parent.Tx, cache)
+	}
+
+	return cache
+}
+
+// txDescendants returns all of the unconfirmed descendants of the given
+// transaction.  Given transactions A, B, and C where C spends B and B spends A,
+// B and C are considered descendants of A.
+//
+// The cache is optional and serves as an optimization to avoid visiting
+// transactions we've already determined descendants of.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txDescendants(tx *provautil.Tx, cache map[chainhash.Hash]*provautil.Tx) map[chainhash.Hash]*provautil.Tx {
+	// If a cache was not provided, we'll initialize one now to use for the
+	// recursive calls.
+	if cache == nil {
+		cache = make(map[chainhash.Hash]*provautil.Tx)
+	}
+
+	// We'll iterate through every outpoint of the transaction to check if
+	// any are spent by other transactions in the mempool.
+	prevOut := wire.OutPoint{Hash: *tx.Hash()}
+	for txOutIdx := range tx.MsgTx().TxOut {
+		prevOut.Index = uint32(txOutIdx)
+		spenderTx, ok := mp.outpoints[prevOut]
+		if !ok {
+			continue
+		}
+		if _, ok := cache[*spenderTx.Hash()]; ok {
+			continue
+		}
+		cache[*spenderTx.Hash()] = spenderTx
+		mp.txDescendants(spenderTx, cache)
+	}
+
+	return cache
+}
+
+// txConflicts returns all of the unconfirmed transactions that would become
+// conflicts if the given transaction were to be accepted into the mempool.  An
+// unconfirmed conflict is known as a transaction that spends an output already
+// spent by a different transaction within the mempool.  Any descendants of
+// these transactions are also considered conflicts as they would no longer
+// exist.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) txConflicts(tx *provautil.Tx) map[chainhash.Hash]*provautil.Tx {
+	conflicts := make(map[chainhash.Hash]*provautil.Tx)
+	for _, txIn := range tx.MsgTx().TxIn {
+		conflict, ok := mp.outpoints[txIn.PreviousOutPoint]
+		if !ok {
+			continue
+		}
+		conflicts[*conflict.Hash()] = conflict
+		for hash, descendant := range mp.txDescendants(conflict, nil) {
+			conflicts[hash] = descendant
+		}
+	}
+	return conflicts
+}
+
+// validateReplacement determines whether a transaction is deemed as a valid
+// replacement of all of its conflicts according to the RBF policy.  If it is
+// valid, no error is returned.  Otherwise, an error is returned indicating
+// what went wrong.
+//
+// A replacement which pays the conflicting outputs back to the sender is how
+// a payment is cancelled before it is mined.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateReplacement(tx *provautil.Tx, txFee int64) (map[chainhash.Hash]*provautil.Tx, error) {
+	// First, we'll make sure the set of conflicting transactions doesn't
+	// exceed the maximum allowed.
+	conflicts := mp.txConflicts(tx)
+	if len(conflicts) > MaxReplacementEvictions {
+		str := fmt.Sprintf("replacement transaction %v evicts more "+
+			"transactions than permitted: max is %v, evicts %v",
+			tx.Hash(), MaxReplacementEvictions, len(conflicts))
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// The set of conflicts (transactions we'll replace) and ancestors
+	// should not overlap, otherwise the replacement would be spending an
+	// output that no longer exists.
+	for ancestorHash := range mp.txAncestors(tx, nil) {
+		if _, ok := conflicts[ancestorHash]; !ok {
+			continue
+		}
+		str := fmt.Sprintf("replacement transaction %v spends parent "+
+			"transaction %v", tx.Hash(), ancestorHash)
+		return nil, txRuleError(wire.RejectInvalid, str)
+	}
+
+	// Since the fee of a single transaction is capped by the chain,
+	// conflicts which already pay close to the cap can never be replaced as
+	// the replacement must pay more than all of them together and for its
+	// own bandwidth, which is determined by our minimum relay fee.
+	var (
+		txSize       = int64(tx.MsgTx().SerializeSize())
+		txFeeRate    = txFee * 1000 / txSize
+		minFee       = calcMinRequiredTxRelayFee(txSize, mp.cfg.Policy.MinRelayTxFee)
+		conflictsFee int64
+	)
+	for hash := range conflicts {
+		conflictsFee += mp.pool[hash].Fee
+	}
+	if conflictsFee+minFee > mp.cfg.ChainParams.MaximumFeeAmount {
+		str := fmt.Sprintf("replacement transaction %v needs a fee of "+
+			"%v which exceeds the maximum fee amount of %v",
+			tx.Hash(), conflictsFee+minFee,
+			mp.cfg.ChainParams.MaximumFeeAmount)
+		return nil, txRuleError(wire.RejectHighFee, str)
+	}
+
+	// The replacement should have a higher fee rate than each of the
+	// conflicting transactions.
+	//
+	// We usually don't want to accept replacements with lower fee rates
+	// than what they replaced as that would lower the fee rate of the next
+	// block.  Requiring that the fee rate always be increased is also an
+	// easy-to-reason about way to prevent DoS attacks via replacements.
+	conflictsParents := make(map[chainhash.Hash]struct{})
+	for hash, conflict := range conflicts {
+		if txFeeRate <= mp.pool[hash].FeePerKB {
+			str := fmt.Sprintf("replacement transaction %v has an "+
+				"insufficient fee rate: needs more than %v, "+
+				"has %v", tx.Hash(), mp.pool[hash].FeePerKB,
+				txFeeRate)
+			return nil, txRuleError(wire.RejectInsufficientFee, str)
+		}
+
+		// We'll track each conflict's parents to ensure the replacement
+		// isn't spending any new unconfirmed inputs.
+		for _, txIn := range conflict.MsgTx().TxIn {
+			conflictsParents[txIn.PreviousOutPoint.Hash] = struct{}{}
+		}
+	}
+
+	// It should also have an absolute fee greater than all of the
+	// transactions it intends to replace and pay for its own bandwidth.
+	if txFee < conflictsFee+minFee {
+		str := fmt.Sprintf("replacement transaction %v has an "+
+			"insufficient absolute fee: needs %v, has %v",
+			tx.Hash(), conflictsFee+minFee, txFee)
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
+	// Finally, it should not spend any new unconfirmed outputs, other than
+	// the ones already included in the parents of the conflicting
+	// transactions it'll replace.
+	for _, txIn := range tx.MsgTx().TxIn {
+		if _, ok := conflictsParents[txIn.PreviousOutPoint.Hash]; ok {
+			continue
+		}
+		// Confirmed outputs are valid to spend in the replacement.
+		if _, ok := mp.pool[txIn.PreviousOutPoint.Hash]; !ok {
+			continue
+		}
+		str := fmt.Sprintf("replacement transaction %v spends new "+
+			"unconfirmed input %v not found in conflicting "+
+			"transactions", tx.Hash(), txIn.PreviousOutPoint)
+		return nil, txRuleError(wire.RejectInvalid, str)
+	}
+
+	return conflicts, nil
+}
+
+// currentRollingMinFee returns the dynamic minimum relay fee in atoms/kB after
+// decaying it for the time passed since it was last updated.  It decays with a
+// half life of rollingFeeHalfLife, which is shortened while the pool is less
+// than half or a quarter full, and it is reset to zero once it drops below half
+// of the static minimum relay fee.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) currentRollingMinFee() provautil.Amount {
+	if mp.rollingMinFee == 0 {
+		return 0
+	}
+
+	now := time.Now()
+	halfLife := rollingFeeHalfLife
+	maxPoolSize := mp.cfg.Policy.MaxPoolSize
+	if mp.totalSize < maxPoolSize/4 {
+		halfLife /= 4
+	} else if mp.totalSize < maxPoolSize/2 {
+		halfLife /= 2
+	}
+	elapsed := now.Sub(mp.lastRollingFeeUpdate)
+	mp.rollingMinFee /= math.Pow(2, elapsed.Seconds()/halfLife.Seconds())
+	mp.lastRollingFeeUpdate = now
+
+	minRelayTxFee := float64(mp.cfg.Policy.MinRelayTxFee)
+	if mp.rollingMinFee < minRelayTxFee/2 || mp.rollingMinFee < 1 {
+		mp.rollingMinFee = 0
+	}
+	return provautil.Amount(mp.rollingMinFee)
+}
+
+// DynamicMinFee returns the dynamic minimum relay fee in atoms/kB new
+// transactions must pay to be accepted into the pool.  It is raised above the
+// fee rates of the transactions evicted when the pool exceeds its maximum size
+// and decays over time afterwards.  It is zero when no transactions were
+// evicted recently.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) DynamicMinFee() provautil.Amount {
+	mp.mtx.Lock()
+	minFee := mp.currentRollingMinFee()
+	mp.mtx.Unlock()
+
+	return minFee
+}
+
+// evictionFeeRate returns the fee rate in atoms/kB used to determine the order
+// in which transactions are evicted when the pool exceeds its maximum size.  It
+// is the fee rate of the package the transaction forms with its descendants,
+// or its own fee rate when that is higher.
+func (txD *TxDesc) evictionFeeRate() int64 {
+	feeRate := txD.DescendantFees * 1000 / txD.DescendantSize
+	if txD.FeePerKB > feeRate {
+		feeRate = txD.FeePerKB
+	}
+	return feeRate
+}
+
+// evictionQueue implements a priority queue of the transactions in the pool
+// ordered by ascending eviction fee rate.  Each transaction tracks its index in
+// the queue so it can be removed or reordered when it changes.
+type evictionQueue []*TxDesc
+
+// Len returns the number of transactions in the queue.  It is part of the
+// heap.Interface implementation.
+func (q evictionQueue) Len() int {
+	return len(q)
+}
+
+// Less returns whether the transaction with index i should be evicted before
+// the transaction with index j.  It is part of the heap.Interface
+// implementation.
+func (q evictionQueue) Less(i, j int) bool {
+	return q[i].evictionFeeRate() < q[j].evictionFeeRate()
+}
+
+// Swap swaps the transactions at the passed indices in the queue.  It is part
+// of the heap.Interface implementation.
+func (q evictionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].evictionIndex = i
+	q[j].evictionIndex = j
+}
+
+// Push pushes the passed transaction onto the queue.  It is part of the
+// heap.Interface implementation.
+func (q *evictionQueue) Push(x interface{}) {
+	txD := x.(*TxDesc)
+	txD.evictionIndex = len(*q)
+	*q = append(*q, txD)
+}
+
+// Pop removes the transaction which should be evicted first from the queue and
+// returns it.  It is part of the heap.Interface implementation.
+func (q *evictionQueue) Pop() interface{} {
+	n := len(*q)
+	txD := (*q)[n-1]
+	(*q)[n-1] = nil
+	*q = (*q)[:n-1]
+	txD.evictionIndex = -1
+	return txD
+}
+
+// limitPoolSize evicts transactions along with their descendants from the pool
+// until it no longer exceeds the maximum pool size.  The transactions are
+// evicted in order of the fee rate of the package they form with their
+// descendants, or their own fee rate when that is higher, so a transaction is
+// not evicted for the low fees of its descendants, while a low fee transaction
+// is kept when its descendants pay for it.  Admin transactions are never
+// evicted since they are free by design.  The dynamic minimum relay
+// fee is raised above the highest evicted fee rate so the pool doesn't
+// immediately fill up again with the same transactions.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) limitPoolSize() {
+	maxPoolSize := mp.cfg.Policy.MaxPoolSize
+	if maxPoolSize <= 0 || mp.totalSize <= maxPoolSize {
+		return
+	}
+
+	var maxEvictedFeeRate int64
+	for mp.totalSize > maxPoolSize && len(mp.evictionQueue) > 0 {
+		txD := mp.evictionQueue[0]
+		feeRate := txD.evictionFeeRate()
+
+		log.Debugf("Evicting transaction %v (fee_rate=%v atoms/kb) "+
+			"from the full memory pool", txD.Tx.Hash(), feeRate)
+		mp.removeTransaction(txD.Tx, true)
+		if feeRate > maxEvictedFeeRate {
+			maxEvictedFeeRate = feeRate
+		}
+	}
+
+	// Raise the dynamic minimum relay fee above the highest evicted fee
+	// rate by the static minimum relay fee.
+	rollingMinFee := float64(maxEvictedFeeRate +
+		int64(mp.cfg.Policy.MinRelayTxFee))
+	if rollingMinFee > float64(mp.currentRollingMinFee()) {
+		mp.rollingMinFee = rollingMinFee
+		mp.lastRollingFeeUpdate = time.Now()
+	}
+}
+
+// checkPoolSize limits the size of the pool after the passed transaction was
+// accepted into it.  A rule error is returned when the transaction was evicted
+// itself.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) checkPoolSize(tx *provautil.Tx) error {
+	mp.limitPoolSize()
+	if !mp.isTransactionInPool(tx.Hash()) {
+		str := fmt.Sprintf("transaction %v has too low a fee rate to be "+
+			"accepted into the full memory pool", tx.Hash())
+		return txRuleError(wire.RejectInsufficientFee, str)
+	}
 	return nil
 }
 
@@ -620,12 +1428,40 @@ func (mp *TxPool) FetchTransaction(txHash *chainhash.Hash) (*provautil.Tx, error
 	return nil, fmt.Errorf("transaction is not in the pool")
 }
 
-// maybeAcceptTransaction is the internal function which implements the public
-// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
-// more details.
+// CheckSpend checks whether the passed outpoint is already spent by a
+// transaction in the transaction pool.  If that's the case the spending
+// transaction will be returned, if not nil will be returned.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) CheckSpend(op wire.OutPoint) *provautil.Tx {
+	mp.mtx.RLock()
+	txR := mp.outpoints[op]
+	mp.mtx.RUnlock()
+
+	return txR
+}
+
+// validatedTx houses the state computed while validating a transaction for
+// acceptance into the memory pool which is needed to add it to the pool.
+type validatedTx struct {
+	utxoView       *blockchain.UtxoViewpoint
+	bestHeight     uint32
+	fee            int64
+	serializedSize int64
+	conflicts      map[chainhash.Hash]*provautil.Tx
+}
+
+// validateTransaction performs all of the checks which determine whether the
+// passed transaction can be accepted into the memory pool without modifying
+// the pool.  The outputs of the passed package transactions, which are not in
+// the pool, are treated as available to the transaction so transactions which
+// depend on each other can be checked together.
+//
+// If the transaction is an orphan (missing parent transactions), each unknown
+// referenced parent is returned.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool, rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
+func (mp *TxPool) validateTransaction(tx *provautil.Tx, isNew, rateLimit, rejectDupOrphans bool, origin TxOrigin, pkgTxs map[chainhash.Hash]*provautil.Tx) ([]*chainhash.Hash, *validatedTx, error) {
 	txHash := tx.Hash()
 
 	// Don't accept the transaction if it already exists in the pool.  This
@@ -703,7 +1539,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 	// at this point.  There is a more in-depth check that happens later
 	// after fetching the referenced transaction inputs from the main chain
 	// which examines the actual spend data and prevents double spends.
-	err = mp.checkPoolDoubleSpend(tx)
+	// Transactions which conflict with ones that signal replacement are
+	// validated as replacements further below.
+	isReplacement, err := mp.checkPoolDoubleSpend(tx)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -720,6 +1558,18 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 		return nil, nil, err
 	}
 
+	// Attempt to populate any remaining missing inputs from the package
+	// transactions.
+	for originHash, entry := range utxoView.Entries() {
+		if entry != nil && !entry.IsFullySpent() {
+			continue
+		}
+
+		if pkgTx, exists := pkgTxs[originHash]; exists {
+			utxoView.AddTxOuts(pkgTx, mining.UnminedHeight)
+		}
+	}
+
 	// Set the data for the keyview from chain
 	keyView := blockchain.NewKeyViewpoint()
 	keyView.SetThreadTips(mp.cfg.ThreadTips())
@@ -791,6 +1641,12 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 		return nil, nil, err
 	}
 
+	// Don't allow destructions of more than the total supply.
+	err = checkSupplyUnderflow(tx, keyView.TotalSupply())
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Don't allow transactions with non-standard inputs if the network
 	// parameters forbid their acceptance.
 	if !mp.cfg.Policy.AcceptNonStd {
@@ -845,21 +1701,52 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 	// which is more desirable.  Therefore, as long as the size of the
 	// transaction does not exceeed 1000 less than the reserved space for
 	// high-priority transactions, don't require a fee for it.
+	//
+	// Transactions from trusted origins with a tag whose policy allows
+	// free transactions are exempted from this and the priority
+	// requirement below.
+	var tagPolicy TagPolicy
+	if origin.Trusted {
+		tagPolicy = mp.cfg.Policy.TagPolicies[origin.Tag]
+	}
 	serializedSize := int64(tx.MsgTx().SerializeSize())
 	minFee := calcMinRequiredTxRelayFee(serializedSize,
 		mp.cfg.Policy.MinRelayTxFee)
-	if serializedSize >= (DefaultBlockPrioritySize-1000) && txFee < minFee {
+	if serializedSize >= (DefaultBlockPrioritySize-1000) &&
+		!tagPolicy.AllowFree && txFee < minFee {
+
 		str := fmt.Sprintf("transaction %v has %d fees which is under "+
 			"the required amount of %d", txHash, txFee,
 			minFee)
 		return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
 	}
 
+	// Don't allow new transactions which pay less than the dynamic minimum
+	// relay fee.  It is only set after transactions were evicted from a
+	// full pool, in which case free transactions are not accepted either.
+	// Transactions which are being added back to the memory pool from
+	// blocks that have been disconnected during a reorg are exempted, as
+	// are admin transactions which are free by design.
+	isAdmin := isAdminTx(tx)
+	rollingMinFee := mp.currentRollingMinFee()
+	if isNew && !isAdmin && rollingMinFee > 0 {
+		poolMinFee := calcMinRequiredTxRelayFee(serializedSize,
+			rollingMinFee)
+		if txFee < poolMinFee {
+			str := fmt.Sprintf("transaction %v has %d fees which is "+
+				"under the memory pool minimum fee of %d", txHash,
+				txFee, poolMinFee)
+			return nil, nil, txRuleError(wire.RejectInsufficientFee, str)
+		}
+	}
+
 	// Require that free transactions have sufficient priority to be mined
 	// in the next block.  Transactions which are being added back to the
 	// memory pool from blocks that have been disconnected during a reorg
-	// are exempted.
-	if isNew && !mp.cfg.Policy.DisableRelayPriority && txFee < minFee {
+	// are exempted, as are admin transactions.
+	if isNew && !isAdmin && !mp.cfg.Policy.DisableRelayPriority &&
+		!tagPolicy.AllowFree && txFee < minFee {
+
 		currentPriority := mining.CalcPriority(tx.MsgTx(), utxoView,
 			nextBlockHeight)
 		if currentPriority <= mining.MinHighPriority {
@@ -871,8 +1758,9 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 	}
 
 	// Free-to-relay transactions are rate limited here to prevent
-	// penny-flooding with tiny transactions as a form of attack.
-	if rateLimit && txFee < minFee {
+	// penny-flooding with tiny transactions as a form of attack.  Admin
+	// transactions are exempted so a flood can't delay them.
+	if rateLimit && !isAdmin && txFee < minFee {
 		nowUnix := time.Now().Unix()
 		// Decay passed data with an exponentially decaying ~10 minute
 		// window - matches bitcoind handling.
@@ -894,6 +1782,24 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 			mp.cfg.Policy.FreeTxRelayLimit*10*1000)
 	}
 
+	// Don't allow transactions which would exceed the package limits of
+	// the pool.  This includes transactions which are being added back to
+	// the memory pool from blocks that have been disconnected during a
+	// reorg, so the packages of the pool remain bounded.
+	if err := mp.checkPackageLimits(tx); err != nil {
+		return nil, nil, err
+	}
+
+	// If the transaction has any conflicts and we've made it this far, then
+	// we're processing a potential replacement.
+	var conflicts map[chainhash.Hash]*provautil.Tx
+	if isReplacement {
+		conflicts, err = mp.validateReplacement(tx, txFee)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// Verify crypto signatures for each input and reject the transaction if
 	// any don't verify.
 	err = blockchain.ValidateTransactionScripts(tx, utxoView, keyView,
@@ -905,8 +1811,46 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 		return nil, nil, err
 	}
 
+	return nil, &validatedTx{
+		utxoView:       utxoView,
+		bestHeight:     bestHeight,
+		fee:            txFee,
+		serializedSize: serializedSize,
+		conflicts:      conflicts,
+	}, nil
+}
+
+// maybeAcceptTransaction is the internal function which implements the public
+// MaybeAcceptTransaction.  See the comment for MaybeAcceptTransaction for
+// more details.  The transaction is recorded as accepted at the passed time.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool, rejectDupOrphans bool, origin TxOrigin, added time.Time) ([]*chainhash.Hash, *TxDesc, error) {
+	missingParents, vtx, err := mp.validateTransaction(tx, isNew,
+		rateLimit, rejectDupOrphans, origin, nil)
+	if err != nil || len(missingParents) > 0 {
+		return missingParents, nil, err
+	}
+
+	// Now that we've deemed the transaction as valid, we can add it to the
+	// mempool.  If it ended up replacing any transactions, we'll remove
+	// them first.
+	txHash := tx.Hash()
+	for _, conflict := range vtx.conflicts {
+		log.Debugf("Replacing transaction %v (fee_rate=%v atoms/kb) "+
+			"with %v (fee_rate=%v atoms/kb)", conflict.Hash(),
+			mp.pool[*conflict.Hash()].FeePerKB, txHash,
+			vtx.fee*1000/vtx.serializedSize)
+
+		// The conflict set should already include the descendants for
+		// each one, so we don't need to remove the redeemers within
+		// this call as they'll be removed eventually.
+		mp.removeTransaction(conflict, false)
+	}
+
 	// Add to transaction pool.
-	txD := mp.addTransaction(utxoView, tx, bestHeight, txFee)
+	txD := mp.addTransaction(vtx.utxoView, tx, vtx.bestHeight, vtx.fee, origin,
+		added)
 
 	log.Debugf("Accepted transaction %v (pool size: %v)", txHash,
 		len(mp.pool))
@@ -924,16 +1868,119 @@ func (mp *TxPool) maybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool
 // parent is returned.  Use ProcessTransaction instead if new orphans should
 // be added to the orphan pool.
 //
+// The transactions with the lowest fee rates are evicted when accepting the
+// transaction makes the pool exceed its maximum size, and the transaction is
+// rejected when it is evicted itself.
+//
 // This function is safe for concurrent access.
 func (mp *TxPool) MaybeAcceptTransaction(tx *provautil.Tx, isNew, rateLimit bool) ([]*chainhash.Hash, *TxDesc, error) {
 	// Protect concurrent access.
 	mp.mtx.Lock()
-	hashes, txD, err := mp.maybeAcceptTransaction(tx, isNew, rateLimit, true)
+	hashes, txD, err := mp.maybeAcceptTransaction(tx, isNew, rateLimit, true,
+		TxOrigin{}, time.Now())
+	if err == nil && txD != nil {
+		// Evict the transactions with the lowest fee rates when the
+		// pool exceeds its maximum size.
+		if err = mp.checkPoolSize(tx); err != nil {
+			txD = nil
+		}
+	}
 	mp.mtx.Unlock()
 
 	return hashes, txD, err
 }
 
+// TestAcceptResult houses the result of testing whether a transaction would be
+// accepted into the memory pool.
+type TestAcceptResult struct {
+	// Tx is the transaction which was tested.
+	Tx *provautil.Tx
+
+	// Fee is the fee the transaction pays.  It is only set when the
+	// transaction would be accepted.
+	Fee int64
+
+	// Err is the reason the transaction would be rejected, or nil when it
+	// would be accepted.  Use ErrToRejectErr to convert it to a reject
+	// code.
+	Err error
+}
+
+// TestAcceptTransactions determines whether each of the passed transactions
+// would be accepted into the memory pool if they were processed from the passed
+// origin in the order given, without adding any of them to the pool.  Later
+// transactions may spend the outputs of earlier ones, so dependent packages of
+// transactions can be tested together.  A transaction which depends on one
+// that would be rejected is rejected as well.
+//
+// The transactions are checked against the same consensus rules and policy as
+// transactions submitted locally, including the validity of the key IDs they
+// pay to, the admin thread tips, and the fee limits.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) TestAcceptTransactions(txs []*provautil.Tx, origin TxOrigin) []*TestAcceptResult {
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	results := make([]*TestAcceptResult, 0, len(txs))
+	pkgTxs := make(map[chainhash.Hash]*provautil.Tx, len(txs))
+	pkgOutpoints := make(map[wire.OutPoint]*provautil.Tx)
+	for _, tx := range txs {
+		result := &TestAcceptResult{Tx: tx}
+		results = append(results, result)
+
+		// Reject transactions which are repeated or double spend an
+		// earlier transaction of the package since they are not in the
+		// pool and thus not detected by the pool checks.
+		if _, exists := pkgTxs[*tx.Hash()]; exists {
+			str := fmt.Sprintf("already have transaction %v in the "+
+				"package", tx.Hash())
+			result.Err = txRuleError(wire.RejectDuplicate, str)
+			continue
+		}
+		for _, txIn := range tx.MsgTx().TxIn {
+			spender, exists := pkgOutpoints[txIn.PreviousOutPoint]
+			if exists {
+				str := fmt.Sprintf("output %v already spent by "+
+					"transaction %v in the package",
+					txIn.PreviousOutPoint, spender.Hash())
+				result.Err = txRuleError(wire.RejectDuplicate, str)
+				break
+			}
+		}
+		if result.Err != nil {
+			continue
+		}
+
+		missingParents, vtx, err := mp.validateTransaction(tx, true,
+			false, true, origin, pkgTxs)
+		if err != nil {
+			result.Err = err
+			continue
+		}
+		if len(missingParents) > 0 {
+			// Only use the first missing parent transaction in the
+			// error message, as done when processing transactions.
+			str := fmt.Sprintf("orphan transaction %v references "+
+				"outputs of unknown or fully-spent "+
+				"transaction %v", tx.Hash(), missingParents[0])
+			result.Err = txRuleError(wire.RejectDuplicate, str)
+			continue
+		}
+
+		// Make the outputs of the transaction available to the later
+		// transactions of the package.
+		result.Fee = vtx.fee
+		pkgTxs[*tx.Hash()] = tx
+		for _, txIn := range tx.MsgTx().TxIn {
+			pkgOutpoints[txIn.PreviousOutPoint] = tx
+		}
+	}
+
+	return results
+}
+
 // processOrphans is the internal function which implements the public
 // ProcessOrphans.  See the comment for ProcessOrphans for more details.
 //
@@ -970,8 +2017,9 @@ func (mp *TxPool) processOrphans(acceptedTx *provautil.Tx) []*TxDesc {
 
 			// Potentially accept an orphan into the tx pool.
 			for _, tx := range orphans {
+				origin := mp.orphans[*tx.Hash()].origin
 				missing, txD, err := mp.maybeAcceptTransaction(
-					tx, true, true, false)
+					tx, true, true, false, origin, time.Now())
 				if err != nil {
 					// The orphan is now invalid, so there
 					// is no way any other orphans which
@@ -1024,38 +2072,45 @@ func (mp *TxPool) processOrphans(acceptedTx *provautil.Tx) []*TxDesc {
 // orphans) until there are no more.
 //
 // It returns a slice of transactions added to the mempool.  A nil slice means
-// no transactions were moved from the orphan pool to the mempool.
+// no transactions were moved from the orphan pool to the mempool.  Accepted
+// transactions which are evicted again because the pool exceeds its maximum
+// size are not included.
 //
 // This function is safe for concurrent access.
 func (mp *TxPool) ProcessOrphans(acceptedTx *provautil.Tx) []*TxDesc {
 	mp.mtx.Lock()
 	acceptedTxns := mp.processOrphans(acceptedTx)
+
+	// Evict the transactions with the lowest fee rates when the pool
+	// exceeds its maximum size and only return the accepted transactions
+	// which were not evicted.
+	if len(acceptedTxns) > 0 {
+		mp.limitPoolSize()
+		remaining := acceptedTxns[:0]
+		for _, txD := range acceptedTxns {
+			if mp.isTransactionInPool(txD.Tx.Hash()) {
+				remaining = append(remaining, txD)
+			}
+		}
+		acceptedTxns = remaining
+		if len(acceptedTxns) == 0 {
+			acceptedTxns = nil
+		}
+	}
 	mp.mtx.Unlock()
 
 	return acceptedTxns
 }
 
-// ProcessTransaction is the main workhorse for handling insertion of new
-// free-standing transactions into the memory pool.  It includes functionality
-// such as rejecting duplicate transactions, ensuring transactions follow all
-// rules, orphan transaction handling, and insertion into the memory pool.
-//
-// It returns a slice of transactions added to the mempool.  When the
-// error is nil, the list will include the passed transaction itself along
-// with any additional orphan transaactions that were added as a result of
-// the passed one being accepted.
+// processTransaction is the internal function which implements the public
+// ProcessTransaction.  See the comment for ProcessTransaction for more details.
+// The transaction is recorded as accepted at the passed time.
 //
-// This function is safe for concurrent access.
-func (mp *TxPool) ProcessTransaction(tx *provautil.Tx, allowOrphan, rateLimit bool, tag Tag) ([]*TxDesc, error) {
-	log.Tracef("Processing transaction %v", tx.Hash())
-
-	// Protect concurrent access.
-	mp.mtx.Lock()
-	defer mp.mtx.Unlock()
-
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) processTransaction(tx *provautil.Tx, allowOrphan, rateLimit bool, origin TxOrigin, added time.Time) ([]*TxDesc, error) {
 	// Potentially accept the transaction to the memory pool.
 	missingParents, txD, err := mp.maybeAcceptTransaction(tx, true, rateLimit,
-		true)
+		true, origin, added)
 	if err != nil {
 		return nil, err
 	}
@@ -1066,12 +2121,23 @@ func (mp *TxPool) ProcessTransaction(tx *provautil.Tx, allowOrphan, rateLimit bo
 		// are now available) and repeat for those accepted
 		// transactions until there are no more.
 		newTxs := mp.processOrphans(tx)
-		acceptedTxs := make([]*TxDesc, len(newTxs)+1)
+
+		// Evict the transactions with the lowest fee rates when the
+		// pool exceeds its maximum size.  The transaction is rejected
+		// when it was evicted itself.
+		if err := mp.checkPoolSize(tx); err != nil {
+			return nil, err
+		}
 
 		// Add the parent transaction first so remote nodes
 		// do not add orphans.
-		acceptedTxs[0] = txD
-		copy(acceptedTxs[1:], newTxs)
+		acceptedTxs := make([]*TxDesc, 0, len(newTxs)+1)
+		acceptedTxs = append(acceptedTxs, txD)
+		for _, newTxD := range newTxs {
+			if mp.isTransactionInPool(newTxD.Tx.Hash()) {
+				acceptedTxs = append(acceptedTxs, newTxD)
+			}
+		}
 
 		return acceptedTxs, nil
 	}
@@ -1095,10 +2161,33 @@ func (mp *TxPool) ProcessTransaction(tx *provautil.Tx, allowOrphan, rateLimit bo
 	}
 
 	// Potentially add the orphan transaction to the orphan pool.
-	err = mp.maybeAddOrphan(tx, tag)
+	err = mp.maybeAddOrphan(tx, origin)
 	return nil, err
 }
 
+// ProcessTransaction is the main workhorse for handling insertion of new
+// free-standing transactions into the memory pool.  It includes functionality
+// such as rejecting duplicate transactions, ensuring transactions follow all
+// rules, orphan transaction handling, and insertion into the memory pool.
+//
+// It returns a slice of transactions added to the mempool.  When the
+// error is nil, the list will include the passed transaction itself along
+// with any additional orphan transaactions that were added as a result of
+// the passed one being accepted.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) ProcessTransaction(tx *provautil.Tx, allowOrphan, rateLimit bool, origin TxOrigin) ([]*TxDesc, error) {
+	log.Tracef("Processing transaction %v", tx.Hash())
+
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	acceptedTxs, err := mp.processTransaction(tx, allowOrphan, rateLimit,
+		origin, time.Now())
+	mp.mtx.Unlock()
+
+	return acceptedTxs, err
+}
+
 // Count returns the number of transactions in the main pool.  It does not
 // include the orphan pool.
 //
@@ -1156,7 +2245,10 @@ func (mp *TxPool) MiningDescs() []*mining.TxDesc {
 	descs := make([]*mining.TxDesc, len(mp.pool))
 	i := 0
 	for _, desc := range mp.pool {
-		descs[i] = &desc.TxDesc
+		// Copy the descriptor since the package statistics are updated
+		// as transactions are added to and removed from the pool.
+		descCopy := desc.TxDesc
+		descs[i] = &descCopy
 		i++
 	}
 	mp.mtx.RUnlock()
@@ -1196,6 +2288,13 @@ func (mp *TxPool) RawMempoolVerbose() map[string]*btcjson.GetRawMempoolVerboseRe
 			StartingPriority: desc.StartingPriority,
 			CurrentPriority:  currentPriority,
 			Depends:          make([]string, 0),
+			DescendantCount:  int64(desc.DescendantCount),
+			DescendantSize:   desc.DescendantSize,
+			DescendantFees:   provautil.Amount(desc.DescendantFees).ToRMG(),
+			AncestorCount:    int64(desc.AncestorCount),
+			AncestorSize:     desc.AncestorSize,
+			AncestorFees:     provautil.Amount(desc.AncestorFees).ToRMG(),
+			Tag:              uint64(desc.Tag),
 		}
 		for _, txIn := range tx.MsgTx().TxIn {
 			hash := &txIn.PreviousOutPoint.Hash
@@ -1219,6 +2318,14 @@ func (mp *TxPool) LastUpdated() time.Time {
 	return time.Unix(atomic.LoadInt64(&mp.lastUpdated), 0)
 }
 
+// MaxPackageCount returns the maximum number of transactions in a package
+// configured for the pool, or zero when the limit is disabled.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MaxPackageCount() int {
+	return mp.cfg.Policy.MaxPackageCount
+}
+
 // New returns a new memory pool for validating and storing standalone
 // transactions until they are mined into a block.
 func New(cfg *Config) *TxPool {
@@ -223,24 +223,31 @@ func (p *poolHarness) CreateCoinbaseTx(blockHeight uint32, numOutputs uint32) (*
 
 // CreateSignedTx creates a new signed transaction that consumes the provided
 // inputs and generates the provided number of outputs by evenly splitting the
-// total input amount.  All outputs will be to the payment script associated
-// with the harness and all inputs are assumed to do the same.
-func (p *poolHarness) CreateSignedTx(inputs []spendableOutput, numOutputs uint32) (*provautil.Tx, error) {
+// total input amount minus the provided fee.  All outputs will be to the
+// payment script associated with the harness and all inputs are assumed to do
+// the same.  The inputs signal replacement when the signalsReplacement flag is
+// set.
+func (p *poolHarness) CreateSignedTx(inputs []spendableOutput, numOutputs uint32, fee provautil.Amount, signalsReplacement bool) (*provautil.Tx, error) {
 	// Calculate the total input amount and split it amongst the requested
 	// number of outputs.
 	var totalInput provautil.Amount
 	for _, input := range inputs {
 		totalInput += input.amount
 	}
+	totalInput -= fee
 	amountPerOutput := int64(totalInput) / int64(numOutputs)
 	remainder := int64(totalInput) - amountPerOutput*int64(numOutputs)
 
+	sequence := wire.MaxTxInSequenceNum
+	if signalsReplacement {
+		sequence = MaxRBFSequence
+	}
 	tx := wire.NewMsgTx(wire.TxVersion)
 	for _, input := range inputs {
 		tx.AddTxIn(&wire.TxIn{
 			PreviousOutPoint: input.outPoint,
 			SignatureScript:  nil,
-			Sequence:         wire.MaxTxInSequenceNum,
+			Sequence:         sequence,
 		})
 	}
 	for i := uint32(0); i < numOutputs; i++ {
@@ -477,7 +484,7 @@ func TestSimpleOrphanChain(t *testing.T) {
 	// none are evicted).
 	for _, tx := range chainedTxns[1 : maxOrphans+1] {
 		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, true,
-			false, 0)
+			false, TxOrigin{})
 		if err != nil {
 			t.Fatalf("ProcessTransaction: failed to accept valid "+
 				"orphan %v", err)
@@ -500,7 +507,7 @@ func TestSimpleOrphanChain(t *testing.T) {
 	// to ensure it has no bearing on whether or not already existing
 	// orphans in the pool are linked.
 	acceptedTxns, err := harness.txPool.ProcessTransaction(chainedTxns[0],
-		false, false, 0)
+		false, false, TxOrigin{})
 	if err != nil {
 		t.Fatalf("ProcessTransaction: failed to accept valid "+
 			"orphan %v", err)
@@ -539,7 +546,7 @@ func TestOrphanReject(t *testing.T) {
 	// Ensure orphans are rejected when the allow orphans flag is not set.
 	for _, tx := range chainedTxns[1:] {
 		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, false,
-			false, 0)
+			false, TxOrigin{})
 		if err == nil {
 			t.Fatalf("ProcessTransaction: did not fail on orphan "+
 				"%v when allow orphans flag is false", tx.Hash())
@@ -596,7 +603,7 @@ func TestOrphanEviction(t *testing.T) {
 	// all accepted.  This will cause an eviction.
 	for _, tx := range chainedTxns[1:] {
 		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, true,
-			false, 0)
+			false, TxOrigin{})
 		if err != nil {
 			t.Fatalf("ProcessTransaction: failed to accept valid "+
 				"orphan %v", err)
@@ -660,7 +667,7 @@ func TestBasicOrphanRemoval(t *testing.T) {
 	// none are evicted).
 	for _, tx := range chainedTxns[1 : maxOrphans+1] {
 		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, true,
-			false, 0)
+			false, TxOrigin{})
 		if err != nil {
 			t.Fatalf("ProcessTransaction: failed to accept valid "+
 				"orphan %v", err)
@@ -683,7 +690,7 @@ func TestBasicOrphanRemoval(t *testing.T) {
 	nonChainedOrphanTx, err := harness.CreateSignedTx([]spendableOutput{{
 		amount:   provautil.Amount(5000000000),
 		outPoint: wire.OutPoint{Hash: chainhash.Hash{}, Index: 0},
-	}}, 1)
+	}}, 1, 0, false)
 	if err != nil {
 		t.Fatalf("unable to create signed tx: %v", err)
 	}
@@ -735,7 +742,7 @@ func TestOrphanChainRemoval(t *testing.T) {
 	// none are evicted).
 	for _, tx := range chainedTxns[1 : maxOrphans+1] {
 		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, true,
-			false, 0)
+			false, TxOrigin{})
 		if err != nil {
 			t.Fatalf("ProcessTransaction: failed to accept valid "+
 				"orphan %v", err)
@@ -798,7 +805,7 @@ func TestMultiInputOrphanDoubleSpend(t *testing.T) {
 	// except the final one.
 	for _, tx := range chainedTxns[1:maxOrphans] {
 		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, true,
-			false, 0)
+			false, TxOrigin{})
 		if err != nil {
 			t.Fatalf("ProcessTransaction: failed to accept valid "+
 				"orphan %v", err)
@@ -819,12 +826,12 @@ func TestMultiInputOrphanDoubleSpend(t *testing.T) {
 	doubleSpendTx, err := harness.CreateSignedTx([]spendableOutput{
 		txOutToSpendableOut(chainedTxns[1], 0),
 		txOutToSpendableOut(chainedTxns[maxOrphans], 0),
-	}, 1)
+	}, 1, 0, false)
 	if err != nil {
 		t.Fatalf("unable to create signed tx: %v", err)
 	}
 	acceptedTxns, err := harness.txPool.ProcessTransaction(doubleSpendTx,
-		true, false, 0)
+		true, false, TxOrigin{})
 	if err != nil {
 		t.Fatalf("ProcessTransaction: failed to accept valid orphan %v",
 			err)
@@ -843,7 +850,7 @@ func TestMultiInputOrphanDoubleSpend(t *testing.T) {
 	// This will cause the shared output to become a concrete spend which
 	// will in turn must cause the double spending orphan to be removed.
 	acceptedTxns, err = harness.txPool.ProcessTransaction(chainedTxns[0],
-		false, false, 0)
+		false, false, TxOrigin{})
 	if err != nil {
 		t.Fatalf("ProcessTransaction: failed to accept valid tx %v", err)
 	}
@@ -862,3 +869,738 @@ func TestMultiInputOrphanDoubleSpend(t *testing.T) {
 	// was not moved to the transaction pool.
 	testPoolMembership(tc, doubleSpendTx, false, false)
 }
+
+// TestCheckSpend tests that CheckSpend returns the expected spends found in
+// the mempool.
+func TestCheckSpend(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+
+	// The mempool is empty, so none of the spendable outputs should have a
+	// spend there.
+	for _, op := range outputs {
+		spend := harness.txPool.CheckSpend(op.outPoint)
+		if spend != nil {
+			t.Fatalf("Unexpected spend found in pool: %v", spend)
+		}
+	}
+
+	// Create a chain of transactions rooted with the first spendable output
+	// provided by the harness.
+	const txChainLength = 5
+	chainedTxns, err := harness.CreateTxChain(outputs[0], txChainLength)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns {
+		_, err := harness.txPool.ProcessTransaction(tx, true, false, TxOrigin{})
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+
+	// The first tx in the chain should be the spend of the spendable
+	// output, and each following tx should spend the output of the tx
+	// before it.
+	spend := harness.txPool.CheckSpend(outputs[0].outPoint)
+	if spend != chainedTxns[0] {
+		t.Fatalf("expected %v to be spent by %v, instead got %v",
+			outputs[0].outPoint, chainedTxns[0], spend)
+	}
+	for i := 0; i < txChainLength-1; i++ {
+		op := wire.OutPoint{
+			Hash:  *chainedTxns[i].Hash(),
+			Index: 0,
+		}
+		expSpend := chainedTxns[i+1]
+		spend = harness.txPool.CheckSpend(op)
+		if spend != expSpend {
+			t.Fatalf("expected %v to be spent by %v, instead "+
+				"got %v", op, expSpend, spend)
+		}
+	}
+
+	// The final tx in the chain should not be spent.
+	op := wire.OutPoint{
+		Hash:  *chainedTxns[txChainLength-1].Hash(),
+		Index: 0,
+	}
+	spend = harness.txPool.CheckSpend(op)
+	if spend != nil {
+		t.Fatalf("Unexpected spend found in pool: %v", spend)
+	}
+}
+
+// addFundingOutputs adds a mature coinbase transaction with the requested
+// number of outputs of the passed amount to the harness chain's utxo set and
+// returns the outputs.  It allows tests to create transactions which pay
+// larger fees than the outputs created by the harness allow.
+func addFundingOutputs(harness *poolHarness, numOutputs uint32, amount provautil.Amount) ([]spendableOutput, error) {
+	coinbase, err := harness.CreateCoinbaseTx(1, numOutputs)
+	if err != nil {
+		return nil, err
+	}
+	msgTx := coinbase.MsgTx()
+	for _, txOut := range msgTx.TxOut {
+		txOut.Value = int64(amount)
+	}
+	coinbase = provautil.NewTx(msgTx)
+	harness.chain.utxos.AddTxOuts(coinbase, 1)
+
+	outputs := make([]spendableOutput, 0, numOutputs)
+	for i := uint32(0); i < numOutputs; i++ {
+		outputs = append(outputs, txOutToSpendableOut(coinbase, i))
+	}
+	return outputs, nil
+}
+
+// checkReplacementReject ensures the passed replacement transaction is
+// rejected by the pool with the passed reject code.
+func checkReplacementReject(t *testing.T, harness *poolHarness, tx *provautil.Tx, code wire.RejectCode) {
+	_, err := harness.txPool.ProcessTransaction(tx, false, false, TxOrigin{})
+	if err == nil {
+		_, file, line, _ := runtime.Caller(1)
+		t.Fatalf("%s:%d -- ProcessTransaction: accepted invalid "+
+			"replacement %v", file, line, tx.Hash())
+	}
+	gotCode, _ := extractRejectCode(err)
+	if gotCode != code {
+		_, file, line, _ := runtime.Caller(1)
+		t.Fatalf("%s:%d -- ProcessTransaction: unexpected reject code "+
+			"-- got %v, want %v (%v)", file, line, gotCode, code,
+			err)
+	}
+}
+
+// TestReplacement ensures transactions which conflict with transactions in the
+// pool are only accepted as replacements when replacement is enabled, the
+// conflicts signal replacement and the replacement follows the fee and
+// eviction rules.
+func TestReplacement(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	outputs, err := addFundingOutputs(harness, 5, 6000000)
+	if err != nil {
+		t.Fatalf("unable to add funding outputs: %v", err)
+	}
+
+	// createTx creates a transaction spending the passed output and fails
+	// the test when it can't be created.
+	createTx := func(output spendableOutput, fee provautil.Amount, signalsReplacement bool) *provautil.Tx {
+		tx, err := harness.CreateSignedTx([]spendableOutput{output}, 1,
+			fee, signalsReplacement)
+		if err != nil {
+			t.Fatalf("unable to create signed tx: %v", err)
+		}
+		return tx
+	}
+
+	// acceptTx processes the passed transaction and fails the test when it
+	// is not accepted.
+	acceptTx := func(tx *provautil.Tx) {
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, TxOrigin{})
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+
+	// Transactions which signal replacement can't be replaced unless
+	// replacement is enabled.
+	signaling := createTx(outputs[4], 1000, true)
+	acceptTx(signaling)
+	checkReplacementReject(t, harness, createTx(outputs[4], 100000, false),
+		wire.RejectDuplicate)
+	testPoolMembership(tc, signaling, false, true)
+	harness.txPool.cfg.Policy.AcceptReplacement = true
+
+	// A transaction which doesn't signal replacement can't be replaced.
+	original := createTx(outputs[0], 1000, false)
+	acceptTx(original)
+	checkReplacementReject(t, harness, createTx(outputs[0], 100000, true),
+		wire.RejectDuplicate)
+	testPoolMembership(tc, original, false, true)
+
+	// Replacements of a transaction which signals replacement, and of its
+	// descendant which inherits the signal, must pay a higher fee rate
+	// than each conflict and more than the sum of their fees.
+	parent := createTx(outputs[1], 1000, true)
+	acceptTx(parent)
+	child := createTx(txOutToSpendableOut(parent, 0), 1000, false)
+	acceptTx(child)
+	checkReplacementReject(t, harness, createTx(outputs[1], 1000, false),
+		wire.RejectInsufficientFee)
+	checkReplacementReject(t, harness, createTx(outputs[1], 1500, false),
+		wire.RejectInsufficientFee)
+	replacement := createTx(outputs[1], 100000, false)
+	acceptTx(replacement)
+	testPoolMembership(tc, parent, false, false)
+	testPoolMembership(tc, child, false, false)
+	testPoolMembership(tc, replacement, false, true)
+
+	// A replacement can't outbid conflicts which already pay close to the
+	// maximum fee amount.
+	maxFee := provautil.Amount(harness.chainParams.MaximumFeeAmount)
+	expensive := createTx(outputs[2], maxFee-100, true)
+	acceptTx(expensive)
+	checkReplacementReject(t, harness, createTx(outputs[2], maxFee, false),
+		wire.RejectHighFee)
+	testPoolMembership(tc, expensive, false, true)
+
+	// A replacement can't evict more than the maximum number of
+	// transactions.
+	root := createTx(outputs[3], 1000, true)
+	acceptTx(root)
+	chainedTxns, err := harness.CreateTxChain(txOutToSpendableOut(root, 0),
+		MaxReplacementEvictions)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns {
+		acceptTx(tx)
+	}
+	checkReplacementReject(t, harness, createTx(outputs[3], 100000, false),
+		wire.RejectNonstandard)
+	testPoolMembership(tc, root, false, true)
+
+	// There is no separate cancellation, so a payment which signals
+	// replacement is cancelled by a replacement which pays its input back
+	// to the sender.  This also evicts the transactions which spend the
+	// payment.
+	payment, err := harness.CreateSignedTx([]spendableOutput{outputs[4]},
+		2, 1000, true)
+	if err != nil {
+		t.Fatalf("unable to create signed tx: %v", err)
+	}
+	harness.txPool.RemoveTransaction(signaling, true)
+	acceptTx(payment)
+	received := createTx(txOutToSpendableOut(payment, 0), 1000, false)
+	acceptTx(received)
+	cancellation := createTx(outputs[4], 100000, false)
+	acceptTx(cancellation)
+	testPoolMembership(tc, payment, false, false)
+	testPoolMembership(tc, received, false, false)
+	testPoolMembership(tc, cancellation, false, true)
+}
+
+// TestPoolSizeLimit ensures the transactions with the lowest fee rates are
+// evicted when the pool exceeds its maximum size and that the dynamic minimum
+// relay fee is raised by evictions and decays afterwards.
+func TestPoolSizeLimit(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	outputs, err := addFundingOutputs(harness, 4, 6000000)
+	if err != nil {
+		t.Fatalf("unable to add funding outputs: %v", err)
+	}
+	txPool := harness.txPool
+
+	// createTx creates a transaction spending the passed output and fails
+	// the test when it can't be created.
+	createTx := func(output spendableOutput, fee provautil.Amount) *provautil.Tx {
+		tx, err := harness.CreateSignedTx([]spendableOutput{output}, 1,
+			fee, false)
+		if err != nil {
+			t.Fatalf("unable to create signed tx: %v", err)
+		}
+		return tx
+	}
+
+	// Fill the pool up to its maximum size with a low and a medium fee
+	// transaction.  The maximum size leaves room for the small differences
+	// in the sizes of the signatures of the transactions.
+	low := createTx(outputs[0], 1000)
+	medium := createTx(outputs[1], 5000)
+	for _, tx := range []*provautil.Tx{low, medium} {
+		_, err := txPool.ProcessTransaction(tx, false, false, TxOrigin{})
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+	txPool.cfg.Policy.MaxPoolSize = txPool.totalSize + 10
+	if fee := txPool.DynamicMinFee(); fee != 0 {
+		t.Fatalf("DynamicMinFee: got %v before evictions, want 0", fee)
+	}
+
+	// Accepting a high fee transaction evicts the low fee one and raises
+	// the dynamic minimum relay fee above its fee rate.
+	high := createTx(outputs[2], 20000)
+	_, err = txPool.ProcessTransaction(high, false, false, TxOrigin{})
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+	}
+	testPoolMembership(tc, low, false, false)
+	testPoolMembership(tc, medium, false, true)
+	testPoolMembership(tc, high, false, true)
+	lowFeeRate := int64(1000) * 1000 / int64(low.MsgTx().SerializeSize())
+	maxMinFee := provautil.Amount(lowFeeRate) + txPool.cfg.Policy.MinRelayTxFee
+	fee := txPool.DynamicMinFee()
+	if fee <= provautil.Amount(lowFeeRate) || fee > maxMinFee {
+		t.Fatalf("DynamicMinFee: got %v after eviction, want above "+
+			"%v and at most %v", fee, provautil.Amount(lowFeeRate),
+			maxMinFee)
+	}
+
+	// New transactions which pay less than the dynamic minimum relay fee
+	// are rejected.
+	lowAgain := createTx(outputs[3], 1000)
+	checkReplacementReject(t, harness, lowAgain, wire.RejectInsufficientFee)
+	testPoolMembership(tc, lowAgain, false, false)
+
+	// Once the dynamic minimum relay fee decayed, a low fee transaction
+	// passes the fee check but is evicted right away since the pool is
+	// still full.
+	txPool.lastRollingFeeUpdate = time.Now().Add(-30 * rollingFeeHalfLife)
+	if fee := txPool.DynamicMinFee(); fee != 0 {
+		t.Fatalf("DynamicMinFee: got %v after decay, want 0", fee)
+	}
+	checkReplacementReject(t, harness, lowAgain, wire.RejectInsufficientFee)
+	testPoolMembership(tc, lowAgain, false, false)
+	testPoolMembership(tc, medium, false, true)
+	testPoolMembership(tc, high, false, true)
+	checkEvictionQueue(t, harness)
+
+	// The pool size is also limited for transactions which are added back
+	// to the pool when a block is disconnected.
+	txPool.lastRollingFeeUpdate = time.Now().Add(-30 * rollingFeeHalfLife)
+	_, txD, err := txPool.MaybeAcceptTransaction(lowAgain, false, false)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("MaybeAcceptTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	if txD != nil {
+		t.Fatal("MaybeAcceptTransaction: returned evicted transaction")
+	}
+	testPoolMembership(tc, lowAgain, false, false)
+	testPoolMembership(tc, medium, false, true)
+	testPoolMembership(tc, high, false, true)
+	checkEvictionQueue(t, harness)
+}
+
+// checkEvictionQueue ensures the eviction queue of the pool holds exactly the
+// transactions in the pool which can be evicted, that each of them knows its
+// index in the queue, and that the queue is ordered by eviction fee rate.
+func checkEvictionQueue(t *testing.T, harness *poolHarness) {
+	txPool := harness.txPool
+	txPool.mtx.RLock()
+	defer txPool.mtx.RUnlock()
+
+	_, file, line, _ := runtime.Caller(1)
+	var numEvictable int
+	for _, txD := range txPool.pool {
+		if !isAdminTx(txD.Tx) {
+			numEvictable++
+		}
+	}
+	queue := txPool.evictionQueue
+	if len(queue) != numEvictable {
+		t.Fatalf("%s:%d -- eviction queue has %d transactions, want %d",
+			file, line, len(queue), numEvictable)
+	}
+	for i, txD := range queue {
+		if txPool.pool[*txD.Tx.Hash()] != txD {
+			t.Fatalf("%s:%d -- eviction queue has tx %v which is "+
+				"not in the pool", file, line, txD.Tx.Hash())
+		}
+		if txD.evictionIndex != i {
+			t.Fatalf("%s:%d -- tx %v has eviction index %d, want %d",
+				file, line, txD.Tx.Hash(), txD.evictionIndex, i)
+		}
+		if i > 0 && queue.Less(i, (i-1)/2) {
+			t.Fatalf("%s:%d -- eviction queue is not ordered at "+
+				"index %d", file, line, i)
+		}
+	}
+}
+
+// checkPackageStats ensures the ancestor and descendant statistics tracked by
+// the pool for the passed transaction match the passed transactions.
+func checkPackageStats(t *testing.T, harness *poolHarness, tx *provautil.Tx, ancestors, descendants []*provautil.Tx) {
+	harness.txPool.mtx.RLock()
+	txD := harness.txPool.pool[*tx.Hash()]
+	harness.txPool.mtx.RUnlock()
+	if txD == nil {
+		_, file, line, _ := runtime.Caller(1)
+		t.Fatalf("%s:%d -- tx %v is not in the pool", file, line,
+			tx.Hash())
+	}
+
+	// packageStats returns the number, size, and fees of the transaction
+	// along with the passed transactions.
+	packageStats := func(txns []*provautil.Tx) (int, int64, int64) {
+		count := len(txns) + 1
+		size := int64(tx.MsgTx().SerializeSize())
+		fees := txD.Fee
+		for _, relative := range txns {
+			size += int64(relative.MsgTx().SerializeSize())
+			fees += harness.txPool.pool[*relative.Hash()].Fee
+		}
+		return count, size, fees
+	}
+
+	count, size, fees := packageStats(ancestors)
+	if txD.AncestorCount != count || txD.AncestorSize != size ||
+		txD.AncestorFees != fees {
+
+		_, file, line, _ := runtime.Caller(1)
+		t.Fatalf("%s:%d -- tx %v has ancestor stats %d/%d/%d, want "+
+			"%d/%d/%d", file, line, tx.Hash(), txD.AncestorCount,
+			txD.AncestorSize, txD.AncestorFees, count, size, fees)
+	}
+	count, size, fees = packageStats(descendants)
+	if txD.DescendantCount != count || txD.DescendantSize != size ||
+		txD.DescendantFees != fees {
+
+		_, file, line, _ := runtime.Caller(1)
+		t.Fatalf("%s:%d -- tx %v has descendant stats %d/%d/%d, want "+
+			"%d/%d/%d", file, line, tx.Hash(), txD.DescendantCount,
+			txD.DescendantSize, txD.DescendantFees, count, size, fees)
+	}
+}
+
+// TestPackageTracking ensures the pool tracks the ancestors and descendants of
+// its transactions as they are added and removed, and enforces the package
+// limits of the policy.
+func TestPackageTracking(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	outputs, err := addFundingOutputs(harness, 1, 6000000)
+	if err != nil {
+		t.Fatalf("unable to add funding outputs: %v", err)
+	}
+	txPool := harness.txPool
+
+	// createTx creates a transaction spending the passed output and fails
+	// the test when it can't be created.
+	createTx := func(output spendableOutput, numOutputs uint32, fee provautil.Amount) *provautil.Tx {
+		tx, err := harness.CreateSignedTx([]spendableOutput{output},
+			numOutputs, fee, false)
+		if err != nil {
+			t.Fatalf("unable to create signed tx: %v", err)
+		}
+		return tx
+	}
+
+	// Accept a parent with a low fee and a child which pays for it.
+	parent := createTx(outputs[0], 2, 1000)
+	child := createTx(txOutToSpendableOut(parent, 0), 1, 50000)
+	for _, tx := range []*provautil.Tx{parent, child} {
+		_, err := txPool.ProcessTransaction(tx, false, false, TxOrigin{})
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+	checkPackageStats(t, harness, parent, nil, []*provautil.Tx{child})
+	checkPackageStats(t, harness, child, []*provautil.Tx{parent}, nil)
+
+	// The ancestor statistics are provided to the block template
+	// generator.
+	for _, desc := range txPool.MiningDescs() {
+		if !desc.Tx.Hash().IsEqual(child.Hash()) {
+			continue
+		}
+		wantFees := int64(1000 + 50000)
+		if desc.AncestorCount != 2 || desc.AncestorFees != wantFees {
+			t.Fatalf("MiningDescs: child has %d ancestors paying %d, "+
+				"want 2 paying %d", desc.AncestorCount,
+				desc.AncestorFees, wantFees)
+		}
+	}
+
+	// Transactions which would have too many ancestors, or would give an
+	// ancestor too many descendants, are rejected.
+	txPool.cfg.Policy.MaxPackageCount = 2
+	grandchild := createTx(txOutToSpendableOut(child, 0), 1, 1000)
+	checkReplacementReject(t, harness, grandchild, wire.RejectNonstandard)
+	testPoolMembership(tc, grandchild, false, false)
+	sibling := createTx(txOutToSpendableOut(parent, 1), 1, 1000)
+	checkReplacementReject(t, harness, sibling, wire.RejectNonstandard)
+	testPoolMembership(tc, sibling, false, false)
+	txPool.cfg.Policy.MaxPackageCount = 0
+	txPool.cfg.Policy.MaxPackageSize = int64(parent.MsgTx().SerializeSize() +
+		child.MsgTx().SerializeSize())
+	checkReplacementReject(t, harness, sibling, wire.RejectNonstandard)
+	txPool.cfg.Policy.MaxPackageSize = 0
+
+	// Without the limits, the statistics of all of the related
+	// transactions are updated as new transactions are added.
+	for _, tx := range []*provautil.Tx{grandchild, sibling} {
+		_, err := txPool.ProcessTransaction(tx, false, false, TxOrigin{})
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+	checkPackageStats(t, harness, parent, nil,
+		[]*provautil.Tx{child, grandchild, sibling})
+	checkPackageStats(t, harness, child, []*provautil.Tx{parent},
+		[]*provautil.Tx{grandchild})
+	checkPackageStats(t, harness, grandchild,
+		[]*provautil.Tx{parent, child}, nil)
+
+	// Removing the parent as if it was mined updates the statistics of
+	// its descendants.
+	txPool.RemoveTransaction(parent, false)
+	checkPackageStats(t, harness, child, nil, []*provautil.Tx{grandchild})
+	checkPackageStats(t, harness, grandchild, []*provautil.Tx{child}, nil)
+	checkPackageStats(t, harness, sibling, nil, nil)
+	checkEvictionQueue(t, harness)
+
+	// Adding the parent back as if its block was disconnected is subject
+	// to the package limits since it gains the descendants which are still
+	// in the pool.
+	txPool.cfg.Policy.MaxPackageCount = 3
+	_, _, err = txPool.MaybeAcceptTransaction(parent, false, false)
+	if code, _ := extractRejectCode(err); code != wire.RejectNonstandard {
+		t.Fatalf("MaybeAcceptTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectNonstandard)
+	}
+	testPoolMembership(tc, parent, false, false)
+	txPool.cfg.Policy.MaxPackageCount = 0
+
+	// Once it is accepted, the statistics of the parent along with its
+	// descendants which are still in the pool are updated.
+	_, _, err = txPool.MaybeAcceptTransaction(parent, false, false)
+	if err != nil {
+		t.Fatalf("MaybeAcceptTransaction: failed to accept tx: %v", err)
+	}
+	checkPackageStats(t, harness, parent, nil,
+		[]*provautil.Tx{child, grandchild, sibling})
+	checkPackageStats(t, harness, child, []*provautil.Tx{parent},
+		[]*provautil.Tx{grandchild})
+	checkPackageStats(t, harness, grandchild,
+		[]*provautil.Tx{parent, child}, nil)
+	checkPackageStats(t, harness, sibling, []*provautil.Tx{parent}, nil)
+	checkEvictionQueue(t, harness)
+
+	// Removing the child without its redeemers disconnects the grandchild
+	// from the parent, while removing the grandchild updates the
+	// statistics of the remaining transactions.
+	txPool.RemoveTransaction(child, false)
+	checkPackageStats(t, harness, parent, nil, []*provautil.Tx{sibling})
+	checkPackageStats(t, harness, grandchild, nil, nil)
+	checkPackageStats(t, harness, sibling, []*provautil.Tx{parent}, nil)
+	checkEvictionQueue(t, harness)
+	txPool.RemoveTransaction(grandchild, true)
+	checkPackageStats(t, harness, parent, nil, []*provautil.Tx{sibling})
+	checkPackageStats(t, harness, sibling, []*provautil.Tx{parent}, nil)
+	checkEvictionQueue(t, harness)
+}
+
+// TestTagPolicy ensures the policy overrides for a tag are applied to the
+// transactions submitted locally with it from trusted origins, but not to
+// transactions from untrusted origins or relayed by peers.
+func TestTagPolicy(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	outputs, err := addFundingOutputs(harness, 3, 1000000000)
+	if err != nil {
+		t.Fatalf("unable to add funding outputs: %v", err)
+	}
+	txPool := harness.txPool
+
+	// createLargeFreeTx creates a free transaction spending the passed
+	// output which is too large to be accepted without a fee and fails the
+	// test when it can't be created.
+	createLargeFreeTx := func(output spendableOutput) *provautil.Tx {
+		tx, err := harness.CreateSignedTx([]spendableOutput{output},
+			1500, 0, false)
+		if err != nil {
+			t.Fatalf("unable to create signed tx: %v", err)
+		}
+		if tx.MsgTx().SerializeSize() < DefaultBlockPrioritySize-1000 {
+			t.Fatalf("free tx is too small to require a fee")
+		}
+		return tx
+	}
+
+	// A large free transaction is rejected when its tag has no policy.
+	const freeTag Tag = 42
+	trusted := TxOrigin{Tag: freeTag, Trusted: true}
+	tx := createLargeFreeTx(outputs[0])
+	_, err = txPool.ProcessTransaction(tx, false, false, trusted)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, tx, false, false)
+
+	// The policy is not applied to transactions from untrusted origins,
+	// such as limited RPC users, even when the tags match.
+	txPool.cfg.Policy.TagPolicies = map[Tag]TagPolicy{
+		freeTag: {AllowFree: true},
+	}
+	untrusted := TxOrigin{Tag: freeTag}
+	_, err = txPool.ProcessTransaction(tx, false, false, untrusted)
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, tx, false, false)
+
+	// It is accepted from a trusted origin once the policy for its tag
+	// allows free transactions, and the tag is kept with it.
+	acceptedTxs, err := txPool.ProcessTransaction(tx, false, false, trusted)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+	}
+	testPoolMembership(tc, tx, false, true)
+	if acceptedTxs[0].Tag != freeTag {
+		t.Fatalf("ProcessTransaction: tx has tag %d, want %d",
+			acceptedTxs[0].Tag, freeTag)
+	}
+	entry := txPool.RawMempoolVerbose()[tx.Hash().String()]
+	if entry == nil || entry.Tag != uint64(freeTag) {
+		t.Fatalf("RawMempoolVerbose: unexpected entry %v for tx with "+
+			"tag %d", entry, freeTag)
+	}
+
+	// Transactions relayed by peers are not tagged, so the policy is not
+	// applied to them even when the peer ID matches the tag.
+	relayed := createLargeFreeTx(outputs[1])
+	_, err = txPool.ProcessTransaction(relayed, false, true,
+		TxOrigin{Peer: freeTag})
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, relayed, false, false)
+
+	// Other tags are not affected by the policy.
+	other := createLargeFreeTx(outputs[2])
+	_, err = txPool.ProcessTransaction(other, false, false,
+		TxOrigin{Tag: freeTag + 1, Trusted: true})
+	if code, _ := extractRejectCode(err); code != wire.RejectInsufficientFee {
+		t.Fatalf("ProcessTransaction: unexpected result -- got %v, "+
+			"want reject code %v", err, wire.RejectInsufficientFee)
+	}
+	testPoolMembership(tc, other, false, false)
+}
+
+// TestTestAcceptTransactions ensures testing transactions for acceptance
+// reports whether they would be accepted along with their fees without
+// modifying the pool, and that dependent packages can be tested together.
+func TestTestAcceptTransactions(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	tc := &testContext{t, harness}
+	outputs, err := addFundingOutputs(harness, 1, 6000000)
+	if err != nil {
+		t.Fatalf("unable to add funding outputs: %v", err)
+	}
+	txPool := harness.txPool
+
+	// createTx creates a transaction spending the passed output and fails
+	// the test when it can't be created.
+	createTx := func(output spendableOutput, fee provautil.Amount) *provautil.Tx {
+		tx, err := harness.CreateSignedTx([]spendableOutput{output}, 1,
+			fee, false)
+		if err != nil {
+			t.Fatalf("unable to create signed tx: %v", err)
+		}
+		return tx
+	}
+
+	// checkResults ensures the results have the passed fees for the
+	// transactions which would be accepted and the passed reject codes for
+	// the ones which would be rejected.  A reject code of zero denotes an
+	// accepted transaction.
+	checkResults := func(results []*TestAcceptResult, txs []*provautil.Tx, fees []int64, codes []wire.RejectCode) {
+		if len(results) != len(txs) {
+			t.Fatalf("TestAcceptTransactions: got %d results, want %d",
+				len(results), len(txs))
+		}
+		for i, result := range results {
+			if result.Tx != txs[i] {
+				t.Fatalf("TestAcceptTransactions: result %d is for "+
+					"tx %v, want %v", i, result.Tx.Hash(),
+					txs[i].Hash())
+			}
+			if codes[i] == 0 {
+				if result.Err != nil {
+					t.Fatalf("TestAcceptTransactions: tx %d "+
+						"rejected: %v", i, result.Err)
+				}
+				if result.Fee != fees[i] {
+					t.Fatalf("TestAcceptTransactions: tx %d "+
+						"has fee %d, want %d", i,
+						result.Fee, fees[i])
+				}
+				continue
+			}
+			code, _ := extractRejectCode(result.Err)
+			if code != codes[i] {
+				t.Fatalf("TestAcceptTransactions: tx %d unexpected "+
+					"result -- got %v, want reject code %v", i,
+					result.Err, codes[i])
+			}
+		}
+	}
+
+	// A parent and a child which spends it are both accepted when tested
+	// together, but the child alone is an orphan.  Neither is added to the
+	// pool.
+	parent := createTx(outputs[0], 1000)
+	child := createTx(txOutToSpendableOut(parent, 0), 2000)
+	pkg := []*provautil.Tx{parent, child}
+	checkResults(txPool.TestAcceptTransactions(pkg, TxOrigin{}), pkg,
+		[]int64{1000, 2000}, []wire.RejectCode{0, 0})
+	pkg = []*provautil.Tx{child}
+	checkResults(txPool.TestAcceptTransactions(pkg, TxOrigin{}), pkg, nil,
+		[]wire.RejectCode{wire.RejectDuplicate})
+	testPoolMembership(tc, parent, false, false)
+	testPoolMembership(tc, child, false, false)
+
+	// A transaction repeated within the package or double spending an
+	// earlier one is rejected, as is a transaction which spends a rejected
+	// one.
+	conflict := createTx(outputs[0], 3000)
+	conflictChild := createTx(txOutToSpendableOut(conflict, 0), 1000)
+	pkg = []*provautil.Tx{parent, parent, conflict, conflictChild}
+	checkResults(txPool.TestAcceptTransactions(pkg, TxOrigin{}), pkg,
+		[]int64{1000, 0, 0, 0}, []wire.RejectCode{0,
+			wire.RejectDuplicate, wire.RejectDuplicate,
+			wire.RejectDuplicate})
+
+	// Once the parent is in the pool, the child is accepted on its own and
+	// a transaction already in the pool is rejected.
+	_, err = txPool.ProcessTransaction(parent, false, false, TxOrigin{})
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+	}
+	pkg = []*provautil.Tx{parent, child}
+	checkResults(txPool.TestAcceptTransactions(pkg, TxOrigin{}), pkg,
+		[]int64{0, 2000}, []wire.RejectCode{wire.RejectDuplicate, 0})
+	testPoolMembership(tc, child, false, false)
+}
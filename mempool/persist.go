@@ -0,0 +1,179 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/wire"
+)
+
+// mempoolStateVersion is the version of the serialized transactions written by
+// Save.
+const mempoolStateVersion uint32 = 2
+
+// savedTx houses a transaction read from a saved memory pool along with the
+// time it was originally accepted, the tag it was submitted with and whether it
+// was submitted from a trusted origin.
+type savedTx struct {
+	tx      *provautil.Tx
+	added   time.Time
+	tag     Tag
+	trusted bool
+}
+
+// Save serializes the transactions in the main pool along with the time they
+// were accepted, the tag they were submitted with and whether they were
+// submitted from a trusted origin to the passed writer.
+// The IDs of the peers which relayed transactions are not saved since they are
+// meaningless once the node restarts.
+// Transactions are always written after the pool transactions they spend from
+// so they can be accepted in order by Load.  It returns the number of
+// transactions written.
+//
+// The serialized format is:
+//
+//	<version><num txns><added><tag><trusted><tx>...
+//
+//	Field        Type      Size
+//	version      uint32    4 bytes
+//	num txns     uint64    8 bytes
+//	added        int64     8 bytes
+//	tag          uint64    8 bytes
+//	trusted      bool      1 byte
+//	tx           MsgTx     variable
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Save(w io.Writer) (int, error) {
+	// Order the transactions while holding the lock so the dependencies
+	// between them are consistent.
+	mp.mtx.RLock()
+	visited := make(map[chainhash.Hash]struct{}, len(mp.pool))
+	ordered := make([]savedTx, 0, len(mp.pool))
+	var visit func(txD *TxDesc)
+	visit = func(txD *TxDesc) {
+		hash := *txD.Tx.Hash()
+		if _, ok := visited[hash]; ok {
+			return
+		}
+		visited[hash] = struct{}{}
+		for _, txIn := range txD.Tx.MsgTx().TxIn {
+			parent, ok := mp.pool[txIn.PreviousOutPoint.Hash]
+			if ok {
+				visit(parent)
+			}
+		}
+		ordered = append(ordered, savedTx{
+			tx:      txD.Tx,
+			added:   txD.Added,
+			tag:     txD.Tag,
+			trusted: txD.Trusted,
+		})
+	}
+	for _, txD := range mp.pool {
+		visit(txD)
+	}
+	mp.mtx.RUnlock()
+
+	err := writeElements(w, mempoolStateVersion, uint64(len(ordered)))
+	if err != nil {
+		return 0, err
+	}
+	for _, stx := range ordered {
+		err := writeElements(w, stx.added.Unix(), uint64(stx.tag),
+			stx.trusted)
+		if err != nil {
+			return 0, err
+		}
+		if err := stx.tx.MsgTx().Serialize(w); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ordered), nil
+}
+
+// readSavedTxs reads the transactions serialized by Save from the passed
+// reader.
+func readSavedTxs(r io.Reader) ([]savedTx, error) {
+	var version uint32
+	var numTxns uint64
+	if err := readElements(r, &version, &numTxns); err != nil {
+		return nil, err
+	}
+	if version != mempoolStateVersion {
+		return nil, fmt.Errorf("incorrect saved mempool version %d, "+
+			"expected %d", version, mempoolStateVersion)
+	}
+
+	var txns []savedTx
+	for i := uint64(0); i < numTxns; i++ {
+		var added int64
+		var tag uint64
+		var trusted bool
+		if err := readElements(r, &added, &tag, &trusted); err != nil {
+			return nil, err
+		}
+		var msgTx wire.MsgTx
+		if err := msgTx.Deserialize(r); err != nil {
+			return nil, err
+		}
+		txns = append(txns, savedTx{
+			tx:      provautil.NewTx(&msgTx),
+			added:   time.Unix(added, 0),
+			tag:     Tag(tag),
+			trusted: trusted,
+		})
+	}
+
+	return txns, nil
+}
+
+// Load reads the transactions serialized by Save from the passed reader and
+// processes them as if they were new, so each one is revalidated against the
+// current chain before it is accepted into the main pool.  Accepted
+// transactions keep the time they were originally accepted and the tag they
+// were submitted with.  The policy for their tag is only applied again to the
+// transactions which were submitted from a trusted origin, so transactions
+// submitted by untrusted clients don't gain it across a restart.
+//
+// It returns the transactions added to the pool along with the number of
+// saved transactions which were rejected.  Nothing is processed when the saved
+// transactions can't be read.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Load(r io.Reader) ([]*TxDesc, int, error) {
+	txns, err := readSavedTxs(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Process the transactions one at a time so the pool is not locked
+	// for the entire load.  Orphans are rejected since Save writes parents
+	// before the transactions which spend them.
+	var accepted []*TxDesc
+	var rejected int
+	for _, stx := range txns {
+		mp.mtx.Lock()
+		origin := TxOrigin{Tag: stx.tag, Trusted: stx.trusted}
+		acceptedTxs, err := mp.processTransaction(stx.tx, false, false,
+			origin, stx.added)
+		mp.mtx.Unlock()
+
+		if err != nil {
+			log.Debugf("Rejected saved transaction %v: %v",
+				stx.tx.Hash(), err)
+			rejected++
+			continue
+		}
+		accepted = append(accepted, acceptedTxs...)
+	}
+
+	return accepted, rejected, nil
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+// TestSaveLoad ensures the transactions saved from the pool are revalidated
+// and accepted again by Load along with their acceptance time and tag.
+func TestSaveLoad(t *testing.T) {
+	t.Parallel()
+
+	harness, outputs, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	chainedTxns, err := harness.CreateTxChain(outputs[0], 3)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	// The first transaction is relayed by a peer while the others are
+	// submitted locally with a tag, so the ID of the peer is not saved.
+	// Only the last one is submitted from a trusted origin.
+	wantOrigins := make(map[chainhash.Hash]TxOrigin)
+	for i, tx := range chainedTxns {
+		origin := TxOrigin{Tag: Tag(i), Trusted: i == len(chainedTxns)-1}
+		if i == 0 {
+			origin = TxOrigin{Peer: 7}
+		}
+		wantOrigins[*tx.Hash()] = TxOrigin{Tag: Tag(i),
+			Trusted: origin.Trusted}
+		_, err := harness.txPool.ProcessTransaction(tx, false, false,
+			origin)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: failed to accept tx: %v",
+				err)
+		}
+	}
+
+	// Pretend the transactions were accepted a while ago so the restored
+	// acceptance time can't match the time they are loaded at.
+	saved := make(map[chainhash.Hash]*TxDesc)
+	for _, txD := range harness.txPool.TxDescs() {
+		txD.Added = txD.Added.Add(-time.Hour)
+		saved[*txD.Tx.Hash()] = txD
+	}
+
+	var buf bytes.Buffer
+	numSaved, err := harness.txPool.Save(&buf)
+	if err != nil {
+		t.Fatalf("Save: unexpected error: %v", err)
+	}
+	if numSaved != len(chainedTxns) {
+		t.Fatalf("Save: saved %d transactions, want %d", numSaved,
+			len(chainedTxns))
+	}
+	state := buf.Bytes()
+
+	// Remove the whole chain from the pool and ensure loading the saved
+	// transactions accepts all of them again with the same acceptance
+	// time, tag and origin trust.
+	harness.txPool.RemoveTransaction(chainedTxns[0], true)
+	if harness.txPool.Count() != 0 {
+		t.Fatalf("pool has %d transactions after removing the chain",
+			harness.txPool.Count())
+	}
+	accepted, rejected, err := harness.txPool.Load(bytes.NewReader(state))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(accepted) != len(chainedTxns) || rejected != 0 {
+		t.Fatalf("Load: accepted %d and rejected %d transactions, "+
+			"want %d and 0", len(accepted), rejected,
+			len(chainedTxns))
+	}
+	for _, txD := range accepted {
+		want := saved[*txD.Tx.Hash()]
+		if want == nil {
+			t.Fatalf("Load: accepted unknown transaction %v",
+				txD.Tx.Hash())
+		}
+		wantOrigin := wantOrigins[*txD.Tx.Hash()]
+		if txD.Tag != wantOrigin.Tag {
+			t.Fatalf("Load: tx %v has tag %d, want %d",
+				txD.Tx.Hash(), txD.Tag, wantOrigin.Tag)
+		}
+		if txD.Trusted != wantOrigin.Trusted {
+			t.Fatalf("Load: tx %v has trusted %v, want %v",
+				txD.Tx.Hash(), txD.Trusted, wantOrigin.Trusted)
+		}
+		if txD.Added.Unix() != want.Added.Unix() {
+			t.Fatalf("Load: tx %v was added at %v, want %v",
+				txD.Tx.Hash(), txD.Added, want.Added)
+		}
+	}
+
+	// Loading the saved transactions again rejects all of them since they
+	// are already in the pool.
+	accepted, rejected, err = harness.txPool.Load(bytes.NewReader(state))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(accepted) != 0 || rejected != len(chainedTxns) {
+		t.Fatalf("Load: accepted %d and rejected %d transactions, "+
+			"want 0 and %d", len(accepted), rejected,
+			len(chainedTxns))
+	}
+
+	// Loading a state with an unknown version or truncated transactions
+	// fails without processing anything.
+	badVersion := append([]byte(nil), state...)
+	badVersion[3]++
+	if _, _, err := harness.txPool.Load(bytes.NewReader(badVersion)); err == nil {
+		t.Fatal("Load: did not fail with a bad version")
+	}
+	truncated := state[:len(state)-1]
+	if _, _, err := harness.txPool.Load(bytes.NewReader(truncated)); err == nil {
+		t.Fatal("Load: did not fail with a truncated state")
+	}
+}
+
+// TestLoadTagPolicy ensures the policy for the tag of a saved transaction is
+// only applied again when it is loaded if the transaction was submitted from a
+// trusted origin.
+func TestLoadTagPolicy(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	outputs, err := addFundingOutputs(harness, 1, 1000000000)
+	if err != nil {
+		t.Fatalf("unable to add funding outputs: %v", err)
+	}
+	txPool := harness.txPool
+
+	// Accept a free transaction which is too large to be accepted without
+	// a fee unless the policy for its tag is applied.
+	const freeTag Tag = 42
+	txPool.cfg.Policy.TagPolicies = map[Tag]TagPolicy{
+		freeTag: {AllowFree: true},
+	}
+	tx, err := harness.CreateSignedTx(outputs, 1500, 0, false)
+	if err != nil {
+		t.Fatalf("unable to create signed tx: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(tx, false, false,
+		TxOrigin{Tag: freeTag, Trusted: true})
+	if err != nil {
+		t.Fatalf("ProcessTransaction: failed to accept tx: %v", err)
+	}
+
+	// save returns the serialized pool with the transaction marked as
+	// submitted from a trusted origin or not and removes it from the pool.
+	save := func(trusted bool) []byte {
+		txPool.TxDescs()[0].Trusted = trusted
+		var buf bytes.Buffer
+		if _, err := txPool.Save(&buf); err != nil {
+			t.Fatalf("Save: unexpected error: %v", err)
+		}
+		txPool.RemoveTransaction(tx, true)
+		return buf.Bytes()
+	}
+
+	// The transaction is accepted again from a trusted origin.
+	accepted, rejected, err := txPool.Load(bytes.NewReader(save(true)))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(accepted) != 1 || rejected != 0 {
+		t.Fatalf("Load: accepted %d and rejected %d transactions, "+
+			"want 1 and 0", len(accepted), rejected)
+	}
+
+	// The same transaction is rejected once it was not submitted from a
+	// trusted origin.
+	accepted, rejected, err = txPool.Load(bytes.NewReader(save(false)))
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if len(accepted) != 0 || rejected != 1 {
+		t.Fatalf("Load: accepted %d and rejected %d transactions, "+
+			"want 0 and 1", len(accepted), rejected)
+	}
+}
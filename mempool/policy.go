@@ -125,13 +125,13 @@ func checkInputsStandard(tx *provautil.Tx, utxoView *blockchain.UtxoViewpoint) e
 					"thread transaction %v with input at position "+
 					"%d. Only input #0 may spend an admin threads.",
 					tx.Hash(), prevOut.Hash, txInIndex)
-				return txRuleError(wire.RejectInvalidAdmin, str)
+				return txRuleError(wire.RejectThreadOrder, str)
 			}
 			if !hasAdminOut {
 				str := fmt.Sprintf("transaction %v spends admin output, "+
 					"yet does not continue admin thread. Should have admin "+
 					"output at position 0.", tx.Hash())
-				return txRuleError(wire.RejectInvalidAdmin, str)
+				return txRuleError(wire.RejectThreadOrder, str)
 			}
 			hasAdminIn = true
 			// check admin thread input is spend to same thread
@@ -139,7 +139,7 @@ func checkInputsStandard(tx *provautil.Tx, utxoView *blockchain.UtxoViewpoint) e
 				thisPkScript[1] != originPkScript[1] {
 				str := fmt.Sprintf("admin transaction input #%d is "+
 					"spending wrong thread.", txInIndex)
-				return txRuleError(wire.RejectInvalidAdmin, str)
+				return txRuleError(wire.RejectThreadOrder, str)
 			}
 		case txscript.NonStandardTy:
 			str := fmt.Sprintf("transaction input #%d has a "+
@@ -153,7 +153,7 @@ func checkInputsStandard(tx *provautil.Tx, utxoView *blockchain.UtxoViewpoint) e
 			str := fmt.Sprintf("tried to issue admin operation "+
 				"at transaction %s:%d without spending valid thread ",
 				tx.Hash(), txInIndex)
-			return txRuleError(wire.RejectInvalidAdmin, str)
+			return txRuleError(wire.RejectThreadOrder, str)
 		}
 
 	}
@@ -181,6 +181,49 @@ func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) er
 	return nil
 }
 
+// isAdminTx returns whether or not the passed transaction continues an admin
+// thread.  Admin transactions are free by design since their outputs must be
+// zero value, so they are exempt from the fee and priority based policies of
+// the pool.
+func isAdminTx(tx *provautil.Tx) bool {
+	threadInt, _ := txscript.GetAdminDetails(tx)
+	return threadInt >= 0
+}
+
+// checkSupplyUnderflow ensures a destruction transaction on the issue thread
+// does not destroy more than the passed total supply.  The destroyed amounts
+// are carried by the null data outputs which follow the thread output.
+//
+// This is not a consensus rule, so blocks containing such transactions are
+// still valid, but they are not accepted into the pool.
+func checkSupplyUnderflow(tx *provautil.Tx, totalSupply uint64) error {
+	threadInt, adminOutputs := txscript.GetAdminDetails(tx)
+	if threadInt < 0 || provautil.ThreadID(threadInt) != provautil.IssueThread {
+		return nil
+	}
+
+	// Issuances only spend the thread tip, so there is nothing destroyed.
+	msgTx := tx.MsgTx()
+	if len(msgTx.TxIn) < 2 {
+		return nil
+	}
+	var destroyed uint64
+	for i, output := range adminOutputs {
+		// +1 here, because first out was thread output, which is not
+		// contained in adminOutputs.
+		if txscript.TypeOfScript(output) == txscript.NullDataTy {
+			destroyed += uint64(msgTx.TxOut[i+1].Value)
+		}
+	}
+	if destroyed > totalSupply {
+		str := fmt.Sprintf("transaction %v destroys %v which is more "+
+			"than the total supply of %v", tx.Hash(), destroyed,
+			totalSupply)
+		return txRuleError(wire.RejectSupplyUnderflow, str)
+	}
+	return nil
+}
+
 // isDust returns whether or not the passed transaction output amount is
 // considered dust or not based on the passed minimum transaction relay fee.
 // Dust is defined in terms of the minimum transaction relay fee.  In
@@ -399,7 +442,7 @@ func checkTransactionStandard(tx *provautil.Tx, height uint32,
 				if !txscript.IsValidAdminOp(adminOpOut, threadId) {
 					str := fmt.Sprintf("admin transaction with invalid admin " +
 						"operation found.")
-					return txRuleError(wire.RejectInvalid, str)
+					return txRuleError(wire.RejectInvalidAdminOp, str)
 				}
 			}
 		}
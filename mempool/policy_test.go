@@ -570,7 +570,7 @@ func TestCheckTransactionStandard(t *testing.T) {
 			},
 			height:     300000,
 			isStandard: false,
-			code:       wire.RejectInvalid,
+			code:       wire.RejectInvalidAdminOp,
 		},
 		{
 			name: "Admin transaction with invalid operation",
@@ -585,7 +585,7 @@ func TestCheckTransactionStandard(t *testing.T) {
 			},
 			height:     300000,
 			isStandard: false,
-			code:       wire.RejectInvalid,
+			code:       wire.RejectInvalidAdminOp,
 		},
 	}
 
@@ -799,7 +799,7 @@ func TestCheckAdminTransactionStandard(t *testing.T) {
 				LockTime: 0,
 			},
 			height:     300000,
-			code:       wire.RejectInvalidAdmin,
+			code:       wire.RejectThreadOrder,
 			isStandard: false,
 		},
 		{
@@ -811,7 +811,7 @@ func TestCheckAdminTransactionStandard(t *testing.T) {
 				LockTime: 0,
 			},
 			height:     300000,
-			code:       wire.RejectInvalidAdmin,
+			code:       wire.RejectThreadOrder,
 			isStandard: false,
 		},
 		{
@@ -823,7 +823,7 @@ func TestCheckAdminTransactionStandard(t *testing.T) {
 				LockTime: 0,
 			},
 			height:     300000,
-			code:       wire.RejectInvalidAdmin,
+			code:       wire.RejectThreadOrder,
 			isStandard: false,
 		},
 		{
@@ -835,7 +835,7 @@ func TestCheckAdminTransactionStandard(t *testing.T) {
 				LockTime: 0,
 			},
 			height:     300000,
-			code:       wire.RejectInvalidAdmin,
+			code:       wire.RejectThreadOrder,
 			isStandard: false,
 		},
 		{
@@ -869,7 +869,7 @@ func TestCheckAdminTransactionStandard(t *testing.T) {
 				LockTime: 0,
 			},
 			height:     300000,
-			code:       wire.RejectInvalidAdmin,
+			code:       wire.RejectThreadOrder,
 			isStandard: false,
 		},
 		{
@@ -948,3 +948,106 @@ func TestCheckAdminTransactionStandard(t *testing.T) {
 		}
 	}
 }
+
+// TestIsAdminTx ensures only transactions which continue an admin thread are
+// treated as admin transactions.
+func TestIsAdminTx(t *testing.T) {
+	rootPkScript, _ := txscript.ProvaThreadScript(provautil.RootThread)
+	adminOpPkScript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).AddData([]byte{0x00}).Script()
+	payPkScript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_TRUE).Script()
+
+	tests := []struct {
+		name      string
+		pkScripts [][]byte
+		isAdmin   bool
+	}{
+		{
+			name:      "admin thread output first",
+			pkScripts: [][]byte{rootPkScript, adminOpPkScript},
+			isAdmin:   true,
+		},
+		{
+			name:      "admin thread output not first",
+			pkScripts: [][]byte{payPkScript, rootPkScript},
+			isAdmin:   false,
+		},
+		{
+			name:      "no admin thread output",
+			pkScripts: [][]byte{payPkScript},
+			isAdmin:   false,
+		},
+	}
+	for _, test := range tests {
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		for _, pkScript := range test.pkScripts {
+			msgTx.AddTxOut(wire.NewTxOut(0, pkScript))
+		}
+		got := isAdminTx(provautil.NewTx(msgTx))
+		if got != test.isAdmin {
+			t.Errorf("isAdminTx (%s): got %v, want %v", test.name,
+				got, test.isAdmin)
+		}
+	}
+}
+
+// TestCheckSupplyUnderflow ensures destructions on the issue thread are only
+// rejected when they destroy more than the total supply.
+func TestCheckSupplyUnderflow(t *testing.T) {
+	issuePkScript, _ := txscript.ProvaThreadScript(provautil.IssueThread)
+	nullPkScript, _ := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).AddData([]byte{0x00}).Script()
+	dummyTxIn := wire.NewTxIn(&wire.OutPoint{}, nil)
+
+	tests := []struct {
+		name      string
+		numInputs int
+		destroyed []int64
+		isValid   bool
+	}{
+		{
+			name:      "destroy up to the total supply",
+			numInputs: 2,
+			destroyed: []int64{400, 600},
+			isValid:   true,
+		},
+		{
+			name:      "destroy more than the total supply",
+			numInputs: 2,
+			destroyed: []int64{400, 601},
+			isValid:   false,
+		},
+		{
+			name:      "issue more than the total supply",
+			numInputs: 1,
+			destroyed: []int64{1001},
+			isValid:   true,
+		},
+	}
+	const totalSupply = 1000
+	for _, test := range tests {
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		for i := 0; i < test.numInputs; i++ {
+			msgTx.AddTxIn(dummyTxIn)
+		}
+		msgTx.AddTxOut(wire.NewTxOut(0, issuePkScript))
+		for _, value := range test.destroyed {
+			msgTx.AddTxOut(wire.NewTxOut(value, nullPkScript))
+		}
+		err := checkSupplyUnderflow(provautil.NewTx(msgTx), totalSupply)
+		if test.isValid {
+			if err != nil {
+				t.Errorf("checkSupplyUnderflow (%s): unexpected "+
+					"error: %v", test.name, err)
+			}
+			continue
+		}
+		code, _ := extractRejectCode(err)
+		if code != wire.RejectSupplyUnderflow {
+			t.Errorf("checkSupplyUnderflow (%s): unexpected result - "+
+				"got %v, want reject code %v", test.name, err,
+				wire.RejectSupplyUnderflow)
+		}
+	}
+}
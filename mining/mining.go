@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"container/heap"
 	"encoding/hex"
+	"sort"
 	"time"
 
 	"github.com/bitgo/prova/blockchain"
@@ -31,7 +32,7 @@ const (
 	// will require changes to the generated block.  Using the wire constant
 	// for generated block version could allow creation of invalid blocks
 	// for the updated version.
-	generatedBlockVersion = 4
+	generatedBlockVersion = 5
 
 	// blockHeaderOverhead is the max number of bytes it takes to serialize
 	// a block header and max possible transaction count.
@@ -61,6 +62,20 @@ type TxDesc struct {
 
 	// FeePerKB is the fee the transaction pays in Satoshi per 1000 bytes.
 	FeePerKB int64
+
+	// AncestorCount is the number of transactions in the source pool the
+	// transaction depends on, directly or indirectly, including itself.
+	AncestorCount int
+
+	// AncestorSize is the total serialized size of the transaction and its
+	// ancestors in the source pool.
+	AncestorSize int64
+
+	// AncestorFees is the total fee paid by the transaction and its
+	// ancestors in the source pool.  Together with AncestorSize, it is
+	// used to select transactions by the fee rate of the package they form
+	// with their ancestors so a child can pay for its parents.
+	AncestorFees int64
 }
 
 // TxSource represents a source of transactions to consider for inclusion in
@@ -92,6 +107,15 @@ type txPrioItem struct {
 	feePerKB int64
 	isAdmin  bool
 
+	// threadID is the admin thread the transaction continues.  It is only
+	// set for admin transactions.
+	threadID provautil.ThreadID
+
+	// ancestorFeePerKB is the fee per kilobyte of the package the
+	// transaction forms with its ancestors in the source pool.  It is zero
+	// when the source pool does not track ancestors.
+	ancestorFeePerKB int64
+
 	// dependsOn holds a map of transaction hashes which this one depends
 	// on.  It will only be set when the transaction references other
 	// transactions in the source pool and hence must come after them in
@@ -115,6 +139,66 @@ func isAdmin(msgTx *wire.MsgTx) bool {
 	return false
 }
 
+// applyAncestorFeeRates raises the fee per kilobyte of every transaction in
+// the passed map to the highest ancestor fee rate of the transactions which
+// depend on it.  Since a transaction can only be included in a block after the
+// transactions it depends on, this allows a child which pays a high fee to pull
+// its low fee parents into the block along with it.
+//
+// The dependencies are followed using the dependsOn map of each item, so it
+// must be called before any of the dependencies are removed.
+func applyAncestorFeeRates(prioItems map[chainhash.Hash]*txPrioItem) {
+	for _, prioItem := range prioItems {
+		feePerKB := prioItem.ancestorFeePerKB
+		if prioItem.dependsOn == nil || feePerKB == 0 {
+			continue
+		}
+
+		visited := make(map[chainhash.Hash]struct{})
+		var raise func(item *txPrioItem)
+		raise = func(item *txPrioItem) {
+			for hash := range item.dependsOn {
+				if _, ok := visited[hash]; ok {
+					continue
+				}
+				visited[hash] = struct{}{}
+				parent, ok := prioItems[hash]
+				if !ok {
+					continue
+				}
+				if feePerKB > parent.feePerKB {
+					parent.feePerKB = feePerKB
+				}
+				raise(parent)
+			}
+		}
+		raise(prioItem)
+	}
+}
+
+// adminPrioItems implements sort.Interface to allow a slice of admin
+// transactions to be sorted by the admin thread they continue.
+type adminPrioItems []*txPrioItem
+
+// Len returns the number of admin transactions in the slice.  It is part of
+// the sort.Interface implementation.
+func (s adminPrioItems) Len() int {
+	return len(s)
+}
+
+// Swap swaps the admin transactions at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s adminPrioItems) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the admin transaction with index i should sort before
+// the admin transaction with index j.  It is part of the sort.Interface
+// implementation.
+func (s adminPrioItems) Less(i, j int) bool {
+	return s[i].threadID < s[j].threadID
+}
+
 // txPriorityQueueLessFunc describes a function that can be used as a compare
 // function for a transaction priority queue (txPriorityQueue).
 type txPriorityQueueLessFunc func(*txPriorityQueue, int, int) bool
@@ -246,6 +330,11 @@ type BlockTemplate struct {
 	// NewBlockTemplate for details on which this can be useful to generate
 	// templates without a coinbase payment address.
 	ValidPayAddress bool
+
+	// NumAdminTxns is the number of admin transactions which directly
+	// follow the coinbase in the generated template.  They must be included
+	// in any block built from the template.
+	NumAdminTxns int
 }
 
 // mergeUtxoView adds all of the entries in view to viewA.  The result is that
@@ -433,15 +522,27 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 // functionality is useful since there are cases such as the getblocktemplate
 // RPC where external mining software is responsible for creating their own
 // coinbase which will replace the one generated for the block template.  Thus
-// the need to have configured address can be avoided.
+// the need to have configured address can be avoided.  Similarly, the block is
+// signed with the passed validate key if it is not nil, or left unsigned so it
+// can be signed by an external block builder.
 //
 // The transactions selected and included are prioritized according to several
 // factors.  First, each transaction has a priority calculated based on its
 // value, age of inputs, and size.  Transactions which consist of larger
 // amounts, older inputs, and small sizes have the highest priority.  Second, a
 // fee per kilobyte is calculated for each transaction.  Transactions with a
-// higher fee per kilobyte are preferred.  Finally, the block generation related
-// policy settings are all taken into account.
+// higher fee per kilobyte are preferred.  The fee per kilobyte of a transaction
+// which other transactions in the source pool depend on is raised to the
+// highest fee rate of the packages those transactions form with their
+// ancestors, so a child paying a high fee pulls its parents into the block.
+// Finally, the block generation related policy settings are all taken into
+// account.
+//
+// Admin transactions are free by design, so they bypass the prioritization
+// entirely.  They are placed directly after the coinbase, ordered by the admin
+// thread they continue and by their order within each thread, before any other
+// transactions are considered.  This ensures space is always available for them
+// regardless of how many other transactions are pending.
 //
 // Transactions which only spend outputs from other transactions already in the
 // block chain are immediately added to a priority queue which either
@@ -471,6 +572,8 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 //   -----------------------------------  --  --
 //  |      Coinbase Transaction         |   |   |
 //  |-----------------------------------|   |   |
+//  |   Admin Transactions (by thread)  |   |   |
+//  |-----------------------------------|   |   |
 //  |                                   |   |   | ----- policy.BlockPrioritySize
 //  |   High-priority Transactions      |   |   |
 //  |                                   |   |   |
@@ -542,6 +645,11 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress provautil.Address, vali
 	// in the block once each transaction has been included.
 	dependers := make(map[chainhash.Hash]map[chainhash.Hash]*txPrioItem)
 
+	// prioItems houses the prioritized transactions which are candidates
+	// for inclusion in the block so the fee rates of the transactions they
+	// depend on can be raised once all of them are known.
+	prioItems := make(map[chainhash.Hash]*txPrioItem, len(sourceTxns))
+
 	// Create slices to hold the fees and number of signature operations
 	// for each of the selected transactions and add an entry for the
 	// coinbase.  This allows the code below to simply append details about
@@ -631,12 +739,15 @@ mempoolLoop:
 		prioItem.feePerKB = txDesc.FeePerKB
 		prioItem.fee = txDesc.Fee
 		prioItem.isAdmin = isAdmin(tx.MsgTx())
-
-		// Add the transaction to the priority queue to mark it ready
-		// for inclusion in the block unless it has dependencies.
-		if prioItem.dependsOn == nil {
-			heap.Push(priorityQueue, prioItem)
+		if prioItem.isAdmin {
+			threadInt, _ := txscript.GetAdminDetailsMsgTx(tx.MsgTx())
+			prioItem.threadID = provautil.ThreadID(threadInt)
 		}
+		if txDesc.AncestorSize > 0 {
+			prioItem.ancestorFeePerKB = txDesc.AncestorFees * 1000 /
+				txDesc.AncestorSize
+		}
+		prioItems[*tx.Hash()] = prioItem
 
 		// Merge the referenced outputs from the input transactions to
 		// this transaction into the block utxo view.  This allows the
@@ -644,8 +755,25 @@ mempoolLoop:
 		mergeUtxoView(blockUtxos, utxos)
 	}
 
-	log.Tracef("Priority queue len %d, dependers len %d",
-		priorityQueue.Len(), len(dependers))
+	// Raise the fee rates of the transactions others depend on so children
+	// pay for their parents.  Then set aside the admin transactions for the
+	// admin lane and add the others to the priority queue to mark them
+	// ready for inclusion in the block unless they have dependencies.
+	applyAncestorFeeRates(prioItems)
+	adminLane := make(map[chainhash.Hash]*txPrioItem)
+	numAdminTxns := 0
+	for hash, prioItem := range prioItems {
+		if prioItem.isAdmin {
+			adminLane[hash] = prioItem
+			continue
+		}
+		if prioItem.dependsOn == nil {
+			heap.Push(priorityQueue, prioItem)
+		}
+	}
+
+	log.Tracef("Priority queue len %d, dependers len %d, admin lane len %d",
+		priorityQueue.Len(), len(dependers), len(adminLane))
 
 	// The starting block size is the size of the block header plus the max
 	// possible transaction count size, plus the size of the coinbase
@@ -654,16 +782,10 @@ mempoolLoop:
 	blockSigOps := numCoinbaseSigOps
 	totalFees := int64(0)
 
-	// Choose which transactions make it into the block.
-	for priorityQueue.Len() > 0 {
-		// Grab the highest priority (or highest fee per kilobyte
-		// depending on the sort order) transaction.
-		prioItem := heap.Pop(priorityQueue).(*txPrioItem)
-		tx := prioItem.tx
-
-		// Grab the list of transactions which depend on this one (if any).
-		deps := dependers[*tx.Hash()]
-
+	// fitTransaction returns the size and number of signature operations
+	// of the passed transaction along with whether or not adding it keeps
+	// the block within the maximum block size and signature operations.
+	fitTransaction := func(tx *provautil.Tx) (uint32, int64, bool) {
 		// Enforce maximum block size.  Also check for overflow.
 		txSize := uint32(tx.MsgTx().SerializeSize())
 		blockPlusTxSize := blockSize + txSize
@@ -672,8 +794,7 @@ mempoolLoop:
 
 			log.Tracef("Skipping tx %s because it would exceed "+
 				"the max block size", tx.Hash())
-			logSkippedDeps(tx, deps)
-			continue
+			return 0, 0, false
 		}
 
 		// Enforce maximum signature operations per block.  Also check
@@ -683,16 +804,14 @@ mempoolLoop:
 			blockSigOps+numSigOps > blockchain.MaxSigOpsPerBlock {
 			log.Tracef("Skipping tx %s because it would "+
 				"exceed the maximum sigops per block", tx.Hash())
-			logSkippedDeps(tx, deps)
-			continue
+			return 0, 0, false
 		}
 		numP2SHSigOps, err := blockchain.CountP2SHSigOps(tx, false,
 			blockUtxos)
 		if err != nil {
 			log.Tracef("Skipping tx %s due to error in "+
 				"CountP2SHSigOps: %v", tx.Hash(), err)
-			logSkippedDeps(tx, deps)
-			continue
+			return 0, 0, false
 		}
 		numSigOps += int64(numP2SHSigOps)
 		if blockSigOps+numSigOps < blockSigOps ||
@@ -700,9 +819,142 @@ mempoolLoop:
 			log.Tracef("Skipping tx %s because it would "+
 				"exceed the maximum sigops per block (p2sh)",
 				tx.Hash())
+			return 0, 0, false
+		}
+
+		return txSize, numSigOps, true
+	}
+
+	// validateTransaction returns whether or not the passed transaction
+	// passes all of the necessary preconditions to be added to the block.
+	validateTransaction := func(tx *provautil.Tx) bool {
+		// Ensure the transaction inputs pass all of the necessary
+		// preconditions before allowing it to be added to the block.
+		_, err := blockchain.CheckTransactionInputs(tx, nextBlockHeight,
+			blockUtxos, g.chainParams)
+		if err != nil {
+			log.Tracef("Skipping tx %s due to error in "+
+				"CheckTransactionInputs: %v", tx.Hash(), err)
+			return false
+		}
+
+		// CheckTransactionOutputs checks outputs for state violations.
+		err = blockchain.CheckTransactionOutputs(tx, keyView)
+		if err != nil {
+			log.Tracef("Skipping tx %s due to error in "+
+				"CheckTransactionOutputs: %v", tx.Hash(), err)
+			return false
+		}
+
+		err = blockchain.ValidateTransactionScripts(tx, blockUtxos, keyView,
+			txscript.StandardVerifyFlags, g.sigCache, g.hashCache)
+		if err != nil {
+			log.Tracef("Skipping tx %s due to error in "+
+				"ValidateTransactionScripts: %v", tx.Hash(), err)
+			return false
+		}
+
+		return true
+	}
+
+	// addTransaction adds the passed transaction to the block and makes
+	// the transactions which depend on it (and also do not have any other
+	// unsatisfied dependencies) eligible for inclusion.  Admin transactions
+	// which are still pending in the admin lane are left for the lane,
+	// while all others are added to the priority queue.
+	addTransaction := func(prioItem *txPrioItem, txSize uint32, numSigOps int64) {
+		// Spend the transaction inputs in the block utxo view and add
+		// an entry for it to ensure any transactions which reference
+		// this one have it available as an input and can ensure they
+		// aren't double spending.
+		tx := prioItem.tx
+		spendTransaction(blockUtxos, tx, nextBlockHeight)
+
+		// Add the transaction to the block, increment counters, and
+		// save the fees and signature operation counts to the block
+		// template.
+		blockTxns = append(blockTxns, tx)
+		blockSize += txSize
+		blockSigOps += numSigOps
+		totalFees += prioItem.fee
+		txFees = append(txFees, prioItem.fee)
+		txSigOpCounts = append(txSigOpCounts, numSigOps)
+
+		log.Tracef("Adding tx %s (priority %.2f, feePerKB %d)",
+			prioItem.tx.Hash(), prioItem.priority, prioItem.feePerKB)
+
+		for hash, item := range dependers[*tx.Hash()] {
+			// Add the transaction to the priority queue if there
+			// are no more dependencies after this one.
+			delete(item.dependsOn, *tx.Hash())
+			if _, ok := adminLane[hash]; ok {
+				continue
+			}
+			if len(item.dependsOn) == 0 {
+				heap.Push(priorityQueue, item)
+			}
+		}
+	}
+
+	// Add the pending admin transactions to the block before any others,
+	// ordered by thread and by the order in which they continue each
+	// thread.  Admin transactions are free by design, so they are exempt
+	// from the free transaction and priority limits below to ensure a
+	// burst of other transactions can't delay critical operations such as
+	// revoking a validate key.  An admin transaction which also depends on
+	// other transactions in the source pool is left for the priority queue
+	// once those are included.
+	adminItems := make(adminPrioItems, 0, len(adminLane))
+	for _, prioItem := range adminLane {
+		adminItems = append(adminItems, prioItem)
+	}
+	sort.Sort(adminItems)
+	for len(adminItems) > 0 {
+		// Grab the first admin transaction in thread order which does
+		// not depend on any transactions which are not included yet.
+		next := -1
+		for i, prioItem := range adminItems {
+			if len(prioItem.dependsOn) == 0 {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			break
+		}
+		prioItem := adminItems[next]
+		adminItems = append(adminItems[:next], adminItems[next+1:]...)
+		delete(adminLane, *prioItem.tx.Hash())
+
+		tx := prioItem.tx
+		deps := dependers[*tx.Hash()]
+		txSize, numSigOps, ok := fitTransaction(tx)
+		if !ok || !validateTransaction(tx) {
+			logSkippedDeps(tx, deps)
+			continue
+		}
+		addTransaction(prioItem, txSize, numSigOps)
+		numAdminTxns++
+	}
+	adminLane = nil
+
+	// Choose which of the remaining transactions make it into the block.
+	for priorityQueue.Len() > 0 {
+		// Grab the highest priority (or highest fee per kilobyte
+		// depending on the sort order) transaction.
+		prioItem := heap.Pop(priorityQueue).(*txPrioItem)
+		tx := prioItem.tx
+
+		// Grab the list of transactions which depend on this one (if any).
+		deps := dependers[*tx.Hash()]
+
+		// Enforce maximum block size and signature operations.
+		txSize, numSigOps, ok := fitTransaction(tx)
+		if !ok {
 			logSkippedDeps(tx, deps)
 			continue
 		}
+		blockPlusTxSize := blockSize + txSize
 
 		// Skip free transactions once the block is larger than the
 		// minimum block size.
@@ -748,65 +1000,13 @@ mempoolLoop:
 			}
 		}
 
-		// Ensure the transaction inputs pass all of the necessary
+		// Ensure the transaction passes all of the necessary
 		// preconditions before allowing it to be added to the block.
-		_, err = blockchain.CheckTransactionInputs(tx, nextBlockHeight,
-			blockUtxos, g.chainParams)
-		if err != nil {
-			log.Tracef("Skipping tx %s due to error in "+
-				"CheckTransactionInputs: %v", tx.Hash(), err)
-			logSkippedDeps(tx, deps)
-			continue
-		}
-
-		// CheckTransactionOutputs checks outputs for state violations.
-		err = blockchain.CheckTransactionOutputs(tx, keyView)
-		if err != nil {
-			log.Tracef("Skipping tx %s due to error in "+
-				"CheckTransactionOutputs: %v", tx.Hash(), err)
-			logSkippedDeps(tx, deps)
-			continue
-		}
-
-		err = blockchain.ValidateTransactionScripts(tx, blockUtxos, keyView,
-			txscript.StandardVerifyFlags, g.sigCache, g.hashCache)
-		if err != nil {
-			log.Tracef("Skipping tx %s due to error in "+
-				"ValidateTransactionScripts: %v", tx.Hash(), err)
+		if !validateTransaction(tx) {
 			logSkippedDeps(tx, deps)
 			continue
 		}
-
-		// Spend the transaction inputs in the block utxo view and add
-		// an entry for it to ensure any transactions which reference
-		// this one have it available as an input and can ensure they
-		// aren't double spending.
-		spendTransaction(blockUtxos, tx, nextBlockHeight)
-
-		// Add the transaction to the block, increment counters, and
-		// save the fees and signature operation counts to the block
-		// template.
-		blockTxns = append(blockTxns, tx)
-		blockSize += txSize
-		blockSigOps += numSigOps
-		totalFees += prioItem.fee
-		txFees = append(txFees, prioItem.fee)
-		txSigOpCounts = append(txSigOpCounts, numSigOps)
-
-		log.Tracef("Adding tx %s (priority %.2f, feePerKB %d)",
-			prioItem.tx.Hash(), prioItem.priority, prioItem.feePerKB)
-
-		// Add transactions which depend on this one (and also do not
-		// have any other unsatisified dependencies) to the priority
-		// queue.
-		for _, item := range deps {
-			// Add the transaction to the priority queue if there
-			// are no more dependencies after this one.
-			delete(item.dependsOn, *tx.Hash())
-			if len(item.dependsOn) == 0 {
-				heap.Push(priorityQueue, item)
-			}
-		}
+		addTransaction(prioItem, txSize, numSigOps)
 	}
 
 	// Now that the actual transactions have been selected, update the
@@ -853,8 +1053,13 @@ mempoolLoop:
 		Size:       blockSize,
 	}
 
-	// Sign the block
-	msgBlock.Header.Sign(validateKey)
+	// Sign the block.  Templates created without a validate key are left
+	// unsigned so they can be signed externally.
+	if validateKey != nil {
+		if err := msgBlock.Header.Sign(validateKey); err != nil {
+			return nil, err
+		}
+	}
 
 	for _, tx := range blockTxns {
 		if err := msgBlock.AddTransaction(tx.MsgTx()); err != nil {
@@ -881,6 +1086,7 @@ mempoolLoop:
 		SigOpCounts:     txSigOpCounts,
 		Height:          nextBlockHeight,
 		ValidPayAddress: payToAddress != nil,
+		NumAdminTxns:    numAdminTxns,
 	}, nil
 }
 
@@ -889,7 +1095,8 @@ mempoolLoop:
 // several blocks to ensure the new time is after that time per the chain
 // consensus rules.  Finally, it will update the target difficulty if needed
 // based on the new time for the test networks since their target difficulty can
-// change based upon time.
+// change based upon time.  The block is re-signed with the passed validate key
+// unless it is nil.
 func (g *BlkTmplGenerator) UpdateBlockTime(msgBlock *wire.MsgBlock,
 	validateKey *btcec.PrivateKey) error {
 
@@ -899,8 +1106,10 @@ func (g *BlkTmplGenerator) UpdateBlockTime(msgBlock *wire.MsgBlock,
 	newTime := medianAdjustedTime(g.chain.BestSnapshot(), g.timeSource)
 	msgBlock.Header.Timestamp = newTime
 
-	// Re-sign the block, since we updated the block time
-	msgBlock.Header.Sign(validateKey)
+	// Re-sign the block, since we updated the block time.
+	if validateKey != nil {
+		return msgBlock.Header.Sign(validateKey)
+	}
 
 	return nil
 }
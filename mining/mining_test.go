@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/provautil"
 )
 
@@ -109,3 +110,52 @@ func TestTxFeePrioHeap(t *testing.T) {
 		highest = prioItem
 	}
 }
+
+// TestApplyAncestorFeeRates ensures the fee rates of transactions are raised to
+// the highest ancestor fee rates of the transactions which depend on them.
+func TestApplyAncestorFeeRates(t *testing.T) {
+	// Create a low fee parent with a high fee child and a low fee
+	// grandchild, along with an unrelated transaction.
+	hash := func(b byte) chainhash.Hash {
+		return chainhash.Hash{b}
+	}
+	dependsOn := func(hashes ...chainhash.Hash) map[chainhash.Hash]struct{} {
+		deps := make(map[chainhash.Hash]struct{})
+		for _, hash := range hashes {
+			deps[hash] = struct{}{}
+		}
+		return deps
+	}
+	parent := &txPrioItem{feePerKB: 1000, ancestorFeePerKB: 1000}
+	child := &txPrioItem{feePerKB: 9000, ancestorFeePerKB: 5000,
+		dependsOn: dependsOn(hash(1))}
+	grandchild := &txPrioItem{feePerKB: 1000, ancestorFeePerKB: 3667,
+		dependsOn: dependsOn(hash(2))}
+	unrelated := &txPrioItem{feePerKB: 2000, ancestorFeePerKB: 2000}
+	prioItems := map[chainhash.Hash]*txPrioItem{
+		hash(1): parent,
+		hash(2): child,
+		hash(3): grandchild,
+		hash(4): unrelated,
+	}
+
+	// The parent is raised to the ancestor fee rate of its child, while
+	// the child keeps its own higher fee rate.
+	applyAncestorFeeRates(prioItems)
+	tests := []struct {
+		name string
+		item *txPrioItem
+		want int64
+	}{
+		{"parent", parent, 5000},
+		{"child", child, 9000},
+		{"grandchild", grandchild, 1000},
+		{"unrelated", unrelated, 2000},
+	}
+	for _, test := range tests {
+		if test.item.feePerKB != test.want {
+			t.Errorf("%s: fee per KB %d, want %d", test.name,
+				test.item.feePerKB, test.want)
+		}
+	}
+}
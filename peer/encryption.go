@@ -0,0 +1,191 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/bitgo/prova/btcec"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// maxRecordPlaintext is the maximum number of bytes of plaintext an
+	// encrypted record carries.  Larger writes are split into multiple
+	// records.
+	maxRecordPlaintext = 1 << 16
+
+	// recordLenSize is the number of bytes of the length prefix of an
+	// encrypted record.
+	recordLenSize = 4
+
+	// sessionIDSize is the number of bytes of the id which identifies an
+	// encrypted session.
+	sessionIDSize = 32
+)
+
+// encryptionSalt is the HKDF salt the keys of an encrypted session are derived
+// with.
+var encryptionSalt = []byte("Prova transport encryption")
+
+// errInvalidRecordLen is returned when the remote peer sends an encrypted record
+// which is larger than allowed or too small to be authenticated.
+var errInvalidRecordLen = errors.New("encrypted record has an invalid length")
+
+// encryptedStream encrypts all data written to and decrypts all data read from
+// the wrapped connection with ChaCha20-Poly1305.  Data is sent in records which
+// consist of a little-endian uint32 length followed by the sealed plaintext.
+// The length is authenticated as additional data.  Each direction uses its own
+// key and a 64-bit counter as the nonce, so a nonce is never reused for a key.
+//
+// The length of the records is not hidden from observers of the connection.
+type encryptedStream struct {
+	rw io.ReadWriter
+
+	writeMtx   sync.Mutex
+	writeAEAD  cipher.AEAD
+	writeNonce uint64
+
+	readAEAD  cipher.AEAD
+	readNonce uint64
+	readBuf   []byte
+}
+
+// nonceBytes returns the nonce for the record with the passed counter.
+func nonceBytes(counter uint64) []byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	return nonce[:]
+}
+
+// Write encrypts the passed data and writes it to the wrapped connection.
+// This is part of the io.Writer interface implementation.
+func (s *encryptedStream) Write(b []byte) (int, error) {
+	s.writeMtx.Lock()
+	defer s.writeMtx.Unlock()
+
+	written := 0
+	for len(b) > 0 {
+		n := len(b)
+		if n > maxRecordPlaintext {
+			n = maxRecordPlaintext
+		}
+
+		record := make([]byte, recordLenSize, recordLenSize+n+
+			s.writeAEAD.Overhead())
+		binary.LittleEndian.PutUint32(record,
+			uint32(n+s.writeAEAD.Overhead()))
+		record = s.writeAEAD.Seal(record, nonceBytes(s.writeNonce),
+			b[:n], record[:recordLenSize])
+		s.writeNonce++
+
+		if _, err := s.rw.Write(record); err != nil {
+			return written, err
+		}
+		written += n
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// Read reads and decrypts data from the wrapped connection.  An error is
+// returned when a record fails to authenticate.  This is part of the io.Reader
+// interface implementation.
+func (s *encryptedStream) Read(b []byte) (int, error) {
+	if len(s.readBuf) == 0 {
+		if err := s.readRecord(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, s.readBuf)
+	s.readBuf = s.readBuf[n:]
+	return n, nil
+}
+
+// readRecord reads the next record from the wrapped connection and decrypts it
+// into the read buffer.
+func (s *encryptedStream) readRecord() error {
+	var lenBytes [recordLenSize]byte
+	if _, err := io.ReadFull(s.rw, lenBytes[:]); err != nil {
+		return err
+	}
+	recordLen := binary.LittleEndian.Uint32(lenBytes[:])
+	overhead := uint32(s.readAEAD.Overhead())
+	if recordLen > maxRecordPlaintext+overhead || recordLen < overhead {
+		return errInvalidRecordLen
+	}
+
+	record := make([]byte, recordLen)
+	if _, err := io.ReadFull(s.rw, record); err != nil {
+		return err
+	}
+	plaintext, err := s.readAEAD.Open(record[:0],
+		nonceBytes(s.readNonce), record, lenBytes[:])
+	if err != nil {
+		return fmt.Errorf("failed to decrypt record: %v", err)
+	}
+	s.readNonce++
+	s.readBuf = plaintext
+	return nil
+}
+
+// newEncryptedStream derives the keys of an encrypted session from the shared
+// secret of the passed ephemeral keys of the local and remote peer and returns
+// a stream which encrypts all data sent over the passed connection along with
+// the id of the session.  Both peers derive the same keys and session id since
+// the public keys are ordered by the direction of the connection.
+func newEncryptedStream(rw io.ReadWriter, localKey *btcec.PrivateKey,
+	remotePubKey *btcec.PublicKey, inbound bool) (*encryptedStream, []byte, error) {
+
+	localPubKey := localKey.PubKey().SerializeCompressed()
+	initiatorPubKey, responderPubKey := localPubKey,
+		remotePubKey.SerializeCompressed()
+	if inbound {
+		initiatorPubKey, responderPubKey = responderPubKey,
+			initiatorPubKey
+	}
+	info := bytes.Join([][]byte{initiatorPubKey, responderPubKey}, nil)
+	secret := btcec.GenerateSharedSecret(localKey, remotePubKey)
+	kdf := hkdf.New(sha256.New, secret, encryptionSalt, info)
+
+	var initiatorKey, responderKey [chacha20poly1305.KeySize]byte
+	sessionID := make([]byte, sessionIDSize)
+	for _, b := range [][]byte{initiatorKey[:], responderKey[:], sessionID} {
+		if _, err := io.ReadFull(kdf, b); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// The initiator of the connection sends with the initiator key and
+	// the responder sends with the responder key.
+	writeKey, readKey := initiatorKey[:], responderKey[:]
+	if inbound {
+		writeKey, readKey = readKey, writeKey
+	}
+	writeAEAD, err := chacha20poly1305.New(writeKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	readAEAD, err := chacha20poly1305.New(readKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stream := &encryptedStream{
+		rw:        rw,
+		writeAEAD: writeAEAD,
+		readAEAD:  readAEAD,
+	}
+	return stream, sessionID, nil
+}
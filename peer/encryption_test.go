@@ -0,0 +1,80 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package peer
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/bitgo/prova/btcec"
+)
+
+// TestEncryptedStream ensures data written to an encrypted stream is read back
+// by the remote end of the session, including data spanning multiple records,
+// and that tampered records are rejected.
+func TestEncryptedStream(t *testing.T) {
+	inKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x01}, 32))
+	outKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x02}, 32))
+
+	var buf bytes.Buffer
+	outStream, outID, err := newEncryptedStream(&buf, outKey,
+		inKey.PubKey(), false)
+	if err != nil {
+		t.Fatalf("newEncryptedStream: unexpected error %v", err)
+	}
+	inStream, inID, err := newEncryptedStream(&buf, inKey,
+		outKey.PubKey(), true)
+	if err != nil {
+		t.Fatalf("newEncryptedStream: unexpected error %v", err)
+	}
+	if !bytes.Equal(inID, outID) {
+		t.Fatalf("session ids differ - inbound %x, outbound %x", inID,
+			outID)
+	}
+
+	tests := [][]byte{
+		[]byte("version"),
+		bytes.Repeat([]byte{0xab}, maxRecordPlaintext*2+1),
+	}
+	for i, data := range tests {
+		if _, err := outStream.Write(data); err != nil {
+			t.Errorf("Write #%d: unexpected error %v", i, err)
+			continue
+		}
+		if bytes.Contains(buf.Bytes(), data) {
+			t.Errorf("Write #%d: plaintext was written", i)
+		}
+		got := make([]byte, len(data))
+		if _, err := io.ReadFull(inStream, got); err != nil {
+			t.Errorf("Read #%d: unexpected error %v", i, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Read #%d: wrong data read", i)
+		}
+	}
+
+	// Data sent by the inbound end must not be readable with the key of
+	// the outbound direction.
+	if _, err := inStream.Write([]byte("verack")); err != nil {
+		t.Fatalf("Write: unexpected error %v", err)
+	}
+	if _, err := io.ReadFull(inStream, make([]byte, 1)); err == nil {
+		t.Errorf("Read: read own data with the remote key")
+	}
+
+	// A tampered record must fail to authenticate.
+	buf.Reset()
+	if _, err := outStream.Write([]byte("tx")); err != nil {
+		t.Fatalf("Write: unexpected error %v", err)
+	}
+	buf.Bytes()[recordLenSize] ^= 0x01
+	if _, err := inStream.Read(make([]byte, 2)); err == nil {
+		t.Errorf("Read: tampered record was accepted")
+	}
+}
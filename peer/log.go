@@ -192,6 +192,52 @@ func messageSummary(msg wire.Message) string {
 	case *wire.MsgAlert:
 		// No summary.
 
+	case *wire.MsgNotice:
+		return fmt.Sprintf("hash %v, key set %d, %d signatures, "+
+			"expires %v", msg.NoticeHash(), msg.KeySet,
+			len(msg.Signatures), msg.Expiration)
+
+	case *wire.MsgGetUTXOs:
+		return fmt.Sprintf("%d outpoints, check mempool %v",
+			len(msg.OutPoints), msg.CheckMempool)
+
+	case *wire.MsgUTXOs:
+		return fmt.Sprintf("block %v (height %d), %d unspent",
+			msg.BlockHash, msg.Height, len(msg.UTXOs))
+
+	case *wire.MsgGetAdminState:
+		return fmt.Sprintf("%d key ids", len(msg.KeyIDs))
+
+	case *wire.MsgAdminState:
+		return fmt.Sprintf("block %v (height %d), %d key sets, %d ASP "+
+			"keys", msg.BlockHash, msg.Height, len(msg.KeySets),
+			len(msg.ASPKeys))
+
+	case *wire.MsgGetCFilters:
+		return fmt.Sprintf("type %d, start height %d, stop hash %v",
+			msg.FilterType, msg.StartHeight, msg.StopHash)
+
+	case *wire.MsgCFilter:
+		return fmt.Sprintf("type %d, block %v, %d bytes",
+			msg.FilterType, msg.BlockHash, len(msg.Data))
+
+	case *wire.MsgGetCFHeaders:
+		return fmt.Sprintf("type %d, start height %d, stop hash %v",
+			msg.FilterType, msg.StartHeight, msg.StopHash)
+
+	case *wire.MsgCFHeaders:
+		return fmt.Sprintf("type %d, stop hash %v, %d filter hashes",
+			msg.FilterType, msg.StopHash, len(msg.FilterHashes))
+
+	case *wire.MsgEncInit:
+		return fmt.Sprintf("pubkey %x", msg.PubKey)
+
+	case *wire.MsgAuthProof:
+		if len(msg.PubKey) == 0 {
+			return "no identity"
+		}
+		return fmt.Sprintf("pubkey %x", msg.PubKey)
+
 	case *wire.MsgMemPool:
 		// No summary.
 
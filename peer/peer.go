@@ -8,6 +8,7 @@ package peer
 import (
 	"bytes"
 	"container/list"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +20,7 @@ import (
 	"time"
 
 	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/wire"
@@ -28,7 +30,7 @@ import (
 
 const (
 	// MaxProtocolVersion is the max protocol version the peer supports.
-	MaxProtocolVersion = wire.FeeFilterVersion
+	MaxProtocolVersion = wire.CFilterVersion
 
 	// outputBufferSize is the number of elements the output channels use.
 	outputBufferSize = 50
@@ -172,6 +174,55 @@ type MessageListeners struct {
 	// message.
 	OnSendHeaders func(p *Peer, msg *wire.MsgSendHeaders)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct bitcoin
+	// message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock)
+
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin
+	// message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn)
+
+	// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin
+	// message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn)
+
+	// OnNotice is invoked when a peer receives a notice bitcoin message.
+	OnNotice func(p *Peer, msg *wire.MsgNotice)
+
+	// OnGetUTXOs is invoked when a peer receives a getutxos bitcoin
+	// message.
+	OnGetUTXOs func(p *Peer, msg *wire.MsgGetUTXOs)
+
+	// OnUTXOs is invoked when a peer receives a utxos bitcoin message.
+	OnUTXOs func(p *Peer, msg *wire.MsgUTXOs)
+
+	// OnGetAdminState is invoked when a peer receives a getadmstate
+	// bitcoin message.
+	OnGetAdminState func(p *Peer, msg *wire.MsgGetAdminState)
+
+	// OnAdminState is invoked when a peer receives an admstate bitcoin
+	// message.
+	OnAdminState func(p *Peer, msg *wire.MsgAdminState)
+
+	// OnGetCFilters is invoked when a peer receives a getcfilters bitcoin
+	// message.
+	OnGetCFilters func(p *Peer, msg *wire.MsgGetCFilters)
+
+	// OnCFilter is invoked when a peer receives a cfilter bitcoin message.
+	OnCFilter func(p *Peer, msg *wire.MsgCFilter)
+
+	// OnGetCFHeaders is invoked when a peer receives a getcfheaders
+	// bitcoin message.
+	OnGetCFHeaders func(p *Peer, msg *wire.MsgGetCFHeaders)
+
+	// OnCFHeaders is invoked when a peer receives a cfheaders bitcoin
+	// message.
+	OnCFHeaders func(p *Peer, msg *wire.MsgCFHeaders)
+
 	// OnRead is invoked when a peer receives a bitcoin message.  It
 	// consists of the number of bytes read, the message, and whether or not
 	// an error in the read occurred.  Typically, callers will opt to use
@@ -234,6 +285,34 @@ type Config struct {
 	// not send inv messages for transactions.
 	DisableRelayTx bool
 
+	// AuthKey specifies the private key the local peer proves control of
+	// to remote peers during the handshake.  This field can be omitted in
+	// which case the local peer does not prove any identity.
+	AuthKey *btcec.PrivateKey
+
+	// AuthorizeKey specifies a callback which is invoked with the public
+	// key a remote peer proved control of during the handshake.  It
+	// returns a description of the identity the key belongs to, or an
+	// error when the key is not authorized.  This field can be omitted in
+	// which case every key is authorized and identified by its hex
+	// encoding.
+	AuthorizeKey func(pubKey *btcec.PublicKey) (string, error)
+
+	// RequireAuth specifies that remote peers must prove control of an
+	// authorized key during the handshake, otherwise the connection is
+	// rejected.  The proof is only bound to the connection when it is
+	// encrypted, so this also requires encryption.
+	RequireAuth bool
+
+	// EncryptTransport specifies that the connection should be encrypted
+	// when the remote peer supports it.  Connections to peers which don't
+	// support encryption fall back to plaintext.
+	EncryptTransport bool
+
+	// RequireEncryption specifies that the connection must be encrypted,
+	// otherwise the connection is rejected.  It is implied by RequireAuth.
+	RequireEncryption bool
+
 	// Listeners houses callback functions to be invoked on receiving peer
 	// messages.
 	Listeners MessageListeners
@@ -340,6 +419,8 @@ type StatsSnap struct {
 	LastPingNonce  uint64
 	LastPingTime   time.Time
 	LastPingMicros int64
+	AuthIdentity   string
+	Encrypted      bool
 }
 
 // HashFunc is a function which returns a block hash, height and error
@@ -389,7 +470,8 @@ type Peer struct {
 	connected     int32
 	disconnect    int32
 
-	conn net.Conn
+	conn      net.Conn
+	transport io.ReadWriter // conn or its encrypted stream
 
 	// These fields are set at creation time and never modified, so they are
 	// safe to read from concurrently without a mutex.
@@ -406,8 +488,14 @@ type Peer struct {
 	advertisedProtoVer   uint32 // protocol version advertised by remote
 	protocolVersion      uint32 // negotiated protocol version
 	sendHeadersPreferred bool   // peer sent a sendheaders message
+	cmpctBlocksSupported bool   // peer sent a supported sendcmpct message
+	cmpctBlocksPreferred bool   // peer wants cmpctblock announcements
 	versionSent          bool
 	verAckReceived       bool
+	authPubKey           *btcec.PublicKey // key the peer authenticated with
+	authIdentity         string           // identity of authPubKey
+	sessionID            []byte           // id of the encrypted session
+	transcript           []byte           // version messages exchanged
 
 	knownInventory     *mruInventoryMap
 	prevGetBlocksMtx   sync.Mutex
@@ -491,6 +579,8 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 	userAgent := p.userAgent
 	services := p.services
 	protocolVersion := p.advertisedProtoVer
+	authIdentity := p.authIdentity
+	encrypted := p.sessionID != nil
 	p.flagsMtx.Unlock()
 
 	// Get a copy of all relevant flags and stats.
@@ -512,6 +602,8 @@ func (p *Peer) StatsSnapshot() *StatsSnap {
 		LastPingNonce:  p.lastPingNonce,
 		LastPingMicros: p.lastPingMicros,
 		LastPingTime:   p.lastPingTime,
+		AuthIdentity:   authIdentity,
+		Encrypted:      encrypted,
 	}
 
 	p.statsMtx.RUnlock()
@@ -578,6 +670,42 @@ func (p *Peer) UserAgent() string {
 	return userAgent
 }
 
+// AuthPubKey returns the authorized public key the remote peer proved control
+// of during the handshake.  It is nil when the peer did not authenticate.
+//
+// This function is safe for concurrent access.
+func (p *Peer) AuthPubKey() *btcec.PublicKey {
+	p.flagsMtx.Lock()
+	authPubKey := p.authPubKey
+	p.flagsMtx.Unlock()
+
+	return authPubKey
+}
+
+// AuthIdentity returns the identity of the authorized key the remote peer
+// proved control of during the handshake.  It is empty when the peer did not
+// authenticate.
+//
+// This function is safe for concurrent access.
+func (p *Peer) AuthIdentity() string {
+	p.flagsMtx.Lock()
+	authIdentity := p.authIdentity
+	p.flagsMtx.Unlock()
+
+	return authIdentity
+}
+
+// Encrypted returns whether the connection to the remote peer is encrypted.
+//
+// This function is safe for concurrent access.
+func (p *Peer) Encrypted() bool {
+	p.flagsMtx.Lock()
+	encrypted := p.sessionID != nil
+	p.flagsMtx.Unlock()
+
+	return encrypted
+}
+
 // LastAnnouncedBlock returns the last announced block of the remote peer.
 //
 // This function is safe for concurrent access.
@@ -755,6 +883,31 @@ func (p *Peer) WantsHeaders() bool {
 	return sendHeadersPreferred
 }
 
+// SupportsCmpctBlocks returns whether the peer signalled support for the
+// compact block version this package supports via a sendcmpct message.
+//
+// This function is safe for concurrent access.
+func (p *Peer) SupportsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	cmpctBlocksSupported := p.cmpctBlocksSupported
+	p.flagsMtx.Unlock()
+
+	return cmpctBlocksSupported
+}
+
+// WantsCmpctBlocks returns if the peer wants new blocks to be announced by
+// sending cmpctblock messages directly instead of inventory vectors or
+// headers.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	cmpctBlocksPreferred := p.cmpctBlocksPreferred
+	p.flagsMtx.Unlock()
+
+	return cmpctBlocksPreferred
+}
+
 // localVersionMsg creates a version message that can be used to send to the
 // remote peer.
 func (p *Peer) localVersionMsg() (*wire.MsgVersion, error) {
@@ -824,8 +977,18 @@ func (p *Peer) localVersionMsg() (*wire.MsgVersion, error) {
 	//      by the remote peer in its version message
 	msg.AddrYou.Services = wire.SFNodeNetwork
 
-	// Advertise the services flag
+	// Advertise the services flag.  The remote peer is asked to
+	// authenticate when the local peer wants to prove its own identity or
+	// requires the remote peer to prove one.
 	msg.Services = p.cfg.Services
+	if p.cfg.AuthKey != nil || p.cfg.RequireAuth {
+		msg.Services |= wire.SFNodeAuth
+	}
+	if p.cfg.EncryptTransport || p.cfg.RequireEncryption ||
+		p.cfg.RequireAuth {
+
+		msg.Services |= wire.SFNodeEncrypt
+	}
 
 	// Advertise our max supported protocol version.
 	msg.ProtocolVersion = int32(p.cfg.ProtocolVersion)
@@ -1081,7 +1244,7 @@ func (p *Peer) handlePongMsg(msg *wire.MsgPong) {
 
 // readMessage reads the next bitcoin message from the peer with logging.
 func (p *Peer) readMessage() (wire.Message, []byte, error) {
-	n, msg, buf, err := wire.ReadMessageN(p.conn, p.ProtocolVersion(),
+	n, msg, buf, err := wire.ReadMessageN(p.transport, p.ProtocolVersion(),
 		p.cfg.ChainParams.Net)
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
 	if p.cfg.Listeners.OnRead != nil {
@@ -1144,7 +1307,7 @@ func (p *Peer) writeMessage(msg wire.Message) error {
 	}))
 
 	// Write the message to the peer.
-	n, err := wire.WriteMessageN(p.conn, msg, p.ProtocolVersion(),
+	n, err := wire.WriteMessageN(p.transport, msg, p.ProtocolVersion(),
 		p.cfg.ChainParams.Net)
 	atomic.AddUint64(&p.bytesSent, uint64(n))
 	if p.cfg.Listeners.OnWrite != nil {
@@ -1240,6 +1403,10 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		// headers.
 		deadline = time.Now().Add(stallResponseTimeout * 3)
 		pendingResponses[wire.CmdHeaders] = deadline
+
+	case wire.CmdGetBlockTxn:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxn] = deadline
 	}
 }
 
@@ -1579,6 +1746,81 @@ out:
 				p.cfg.Listeners.OnSendHeaders(p, msg)
 			}
 
+		case *wire.MsgSendCmpct:
+			// Compact blocks are only used with peers which
+			// support the same version.  A later message may
+			// change whether blocks are announced directly.
+			if msg.CmpctBlockVersion == wire.CmpctBlockVersion {
+				p.flagsMtx.Lock()
+				p.cmpctBlocksSupported = true
+				p.cmpctBlocksPreferred = msg.AnnounceUsingCmpctBlock
+				p.flagsMtx.Unlock()
+			}
+
+			if p.cfg.Listeners.OnSendCmpct != nil {
+				p.cfg.Listeners.OnSendCmpct(p, msg)
+			}
+
+		case *wire.MsgCmpctBlock:
+			if p.cfg.Listeners.OnCmpctBlock != nil {
+				p.cfg.Listeners.OnCmpctBlock(p, msg)
+			}
+
+		case *wire.MsgGetBlockTxn:
+			if p.cfg.Listeners.OnGetBlockTxn != nil {
+				p.cfg.Listeners.OnGetBlockTxn(p, msg)
+			}
+
+		case *wire.MsgBlockTxn:
+			if p.cfg.Listeners.OnBlockTxn != nil {
+				p.cfg.Listeners.OnBlockTxn(p, msg)
+			}
+
+		case *wire.MsgNotice:
+			if p.cfg.Listeners.OnNotice != nil {
+				p.cfg.Listeners.OnNotice(p, msg)
+			}
+
+		case *wire.MsgGetUTXOs:
+			if p.cfg.Listeners.OnGetUTXOs != nil {
+				p.cfg.Listeners.OnGetUTXOs(p, msg)
+			}
+
+		case *wire.MsgUTXOs:
+			if p.cfg.Listeners.OnUTXOs != nil {
+				p.cfg.Listeners.OnUTXOs(p, msg)
+			}
+
+		case *wire.MsgGetAdminState:
+			if p.cfg.Listeners.OnGetAdminState != nil {
+				p.cfg.Listeners.OnGetAdminState(p, msg)
+			}
+
+		case *wire.MsgAdminState:
+			if p.cfg.Listeners.OnAdminState != nil {
+				p.cfg.Listeners.OnAdminState(p, msg)
+			}
+
+		case *wire.MsgGetCFilters:
+			if p.cfg.Listeners.OnGetCFilters != nil {
+				p.cfg.Listeners.OnGetCFilters(p, msg)
+			}
+
+		case *wire.MsgCFilter:
+			if p.cfg.Listeners.OnCFilter != nil {
+				p.cfg.Listeners.OnCFilter(p, msg)
+			}
+
+		case *wire.MsgGetCFHeaders:
+			if p.cfg.Listeners.OnGetCFHeaders != nil {
+				p.cfg.Listeners.OnGetCFHeaders(p, msg)
+			}
+
+		case *wire.MsgCFHeaders:
+			if p.cfg.Listeners.OnCFHeaders != nil {
+				p.cfg.Listeners.OnCFHeaders(p, msg)
+			}
+
 		default:
 			log.Debugf("Received unhandled message of type %v "+
 				"from %v", rmsg.Command(), p)
@@ -1888,6 +2130,7 @@ func (p *Peer) AssociateConnection(conn net.Conn) {
 	}
 
 	p.conn = conn
+	p.transport = conn
 	p.timeConnected = time.Now()
 
 	if p.inbound {
@@ -1983,12 +2226,13 @@ func (p *Peer) WaitForDisconnect() {
 
 // readRemoteVersionMsg waits for the next message to arrive from the remote
 // peer.  If the next message is not a version message or the version is not
-// acceptable then return an error.
-func (p *Peer) readRemoteVersionMsg() error {
+// acceptable then return an error.  The remote peer is sent a reject message
+// when the next message is not a version message.
+func (p *Peer) readRemoteVersionMsg() (*wire.MsgVersion, error) {
 	// Read their version message.
 	msg, _, err := p.readMessage()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	remoteVerMsg, ok := msg.(*wire.MsgVersion)
@@ -1998,56 +2242,343 @@ func (p *Peer) readRemoteVersionMsg() error {
 
 		rejectMsg := wire.NewMsgReject(msg.Command(), wire.RejectMalformed,
 			errStr)
-		return p.writeMessage(rejectMsg)
+		if err := p.writeMessage(rejectMsg); err != nil {
+			return nil, err
+		}
+		return nil, errors.New(errStr)
 	}
 
 	if err := p.handleRemoteVersionMsg(remoteVerMsg); err != nil {
-		return err
+		return nil, err
 	}
 
-	if p.cfg.Listeners.OnVersion != nil {
-		p.cfg.Listeners.OnVersion(p, remoteVerMsg)
-	}
-	return nil
+	return remoteVerMsg, nil
 }
 
-// writeLocalVersionMsg writes our version message to the remote peer.
-func (p *Peer) writeLocalVersionMsg() error {
+// writeLocalVersionMsg writes our version message to the remote peer and
+// returns it.
+func (p *Peer) writeLocalVersionMsg() (*wire.MsgVersion, error) {
 	localVerMsg, err := p.localVersionMsg()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if err := p.writeMessage(localVerMsg); err != nil {
-		return err
+		return nil, err
 	}
 
 	p.flagsMtx.Lock()
 	p.versionSent = true
 	p.flagsMtx.Unlock()
-	return nil
+	return localVerMsg, nil
 }
 
 // negotiateInboundProtocol waits to receive a version message from the peer
 // then sends our version message. If the events do not occur in that order then
 // it returns an error.
 func (p *Peer) negotiateInboundProtocol() error {
-	if err := p.readRemoteVersionMsg(); err != nil {
+	remoteVerMsg, err := p.readRemoteVersionMsg()
+	if err != nil {
 		return err
 	}
 
-	return p.writeLocalVersionMsg()
+	localVerMsg, err := p.writeLocalVersionMsg()
+	if err != nil {
+		return err
+	}
+
+	return p.completeNegotiation(localVerMsg, remoteVerMsg)
 }
 
 // negotiateOutboundProtocol sends our version message then waits to receive a
 // version message from the peer.  If the events do not occur in that order then
 // it returns an error.
 func (p *Peer) negotiateOutboundProtocol() error {
-	if err := p.writeLocalVersionMsg(); err != nil {
+	localVerMsg, err := p.writeLocalVersionMsg()
+	if err != nil {
+		return err
+	}
+
+	remoteVerMsg, err := p.readRemoteVersionMsg()
+	if err != nil {
+		return err
+	}
+
+	return p.completeNegotiation(localVerMsg, remoteVerMsg)
+}
+
+// completeNegotiation encrypts the connection and authenticates the remote
+// peer once the version messages have been exchanged and then invokes the
+// version listener, so the listener is never invoked for peers which fail to
+// do so.
+func (p *Peer) completeNegotiation(localVerMsg, remoteVerMsg *wire.MsgVersion) error {
+	outVerMsg, inVerMsg := localVerMsg, remoteVerMsg
+	if p.inbound {
+		outVerMsg, inVerMsg = remoteVerMsg, localVerMsg
+	}
+	p.transcript = handshakeTranscript(outVerMsg, inVerMsg)
+
+	if err := p.negotiateEncryption(); err != nil {
+		return err
+	}
+	if err := p.negotiateAuth(); err != nil {
+		return err
+	}
+
+	if p.cfg.Listeners.OnVersion != nil {
+		p.cfg.Listeners.OnVersion(p, remoteVerMsg)
+	}
+	return nil
+}
+
+// negotiateEncryption exchanges encinit messages carrying ephemeral public keys
+// with the remote peer when both peers support encryption, and encrypts all
+// further messages with the keys derived from their shared secret.  The
+// outbound peer sends its key first.
+func (p *Peer) negotiateEncryption() error {
+	p.flagsMtx.Lock()
+	remoteEncrypt := p.services&wire.SFNodeEncrypt == wire.SFNodeEncrypt
+	protocolVersion := p.protocolVersion
+	p.flagsMtx.Unlock()
+
+	// A proof of identity over a plaintext connection could be relayed
+	// by a man-in-the-middle, so authentication requires encryption.
+	requireEncrypt := p.cfg.RequireEncryption || p.cfg.RequireAuth
+	localEncrypt := p.cfg.EncryptTransport || requireEncrypt
+	if !localEncrypt || !remoteEncrypt ||
+		protocolVersion < wire.EncryptionVersion {
+
+		if requireEncrypt {
+			return p.rejectHandshake(wire.CmdVersion,
+				"encryption is required")
+		}
+		return nil
+	}
+
+	localKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		return err
+	}
+	localInit := wire.NewMsgEncInit(localKey.PubKey())
+	var remoteInit *wire.MsgEncInit
+	if p.inbound {
+		if remoteInit, err = p.readEncInit(); err != nil {
+			return err
+		}
+		if err := p.writeMessage(localInit); err != nil {
+			return err
+		}
+	} else {
+		if err := p.writeMessage(localInit); err != nil {
+			return err
+		}
+		if remoteInit, err = p.readEncInit(); err != nil {
+			return err
+		}
+	}
+
+	remotePubKey, err := btcec.ParsePubKey(remoteInit.PubKey[:],
+		btcec.S256())
+	if err != nil {
+		return fmt.Errorf("invalid encryption key: %v", err)
+	}
+	stream, sessionID, err := newEncryptedStream(p.transport, localKey,
+		remotePubKey, p.inbound)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Encrypted connection to peer %s", p)
+	p.transport = stream
+	p.flagsMtx.Lock()
+	p.sessionID = sessionID
+	p.flagsMtx.Unlock()
+	return nil
+}
+
+// handshakeTranscript returns the parts of the passed version messages of the
+// outbound and inbound peer which determine what the peers negotiate.  The
+// version messages are not authenticated, so authentication proofs sign the
+// transcript to detect a man-in-the-middle which changed them, for instance by
+// removing SFNodeEncrypt to keep the connection in plaintext.
+func handshakeTranscript(outVerMsg, inVerMsg *wire.MsgVersion) []byte {
+	transcript := make([]byte, 0, 2*20)
+	for _, msg := range []*wire.MsgVersion{outVerMsg, inVerMsg} {
+		var buf [20]byte
+		binary.LittleEndian.PutUint32(buf[0:4], uint32(msg.ProtocolVersion))
+		binary.LittleEndian.PutUint64(buf[4:12], uint64(msg.Services))
+		binary.LittleEndian.PutUint64(buf[12:20], msg.Nonce)
+		transcript = append(transcript, buf[:]...)
+	}
+	return transcript
+}
+
+// readEncInit reads the encinit message of the remote peer during the
+// handshake.
+func (p *Peer) readEncInit() (*wire.MsgEncInit, error) {
+	msg, _, err := p.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	encInit, ok := msg.(*wire.MsgEncInit)
+	if !ok {
+		return nil, fmt.Errorf("expected encinit message, got %s",
+			msg.Command())
+	}
+	return encInit, nil
+}
+
+// negotiateAuth exchanges authchal and authproof messages with the remote
+// peer when either peer asked for authentication in its version message.  Each
+// peer signs the challenge of the other one with its node key, or answers with
+// an empty proof when it has none.  The outbound peer sends its challenge and
+// its proof first, so the messages are exchanged in lock step and the inbound
+// peer never proves its identity to a peer it rejects.
+func (p *Peer) negotiateAuth() error {
+	p.flagsMtx.Lock()
+	remoteAuth := p.services&wire.SFNodeAuth == wire.SFNodeAuth
+	protocolVersion := p.protocolVersion
+	p.flagsMtx.Unlock()
+
+	if p.cfg.AuthKey == nil && !p.cfg.RequireAuth && !remoteAuth {
+		return nil
+	}
+	if protocolVersion < wire.NodeAuthVersion {
+		if p.cfg.RequireAuth {
+			return p.rejectHandshake(wire.CmdVersion, fmt.Sprintf(
+				"protocol version must be %d or greater to "+
+					"authenticate", wire.NodeAuthVersion))
+		}
+		return nil
+	}
+
+	challenge, err := wire.NewMsgAuthChallenge()
+	if err != nil {
+		return err
+	}
+	if p.inbound {
+		remoteChallenge, err := p.readAuthChallenge()
+		if err != nil {
+			return err
+		}
+		if err := p.writeMessage(challenge); err != nil {
+			return err
+		}
+		if err := p.readAuthProof(challenge); err != nil {
+			return err
+		}
+		return p.writeAuthProof(remoteChallenge)
+	}
+
+	if err := p.writeMessage(challenge); err != nil {
+		return err
+	}
+	remoteChallenge, err := p.readAuthChallenge()
+	if err != nil {
+		return err
+	}
+	if err := p.writeAuthProof(remoteChallenge); err != nil {
+		return err
+	}
+	return p.readAuthProof(challenge)
+}
+
+// readAuthChallenge reads the authchal message of the remote peer during the
+// handshake.
+func (p *Peer) readAuthChallenge() (*wire.MsgAuthChallenge, error) {
+	msg, _, err := p.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	challenge, ok := msg.(*wire.MsgAuthChallenge)
+	if !ok {
+		return nil, fmt.Errorf("expected authchal message, got %s",
+			msg.Command())
+	}
+	return challenge, nil
+}
+
+// readAuthProof reads the authproof message of the remote peer during the
+// handshake and verifies it answers the passed challenge.  The remote peer is
+// rejected when the proof is invalid, or when authentication is required and
+// the proof is empty or made with a key which is not authorized.
+func (p *Peer) readAuthProof(challenge *wire.MsgAuthChallenge) error {
+	msg, _, err := p.readMessage()
+	if err != nil {
 		return err
 	}
+	remoteProof, ok := msg.(*wire.MsgAuthProof)
+	if !ok {
+		return fmt.Errorf("expected authproof message, got %s",
+			msg.Command())
+	}
+
+	// A peer which does not prove any identity is only rejected when
+	// authentication is required.
+	if len(remoteProof.PubKey) == 0 {
+		if p.cfg.RequireAuth {
+			return p.rejectHandshake(wire.CmdAuthProof,
+				"authentication is required")
+		}
+		return nil
+	}
+
+	// A proof which does not verify is always rejected since the peer
+	// claims an identity it can't prove.
+	pubKey, err := btcec.ParsePubKey(remoteProof.PubKey, btcec.S256())
+	if err != nil {
+		return p.rejectHandshake(wire.CmdAuthProof, fmt.Sprintf("invalid "+
+			"public key: %v", err))
+	}
+	signature, err := btcec.ParseDERSignature(remoteProof.Signature,
+		btcec.S256())
+	hash := challenge.ProofHash(p.sessionID, p.transcript)
+	if err != nil || !signature.Verify(hash[:], pubKey) {
+		return p.rejectHandshake(wire.CmdAuthProof, "invalid signature")
+	}
 
-	return p.readRemoteVersionMsg()
+	identity := fmt.Sprintf("%x", remoteProof.PubKey)
+	if p.cfg.AuthorizeKey != nil {
+		identity, err = p.cfg.AuthorizeKey(pubKey)
+		if err != nil {
+			if p.cfg.RequireAuth {
+				return p.rejectHandshake(wire.CmdAuthProof,
+					err.Error())
+			}
+			log.Debugf("Peer %s proved control of unauthorized "+
+				"key %x: %v", p, remoteProof.PubKey, err)
+			return nil
+		}
+	}
+
+	log.Debugf("Authenticated peer %s as %s", p, identity)
+	p.flagsMtx.Lock()
+	p.authPubKey = pubKey
+	p.authIdentity = identity
+	p.flagsMtx.Unlock()
+	return nil
+}
+
+// writeAuthProof answers the passed challenge of the remote peer with a proof
+// signed by the node key of the local peer.
+func (p *Peer) writeAuthProof(challenge *wire.MsgAuthChallenge) error {
+	proof, err := wire.NewMsgAuthProof(p.cfg.AuthKey, challenge,
+		p.sessionID, p.transcript)
+	if err != nil {
+		return err
+	}
+	return p.writeMessage(proof)
+}
+
+// rejectHandshake sends a reject message for the passed command to the remote
+// peer which failed to complete the handshake and returns an error describing
+// the reason.
+func (p *Peer) rejectHandshake(command, reason string) error {
+	rejectMsg := wire.NewMsgReject(command, wire.RejectInvalid, reason)
+	if err := p.writeMessage(rejectMsg); err != nil {
+		log.Debugf("Failed to send reject message to %s: %v", p, err)
+	}
+	return fmt.Errorf("handshake rejected: %s", reason)
 }
 
 // newPeerBase returns a new base bitcoin peer based on the inbound flag.  This
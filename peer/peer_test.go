@@ -6,6 +6,7 @@
 package peer_test
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"net"
@@ -13,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/peer"
@@ -312,6 +314,433 @@ func TestPeerConnection(t *testing.T) {
 	}
 }
 
+// TestPeerAuthentication tests the authentication of peers during the
+// handshake.
+func TestPeerAuthentication(t *testing.T) {
+	nodeKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x01}, 32))
+	otherKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x02}, 32))
+	authorizeKey := func(pubKey *btcec.PublicKey) (string, error) {
+		if !pubKey.IsEqual(nodeKey.PubKey()) {
+			return "", errors.New("unknown key")
+		}
+		return "node", nil
+	}
+
+	tests := []struct {
+		name          string
+		inKey         *btcec.PrivateKey
+		outKey        *btcec.PrivateKey
+		requireAuth   bool
+		wantConnected bool
+		wantInIdent   string
+		wantOutIdent  string
+	}{{
+		name:          "no authentication",
+		wantConnected: true,
+	}, {
+		name:          "optional authentication",
+		outKey:        nodeKey,
+		wantConnected: true,
+		wantInIdent:   "node",
+	}, {
+		name:          "mutual authentication",
+		inKey:         nodeKey,
+		outKey:        nodeKey,
+		requireAuth:   true,
+		wantConnected: true,
+		wantInIdent:   "node",
+		wantOutIdent:  "node",
+	}, {
+		name:          "optional authentication with unknown key",
+		outKey:        otherKey,
+		wantConnected: true,
+	}, {
+		name:          "required authentication with unknown key",
+		inKey:         nodeKey,
+		outKey:        otherKey,
+		requireAuth:   true,
+		wantConnected: false,
+	}, {
+		name:          "required authentication without key",
+		inKey:         nodeKey,
+		requireAuth:   true,
+		wantConnected: false,
+	}}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		verack := make(chan struct{}, 2)
+		newCfg := func(key *btcec.PrivateKey) *peer.Config {
+			return &peer.Config{
+				Listeners: peer.MessageListeners{
+					OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+						verack <- struct{}{}
+					},
+				},
+				UserAgentName:    "peer",
+				UserAgentVersion: "1.0",
+				ChainParams:      &chaincfg.MainNetParams,
+				AuthKey:          key,
+				AuthorizeKey:     authorizeKey,
+				RequireAuth:      test.requireAuth,
+			}
+		}
+
+		inConn, outConn := pipe(
+			&conn{raddr: "10.0.0.1:8333"},
+			&conn{raddr: "10.0.0.2:8333"},
+		)
+		inPeer := peer.NewInboundPeer(newCfg(test.inKey))
+		inPeer.AssociateConnection(inConn)
+		outPeer, err := peer.NewOutboundPeer(newCfg(test.outKey),
+			"10.0.0.2:8333")
+		if err != nil {
+			t.Errorf("%s: NewOutboundPeer: unexpected err %v",
+				test.name, err)
+			continue
+		}
+		outPeer.AssociateConnection(outConn)
+
+		if test.wantConnected {
+			for i := 0; i < 2; i++ {
+				select {
+				case <-verack:
+				case <-time.After(time.Second):
+					t.Fatalf("%s: verack timeout", test.name)
+				}
+			}
+		} else {
+			disconnected := make(chan struct{})
+			go func() {
+				inPeer.WaitForDisconnect()
+				outPeer.WaitForDisconnect()
+				close(disconnected)
+			}()
+			select {
+			case <-disconnected:
+			case <-time.After(time.Second):
+				t.Fatalf("%s: disconnect timeout", test.name)
+			}
+		}
+
+		if inPeer.AuthIdentity() != test.wantInIdent {
+			t.Errorf("%s: wrong inbound identity - got %q, want %q",
+				test.name, inPeer.AuthIdentity(),
+				test.wantInIdent)
+		}
+		if outPeer.AuthIdentity() != test.wantOutIdent {
+			t.Errorf("%s: wrong outbound identity - got %q, want %q",
+				test.name, outPeer.AuthIdentity(),
+				test.wantOutIdent)
+		}
+		if identity := inPeer.StatsSnapshot().AuthIdentity; identity !=
+			test.wantInIdent {
+
+			t.Errorf("%s: wrong stats identity - got %q, want %q",
+				test.name, identity, test.wantInIdent)
+		}
+
+		inPeer.Disconnect()
+		outPeer.Disconnect()
+		inPeer.WaitForDisconnect()
+		outPeer.WaitForDisconnect()
+	}
+}
+
+// TestPeerEncryption tests that the transport between peers is encrypted when
+// both peers support it, falls back to plaintext otherwise, and that peers
+// which require encryption or authentication reject peers without support for
+// encryption.
+func TestPeerEncryption(t *testing.T) {
+	nodeKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x01}, 32))
+	authorizeKey := func(pubKey *btcec.PublicKey) (string, error) {
+		return "node", nil
+	}
+
+	tests := []struct {
+		name          string
+		inEncrypt     bool
+		outEncrypt    bool
+		outRequire    bool
+		authKey       *btcec.PrivateKey
+		inAuth        bool
+		outAuth       bool
+		wantConnected bool
+		wantEncrypted bool
+	}{{
+		name:          "no encryption",
+		wantConnected: true,
+	}, {
+		name:          "encryption",
+		inEncrypt:     true,
+		outEncrypt:    true,
+		wantConnected: true,
+		wantEncrypted: true,
+	}, {
+		name:          "fallback to plaintext",
+		inEncrypt:     true,
+		wantConnected: true,
+	}, {
+		name:          "required encryption",
+		inEncrypt:     true,
+		outRequire:    true,
+		wantConnected: true,
+		wantEncrypted: true,
+	}, {
+		name:          "required encryption without support",
+		outRequire:    true,
+		wantConnected: false,
+	}, {
+		name:          "encryption with authentication",
+		inEncrypt:     true,
+		outEncrypt:    true,
+		authKey:       nodeKey,
+		inAuth:        true,
+		outAuth:       true,
+		wantConnected: true,
+		wantEncrypted: true,
+	}, {
+		name:          "required authentication implies encryption",
+		inEncrypt:     true,
+		authKey:       nodeKey,
+		outAuth:       true,
+		wantConnected: true,
+		wantEncrypted: true,
+	}, {
+		name:          "required authentication without encryption",
+		authKey:       nodeKey,
+		outAuth:       true,
+		wantConnected: false,
+	}}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		verack := make(chan struct{}, 2)
+		newCfg := func(encrypt, require, auth bool) *peer.Config {
+			return &peer.Config{
+				Listeners: peer.MessageListeners{
+					OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+						verack <- struct{}{}
+					},
+				},
+				UserAgentName:     "peer",
+				UserAgentVersion:  "1.0",
+				ChainParams:       &chaincfg.MainNetParams,
+				AuthKey:           test.authKey,
+				AuthorizeKey:      authorizeKey,
+				RequireAuth:       auth,
+				EncryptTransport:  encrypt,
+				RequireEncryption: require,
+			}
+		}
+
+		inConn, outConn := pipe(
+			&conn{raddr: "10.0.0.1:8333"},
+			&conn{raddr: "10.0.0.2:8333"},
+		)
+		inPeer := peer.NewInboundPeer(newCfg(test.inEncrypt, false,
+			test.inAuth))
+		inPeer.AssociateConnection(inConn)
+		outPeer, err := peer.NewOutboundPeer(newCfg(test.outEncrypt,
+			test.outRequire, test.outAuth), "10.0.0.2:8333")
+		if err != nil {
+			t.Errorf("%s: NewOutboundPeer: unexpected err %v",
+				test.name, err)
+			continue
+		}
+		outPeer.AssociateConnection(outConn)
+
+		if test.wantConnected {
+			for i := 0; i < 2; i++ {
+				select {
+				case <-verack:
+				case <-time.After(time.Second):
+					t.Fatalf("%s: verack timeout", test.name)
+				}
+			}
+		} else {
+			// Only the outbound peer requires encryption, and the
+			// mock connection isn't closed for the inbound peer
+			// when it disconnects.
+			disconnected := make(chan struct{})
+			go func() {
+				outPeer.WaitForDisconnect()
+				close(disconnected)
+			}()
+			select {
+			case <-disconnected:
+			case <-time.After(time.Second):
+				t.Fatalf("%s: disconnect timeout", test.name)
+			}
+		}
+
+		if inPeer.Encrypted() != test.wantEncrypted ||
+			outPeer.Encrypted() != test.wantEncrypted {
+
+			t.Errorf("%s: wrong encryption - got inbound %v, "+
+				"outbound %v, want %v", test.name,
+				inPeer.Encrypted(), outPeer.Encrypted(),
+				test.wantEncrypted)
+		}
+		if encrypted := outPeer.StatsSnapshot().Encrypted; encrypted !=
+			test.wantEncrypted {
+
+			t.Errorf("%s: wrong stats encryption - got %v, want %v",
+				test.name, encrypted, test.wantEncrypted)
+		}
+		if test.authKey != nil && test.wantConnected &&
+			inPeer.AuthIdentity() != "node" {
+
+			t.Errorf("%s: wrong inbound identity - got %q, want %q",
+				test.name, inPeer.AuthIdentity(), "node")
+		}
+
+		inPeer.Disconnect()
+		outPeer.Disconnect()
+		inPeer.WaitForDisconnect()
+		outPeer.WaitForDisconnect()
+	}
+}
+
+// TestPeerAuthTranscript tests that a man-in-the-middle which removes the
+// encryption service flag from the version messages to keep the connection in
+// plaintext is detected by the authentication proofs.
+func TestPeerAuthTranscript(t *testing.T) {
+	nodeKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x01}, 32))
+	newCfg := func(key *btcec.PrivateKey) *peer.Config {
+		return &peer.Config{
+			UserAgentName:    "peer",
+			UserAgentVersion: "1.0",
+			ChainParams:      &chaincfg.MainNetParams,
+			AuthKey:          key,
+			EncryptTransport: true,
+		}
+	}
+
+	// relay copies everything written to w to r after removing the
+	// encryption service flag from the first message, which is the
+	// version message.
+	relay := func(r io.Reader, w io.Writer) {
+		msg, _, err := wire.ReadMessage(r, peer.MaxProtocolVersion,
+			wire.MainNet)
+		if err != nil {
+			return
+		}
+		if verMsg, ok := msg.(*wire.MsgVersion); ok {
+			verMsg.Services &^= wire.SFNodeEncrypt
+		}
+		err = wire.WriteMessage(w, msg, peer.MaxProtocolVersion,
+			wire.MainNet)
+		if err != nil {
+			return
+		}
+		io.Copy(w, r)
+	}
+	outToRelay, outWriter := io.Pipe()
+	inReader, relayToIn := io.Pipe()
+	inToRelay, inWriter := io.Pipe()
+	outReader, relayToOut := io.Pipe()
+	go relay(outToRelay, relayToIn)
+	go relay(inToRelay, relayToOut)
+
+	inConn := &conn{raddr: "10.0.0.1:8333", Reader: inReader,
+		Writer: inWriter}
+	outConn := &conn{raddr: "10.0.0.2:8333", Reader: outReader,
+		Writer: outWriter}
+	inPeer := peer.NewInboundPeer(newCfg(nil))
+	inPeer.AssociateConnection(inConn)
+	outPeer, err := peer.NewOutboundPeer(newCfg(nodeKey), "10.0.0.2:8333")
+	if err != nil {
+		t.Fatalf("NewOutboundPeer: unexpected err %v", err)
+	}
+	outPeer.AssociateConnection(outConn)
+
+	disconnected := make(chan struct{})
+	go func() {
+		inPeer.WaitForDisconnect()
+		close(disconnected)
+	}()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("disconnect timeout")
+	}
+	if identity := inPeer.AuthIdentity(); identity != "" {
+		t.Errorf("wrong inbound identity - got %q, want %q", identity,
+			"")
+	}
+
+	outPeer.Disconnect()
+	outPeer.WaitForDisconnect()
+}
+
+// TestPeerNoVersion tests that a remote peer whose first message is not a
+// version message is rejected and disconnected before any further messages are
+// processed.
+func TestPeerNoVersion(t *testing.T) {
+	for _, requireAuth := range []bool{false, true} {
+		versions := make(chan struct{}, 1)
+		cfg := &peer.Config{
+			Listeners: peer.MessageListeners{
+				OnVersion: func(p *peer.Peer, msg *wire.MsgVersion) {
+					versions <- struct{}{}
+				},
+			},
+			UserAgentName:    "peer",
+			UserAgentVersion: "1.0",
+			ChainParams:      &chaincfg.MainNetParams,
+			RequireAuth:      requireAuth,
+		}
+
+		inConn, remoteConn := pipe(
+			&conn{raddr: "10.0.0.1:8333"},
+			&conn{raddr: "10.0.0.2:8333"},
+		)
+		inPeer := peer.NewInboundPeer(cfg)
+		inPeer.AssociateConnection(inConn)
+
+		go wire.WriteMessage(remoteConn, wire.NewMsgPing(1),
+			peer.MaxProtocolVersion, wire.MainNet)
+		msg, _, err := wire.ReadMessage(remoteConn,
+			peer.MaxProtocolVersion, wire.MainNet)
+		if err != nil {
+			t.Fatalf("requireAuth %v: ReadMessage: unexpected err %v",
+				requireAuth, err)
+		}
+		rejectMsg, ok := msg.(*wire.MsgReject)
+		if !ok || rejectMsg.Cmd != wire.CmdPing {
+			t.Errorf("requireAuth %v: unexpected message - got %v, "+
+				"want reject of %s", requireAuth, msg,
+				wire.CmdPing)
+		}
+
+		disconnected := make(chan struct{})
+		go func() {
+			inPeer.WaitForDisconnect()
+			close(disconnected)
+		}()
+		select {
+		case <-disconnected:
+		case <-time.After(time.Second):
+			t.Fatalf("requireAuth %v: disconnect timeout", requireAuth)
+		}
+		select {
+		case <-versions:
+			t.Errorf("requireAuth %v: version listener invoked",
+				requireAuth)
+		default:
+		}
+		if inPeer.Connected() {
+			t.Errorf("requireAuth %v: peer still connected",
+				requireAuth)
+		}
+	}
+}
+
 // TestPeerListeners tests that the peer listeners are called as expected.
 func TestPeerListeners(t *testing.T) {
 	verack := make(chan struct{}, 1)
@@ -387,6 +816,45 @@ func TestPeerListeners(t *testing.T) {
 			OnSendHeaders: func(p *peer.Peer, msg *wire.MsgSendHeaders) {
 				ok <- msg
 			},
+			OnSendCmpct: func(p *peer.Peer, msg *wire.MsgSendCmpct) {
+				ok <- msg
+			},
+			OnCmpctBlock: func(p *peer.Peer, msg *wire.MsgCmpctBlock) {
+				ok <- msg
+			},
+			OnGetBlockTxn: func(p *peer.Peer, msg *wire.MsgGetBlockTxn) {
+				ok <- msg
+			},
+			OnBlockTxn: func(p *peer.Peer, msg *wire.MsgBlockTxn) {
+				ok <- msg
+			},
+			OnNotice: func(p *peer.Peer, msg *wire.MsgNotice) {
+				ok <- msg
+			},
+			OnGetUTXOs: func(p *peer.Peer, msg *wire.MsgGetUTXOs) {
+				ok <- msg
+			},
+			OnUTXOs: func(p *peer.Peer, msg *wire.MsgUTXOs) {
+				ok <- msg
+			},
+			OnGetAdminState: func(p *peer.Peer, msg *wire.MsgGetAdminState) {
+				ok <- msg
+			},
+			OnAdminState: func(p *peer.Peer, msg *wire.MsgAdminState) {
+				ok <- msg
+			},
+			OnGetCFilters: func(p *peer.Peer, msg *wire.MsgGetCFilters) {
+				ok <- msg
+			},
+			OnCFilter: func(p *peer.Peer, msg *wire.MsgCFilter) {
+				ok <- msg
+			},
+			OnGetCFHeaders: func(p *peer.Peer, msg *wire.MsgGetCFHeaders) {
+				ok <- msg
+			},
+			OnCFHeaders: func(p *peer.Peer, msg *wire.MsgCFHeaders) {
+				ok <- msg
+			},
 		},
 		UserAgentName:    "peer",
 		UserAgentVersion: "1.0",
@@ -511,6 +979,62 @@ func TestPeerListeners(t *testing.T) {
 			"OnSendHeaders",
 			wire.NewMsgSendHeaders(),
 		},
+		{
+			"OnSendCmpct",
+			wire.NewMsgSendCmpct(true, wire.CmpctBlockVersion),
+		},
+		{
+			"OnCmpctBlock",
+			wire.NewMsgCmpctBlock(wire.NewMsgBlock(wire.NewBlockHeader(&chainhash.Hash{}, &chainhash.Hash{}, 1, 1)), 1),
+		},
+		{
+			"OnGetBlockTxn",
+			wire.NewMsgGetBlockTxn(&chainhash.Hash{}, []uint32{1}),
+		},
+		{
+			"OnBlockTxn",
+			wire.NewMsgBlockTxn(&chainhash.Hash{}, nil),
+		},
+		{
+			"OnNotice",
+			wire.NewMsgNotice(btcec.RootKeySet, time.Unix(0, 0),
+				time.Unix(3600, 0), 1, "notice"),
+		},
+		{
+			"OnGetUTXOs",
+			wire.NewMsgGetUTXOs(false),
+		},
+		{
+			"OnUTXOs",
+			wire.NewMsgUTXOs(1, &chainhash.Hash{}, 0),
+		},
+		{
+			"OnGetAdminState",
+			wire.NewMsgGetAdminState(),
+		},
+		{
+			"OnAdminState",
+			wire.NewMsgAdminState(1, &chainhash.Hash{}, 0),
+		},
+		{
+			"OnGetCFilters",
+			wire.NewMsgGetCFilters(wire.GCSFilterRegular, 0,
+				&chainhash.Hash{}),
+		},
+		{
+			"OnCFilter",
+			wire.NewMsgCFilter(wire.GCSFilterRegular,
+				&chainhash.Hash{}, []byte{0x00}),
+		},
+		{
+			"OnGetCFHeaders",
+			wire.NewMsgGetCFHeaders(wire.GCSFilterRegular, 0,
+				&chainhash.Hash{}),
+		},
+		{
+			"OnCFHeaders",
+			wire.NewMsgCFHeaders(),
+		},
 	}
 	t.Logf("Running %d tests", len(tests))
 	for _, test := range tests {
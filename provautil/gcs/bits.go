@@ -0,0 +1,70 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"io"
+)
+
+// bitWriter appends bits to a byte slice, starting with the most significant
+// bit of each byte.
+type bitWriter struct {
+	bytes []byte
+	used  uint8 // number of bits used in the last byte
+}
+
+// writeBit appends the passed bit.
+func (w *bitWriter) writeBit(bit bool) {
+	if w.used == 0 || w.used == 8 {
+		w.bytes = append(w.bytes, 0)
+		w.used = 0
+	}
+	if bit {
+		w.bytes[len(w.bytes)-1] |= 0x80 >> w.used
+	}
+	w.used++
+}
+
+// writeBits appends the n least significant bits of the passed value, starting
+// with the most significant of them.
+func (w *bitWriter) writeBits(v uint64, n uint8) {
+	for i := n; i > 0; i-- {
+		w.writeBit(v&(1<<(i-1)) != 0)
+	}
+}
+
+// bitReader reads bits from a byte slice, starting with the most significant
+// bit of each byte.
+type bitReader struct {
+	bytes []byte
+	pos   uint64 // position of the next bit
+}
+
+// readBit reads the next bit.  io.EOF is returned when all bits have been
+// read.
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= uint64(len(r.bytes))*8 {
+		return false, io.EOF
+	}
+	bit := r.bytes[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+// readBits reads the next n bits as the least significant bits of a value.
+func (r *bitReader) readBits(n uint8) (uint64, error) {
+	var v uint64
+	for i := uint8(0); i < n; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		v <<= 1
+		if bit {
+			v |= 1
+		}
+	}
+	return v, nil
+}
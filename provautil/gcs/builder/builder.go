@@ -0,0 +1,169 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package builder
+
+import (
+	"encoding/binary"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/provautil/gcs"
+	"github.com/bitgo/prova/txscript"
+	"github.com/bitgo/prova/wire"
+)
+
+const (
+	// DefaultP is the default collision probability (2^-19).
+	DefaultP = 19
+
+	// DefaultM is the default value used for the hash range.
+	DefaultM uint64 = 784931
+)
+
+// DeriveKey derives the key a block's filter is built with from the hash of
+// the block.  The key is the first KeySize bytes of the hash.
+func DeriveKey(blockHash *chainhash.Hash) [gcs.KeySize]byte {
+	var key [gcs.KeySize]byte
+	copy(key[:], blockHash[:gcs.KeySize])
+	return key
+}
+
+// KeyIDElement returns the filter element which represents the passed keyID.
+// It is the keyID in the 4-byte little-endian format used by addresses.
+func KeyIDElement(keyID btcec.KeyID) []byte {
+	element := make([]byte, btcec.KeyIDSize)
+	keyID.ToAddressFormat(element)
+	return element
+}
+
+// OutPointElement returns the filter element which represents the spending of
+// the passed outpoint.  It is the hash of the transaction followed by the
+// little-endian output index.
+func OutPointElement(outPoint *wire.OutPoint) []byte {
+	element := make([]byte, chainhash.HashSize+4)
+	copy(element, outPoint.Hash[:])
+	binary.LittleEndian.PutUint32(element[chainhash.HashSize:],
+		outPoint.Index)
+	return element
+}
+
+// elementSet collects the unique elements of a filter.
+type elementSet struct {
+	seen     map[string]struct{}
+	elements [][]byte
+}
+
+// add adds the passed element unless it is already part of the set.
+func (s *elementSet) add(element []byte) {
+	if _, ok := s.seen[string(element)]; ok {
+		return
+	}
+	s.seen[string(element)] = struct{}{}
+	s.elements = append(s.elements, element)
+}
+
+// addAdminOps adds the public keys and ASP keyIDs of the admin operations in
+// the passed transaction, if it is an admin transaction.
+func (s *elementSet) addAdminOps(msgTx *wire.MsgTx) {
+	threadInt, adminOutputs := txscript.GetAdminDetailsMsgTx(msgTx)
+	if threadInt < 0 {
+		return
+	}
+	threadID := provautil.ThreadID(threadInt)
+	for _, pops := range adminOutputs {
+		if !txscript.IsValidAdminOp(pops, threadID) {
+			continue
+		}
+		_, _, pubKey, keyID := txscript.ExtractAdminOpData(pops)
+		if pubKey != nil {
+			s.add(pubKey.SerializeCompressed())
+		}
+		if keyID != 0 {
+			s.add(KeyIDElement(keyID))
+		}
+	}
+}
+
+// BuildBasicFilter builds the basic filter of the passed block.  The filter
+// contains:
+//
+//   - the pubkey hashes and keyIDs of every Prova output
+//   - the scripts of the admin thread outputs
+//   - the public keys and ASP keyIDs of the admin operations
+//   - the outpoints spent by the block, except for the coinbase
+//
+// This allows wallets and ASPs to find the blocks which pay to or spend from
+// their keys, and to follow the admin threads, without revealing their keys to
+// the peers serving the filters.
+func BuildBasicFilter(block *wire.MsgBlock) (*gcs.Filter, error) {
+	blockHash := block.BlockHash()
+	set := elementSet{seen: make(map[string]struct{})}
+
+	for i, tx := range block.Transactions {
+		// The first transaction of a block is the coinbase, which
+		// spends nothing.
+		if i != 0 {
+			for _, txIn := range tx.TxIn {
+				set.add(OutPointElement(&txIn.PreviousOutPoint))
+			}
+		}
+
+		for _, txOut := range tx.TxOut {
+			switch txscript.GetScriptClass(txOut.PkScript) {
+			case txscript.ProvaTy, txscript.GeneralProvaTy:
+				keyHashes, keyIDs, err := txscript.ExtractProvaKeys(
+					txOut.PkScript)
+				if err != nil {
+					continue
+				}
+				for _, keyHash := range keyHashes {
+					set.add(keyHash)
+				}
+				for _, keyID := range keyIDs {
+					set.add(KeyIDElement(keyID))
+				}
+
+			case txscript.ProvaAdminTy:
+				set.add(txOut.PkScript)
+			}
+		}
+
+		set.addAdminOps(tx)
+	}
+
+	return gcs.BuildGCSFilter(DefaultP, DefaultM, DeriveKey(&blockHash),
+		set.elements)
+}
+
+// GetFilterHash returns the double-SHA256 of the serialized filter.
+func GetFilterHash(filter *gcs.Filter) (chainhash.Hash, error) {
+	filterData, err := filter.NBytes()
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return chainhash.DoubleHashH(filterData), nil
+}
+
+// MakeHeaderForFilter makes a filter chain header for a filter, given the
+// filter and the previous filter chain header.  The header commits to the
+// filter and, through the previous header, to the filters of all previous
+// blocks.
+func MakeHeaderForFilter(filter *gcs.Filter, prevHeader chainhash.Hash) (chainhash.Hash, error) {
+	filterHash, err := GetFilterHash(filter)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return MakeHeaderForFilterHash(filterHash, prevHeader), nil
+}
+
+// MakeHeaderForFilterHash makes a filter chain header from the hash of a filter
+// and the previous filter chain header.
+func MakeHeaderForFilterHash(filterHash, prevHeader chainhash.Hash) chainhash.Hash {
+	var headerData [2 * chainhash.HashSize]byte
+	copy(headerData[:], filterHash[:])
+	copy(headerData[chainhash.HashSize:], prevHeader[:])
+	return chainhash.DoubleHashH(headerData[:])
+}
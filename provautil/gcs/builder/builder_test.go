@@ -0,0 +1,141 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package builder_test
+
+import (
+	"testing"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/provautil/gcs/builder"
+	"github.com/bitgo/prova/txscript"
+	"github.com/bitgo/prova/wire"
+)
+
+// TestBuildBasicFilter ensures the basic filter of a block contains the keys,
+// admin thread scripts, admin operations, and spent outpoints of the block.
+func TestBuildBasicFilter(t *testing.T) {
+	genesis := chaincfg.RegressionNetParams.GenesisBlock
+	genesisCoinbase := genesis.Transactions[0]
+	genesisCoinbaseHash := genesisCoinbase.TxHash()
+
+	// An admin transaction which spends the provision thread and adds an
+	// ASP key.
+	_, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{
+		0x2b, 0x8c, 0x52, 0xb7, 0x7b, 0x32, 0x7c, 0x75,
+		0x5b, 0x9b, 0x37, 0x55, 0x00, 0xd3, 0xf4, 0xb2,
+		0xda, 0x9b, 0x0a, 0x1f, 0xf6, 0x5f, 0x68, 0x91,
+		0xd3, 0x11, 0xfe, 0x94, 0x29, 0x5b, 0xc2, 0x6a,
+	})
+	aspKeyID := btcec.KeyID(0x10001)
+	data := make([]byte, 1+btcec.PubKeyBytesLenCompressed+btcec.KeyIDSize)
+	data[0] = txscript.AdminOpASPKeyAdd
+	copy(data[1:], pubKey.SerializeCompressed())
+	aspKeyID.ToAddressFormat(data[1+btcec.PubKeyBytesLenCompressed:])
+	adminOpScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).AddData(data).Script()
+	if err != nil {
+		t.Fatalf("unable to build admin op script: %v", err)
+	}
+	threadScript, err := txscript.ProvaThreadScript(provautil.ProvisionThread)
+	if err != nil {
+		t.Fatalf("unable to build thread script: %v", err)
+	}
+	threadOutPoint := wire.OutPoint{Hash: genesisCoinbaseHash, Index: 1}
+	adminTx := wire.NewMsgTx(1)
+	adminTx.AddTxIn(wire.NewTxIn(&threadOutPoint, nil))
+	adminTx.AddTxOut(wire.NewTxOut(0, threadScript))
+	adminTx.AddTxOut(wire.NewTxOut(0, adminOpScript))
+
+	// A transaction paying to a Prova address.
+	keyHash := []byte{
+		0x35, 0xdb, 0xbf, 0x04, 0xbc, 0xa0, 0x61, 0xe4, 0x9d, 0xac,
+		0xe0, 0x8f, 0x85, 0x8d, 0x87, 0x75, 0xc0, 0xa5, 0x7c, 0x8e,
+	}
+	keyIDs := []btcec.KeyID{aspKeyID, 2}
+	addr, err := provautil.NewAddressProva(keyHash, keyIDs,
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("unable to create address: %v", err)
+	}
+	provaScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("unable to build prova script: %v", err)
+	}
+	spentOutPoint := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 3}
+	payTx := wire.NewMsgTx(1)
+	payTx.AddTxIn(wire.NewTxIn(&spentOutPoint, nil))
+	payTx.AddTxOut(wire.NewTxOut(1000, provaScript))
+
+	block := wire.MsgBlock{
+		Header:       genesis.Header,
+		Transactions: []*wire.MsgTx{genesisCoinbase, adminTx, payTx},
+	}
+	blockHash := block.BlockHash()
+	key := builder.DeriveKey(&blockHash)
+
+	filter, err := builder.BuildBasicFilter(&block)
+	if err != nil {
+		t.Fatalf("BuildBasicFilter: unexpected error: %v", err)
+	}
+
+	contained := map[string][]byte{
+		"key hash":           keyHash,
+		"asp keyID":          builder.KeyIDElement(aspKeyID),
+		"keyID":              builder.KeyIDElement(2),
+		"thread script":      threadScript,
+		"admin op key":       pubKey.SerializeCompressed(),
+		"spent thread":       builder.OutPointElement(&threadOutPoint),
+		"spent prova output": builder.OutPointElement(&spentOutPoint),
+	}
+	for name, element := range contained {
+		match, err := filter.Match(key, element)
+		if err != nil {
+			t.Fatalf("Match: unexpected error: %v", err)
+		}
+		if !match {
+			t.Errorf("filter doesn't contain the %s", name)
+		}
+	}
+
+	notContained := map[string][]byte{
+		"unused keyID": builder.KeyIDElement(3),
+		"coinbase input": builder.OutPointElement(
+			&genesisCoinbase.TxIn[0].PreviousOutPoint),
+	}
+	for name, element := range notContained {
+		match, err := filter.Match(key, element)
+		if err != nil {
+			t.Fatalf("Match: unexpected error: %v", err)
+		}
+		if match {
+			t.Errorf("filter contains the %s", name)
+		}
+	}
+
+	// The header of the filter must commit to the filter and the previous
+	// header.
+	header, err := builder.MakeHeaderForFilter(filter, chainhash.Hash{})
+	if err != nil {
+		t.Fatalf("MakeHeaderForFilter: unexpected error: %v", err)
+	}
+	filterHash, err := builder.GetFilterHash(filter)
+	if err != nil {
+		t.Fatalf("GetFilterHash: unexpected error: %v", err)
+	}
+	if builder.MakeHeaderForFilterHash(filterHash, chainhash.Hash{}) != header {
+		t.Errorf("MakeHeaderForFilterHash doesn't match " +
+			"MakeHeaderForFilter")
+	}
+	otherHeader, err := builder.MakeHeaderForFilter(filter, header)
+	if err != nil {
+		t.Fatalf("MakeHeaderForFilter: unexpected error: %v", err)
+	}
+	if otherHeader == header {
+		t.Errorf("filter header doesn't commit to the previous header")
+	}
+}
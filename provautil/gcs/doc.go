@@ -0,0 +1,24 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package gcs provides an API for building and using a Golomb-coded set filter.
+
+Golomb-Coded Set
+
+A Golomb-coded set is a probabilistic data structure used similarly to a Bloom
+filter.  A filter uses constant-size overhead plus on average n+2 bits per
+item added to the filter, where 2^-n is the desired false positive (collision)
+probability.
+
+GCS use in Prova
+
+GCS filters are a proposed mechanism for storing and transmitting per-block
+filters (BIP0157 and BIP0158).  The idea is that a light client downloads the
+filters of the blocks and tests them locally for the items it is interested
+in, so it does not reveal those items to the serving peers as it would with
+bloom filters.  The builder subpackage defines which items of a Prova block
+are added to its filter.
+*/
+package gcs
@@ -0,0 +1,322 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/bitgo/prova/wire"
+)
+
+const (
+	// KeySize is the size of the key the items of a filter are hashed
+	// with.
+	KeySize = 16
+
+	// maxP is the largest supported Golomb-Rice parameter.
+	maxP = 32
+)
+
+var (
+	// ErrNTooBig signifies that the filter can't handle N items.
+	ErrNTooBig = errors.New("N is too big to fit in uint32")
+
+	// ErrPTooBig signifies that the filter can't handle `1/2**P`
+	// collision probability.
+	ErrPTooBig = errors.New("P is too big to fit in uint32")
+)
+
+// uint64Slice implements sort.Interface to allow a slice of uint64 values to
+// be sorted in ascending order.
+type uint64Slice []uint64
+
+// Len returns the number of values in the slice.  It is part of the
+// sort.Interface implementation.
+func (s uint64Slice) Len() int { return len(s) }
+
+// Less returns whether the value with index i should sort before the value with
+// index j.  It is part of the sort.Interface implementation.
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+
+// Swap swaps the values at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s uint64Slice) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// fastReduction maps the passed uniformly distributed 64-bit value onto the
+// range [0, n) by taking the high 64 bits of the 128-bit product v * n, which
+// is considerably faster than a modulo operation.
+func fastReduction(v, n uint64) uint64 {
+	vHi, vLo := v>>32, v&0xffffffff
+	nHi, nLo := n>>32, n&0xffffffff
+
+	loLo := vLo * nLo
+	hiLo := vHi * nLo
+	loHi := vLo * nHi
+	hiHi := vHi * nHi
+
+	cross := loLo>>32 + hiLo&0xffffffff + loHi
+	return hiHi + hiLo>>32 + cross>>32
+}
+
+// Filter describes an immutable filter that can be built from a set of data
+// elements, serialized, deserialized, and queried in a thread-safe manner.
+// The serialized form is compressed as a Golomb Coded Set (GCS), but does not
+// include N or P to allow the user to encode the metadata separately if
+// necessary.  The hash function used is SipHash, a keyed function; the key
+// used in building the filter is required in order to match filter values and
+// is not included in the serialized form.
+type Filter struct {
+	n          uint32
+	p          uint8
+	modulusNM  uint64
+	filterData []byte
+}
+
+// BuildGCSFilter builds a new GCS filter with the collision probability of
+// `1/(2**P)`, key `key`, and including every `[]byte` in `data` as a member of
+// the set.  The items are hashed onto the range [0, N * M), so M controls the
+// false positive rate together with P.
+func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filter, error) {
+	// Some initial parameter checks: make sure we have data from which to
+	// build the filter, and make sure our parameters will fit the hash
+	// function we're using.
+	if uint64(len(data)) > math.MaxUint32 {
+		return nil, ErrNTooBig
+	}
+	if P > maxP {
+		return nil, ErrPTooBig
+	}
+
+	f := Filter{
+		n: uint32(len(data)),
+		p: P,
+	}
+	f.modulusNM = uint64(f.n) * M
+
+	// Hash the items onto the range of the filter and sort them so they can
+	// be delta encoded.
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	values := make(uint64Slice, 0, len(data))
+	for _, d := range data {
+		v := fastReduction(wire.SipHash24(k0, k1, d), f.modulusNM)
+		values = append(values, v)
+	}
+	sort.Sort(values)
+
+	// Write the sorted values as Golomb-Rice coded differences: the
+	// quotient of each difference by 2**P in unary followed by the
+	// remainder in P bits.
+	var w bitWriter
+	var lastValue uint64
+	for _, v := range values {
+		delta := v - lastValue
+		lastValue = v
+
+		for q := delta >> P; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(delta, P)
+	}
+	f.filterData = w.bytes
+
+	return &f, nil
+}
+
+// FromBytes deserializes a GCS filter from a known N, P, and serialized filter
+// as returned by Bytes().
+func FromBytes(N uint32, P uint8, M uint64, d []byte) (*Filter, error) {
+	// Basic sanity check.
+	if P > maxP {
+		return nil, ErrPTooBig
+	}
+
+	// Create the filter object and insert metadata.
+	f := &Filter{
+		n:         N,
+		p:         P,
+		modulusNM: uint64(N) * M,
+	}
+
+	// Copy the filter.
+	f.filterData = make([]byte, len(d))
+	copy(f.filterData, d)
+
+	return f, nil
+}
+
+// FromNBytes deserializes a GCS filter from a known P, and serialized N and
+// filter as returned by NBytes().
+func FromNBytes(P uint8, M uint64, d []byte) (*Filter, error) {
+	buffer := bytes.NewBuffer(d)
+	N, err := wire.ReadVarInt(buffer, 0)
+	if err != nil {
+		return nil, err
+	}
+	if N > math.MaxUint32 {
+		return nil, ErrNTooBig
+	}
+	return FromBytes(uint32(N), P, M, buffer.Bytes())
+}
+
+// Bytes returns the serialized format of the GCS filter, which does not
+// include N or P (returned by separate methods) or the key used by SipHash.
+func (f *Filter) Bytes() []byte {
+	filterData := make([]byte, len(f.filterData))
+	copy(filterData, f.filterData)
+	return filterData
+}
+
+// NBytes returns the serialized format of the GCS filter with N, which does
+// not include P (returned by a separate method) or the key used by SipHash.
+func (f *Filter) NBytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.Grow(wire.VarIntSerializeSize(uint64(f.n)) + len(f.filterData))
+
+	err := wire.WriteVarInt(&buffer, 0, uint64(f.n))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = buffer.Write(f.filterData)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// P returns the filter's collision probability as a negative power of 2 (that
+// is, a collision probability of `1/2**20` is represented as 20).
+func (f *Filter) P() uint8 {
+	return f.p
+}
+
+// N returns the size of the data set used to build the filter.
+func (f *Filter) N() uint32 {
+	return f.n
+}
+
+// readFullUint64 reads a value represented by the sum of a unary multiple of
+// the filter's P modulus (`2**P`) and a big-endian P-bit remainder.
+func (f *Filter) readFullUint64(r *bitReader) (uint64, error) {
+	// Count the ones of the unary coded quotient up to the terminating
+	// zero.
+	var quotient uint64
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		quotient++
+	}
+
+	remainder, err := r.readBits(f.p)
+	if err != nil {
+		return 0, err
+	}
+
+	// Add the multiple and the remainder.
+	return quotient<<f.p + remainder, nil
+}
+
+// Match checks whether a []byte value is likely (within collision probability)
+// to be a member of the set represented by the filter.
+func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
+	// An empty filter matches nothing.
+	if f.n == 0 {
+		return false, nil
+	}
+
+	// Hash our search term with the same parameters as the filter.
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	term := fastReduction(wire.SipHash24(k0, k1, data), f.modulusNM)
+
+	// Go through the search filter and look for the desired value.
+	r := bitReader{bytes: f.filterData}
+	var value uint64
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := f.readFullUint64(&r)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		value += delta
+
+		switch {
+		case value == term:
+			return true, nil
+		case value > term:
+			return false, nil
+		}
+	}
+
+	// The value was not found.
+	return false, nil
+}
+
+// MatchAny returns checks whether any []byte value is likely (within collision
+// probability) to be a member of the set represented by the filter faster than
+// calling Match() for each value individually.
+func (f *Filter) MatchAny(key [KeySize]byte, data [][]byte) (bool, error) {
+	// An empty filter or empty query matches nothing.
+	if f.n == 0 || len(data) == 0 {
+		return false, nil
+	}
+
+	// Create an uncompressed filter of the search values.
+	k0 := binary.LittleEndian.Uint64(key[0:8])
+	k1 := binary.LittleEndian.Uint64(key[8:16])
+	values := make(uint64Slice, 0, len(data))
+	for _, d := range data {
+		v := fastReduction(wire.SipHash24(k0, k1, d), f.modulusNM)
+		values = append(values, v)
+	}
+	sort.Sort(values)
+
+	// Zip down the filters, comparing values until we either run out of
+	// values to compare in one of the filters or we reach a matching
+	// value.
+	r := bitReader{bytes: f.filterData}
+	var value uint64
+	searchIdx := 0
+	for i := uint32(0); i < f.n; i++ {
+		delta, err := f.readFullUint64(&r)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		value += delta
+
+		// Skip the search values which are smaller than the current
+		// filter value.
+		for values[searchIdx] < value {
+			searchIdx++
+			if searchIdx == len(values) {
+				return false, nil
+			}
+		}
+		if values[searchIdx] == value {
+			return true, nil
+		}
+	}
+
+	// No values matched.
+	return false, nil
+}
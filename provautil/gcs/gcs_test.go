@@ -0,0 +1,187 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+const (
+	// testP and testM are the parameters the test filters are built with.
+	testP = 19
+	testM = 784931
+)
+
+var (
+	// testKey is the key the test filters are built with.
+	testKey = [KeySize]byte{0x4c, 0xb1, 0xab, 0x12, 0x57, 0x62, 0x1e, 0x41,
+		0x3b, 0x8b, 0x0e, 0x26, 0x64, 0x8d, 0x4a, 0x15}
+
+	// testContents are the items of the test filter.
+	testContents = [][]byte{
+		[]byte("Alex"),
+		[]byte("Bob"),
+		[]byte("Charlie"),
+		[]byte("Dick"),
+		[]byte("Ed"),
+		[]byte("Frank"),
+		[]byte("George"),
+		[]byte("Harry"),
+		[]byte("Ilya"),
+		[]byte("John"),
+		[]byte("Kevin"),
+		[]byte("Larry"),
+		[]byte("Michael"),
+		[]byte("Nate"),
+		[]byte("Owen"),
+		[]byte("Paul"),
+		[]byte("Quentin"),
+	}
+)
+
+// TestGCSFilterBuild ensures filters survive serialization and deserialization
+// and match the items they were built from.
+func TestGCSFilterBuild(t *testing.T) {
+	filter, err := BuildGCSFilter(testP, testM, testKey, testContents)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+	if filter.N() != uint32(len(testContents)) {
+		t.Fatalf("N: got %d, want %d", filter.N(), len(testContents))
+	}
+	if filter.P() != testP {
+		t.Fatalf("P: got %d, want %d", filter.P(), testP)
+	}
+
+	// Make sure the filter survives a round trip through both serialized
+	// forms.
+	filter2, err := FromBytes(filter.N(), testP, testM, filter.Bytes())
+	if err != nil {
+		t.Fatalf("FromBytes: unexpected error: %v", err)
+	}
+	nBytes, err := filter.NBytes()
+	if err != nil {
+		t.Fatalf("NBytes: unexpected error: %v", err)
+	}
+	filter3, err := FromNBytes(testP, testM, nBytes)
+	if err != nil {
+		t.Fatalf("FromNBytes: unexpected error: %v", err)
+	}
+	for i, f := range []*Filter{filter2, filter3} {
+		if f.N() != filter.N() || !bytes.Equal(f.Bytes(), filter.Bytes()) {
+			t.Fatalf("deserialized filter #%d does not match the "+
+				"original", i)
+		}
+	}
+
+	// Every item of the filter must match.
+	for _, item := range testContents {
+		match, err := filter3.Match(testKey, item)
+		if err != nil {
+			t.Fatalf("Match: unexpected error: %v", err)
+		}
+		if !match {
+			t.Errorf("filter didn't match %s", item)
+		}
+	}
+
+	// Items which are not in the filter should not match, barring an
+	// unlikely collision.
+	for _, item := range [][]byte{[]byte("Nate2"), []byte("Quentin2")} {
+		match, err := filter.Match(testKey, item)
+		if err != nil {
+			t.Fatalf("Match: unexpected error: %v", err)
+		}
+		if match {
+			t.Errorf("filter matched %s which it doesn't contain",
+				item)
+		}
+	}
+
+	// MatchAny must match if only one of the items is in the filter.
+	match, err := filter.MatchAny(testKey, [][]byte{[]byte("Nate2"),
+		[]byte("Quentin2"), []byte("Ilya")})
+	if err != nil {
+		t.Fatalf("MatchAny: unexpected error: %v", err)
+	}
+	if !match {
+		t.Errorf("MatchAny didn't match a contained item")
+	}
+	match, err = filter.MatchAny(testKey, [][]byte{[]byte("Nate2"),
+		[]byte("Quentin2")})
+	if err != nil {
+		t.Fatalf("MatchAny: unexpected error: %v", err)
+	}
+	if match {
+		t.Errorf("MatchAny matched items which aren't contained")
+	}
+
+	// A filter built with a different key must not match.
+	otherKey := testKey
+	otherKey[0] ^= 0xff
+	match, err = filter.MatchAny(otherKey, testContents)
+	if err != nil {
+		t.Fatalf("MatchAny: unexpected error: %v", err)
+	}
+	if match {
+		t.Errorf("MatchAny matched with a different key")
+	}
+}
+
+// TestGCSFilterEmpty ensures an empty filter is serialized as a single zero
+// byte and matches nothing.
+func TestGCSFilterEmpty(t *testing.T) {
+	filter, err := BuildGCSFilter(testP, testM, testKey, nil)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+	nBytes, err := filter.NBytes()
+	if err != nil {
+		t.Fatalf("NBytes: unexpected error: %v", err)
+	}
+	if !bytes.Equal(nBytes, []byte{0x00}) {
+		t.Fatalf("NBytes: got %x, want 00", nBytes)
+	}
+	match, err := filter.MatchAny(testKey, testContents)
+	if err != nil {
+		t.Fatalf("MatchAny: unexpected error: %v", err)
+	}
+	if match {
+		t.Fatalf("empty filter matched")
+	}
+}
+
+// TestGCSFilterPTooBig ensures filters can't be built with an unsupported
+// collision probability.
+func TestGCSFilterPTooBig(t *testing.T) {
+	_, err := BuildGCSFilter(maxP+1, testM, testKey, testContents)
+	if err != ErrPTooBig {
+		t.Fatalf("BuildGCSFilter: got %v, want %v", err, ErrPTooBig)
+	}
+}
+
+// TestFastReduction ensures the fast reduction of a value onto a range agrees
+// with the result of the full 128-bit multiplication.
+func TestFastReduction(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var buf [16]byte
+	for i := 0; i < 10000; i++ {
+		r.Read(buf[:])
+		v := binary.LittleEndian.Uint64(buf[:8])
+		n := binary.LittleEndian.Uint64(buf[8:])
+
+		want := new(big.Int).Mul(new(big.Int).SetUint64(v),
+			new(big.Int).SetUint64(n))
+		want.Rsh(want, 64)
+		if got := fastReduction(v, n); got != want.Uint64() {
+			t.Fatalf("fastReduction(%d, %d): got %d, want %d", v,
+				n, got, want.Uint64())
+		}
+	}
+}
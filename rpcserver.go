@@ -7,6 +7,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -17,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/bitgo/prova/blockchain"
+	"github.com/bitgo/prova/blockchain/indexers"
 	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/btcjson"
 	"github.com/bitgo/prova/chaincfg"
@@ -117,47 +119,62 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 // a dependency loop.
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
-	"addnode":               handleAddNode,
-	"createrawtransaction":  handleCreateRawTransaction,
-	"debuglevel":            handleDebugLevel,
-	"decoderawtransaction":  handleDecodeRawTransaction,
-	"generate":              handleGenerate,
-	"getaddednodeinfo":      handleGetAddedNodeInfo,
-	"getaddresstxids":       handleGetAddressTxIds,
-	"getadmininfo":          handleGetAdminInfo,
-	"getbestblock":          handleGetBestBlock,
-	"getbestblockhash":      handleGetBestBlockHash,
-	"getblock":              handleGetBlock,
-	"getblockcount":         handleGetBlockCount,
-	"getblockhash":          handleGetBlockHash,
-	"getblockheader":        handleGetBlockHeader,
-	"getblocktemplate":      handleGetBlockTemplate,
-	"getconnectioncount":    handleGetConnectionCount,
-	"getcurrentnet":         handleGetCurrentNet,
-	"getdifficulty":         handleGetDifficulty,
-	"getgenerate":           handleGetGenerate,
-	"gethashespersec":       handleGetHashesPerSec,
-	"getheaders":            handleGetHeaders,
-	"getinfo":               handleGetInfo,
-	"getmempoolinfo":        handleGetMempoolInfo,
-	"getmininginfo":         handleGetMiningInfo,
-	"getnettotals":          handleGetNetTotals,
-	"getnetworkhashps":      handleGetNetworkHashPS,
-	"getpeerinfo":           handleGetPeerInfo,
-	"getrawmempool":         handleGetRawMempool,
-	"getrawtransaction":     handleGetRawTransaction,
-	"gettxout":              handleGetTxOut,
-	"help":                  handleHelp,
-	"node":                  handleNode,
-	"ping":                  handlePing,
-	"searchrawtransactions": handleSearchRawTransactions,
-	"sendrawtransaction":    handleSendRawTransaction,
-	"setgenerate":           handleSetGenerate,
-	"setvalidatekeys":       handleSetValidateKeys,
-	"stop":                  handleStop,
-	"submitblock":           handleSubmitBlock,
-	"validateaddress":       handleValidateAddress,
-	"verifychain":           handleVerifyChain,
+	"addnode":                    handleAddNode,
+	"createrawtransaction":       handleCreateRawTransaction,
+	"createrecoverytransactions": handleCreateRecoveryTransactions,
+	"debuglevel":                 handleDebugLevel,
+	"decoderawtransaction":       handleDecodeRawTransaction,
+	"dumptxoutset":               handleDumpTxOutSet,
+	"estimatefee":                handleEstimateFee,
+	"estimatesmartfee":           handleEstimateSmartFee,
+	"generate":                   handleGenerate,
+	"getaddednodeinfo":           handleGetAddedNodeInfo,
+	"getaddresstxids":            handleGetAddressTxIds,
+	"getadmininfo":               handleGetAdminInfo,
+	"getbestblock":               handleGetBestBlock,
+	"getbestblockhash":           handleGetBestBlockHash,
+	"getblock":                   handleGetBlock,
+	"getblockchaininfo":          handleGetBlockChainInfo,
+	"getblockcount":              handleGetBlockCount,
+	"getblockhash":               handleGetBlockHash,
+	"getblockheader":             handleGetBlockHeader,
+	"getblocktemplate":           handleGetBlockTemplate,
+	"getcfilter":                 handleGetCFilter,
+	"getconnectioncount":         handleGetConnectionCount,
+	"getcurrentnet":              handleGetCurrentNet,
+	"getdifficulty":              handleGetDifficulty,
+	"getgenerate":                handleGetGenerate,
+	"gethashespersec":            handleGetHashesPerSec,
+	"getheaders":                 handleGetHeaders,
+	"getinfo":                    handleGetInfo,
+	"getmempoolinfo":             handleGetMempoolInfo,
+	"getmininginfo":              handleGetMiningInfo,
+	"getnettotals":               handleGetNetTotals,
+	"getnetworkhashps":           handleGetNetworkHashPS,
+	"getnotices":                 handleGetNotices,
+	"getpeerinfo":                handleGetPeerInfo,
+	"getrawmempool":              handleGetRawMempool,
+	"getrawtransaction":          handleGetRawTransaction,
+	"getrevokeimpact":            handleGetRevokeImpact,
+	"gettxout":                   handleGetTxOut,
+	"help":                       handleHelp,
+	"listkeyidutxos":             handleListKeyIDUtxos,
+	"loadmempool":                handleLoadMempool,
+	"loadtxoutset":               handleLoadTxOutSet,
+	"node":                       handleNode,
+	"ping":                       handlePing,
+	"savemempool":                handleSaveMempool,
+	"searchkeyidtransactions":    handleSearchKeyIDTransactions,
+	"searchrawtransactions":      handleSearchRawTransactions,
+	"sendnotice":                 handleSendNotice,
+	"sendrawtransaction":         handleSendRawTransaction,
+	"setgenerate":                handleSetGenerate,
+	"setvalidatekeys":            handleSetValidateKeys,
+	"stop":                       handleStop,
+	"submitblock":                handleSubmitBlock,
+	"testmempoolaccept":          handleTestMempoolAccept,
+	"validateaddress":            handleValidateAddress,
+	"verifychain":                handleVerifyChain,
 }
 
 // list of commands that we recognize, but for which there is no support because
@@ -209,16 +226,21 @@ var rpcAskWallet = map[string]struct{}{
 
 // Commands that are currently unimplemented, but should ultimately be.
 var rpcUnimplemented = map[string]struct{}{
-	"estimatefee":       {},
-	"estimatepriority":  {},
-	"getblockchaininfo": {},
-	"getchaintips":      {},
-	"getmempoolentry":   {},
-	"getnetworkinfo":    {},
-	"getwork":           {},
-	"invalidateblock":   {},
-	"preciousblock":     {},
-	"reconsiderblock":   {},
+	"estimatepriority": {},
+	"getchaintips":     {},
+	"getmempoolentry":  {},
+	"getnetworkinfo":   {},
+	"getwork":          {},
+	"invalidateblock":  {},
+	"preciousblock":    {},
+	"reconsiderblock":  {},
+}
+
+// rpcLimitedHandlers maps the commands which behave differently for limited
+// users to the handlers used for them in place of the ones in rpcHandlers.
+var rpcLimitedHandlers = map[string]commandHandler{
+	"sendrawtransaction": handleLimitedSendRawTransaction,
+	"testmempoolaccept":  handleLimitedTestMempoolAccept,
 }
 
 // Commands that are available to a limited user
@@ -227,6 +249,7 @@ var rpcLimited = map[string]struct{}{
 	"loadtxfilter":          {},
 	"notifyblocks":          {},
 	"notifynewtransactions": {},
+	"notifynotices":         {},
 	"notifyreceived":        {},
 	"notifyspent":           {},
 	"rescan":                {},
@@ -237,30 +260,40 @@ var rpcLimited = map[string]struct{}{
 	"help": {},
 
 	// HTTP/S-only commands
-	"createrawtransaction":  {},
-	"decoderawtransaction":  {},
-	"decodescript":          {},
-	"getaddresstxids":       {},
-	"getadmininfo":          {},
-	"getbestblock":          {},
-	"getbestblockhash":      {},
-	"getblock":              {},
-	"getblockcount":         {},
-	"getblockhash":          {},
-	"getcurrentnet":         {},
-	"getdifficulty":         {},
-	"getheaders":            {},
-	"getinfo":               {},
-	"getnettotals":          {},
-	"getnetworkhashps":      {},
-	"getrawmempool":         {},
-	"getrawtransaction":     {},
-	"gettxout":              {},
-	"searchrawtransactions": {},
-	"sendrawtransaction":    {},
-	"submitblock":           {},
-	"validateaddress":       {},
-	"verifymessage":         {},
+	"createrawtransaction":       {},
+	"createrecoverytransactions": {},
+	"decoderawtransaction":       {},
+	"decodescript":               {},
+	"estimatefee":                {},
+	"estimatesmartfee":           {},
+	"getaddresstxids":            {},
+	"getadmininfo":               {},
+	"getbestblock":               {},
+	"getbestblockhash":           {},
+	"getblock":                   {},
+	"getblockchaininfo":          {},
+	"getblockcount":              {},
+	"getblockhash":               {},
+	"getcfilter":                 {},
+	"getcurrentnet":              {},
+	"getdifficulty":              {},
+	"getheaders":                 {},
+	"getinfo":                    {},
+	"getnettotals":               {},
+	"getnetworkhashps":           {},
+	"getnotices":                 {},
+	"getrawmempool":              {},
+	"getrawtransaction":          {},
+	"getrevokeimpact":            {},
+	"gettxout":                   {},
+	"listkeyidutxos":             {},
+	"searchkeyidtransactions":    {},
+	"searchrawtransactions":      {},
+	"sendrawtransaction":         {},
+	"submitblock":                {},
+	"testmempoolaccept":          {},
+	"validateaddress":            {},
+	"verifymessage":              {},
 }
 
 // builderScript is a convenience function which is used for hard-coded scripts
@@ -315,6 +348,7 @@ type gbtWorkState struct {
 	prevHash      *chainhash.Hash
 	minTimestamp  time.Time
 	template      *mining.BlockTemplate
+	validateKeys  []btcjson.GetBlockTemplateResultValidateKey
 	notifyMap     map[chainhash.Hash]map[int64]chan struct{}
 	timeSource    blockchain.MedianTimeSource
 }
@@ -589,6 +623,368 @@ func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 	return mtxHex, nil
 }
 
+// revokeImpactGroup houses the unspent outputs secured by a keyID which share
+// the same public key script along with whether they remain spendable once the
+// keyID is revoked.  The number and total value cover all of the outputs of
+// the group, while only the requested page of them is kept.
+type revokeImpactGroup struct {
+	pkScript      []byte
+	address       provautil.Address
+	requiredSigs  int
+	availableKeys int
+	count         uint32
+	total         provautil.Amount
+	utxos         []btcjson.KeyIDUtxoResult
+	outPoints     []wire.OutPoint
+	amounts       []provautil.Amount
+}
+
+// spendable returns whether enough keys remain to spend the outputs of the
+// group.
+func (g *revokeImpactGroup) spendable() bool {
+	return g.availableKeys >= g.requiredSigs
+}
+
+// revokeImpact groups the unspent outputs secured by a keyID by their public
+// key script in the order the groups are first encountered, treating the keyID
+// as revoked.
+type revokeImpact struct {
+	keyID          btcec.KeyID
+	keyIDs         btcec.KeyIdMap
+	chainParams    *chaincfg.Params
+	groups         []*revokeImpactGroup
+	groupsByScript map[string]*revokeImpactGroup
+}
+
+// newRevokeImpact returns an empty revoke impact for the passed keyID given the
+// currently provisioned keyIDs.
+func newRevokeImpact(keyID btcec.KeyID, keyIDs btcec.KeyIdMap, chainParams *chaincfg.Params) *revokeImpact {
+	return &revokeImpact{
+		keyID:          keyID,
+		keyIDs:         keyIDs,
+		chainParams:    chainParams,
+		groupsByScript: make(map[string]*revokeImpactGroup),
+	}
+}
+
+// addUtxo adds the passed unspent output to the group of its public key
+// script.  It only counts towards the totals of the group unless it is part of
+// the requested page, in which case its details are kept as well.
+func (r *revokeImpact) addUtxo(utxo *indexers.KeyIDUtxo, pkScript []byte, confirmations int64, inPage bool) error {
+	group, ok := r.groupsByScript[string(pkScript)]
+	if !ok {
+		var err error
+		group, err = newRevokeImpactGroup(r.chainParams, pkScript,
+			r.keyID, r.keyIDs)
+		if err != nil {
+			return err
+		}
+		r.groupsByScript[string(pkScript)] = group
+		r.groups = append(r.groups, group)
+	}
+
+	group.count++
+	group.total += utxo.Amount
+	if !inPage {
+		return nil
+	}
+
+	utxoResult := btcjson.KeyIDUtxoResult{
+		TxID:          utxo.OutPoint.Hash.String(),
+		Vout:          utxo.OutPoint.Index,
+		Amount:        utxo.Amount.ToRMG(),
+		ScriptPubKey:  hex.EncodeToString(pkScript),
+		Confirmations: confirmations,
+	}
+	if group.address != nil {
+		utxoResult.Address = group.address.EncodeAddress()
+	}
+	group.utxos = append(group.utxos, utxoResult)
+	group.outPoints = append(group.outPoints, utxo.OutPoint)
+	group.amounts = append(group.amounts, utxo.Amount)
+	return nil
+}
+
+// revokeImpactBatchSize is the maximum number of keyID index entries which are
+// loaded at once while determining the impact of revoking a keyID.
+const revokeImpactBatchSize = 1000
+
+// fetchRevokeImpact returns the impact of revoking the passed keyID on the
+// unspent outputs it secures.  The keyID is treated as revoked regardless of
+// whether it is still provisioned, so the result describes the impact both
+// before and after the revoke.  All of the unspent outputs count towards the
+// totals, while only the details of the requested page of them are kept.
+// Whether the keyID is already revoked is returned as well.
+func fetchRevokeImpact(s *rpcServer, keyID btcec.KeyID, numToSkip, numRequested uint32) (*revokeImpact, bool, error) {
+	keyIDIndex := s.server.keyIDIndex
+	if keyIDIndex == nil {
+		return nil, false, keyIDIndexDisabledError
+	}
+
+	best := s.chain.BestSnapshot()
+	keyIDs := s.chain.KeyIDs()
+	impact := newRevokeImpact(keyID, keyIDs, s.server.chainParams)
+
+	// Load the index entries in batches so the memory used does not depend
+	// on the number of outputs secured by the keyID.
+	var offset uint32
+	for {
+		utxos, count, _, err := keyIDIndex.UtxosForKeyID(keyID, offset,
+			revokeImpactBatchSize)
+		if err != nil {
+			context := "Failed to load keyID index entries"
+			return nil, false, internalRPCError(err.Error(), context)
+		}
+		for i := range utxos {
+			utxo := &utxos[i]
+			position := offset + uint32(i)
+
+			// Skip entries which have been spent since the index
+			// was queried.
+			entry, err := s.chain.FetchUtxoEntry(&utxo.OutPoint.Hash)
+			if err != nil {
+				context := "Failed to fetch utxo"
+				return nil, false, internalRPCError(err.Error(),
+					context)
+			}
+			if entry == nil || entry.IsOutputSpent(utxo.OutPoint.Index) {
+				continue
+			}
+
+			pkScript := entry.PkScriptByIndex(utxo.OutPoint.Index)
+			confirmations := int64(1 + best.Height - entry.BlockHeight())
+			inPage := position >= numToSkip &&
+				position-numToSkip < numRequested
+			err = impact.addUtxo(utxo, pkScript, confirmations, inPage)
+			if err != nil {
+				return nil, false, err
+			}
+		}
+
+		offset += uint32(len(utxos))
+		if len(utxos) == 0 || offset >= count {
+			break
+		}
+	}
+
+	return impact, keyIDs[keyID] == nil, nil
+}
+
+// newRevokeImpactGroup returns an empty group for the passed public key script
+// with the number of keys which remain available to sign for it once the
+// passed keyID is revoked.
+func newRevokeImpactGroup(chainParams *chaincfg.Params, pkScript []byte, revoked btcec.KeyID, keyIDs btcec.KeyIdMap) (*revokeImpactGroup, error) {
+	keyHashes, scriptKeyIDs, err := txscript.ExtractProvaKeys(pkScript)
+	if err != nil {
+		context := "Failed to extract keys from script"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	requiredSigs, err := txscript.ProvaRequiredSigs(pkScript)
+	if err != nil {
+		context := "Failed to extract required signatures from script"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// The keyIDs are only resolved to the provisioned ASP keys during
+	// script validation for standard Prova scripts, so they never count
+	// towards the available keys of the other scripts.
+	group := &revokeImpactGroup{
+		pkScript:      pkScript,
+		requiredSigs:  requiredSigs,
+		availableKeys: len(keyHashes),
+	}
+	class, addrs, _, _ := txscript.ExtractPkScriptAddrs(pkScript,
+		chainParams)
+	if class == txscript.ProvaTy {
+		for _, keyID := range scriptKeyIDs {
+			if keyID != revoked && keyIDs[keyID] != nil {
+				group.availableKeys++
+			}
+		}
+	}
+	if len(addrs) > 0 {
+		group.address = addrs[0]
+	}
+	return group, nil
+}
+
+// recoverySigScriptSize returns the estimated size of a signature script which
+// spends a Prova output with the passed number of required signatures.  It
+// consists of a push of a compressed public key and a signature with the hash
+// type for each of them.
+func recoverySigScriptSize(requiredSigs int) int {
+	return requiredSigs * (1 + 33 + 1 + 73)
+}
+
+// maxRecoveryInputs returns the maximum number of inputs of a recovery
+// transaction which spends Prova outputs with the passed number of required
+// signatures so the signed transaction remains standard.  Each input consists
+// of the outpoint, the signature script with its 3 byte length prefix, and the
+// sequence number.
+func maxRecoveryInputs(requiredSigs int) int {
+	return (mempool.MaxStandardTxSize - 1000) /
+		(chainhash.HashSize + 4 + 3 + recoverySigScriptSize(requiredSigs) + 4)
+}
+
+// createRecoveryTransactions returns transactions which move the outputs of
+// the passed group to the passed public key script, splitting the outputs
+// across multiple transactions when there are too many of them.  The fee for
+// the estimated size of each signed transaction is deducted from its output,
+// and it is capped at the passed maximum fee.
+func createRecoveryTransactions(group *revokeImpactGroup, pkScript []byte, feeRate, maxFee provautil.Amount) ([]*wire.MsgTx, []provautil.Amount, error) {
+	maxInputs := maxRecoveryInputs(group.requiredSigs)
+	var txns []*wire.MsgTx
+	var fees []provautil.Amount
+	for start := 0; start < len(group.outPoints); start += maxInputs {
+		end := start + maxInputs
+		if end > len(group.outPoints) {
+			end = len(group.outPoints)
+		}
+
+		var amount provautil.Amount
+		mtx := wire.NewMsgTx(wire.TxVersion)
+		for i := start; i < end; i++ {
+			mtx.AddTxIn(wire.NewTxIn(&group.outPoints[i], nil))
+			amount += group.amounts[i]
+		}
+		mtx.AddTxOut(wire.NewTxOut(0, pkScript))
+
+		size := mtx.SerializeSize() + len(mtx.TxIn)*
+			(recoverySigScriptSize(group.requiredSigs)+2)
+		fee := provautil.Amount(int64(size) * int64(feeRate) / 1000)
+		if fee > maxFee {
+			fee = maxFee
+		}
+		if fee >= amount {
+			return nil, nil, fmt.Errorf("the outputs do not cover "+
+				"the fee of %v", fee)
+		}
+		mtx.TxOut[0].Value = int64(amount - fee)
+
+		txns = append(txns, mtx)
+		fees = append(fees, fee)
+	}
+	return txns, fees, nil
+}
+
+// handleCreateRecoveryTransactions implements the createrecoverytransactions
+// command.
+func handleCreateRecoveryTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.CreateRecoveryTransactionsCmd)
+	keyID := btcec.KeyID(c.KeyID)
+
+	// Use the minimum relay fee of the node unless a fee rate is given.
+	feeRate := cfg.minRelayTxFee
+	if c.FeeRate != nil {
+		var err error
+		feeRate, err = provautil.NewAmount(*c.FeeRate)
+		if err != nil || feeRate < 0 {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCType,
+				Message: "Invalid fee rate",
+			}
+		}
+	}
+
+	// Decode the destination addresses up front and ensure they are only
+	// secured by provisioned keys other than the revoked one.
+	keyIDs := s.chain.KeyIDs()
+	destinations := make(map[string]*provautil.AddressProva)
+	for source, encodedAddr := range c.Destinations {
+		addr, err := provautil.DecodeAddress(encodedAddr,
+			s.server.chainParams)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid address or key: " + err.Error(),
+			}
+		}
+		provaAddr, ok := addr.(*provautil.AddressProva)
+		if !ok || !provaAddr.IsForNet(s.server.chainParams) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidAddressOrKey,
+				Message: "Invalid address: " + encodedAddr +
+					" is not a Prova address for this network",
+			}
+		}
+		for _, addrKeyID := range provaAddr.ScriptKeyIDs() {
+			if addrKeyID == keyID || keyIDs[addrKeyID] == nil {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidAddressOrKey,
+					Message: fmt.Sprintf("Invalid address: %s "+
+						"is secured by keyID %d which "+
+						"is not provisioned or revoked",
+						encodedAddr, addrKeyID),
+				}
+			}
+		}
+		destinations[source] = provaAddr
+	}
+
+	numToSkip, numRequested := normalizeSkipCount(c.Skip, c.Count)
+	impact, _, err := fetchRevokeImpact(s, keyID, numToSkip, numRequested)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the transactions which move the requested outputs of each
+	// spendable group with a destination to the destination.  The groups
+	// are identified by their address or their hex-encoded public key
+	// script when they do not have an address.
+	maxFee := provautil.Amount(s.server.chainParams.MaximumFeeAmount)
+	results := make([]btcjson.RecoveryTransactionResult, 0,
+		len(impact.groups))
+	for _, group := range impact.groups {
+		var source string
+		if group.address != nil {
+			source = group.address.EncodeAddress()
+		}
+		dest, ok := destinations[source]
+		if !ok {
+			dest, ok = destinations[hex.EncodeToString(group.pkScript)]
+		}
+		if !ok || !group.spendable() || len(group.outPoints) == 0 {
+			continue
+		}
+
+		pkScript, err := txscript.PayToAddrScript(dest)
+		if err != nil {
+			context := "Failed to generate pay-to-address script"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		txns, fees, err := createRecoveryTransactions(group, pkScript,
+			feeRate, maxFee)
+		if err != nil {
+			label := source
+			if label == "" {
+				label = hex.EncodeToString(group.pkScript)
+			}
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCMisc,
+				Message: fmt.Sprintf("Failed to create recovery "+
+					"transactions for %s: %v", label, err),
+			}
+		}
+		for i, mtx := range txns {
+			mtxHex, err := messageToHex(mtx)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, btcjson.RecoveryTransactionResult{
+				Address:     source,
+				Destination: dest.EncodeAddress(),
+				Hex:         mtxHex,
+				NumInputs:   len(mtx.TxIn),
+				Amount:      provautil.Amount(mtx.TxOut[0].Value).ToRMG(),
+				Fee:         fees[i].ToRMG(),
+			})
+		}
+	}
+
+	return results, nil
+}
+
 type addressToKey struct {
 	key        *btcec.PrivateKey
 	compressed bool
@@ -768,6 +1164,125 @@ func handleDecodeRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan
 	return txReply, nil
 }
 
+// snapshotResult returns the result of the dumptxoutset and loadtxoutset
+// commands for the passed snapshot.
+func snapshotResult(path string, info *blockchain.SnapshotInfo) *btcjson.TxOutSetSnapshotResult {
+	return &btcjson.TxOutSetSnapshotResult{
+		Path:          path,
+		Hash:          info.Hash.String(),
+		BestBlockHash: info.BlockHash.String(),
+		Height:        info.Height,
+		NumUtxos:      info.NumUtxos,
+		NumHeaders:    info.NumHeaders,
+	}
+}
+
+// handleDumpTxOutSet implements the dumptxoutset command.
+func handleDumpTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpTxOutSetCmd)
+
+	// Refuse to overwrite existing files and write the snapshot to a
+	// temporary file first, so a partially written snapshot is never left
+	// at the requested path.
+	path := cleanAndExpandPath(c.Path)
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("File %s already exists", path),
+		}
+	}
+	tmpPath := path + ".incomplete"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		context := "Failed to create snapshot file"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	w := bufio.NewWriter(f)
+	info, err := s.chain.DumpUtxoSnapshot(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		context := "Failed to dump utxo set snapshot"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	return snapshotResult(path, info), nil
+}
+
+// checkEstimateFeeTarget returns an error when the passed number of blocks is
+// outside of the range the fee estimator supports.
+func checkEstimateFeeTarget(numBlocks int64) error {
+	if numBlocks < 1 || numBlocks > mempool.EstimateFeeMaxTarget {
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Number of blocks must be between 1 "+
+				"and %d", mempool.EstimateFeeMaxTarget),
+		}
+	}
+	return nil
+}
+
+// handleEstimateFee handles estimatefee commands.
+func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateFeeCmd)
+	if err := checkEstimateFeeTarget(c.NumBlocks); err != nil {
+		return nil, err
+	}
+
+	// Like the reference client, return -1 when there is not enough data
+	// to provide an estimate yet.
+	feeRate, err := s.server.feeEstimator.EstimateFee(uint32(c.NumBlocks))
+	if err != nil {
+		return -1.0, nil
+	}
+	return float64(feeRate), nil
+}
+
+// handleEstimateSmartFee handles estimatesmartfee commands.
+func handleEstimateSmartFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.EstimateSmartFeeCmd)
+
+	// Clamp the target to the supported range instead of failing.
+	target := c.ConfTarget
+	if target < 1 {
+		target = 1
+	}
+	if target > mempool.EstimateFeeMaxTarget {
+		target = mempool.EstimateFeeMaxTarget
+	}
+
+	result := &btcjson.EstimateSmartFeeResult{Blocks: target}
+	feeRate, err := s.server.feeEstimator.EstimateFee(uint32(target))
+	if err != nil {
+		result.Errors = []string{err.Error()}
+		return result, nil
+	}
+	if feeRate <= 0 {
+		result.Errors = []string{"Insufficient data or no feerate found"}
+		return result, nil
+	}
+
+	// Never estimate a fee rate which would not be relayed or accepted
+	// into the full memory pool.
+	rate := float64(feeRate)
+	if minRate := cfg.minRelayTxFee.ToRMG(); rate < minRate {
+		rate = minRate
+	}
+	if minRate := s.server.txMemPool.DynamicMinFee().ToRMG(); rate < minRate {
+		rate = minRate
+	}
+	result.FeeRate = &rate
+	return result, nil
+}
+
 // handleGenerate handles generate commands.
 func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if there are no addresses to pay the
@@ -1131,6 +1646,14 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 		blkBytes, err = dbTx.FetchBlock(hash)
 		return err
 	})
+	if dbErr, ok := err.(database.Error); ok &&
+		dbErr.ErrorCode == database.ErrBlockPruned {
+
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not available (pruned data)",
+		}
+	}
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCBlockNotFound,
@@ -1216,6 +1739,47 @@ func handleGetBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return blockReply, nil
 }
 
+// handleGetBlockChainInfo implements the getblockchaininfo command.
+func handleGetBlockChainInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	best := s.chain.BestSnapshot()
+
+	// Estimate the verification progress from the height reported by the
+	// sync peer, if any.
+	progress := 1.0
+	if syncPeer := s.server.blockManager.SyncPeer(); syncPeer != nil {
+		if peerHeight := syncPeer.LastBlock(); peerHeight > best.Height {
+			progress = float64(best.Height) / float64(peerHeight)
+		}
+	}
+
+	ret := &btcjson.GetBlockChainInfoResult{
+		Chain:                activeNetParams.Name,
+		Blocks:               int32(best.Height),
+		Headers:              int32(s.server.blockManager.HeaderHeight()),
+		BestBlockHash:        best.Hash.String(),
+		Difficulty:           getDifficultyRatio(best.Bits),
+		VerificationProgress: progress,
+		ChainWork:            fmt.Sprintf("%064x", best.WorkSum),
+	}
+
+	pruned, err := s.chain.IsPruned()
+	if err != nil {
+		context := "Failed to determine pruned state"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	ret.Pruned = pruned
+
+	// Report the assume-valid block along with the height up to which
+	// script validation was skipped because of it.
+	assumeValid, assumeValidHeight := s.chain.AssumeValid()
+	if assumeValid != nil {
+		ret.AssumeValid = assumeValid.String()
+		ret.AssumeValidHeight = assumeValidHeight
+	}
+
+	return ret, nil
+}
+
 // handleGetBlockCount implements the getblockcount command.
 func handleGetBlockCount(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	best := s.chain.BestSnapshot()
@@ -1236,6 +1800,49 @@ func handleGetBlockHash(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return hash.String(), nil
 }
 
+// handleGetCFilter implements the getcfilter command.
+func handleGetCFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the committed filter index is not enabled
+	// or is no longer maintained since the chain was bootstrapped from a
+	// utxo set snapshot.
+	cfIndex := s.server.cfIndex
+	if cfIndex == nil || s.server.Services()&wire.SFNodeCF == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Committed filter index must be enabled",
+		}
+	}
+
+	c := cmd.(*btcjson.GetCFilterCmd)
+	if c.FilterType != nil &&
+		*c.FilterType != uint32(wire.GCSFilterRegular) {
+
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Unsupported filter type %d",
+				*c.FilterType),
+		}
+	}
+
+	hash, err := chainhash.NewHashFromStr(c.Hash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Hash)
+	}
+	filter, err := cfIndex.FilterByBlockHash(hash)
+	if err != nil {
+		context := "Failed to fetch committed filter"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if filter == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found in the main chain",
+		}
+	}
+
+	return hex.EncodeToString(filter), nil
+}
+
 // handleGetBlockHeader implements the getblockheader command.
 func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetBlockHeaderCmd)
@@ -1504,6 +2111,14 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		targetDifficulty = fmt.Sprintf("%064x",
 			blockchain.CompactToBig(msgBlock.Header.Bits))
 
+		// Determine which validate keys are able to sign a block built
+		// from the new template.
+		validateKeys, err := validateKeyStatuses(s.server.blockManager.chain)
+		if err != nil {
+			context := "Failed to check validate keys"
+			return internalRPCError(err.Error(), context)
+		}
+
 		// Get the minimum allowed timestamp for the block based on the
 		// median timestamp of the last several blocks per the chain
 		// consensus rules.
@@ -1513,6 +2128,7 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 		// Update work state to ensure another block template isn't
 		// generated until needed.
 		state.template = template
+		state.validateKeys = validateKeys
 		state.lastGenerated = time.Now()
 		state.lastTxUpdate = lastTxUpdate
 		state.prevHash = latestHash
@@ -1553,10 +2169,12 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 			template.Block.Transactions[0].TxOut[0].PkScript = pkScript
 			template.ValidPayAddress = true
 
-			// Update the merkle root.
+			// Update the merkle root and the size of the block
+			// since the coinbase changed.
 			block := provautil.NewBlock(template.Block)
 			merkles := blockchain.BuildMerkleTreeStore(block.Transactions())
 			template.Block.Header.MerkleRoot = *merkles[len(merkles)-1]
+			template.Block.Header.Size = uint32(template.Block.SerializeSize())
 		}
 
 		// Set locals for convenience.
@@ -1578,6 +2196,29 @@ func (state *gbtWorkState) updateBlockTemplate(s *rpcServer, useCoinbaseValue bo
 	return nil
 }
 
+// validateKeyStatuses returns the active validate keys as of the current best
+// chain along with whether each of them is rate limited from signing the next
+// block.  This allows external block builders to choose a key which is able to
+// sign a block built from the template.
+func validateKeyStatuses(chain *blockchain.BlockChain) ([]btcjson.GetBlockTemplateResultValidateKey, error) {
+	validateKeySet := chain.AdminKeySets()[btcec.ValidateKeySet]
+	statuses := make([]btcjson.GetBlockTemplateResultValidateKey, 0,
+		len(validateKeySet))
+	for _, pubKey := range validateKeySet {
+		var validatePubKey wire.BlockValidatingPubKey
+		copy(validatePubKey[:], pubKey.SerializeCompressed())
+		isRateLimited, err := chain.IsValidateKeyRateLimited(validatePubKey)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, btcjson.GetBlockTemplateResultValidateKey{
+			PubKey:      hex.EncodeToString(validatePubKey[:]),
+			RateLimited: isRateLimited,
+		})
+	}
+	return statuses, nil
+}
+
 // blockTemplateResult returns the current block template associated with the
 // state as a btcjson.GetBlockTemplateResult that is ready to be encoded to JSON
 // and returned to the caller.
@@ -1605,9 +2246,15 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 
 	// Convert each transaction in the block template to a template result
 	// transaction.  The result does not include the coinbase, so notice
-	// the adjustments to the various lengths and indices.
+	// the adjustments to the various lengths and indices.  The admin
+	// transactions which directly follow the coinbase are returned in a
+	// separate section since they must be included in the final block.
 	numTx := len(msgBlock.Transactions)
-	transactions := make([]btcjson.GetBlockTemplateResultTx, 0, numTx-1)
+	numAdminTx := template.NumAdminTxns
+	adminTransactions := make([]btcjson.GetBlockTemplateResultTx, 0,
+		numAdminTx)
+	transactions := make([]btcjson.GetBlockTemplateResultTx, 0,
+		numTx-numAdminTx-1)
 	txIndex := make(map[chainhash.Hash]int64, numTx)
 	for i, tx := range msgBlock.Transactions {
 		txHash := tx.TxHash()
@@ -1649,6 +2296,11 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 			Fee:     template.Fees[i],
 			SigOps:  template.SigOpCounts[i],
 		}
+		if i <= numAdminTx {
+			resultTx.Required = true
+			adminTransactions = append(adminTransactions, resultTx)
+			continue
+		}
 		transactions = append(transactions, resultTx)
 	}
 
@@ -1659,22 +2311,25 @@ func (state *gbtWorkState) blockTemplateResult(useCoinbaseValue bool, submitOld
 	targetDifficulty := fmt.Sprintf("%064x", blockchain.CompactToBig(header.Bits))
 	templateID := encodeTemplateID(state.prevHash, state.lastGenerated)
 	reply := btcjson.GetBlockTemplateResult{
-		Bits:         strconv.FormatInt(int64(header.Bits), 16),
-		CurTime:      header.Timestamp.Unix(),
-		Height:       int64(template.Height),
-		PreviousHash: header.PrevBlock.String(),
-		SigOpLimit:   blockchain.MaxSigOpsPerBlock,
-		SizeLimit:    wire.MaxBlockPayload,
-		Transactions: transactions,
-		Version:      header.Version,
-		LongPollID:   templateID,
-		SubmitOld:    submitOld,
-		Target:       targetDifficulty,
-		MinTime:      state.minTimestamp.Unix(),
-		MaxTime:      maxTime.Unix(),
-		Mutable:      gbtMutableFields,
-		NonceRange:   gbtNonceRange,
-		Capabilities: gbtCapabilities,
+		Bits:              strconv.FormatInt(int64(header.Bits), 16),
+		CurTime:           header.Timestamp.Unix(),
+		Height:            int64(template.Height),
+		PreviousHash:      header.PrevBlock.String(),
+		SigOpLimit:        blockchain.MaxSigOpsPerBlock,
+		SizeLimit:         wire.MaxBlockPayload,
+		Transactions:      transactions,
+		Version:           header.Version,
+		AdminTransactions: adminTransactions,
+		Size:              int64(header.Size),
+		ValidateKeys:      state.validateKeys,
+		LongPollID:        templateID,
+		SubmitOld:         submitOld,
+		Target:            targetDifficulty,
+		MinTime:           state.minTimestamp.Unix(),
+		MaxTime:           maxTime.Unix(),
+		Mutable:           gbtMutableFields,
+		NonceRange:        gbtNonceRange,
+		Capabilities:      gbtCapabilities,
 	}
 	if useCoinbaseValue {
 		reply.CoinbaseAux = gbtCoinbaseAux
@@ -1992,6 +2647,18 @@ func chainErrToGBTErrString(err error) string {
 		return "invalid-validate-key"
 	case blockchain.ErrFeeTooHigh:
 		return "bad-txns-highfee"
+	case blockchain.ErrUnknownKeyID:
+		return "bad-txns-unknown-keyid"
+	case blockchain.ErrKeyIDRevoked:
+		return "bad-txns-revoked-keyid"
+	case blockchain.ErrThreadOutOfOrder:
+		return "bad-txns-thread-order"
+	case blockchain.ErrInvalidAdminOp:
+		return "bad-txns-admin-op"
+	case blockchain.ErrValidateKeyRateLimited:
+		return "validate-key-rate-limited"
+	case blockchain.ErrPrevBlockNotBest:
+		return "inconclusive-not-best-prevblk"
 	}
 
 	return "rejected: " + err.Error()
@@ -2040,9 +2707,11 @@ func handleGetBlockTemplateProposal(s *rpcServer, request *btcjson.TemplateReque
 		return "bad-prevblk", nil
 	}
 
-	flags := blockchain.BFDryRun | blockchain.BFNoPoWCheck
-	isOrphan, err := s.server.blockManager.ProcessBlock(block, flags)
-	if err != nil {
+	// Fully validate the proposed block against the current chain tip.
+	// Proposals from external block builders are typically checked before
+	// they are signed, in which case the block signature and validate key
+	// checks are skipped.
+	if err := s.chain.CheckConnectBlockTemplate(block); err != nil {
 		if _, ok := err.(blockchain.RuleError); !ok {
 			err := rpcsLog.Errorf("Failed to process block "+
 				"proposal: %v", err)
@@ -2055,9 +2724,6 @@ func handleGetBlockTemplateProposal(s *rpcServer, request *btcjson.TemplateReque
 		rpcsLog.Infof("Rejected block proposal: %v", err)
 		return chainErrToGBTErrString(err), nil
 	}
-	if isOrphan {
-		return "orphan", nil
-	}
 
 	return nil, nil
 }
@@ -2203,8 +2869,11 @@ func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	}
 
 	ret := &btcjson.GetMempoolInfoResult{
-		Size:  int64(len(mempoolTxns)),
-		Bytes: numBytes,
+		Size:          int64(len(mempoolTxns)),
+		Bytes:         numBytes,
+		MaxMempool:    int64(cfg.MaxMempool) * 1000000,
+		MempoolMinFee: s.server.txMemPool.DynamicMinFee().ToRMG(),
+		MinRelayTxFee: cfg.minRelayTxFee.ToRMG(),
 	}
 
 	return ret, nil
@@ -2350,6 +3019,36 @@ func handleGetNetworkHashPS(s *rpcServer, cmd interface{}, closeChan <-chan stru
 	return hashesPerSec.Int64(), nil
 }
 
+// createNoticeResult converts the passed network notice into a result which is
+// used by the getnotices command and the notice websocket notification.
+func createNoticeResult(notice *wire.MsgNotice) *btcjson.NoticeResult {
+	signers := make([]string, 0, len(notice.Signatures))
+	for i := range notice.Signatures {
+		signers = append(signers,
+			hex.EncodeToString(notice.Signatures[i].PubKey[:]))
+	}
+
+	return &btcjson.NoticeResult{
+		Hash:       notice.NoticeHash().String(),
+		KeySet:     btcec.KeySetType(notice.KeySet).String(),
+		Timestamp:  notice.Timestamp.Unix(),
+		Expiration: notice.Expiration.Unix(),
+		Priority:   notice.Priority,
+		Message:    notice.Message,
+		Signers:    signers,
+	}
+}
+
+// handleGetNotices implements the getnotices command.
+func handleGetNotices(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	notices := s.server.ActiveNotices()
+	results := make([]*btcjson.NoticeResult, 0, len(notices))
+	for _, notice := range notices {
+		results = append(results, createNoticeResult(notice))
+	}
+	return results, nil
+}
+
 // handleGetPeerInfo implements the getpeerinfo command.
 func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	peers := s.server.Peers()
@@ -2378,6 +3077,8 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 			BanScore:       int32(p.banScore.Int()),
 			FeeFilter:      atomic.LoadInt64(&p.feeFilter),
 			SyncNode:       p == syncPeer,
+			AuthIdentity:   statsSnap.AuthIdentity,
+			Encrypted:      statsSnap.Encrypted,
 		}
 		if p.LastPingNonce() != 0 {
 			wait := float64(time.Since(statsSnap.LastPingTime).Nanoseconds())
@@ -2395,15 +3096,29 @@ func handleGetRawMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	mp := s.server.txMemPool
 
 	if c.Verbose != nil && *c.Verbose {
-		return mp.RawMempoolVerbose(), nil
+		result := mp.RawMempoolVerbose()
+
+		// Only include the transactions with the requested tag when
+		// one was provided.
+		if c.Tag != nil {
+			for hash, entry := range result {
+				if entry.Tag != *c.Tag {
+					delete(result, hash)
+				}
+			}
+		}
+		return result, nil
 	}
 
 	// The response is simply an array of the transaction hashes if the
 	// verbose flag is not set.
 	descs := mp.TxDescs()
-	hashStrings := make([]string, len(descs))
-	for i := range hashStrings {
-		hashStrings[i] = descs[i].Tx.Hash().String()
+	hashStrings := make([]string, 0, len(descs))
+	for _, desc := range descs {
+		if c.Tag != nil && uint64(desc.Tag) != *c.Tag {
+			continue
+		}
+		hashStrings = append(hashStrings, desc.Tx.Hash().String())
 	}
 
 	return hashStrings, nil
@@ -2529,6 +3244,50 @@ func handleGetRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan str
 	return *rawTxn, nil
 }
 
+// handleGetRevokeImpact implements the getrevokeimpact command.
+func handleGetRevokeImpact(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetRevokeImpactCmd)
+	numToSkip, numRequested := normalizeSkipCount(c.Skip, c.Count)
+	impact, revoked, err := fetchRevokeImpact(s, btcec.KeyID(c.KeyID),
+		numToSkip, numRequested)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &btcjson.RevokeImpactResult{
+		KeyID:     c.KeyID,
+		Revoked:   revoked,
+		Addresses: make([]btcjson.RevokeImpactAddressResult, 0, len(impact.groups)),
+	}
+	var total, spendable provautil.Amount
+	for _, group := range impact.groups {
+		addrResult := btcjson.RevokeImpactAddressResult{
+			ScriptPubKey:  hex.EncodeToString(group.pkScript),
+			RequiredSigs:  int32(group.requiredSigs),
+			AvailableKeys: int32(group.availableKeys),
+			Spendable:     group.spendable(),
+			TotalCount:    group.count,
+			TotalValue:    group.total.ToRMG(),
+			Utxos:         group.utxos,
+		}
+		if group.address != nil {
+			addrResult.Address = group.address.EncodeAddress()
+		}
+		result.Addresses = append(result.Addresses, addrResult)
+
+		result.TotalCount += group.count
+		total += group.total
+		if group.spendable() {
+			spendable += group.total
+		}
+	}
+	result.TotalValue = total.ToRMG()
+	result.SpendableValue = spendable.ToRMG()
+	result.UnspendableValue = (total - spendable).ToRMG()
+
+	return result, nil
+}
+
 // handleGetTxOut handles gettxout commands.
 func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetTxOutCmd)
@@ -2677,6 +3436,141 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return help, nil
 }
 
+// keyIDIndexDisabledError is returned by the commands which require the keyID
+// index when it is not enabled.
+var keyIDIndexDisabledError = &btcjson.RPCError{
+	Code:    btcjson.ErrRPCMisc,
+	Message: "KeyID index must be enabled (--keyidindex)",
+}
+
+// normalizeSkipCount returns the number of entries to skip and the number of
+// entries requested by the passed optional command parameters, applying the
+// same defaults and limits as searchrawtransactions.
+func normalizeSkipCount(skip, count *int) (uint32, uint32) {
+	numRequested := 100
+	if count != nil {
+		numRequested = *count
+		if numRequested < 0 {
+			numRequested = 1
+		}
+	}
+
+	var numToSkip int
+	if skip != nil {
+		numToSkip = *skip
+		if numToSkip < 0 {
+			numToSkip = 0
+		}
+	}
+	return uint32(numToSkip), uint32(numRequested)
+}
+
+// handleListKeyIDUtxos implements the listkeyidutxos command.
+func handleListKeyIDUtxos(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the keyID index is not enabled.
+	keyIDIndex := s.server.keyIDIndex
+	if keyIDIndex == nil {
+		return nil, keyIDIndexDisabledError
+	}
+
+	c := cmd.(*btcjson.ListKeyIDUtxosCmd)
+	numToSkip, numRequested := normalizeSkipCount(c.Skip, c.Count)
+	utxos, count, total, err := keyIDIndex.UtxosForKeyID(
+		btcec.KeyID(c.KeyID), numToSkip, numRequested)
+	if err != nil {
+		context := "Failed to load keyID index entries"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	best := s.chain.BestSnapshot()
+	result := &btcjson.ListKeyIDUtxosResult{
+		KeyID:      c.KeyID,
+		TotalCount: count,
+		TotalValue: total.ToRMG(),
+		Utxos:      make([]btcjson.KeyIDUtxoResult, 0, len(utxos)),
+	}
+	for _, utxo := range utxos {
+		utxoResult := btcjson.KeyIDUtxoResult{
+			TxID:   utxo.OutPoint.Hash.String(),
+			Vout:   utxo.OutPoint.Index,
+			Amount: utxo.Amount.ToRMG(),
+		}
+
+		// The entry might have been spent since the index was queried,
+		// in which case only the details from the index are returned.
+		entry, err := s.chain.FetchUtxoEntry(&utxo.OutPoint.Hash)
+		if err != nil {
+			context := "Failed to fetch utxo"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		if entry != nil && !entry.IsOutputSpent(utxo.OutPoint.Index) {
+			pkScript := entry.PkScriptByIndex(utxo.OutPoint.Index)
+			_, addrs, _, _ := txscript.ExtractPkScriptAddrs(pkScript,
+				s.server.chainParams)
+			if len(addrs) > 0 {
+				utxoResult.Address = addrs[0].EncodeAddress()
+			}
+			utxoResult.ScriptPubKey = hex.EncodeToString(pkScript)
+			utxoResult.Confirmations = int64(1 + best.Height -
+				entry.BlockHeight())
+		}
+		result.Utxos = append(result.Utxos, utxoResult)
+	}
+
+	return result, nil
+}
+
+// handleLoadMempool implements the loadmempool command.
+func handleLoadMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	accepted, rejected, err := s.server.loadMempool()
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to load mempool: %v", err),
+		}
+	}
+	s.server.AnnounceNewTransactions(accepted)
+
+	return &btcjson.LoadMempoolResult{
+		Accepted: len(accepted),
+		Rejected: rejected,
+	}, nil
+}
+
+// handleLoadTxOutSet implements the loadtxoutset command.
+func handleLoadTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LoadTxOutSetCmd)
+
+	// The optional indexes are built from the block history a snapshot
+	// does not contain, so refuse to load one while any of them is
+	// enabled, the same as the --loadsnapshot option.
+	if s.server.txIndex != nil || s.server.addrIndex != nil ||
+		s.server.keyIDIndex != nil || s.server.cfIndex != nil {
+
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCMisc,
+			Message: "A snapshot may not be loaded while the " +
+				"transaction, address, key id or committed " +
+				"filter index is enabled",
+		}
+	}
+
+	path := cleanAndExpandPath(c.Path)
+	info, err := s.server.blockManager.LoadUtxoSnapshot(path)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to load snapshot: %v", err),
+		}
+	}
+
+	// The node no longer has the full block history, so stop advertising
+	// that it serves it.
+	s.server.limitServices()
+
+	return snapshotResult(path, info), nil
+}
+
 // handlePing implements the ping command.
 func handlePing(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Ask server to ping \o_
@@ -2907,6 +3801,118 @@ func fetchMempoolTxnsForAddress(s *rpcServer, addr provautil.Address, numToSkip,
 	return mpTxns[numToSkip:rangeEnd], numToSkip
 }
 
+// handleSaveMempool implements the savemempool command.
+func handleSaveMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	if _, err := s.server.saveMempool(); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Failed to save mempool: %v", err),
+		}
+	}
+
+	return nil, nil
+}
+
+// handleSearchKeyIDTransactions implements the searchkeyidtransactions command.
+func handleSearchKeyIDTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Respond with an error if the keyID index is not enabled.
+	keyIDIndex := s.server.keyIDIndex
+	if keyIDIndex == nil {
+		return nil, keyIDIndexDisabledError
+	}
+
+	// Just return now if the number of requested entries is zero to avoid
+	// extra work.
+	c := cmd.(*btcjson.SearchKeyIDTransactionsCmd)
+	numToSkip, numRequested := normalizeSkipCount(c.Skip, c.Count)
+	if numRequested == 0 {
+		return nil, nil
+	}
+	var reverse bool
+	if c.Reverse != nil {
+		reverse = *c.Reverse
+	}
+
+	// Fetch the confirmed transactions which involve the keyID.  Unlike
+	// searchrawtransactions, there is no unconfirmed index to consult.
+	var retrieved []retrievedTx
+	err := s.server.db.View(func(dbTx database.Tx) error {
+		regions, _, err := keyIDIndex.TxRegionsForKeyID(dbTx,
+			btcec.KeyID(c.KeyID), numToSkip, numRequested, reverse)
+		if err != nil {
+			return err
+		}
+
+		serializedTxns, err := dbTx.FetchBlockRegions(regions)
+		if err != nil {
+			return err
+		}
+		for i, serializedTx := range serializedTxns {
+			retrieved = append(retrieved, retrievedTx{
+				txBytes: serializedTx,
+				blkHash: regions[i].Hash,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		context := "Failed to load keyID index entries"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// The keyID has never been used if there are no results.
+	if len(retrieved) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoTxInfo,
+			Message: "No information available about keyID",
+		}
+	}
+
+	// When not in verbose mode, simply return a list of serialized txns.
+	if c.Verbose != nil && *c.Verbose == 0 {
+		hexTxns := make([]string, len(retrieved))
+		for i := range retrieved {
+			hexTxns[i] = hex.EncodeToString(retrieved[i].txBytes)
+		}
+		return hexTxns, nil
+	}
+
+	best := s.chain.BestSnapshot()
+	results := make([]btcjson.TxRawResult, 0, len(retrieved))
+	for i := range retrieved {
+		rtx := &retrieved[i]
+		var mtx wire.MsgTx
+		err := mtx.Deserialize(bytes.NewReader(rtx.txBytes))
+		if err != nil {
+			context := "Failed to deserialize transaction"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		header, err := s.chain.FetchHeader(rtx.blkHash)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+		height, err := s.chain.BlockHeightByHash(rtx.blkHash)
+		if err != nil {
+			context := "Failed to obtain block height"
+			return nil, internalRPCError(err.Error(), context)
+		}
+
+		result, err := createTxRawResult(s.server.chainParams, &mtx,
+			mtx.TxHash().String(), &header, rtx.blkHash.String(),
+			height, best.Height)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
 // handleSearchRawTransactions implements the searchrawtransactions command.
 func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if the address index is not enabled.
@@ -3171,9 +4177,50 @@ func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan
 	return srtList, nil
 }
 
-// handleSendRawTransaction implements the sendrawtransaction command.
+// rejectErrToRPCErrorCode returns the JSON-RPC error code for the passed
+// transaction rejection error.  Violations of the Prova specific rules are
+// given distinct codes so clients can identify them, while all other errors
+// use the deserialization error code (to match bitcoind behavior).
+func rejectErrToRPCErrorCode(err error) btcjson.RPCErrorCode {
+	code, _ := mempool.ErrToRejectErr(err)
+	switch code {
+	case wire.RejectUnknownKeyID:
+		return btcjson.ErrRPCUnknownKeyID
+	case wire.RejectKeyIDRevoked:
+		return btcjson.ErrRPCKeyIDRevoked
+	case wire.RejectThreadOrder:
+		return btcjson.ErrRPCThreadOutOfOrder
+	case wire.RejectSupplyUnderflow:
+		return btcjson.ErrRPCSupplyUnderflow
+	case wire.RejectInvalidAdminOp:
+		return btcjson.ErrRPCInvalidAdminOp
+	case wire.RejectRateLimited:
+		return btcjson.ErrRPCValidateKeyRateLimited
+	case wire.RejectHighFee:
+		return btcjson.ErrRPCFeeTooHigh
+	}
+
+	return btcjson.ErrRPCDeserialization
+}
+
+// handleSendRawTransaction implements the sendrawtransaction command for admin
+// users.
 func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
-	c := cmd.(*btcjson.SendRawTransactionCmd)
+	return sendRawTransaction(s, cmd.(*btcjson.SendRawTransactionCmd), true)
+}
+
+// handleLimitedSendRawTransaction implements the sendrawtransaction command for
+// limited users.  Their transactions keep the tag they are submitted with, but
+// the memory pool policy overrides configured for the tag are not applied.
+func handleLimitedSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return sendRawTransaction(s, cmd.(*btcjson.SendRawTransactionCmd), false)
+}
+
+// sendRawTransaction submits the transaction of the passed command to the
+// memory pool and relays it when it is accepted.  The memory pool policy
+// overrides for the tag of the transaction are only applied when the caller is
+// trusted.
+func sendRawTransaction(s *rpcServer, c *btcjson.SendRawTransactionCmd, trusted bool) (interface{}, error) {
 	// Deserialize and send off to tx relay
 	hexStr := c.HexTx
 	if len(hexStr)%2 != 0 {
@@ -3192,16 +4239,23 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 		}
 	}
 
-	// User 0 for the tag to represent local node
+	// Use the tag provided by the caller, which defaults to 0 to represent
+	// the local node, so the transaction can be identified in the memory
+	// pool.
+	origin := mempool.TxOrigin{Trusted: trusted}
+	if c.Tag != nil {
+		origin.Tag = mempool.Tag(*c.Tag)
+	}
 	tx := provautil.NewTx(&msgTx)
-	acceptedTxs, err := s.server.txMemPool.ProcessTransaction(tx, false, false, 0)
+	acceptedTxs, err := s.server.txMemPool.ProcessTransaction(tx, false,
+		false, origin)
 	if err != nil {
 		// When the error is a rule error, it means the transaction was
 		// simply rejected as opposed to something actually going wrong,
 		// so log it as such.  Otherwise, something really did go wrong,
 		// so log it as an actual error.  In both cases, a JSON-RPC
-		// error is returned to the client with the deserialization
-		// error code (to match bitcoind behavior).
+		// error is returned to the client with the error code for the
+		// rule which was violated.
 		if _, ok := err.(mempool.RuleError); ok {
 			rpcsLog.Debugf("Rejected transaction %v: %v", tx.Hash(),
 				err)
@@ -3210,7 +4264,7 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 				tx.Hash(), err)
 		}
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCDeserialization,
+			Code:    rejectErrToRPCErrorCode(err),
 			Message: "TX rejected: " + err.Error(),
 		}
 	}
@@ -3241,6 +4295,44 @@ func handleSendRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan st
 	return tx.Hash().String(), nil
 }
 
+// handleSendNotice implements the sendnotice command.
+func handleSendNotice(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SendNoticeCmd)
+	hexStr := c.HexNotice
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	serializedNotice, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	var notice wire.MsgNotice
+	err = notice.BtcDecode(bytes.NewReader(serializedNotice),
+		wire.ProtocolVersion)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Notice decode failed: " + err.Error(),
+		}
+	}
+
+	// The notice is only stored and relayed when it is signed by a quorum
+	// of the keys of the root or provision key set.
+	if _, err := s.server.AddNotice(&notice, nil); err != nil {
+		if _, ok := err.(blockchain.RuleError); ok {
+			rpcsLog.Debugf("Rejected notice %v: %v",
+				notice.NoticeHash(), err)
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCVerify,
+				Message: "Notice rejected: " + err.Error(),
+			}
+		}
+		return nil, internalRPCError(err.Error(), "Could not add notice")
+	}
+
+	return notice.NoticeHash().String(), nil
+}
+
 // handleSetGenerate implements the setgenerate command.
 func handleSetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SetGenerateCmd)
@@ -3330,6 +4422,59 @@ func handleStop(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 	return "Prova stopping.", nil
 }
 
+// setBlockSignature sets the passed hex-encoded validating public key and
+// signature in the passed block header.  Both must be provided and must be the
+// exact size of the respective header fields.
+func setBlockSignature(header *wire.BlockHeader, pubKeyStr, sigStr string) error {
+	if pubKeyStr == "" || sigStr == "" {
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Both the validating public key and the " +
+				"signature must be provided",
+		}
+	}
+
+	pubKey, err := hex.DecodeString(pubKeyStr)
+	if err != nil {
+		return rpcDecodeHexError(pubKeyStr)
+	}
+	if len(pubKey) != wire.BlockValidatingPubKeySize {
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Validating public key must be "+
+				"%d bytes, got %d", wire.BlockValidatingPubKeySize,
+				len(pubKey)),
+		}
+	}
+	if _, err := btcec.ParsePubKey(pubKey, btcec.S256()); err != nil {
+		return &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid validating public key: " + err.Error(),
+		}
+	}
+
+	// The signature is serialized in DER format which is shorter than the
+	// header field, so it is zero padded like the signatures created by
+	// signing the header directly.
+	sig, err := hex.DecodeString(sigStr)
+	if err != nil {
+		return rpcDecodeHexError(sigStr)
+	}
+	if len(sig) > wire.BlockSignatureSize {
+		return &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Signature must be at most %d "+
+				"bytes, got %d", wire.BlockSignatureSize,
+				len(sig)),
+		}
+	}
+
+	copy(header.ValidatingPubKey[:], pubKey)
+	header.Signature = wire.BlockSignature{}
+	copy(header.Signature[:], sig)
+	return nil
+}
+
 // handleSubmitBlock implements the submitblock command.
 func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SubmitBlockCmd)
@@ -3344,7 +4489,8 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 		return nil, rpcDecodeHexError(hexStr)
 	}
 
-	block, err := provautil.NewBlockFromBytes(serializedBlock)
+	var msgBlock wire.MsgBlock
+	err = msgBlock.Deserialize(bytes.NewReader(serializedBlock))
 	if err != nil {
 		return nil, &btcjson.RPCError{
 			Code:    btcjson.ErrRPCDeserialization,
@@ -3352,6 +4498,19 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 		}
 	}
 
+	// Set the validating public key and signature in the header when the
+	// block was built from a template and signed externally.
+	if c.Options != nil && (c.Options.ValidatingPubKey != "" ||
+		c.Options.Signature != "") {
+
+		err := setBlockSignature(&msgBlock.Header,
+			c.Options.ValidatingPubKey, c.Options.Signature)
+		if err != nil {
+			return nil, err
+		}
+	}
+	block := provautil.NewBlock(&msgBlock)
+
 	_, err = s.server.blockManager.ProcessBlock(block, blockchain.BFNone)
 	if err != nil {
 		return fmt.Sprintf("rejected: %s", err.Error()), nil
@@ -3361,6 +4520,99 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return nil, nil
 }
 
+// handleTestMempoolAccept implements the testmempoolaccept command for admin
+// users.
+func handleTestMempoolAccept(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return testMempoolAccept(s, cmd.(*btcjson.TestMempoolAcceptCmd), true)
+}
+
+// handleLimitedTestMempoolAccept implements the testmempoolaccept command for
+// limited users, which doesn't apply the memory pool policy overrides
+// configured for the tag, the same as sendrawtransaction.
+func handleLimitedTestMempoolAccept(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return testMempoolAccept(s, cmd.(*btcjson.TestMempoolAcceptCmd), false)
+}
+
+// testMempoolAccept tests whether the transactions of the passed command would
+// be accepted into the memory pool.  The memory pool policy overrides for the
+// tag are only applied when the caller is trusted.
+func testMempoolAccept(s *rpcServer, c *btcjson.TestMempoolAcceptCmd, trusted bool) (interface{}, error) {
+	// Limit the number of transactions to the maximum number of
+	// transactions in a package configured for the memory pool since all
+	// of them are validated while it is locked.  The default is used when
+	// the package limits are disabled so the time it is locked for stays
+	// bounded.
+	maxTxs := s.server.txMemPool.MaxPackageCount()
+	if maxTxs <= 0 {
+		maxTxs = mempool.DefaultMaxPackageCount
+	}
+	if len(c.RawTxs) > maxTxs {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("too many transactions to test (%d), "+
+				"the maximum is %d", len(c.RawTxs), maxTxs),
+		}
+	}
+
+	// Deserialize all of the transactions before testing any of them so a
+	// malformed transaction fails the whole request.
+	txs := make([]*provautil.Tx, 0, len(c.RawTxs))
+	for _, hexStr := range c.RawTxs {
+		if len(hexStr)%2 != 0 {
+			hexStr = "0" + hexStr
+		}
+		serializedTx, err := hex.DecodeString(hexStr)
+		if err != nil {
+			return nil, rpcDecodeHexError(hexStr)
+		}
+		var msgTx wire.MsgTx
+		err = msgTx.Deserialize(bytes.NewReader(serializedTx))
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		txs = append(txs, provautil.NewTx(&msgTx))
+	}
+
+	// Test the transactions in order with the tag provided by the caller,
+	// which defaults to 0 to represent the local node, so the same policy
+	// as sendrawtransaction is applied.
+	origin := mempool.TxOrigin{Trusted: trusted}
+	if c.Tag != nil {
+		origin.Tag = mempool.Tag(*c.Tag)
+	}
+	results := s.server.txMemPool.TestAcceptTransactions(txs, origin)
+	reply := make([]btcjson.TestMempoolAcceptResult, 0, len(results))
+	for _, result := range results {
+		entry := btcjson.TestMempoolAcceptResult{
+			TxID:    result.Tx.Hash().String(),
+			Allowed: result.Err == nil,
+		}
+		if result.Err != nil {
+			// Only rule errors mean the transaction would simply be
+			// rejected as opposed to something actually going
+			// wrong.
+			if _, ok := result.Err.(mempool.RuleError); !ok {
+				context := fmt.Sprintf("Failed to test "+
+					"transaction %v", result.Tx.Hash())
+				return nil, internalRPCError(result.Err.Error(),
+					context)
+			}
+			code, reason := mempool.ErrToRejectErr(result.Err)
+			entry.RejectCode = uint8(code)
+			entry.RejectReason = reason
+		} else {
+			fee := provautil.Amount(result.Fee).ToRMG()
+			entry.Fee = &fee
+		}
+		reply = append(reply, entry)
+	}
+
+	return reply, nil
+}
+
 // handleValidateAddress implements the validateaddress command.
 func handleValidateAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.ValidateAddressCmd)
@@ -3625,8 +4877,14 @@ type parsedRPCCmd struct {
 // standardCmdResult checks that a parsed command is a standard Bitcoin JSON-RPC
 // command and runs the appropriate handler to reply to the command.  Any
 // commands which are not recognized or not implemented will return an error
-// suitable for use in replies.
-func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}) (interface{}, error) {
+// suitable for use in replies.  Limited users are served by the handlers in
+// rpcLimitedHandlers for the commands which have one.
+func (s *rpcServer) standardCmdResult(cmd *parsedRPCCmd, closeChan <-chan struct{}, isAdmin bool) (interface{}, error) {
+	if !isAdmin {
+		if handler, ok := rpcLimitedHandlers[cmd.method]; ok {
+			return handler(s, cmd.cmd, closeChan)
+		}
+	}
 	handler, ok := rpcHandlers[cmd.method]
 	if ok {
 		goto handled
@@ -3800,7 +5058,8 @@ func (s *rpcServer) jsonRPCRead(w http.ResponseWriter, r *http.Request, isAdmin
 			if parsedCmd.err != nil {
 				jsonErr = parsedCmd.err
 			} else {
-				result, jsonErr = s.standardCmdResult(parsedCmd, closeChan)
+				result, jsonErr = s.standardCmdResult(parsedCmd,
+					closeChan, isAdmin)
 			}
 		}
 	}
@@ -3941,7 +5200,7 @@ func newRPCServer(listenAddrs []string, generator *mining.BlkTmplGenerator, s *s
 		gbtWorkState:           newGbtWorkState(s.timeSource),
 		helpCacher:             newHelpCacher(),
 		requestProcessShutdown: make(chan struct{}),
-		quit: make(chan int),
+		quit:                   make(chan int),
 	}
 	if cfg.RPCUser != "" && cfg.RPCPass != "" {
 		login := cfg.RPCUser + ":" + cfg.RPCPass
@@ -0,0 +1,396 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/bitgo/prova/blockchain/indexers"
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/btcjson"
+	"github.com/bitgo/prova/chaincfg"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/bitgo/prova/mempool"
+	"github.com/bitgo/prova/provautil"
+	"github.com/bitgo/prova/txscript"
+	"github.com/bitgo/prova/wire"
+)
+
+// newTestProvaScript returns a standard Prova public key script which is
+// secured by the passed keyIDs.
+func newTestProvaScript(t *testing.T, keyIDs ...btcec.KeyID) []byte {
+	addr, err := provautil.NewAddressProva(make([]byte, 20), keyIDs,
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressProva: unexpected error: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	return pkScript
+}
+
+// TestRevokeImpact ensures the unspent outputs secured by a revoked keyID are
+// grouped by script, that the groups which still have enough provisioned keys
+// are spendable, and that only the requested outputs are kept.
+func TestRevokeImpact(t *testing.T) {
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("NewPrivateKey: unexpected error: %v", err)
+	}
+	keyIDs := btcec.KeyIdMap{
+		1: privKey.PubKey(),
+		2: privKey.PubKey(),
+	}
+
+	// Revoking keyID 1 leaves the account key and keyID 2 for the first
+	// script, while keyID 3 of the second script is not provisioned.
+	spendableScript := newTestProvaScript(t, 1, 2)
+	unspendableScript := newTestProvaScript(t, 1, 3)
+	utxos := []struct {
+		pkScript []byte
+		amount   provautil.Amount
+		inPage   bool
+	}{
+		{pkScript: spendableScript, amount: 100, inPage: false},
+		{pkScript: unspendableScript, amount: 200, inPage: true},
+		{pkScript: spendableScript, amount: 300, inPage: true},
+		{pkScript: spendableScript, amount: 400, inPage: true},
+		{pkScript: unspendableScript, amount: 500, inPage: false},
+	}
+
+	impact := newRevokeImpact(1, keyIDs, &chaincfg.RegressionNetParams)
+	for i, test := range utxos {
+		utxo := &indexers.KeyIDUtxo{
+			OutPoint: wire.OutPoint{
+				Hash:  chainhash.Hash{byte(i)},
+				Index: uint32(i),
+			},
+			Amount: test.amount,
+		}
+		err := impact.addUtxo(utxo, test.pkScript, 1, test.inPage)
+		if err != nil {
+			t.Fatalf("addUtxo #%d: unexpected error: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		pkScript      []byte
+		availableKeys int
+		spendable     bool
+		count         uint32
+		total         provautil.Amount
+		amounts       []provautil.Amount
+	}{
+		{
+			pkScript:      spendableScript,
+			availableKeys: 2,
+			spendable:     true,
+			count:         3,
+			total:         800,
+			amounts:       []provautil.Amount{300, 400},
+		},
+		{
+			pkScript:      unspendableScript,
+			availableKeys: 1,
+			spendable:     false,
+			count:         2,
+			total:         700,
+			amounts:       []provautil.Amount{200},
+		},
+	}
+	if len(impact.groups) != len(tests) {
+		t.Fatalf("unexpected number of groups - got %d, want %d",
+			len(impact.groups), len(tests))
+	}
+	for i, test := range tests {
+		group := impact.groups[i]
+		if string(group.pkScript) != string(test.pkScript) {
+			t.Errorf("group #%d: unexpected script - got %x, want %x",
+				i, group.pkScript, test.pkScript)
+			continue
+		}
+		if group.requiredSigs != 2 {
+			t.Errorf("group #%d: unexpected required signatures - "+
+				"got %d, want 2", i, group.requiredSigs)
+		}
+		if group.availableKeys != test.availableKeys {
+			t.Errorf("group #%d: unexpected available keys - got %d, "+
+				"want %d", i, group.availableKeys,
+				test.availableKeys)
+		}
+		if group.spendable() != test.spendable {
+			t.Errorf("group #%d: unexpected spendable - got %v, want "+
+				"%v", i, group.spendable(), test.spendable)
+		}
+		if group.count != test.count || group.total != test.total {
+			t.Errorf("group #%d: unexpected totals - got %d (%v), "+
+				"want %d (%v)", i, group.count, group.total,
+				test.count, test.total)
+		}
+		if len(group.utxos) != len(test.amounts) ||
+			len(group.outPoints) != len(test.amounts) {
+
+			t.Errorf("group #%d: unexpected number of kept outputs - "+
+				"got %d, want %d", i, len(group.outPoints),
+				len(test.amounts))
+			continue
+		}
+		for j, amount := range test.amounts {
+			if group.amounts[j] != amount {
+				t.Errorf("group #%d: unexpected amount #%d - got "+
+					"%v, want %v", i, j, group.amounts[j],
+					amount)
+			}
+		}
+	}
+}
+
+// TestCreateRecoveryTransactions ensures the outputs of a group are split
+// across transactions according to the signatures they require and that the
+// fees of the transactions are deducted from their outputs and capped.
+func TestCreateRecoveryTransactions(t *testing.T) {
+	// newGroup returns a group with the passed number of outputs which
+	// require the passed number of signatures.
+	newGroup := func(numOutputs, requiredSigs int, amount provautil.Amount) *revokeImpactGroup {
+		group := &revokeImpactGroup{requiredSigs: requiredSigs}
+		for i := 0; i < numOutputs; i++ {
+			group.outPoints = append(group.outPoints, wire.OutPoint{
+				Hash:  chainhash.Hash{byte(i), byte(i >> 8)},
+				Index: uint32(i),
+			})
+			group.amounts = append(group.amounts, amount)
+		}
+		return group
+	}
+
+	// The outputs requiring a single signature have smaller signature
+	// scripts, so more of them fit in a transaction.
+	if maxRecoveryInputs(1) <= maxRecoveryInputs(2) {
+		t.Fatalf("maxRecoveryInputs: unexpected limits - got %d for 1 "+
+			"signature and %d for 2 signatures",
+			maxRecoveryInputs(1), maxRecoveryInputs(2))
+	}
+
+	pkScript := newTestProvaScript(t, 2, 3)
+	const maxFee = provautil.Amount(5000000)
+	tests := []struct {
+		name         string
+		numOutputs   int
+		requiredSigs int
+		feeRate      provautil.Amount
+		numInputs    []int
+		capped       bool
+	}{
+		{
+			name:         "single transaction",
+			numOutputs:   3,
+			requiredSigs: 2,
+			feeRate:      1000,
+			numInputs:    []int{3},
+		},
+		{
+			name:         "split by two signatures",
+			numOutputs:   maxRecoveryInputs(2) + 1,
+			requiredSigs: 2,
+			feeRate:      1000,
+			numInputs:    []int{maxRecoveryInputs(2), 1},
+		},
+		{
+			name:         "not split by one signature",
+			numOutputs:   maxRecoveryInputs(2) + 1,
+			requiredSigs: 1,
+			feeRate:      1000,
+			numInputs:    []int{maxRecoveryInputs(2) + 1},
+		},
+		{
+			name:         "capped fee",
+			numOutputs:   10,
+			requiredSigs: 2,
+			feeRate:      100000000,
+			numInputs:    []int{10},
+			capped:       true,
+		},
+	}
+
+	for _, test := range tests {
+		const amount = provautil.Amount(10000000)
+		group := newGroup(test.numOutputs, test.requiredSigs, amount)
+		txns, fees, err := createRecoveryTransactions(group, pkScript,
+			test.feeRate, maxFee)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if len(txns) != len(test.numInputs) || len(fees) != len(txns) {
+			t.Errorf("%s: unexpected number of transactions - got %d, "+
+				"want %d", test.name, len(txns),
+				len(test.numInputs))
+			continue
+		}
+		for i, mtx := range txns {
+			if len(mtx.TxIn) != test.numInputs[i] {
+				t.Errorf("%s: unexpected number of inputs of tx #%d "+
+					"- got %d, want %d", test.name, i,
+					len(mtx.TxIn), test.numInputs[i])
+				continue
+			}
+
+			wantFee := maxFee
+			if !test.capped {
+				size := mtx.SerializeSize() + len(mtx.TxIn)*
+					(recoverySigScriptSize(test.requiredSigs)+2)
+				wantFee = provautil.Amount(int64(size) *
+					int64(test.feeRate) / 1000)
+			}
+			if fees[i] != wantFee {
+				t.Errorf("%s: unexpected fee of tx #%d - got %v, "+
+					"want %v", test.name, i, fees[i], wantFee)
+			}
+			if len(mtx.TxOut) != 1 ||
+				string(mtx.TxOut[0].PkScript) != string(pkScript) {
+
+				t.Errorf("%s: unexpected outputs of tx #%d",
+					test.name, i)
+				continue
+			}
+			want := amount*provautil.Amount(len(mtx.TxIn)) - wantFee
+			if provautil.Amount(mtx.TxOut[0].Value) != want {
+				t.Errorf("%s: unexpected output value of tx #%d - "+
+					"got %v, want %v", test.name, i,
+					mtx.TxOut[0].Value, want)
+			}
+		}
+	}
+
+	// Outputs which do not cover the fee must be rejected.
+	group := newGroup(1, 2, 100)
+	_, _, err := createRecoveryTransactions(group, pkScript, 1000000, maxFee)
+	if err == nil {
+		t.Fatal("createRecoveryTransactions: did not reject outputs " +
+			"which do not cover the fee")
+	}
+}
+
+// TestLimitedHandlers ensures the commands with handlers for limited users are
+// available to limited users and have a handler for admin users as well.
+func TestLimitedHandlers(t *testing.T) {
+	for method := range rpcLimitedHandlers {
+		if _, ok := rpcLimited[method]; !ok {
+			t.Errorf("limited handler for %q which is not available "+
+				"to limited users", method)
+		}
+		if _, ok := rpcHandlersBeforeInit[method]; !ok {
+			t.Errorf("limited handler for %q which has no handler "+
+				"for admin users", method)
+		}
+	}
+}
+
+// TestLoadTxOutSetIndexes ensures a snapshot is not loaded while any of the
+// optional indexes is enabled.
+func TestLoadTxOutSetIndexes(t *testing.T) {
+	tests := []struct {
+		name   string
+		server *server
+	}{
+		{
+			name:   "transaction index",
+			server: &server{txIndex: &indexers.TxIndex{}},
+		},
+		{
+			name:   "address index",
+			server: &server{addrIndex: &indexers.AddrIndex{}},
+		},
+		{
+			name:   "key id index",
+			server: &server{keyIDIndex: &indexers.KeyIDIndex{}},
+		},
+		{
+			name:   "committed filter index",
+			server: &server{cfIndex: &indexers.CfIndex{}},
+		},
+	}
+	for _, test := range tests {
+		// The server has no block manager, so attempting to load the
+		// snapshot would panic.
+		s := &rpcServer{server: test.server}
+		cmd := btcjson.NewLoadTxOutSetCmd("snapshot.dat")
+		_, err := handleLoadTxOutSet(s, cmd, nil)
+		rerr, ok := err.(*btcjson.RPCError)
+		if !ok || rerr.Code != btcjson.ErrRPCMisc {
+			t.Errorf("handleLoadTxOutSet (%s): unexpected error - "+
+				"got %v, want %v", test.name, err,
+				btcjson.ErrRPCMisc)
+		}
+	}
+}
+
+// TestTestMempoolAcceptLimit ensures the number of transactions tested at a
+// time is limited to the maximum number of transactions in a package
+// configured for the memory pool, or the default when it is disabled.
+func TestTestMempoolAcceptLimit(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxPackageCount int
+		numTxs          int
+		wantLimited     bool
+	}{
+		{
+			name:            "below configured limit",
+			maxPackageCount: 2,
+			numTxs:          2,
+			wantLimited:     false,
+		},
+		{
+			name:            "above configured limit",
+			maxPackageCount: 2,
+			numTxs:          3,
+			wantLimited:     true,
+		},
+		{
+			name:            "above default limit when disabled",
+			maxPackageCount: 0,
+			numTxs:          mempool.DefaultMaxPackageCount + 1,
+			wantLimited:     true,
+		},
+		{
+			name:            "default limit when disabled",
+			maxPackageCount: 0,
+			numTxs:          mempool.DefaultMaxPackageCount,
+			wantLimited:     false,
+		},
+	}
+	for _, test := range tests {
+		txPool := mempool.New(&mempool.Config{
+			Policy: mempool.Policy{
+				MaxPackageCount: test.maxPackageCount,
+			},
+		})
+		s := &rpcServer{server: &server{txMemPool: txPool}}
+
+		// The transactions are malformed, so they fail to decode once
+		// they are within the limit.
+		rawTxs := make([]string, test.numTxs)
+		for i := range rawTxs {
+			rawTxs[i] = "00"
+		}
+		cmd := btcjson.NewTestMempoolAcceptCmd(rawTxs, nil)
+		_, err := testMempoolAccept(s, cmd, true)
+		rerr, ok := err.(*btcjson.RPCError)
+		if !ok {
+			t.Errorf("testMempoolAccept (%s): unexpected error %v",
+				test.name, err)
+			continue
+		}
+		limited := rerr.Code == btcjson.ErrRPCInvalidParameter
+		if limited != test.wantLimited {
+			t.Errorf("testMempoolAccept (%s): unexpected error - "+
+				"got %v, want limited %v", test.name, err,
+				test.wantLimited)
+		}
+	}
+}
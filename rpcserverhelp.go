@@ -57,6 +57,30 @@ var helpDescsEnUS = map[string]string{
 	"createrawtransaction-locktime":       "Locktime value; a non-zero value will also locktime-activate the inputs",
 	"createrawtransaction--result0":       "Hex-encoded bytes of the serialized transaction",
 
+	// CreateRecoveryTransactionsCmd help.
+	"createrecoverytransactions--synopsis": "Returns new transactions which move the unspent outputs secured by the passed ASP keyID to the provided addresses.\n" +
+		"Only the outputs which remain spendable without the key are moved, and the outputs of an address are split across multiple transactions when there are too many of them.\n" +
+		"The transaction inputs are not signed and must be signed with the remaining keys of the outputs.\n" +
+		"Only the requested page of the unspent outputs secured by the keyID is considered, so the command must be repeated with increasing skip values to move all of them.\n" +
+		"The fee of each transaction is capped at the maximum fee allowed by the network.\n" +
+		"Usage of this RPC requires the optional --keyidindex flag to be activated.",
+	"createrecoverytransactions-keyid":               "The keyID of the revoked or to be revoked ASP key",
+	"createrecoverytransactions-destinations":        "JSON object with the affected addresses as keys and the new addresses as values",
+	"createrecoverytransactions-destinations--key":   "address",
+	"createrecoverytransactions-destinations--value": "newaddress",
+	"createrecoverytransactions-destinations--desc":  "The affected address, or the hex-encoded public key script when it has no address, as the key and the new address as the value",
+	"createrecoverytransactions-feerate":             "The fee rate in RMG/kB to pay, which defaults to the minimum relay fee of the node",
+	"createrecoverytransactions-skip":                "The number of leading unspent outputs secured by the keyID to leave out",
+	"createrecoverytransactions-count":               "The maximum number of unspent outputs secured by the keyID to move",
+
+	// RecoveryTransactionResult help.
+	"recoverytransactionresult-address":     "The affected address the outputs are moved from",
+	"recoverytransactionresult-destination": "The new address the outputs are moved to",
+	"recoverytransactionresult-hex":         "Hex-encoded bytes of the serialized unsigned transaction",
+	"recoverytransactionresult-numinputs":   "The number of outputs the transaction spends",
+	"recoverytransactionresult-amount":      "The amount in RMG the transaction moves to the new address",
+	"recoverytransactionresult-fee":         "The fee in RMG the transaction pays",
+
 	// ScriptSig help.
 	"scriptsig-asm": "Disassembly of the script",
 	"scriptsig-hex": "Hex-encoded bytes of the script",
@@ -104,6 +128,22 @@ var helpDescsEnUS = map[string]string{
 	"decoderawtransaction--synopsis": "Returns a JSON object representing the provided serialized, hex-encoded transaction.",
 	"decoderawtransaction-hextx":     "Serialized, hex-encoded transaction",
 
+	// DumpTxOutSetCmd help.
+	"dumptxoutset--synopsis": "Writes a snapshot of the utxo set, the admin state and the best chain state to a file which can be loaded with loadtxoutset.",
+	"dumptxoutset-path":      "Path of the snapshot file to create, which must not exist yet",
+
+	// LoadTxOutSetCmd help.
+	"loadtxoutset--synopsis": "Bootstraps the chain state from a snapshot created with dumptxoutset.  The chain must not have any blocks yet, the snapshot must match a known snapshot for the network and the transaction, address, key id and committed filter indexes must be disabled.",
+	"loadtxoutset-path":      "Path of the snapshot file to load",
+
+	// TxOutSetSnapshotResult help.
+	"txoutsetsnapshotresult-path":          "The path of the snapshot file",
+	"txoutsetsnapshotresult-hash":          "The commitment hash of the snapshot",
+	"txoutsetsnapshotresult-bestblockhash": "The hash of the block the snapshot is for",
+	"txoutsetsnapshotresult-height":        "The height of the block the snapshot is for",
+	"txoutsetsnapshotresult-numutxos":      "The number of utxo entries in the snapshot",
+	"txoutsetsnapshotresult-numheaders":    "The number of block headers in the snapshot",
+
 	// SetValidateKeysCmd help.
 	"setvalidatekeys--synopsis": "Sets the private keys to use to sign generated blocks",
 	"setvalidatekeys-privkeys":  "Hex-encoded 32 byte private keys",
@@ -119,6 +159,22 @@ var helpDescsEnUS = map[string]string{
 	"decodescript--synopsis": "Returns a JSON object with information about the provided hex-encoded script.",
 	"decodescript-hexscript": "Hex-encoded script",
 
+	// EstimateFeeCmd help.
+	"estimatefee--synopsis": "Estimates the fee rate in RMG/kB needed for a transaction to be mined within the number of blocks.\n" +
+		"The estimate is based on how long the transactions observed in the memory pool took to be mined, and -1 is returned when not enough blocks have been observed yet.",
+	"estimatefee-numblocks": "The maximum number of blocks the transaction may take to be mined, up to 25",
+	"estimatefee--result0":  "Estimated fee rate in RMG/kB, or -1",
+
+	// EstimateSmartFeeCmd help.
+	"estimatesmartfee--synopsis": "Estimates the fee rate in RMG/kB needed for a transaction to be mined within the number of blocks.\n" +
+		"The target is limited to the supported range and the estimate is never less than the minimum relay fee of the node.",
+	"estimatesmartfee-conftarget": "The maximum number of blocks the transaction may take to be mined",
+
+	// EstimateSmartFeeResult help.
+	"estimatesmartfeeresult-feerate": "Estimated fee rate in RMG/kB, omitted when no estimate is available",
+	"estimatesmartfeeresult-errors":  "Errors encountered while estimating the fee rate",
+	"estimatesmartfeeresult-blocks":  "The number of blocks the estimate is for",
+
 	// GenerateCmd help
 	"generate--synopsis": "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n" +
 		" array of their hashes.",
@@ -242,6 +298,21 @@ var helpDescsEnUS = map[string]string{
 	"getblockverboseresult-validatingpubkey":  "The validating public key signing the block",
 	"getblockverboseresult-signature":         "The signature of the block generator",
 
+	// GetBlockChainInfoCmd help.
+	"getblockchaininfo--synopsis": "Returns information about the current state of the block chain.",
+
+	// GetBlockChainInfoResult help.
+	"getblockchaininforesult-chain":                "The name of the chain the node is on",
+	"getblockchaininforesult-blocks":               "The number of blocks in the best known chain",
+	"getblockchaininforesult-headers":              "The number of headers in the best known chain",
+	"getblockchaininforesult-bestblockhash":        "The hash of the best block in the chain",
+	"getblockchaininforesult-difficulty":           "The current chain difficulty",
+	"getblockchaininforesult-verificationprogress": "An estimate of the fraction of the chain which has been verified",
+	"getblockchaininforesult-chainwork":            "The total cumulative work in the best chain as a hex string",
+	"getblockchaininforesult-pruned":               "Whether or not the data for old blocks has been deleted by pruning",
+	"getblockchaininforesult-assumevalid":          "The hash of the block whose ancestors are assumed to have valid scripts (only when one is configured)",
+	"getblockchaininforesult-assumevalidheight":    "The height of the most recent block connected without script validation because of assumevalid (only when non-zero)",
+
 	// GetBlockCountCmd help.
 	"getblockcount--synopsis": "Returns the number of blocks in the longest block chain.",
 	"getblockcount--result0":  "The current block count",
@@ -251,6 +322,12 @@ var helpDescsEnUS = map[string]string{
 	"getblockhash-index":     "The block height",
 	"getblockhash--result0":  "The block hash",
 
+	// GetCFilterCmd help.
+	"getcfilter--synopsis":  "Returns the committed filter of a block of the main chain given its hash.",
+	"getcfilter-hash":       "The hash of the block",
+	"getcfilter-filtertype": "The type of the filter, only the regular filter (0) is supported",
+	"getcfilter--result0":   "The hex-encoded filter, starting with the number of its items",
+
 	// GetBlockHeaderCmd help.
 	"getblockheader--synopsis":   "Returns information about a block header given its hash.",
 	"getblockheader-hash":        "The hash of the block",
@@ -282,15 +359,20 @@ var helpDescsEnUS = map[string]string{
 	"templaterequest-sizelimit":    "Number of bytes allowed in blocks (this parameter is ignored)",
 	"templaterequest-maxversion":   "Highest supported block version number (this parameter is ignored)",
 	"templaterequest-target":       "The desired target for the block template (this parameter is ignored)",
-	"templaterequest-data":         "Hex-encoded block data (only for mode=proposal)",
+	"templaterequest-data":         "Hex-encoded block data (only for mode=proposal); the block may be left unsigned to skip the signature and validate key checks",
 	"templaterequest-workid":       "The server provided workid if provided in block template (not applicable)",
 
 	// GetBlockTemplateResultTx help.
-	"getblocktemplateresulttx-data":    "Hex-encoded transaction data (byte-for-byte)",
-	"getblocktemplateresulttx-hash":    "Hex-encoded transaction hash (little endian if treated as a 256-bit number)",
-	"getblocktemplateresulttx-depends": "Other transactions before this one (by 1-based index in the 'transactions'  list) that must be present in the final block if this one is",
-	"getblocktemplateresulttx-fee":     "Difference in value between transaction inputs and outputs (in Atoms)",
-	"getblocktemplateresulttx-sigops":  "Total number of signature operations as counted for purposes of block limits",
+	"getblocktemplateresulttx-data":     "Hex-encoded transaction data (byte-for-byte)",
+	"getblocktemplateresulttx-hash":     "Hex-encoded transaction hash (little endian if treated as a 256-bit number)",
+	"getblocktemplateresulttx-depends":  "Other transactions before this one (by 1-based index in the 'admintransactions' list followed by the 'transactions' list) that must be present in the final block if this one is",
+	"getblocktemplateresulttx-fee":      "Difference in value between transaction inputs and outputs (in Atoms)",
+	"getblocktemplateresulttx-sigops":   "Total number of signature operations as counted for purposes of block limits",
+	"getblocktemplateresulttx-required": "Whether or not the transaction must be included in the final block",
+
+	// GetBlockTemplateResultValidateKey help.
+	"getblocktemplateresultvalidatekey-pubkey":      "Hex-encoded compressed validate public key",
+	"getblocktemplateresultvalidatekey-ratelimited": "Whether or not the key is rate limited from signing a block built from the template",
 
 	// GetBlockTemplateResultAux help.
 	"getblocktemplateresultaux-flags": "Hex-encoded byte-for-byte data to include in the coinbase signature script",
@@ -302,7 +384,7 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplateresult-previousblockhash": "Hex-encoded big-endian hash of the previous block",
 	"getblocktemplateresult-sigoplimit":        "Number of sigops allowed in blocks ",
 	"getblocktemplateresult-sizelimit":         "Number of bytes allowed in blocks",
-	"getblocktemplateresult-transactions":      "Array of transactions as JSON objects",
+	"getblocktemplateresult-transactions":      "Array of transactions which follow the admin transactions as JSON objects",
 	"getblocktemplateresult-version":           "The block version",
 	"getblocktemplateresult-coinbaseaux":       "Data that should be included in the coinbase signature script",
 	"getblocktemplateresult-coinbasetxn":       "Information about the coinbase transaction",
@@ -319,6 +401,9 @@ var helpDescsEnUS = map[string]string{
 	"getblocktemplateresult-noncerange":        "Two concatenated hex-encoded big-endian 32-bit integers which represent the valid ranges of nonces the miner may scan",
 	"getblocktemplateresult-capabilities":      "List of server capabilities including 'proposal' to indicate support for block proposals",
 	"getblocktemplateresult-reject-reason":     "Reason the proposal was invalid as-is (only applies to proposal responses)",
+	"getblocktemplateresult-admintransactions": "Array of pending admin transactions as JSON objects which must directly follow the coinbase, in order, in the final block -- Always present and empty when there are none",
+	"getblocktemplateresult-size":              "Serialized size of the template block which the size in the header of the final block must match",
+	"getblocktemplateresult-validatekeys":      "Array of the active validate keys which may sign the final block as JSON objects",
 
 	// GetBlockTemplateCmd help.
 	"getblocktemplate--synopsis": "Returns a JSON object with information necessary to construct a block to mine or accepts a proposal to validate.\n" +
@@ -392,8 +477,11 @@ var helpDescsEnUS = map[string]string{
 	"getmempoolinfo--synopsis": "Returns memory pool information",
 
 	// GetMempoolInfoResult help.
-	"getmempoolinforesult-bytes": "Size in bytes of the mempool",
-	"getmempoolinforesult-size":  "Number of transactions in the mempool",
+	"getmempoolinforesult-bytes":         "Size in bytes of the mempool",
+	"getmempoolinforesult-size":          "Number of transactions in the mempool",
+	"getmempoolinforesult-maxmempool":    "Maximum total size in bytes of the transactions in the mempool (0 when unlimited)",
+	"getmempoolinforesult-mempoolminfee": "Dynamic minimum fee rate in RMG/kB for transactions to be accepted, which is raised when transactions are evicted from the full mempool (0 when not raised)",
+	"getmempoolinforesult-minrelaytxfee": "Minimum fee rate in RMG/kB for transactions to be considered to pay a non-zero fee",
 
 	// GetMiningInfoResult help.
 	"getmininginforesult-blocks":           "Height of the latest best block",
@@ -425,6 +513,18 @@ var helpDescsEnUS = map[string]string{
 	"getnettotalsresult-totalbytessent": "Total bytes sent",
 	"getnettotalsresult-timemillis":     "Number of milliseconds since 1 Jan 1970 GMT",
 
+	// GetNoticesCmd help.
+	"getnotices--synopsis": "Returns the network notices which are signed by a quorum of the root or provision keys as of the current admin state and have not expired, ordered by descending priority.",
+
+	// NoticeResult help.
+	"noticeresult-hash":       "The hash which identifies the notice",
+	"noticeresult-keyset":     "The admin key set which signed the notice (ROOT or PROVISION)",
+	"noticeresult-timestamp":  "The time the notice was created in seconds since 1 Jan 1970 GMT",
+	"noticeresult-expiration": "The time the notice expires in seconds since 1 Jan 1970 GMT",
+	"noticeresult-priority":   "The priority of the notice, higher values are more important",
+	"noticeresult-message":    "The text of the notice",
+	"noticeresult-signers":    "The hex-encoded public keys which signed the notice",
+
 	// GetPeerInfoResult help.
 	"getpeerinforesult-id":             "A unique node ID",
 	"getpeerinforesult-addr":           "The ip address and port of the peer",
@@ -447,6 +547,8 @@ var helpDescsEnUS = map[string]string{
 	"getpeerinforesult-banscore":       "The ban score",
 	"getpeerinforesult-feefilter":      "The requested minimum fee a transaction must have to be announced to the peer",
 	"getpeerinforesult-syncnode":       "Whether or not the peer is the sync peer",
+	"getpeerinforesult-authidentity":   "The admin key set and key, or the ASP key id, the peer proved control of during the handshake (omitted when the peer did not authenticate)",
+	"getpeerinforesult-encrypted":      "Whether or not the connection to the peer is encrypted",
 
 	// GetPeerInfoCmd help.
 	"getpeerinfo--synopsis": "Returns data about each connected network peer as an array of json objects.",
@@ -459,10 +561,18 @@ var helpDescsEnUS = map[string]string{
 	"getrawmempoolverboseresult-startingpriority": "Priority when transaction entered the pool",
 	"getrawmempoolverboseresult-currentpriority":  "Current priority",
 	"getrawmempoolverboseresult-depends":          "Unconfirmed transactions used as inputs for this transaction",
+	"getrawmempoolverboseresult-descendantcount":  "Number of transactions in the pool depending on this transaction, including itself",
+	"getrawmempoolverboseresult-descendantsize":   "Total size in bytes of this transaction and its descendants in the pool",
+	"getrawmempoolverboseresult-descendantfees":   "Total fee in grams of this transaction and its descendants in the pool",
+	"getrawmempoolverboseresult-ancestorcount":    "Number of transactions in the pool this transaction depends on, including itself",
+	"getrawmempoolverboseresult-ancestorsize":     "Total size in bytes of this transaction and its ancestors in the pool",
+	"getrawmempoolverboseresult-ancestorfees":     "Total fee in grams of this transaction and its ancestors in the pool",
+	"getrawmempoolverboseresult-tag":              "The tag the transaction was submitted with, or 0 for transactions relayed by peers",
 
 	// GetRawMempoolCmd help.
 	"getrawmempool--synopsis":   "Returns information about all of the transactions currently in the memory pool.",
 	"getrawmempool-verbose":     "Returns JSON object when true or an array of transaction hashes when false",
+	"getrawmempool-tag":         "Only return the transactions with this tag",
 	"getrawmempool--condition0": "verbose=false",
 	"getrawmempool--condition1": "verbose=true",
 	"getrawmempool--result0":    "Array of transaction hashes",
@@ -475,6 +585,34 @@ var helpDescsEnUS = map[string]string{
 	"getrawtransaction--condition1": "verbose=true",
 	"getrawtransaction--result0":    "Hex-encoded bytes of the serialized transaction",
 
+	// GetRevokeImpactCmd help.
+	"getrevokeimpact--synopsis": "Returns the unspent outputs which are secured by the passed ASP keyID grouped by address, and whether each address remains spendable by its other keys once the key is revoked.\n" +
+		"This can be used both before and after the key is revoked.\n" +
+		"The totals cover all of the affected unspent outputs, while only the requested page of them is listed.\n" +
+		"Usage of this RPC requires the optional --keyidindex flag to be activated.",
+	"getrevokeimpact-keyid": "The keyID of the ASP key",
+	"getrevokeimpact-skip":  "The number of leading unspent outputs to leave out of the listed ones",
+	"getrevokeimpact-count": "The maximum number of unspent outputs to list",
+
+	// RevokeImpactResult help.
+	"revokeimpactresult-keyid":            "The keyID of the ASP key",
+	"revokeimpactresult-revoked":          "Whether the key is already revoked",
+	"revokeimpactresult-totalcount":       "The total number of unspent outputs secured by the keyID",
+	"revokeimpactresult-totalvalue":       "The total value of the unspent outputs secured by the keyID in RMG",
+	"revokeimpactresult-spendablevalue":   "The value of the unspent outputs which remain spendable without the key in RMG",
+	"revokeimpactresult-unspendablevalue": "The value of the unspent outputs which become unspendable without the key in RMG",
+	"revokeimpactresult-addresses":        "The affected unspent outputs grouped by address",
+
+	// RevokeImpactAddressResult help.
+	"revokeimpactaddressresult-address":       "The affected address",
+	"revokeimpactaddressresult-scriptpubkey":  "Hex-encoded public key script of the address",
+	"revokeimpactaddressresult-requiredsigs":  "The number of signatures required to spend the outputs",
+	"revokeimpactaddressresult-availablekeys": "The number of keys which remain available to sign without the key",
+	"revokeimpactaddressresult-spendable":     "Whether the outputs remain spendable without the key",
+	"revokeimpactaddressresult-totalcount":    "The total number of unspent outputs of the address",
+	"revokeimpactaddressresult-totalvalue":    "The total value of the outputs of the address in RMG",
+	"revokeimpactaddressresult-utxos":         "The requested unspent outputs of the address ordered by outpoint",
+
 	// GetTxOutResult help.
 	"gettxoutresult-bestblock":     "The block hash that contains the transaction output",
 	"gettxoutresult-confirmations": "The number of confirmations",
@@ -497,10 +635,41 @@ var helpDescsEnUS = map[string]string{
 	"help--result0":    "List of commands",
 	"help--result1":    "Help for specified command",
 
+	// ListKeyIDUtxosCmd help.
+	"listkeyidutxos--synopsis": "Returns the unspent outputs which are secured by the passed ASP keyID along with the total number and value of all of them, which is the value at risk should the key be compromised or revoked.\n" +
+		"Usage of this RPC requires the optional --keyidindex flag to be activated.",
+	"listkeyidutxos-keyid": "The keyID of the ASP key to list the unspent outputs for",
+	"listkeyidutxos-skip":  "The number of leading unspent outputs to leave out of the final response",
+	"listkeyidutxos-count": "The maximum number of unspent outputs to return",
+
+	// ListKeyIDUtxosResult help.
+	"listkeyidutxosresult-keyid":      "The keyID of the ASP key",
+	"listkeyidutxosresult-totalcount": "The total number of unspent outputs secured by the keyID",
+	"listkeyidutxosresult-totalvalue": "The total value of the unspent outputs secured by the keyID in RMG",
+	"listkeyidutxosresult-utxos":      "The requested unspent outputs ordered by outpoint",
+
+	// KeyIDUtxoResult help.
+	"keyidutxoresult-txid":          "The hash of the transaction which created the output",
+	"keyidutxoresult-vout":          "The index of the output",
+	"keyidutxoresult-amount":        "The value of the output in RMG",
+	"keyidutxoresult-address":       "The address of the output",
+	"keyidutxoresult-scriptpubkey":  "Hex-encoded public key script of the output",
+	"keyidutxoresult-confirmations": "Number of confirmations of the output",
+
 	// PingCmd help.
 	"ping--synopsis": "Queues a ping to be sent to each connected peer.\n" +
 		"Ping times are provided by getpeerinfo via the pingtime and pingwait fields.",
 
+	// SaveMempoolCmd help.
+	"savemempool--synopsis": "Writes the transactions in the memory pool along with the time they were accepted and their tag to mempool.dat in the data directory.",
+
+	// LoadMempoolCmd help.
+	"loadmempool--synopsis": "Revalidates the transactions saved to mempool.dat in the data directory and adds the ones which are still valid to the memory pool.",
+
+	// LoadMempoolResult help.
+	"loadmempoolresult-accepted": "The number of transactions added to the memory pool",
+	"loadmempoolresult-rejected": "The number of saved transactions which were rejected",
+
 	// SearchRawTransactionsCmd help.
 	"searchrawtransactions--synopsis": "Returns raw data for transactions involving the passed address.\n" +
 		"Returned transactions are pulled from both the database, and transactions currently in the mempool.\n" +
@@ -518,12 +687,30 @@ var helpDescsEnUS = map[string]string{
 	"searchrawtransactions-filteraddrs": "Address list.  Only inputs or outputs with matching address will be returned",
 	"searchrawtransactions--result0":    "Hex-encoded serialized transaction",
 
+	// SearchKeyIDTransactionsCmd help.
+	"searchkeyidtransactions--synopsis": "Returns raw data for the confirmed transactions which create or spend outputs secured by the passed ASP keyID, or provision or revoke the key.\n" +
+		"Usage of this RPC requires the optional --keyidindex flag to be activated.",
+	"searchkeyidtransactions-keyid":       "The keyID of the ASP key to search for",
+	"searchkeyidtransactions-verbose":     "Specifies the transaction is returned as a JSON object instead of hex-encoded string",
+	"searchkeyidtransactions--condition0": "verbose=0",
+	"searchkeyidtransactions--condition1": "verbose=1",
+	"searchkeyidtransactions-skip":        "The number of leading transactions to leave out of the final response",
+	"searchkeyidtransactions-count":       "The maximum number of transactions to return",
+	"searchkeyidtransactions-reverse":     "Specifies that the transactions should be returned in reverse chronological order",
+	"searchkeyidtransactions--result0":    "Hex-encoded serialized transaction",
+
 	// SendRawTransactionCmd help.
 	"sendrawtransaction--synopsis":     "Submits the serialized, hex-encoded transaction to the local peer and relays it to the network.",
 	"sendrawtransaction-hextx":         "Serialized, hex-encoded signed transaction",
 	"sendrawtransaction-allowhighfees": "Whether or not to allow insanely high fees (btcd does not yet implement this parameter, so it has no effect)",
+	"sendrawtransaction-tag":           "Tag to identify the transaction with, such as a customer or batch ID, which also selects the memory pool policy overrides configured for it when submitted by an admin user",
 	"sendrawtransaction--result0":      "The hash of the transaction",
 
+	// SendNoticeCmd help.
+	"sendnotice--synopsis": "Submits the serialized, hex-encoded network notice to the local peer and relays it to the network.  The notice must be signed by a quorum of the root or provision keys.",
+	"sendnotice-hexnotice": "Serialized, hex-encoded signed notice",
+	"sendnotice--result0":  "The hash of the notice",
+
 	// SetGenerateCmd help.
 	"setgenerate--synopsis":    "Set the server to generate coins (mine) or not.",
 	"setgenerate-generate":     "Use true to enable generation, false to disable it",
@@ -534,16 +721,31 @@ var helpDescsEnUS = map[string]string{
 	"stop--result0":  "The string 'Prova stopping.'",
 
 	// SubmitBlockOptions help.
-	"submitblockoptions-workid": "This parameter is currently ignored",
+	"submitblockoptions-workid":           "This parameter is currently ignored",
+	"submitblockoptions-validatingpubkey": "Hex-encoded validating public key to set in the block header when the block was signed externally",
+	"submitblockoptions-signature":        "Hex-encoded DER signature of the block header to set when the block was signed externally",
 
 	// SubmitBlockCmd help.
 	"submitblock--synopsis":   "Attempts to submit a new serialized, hex-encoded block to the network.",
 	"submitblock-hexblock":    "Serialized, hex-encoded block",
-	"submitblock-options":     "This parameter is currently ignored",
+	"submitblock-options":     "Options for the submitted block",
 	"submitblock--condition0": "Block successfully submitted",
 	"submitblock--condition1": "Block rejected",
 	"submitblock--result1":    "The reason the block was rejected",
 
+	// TestMempoolAcceptResult help.
+	"testmempoolacceptresult-txid":         "The hash of the transaction",
+	"testmempoolacceptresult-allowed":      "Whether or not the transaction would be accepted into the memory pool",
+	"testmempoolacceptresult-rejectcode":   "The reject code for the transaction (only when allowed is false)",
+	"testmempoolacceptresult-rejectreason": "The reason the transaction would be rejected (only when allowed is false)",
+	"testmempoolacceptresult-fee":          "The fee the transaction pays in RMG (only when allowed is true)",
+
+	// TestMempoolAcceptCmd help.
+	"testmempoolaccept--synopsis": "Checks whether the serialized, hex-encoded transactions would be accepted into the memory pool without adding or relaying them.\n" +
+		"The transactions are tested in order and may spend the outputs of earlier ones, so dependent packages of transactions can be tested together.",
+	"testmempoolaccept-rawtxs": "Serialized, hex-encoded signed transactions to test, up to the maximum number of transactions in a package of the memory pool (25 by default) at a time",
+	"testmempoolaccept-tag":    "Tag to test the transactions with, which selects the memory pool policy overrides configured for it when called by an admin user",
+
 	// ValidateAddressResult help.
 	"validateaddresschainresult-isvalid": "Whether or not the address is valid",
 	"validateaddresschainresult-address": "The bitcoin address (only when isvalid is true)",
@@ -588,6 +790,12 @@ var helpDescsEnUS = map[string]string{
 	// StopNotifyNewTransactionsCmd help.
 	"stopnotifynewtransactions--synopsis": "Stop sending either a txaccepted or a txacceptedverbose notification when a new transaction is accepted into the mempool.",
 
+	// NotifyNoticesCmd help.
+	"notifynotices--synopsis": "Send a notice notification when a new network notice signed by the root or provision keys is received.",
+
+	// StopNotifyNoticesCmd help.
+	"stopnotifynotices--synopsis": "Stop sending notice notifications when a new network notice is received.",
+
 	// NotifyReceivedCmd help.
 	"notifyreceived--synopsis": "Send a recvtx notification when a transaction added to mempool or appears in a newly-attached block contains a txout pkScript sending to any of the passed addresses.\n" +
 		"Matching outpoints are automatically registered for redeemingtx notifications.",
@@ -639,49 +847,64 @@ var helpDescsEnUS = map[string]string{
 // This information is used to generate the help.  Each result type must be a
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
-	"addnode":               nil,
-	"createrawtransaction":  {(*string)(nil)},
-	"debuglevel":            {(*string)(nil), (*string)(nil)},
-	"decoderawtransaction":  {(*btcjson.TxRawDecodeResult)(nil)},
-	"decodescript":          {(*btcjson.DecodeScriptResult)(nil)},
-	"generate":              {(*[]string)(nil)},
-	"getaddednodeinfo":      {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
-	"getaddresstxids":       {(*[]string)(nil)},
-	"getadmininfo":          {(*btcjson.GetAdminInfoResult)(nil)},
-	"getbestblock":          {(*btcjson.GetBestBlockResult)(nil)},
-	"getbestblockhash":      {(*string)(nil)},
-	"getblock":              {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
-	"getblockcount":         {(*int64)(nil)},
-	"getblockhash":          {(*string)(nil)},
-	"getblockheader":        {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
-	"getblocktemplate":      {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
-	"getconnectioncount":    {(*int32)(nil)},
-	"getcurrentnet":         {(*uint32)(nil)},
-	"getdifficulty":         {(*float64)(nil)},
-	"getgenerate":           {(*bool)(nil)},
-	"gethashespersec":       {(*float64)(nil)},
-	"getheaders":            {(*[]string)(nil)},
-	"getinfo":               {(*btcjson.InfoChainResult)(nil)},
-	"getmempoolinfo":        {(*btcjson.GetMempoolInfoResult)(nil)},
-	"getmininginfo":         {(*btcjson.GetMiningInfoResult)(nil)},
-	"getnettotals":          {(*btcjson.GetNetTotalsResult)(nil)},
-	"getnetworkhashps":      {(*int64)(nil)},
-	"getpeerinfo":           {(*[]btcjson.GetPeerInfoResult)(nil)},
-	"getrawmempool":         {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
-	"getrawtransaction":     {(*string)(nil), (*btcjson.TxRawResult)(nil)},
-	"gettxout":              {(*btcjson.GetTxOutResult)(nil)},
-	"node":                  nil,
-	"help":                  {(*string)(nil), (*string)(nil)},
-	"ping":                  nil,
-	"searchrawtransactions": {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
-	"sendrawtransaction":    {(*string)(nil)},
-	"setgenerate":           nil,
-	"setvalidatekeys":       nil,
-	"stop":                  {(*string)(nil)},
-	"submitblock":           {nil, (*string)(nil)},
-	"validateaddress":       {(*btcjson.ValidateAddressChainResult)(nil)},
-	"verifychain":           {(*bool)(nil)},
-	"verifymessage":         {(*bool)(nil)},
+	"addnode":                    nil,
+	"createrawtransaction":       {(*string)(nil)},
+	"createrecoverytransactions": {(*[]btcjson.RecoveryTransactionResult)(nil)},
+	"debuglevel":                 {(*string)(nil), (*string)(nil)},
+	"decoderawtransaction":       {(*btcjson.TxRawDecodeResult)(nil)},
+	"decodescript":               {(*btcjson.DecodeScriptResult)(nil)},
+	"estimatefee":                {(*float64)(nil)},
+	"estimatesmartfee":           {(*btcjson.EstimateSmartFeeResult)(nil)},
+	"dumptxoutset":               {(*btcjson.TxOutSetSnapshotResult)(nil)},
+	"generate":                   {(*[]string)(nil)},
+	"getaddednodeinfo":           {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
+	"getaddresstxids":            {(*[]string)(nil)},
+	"getadmininfo":               {(*btcjson.GetAdminInfoResult)(nil)},
+	"getbestblock":               {(*btcjson.GetBestBlockResult)(nil)},
+	"getbestblockhash":           {(*string)(nil)},
+	"getblock":                   {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
+	"getblockchaininfo":          {(*btcjson.GetBlockChainInfoResult)(nil)},
+	"getblockcount":              {(*int64)(nil)},
+	"getblockhash":               {(*string)(nil)},
+	"getblockheader":             {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
+	"getcfilter":                 {(*string)(nil)},
+	"getblocktemplate":           {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
+	"getconnectioncount":         {(*int32)(nil)},
+	"getcurrentnet":              {(*uint32)(nil)},
+	"getdifficulty":              {(*float64)(nil)},
+	"getgenerate":                {(*bool)(nil)},
+	"gethashespersec":            {(*float64)(nil)},
+	"getheaders":                 {(*[]string)(nil)},
+	"getinfo":                    {(*btcjson.InfoChainResult)(nil)},
+	"getmempoolinfo":             {(*btcjson.GetMempoolInfoResult)(nil)},
+	"getmininginfo":              {(*btcjson.GetMiningInfoResult)(nil)},
+	"getnettotals":               {(*btcjson.GetNetTotalsResult)(nil)},
+	"getnetworkhashps":           {(*int64)(nil)},
+	"getnotices":                 {(*[]btcjson.NoticeResult)(nil)},
+	"getpeerinfo":                {(*[]btcjson.GetPeerInfoResult)(nil)},
+	"getrawmempool":              {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
+	"getrawtransaction":          {(*string)(nil), (*btcjson.TxRawResult)(nil)},
+	"getrevokeimpact":            {(*btcjson.RevokeImpactResult)(nil)},
+	"gettxout":                   {(*btcjson.GetTxOutResult)(nil)},
+	"node":                       nil,
+	"help":                       {(*string)(nil), (*string)(nil)},
+	"listkeyidutxos":             {(*btcjson.ListKeyIDUtxosResult)(nil)},
+	"loadmempool":                {(*btcjson.LoadMempoolResult)(nil)},
+	"loadtxoutset":               {(*btcjson.TxOutSetSnapshotResult)(nil)},
+	"ping":                       nil,
+	"savemempool":                nil,
+	"searchkeyidtransactions":    {(*string)(nil), (*[]btcjson.TxRawResult)(nil)},
+	"searchrawtransactions":      {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
+	"sendnotice":                 {(*string)(nil)},
+	"sendrawtransaction":         {(*string)(nil)},
+	"setgenerate":                nil,
+	"setvalidatekeys":            nil,
+	"stop":                       {(*string)(nil)},
+	"submitblock":                {nil, (*string)(nil)},
+	"testmempoolaccept":          {(*[]btcjson.TestMempoolAcceptResult)(nil)},
+	"validateaddress":            {(*btcjson.ValidateAddressChainResult)(nil)},
+	"verifychain":                {(*bool)(nil)},
+	"verifymessage":              {(*bool)(nil)},
 
 	// Websocket commands.
 	"loadtxfilter":              nil,
@@ -690,6 +913,8 @@ var rpcResultTypes = map[string][]interface{}{
 	"stopnotifyblocks":          nil,
 	"notifynewtransactions":     nil,
 	"stopnotifynewtransactions": nil,
+	"notifynotices":             nil,
+	"stopnotifynotices":         nil,
 	"notifyreceived":            nil,
 	"stopnotifyreceived":        nil,
 	"notifyspent":               nil,
@@ -25,6 +25,7 @@ import (
 	"github.com/bitgo/prova/btcjson"
 	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/database"
+	"github.com/bitgo/prova/mempool"
 	"github.com/bitgo/prova/provautil"
 	"github.com/bitgo/prova/txscript"
 	"github.com/bitgo/prova/wire"
@@ -67,11 +68,13 @@ var wsHandlersBeforeInit = map[string]wsCommandHandler{
 	"help":                      handleWebsocketHelp,
 	"notifyblocks":              handleNotifyBlocks,
 	"notifynewtransactions":     handleNotifyNewTransactions,
+	"notifynotices":             handleNotifyNotices,
 	"notifyreceived":            handleNotifyReceived,
 	"notifyspent":               handleNotifySpent,
 	"session":                   handleSession,
 	"stopnotifyblocks":          handleStopNotifyBlocks,
 	"stopnotifynewtransactions": handleStopNotifyNewTransactions,
+	"stopnotifynotices":         handleStopNotifyNotices,
 	"stopnotifyspent":           handleStopNotifySpent,
 	"stopnotifyreceived":        handleStopNotifyReceived,
 	"rescan":                    handleRescan,
@@ -226,14 +229,15 @@ func (m *wsNotificationManager) NotifyBlockDisconnected(block *provautil.Block)
 	}
 }
 
-// NotifyMempoolTx passes a transaction accepted by mempool to the
-// notification manager for transaction notification processing.  If
-// isNew is true, the tx is is a new transaction, rather than one
-// added to the mempool during a reorg.
-func (m *wsNotificationManager) NotifyMempoolTx(tx *provautil.Tx, isNew bool) {
+// NotifyMempoolTx passes a transaction accepted by mempool along with the tag
+// it was processed with to the notification manager for transaction
+// notification processing.  If isNew is true, the tx is is a new transaction,
+// rather than one added to the mempool during a reorg.
+func (m *wsNotificationManager) NotifyMempoolTx(tx *provautil.Tx, tag mempool.Tag, isNew bool) {
 	n := &notificationTxAcceptedByMempool{
 		isNew: isNew,
 		tx:    tx,
+		tag:   tag,
 	}
 
 	// As NotifyMempoolTx will be called by mempool and the RPC server
@@ -246,13 +250,27 @@ func (m *wsNotificationManager) NotifyMempoolTx(tx *provautil.Tx, isNew bool) {
 	}
 }
 
+// NotifyNotice passes a network notice which was newly received by the server
+// to the notification manager for notice notification processing.
+func (m *wsNotificationManager) NotifyNotice(notice *wire.MsgNotice) {
+	// As NotifyNotice will be called by the server and the RPC server may
+	// no longer be running, use a select statement to unblock enqueuing
+	// the notification once the RPC server has begun shutting down.
+	select {
+	case m.queueNotification <- (*notificationNotice)(notice):
+	case <-m.quit:
+	}
+}
+
 // Notification types
 type notificationBlockConnected provautil.Block
 type notificationBlockDisconnected provautil.Block
 type notificationTxAcceptedByMempool struct {
 	isNew bool
 	tx    *provautil.Tx
+	tag   mempool.Tag
 }
+type notificationNotice wire.MsgNotice
 
 // Notification control requests
 type notificationRegisterClient wsClient
@@ -261,6 +279,8 @@ type notificationRegisterBlocks wsClient
 type notificationUnregisterBlocks wsClient
 type notificationRegisterNewMempoolTxs wsClient
 type notificationUnregisterNewMempoolTxs wsClient
+type notificationRegisterNotices wsClient
+type notificationUnregisterNotices wsClient
 type notificationRegisterSpent struct {
 	wsc *wsClient
 	ops []*wire.OutPoint
@@ -293,6 +313,7 @@ func (m *wsNotificationManager) notificationHandler() {
 	// since it is quite a bit more efficient than using the entire struct.
 	blockNotifications := make(map[chan struct{}]*wsClient)
 	txNotifications := make(map[chan struct{}]*wsClient)
+	noticeNotifications := make(map[chan struct{}]*wsClient)
 	watchedOutPoints := make(map[wire.OutPoint]map[chan struct{}]*wsClient)
 	watchedAddrs := make(map[string]map[chan struct{}]*wsClient)
 
@@ -336,11 +357,18 @@ out:
 
 			case *notificationTxAcceptedByMempool:
 				if n.isNew && len(txNotifications) != 0 {
-					m.notifyForNewTx(txNotifications, n.tx)
+					m.notifyForNewTx(txNotifications, n.tx,
+						n.tag)
 				}
 				m.notifyForTx(watchedOutPoints, watchedAddrs, n.tx, nil)
 				m.notifyRelevantTxAccepted(n.tx, clients)
 
+			case *notificationNotice:
+				if len(noticeNotifications) != 0 {
+					m.notifyNotice(noticeNotifications,
+						(*wire.MsgNotice)(n))
+				}
+
 			case *notificationRegisterBlocks:
 				wsc := (*wsClient)(n)
 				blockNotifications[wsc.quit] = wsc
@@ -359,6 +387,7 @@ out:
 				// the client itself.
 				delete(blockNotifications, wsc.quit)
 				delete(txNotifications, wsc.quit)
+				delete(noticeNotifications, wsc.quit)
 				for k := range wsc.spentRequests {
 					op := k
 					m.removeSpentRequest(watchedOutPoints, wsc, &op)
@@ -388,6 +417,14 @@ out:
 				wsc := (*wsClient)(n)
 				delete(txNotifications, wsc.quit)
 
+			case *notificationRegisterNotices:
+				wsc := (*wsClient)(n)
+				noticeNotifications[wsc.quit] = wsc
+
+			case *notificationUnregisterNotices:
+				wsc := (*wsClient)(n)
+				delete(noticeNotifications, wsc.quit)
+
 			default:
 				rpcsLog.Warn("Unhandled notification type")
 			}
@@ -745,8 +782,9 @@ func (m *wsNotificationManager) UnregisterNewMempoolTxsUpdates(wsc *wsClient) {
 }
 
 // notifyForNewTx notifies websocket clients that have registered for updates
-// when a new transaction is added to the memory pool.
-func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClient, tx *provautil.Tx) {
+// when a new transaction is added to the memory pool.  The notifications
+// include the tag the transaction was processed with.
+func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClient, tx *provautil.Tx, tag mempool.Tag) {
 	txHashStr := tx.Hash().String()
 	mtx := tx.MsgTx()
 
@@ -755,7 +793,9 @@ func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClie
 		amount += txOut.Value
 	}
 
-	ntfn := btcjson.NewTxAcceptedNtfn(txHashStr, provautil.Amount(amount).ToRMG())
+	txTag := uint64(tag)
+	ntfn := btcjson.NewTxAcceptedNtfn(txHashStr,
+		provautil.Amount(amount).ToRMG(), &txTag)
 	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
 	if err != nil {
 		rpcsLog.Errorf("Failed to marshal tx notification: %s", err.Error())
@@ -778,7 +818,8 @@ func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClie
 				return
 			}
 
-			verboseNtfn = btcjson.NewTxAcceptedVerboseNtfn(*rawTx)
+			verboseNtfn = btcjson.NewTxAcceptedVerboseNtfn(*rawTx,
+				&txTag)
 			marshalledJSONVerbose, err = btcjson.MarshalCmd(nil,
 				verboseNtfn)
 			if err != nil {
@@ -793,6 +834,33 @@ func (m *wsNotificationManager) notifyForNewTx(clients map[chan struct{}]*wsClie
 	}
 }
 
+// RegisterNoticeUpdates requests notifications to the passed websocket client
+// when new network notices are received.
+func (m *wsNotificationManager) RegisterNoticeUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationRegisterNotices)(wsc)
+}
+
+// UnregisterNoticeUpdates removes notifications to the passed websocket client
+// when new network notices are received.
+func (m *wsNotificationManager) UnregisterNoticeUpdates(wsc *wsClient) {
+	m.queueNotification <- (*notificationUnregisterNotices)(wsc)
+}
+
+// notifyNotice notifies websocket clients that have registered for notice
+// updates when a new network notice is received.
+func (m *wsNotificationManager) notifyNotice(clients map[chan struct{}]*wsClient, notice *wire.MsgNotice) {
+	ntfn := btcjson.NewNoticeNtfn(*createNoticeResult(notice))
+	marshalledJSON, err := btcjson.MarshalCmd(nil, ntfn)
+	if err != nil {
+		rpcsLog.Errorf("Failed to marshal notice notification: %v",
+			err)
+		return
+	}
+	for _, wsc := range clients {
+		wsc.QueueNotification(marshalledJSON)
+	}
+}
+
 // RegisterSpentRequests requests a notification when each of the passed
 // outpoints is confirmed spent (contained in a block connected to the main
 // chain) for the passed websocket client.  The request is automatically
@@ -1403,7 +1471,7 @@ func (c *wsClient) serviceRequest(r *parsedRPCCmd) {
 	if ok {
 		result, err = wsHandler(c, r.cmd)
 	} else {
-		result, err = c.server.standardCmdResult(r, nil)
+		result, err = c.server.standardCmdResult(r, nil, c.isAdmin)
 	}
 	reply, err := createMarshalledReply(r.id, result, err)
 	if err != nil {
@@ -1789,6 +1857,20 @@ func handleStopNotifyNewTransactions(wsc *wsClient, icmd interface{}) (interface
 	return nil, nil
 }
 
+// handleNotifyNotices implements the notifynotices command extension for
+// websocket connections.
+func handleNotifyNotices(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.RegisterNoticeUpdates(wsc)
+	return nil, nil
+}
+
+// handleStopNotifyNotices implements the stopnotifynotices command extension
+// for websocket connections.
+func handleStopNotifyNotices(wsc *wsClient, icmd interface{}) (interface{}, error) {
+	wsc.server.ntfnMgr.UnregisterNoticeUpdates(wsc)
+	return nil, nil
+}
+
 // handleNotifyReceived implements the notifyreceived command extension for
 // websocket connections.
 func handleNotifyReceived(wsc *wsClient, icmd interface{}) (interface{}, error) {
@@ -7,6 +7,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/rand"
 	"encoding/binary"
@@ -14,7 +15,10 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,6 +28,7 @@ import (
 	"github.com/bitgo/prova/addrmgr"
 	"github.com/bitgo/prova/blockchain"
 	"github.com/bitgo/prova/blockchain/indexers"
+	"github.com/bitgo/prova/btcec"
 	"github.com/bitgo/prova/chaincfg"
 	"github.com/bitgo/prova/chaincfg/chainhash"
 	"github.com/bitgo/prova/connmgr"
@@ -41,7 +46,8 @@ import (
 const (
 	// defaultServices describes the default services that are supported by
 	// the server.
-	defaultServices = wire.SFNodeNetwork | wire.SFNodeBloom
+	defaultServices = wire.SFNodeNetwork | wire.SFNodeBloom |
+		wire.SFNodeCompactBlocks | wire.SFNodeCF
 
 	// defaultRequiredServices describes the default services that are
 	// required to be supported by outbound peers.
@@ -54,6 +60,18 @@ const (
 	// retries when connecting to persistent peers.  It is adjusted by the
 	// number of retries such that there is a retry backoff.
 	connectionRetryInterval = time.Second * 5
+
+	// mempoolFileName is the name of the file in the data directory the
+	// transactions in the memory pool are saved to.
+	mempoolFileName = "mempool.dat"
+
+	// feeFilterInterval is the interval at which changes of the dynamic
+	// minimum relay fee of the memory pool are announced to peers.
+	feeFilterInterval = time.Minute * 10
+
+	// maxNotices is the maximum number of network notices which are kept
+	// and relayed to newly connected peers.
+	maxNotices = 100
 )
 
 var (
@@ -165,6 +183,7 @@ type server struct {
 	// Putting the uint64s first makes them 64-bit aligned for 32-bit systems.
 	bytesReceived uint64 // Total bytes received from all peers since start.
 	bytesSent     uint64 // Total bytes sent by all peers since start.
+	services      uint64 // Advertised wire.ServiceFlag services.
 	started       int32
 	shutdown      int32
 	shutdownSched int32
@@ -191,21 +210,38 @@ type server struct {
 	nat                  NAT
 	db                   database.DB
 	timeSource           blockchain.MedianTimeSource
-	services             wire.ServiceFlag
+
+	// noticesMtx protects the network notices which have been received and
+	// are relayed to newly connected peers.  The notices are keyed by
+	// their notice hash.
+	noticesMtx sync.RWMutex
+	notices    map[chainhash.Hash]*wire.MsgNotice
+
+	// feeEstimator estimates the fee rates needed to have transactions
+	// mined within a number of blocks.  Its state is saved to the database
+	// on shutdown and restored on start up.
+	feeEstimator *mempool.FeeEstimator
+
+	// mempoolFileMtx serializes access to the file the transactions in the
+	// memory pool are saved to.
+	mempoolFileMtx sync.Mutex
 
 	// The following fields are used for optional indexes.  They will be nil
 	// if the associated index is not enabled.  These fields are set during
 	// initial creation of the server and never changed afterwards, so they
 	// do not need to be protected for concurrent access.
-	txIndex   *indexers.TxIndex
-	addrIndex *indexers.AddrIndex
+	txIndex    *indexers.TxIndex
+	addrIndex  *indexers.AddrIndex
+	cfIndex    *indexers.CfIndex
+	keyIDIndex *indexers.KeyIDIndex
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
 // the blockmanager.
 type serverPeer struct {
 	// The following variables must only be used atomically
-	feeFilter int64
+	feeFilter     int64
+	sentFeeFilter int64
 
 	*peer.Peer
 
@@ -352,6 +388,27 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) {
 	// is received.
 	sp.setDisableRelayTx(msg.DisableRelayTx)
 
+	// Ask peers which support compact block relay to announce new blocks
+	// by sending cmpctblock messages directly, since block propagation
+	// latency determines how often validators build on stale tips.
+	if sp.ProtocolVersion() >= wire.CompactBlocksVersion &&
+		sp.Services()&wire.SFNodeCompactBlocks == wire.SFNodeCompactBlocks {
+
+		sp.QueueMessage(wire.NewMsgSendCmpct(true,
+			wire.CmpctBlockVersion), nil)
+	}
+
+	// Tell the peer which transactions not to announce to us.
+	sp.pushFeeFilterMsg()
+
+	// Send the active network notices to peers which support them so
+	// announcements reach nodes which connect after they were broadcast.
+	if sp.ProtocolVersion() >= wire.NoticeVersion {
+		for _, notice := range sp.server.ActiveNotices() {
+			sp.QueueMessage(notice, nil)
+		}
+	}
+
 	// Update the address manager and request known addresses from the
 	// remote peer for outbound connections.  This is skipped when running
 	// on the simulation test network since it is only intended to connect
@@ -398,7 +455,7 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) {
 func (sp *serverPeer) OnMemPool(_ *peer.Peer, msg *wire.MsgMemPool) {
 	// Only allow mempool requests if the server has bloom filtering
 	// enabled.
-	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom {
+	if sp.server.Services()&wire.SFNodeBloom != wire.SFNodeBloom {
 		peerLog.Debugf("peer %v sent mempool request with bloom "+
 			"filtering disabled -- disconnecting", sp)
 		sp.Disconnect()
@@ -492,6 +549,61 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	<-sp.blockProcessed
 }
 
+// OnHeaders is invoked when a peer receives a headers bitcoin message.  The
+// message is passed down to the block manager.
+func (sp *serverPeer) OnHeaders(_ *peer.Peer, msg *wire.MsgHeaders) {
+	sp.server.blockManager.QueueHeaders(msg, sp)
+}
+
+// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message.
+// The message is passed down to the block manager which reconstructs the block
+// from the transaction memory pool.
+func (sp *serverPeer) OnCmpctBlock(_ *peer.Peer, msg *wire.MsgCmpctBlock) {
+	// Add the block to the known inventory for the peer.
+	blockHash := msg.BlockHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &blockHash)
+	sp.AddKnownInventory(iv)
+
+	sp.server.blockManager.QueueCmpctBlock(msg, sp)
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message.  The
+// message is passed down to the block manager to complete the reconstruction
+// of a compact block.
+func (sp *serverPeer) OnBlockTxn(_ *peer.Peer, msg *wire.MsgBlockTxn) {
+	sp.server.blockManager.QueueBlockTxn(msg, sp)
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message.
+// It responds with the requested transactions of the block via a blocktxn
+// message.
+func (sp *serverPeer) OnGetBlockTxn(_ *peer.Peer, msg *wire.MsgGetBlockTxn) {
+	block, err := sp.server.blockManager.chain.BlockByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Unable to fetch block %v requested by %s via "+
+			"getblocktxn: %v", msg.BlockHash, sp, err)
+		return
+	}
+
+	// Requesting transactions which are not in the block is a protocol
+	// violation.
+	txns := block.MsgBlock().Transactions
+	blockTxns := make([]*wire.MsgTx, 0, len(msg.Indexes))
+	for _, index := range msg.Indexes {
+		if index >= uint32(len(txns)) {
+			peerLog.Warnf("Peer %s requested transaction index %d "+
+				"of block %v which only has %d transactions -- "+
+				"disconnecting", sp, index, msg.BlockHash,
+				len(txns))
+			sp.Disconnect()
+			return
+		}
+		blockTxns = append(blockTxns, txns[index])
+	}
+
+	sp.QueueMessage(wire.NewMsgBlockTxn(&msg.BlockHash, blockTxns), nil)
+}
+
 // OnInv is invoked when a peer receives an inv bitcoin message and is
 // used to examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
@@ -569,6 +681,8 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan)
 		case wire.InvTypeFilteredBlock:
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan)
+		case wire.InvTypeCmpctBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan)
 		default:
 			peerLog.Warnf("Unknown type in inventory request %d",
 				iv.Type)
@@ -775,7 +889,7 @@ func (sp *serverPeer) OnGetHeaders(_ *peer.Peer, msg *wire.MsgGetHeaders) {
 // version  that is high enough to observe the bloom filter service support bit,
 // it will be banned since it is intentionally violating the protocol.
 func (sp *serverPeer) enforceNodeBloomFlag(cmd string) bool {
-	if sp.server.services&wire.SFNodeBloom != wire.SFNodeBloom {
+	if sp.server.Services()&wire.SFNodeBloom != wire.SFNodeBloom {
 		// Ban the peer if the protocol version is high enough that the
 		// peer is knowingly violating the protocol and banning is
 		// enabled.
@@ -805,6 +919,284 @@ func (sp *serverPeer) enforceNodeBloomFlag(cmd string) bool {
 	return true
 }
 
+// enforceServiceFlag disconnects and bans the peer when the server does not
+// advertise the passed service flag which is required to serve the passed
+// command, since the peer is knowingly violating the protocol.
+func (sp *serverPeer) enforceServiceFlag(flag wire.ServiceFlag, cmd string) bool {
+	if sp.server.Services()&flag == flag {
+		return true
+	}
+
+	peerLog.Debugf("%s sent an unsupported %s request -- disconnecting",
+		sp, cmd)
+	sp.addBanScore(100, 0, cmd)
+	sp.Disconnect()
+	return false
+}
+
+// OnGetUTXOs is invoked when a peer receives a getutxos bitcoin message.  It
+// looks up the queried outpoints in the utxo set of the best chain, and in the
+// memory pool when requested, and responds with a utxos message anchored to the
+// best block.
+func (sp *serverPeer) OnGetUTXOs(_ *peer.Peer, msg *wire.MsgGetUTXOs) {
+	if !sp.enforceServiceFlag(wire.SFNodeGetUTXO, wire.CmdGetUTXOs) {
+		return
+	}
+
+	// Ignore getutxos requests if not in sync.
+	if !sp.server.blockManager.IsCurrent() {
+		return
+	}
+
+	// A decaying ban score increase is applied to prevent flooding with
+	// queries which each hit the database.
+	sp.addBanScore(0, 10, wire.CmdGetUTXOs)
+
+	txHashes := make([]*chainhash.Hash, 0, len(msg.OutPoints))
+	for _, op := range msg.OutPoints {
+		txHashes = append(txHashes, &op.Hash)
+	}
+	chain := sp.server.blockManager.chain
+	view, best, err := chain.FetchTipUtxoView(txHashes)
+	if err != nil {
+		peerLog.Errorf("OnGetUTXOs: failed to fetch utxos: %v", err)
+		return
+	}
+
+	txMemPool := sp.server.txMemPool
+	reply := wire.NewMsgUTXOs(best.Height, best.Hash, len(msg.OutPoints))
+	for i, op := range msg.OutPoints {
+		// Outputs spent by transactions in the memory pool are
+		// reported as spent when the mempool is checked.
+		if msg.CheckMempool && txMemPool.CheckSpend(*op) != nil {
+			continue
+		}
+
+		var utxo *wire.UTXO
+		entry := view.LookupEntry(&op.Hash)
+		if entry != nil && !entry.IsOutputSpent(op.Index) {
+			utxo = &wire.UTXO{
+				TxVersion: entry.Version(),
+				Height:    entry.BlockHeight(),
+				TxOut: wire.TxOut{
+					Value:    entry.AmountByIndex(op.Index),
+					PkScript: entry.PkScriptByIndex(op.Index),
+				},
+			}
+		} else if msg.CheckMempool {
+			tx, err := txMemPool.FetchTransaction(&op.Hash)
+			if err == nil && op.Index < uint32(len(tx.MsgTx().TxOut)) {
+				utxo = &wire.UTXO{
+					TxVersion: tx.MsgTx().Version,
+					Height:    wire.MempoolHeight,
+					TxOut:     *tx.MsgTx().TxOut[op.Index],
+				}
+			}
+		}
+		if utxo != nil {
+			reply.AddUTXO(i, utxo)
+		}
+	}
+
+	sp.QueueMessage(reply, nil)
+}
+
+// OnGetAdminState is invoked when a peer receives a getadmstate bitcoin
+// message.  It responds with an admstate message which contains the admin key
+// sets of the best chain and the public keys of the queried ASP key ids, and is
+// anchored to the best block.
+func (sp *serverPeer) OnGetAdminState(_ *peer.Peer, msg *wire.MsgGetAdminState) {
+	if !sp.enforceServiceFlag(wire.SFNodeAdminState,
+		wire.CmdGetAdminState) {
+
+		return
+	}
+
+	// Ignore getadmstate requests if not in sync.
+	if !sp.server.blockManager.IsCurrent() {
+		return
+	}
+
+	// A decaying ban score increase is applied to prevent flooding.
+	sp.addBanScore(0, 10, wire.CmdGetAdminState)
+
+	state := sp.server.blockManager.chain.FetchAdminState()
+	reply := wire.NewMsgAdminState(state.BestState.Height,
+		state.BestState.Hash, state.LastKeyID)
+
+	// Add the admin key sets in a fixed order so responses of several peers
+	// can be compared.
+	for setType := btcec.RootKeySet; setType < btcec.ASPKeySet; setType++ {
+		keySet, ok := state.AdminKeySets[setType]
+		if !ok {
+			continue
+		}
+		adminKeySet := wire.AdminKeySet{
+			Type: setType,
+			PubKeys: make([][btcec.PubKeyBytesLenCompressed]byte,
+				len(keySet)),
+		}
+		for i := range keySet {
+			copy(adminKeySet.PubKeys[i][:],
+				keySet[i].SerializeCompressed())
+		}
+		reply.KeySets = append(reply.KeySets, &adminKeySet)
+	}
+
+	// Add the queried ASP keys which are provisioned.
+	for _, keyID := range msg.KeyIDs {
+		pubKey, ok := state.ASPKeyIDs[keyID]
+		if !ok {
+			continue
+		}
+		aspKey := wire.ASPKey{KeyID: keyID}
+		copy(aspKey.PubKey[:], pubKey.SerializeCompressed())
+		reply.ASPKeys = append(reply.ASPKeys, &aspKey)
+	}
+
+	sp.QueueMessage(reply, nil)
+}
+
+// cfRangeHashes returns the hashes of the main chain blocks from the passed
+// start height through the passed stop hash, which are requested by the
+// committed filter command cmd.  Nil is returned when the stop hash is not part
+// of the main chain or the range is empty or larger than maxRange, in which
+// case the request is ignored.
+func (sp *serverPeer) cfRangeHashes(startHeight uint32, stopHash *chainhash.Hash,
+	maxRange uint32, cmd string) []chainhash.Hash {
+
+	chain := sp.server.blockManager.chain
+	stopHeight, err := chain.BlockHeightByHash(stopHash)
+	if err != nil {
+		peerLog.Debugf("%s requested %s for unknown block %v", sp, cmd,
+			stopHash)
+		return nil
+	}
+	if startHeight > stopHeight || stopHeight-startHeight >= maxRange {
+		peerLog.Debugf("%s requested %s for invalid range %d to %d", sp,
+			cmd, startHeight, stopHeight)
+		return nil
+	}
+
+	hashes, err := chain.HeightRange(startHeight, stopHeight+1)
+	if err != nil {
+		peerLog.Errorf("%s: failed to fetch block hashes: %v", cmd, err)
+		return nil
+	}
+
+	// The main chain may have been reorganized since the stop block was
+	// looked up.
+	if len(hashes) == 0 || hashes[len(hashes)-1] != *stopHash {
+		return nil
+	}
+	return hashes
+}
+
+// OnGetCFilters is invoked when a peer receives a getcfilters bitcoin message.
+// It responds with a cfilter message for every block of the requested range.
+func (sp *serverPeer) OnGetCFilters(_ *peer.Peer, msg *wire.MsgGetCFilters) {
+	if !sp.enforceServiceFlag(wire.SFNodeCF, wire.CmdGetCFilters) {
+		return
+	}
+
+	// Ignore getcfilters requests if not in sync.
+	if !sp.server.blockManager.IsCurrent() {
+		return
+	}
+
+	// Only the regular filter is supported.
+	if msg.FilterType != wire.GCSFilterRegular {
+		peerLog.Debugf("%s requested unsupported filter type %d", sp,
+			msg.FilterType)
+		return
+	}
+
+	// A decaying ban score increase is applied to prevent flooding with
+	// requests which each hit the database.
+	sp.addBanScore(0, 10, wire.CmdGetCFilters)
+
+	hashes := sp.cfRangeHashes(msg.StartHeight, &msg.StopHash,
+		wire.MaxGetCFiltersReqRange, wire.CmdGetCFilters)
+	for i := range hashes {
+		filter, err := sp.server.cfIndex.FilterByBlockHash(&hashes[i])
+		if err != nil || filter == nil {
+			peerLog.Errorf("OnGetCFilters: failed to fetch filter "+
+				"of block %v: %v", hashes[i], err)
+			return
+		}
+		sp.QueueMessage(wire.NewMsgCFilter(msg.FilterType, &hashes[i],
+			filter), nil)
+	}
+}
+
+// OnGetCFHeaders is invoked when a peer receives a getcfheaders bitcoin
+// message.  It responds with a cfheaders message which contains the filter
+// hashes of the requested range and the filter header of the block before it.
+func (sp *serverPeer) OnGetCFHeaders(_ *peer.Peer, msg *wire.MsgGetCFHeaders) {
+	if !sp.enforceServiceFlag(wire.SFNodeCF, wire.CmdGetCFHeaders) {
+		return
+	}
+
+	// Ignore getcfheaders requests if not in sync.
+	if !sp.server.blockManager.IsCurrent() {
+		return
+	}
+
+	// Only the regular filter is supported.
+	if msg.FilterType != wire.GCSFilterRegular {
+		peerLog.Debugf("%s requested unsupported filter type %d", sp,
+			msg.FilterType)
+		return
+	}
+
+	// A decaying ban score increase is applied to prevent flooding with
+	// requests which each hit the database.
+	sp.addBanScore(0, 10, wire.CmdGetCFHeaders)
+
+	// Include the block before the range unless the range starts at the
+	// genesis block, whose previous filter header is all zeros, so the
+	// previous filter header is consistent with the range.
+	startHeight := msg.StartHeight
+	if startHeight > 0 {
+		startHeight--
+	}
+	hashes := sp.cfRangeHashes(startHeight, &msg.StopHash,
+		wire.MaxCFHeadersPerMsg+1, wire.CmdGetCFHeaders)
+	if hashes == nil {
+		return
+	}
+
+	cfIndex := sp.server.cfIndex
+	reply := wire.NewMsgCFHeaders()
+	reply.FilterType = msg.FilterType
+	reply.StopHash = msg.StopHash
+	if msg.StartHeight > 0 {
+		prevHeader, err := cfIndex.FilterHeaderByBlockHash(&hashes[0])
+		if err != nil || prevHeader == nil {
+			peerLog.Errorf("OnGetCFHeaders: failed to fetch filter "+
+				"header of block %v: %v", hashes[0], err)
+			return
+		}
+		reply.PrevFilterHeader = *prevHeader
+		hashes = hashes[1:]
+	}
+	if len(hashes) == 0 || len(hashes) > wire.MaxCFHeadersPerMsg {
+		return
+	}
+	for i := range hashes {
+		filter, err := cfIndex.FilterByBlockHash(&hashes[i])
+		if err != nil || filter == nil {
+			peerLog.Errorf("OnGetCFHeaders: failed to fetch filter "+
+				"of block %v: %v", hashes[i], err)
+			return
+		}
+		filterHash := chainhash.DoubleHashH(filter)
+		reply.AddCFHash(&filterHash)
+	}
+
+	sp.QueueMessage(reply, nil)
+}
+
 // OnFeeFilter is invoked when a peer receives a feefilter bitcoin message and
 // is used by remote peers to request that no transactions which have a fee rate
 // lower than provided value are inventoried to them.  The peer will be
@@ -821,6 +1213,25 @@ func (sp *serverPeer) OnFeeFilter(p *peer.Peer, msg *wire.MsgFeeFilter) {
 	atomic.StoreInt64(&sp.feeFilter, msg.MinFee)
 }
 
+// pushFeeFilterMsg sends a feefilter message with the dynamic minimum relay fee
+// of the memory pool to the peer when it supports them and the fee changed
+// since it was last sent.  Peers are asked not to announce any transactions
+// when the server doesn't accept transactions from peers.
+func (sp *serverPeer) pushFeeFilterMsg() {
+	if sp.ProtocolVersion() < wire.FeeFilterVersion {
+		return
+	}
+
+	minFee := int64(sp.server.txMemPool.DynamicMinFee())
+	if cfg.BlocksOnly {
+		minFee = provautil.MaxAtoms
+	}
+	if atomic.SwapInt64(&sp.sentFeeFilter, minFee) == minFee {
+		return
+	}
+	sp.QueueMessage(wire.NewMsgFeeFilter(minFee), nil)
+}
+
 // OnFilterAdd is invoked when a peer receives a filteradd bitcoin
 // message and is used by remote peers to add data to an already loaded bloom
 // filter.  The peer will be disconnected if a filter is not loaded when this
@@ -880,6 +1291,18 @@ func (sp *serverPeer) OnFilterLoad(_ *peer.Peer, msg *wire.MsgFilterLoad) {
 	sp.filter.Reload(msg)
 }
 
+// OnNotice is invoked when a peer receives a notice bitcoin message.  Notices
+// which are authenticated against the admin key sets of the chain are stored
+// and relayed to the other peers.  Invalid notices increase the ban score of
+// the peer to prevent it from flooding the network with them.
+func (sp *serverPeer) OnNotice(_ *peer.Peer, msg *wire.MsgNotice) {
+	if _, err := sp.server.AddNotice(msg, sp); err != nil {
+		peerLog.Debugf("Rejected notice %v from %s: %v",
+			msg.NoticeHash(), sp, err)
+		sp.addBanScore(0, 20, "notice")
+	}
+}
+
 // OnGetAddr is invoked when a peer receives a getaddr bitcoin message
 // and is used to provide the peer with known addresses from the address
 // manager.
@@ -1033,7 +1456,8 @@ func (s *server) AnnounceNewTransactions(newTxs []*mempool.TxDesc) {
 
 		if s.rpcServer != nil {
 			// Notify websocket clients about mempool transactions.
-			s.rpcServer.ntfnMgr.NotifyMempoolTx(txD.Tx, true)
+			s.rpcServer.ntfnMgr.NotifyMempoolTx(txD.Tx, txD.Tag,
+				true)
 
 			// Potentially notify any getblocktemplate long poll clients
 			// about stale block templates due to the new transaction.
@@ -1192,6 +1616,51 @@ func (s *server) pushMerkleBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneCh
 	return nil
 }
 
+// pushCmpctBlockMsg sends a cmpctblock message for the provided block hash to
+// the connected peer.  An error is returned if the block hash is not known.
+func (s *server) pushCmpctBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan chan<- struct{}, waitChan <-chan struct{}) error {
+	blk, err := sp.server.blockManager.chain.BlockByHash(hash)
+	if err != nil {
+		peerLog.Tracef("Unable to fetch requested block hash %v: %v",
+			hash, err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+
+	cmpctBlock, err := newCmpctBlock(blk)
+	if err != nil {
+		peerLog.Errorf("Unable to create compact block %v: %v", hash,
+			err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	sp.QueueMessage(cmpctBlock, doneChan)
+
+	return nil
+}
+
+// newCmpctBlock returns a cmpctblock message for the passed block using a
+// random nonce for the short transaction IDs.
+func newCmpctBlock(block *provautil.Block) (*wire.MsgCmpctBlock, error) {
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		return nil, err
+	}
+	return wire.NewMsgCmpctBlock(block.MsgBlock(), nonce), nil
+}
+
 // handleUpdatePeerHeight updates the heights of all peers who were known to
 // announce a block we recently accepted.
 func (s *server) handleUpdatePeerHeights(state *peerState, umsg updatePeerHeightsMsg) {
@@ -1340,16 +1809,37 @@ func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
 			return
 		}
 
+		// If the inventory is a block and the peer prefers compact
+		// blocks, generate and send a cmpctblock message instead of an
+		// inventory message.
+		if msg.invVect.Type == wire.InvTypeBlock && sp.WantsCmpctBlocks() {
+			block, ok := msg.data.(*provautil.Block)
+			if !ok {
+				peerLog.Warnf("Underlying data for compact block" +
+					" is not a block")
+				return
+			}
+			cmpctBlock, err := newCmpctBlock(block)
+			if err != nil {
+				peerLog.Errorf("Failed to create compact block "+
+					"%v: %v", block.Hash(), err)
+				return
+			}
+			sp.QueueMessage(cmpctBlock, nil)
+			return
+		}
+
 		// If the inventory is a block and the peer prefers headers,
 		// generate and send a headers message instead of an inventory
 		// message.
 		if msg.invVect.Type == wire.InvTypeBlock && sp.WantsHeaders() {
-			blockHeader, ok := msg.data.(wire.BlockHeader)
+			block, ok := msg.data.(*provautil.Block)
 			if !ok {
 				peerLog.Warnf("Underlying data for headers" +
-					" is not a block header")
+					" is not a block")
 				return
 			}
+			blockHeader := block.MsgBlock().Header
 			msgHeaders := wire.NewMsgHeaders()
 			if err := msgHeaders.AddBlockHeader(&blockHeader); err != nil {
 				peerLog.Errorf("Failed to add block"+
@@ -1412,6 +1902,13 @@ func (s *server) handleBroadcastMsg(state *peerState, bmsg *broadcastMsg) {
 			}
 		}
 
+		// Network notices can't be encoded for peers which negotiated
+		// a protocol version that predates them.
+		if _, ok := bmsg.message.(*wire.MsgNotice); ok &&
+			sp.ProtocolVersion() < wire.NoticeVersion {
+			return
+		}
+
 		sp.QueueMessage(bmsg.message, nil)
 	})
 }
@@ -1589,38 +2086,51 @@ func disconnectPeer(peerList map[int32]*serverPeer, compareFunc func(*serverPeer
 func newPeerConfig(sp *serverPeer) *peer.Config {
 	return &peer.Config{
 		Listeners: peer.MessageListeners{
-			OnVersion:     sp.OnVersion,
-			OnMemPool:     sp.OnMemPool,
-			OnTx:          sp.OnTx,
-			OnBlock:       sp.OnBlock,
-			OnInv:         sp.OnInv,
-			OnGetData:     sp.OnGetData,
-			OnGetBlocks:   sp.OnGetBlocks,
-			OnGetHeaders:  sp.OnGetHeaders,
-			OnFeeFilter:   sp.OnFeeFilter,
-			OnFilterAdd:   sp.OnFilterAdd,
-			OnFilterClear: sp.OnFilterClear,
-			OnFilterLoad:  sp.OnFilterLoad,
-			OnGetAddr:     sp.OnGetAddr,
-			OnAddr:        sp.OnAddr,
-			OnRead:        sp.OnRead,
-			OnWrite:       sp.OnWrite,
-
-			// Note: The reference client currently bans peers that send alerts
-			// not signed with its key.  We could verify against their key, but
-			// since the reference client is currently unwilling to support
-			// other implementations' alert messages, we will not relay theirs.
+			OnVersion:       sp.OnVersion,
+			OnMemPool:       sp.OnMemPool,
+			OnTx:            sp.OnTx,
+			OnBlock:         sp.OnBlock,
+			OnInv:           sp.OnInv,
+			OnHeaders:       sp.OnHeaders,
+			OnCmpctBlock:    sp.OnCmpctBlock,
+			OnGetBlockTxn:   sp.OnGetBlockTxn,
+			OnBlockTxn:      sp.OnBlockTxn,
+			OnGetData:       sp.OnGetData,
+			OnGetBlocks:     sp.OnGetBlocks,
+			OnGetHeaders:    sp.OnGetHeaders,
+			OnFeeFilter:     sp.OnFeeFilter,
+			OnFilterAdd:     sp.OnFilterAdd,
+			OnFilterClear:   sp.OnFilterClear,
+			OnFilterLoad:    sp.OnFilterLoad,
+			OnGetAddr:       sp.OnGetAddr,
+			OnAddr:          sp.OnAddr,
+			OnNotice:        sp.OnNotice,
+			OnGetUTXOs:      sp.OnGetUTXOs,
+			OnGetAdminState: sp.OnGetAdminState,
+			OnGetCFilters:   sp.OnGetCFilters,
+			OnGetCFHeaders:  sp.OnGetCFHeaders,
+			OnRead:          sp.OnRead,
+			OnWrite:         sp.OnWrite,
+
+			// Note: Alerts are deprecated in favor of network notices
+			// which are authenticated against the admin key sets of the
+			// chain, so they are neither verified nor relayed.
 			OnAlert: nil,
 		},
-		NewestBlock:      sp.newestBlock,
-		HostToNetAddress: sp.server.addrManager.HostToNetAddress,
-		Proxy:            cfg.Proxy,
-		UserAgentName:    userAgentName,
-		UserAgentVersion: userAgentVersion,
-		ChainParams:      sp.server.chainParams,
-		Services:         sp.server.services,
-		DisableRelayTx:   cfg.BlocksOnly,
-		ProtocolVersion:  wire.FeeFilterVersion,
+		NewestBlock:       sp.newestBlock,
+		HostToNetAddress:  sp.server.addrManager.HostToNetAddress,
+		Proxy:             cfg.Proxy,
+		UserAgentName:     userAgentName,
+		UserAgentVersion:  userAgentVersion,
+		ChainParams:       sp.server.chainParams,
+		Services:          sp.server.Services(),
+		DisableRelayTx:    cfg.BlocksOnly,
+		ProtocolVersion:   wire.CFilterVersion,
+		AuthKey:           cfg.nodeKey,
+		AuthorizeKey:      sp.server.authorizePeerKey,
+		RequireAuth:       cfg.AuthPeers,
+		EncryptTransport:  !cfg.NoEncryption,
+		RequireEncryption: cfg.RequireEncryption,
 	}
 }
 
@@ -1665,7 +2175,7 @@ func (s *server) peerDoneHandler(sp *serverPeer) {
 		s.blockManager.DonePeer(sp)
 
 		// Evict any remaining orphans that were sent by the peer.
-		numEvicted := s.txMemPool.RemoveOrphansByTag(mempool.Tag(sp.ID()))
+		numEvicted := s.txMemPool.RemoveOrphansByPeer(mempool.Tag(sp.ID()))
 		if numEvicted > 0 {
 			txmpLog.Debugf("Evicted %d %s from peer %v (id %d)",
 				numEvicted, pickNoun(numEvicted, "orphan",
@@ -1711,6 +2221,11 @@ func (s *server) peerHandler() {
 	}
 	go s.connManager.Start()
 
+	// Periodically announce changes of the dynamic minimum relay fee to
+	// the connected peers.
+	feeFilterTicker := time.NewTicker(feeFilterInterval)
+	defer feeFilterTicker.Stop()
+
 out:
 	for {
 		select {
@@ -1742,6 +2257,12 @@ out:
 		case qmsg := <-s.query:
 			s.handleQuery(state, qmsg)
 
+		// Announce changes of the dynamic minimum relay fee.
+		case <-feeFilterTicker.C:
+			state.forAllPeers(func(sp *serverPeer) {
+				sp.pushFeeFilterMsg()
+			})
+
 		case <-s.quit:
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *serverPeer) {
@@ -1794,12 +2315,151 @@ func (s *server) RelayInventory(invVect *wire.InvVect, data interface{}) {
 // BroadcastMessage sends msg to all peers currently connected to the server
 // except those in the passed peers to exclude.
 func (s *server) BroadcastMessage(msg wire.Message, exclPeers ...*serverPeer) {
-	// XXX: Need to determine if this is an alert that has already been
-	// broadcast and refrain from broadcasting again.
 	bmsg := broadcastMsg{message: msg, excludePeers: exclPeers}
 	s.broadcast <- bmsg
 }
 
+// authorizePeerKey returns the identity of the passed public key which a peer
+// proved control of during the handshake.  The keys of the admin key sets and
+// the ASP keys, which are provisioned with admin transactions, are authorized
+// as of the end of the main chain.
+//
+// This function is safe for concurrent access.
+func (s *server) authorizePeerKey(pubKey *btcec.PublicKey) (string, error) {
+	chain := s.blockManager.chain
+	adminKeySets := chain.AdminKeySets()
+	for _, keySetType := range []btcec.KeySetType{btcec.RootKeySet,
+		btcec.ProvisionKeySet, btcec.IssueKeySet, btcec.ValidateKeySet} {
+
+		if adminKeySets[keySetType].Pos(pubKey) >= 0 {
+			return fmt.Sprintf("%v:%x", keySetType,
+				pubKey.SerializeCompressed()), nil
+		}
+	}
+	for keyID, aspKey := range chain.KeyIDs() {
+		if aspKey.IsEqual(pubKey) {
+			return fmt.Sprintf("%v:%d", btcec.ASPKeySet, keyID), nil
+		}
+	}
+
+	return "", fmt.Errorf("key %x is neither an admin key nor an ASP key",
+		pubKey.SerializeCompressed())
+}
+
+// AddNotice validates the passed network notice against the admin key sets of
+// the chain and, when it has not been seen before, stores it, relays it to all
+// connected peers other than the source peer, and notifies websocket clients.
+// The source peer may be nil when the notice was submitted locally.  It returns
+// whether the notice was new.
+//
+// This function is safe for concurrent access.
+func (s *server) AddNotice(notice *wire.MsgNotice, source *serverPeer) (bool, error) {
+	if err := s.blockManager.chain.CheckNotice(notice); err != nil {
+		return false, err
+	}
+
+	hash := notice.NoticeHash()
+	s.noticesMtx.Lock()
+	if _, ok := s.notices[hash]; ok {
+		s.noticesMtx.Unlock()
+		return false, nil
+	}
+
+	// Make room for the notice when the limit is reached by removing the
+	// notices which are no longer valid and then the least important one.
+	// The notice is ignored when it is not more important than any of the
+	// notices which are kept.
+	if len(s.notices) >= maxNotices {
+		notices := s.pruneNotices()
+		if len(notices) >= maxNotices {
+			last := notices[len(notices)-1]
+			if !noticeSorter([]*wire.MsgNotice{notice, last}).Less(0, 1) {
+				s.noticesMtx.Unlock()
+				srvrLog.Debugf("Ignoring network notice %v since "+
+					"the limit of %d notices is reached", hash,
+					maxNotices)
+				return false, nil
+			}
+			delete(s.notices, last.NoticeHash())
+		}
+	}
+	s.notices[hash] = notice
+	s.noticesMtx.Unlock()
+
+	srvrLog.Infof("Received network notice %v signed by the %v key set: %s",
+		hash, btcec.KeySetType(notice.KeySet), notice.Message)
+
+	if source != nil {
+		s.BroadcastMessage(notice, source)
+	} else {
+		s.BroadcastMessage(notice)
+	}
+	if s.rpcServer != nil {
+		s.rpcServer.ntfnMgr.NotifyNotice(notice)
+	}
+
+	return true, nil
+}
+
+// ActiveNotices returns the network notices which are still valid against the
+// current admin key sets of the chain, ordered by descending priority and then
+// by descending timestamp.  Notices which expired or are no longer signed by a
+// quorum of the current keys are removed.
+//
+// This function is safe for concurrent access.
+func (s *server) ActiveNotices() []*wire.MsgNotice {
+	s.noticesMtx.Lock()
+	notices := s.pruneNotices()
+	s.noticesMtx.Unlock()
+	return notices
+}
+
+// pruneNotices removes the network notices which expired or are no longer
+// signed by a quorum of the current keys and returns the remaining ones ordered
+// by descending priority and then by descending timestamp.
+//
+// This function MUST be called with the notices lock held (for writes).
+func (s *server) pruneNotices() []*wire.MsgNotice {
+	notices := make([]*wire.MsgNotice, 0, len(s.notices))
+	for hash, notice := range s.notices {
+		if err := s.blockManager.chain.CheckNotice(notice); err != nil {
+			srvrLog.Debugf("Removing network notice %v: %v", hash,
+				err)
+			delete(s.notices, hash)
+			continue
+		}
+		notices = append(notices, notice)
+	}
+
+	sort.Sort(noticeSorter(notices))
+	return notices
+}
+
+// noticeSorter implements sort.Interface to allow a slice of network notices to
+// be sorted by descending priority and then by descending timestamp.
+type noticeSorter []*wire.MsgNotice
+
+// Len returns the number of notices in the slice.  It is part of the
+// sort.Interface implementation.
+func (s noticeSorter) Len() int {
+	return len(s)
+}
+
+// Swap swaps the notices at the passed indices.  It is part of the
+// sort.Interface implementation.
+func (s noticeSorter) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+// Less returns whether the notice with index i should sort before the notice
+// with index j.  It is part of the sort.Interface implementation.
+func (s noticeSorter) Less(i, j int) bool {
+	if s[i].Priority != s[j].Priority {
+		return s[i].Priority > s[j].Priority
+	}
+	return s[i].Timestamp.After(s[j].Timestamp)
+}
+
 // ConnectedCount returns the number of currently connected peers.
 func (s *server) ConnectedCount() int32 {
 	replyChan := make(chan int32)
@@ -1958,7 +2618,13 @@ out:
 		case <-timer.C:
 			// Any inventory we have has not made it into a block
 			// yet. We periodically resubmit them until they have.
+			// Transactions which are no longer in the memory pool,
+			// such as the ones which were replaced, are dropped.
 			for iv, data := range pendingInvs {
+				if !s.txMemPool.IsTransactionInPool(&iv.Hash) {
+					delete(pendingInvs, iv)
+					continue
+				}
 				ivCopy := iv
 				s.RelayInventory(&ivCopy, data)
 			}
@@ -2042,11 +2708,84 @@ func (s *server) Stop() error {
 		s.rpcServer.Stop()
 	}
 
+	// Save the fee estimator state in the database.
+	err := s.db.Update(func(dbTx database.Tx) error {
+		return dbTx.Metadata().Put(mempool.EstimateFeeDatabaseKey,
+			s.feeEstimator.Save())
+	})
+	if err != nil {
+		srvrLog.Errorf("Failed to save fee estimator state: %v", err)
+	}
+
+	// Save the transactions in the memory pool so they are reloaded on the
+	// next start.
+	if !cfg.NoPersistMempool {
+		numTxns, err := s.saveMempool()
+		if err != nil {
+			srvrLog.Errorf("Failed to save mempool: %v", err)
+		} else {
+			srvrLog.Infof("Saved %d mempool transactions", numTxns)
+		}
+	}
+
 	// Signal the remaining goroutines to quit.
 	close(s.quit)
 	return nil
 }
 
+// saveMempool writes the transactions in the memory pool to the mempool file
+// in the data directory and returns the number of transactions written.  The
+// transactions are written to a temporary file first so a partially written
+// file never replaces a complete one.
+//
+// This function is safe for concurrent access.
+func (s *server) saveMempool() (int, error) {
+	s.mempoolFileMtx.Lock()
+	defer s.mempoolFileMtx.Unlock()
+
+	path := filepath.Join(cfg.DataDir, mempoolFileName)
+	tmpPath := path + ".new"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	numTxns, err := s.txMemPool.Save(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	return numTxns, nil
+}
+
+// loadMempool processes the transactions saved to the mempool file in the
+// data directory.  It returns the transactions added to the memory pool along
+// with the number of saved transactions which were rejected.
+//
+// This function is safe for concurrent access.
+func (s *server) loadMempool() ([]*mempool.TxDesc, int, error) {
+	s.mempoolFileMtx.Lock()
+	defer s.mempoolFileMtx.Unlock()
+
+	f, err := os.Open(filepath.Join(cfg.DataDir, mempoolFileName))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	return s.txMemPool.Load(bufio.NewReader(f))
+}
+
 // WaitForShutdown blocks until the main listener and peer handlers are stopped.
 func (s *server) WaitForShutdown() {
 	s.wg.Wait()
@@ -2171,7 +2910,7 @@ out:
 					continue out
 				}
 				na := wire.NewNetAddressIPPort(externalip, uint16(listenPort),
-					s.services)
+					s.Services())
 				err = s.addrManager.AddLocalAddress(na, addrmgr.UpnpPrio)
 				if err != nil {
 					// XXX DeletePortMapping?
@@ -2196,6 +2935,38 @@ out:
 	s.wg.Done()
 }
 
+// limitedServices returns the passed services adjusted for a node which does
+// not have the full block history.  Only the most recent blocks are served and
+// the committed filter index, which can't be built without the history, is not
+// maintained.
+func limitedServices(services wire.ServiceFlag) wire.ServiceFlag {
+	services &^= wire.SFNodeNetwork | wire.SFNodeCF
+	return services | wire.SFNodeNetworkLimited
+}
+
+// Services returns the services the server advertises to peers.
+//
+// This function is safe for concurrent access.
+func (s *server) Services() wire.ServiceFlag {
+	return wire.ServiceFlag(atomic.LoadUint64(&s.services))
+}
+
+// limitServices stops advertising the services which require the full block
+// history.  It is used once the chain is bootstrapped from a utxo set snapshot
+// while the server is running.  Peers which are already connected keep the
+// services they negotiated.
+//
+// This function is safe for concurrent access.
+func (s *server) limitServices() {
+	for {
+		services := atomic.LoadUint64(&s.services)
+		limited := uint64(limitedServices(wire.ServiceFlag(services)))
+		if atomic.CompareAndSwapUint64(&s.services, services, limited) {
+			return
+		}
+	}
+}
+
 // newServer returns a new btcd server configured to listen on addr for the
 // bitcoin network type specified by chainParams.  Use start to begin accepting
 // connections from peers.
@@ -2204,6 +2975,41 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 	if cfg.NoPeerBloomFilters {
 		services &^= wire.SFNodeBloom
 	}
+	if cfg.StateQueries {
+		services |= wire.SFNodeGetUTXO | wire.SFNodeAdminState
+	}
+
+	// A pruned node, or one which will prune, no longer has the full block
+	// history, so advertise that only the most recent blocks are served.
+	// The same applies to a node which is, or will be, bootstrapped from a
+	// utxo set snapshot since it never had the blocks before the snapshot.
+	pruned := cfg.Prune != 0 || cfg.LoadSnapshot != ""
+	if !pruned {
+		err := db.View(func(dbTx database.Tx) error {
+			var err error
+			pruned, err = dbTx.BeenPruned()
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !pruned {
+		var err error
+		pruned, err = blockchain.IsSnapshotBootstrapped(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pruned {
+		services = limitedServices(services)
+	}
+
+	// The committed filter index is not maintained by pruned nodes, so
+	// committed filters are only served when it is enabled.
+	if cfg.NoCFilters {
+		services &^= wire.SFNodeCF
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, btcdLookup)
 
@@ -2346,24 +3152,30 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		nat:                  nat,
 		db:                   db,
 		timeSource:           blockchain.NewMedianTime(),
-		services:             services,
+		services:             uint64(services),
+		notices:              make(map[chainhash.Hash]*wire.MsgNotice),
 		sigCache:             txscript.NewSigCache(cfg.SigCacheMaxSize),
 		hashCache:            txscript.NewHashCache(cfg.SigCacheMaxSize),
 	}
 
-	// Create the transaction and address indexes if needed.
+	// Create the transaction, address, and keyID indexes if needed.
 	//
 	// CAUTION: the txindex needs to be first in the indexes array because
-	// the addrindex uses data from the txindex during catchup.  If the
-	// addrindex is run first, it may not have the transactions from the
-	// current block indexed.
+	// the addrindex and keyidindex use data from the txindex during
+	// catchup.  If they are run first, they may not have the transactions
+	// from the current block indexed.
 	var indexes []indexers.Indexer
-	if cfg.TxIndex || cfg.AddrIndex {
-		// Enable transaction index if address index is enabled since it
-		// requires it.
+	if pruned && (cfg.TxIndex || cfg.AddrIndex || cfg.KeyIDIndex) {
+		return nil, errors.New("the optional indexes may not be " +
+			"used with a pruned database since they require all " +
+			"blocks to be available")
+	}
+	if cfg.TxIndex || cfg.AddrIndex || cfg.KeyIDIndex {
+		// Enable transaction index if the address or keyID index is
+		// enabled since they require it.
 		if !cfg.TxIndex {
 			indxLog.Infof("Transaction index enabled because it " +
-				"is required by the address index")
+				"is required by the address or keyID index")
 			cfg.TxIndex = true
 		} else {
 			indxLog.Info("Transaction index is enabled")
@@ -2377,6 +3189,25 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		s.addrIndex = indexers.NewAddrIndex(db, chainParams)
 		indexes = append(indexes, s.addrIndex)
 	}
+	if cfg.KeyIDIndex {
+		indxLog.Info("KeyID index is enabled")
+		s.keyIDIndex = indexers.NewKeyIDIndex(db)
+		indexes = append(indexes, s.keyIDIndex)
+	}
+	if !cfg.NoCFilters {
+		// The index can't be caught up without the full block history,
+		// so it is disabled instead of failing like the indexes which
+		// have to be requested explicitly.
+		if pruned {
+			indxLog.Info("Committed filter index is disabled " +
+				"since the database is pruned or bootstrapped " +
+				"from a utxo set snapshot")
+		} else {
+			indxLog.Info("Committed filter index is enabled")
+			s.cfIndex = indexers.NewCfIndex(db)
+			indexes = append(indexes, s.cfIndex)
+		}
+	}
 
 	// Create an index manager if any of the optional indexes are enabled.
 	var indexManager blockchain.IndexManager
@@ -2389,6 +3220,44 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 	}
 	s.blockManager = bm
 
+	// Search for a fee estimator state in the database.  If none can be
+	// found or if it cannot be loaded, create a new one.  The state is
+	// deleted once it is read so a stale state is never restored after an
+	// unclean shutdown.
+	err = db.Update(func(dbTx database.Tx) error {
+		metadata := dbTx.Metadata()
+		feeEstimationData := metadata.Get(mempool.EstimateFeeDatabaseKey)
+		if feeEstimationData == nil {
+			return nil
+		}
+
+		var err error
+		s.feeEstimator, err = mempool.RestoreFeeEstimator(
+			feeEstimationData)
+		if err != nil {
+			srvrLog.Warnf("Failed to restore fee estimator: %v", err)
+		}
+		return metadata.Delete(mempool.EstimateFeeDatabaseKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Start over when the restored estimator is behind the chain since the
+	// confirmation delays of the blocks it missed are unknown.
+	if s.feeEstimator == nil || s.feeEstimator.LastKnownHeight() !=
+		bm.chain.BestSnapshot().Height {
+
+		s.feeEstimator = mempool.NewFeeEstimator(
+			mempool.DefaultEstimateFeeMaxRollback,
+			mempool.DefaultEstimateFeeMinRegisteredBlocks)
+	}
+
+	tagPolicies := make(map[mempool.Tag]mempool.TagPolicy,
+		len(cfg.FreeRelayTags))
+	for _, tag := range cfg.FreeRelayTags {
+		tagPolicies[mempool.Tag(tag)] = mempool.TagPolicy{AllowFree: true}
+	}
 	txC := mempool.Config{
 		Policy: mempool.Policy{
 			DisableRelayPriority: !cfg.RelayPriority,
@@ -2399,6 +3268,11 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 			MaxSigOpsPerTx:       blockchain.MaxSigOpsPerBlock / 5,
 			MinRelayTxFee:        cfg.minRelayTxFee,
 			MaxTxVersion:         2,
+			AcceptReplacement:    cfg.AcceptReplacement,
+			MaxPoolSize:          int64(cfg.MaxMempool) * 1000000,
+			MaxPackageCount:      mempool.DefaultMaxPackageCount,
+			MaxPackageSize:       mempool.DefaultMaxPackageSize,
+			TagPolicies:          tagPolicies,
 		},
 		ChainParams:     chainParams,
 		FetchUtxoView:   s.blockManager.chain.FetchUtxoView,
@@ -2413,12 +3287,28 @@ func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Param
 		HashCache:       s.hashCache,
 		TimeSource:      s.timeSource,
 		AddrIndex:       s.addrIndex,
+		FeeEstimator:    s.feeEstimator,
 		CalcSequenceLock: func(tx *provautil.Tx, view *blockchain.UtxoViewpoint) (*blockchain.SequenceLock, error) {
 			return bm.chain.CalcSequenceLock(tx, view, true)
 		},
 	}
 	s.txMemPool = mempool.New(&txC)
 
+	// Reload the transactions saved from the memory pool on the last
+	// shutdown.  They are revalidated against the current chain, so the
+	// ones which were mined or became invalid in the meantime are dropped.
+	if !cfg.NoPersistMempool {
+		accepted, rejected, err := s.loadMempool()
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			srvrLog.Warnf("Failed to load saved mempool: %v", err)
+		default:
+			srvrLog.Infof("Loaded %d saved mempool transactions "+
+				"(%d rejected)", len(accepted), rejected)
+		}
+	}
+
 	// Create the mining policy and block template generator based on the
 	// configuration options.
 	//
@@ -74,6 +74,12 @@ const (
 	// ScriptVerifyStrictEncoding defines that signature scripts and
 	// public keys must follow the strict encoding requirements.
 	ScriptVerifyStrictEncoding
+
+	// ScriptVerifyCompressedPubKeys defines that all public keys checked
+	// by OP_CHECKSIG, OP_CHECKSAFEMULTISIG and OP_CHECKTHREAD must be in
+	// the 33-byte compressed form.  This is the public key type
+	// restriction of BIP0143.
+	ScriptVerifyCompressedPubKeys
 )
 
 const (
@@ -416,18 +422,29 @@ func (vm *Engine) checkHashTypeEncoding(hashType SigHashType) error {
 	return nil
 }
 
+// isCompressedPubKey returns whether or not the passed public key is encoded
+// in the 33-byte compressed form.
+func isCompressedPubKey(pubKey []byte) bool {
+	return len(pubKey) == btcec.PubKeyBytesLenCompressed &&
+		(pubKey[0] == 0x02 || pubKey[0] == 0x03)
+}
+
 // checkPubKeyEncoding returns whether or not the passed public key adheres to
-// the strict encoding requirements if enabled.
+// the strict encoding and compressed public key requirements if enabled.
 func (vm *Engine) checkPubKeyEncoding(pubKey []byte) error {
+	if vm.hasFlag(ScriptVerifyCompressedPubKeys) && !isCompressedPubKey(pubKey) {
+		return scriptError(ErrUncompressedPubKey,
+			"only compressed public keys are allowed")
+	}
+
 	if !vm.hasFlag(ScriptVerifyStrictEncoding) {
 		return nil
 	}
 
-	if len(pubKey) == 33 && (pubKey[0] == 0x02 || pubKey[0] == 0x03) {
+	if isCompressedPubKey(pubKey) {
 		// Compressed
 		return nil
 	}
-	// TODO(prova): disallow uncompressed keys
 	if len(pubKey) == 65 && pubKey[0] == 0x04 {
 		// Uncompressed
 		return nil
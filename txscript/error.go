@@ -190,6 +190,11 @@ const (
 	// operations.
 	ErrNullFail
 
+	// ErrUncompressedPubKey is returned when the
+	// ScriptVerifyCompressedPubKeys flag is set and the script contains a
+	// public key which is not in the 33-byte compressed form.
+	ErrUncompressedPubKey
+
 	// -------------------------------
 	// Failures related to soft forks.
 	// -------------------------------
@@ -254,6 +259,7 @@ var errorCodeStrings = map[ErrorCode]string{
 	ErrPubKeyType:               "ErrPubKeyType",
 	ErrCleanStack:               "ErrCleanStack",
 	ErrNullFail:                 "ErrNullFail",
+	ErrUncompressedPubKey:       "ErrUncompressedPubKey",
 	ErrDiscourageUpgradableNOPs: "ErrDiscourageUpgradableNOPs",
 	ErrNegativeLockTime:         "ErrNegativeLockTime",
 	ErrUnsatisfiedLockTime:      "ErrUnsatisfiedLockTime",
@@ -135,6 +135,8 @@ func parseScriptFlags(flagStr string) (ScriptFlags, error) {
 			flags |= ScriptVerifyCheckLockTimeVerify
 		case "CHECKSEQUENCEVERIFY":
 			flags |= ScriptVerifyCheckSequenceVerify
+		case "COMPRESSED_PUBKEYS":
+			flags |= ScriptVerifyCompressedPubKeys
 		case "CLEANSTACK":
 			flags |= ScriptVerifyCleanStack
 		case "DERSIG":
@@ -289,6 +289,54 @@ func ExtractKeyIDs(pkScript []parsedOpcode) ([]btcec.KeyID, error) {
 	return keyIDs, nil
 }
 
+// ExtractProvaKeys takes a Prova pkScript and returns the pubkey hashes and
+// the keyIDs it pays to, in the order they appear in the script.  Unlike
+// ExtractKeyIDs it supports the generalized m-of-n structure:
+// <x hash/keyID hash/keyID y OP_CHECKSAFEMULTISIG>
+// An error is returned for any script which is not a Prova script.
+func ExtractProvaKeys(pkScript []byte) ([][]byte, []btcec.KeyID, error) {
+	pops, err := ParseScript(pkScript)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isGeneralProva(pops) {
+		return nil, nil, fmt.Errorf("unable to extract keys from script, "+
+			"not a prova script %v", pops)
+	}
+	var keyHashes [][]byte
+	var keyIDs []btcec.KeyID
+	for _, pop := range pops[1 : len(pops)-2] {
+		if len(pop.data) == 20 {
+			keyHashes = append(keyHashes, pop.data)
+			continue
+		}
+		if !isUint32(pop.opcode) {
+			continue
+		}
+		keyID, err := asInt32(pop)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyIDs = append(keyIDs, btcec.KeyID(keyID))
+	}
+	return keyHashes, keyIDs, nil
+}
+
+// ProvaRequiredSigs returns the number of signatures which are required to
+// spend the passed Prova pkScript.  An error is returned for any script which
+// is not a Prova script.
+func ProvaRequiredSigs(pkScript []byte) (int, error) {
+	pops, err := ParseScript(pkScript)
+	if err != nil {
+		return 0, err
+	}
+	if !isGeneralProva(pops) {
+		return 0, fmt.Errorf("unable to extract required signatures "+
+			"from script, not a prova script %v", pops)
+	}
+	return asSmallInt(pops[0].opcode), nil
+}
+
 // ReplaceKeyIds replaces keyIds in a pkScript with pubKeyHashes.
 // We assume a Prova address structure like this:
 // basic: <2 hash keyID1 keyID2 3 OP_CHECKSAFEMULTISIG>
@@ -350,13 +398,30 @@ func ThreadPkScript(keyHashes [][]byte) ([]byte, error) {
 	return pkScript.AddInt64(int64(len(keyHashes))).AddOp(OP_CHECKTHREAD).Script()
 }
 
+// parseAdminPubKey parses the public key which follows the operation type byte
+// in the data push of an admin op.  Admin keys are only ever accepted in the
+// 33-byte compressed form, so any other encoding is rejected.
+func parseAdminPubKey(data []byte) (*btcec.PublicKey, error) {
+	if len(data) < 1+btcec.PubKeyBytesLenCompressed {
+		str := fmt.Sprintf("admin op data length %d is too short for "+
+			"a public key", len(data))
+		return nil, scriptError(ErrMalformedPush, str)
+	}
+	pkBytes := data[1 : 1+btcec.PubKeyBytesLenCompressed]
+	if !isCompressedPubKey(pkBytes) {
+		return nil, scriptError(ErrUncompressedPubKey,
+			"admin op public key is not compressed")
+	}
+	return btcec.ParsePubKey(pkBytes, btcec.S256())
+}
+
 // ExtractAdminData can read OP_*KEYADD and OP_*KEYREVOKE from admin outputs.
 // An admin op script of structure <OP_RETURN><OP_DATA> can be assumed from
 // previous validation.
 // This function returns the admin operation type byte, and the parsed
 // public key.
 func ExtractAdminData(pkScript []parsedOpcode) (byte, *btcec.PublicKey, error) {
-	pubKey, err := btcec.ParsePubKey(pkScript[1].data[1:1+btcec.PubKeyBytesLenCompressed], btcec.S256())
+	pubKey, err := parseAdminPubKey(pkScript[1].data)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -369,7 +434,7 @@ func ExtractAdminData(pkScript []parsedOpcode) (byte, *btcec.PublicKey, error) {
 // This function returns the admin operation type byte, the parsed keyID, and
 // the parsed public key.
 func ExtractASPData(pkScript []parsedOpcode) (byte, *btcec.PublicKey, btcec.KeyID, error) {
-	pubKey, err := btcec.ParsePubKey(pkScript[1].data[1:1+btcec.PubKeyBytesLenCompressed], btcec.S256())
+	pubKey, err := parseAdminPubKey(pkScript[1].data)
 	if err != nil {
 		return 0, nil, 0, err
 	}
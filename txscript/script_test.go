@@ -4163,3 +4163,49 @@ func TestIsUnspendable(t *testing.T) {
 		}
 	}
 }
+
+// TestParseAdminPubKey ensures the public key of an admin op is only accepted
+// in the compressed form and that data which is too short to hold a public key
+// is rejected as malformed.
+func TestParseAdminPubKey(t *testing.T) {
+	t.Parallel()
+
+	compressed := hexToBytes("0279be667ef9dcbbac55a06295ce870b07029bfcdb2d" +
+		"ce28d959f2815b16f81798")
+	uncompressed := hexToBytes("0479be667ef9dcbbac55a06295ce870b07029bfcdb2d" +
+		"ce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448" +
+		"a68554199c47d08ffb10d4b8")
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{
+			name: "compressed public key",
+			data: append([]byte{AdminOpIssueKeyAdd}, compressed...),
+			err:  nil,
+		},
+		{
+			name: "uncompressed public key",
+			data: append([]byte{AdminOpIssueKeyAdd}, uncompressed...),
+			err:  scriptError(ErrUncompressedPubKey, ""),
+		},
+		{
+			name: "truncated public key",
+			data: append([]byte{AdminOpIssueKeyAdd}, compressed[:32]...),
+			err:  scriptError(ErrMalformedPush, ""),
+		},
+		{
+			name: "operation type only",
+			data: []byte{AdminOpIssueKeyAdd},
+			err:  scriptError(ErrMalformedPush, ""),
+		},
+	}
+
+	for _, test := range tests {
+		_, err := parseAdminPubKey(test.data)
+		if e := tstCheckScriptError(err, test.err); e != nil {
+			t.Errorf("%s: %v", test.name, e)
+		}
+	}
+}
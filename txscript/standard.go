@@ -39,7 +39,8 @@ const (
 		ScriptVerifyNullFail |
 		ScriptVerifyCheckLockTimeVerify |
 		ScriptVerifyCheckSequenceVerify |
-		ScriptVerifyLowS
+		ScriptVerifyLowS |
+		ScriptVerifyCompressedPubKeys
 )
 
 // ScriptClass is an enumeration for the list of standard types of script.
@@ -121,6 +121,88 @@ func TestExtractPkScriptAddrs(t *testing.T) {
 	}
 }
 
+// TestExtractProvaKeys ensures that extracting the pubkey hashes and keyIDs
+// from Prova pkScripts works as intended.
+func TestExtractProvaKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		script    []byte
+		keyHashes [][]byte
+		keyIDs    []btcec.KeyID
+		nRequired int
+		valid     bool
+	}{
+		{
+			name: "standard prova",
+			script: decodeHex("521435dbbf04bca061e49dace08f858d87" +
+				"75c0a57c8e030000015153ba"),
+			keyHashes: [][]byte{
+				decodeHex("35dbbf04bca061e49dace08f858d8775c0a57c8e"),
+			},
+			keyIDs:    []btcec.KeyID{0x10000, 1},
+			nRequired: 2,
+			valid:     true,
+		},
+		{
+			name: "general prova",
+			script: mustParseShortForm("3 DATA_20 0x35dbbf04bca061e49dac" +
+				"e08f858d8775c0a57c8e DATA_20 0x0102030405060708090a0" +
+				"b0c0d0e0f1011121314 1 2 3 5 CHECKSAFEMULTISIG"),
+			keyHashes: [][]byte{
+				decodeHex("35dbbf04bca061e49dace08f858d8775c0a57c8e"),
+				decodeHex("0102030405060708090a0b0c0d0e0f1011121314"),
+			},
+			keyIDs:    []btcec.KeyID{1, 2, 3},
+			nRequired: 3,
+			valid:     true,
+		},
+		{
+			name:   "admin thread script",
+			script: mustParseShortForm("1 CHECKTHREAD"),
+			valid:  false,
+		},
+		{
+			name:   "script that does not parse",
+			script: []byte{OP_DATA_45},
+			valid:  false,
+		},
+	}
+
+	for i, test := range tests {
+		keyHashes, keyIDs, err := ExtractProvaKeys(test.script)
+		if (err == nil) != test.valid {
+			t.Errorf("ExtractProvaKeys #%d (%s) unexpected error "+
+				"result - got %v, want valid %v", i, test.name,
+				err, test.valid)
+			continue
+		}
+		if !test.valid {
+			continue
+		}
+		if !reflect.DeepEqual(keyHashes, test.keyHashes) {
+			t.Errorf("ExtractProvaKeys #%d (%s) unexpected key "+
+				"hashes\ngot  %x\nwant %x", i, test.name,
+				keyHashes, test.keyHashes)
+			continue
+		}
+		if !reflect.DeepEqual(keyIDs, test.keyIDs) {
+			t.Errorf("ExtractProvaKeys #%d (%s) unexpected key "+
+				"ids\ngot  %v\nwant %v", i, test.name, keyIDs,
+				test.keyIDs)
+			continue
+		}
+
+		nRequired, err := ProvaRequiredSigs(test.script)
+		if err != nil || nRequired != test.nRequired {
+			t.Errorf("ProvaRequiredSigs #%d (%s) unexpected result "+
+				"- got %d (%v), want %d", i, test.name,
+				nRequired, err, test.nRequired)
+		}
+	}
+}
+
 // TestIsValidAdminOp tests the IsValidAdminOp function.
 func TestIsValidAdminOp(t *testing.T) {
 	// Create some dummy admin op output.
@@ -41,7 +41,7 @@ func (s BlockSignature) String() string {
 
 // BlockVersion is the current latest supported block version.
 // TODO(prova): change this
-const BlockVersion = 4
+const BlockVersion = 5
 
 // MaxBlockHeaderPayload is the maximum number of bytes a block header can be.
 const MaxBlockHeaderPayload = 32 + (chainhash.HashSize * 2) + BlockValidatingPubKeySize + BlockSignatureSize
@@ -29,29 +29,45 @@ const MaxMessagePayload = (1024 * 1024 * 32) // 32MB
 
 // Commands used in bitcoin message headers which describe the type of message.
 const (
-	CmdVersion     = "version"
-	CmdVerAck      = "verack"
-	CmdGetAddr     = "getaddr"
-	CmdAddr        = "addr"
-	CmdGetBlocks   = "getblocks"
-	CmdInv         = "inv"
-	CmdGetData     = "getdata"
-	CmdNotFound    = "notfound"
-	CmdBlock       = "block"
-	CmdTx          = "tx"
-	CmdGetHeaders  = "getheaders"
-	CmdHeaders     = "headers"
-	CmdPing        = "ping"
-	CmdPong        = "pong"
-	CmdAlert       = "alert"
-	CmdMemPool     = "mempool"
-	CmdFilterAdd   = "filteradd"
-	CmdFilterClear = "filterclear"
-	CmdFilterLoad  = "filterload"
-	CmdMerkleBlock = "merkleblock"
-	CmdReject      = "reject"
-	CmdSendHeaders = "sendheaders"
-	CmdFeeFilter   = "feefilter"
+	CmdVersion       = "version"
+	CmdVerAck        = "verack"
+	CmdGetAddr       = "getaddr"
+	CmdAddr          = "addr"
+	CmdGetBlocks     = "getblocks"
+	CmdInv           = "inv"
+	CmdGetData       = "getdata"
+	CmdNotFound      = "notfound"
+	CmdBlock         = "block"
+	CmdTx            = "tx"
+	CmdGetHeaders    = "getheaders"
+	CmdHeaders       = "headers"
+	CmdPing          = "ping"
+	CmdPong          = "pong"
+	CmdAlert         = "alert"
+	CmdMemPool       = "mempool"
+	CmdFilterAdd     = "filteradd"
+	CmdFilterClear   = "filterclear"
+	CmdFilterLoad    = "filterload"
+	CmdMerkleBlock   = "merkleblock"
+	CmdReject        = "reject"
+	CmdSendHeaders   = "sendheaders"
+	CmdFeeFilter     = "feefilter"
+	CmdSendCmpct     = "sendcmpct"
+	CmdCmpctBlock    = "cmpctblock"
+	CmdGetBlockTxn   = "getblocktxn"
+	CmdBlockTxn      = "blocktxn"
+	CmdNotice        = "notice"
+	CmdAuthChallenge = "authchal"
+	CmdAuthProof     = "authproof"
+	CmdEncInit       = "encinit"
+	CmdGetUTXOs      = "getutxos"
+	CmdUTXOs         = "utxos"
+	CmdGetAdminState = "getadmstate"
+	CmdAdminState    = "admstate"
+	CmdGetCFilters   = "getcfilters"
+	CmdCFilter       = "cfilter"
+	CmdGetCFHeaders  = "getcfheaders"
+	CmdCFHeaders     = "cfheaders"
 )
 
 // Message is an interface that describes a bitcoin message.  A type that
@@ -139,6 +155,54 @@ func makeEmptyMessage(command string) (Message, error) {
 	case CmdFeeFilter:
 		msg = &MsgFeeFilter{}
 
+	case CmdSendCmpct:
+		msg = &MsgSendCmpct{}
+
+	case CmdCmpctBlock:
+		msg = &MsgCmpctBlock{}
+
+	case CmdGetBlockTxn:
+		msg = &MsgGetBlockTxn{}
+
+	case CmdBlockTxn:
+		msg = &MsgBlockTxn{}
+
+	case CmdNotice:
+		msg = &MsgNotice{}
+
+	case CmdAuthChallenge:
+		msg = &MsgAuthChallenge{}
+
+	case CmdAuthProof:
+		msg = &MsgAuthProof{}
+
+	case CmdEncInit:
+		msg = &MsgEncInit{}
+
+	case CmdGetUTXOs:
+		msg = &MsgGetUTXOs{}
+
+	case CmdUTXOs:
+		msg = &MsgUTXOs{}
+
+	case CmdGetAdminState:
+		msg = &MsgGetAdminState{}
+
+	case CmdAdminState:
+		msg = &MsgAdminState{}
+
+	case CmdGetCFilters:
+		msg = &MsgGetCFilters{}
+
+	case CmdCFilter:
+		msg = &MsgCFilter{}
+
+	case CmdGetCFHeaders:
+		msg = &MsgGetCFHeaders{}
+
+	case CmdCFHeaders:
+		msg = &MsgCFHeaders{}
+
 	default:
 		return nil, fmt.Errorf("unhandled command [%s]", command)
 	}
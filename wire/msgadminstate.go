@@ -0,0 +1,257 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+const (
+	// maxAdminKeySetsPerMsg is the maximum number of admin key sets in a
+	// bitcoin admstate message.
+	maxAdminKeySetsPerMsg = 8
+
+	// maxKeysPerAdminKeySet is the maximum number of public keys of an admin
+	// key set in a bitcoin admstate message.
+	maxKeysPerAdminKeySet = 1000
+)
+
+// AdminKeySet defines the public keys of an admin key set in a bitcoin
+// admstate message.
+type AdminKeySet struct {
+	Type    btcec.KeySetType
+	PubKeys [][btcec.PubKeyBytesLenCompressed]byte
+}
+
+// ASPKey defines the public key of an ASP key id in a bitcoin admstate
+// message.
+type ASPKey struct {
+	KeyID  btcec.KeyID
+	PubKey [btcec.PubKeyBytesLenCompressed]byte
+}
+
+// MsgAdminState implements the Message interface and represents a bitcoin
+// admstate message.  It is sent in response to a getadmstate message
+// (MsgGetAdminState) and anchored to the block at the given height and hash
+// the state was looked up at, so light clients can cross-check the responses
+// of several peers.
+//
+// The message contains all admin key sets of the chain, the last assigned ASP
+// key id, and the public keys of the queried ASP key ids which are currently
+// provisioned.  Queried key ids without a public key in the message are not
+// provisioned.
+//
+// This message was not added until protocol versions starting with
+// StateQueryVersion.
+type MsgAdminState struct {
+	Height    uint32
+	BlockHash chainhash.Hash
+	LastKeyID btcec.KeyID
+	KeySets   []*AdminKeySet
+	ASPKeys   []*ASPKey
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgAdminState) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("admstate message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgAdminState.BtcDecode", str)
+	}
+
+	var lastKeyID uint32
+	err := readElements(r, &msg.Height, &msg.BlockHash, &lastKeyID)
+	if err != nil {
+		return err
+	}
+	msg.LastKeyID = btcec.KeyID(lastKeyID)
+
+	// Read num admin key sets and limit to max.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxAdminKeySetsPerMsg {
+		str := fmt.Sprintf("too many admin key sets for message "+
+			"[count %v, max %v]", count, maxAdminKeySetsPerMsg)
+		return messageError("MsgAdminState.BtcDecode", str)
+	}
+	msg.KeySets = make([]*AdminKeySet, 0, count)
+	for i := uint64(0); i < count; i++ {
+		var setType uint8
+		err := readElement(r, &setType)
+		if err != nil {
+			return err
+		}
+
+		// Read num public keys and limit to max.
+		numKeys, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		if numKeys > maxKeysPerAdminKeySet {
+			str := fmt.Sprintf("too many keys for admin key set "+
+				"[count %v, max %v]", numKeys,
+				maxKeysPerAdminKeySet)
+			return messageError("MsgAdminState.BtcDecode", str)
+		}
+		keySet := AdminKeySet{
+			Type:    btcec.KeySetType(setType),
+			PubKeys: make([][btcec.PubKeyBytesLenCompressed]byte, numKeys),
+		}
+		for j := range keySet.PubKeys {
+			_, err := io.ReadFull(r, keySet.PubKeys[j][:])
+			if err != nil {
+				return err
+			}
+		}
+		msg.KeySets = append(msg.KeySets, &keySet)
+	}
+
+	// Read num ASP keys and limit to max.
+	count, err = ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > MaxGetAdminStatePerMsg {
+		str := fmt.Sprintf("too many ASP keys for message "+
+			"[count %v, max %v]", count, MaxGetAdminStatePerMsg)
+		return messageError("MsgAdminState.BtcDecode", str)
+	}
+
+	// Create a contiguous slice of ASP keys to deserialize into in order to
+	// reduce the number of allocations.
+	aspKeys := make([]ASPKey, count)
+	msg.ASPKeys = make([]*ASPKey, 0, count)
+	for i := uint64(0); i < count; i++ {
+		aspKey := &aspKeys[i]
+		keyID, err := binarySerializer.Uint32(r, littleEndian)
+		if err != nil {
+			return err
+		}
+		aspKey.KeyID = btcec.KeyID(keyID)
+		_, err = io.ReadFull(r, aspKey.PubKey[:])
+		if err != nil {
+			return err
+		}
+		msg.ASPKeys = append(msg.ASPKeys, aspKey)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgAdminState) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("admstate message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgAdminState.BtcEncode", str)
+	}
+
+	// Limit to max admin key sets and ASP keys per message.
+	if len(msg.KeySets) > maxAdminKeySetsPerMsg {
+		str := fmt.Sprintf("too many admin key sets for message "+
+			"[count %v, max %v]", len(msg.KeySets),
+			maxAdminKeySetsPerMsg)
+		return messageError("MsgAdminState.BtcEncode", str)
+	}
+	if len(msg.ASPKeys) > MaxGetAdminStatePerMsg {
+		str := fmt.Sprintf("too many ASP keys for message "+
+			"[count %v, max %v]", len(msg.ASPKeys),
+			MaxGetAdminStatePerMsg)
+		return messageError("MsgAdminState.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.Height, &msg.BlockHash,
+		uint32(msg.LastKeyID))
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.KeySets)))
+	if err != nil {
+		return err
+	}
+	for _, keySet := range msg.KeySets {
+		if len(keySet.PubKeys) > maxKeysPerAdminKeySet {
+			str := fmt.Sprintf("too many keys for admin key set "+
+				"[count %v, max %v]", len(keySet.PubKeys),
+				maxKeysPerAdminKeySet)
+			return messageError("MsgAdminState.BtcEncode", str)
+		}
+
+		err := writeElement(w, uint8(keySet.Type))
+		if err != nil {
+			return err
+		}
+		err = WriteVarInt(w, pver, uint64(len(keySet.PubKeys)))
+		if err != nil {
+			return err
+		}
+		for i := range keySet.PubKeys {
+			_, err := w.Write(keySet.PubKeys[i][:])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.ASPKeys)))
+	if err != nil {
+		return err
+	}
+	for _, aspKey := range msg.ASPKeys {
+		err := binarySerializer.PutUint32(w, littleEndian,
+			uint32(aspKey.KeyID))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(aspKey.PubKey[:])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgAdminState) Command() string {
+	return CmdAdminState
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgAdminState) MaxPayloadLength(pver uint32) uint32 {
+	// Height 4 bytes + block hash + last key id 4 bytes + num admin key
+	// sets (varInt) + max allowed admin key sets of a type byte, num keys
+	// (varInt) and max allowed keys each + num ASP keys (varInt) + max
+	// allowed ASP keys of a key id and a key each.
+	return 4 + chainhash.HashSize + 4 + MaxVarIntPayload +
+		(maxAdminKeySetsPerMsg * (1 + MaxVarIntPayload +
+			maxKeysPerAdminKeySet*btcec.PubKeyBytesLenCompressed)) +
+		MaxVarIntPayload + (MaxGetAdminStatePerMsg *
+		(4 + btcec.PubKeyBytesLenCompressed))
+}
+
+// NewMsgAdminState returns a new bitcoin admstate message anchored to the
+// block at the passed height and hash that conforms to the Message interface.
+// See MsgAdminState for details.
+func NewMsgAdminState(height uint32, blockHash *chainhash.Hash,
+	lastKeyID btcec.KeyID) *MsgAdminState {
+
+	return &MsgAdminState{
+		Height:    height,
+		BlockHash: *blockHash,
+		LastKeyID: lastKeyID,
+	}
+}
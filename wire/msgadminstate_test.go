@@ -0,0 +1,117 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetAdminStateWire tests the MsgGetAdminState wire encode and decode.
+func TestGetAdminStateWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgGetAdminState()
+	if cmd := msg.Command(); cmd != "getadmstate" {
+		t.Errorf("NewMsgGetAdminState: wrong command - got %v want %v",
+			cmd, "getadmstate")
+	}
+	for i := 0; i < MaxGetAdminStatePerMsg; i++ {
+		if err := msg.AddKeyID(btcec.KeyID(i)); err != nil {
+			t.Fatalf("AddKeyID: unexpected error %v", err)
+		}
+	}
+	if err := msg.AddKeyID(0); err == nil {
+		t.Errorf("AddKeyID: expected error on too many key ids")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgGetAdminState failed %v", err)
+	}
+	if uint32(buf.Len()) > msg.MaxPayloadLength(pver) {
+		t.Errorf("BtcEncode: length %d exceeds max payload %d",
+			buf.Len(), msg.MaxPayloadLength(pver))
+	}
+	var readmsg MsgGetAdminState
+	if err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver); err != nil {
+		t.Fatalf("decode of MsgGetAdminState failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+		StateQueryVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgGetAdminState passed for old protocol " +
+			"version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, StateQueryVersion-1); err == nil {
+		t.Errorf("encode of MsgGetAdminState passed for old protocol " +
+			"version")
+	}
+}
+
+// TestAdminStateWire tests the MsgAdminState wire encode and decode.
+func TestAdminStateWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	blockHash := chainhash.Hash{0x01}
+	msg := NewMsgAdminState(10, &blockHash, 3)
+	if cmd := msg.Command(); cmd != "admstate" {
+		t.Errorf("NewMsgAdminState: wrong command - got %v want %v",
+			cmd, "admstate")
+	}
+	var pubKey [btcec.PubKeyBytesLenCompressed]byte
+	pubKey[0] = 0x02
+	msg.KeySets = []*AdminKeySet{{
+		Type:    btcec.RootKeySet,
+		PubKeys: [][btcec.PubKeyBytesLenCompressed]byte{pubKey, pubKey},
+	}, {
+		Type:    btcec.ValidateKeySet,
+		PubKeys: [][btcec.PubKeyBytesLenCompressed]byte{pubKey},
+	}}
+	msg.ASPKeys = []*ASPKey{{KeyID: 1, PubKey: pubKey}, {KeyID: 3,
+		PubKey: pubKey}}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgAdminState failed %v", err)
+	}
+	if uint32(buf.Len()) > msg.MaxPayloadLength(pver) {
+		t.Errorf("BtcEncode: length %d exceeds max payload %d",
+			buf.Len(), msg.MaxPayloadLength(pver))
+	}
+	var readmsg MsgAdminState
+	if err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver); err != nil {
+		t.Fatalf("decode of MsgAdminState failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+		StateQueryVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgAdminState passed for old protocol " +
+			"version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, StateQueryVersion-1); err == nil {
+		t.Errorf("encode of MsgAdminState passed for old protocol " +
+			"version")
+	}
+}
@@ -319,6 +319,9 @@ func NewAlertFromPayload(serializedPayload []byte, pver uint32) (*Alert, error)
 // This is a signed message that provides notifications that the client should
 // display if the signature matches the key.  bitcoind/bitcoin-qt only checks
 // against a signature from the core developers.
+//
+// NOTE: Deprecated.  Alerts are neither verified nor relayed.  Use MsgNotice
+// for network notices signed by the admin keys of the chain instead.
 type MsgAlert struct {
 	// SerializedPayload is the alert payload serialized as a string so that the
 	// version can change but the Alert can still be passed on by older
@@ -0,0 +1,99 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+// AuthChallengeSize is the number of random bytes of an authchal message.
+const AuthChallengeSize = 32
+
+// authProofMagic is prepended to the challenge before hashing it so a proof
+// signature can't be mistaken for a signature of any other kind of data.
+var authProofMagic = []byte("Prova node authentication:\n")
+
+// MsgAuthChallenge implements the Message interface and represents a bitcoin
+// authchal message.  It is sent during the handshake by peers which
+// authenticate each other and carries random data the remote peer has to sign
+// with its node key in the authproof message (MsgAuthProof).
+//
+// This message was not added until protocol versions starting with
+// NodeAuthVersion.
+type MsgAuthChallenge struct {
+	Challenge [AuthChallengeSize]byte
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgAuthChallenge) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < NodeAuthVersion {
+		str := fmt.Sprintf("authchal message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgAuthChallenge.BtcDecode", str)
+	}
+
+	_, err := io.ReadFull(r, msg.Challenge[:])
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgAuthChallenge) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < NodeAuthVersion {
+		str := fmt.Sprintf("authchal message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgAuthChallenge.BtcEncode", str)
+	}
+
+	_, err := w.Write(msg.Challenge[:])
+	return err
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgAuthChallenge) Command() string {
+	return CmdAuthChallenge
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgAuthChallenge) MaxPayloadLength(pver uint32) uint32 {
+	return AuthChallengeSize
+}
+
+// ProofHash returns the hash an authproof message answering the challenge
+// signs.  The passed session id identifies the encrypted transport the
+// challenge was received over, which binds the proof to the connection so it
+// can't be relayed to another peer.  It is nil for plaintext connections.  The
+// passed transcript describes the version messages the peers exchanged, which
+// binds the proof to the negotiated services so a man-in-the-middle can't
+// change them, for instance to prevent encryption.
+func (msg *MsgAuthChallenge) ProofHash(sessionID, transcript []byte) chainhash.Hash {
+	var buf bytes.Buffer
+	buf.Grow(len(authProofMagic) + AuthChallengeSize + len(sessionID) +
+		len(transcript))
+	buf.Write(authProofMagic)
+	buf.Write(msg.Challenge[:])
+	buf.Write(sessionID)
+	buf.Write(transcript)
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// NewMsgAuthChallenge returns a new bitcoin authchal message with a random
+// challenge that conforms to the Message interface.  See MsgAuthChallenge for
+// details.
+func NewMsgAuthChallenge() (*MsgAuthChallenge, error) {
+	var msg MsgAuthChallenge
+	if _, err := rand.Read(msg.Challenge[:]); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/btcec"
+)
+
+// MaxAuthProofSignatureSize is the maximum number of bytes of the DER encoded
+// signature of an authproof message.
+const MaxAuthProofSignatureSize = 72
+
+// maxAuthProofPayload is the maximum number of bytes an authproof message can
+// be.  Public key length 1 byte + public key 33 bytes + signature length 1
+// byte + signature.
+const maxAuthProofPayload = 2 + btcec.PubKeyBytesLenCompressed +
+	MaxAuthProofSignatureSize
+
+// MsgAuthProof implements the Message interface and represents a bitcoin
+// authproof message.  It answers an authchal message (MsgAuthChallenge)
+// during the handshake and proves the sending peer controls the private key of
+// the included public key by signing the challenge.  A peer which has no node
+// key sends a proof with an empty public key and signature.
+//
+// This message was not added until protocol versions starting with
+// NodeAuthVersion.
+type MsgAuthProof struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgAuthProof) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < NodeAuthVersion {
+		str := fmt.Sprintf("authproof message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgAuthProof.BtcDecode", str)
+	}
+
+	var err error
+	msg.PubKey, err = ReadVarBytes(r, pver,
+		btcec.PubKeyBytesLenCompressed, "authproof public key")
+	if err != nil {
+		return err
+	}
+	msg.Signature, err = ReadVarBytes(r, pver, MaxAuthProofSignatureSize,
+		"authproof signature")
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgAuthProof) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < NodeAuthVersion {
+		str := fmt.Sprintf("authproof message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgAuthProof.BtcEncode", str)
+	}
+	if len(msg.PubKey) > btcec.PubKeyBytesLenCompressed {
+		str := fmt.Sprintf("authproof public key is too long [len %d, "+
+			"max %d]", len(msg.PubKey), btcec.PubKeyBytesLenCompressed)
+		return messageError("MsgAuthProof.BtcEncode", str)
+	}
+	if len(msg.Signature) > MaxAuthProofSignatureSize {
+		str := fmt.Sprintf("authproof signature is too long [len %d, "+
+			"max %d]", len(msg.Signature), MaxAuthProofSignatureSize)
+		return messageError("MsgAuthProof.BtcEncode", str)
+	}
+
+	if err := WriteVarBytes(w, pver, msg.PubKey); err != nil {
+		return err
+	}
+	return WriteVarBytes(w, pver, msg.Signature)
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgAuthProof) Command() string {
+	return CmdAuthProof
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgAuthProof) MaxPayloadLength(pver uint32) uint32 {
+	return maxAuthProofPayload
+}
+
+// NewMsgAuthProof returns a new bitcoin authproof message which answers the
+// passed challenge received over the connection with the passed session id and
+// handshake transcript and conforms to the Message interface.  The proof is
+// signed with the passed key, or is empty when the key is nil.  See
+// MsgAuthProof and MsgAuthChallenge.ProofHash for details.
+func NewMsgAuthProof(key *btcec.PrivateKey, challenge *MsgAuthChallenge,
+	sessionID, transcript []byte) (*MsgAuthProof, error) {
+
+	if key == nil {
+		return &MsgAuthProof{}, nil
+	}
+
+	hash := challenge.ProofHash(sessionID, transcript)
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		return nil, err
+	}
+	return &MsgAuthProof{
+		PubKey:    key.PubKey().SerializeCompressed(),
+		Signature: signature.Serialize(),
+	}, nil
+}
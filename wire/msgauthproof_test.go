@@ -0,0 +1,147 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestAuthChallengeWire tests the MsgAuthChallenge wire encode and decode.
+func TestAuthChallengeWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg, err := NewMsgAuthChallenge()
+	if err != nil {
+		t.Fatalf("NewMsgAuthChallenge: unexpected error: %v", err)
+	}
+	if cmd := msg.Command(); cmd != "authchal" {
+		t.Errorf("NewMsgAuthChallenge: wrong command - got %v want %v",
+			cmd, "authchal")
+	}
+	other, err := NewMsgAuthChallenge()
+	if err != nil {
+		t.Fatalf("NewMsgAuthChallenge: unexpected error: %v", err)
+	}
+	if msg.Challenge == other.Challenge {
+		t.Errorf("NewMsgAuthChallenge: challenges are not random")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgAuthChallenge failed %v", err)
+	}
+	if uint32(buf.Len()) != msg.MaxPayloadLength(pver) {
+		t.Errorf("BtcEncode: got length %d, want %d", buf.Len(),
+			msg.MaxPayloadLength(pver))
+	}
+	var readmsg MsgAuthChallenge
+	if err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver); err != nil {
+		t.Fatalf("decode of MsgAuthChallenge failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), NodeAuthVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgAuthChallenge passed for old protocol " +
+			"version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, NodeAuthVersion-1); err == nil {
+		t.Errorf("encode of MsgAuthChallenge passed for old protocol " +
+			"version")
+	}
+}
+
+// TestAuthProofWire tests the MsgAuthProof wire encode and decode for signed
+// and empty proofs.
+func TestAuthProofWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	challenge, err := NewMsgAuthChallenge()
+	if err != nil {
+		t.Fatalf("NewMsgAuthChallenge: unexpected error: %v", err)
+	}
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x01}, 32))
+	sessionID := bytes.Repeat([]byte{0x02}, 32)
+	transcript := bytes.Repeat([]byte{0x03}, 40)
+	signed, err := NewMsgAuthProof(key, challenge, sessionID, transcript)
+	if err != nil {
+		t.Fatalf("NewMsgAuthProof: unexpected error: %v", err)
+	}
+	if cmd := signed.Command(); cmd != "authproof" {
+		t.Errorf("NewMsgAuthProof: wrong command - got %v want %v",
+			cmd, "authproof")
+	}
+
+	// Ensure the signature of the proof is made over the proof hash of the
+	// challenge for the session and transcript with the key.
+	sig, err := btcec.ParseDERSignature(signed.Signature, btcec.S256())
+	if err != nil {
+		t.Fatalf("ParseDERSignature: unexpected error: %v", err)
+	}
+	hash := challenge.ProofHash(sessionID, transcript)
+	if !sig.Verify(hash[:], key.PubKey()) {
+		t.Errorf("NewMsgAuthProof: signature does not verify")
+	}
+	hash = challenge.ProofHash(nil, transcript)
+	if sig.Verify(hash[:], key.PubKey()) {
+		t.Errorf("NewMsgAuthProof: signature verifies for another " +
+			"session")
+	}
+	hash = challenge.ProofHash(sessionID, transcript[1:])
+	if sig.Verify(hash[:], key.PubKey()) {
+		t.Errorf("NewMsgAuthProof: signature verifies for another " +
+			"transcript")
+	}
+	if !bytes.Equal(signed.PubKey, key.PubKey().SerializeCompressed()) {
+		t.Errorf("NewMsgAuthProof: wrong public key %x", signed.PubKey)
+	}
+
+	empty, err := NewMsgAuthProof(nil, challenge, sessionID, transcript)
+	if err != nil {
+		t.Fatalf("NewMsgAuthProof: unexpected error: %v", err)
+	}
+
+	for _, msg := range []*MsgAuthProof{signed, empty} {
+		var buf bytes.Buffer
+		if err := msg.BtcEncode(&buf, pver); err != nil {
+			t.Fatalf("encode of MsgAuthProof failed %v", err)
+		}
+		if uint32(buf.Len()) > msg.MaxPayloadLength(pver) {
+			t.Errorf("BtcEncode: length %d exceeds max payload %d",
+				buf.Len(), msg.MaxPayloadLength(pver))
+		}
+		var readmsg MsgAuthProof
+		err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver)
+		if err != nil {
+			t.Fatalf("decode of MsgAuthProof failed %v", err)
+		}
+		if !bytes.Equal(readmsg.PubKey, msg.PubKey) ||
+			!bytes.Equal(readmsg.Signature, msg.Signature) {
+
+			t.Errorf("BtcDecode\n got: %s want: %s",
+				spew.Sdump(readmsg), spew.Sdump(msg))
+		}
+
+		// Older protocol versions should fail encode and decode since
+		// the message didn't exist yet.
+		err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+			NodeAuthVersion-1)
+		if err == nil {
+			t.Errorf("decode of MsgAuthProof passed for old " +
+				"protocol version")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBlockTxnWire tests the MsgBlockTxn wire encode and decode.
+func TestBlockTxnWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	hash := chainhash.Hash{0x01}
+	msg := NewMsgBlockTxn(&hash, []*MsgTx{multiTx})
+	if cmd := msg.Command(); cmd != "blocktxn" {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, "blocktxn")
+	}
+
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Fatalf("encode of MsgBlockTxn failed %v", err)
+	}
+	wantLen := chainhash.HashSize + 1 + multiTx.SerializeSize()
+	if buf.Len() != wantLen {
+		t.Errorf("BtcEncode: wrong length - got %d, want %d",
+			buf.Len(), wantLen)
+	}
+
+	var readmsg MsgBlockTxn
+	err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver)
+	if err != nil {
+		t.Fatalf("decode of MsgBlockTxn failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail decode since the message didn't
+	// exist yet.
+	err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+		CompactBlocksVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgBlockTxn passed for old protocol " +
+			"version")
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCFilterMessagesWire tests the wire encode and decode of the committed
+// filter messages.
+func TestCFilterMessagesWire(t *testing.T) {
+	pver := ProtocolVersion
+	stopHash := chainhash.Hash{0x01, 0x02}
+
+	cfHeaders := NewMsgCFHeaders()
+	cfHeaders.StopHash = stopHash
+	cfHeaders.PrevFilterHeader = chainhash.Hash{0x03}
+	for i := 0; i < MaxCFHeadersPerMsg; i++ {
+		if err := cfHeaders.AddCFHash(&chainhash.Hash{byte(i)}); err != nil {
+			t.Fatalf("AddCFHash: unexpected error %v", err)
+		}
+	}
+	if err := cfHeaders.AddCFHash(&chainhash.Hash{}); err == nil {
+		t.Errorf("AddCFHash: expected error on too many hashes")
+	}
+
+	tests := []struct {
+		msg     Message
+		readMsg Message
+		command string
+	}{
+		{
+			msg:     NewMsgGetCFilters(GCSFilterRegular, 10, &stopHash),
+			readMsg: &MsgGetCFilters{},
+			command: "getcfilters",
+		},
+		{
+			msg: NewMsgCFilter(GCSFilterRegular, &stopHash,
+				bytes.Repeat([]byte{0xaa}, MaxCFilterDataSize)),
+			readMsg: &MsgCFilter{},
+			command: "cfilter",
+		},
+		{
+			msg:     NewMsgGetCFHeaders(GCSFilterRegular, 10, &stopHash),
+			readMsg: &MsgGetCFHeaders{},
+			command: "getcfheaders",
+		},
+		{
+			msg:     cfHeaders,
+			readMsg: &MsgCFHeaders{},
+			command: "cfheaders",
+		},
+	}
+
+	for i, test := range tests {
+		if cmd := test.msg.Command(); cmd != test.command {
+			t.Errorf("Command #%d: wrong command - got %v want %v",
+				i, cmd, test.command)
+		}
+
+		var buf bytes.Buffer
+		if err := test.msg.BtcEncode(&buf, pver); err != nil {
+			t.Fatalf("encode #%d failed %v", i, err)
+		}
+		if uint32(buf.Len()) > test.msg.MaxPayloadLength(pver) {
+			t.Errorf("BtcEncode #%d: length %d exceeds max payload "+
+				"%d", i, buf.Len(), test.msg.MaxPayloadLength(pver))
+		}
+		err := test.readMsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver)
+		if err != nil {
+			t.Fatalf("decode #%d failed %v", i, err)
+		}
+		if !reflect.DeepEqual(test.readMsg, test.msg) {
+			t.Errorf("BtcDecode #%d\n got: %s want: %s", i,
+				spew.Sdump(test.readMsg), spew.Sdump(test.msg))
+		}
+
+		// Older protocol versions should fail encode and decode since
+		// the messages didn't exist yet.
+		err = test.readMsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+			CFilterVersion-1)
+		if err == nil {
+			t.Errorf("decode #%d passed for old protocol version", i)
+		}
+		err = test.msg.BtcEncode(&bytes.Buffer{}, CFilterVersion-1)
+		if err == nil {
+			t.Errorf("encode #%d passed for old protocol version", i)
+		}
+	}
+}
+
+// TestCFilterTooLarge ensures filters larger than the maximum filter size are
+// rejected.
+func TestCFilterTooLarge(t *testing.T) {
+	pver := ProtocolVersion
+	msg := NewMsgCFilter(GCSFilterRegular, &chainhash.Hash{},
+		make([]byte, MaxCFilterDataSize+1))
+	if err := msg.BtcEncode(&bytes.Buffer{}, pver); err == nil {
+		t.Errorf("encode of oversized filter passed")
+	}
+
+	// Encode the oversized filter by hand to make sure decoding rejects it
+	// as well.
+	var buf bytes.Buffer
+	writeElement(&buf, msg.FilterType)
+	writeElement(&buf, &msg.BlockHash)
+	WriteVarBytes(&buf, pver, msg.Data)
+	var readMsg MsgCFilter
+	if err := readMsg.BtcDecode(&buf, pver); err == nil {
+		t.Errorf("decode of oversized filter passed")
+	}
+}
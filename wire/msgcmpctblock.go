@@ -0,0 +1,273 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+// ShortTxIDSize is the number of bytes used to encode a short transaction ID
+// in a cmpctblock message.
+const ShortTxIDSize = 6
+
+// maxCmpctBlockTxIndex is the maximum index of a transaction which can be
+// referenced by a cmpctblock or getblocktxn message.
+const maxCmpctBlockTxIndex = math.MaxUint16
+
+// PrefilledTx is a transaction which is included in full in a cmpctblock
+// message along with its index in the block.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a bitcoin
+// cmpctblock message.  It is used to relay a block in a compact form which
+// identifies most of its transactions by short transaction IDs so the
+// receiving peer can reconstruct the block from the transactions it already
+// has (BIP0152).  The short transaction IDs are calculated from the full hash
+// of the transactions including their signatures, so a transaction with
+// different signatures is never mistaken for the one in the block.
+//
+// This message was not added until protocol versions starting with
+// CompactBlocksVersion.
+type MsgCmpctBlock struct {
+	Header       BlockHeader
+	Nonce        uint64
+	ShortIDs     []uint64
+	PrefilledTxs []PrefilledTx
+}
+
+// TotalTransactions returns the number of transactions in the block the
+// compact block represents.
+func (msg *MsgCmpctBlock) TotalTransactions() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxs)
+}
+
+// ShortIDKeys returns the SipHash-2-4 keys used to calculate the short
+// transaction IDs of the compact block.  They are the first two little-endian
+// 64-bit integers of the single SHA256 of the serialized block header followed
+// by the little-endian nonce.
+func (msg *MsgCmpctBlock) ShortIDKeys() (uint64, uint64) {
+	var buf bytes.Buffer
+	buf.Grow(MaxBlockHeaderPayload + 8)
+	// Ignore the error returns since the only way the encode could fail
+	// is being out of memory or due to nil pointers, both of which would
+	// cause a run-time panic.
+	_ = writeBlockHeader(&buf, 0, &msg.Header)
+	_ = writeElement(&buf, msg.Nonce)
+	hash := chainhash.HashB(buf.Bytes())
+	return littleEndian.Uint64(hash[0:8]), littleEndian.Uint64(hash[8:16])
+}
+
+// ShortTxID returns the short transaction ID of the passed full transaction
+// hash (including signatures, see MsgTx.TxHashWithSig) using the passed keys
+// from ShortIDKeys.
+func ShortTxID(k0, k1 uint64, txHash *chainhash.Hash) uint64 {
+	return SipHash24(k0, k1, txHash[:]) & (1<<(8*ShortTxIDSize) - 1)
+}
+
+// readShortTxID reads a little-endian short transaction ID from r.
+func readShortTxID(r io.Reader) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:ShortTxIDSize]); err != nil {
+		return 0, err
+	}
+	return littleEndian.Uint64(buf[:]), nil
+}
+
+// writeShortTxID writes the passed short transaction ID to w in little-endian.
+func writeShortTxID(w io.Writer, shortID uint64) error {
+	var buf [8]byte
+	littleEndian.PutUint64(buf[:], shortID)
+	_, err := w.Write(buf[:ShortTxIDSize])
+	return err
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	err := readBlockHeader(r, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+	err = readElement(r, &msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	// Prevent more short IDs than could possibly fit into a block.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short ids to fit into a block "+
+			"[count %d, max %d]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+	msg.ShortIDs = make([]uint64, 0, count)
+	for i := uint64(0); i < count; i++ {
+		shortID, err := readShortTxID(r)
+		if err != nil {
+			return err
+		}
+		msg.ShortIDs = append(msg.ShortIDs, shortID)
+	}
+
+	// Prevent more prefilled transactions than could possibly fit into a
+	// block along with the short IDs.  The number of short IDs is already
+	// limited, so subtracting it can't underflow while adding it to the
+	// count could overflow.
+	count, err = ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock-uint64(len(msg.ShortIDs)) {
+		str := fmt.Sprintf("too many prefilled transactions to fit "+
+			"into a block [count %d, short ids %d, max %d]", count,
+			len(msg.ShortIDs), maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	// The indexes of the prefilled transactions are differentially
+	// encoded relative to the index following the previous one.
+	msg.PrefilledTxs = make([]PrefilledTx, 0, count)
+	nextIndex := uint64(0)
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarInt(r, pver)
+		if err != nil {
+			return err
+		}
+		index := nextIndex + diff
+		if index > maxCmpctBlockTxIndex || index < nextIndex {
+			str := fmt.Sprintf("prefilled transaction index %d "+
+				"is out of range", index)
+			return messageError("MsgCmpctBlock.BtcDecode", str)
+		}
+
+		tx := MsgTx{}
+		err = tx.BtcDecode(r, pver)
+		if err != nil {
+			return err
+		}
+		msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{
+			Index: uint32(index),
+			Tx:    &tx,
+		})
+		nextIndex = index + 1
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < CompactBlocksVersion {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+
+	err := writeBlockHeader(w, pver, &msg.Header)
+	if err != nil {
+		return err
+	}
+	err = writeElement(w, msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.ShortIDs)))
+	if err != nil {
+		return err
+	}
+	for _, shortID := range msg.ShortIDs {
+		err = writeShortTxID(w, shortID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarInt(w, pver, uint64(len(msg.PrefilledTxs)))
+	if err != nil {
+		return err
+	}
+	nextIndex := uint32(0)
+	for _, ptx := range msg.PrefilledTxs {
+		if ptx.Index < nextIndex || ptx.Index > maxCmpctBlockTxIndex {
+			str := fmt.Sprintf("prefilled transaction index %d "+
+				"is out of order or range", ptx.Index)
+			return messageError("MsgCmpctBlock.BtcEncode", str)
+		}
+		err = WriteVarInt(w, pver, uint64(ptx.Index-nextIndex))
+		if err != nil {
+			return err
+		}
+		err = ptx.Tx.BtcEncode(w, pver)
+		if err != nil {
+			return err
+		}
+		nextIndex = ptx.Index + 1
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	// A short ID is never larger than the transaction it replaces, so the
+	// only overhead compared to a full block is the nonce, an additional
+	// count and the differentially encoded prefilled transaction indexes.
+	return MaxBlockPayload + 8 + MaxVarIntPayload + maxTxPerBlock*3
+}
+
+// BlockHash computes the block identifier hash for this compact block.
+func (msg *MsgCmpctBlock) BlockHash() chainhash.Hash {
+	return msg.Header.BlockHash()
+}
+
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message for the passed
+// block that conforms to the Message interface.  The coinbase transaction is
+// prefilled since the receiving peer can't have it yet, and all other
+// transactions are identified by their short transaction IDs.  See
+// MsgCmpctBlock for details.
+func NewMsgCmpctBlock(block *MsgBlock, nonce uint64) *MsgCmpctBlock {
+	msg := &MsgCmpctBlock{
+		Header: block.Header,
+		Nonce:  nonce,
+	}
+	if len(block.Transactions) == 0 {
+		return msg
+	}
+
+	msg.PrefilledTxs = []PrefilledTx{{Index: 0, Tx: block.Transactions[0]}}
+	msg.ShortIDs = make([]uint64, 0, len(block.Transactions)-1)
+	k0, k1 := msg.ShortIDKeys()
+	for _, tx := range block.Transactions[1:] {
+		txHash := tx.TxHashWithSig()
+		msg.ShortIDs = append(msg.ShortIDs, ShortTxID(k0, k1, &txHash))
+	}
+	return msg
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestSipHash24 ensures the SipHash-2-4 implementation used for short
+// transaction IDs matches the reference test vectors.
+func TestSipHash24(t *testing.T) {
+	// The reference vectors use the key 00 01 02 ... 0f and messages of
+	// the form 00 01 02 ... (len - 1).
+	k0 := uint64(0x0706050403020100)
+	k1 := uint64(0x0f0e0d0c0b0a0908)
+	tests := []struct {
+		len  int
+		want uint64
+	}{
+		{0, 0x726fdb47dd0e0e31},
+		{8, 0x93f5f5799a932462},
+		{15, 0xa129ca6149be45e5},
+	}
+
+	for i, test := range tests {
+		data := make([]byte, test.len)
+		for j := range data {
+			data[j] = byte(j)
+		}
+		got := SipHash24(k0, k1, data)
+		if got != test.want {
+			t.Errorf("SipHash24 #%d: got %x, want %x", i, got,
+				test.want)
+		}
+	}
+}
+
+// TestCmpctBlock tests the MsgCmpctBlock API.
+func TestCmpctBlock(t *testing.T) {
+	pver := ProtocolVersion
+
+	block := NewMsgBlock(&blockOne.Header)
+	block.AddTransaction(blockOne.Transactions[0])
+	block.AddTransaction(multiTx)
+	msg := NewMsgCmpctBlock(block, 0x0123456789abcdef)
+
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure the coinbase is prefilled and the remaining transactions are
+	// identified by the short ID of their full hash.
+	if msg.TotalTransactions() != 2 {
+		t.Errorf("TotalTransactions: got %d, want 2",
+			msg.TotalTransactions())
+	}
+	if len(msg.PrefilledTxs) != 1 || msg.PrefilledTxs[0].Index != 0 ||
+		msg.PrefilledTxs[0].Tx != blockOne.Transactions[0] {
+		t.Errorf("NewMsgCmpctBlock: coinbase not prefilled - got %v",
+			spew.Sdump(msg.PrefilledTxs))
+	}
+	k0, k1 := msg.ShortIDKeys()
+	txHash := multiTx.TxHashWithSig()
+	wantShortID := ShortTxID(k0, k1, &txHash)
+	if len(msg.ShortIDs) != 1 || msg.ShortIDs[0] != wantShortID {
+		t.Errorf("NewMsgCmpctBlock: wrong short ids - got %x, want %x",
+			msg.ShortIDs, wantShortID)
+	}
+	if wantShortID>>(8*ShortTxIDSize) != 0 {
+		t.Errorf("ShortTxID: short id %x is larger than %d bytes",
+			wantShortID, ShortTxIDSize)
+	}
+
+	// Changing the nonce must change the short ids.
+	other := NewMsgCmpctBlock(block, 0)
+	if other.ShortIDs[0] == msg.ShortIDs[0] {
+		t.Errorf("NewMsgCmpctBlock: short id did not change with nonce")
+	}
+
+	// Ensure the message round trips through the wire encoding.
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Fatalf("encode of MsgCmpctBlock failed %v", err)
+	}
+	var readmsg MsgCmpctBlock
+	err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver)
+	if err != nil {
+		t.Fatalf("decode of MsgCmpctBlock failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+	if readmsg.BlockHash() != block.BlockHash() {
+		t.Errorf("BlockHash: got %v, want %v", readmsg.BlockHash(),
+			block.BlockHash())
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	oldPver := CompactBlocksVersion - 1
+	if err := msg.BtcEncode(&buf, oldPver); err == nil {
+		t.Errorf("encode of MsgCmpctBlock passed for old protocol "+
+			"version %v", oldPver)
+	}
+	if err := readmsg.BtcDecode(&buf, oldPver); err == nil {
+		t.Errorf("decode of MsgCmpctBlock passed for old protocol "+
+			"version %v", oldPver)
+	}
+
+	// Prefilled transactions which are out of order must not encode.
+	msg.PrefilledTxs = append(msg.PrefilledTxs, PrefilledTx{
+		Index: 0,
+		Tx:    multiTx,
+	})
+	if err := msg.BtcEncode(&buf, pver); err == nil {
+		t.Errorf("encode of MsgCmpctBlock with out of order prefilled " +
+			"transactions passed")
+	}
+}
+
+// TestCmpctBlockTooManyTxns ensures decoding a cmpctblock message which claims
+// more prefilled transactions than fit into a block along with its short IDs
+// fails, including counts which overflow when added to the number of short
+// IDs.
+func TestCmpctBlockTooManyTxns(t *testing.T) {
+	pver := ProtocolVersion
+	tests := []struct {
+		name  string
+		count uint64
+	}{
+		{"max prefilled count", math.MaxUint64},
+		{"one more than fits", maxTxPerBlock},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := writeBlockHeader(&buf, pver, &blockOne.Header); err != nil {
+			t.Fatalf("writeBlockHeader: unexpected error: %v", err)
+		}
+		if err := writeElement(&buf, uint64(0)); err != nil {
+			t.Fatalf("writeElement: unexpected error: %v", err)
+		}
+		if err := WriteVarInt(&buf, pver, 1); err != nil {
+			t.Fatalf("WriteVarInt: unexpected error: %v", err)
+		}
+		if err := writeShortTxID(&buf, 0x010203040506); err != nil {
+			t.Fatalf("writeShortTxID: unexpected error: %v", err)
+		}
+		if err := WriteVarInt(&buf, pver, test.count); err != nil {
+			t.Fatalf("WriteVarInt: unexpected error: %v", err)
+		}
+
+		var msg MsgCmpctBlock
+		err := msg.BtcDecode(bytes.NewReader(buf.Bytes()), pver)
+		if _, ok := err.(*MessageError); !ok {
+			t.Errorf("BtcDecode (%s): wrong error - got %v, want "+
+				"*MessageError", test.name, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/btcec"
+)
+
+// MsgEncInit implements the Message interface and represents a bitcoin encinit
+// message.  It is sent during the handshake by peers which both support
+// encrypting the connection and carries an ephemeral public key.  The peers
+// derive the keys which encrypt all further messages from the shared secret of
+// their ephemeral keys.
+//
+// This message was not added until protocol versions starting with
+// EncryptionVersion.
+type MsgEncInit struct {
+	PubKey [btcec.PubKeyBytesLenCompressed]byte
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgEncInit) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < EncryptionVersion {
+		str := fmt.Sprintf("encinit message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgEncInit.BtcDecode", str)
+	}
+
+	_, err := io.ReadFull(r, msg.PubKey[:])
+	return err
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgEncInit) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < EncryptionVersion {
+		str := fmt.Sprintf("encinit message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgEncInit.BtcEncode", str)
+	}
+
+	_, err := w.Write(msg.PubKey[:])
+	return err
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgEncInit) Command() string {
+	return CmdEncInit
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgEncInit) MaxPayloadLength(pver uint32) uint32 {
+	return btcec.PubKeyBytesLenCompressed
+}
+
+// NewMsgEncInit returns a new bitcoin encinit message carrying the passed
+// ephemeral public key that conforms to the Message interface.  See MsgEncInit
+// for details.
+func NewMsgEncInit(pubKey *btcec.PublicKey) *MsgEncInit {
+	var msg MsgEncInit
+	copy(msg.PubKey[:], pubKey.SerializeCompressed())
+	return &msg
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestEncInitWire tests the MsgEncInit wire encode and decode.
+func TestEncInitWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{0x01}, 32))
+	msg := NewMsgEncInit(key.PubKey())
+	if cmd := msg.Command(); cmd != "encinit" {
+		t.Errorf("NewMsgEncInit: wrong command - got %v want %v", cmd,
+			"encinit")
+	}
+	if !bytes.Equal(msg.PubKey[:], key.PubKey().SerializeCompressed()) {
+		t.Errorf("NewMsgEncInit: wrong public key %x", msg.PubKey)
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgEncInit failed %v", err)
+	}
+	if uint32(buf.Len()) != msg.MaxPayloadLength(pver) {
+		t.Errorf("BtcEncode: got length %d, want %d", buf.Len(),
+			msg.MaxPayloadLength(pver))
+	}
+	var readmsg MsgEncInit
+	if err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver); err != nil {
+		t.Fatalf("decode of MsgEncInit failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+		EncryptionVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgEncInit passed for old protocol version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, EncryptionVersion-1); err == nil {
+		t.Errorf("encode of MsgEncInit passed for old protocol version")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/btcec"
+)
+
+// MaxGetAdminStatePerMsg is the maximum number of ASP key ids that can be
+// queried in a single bitcoin getadmstate message.
+const MaxGetAdminStatePerMsg = 1000
+
+// MsgGetAdminState implements the Message interface and represents a bitcoin
+// getadmstate message.  It is used by light clients to request the admin key
+// sets of the chain and the public keys of a list of ASP key ids from peers
+// which advertise SFNodeAdminState.  The response is an admstate message
+// (MsgAdminState) anchored to the block the state was looked up at.
+//
+// Use AddKeyID to build up the list of ASP key ids.
+//
+// This message was not added until protocol versions starting with
+// StateQueryVersion.
+type MsgGetAdminState struct {
+	KeyIDs []btcec.KeyID
+}
+
+// AddKeyID adds a new ASP key id to the message.
+func (msg *MsgGetAdminState) AddKeyID(keyID btcec.KeyID) error {
+	if len(msg.KeyIDs)+1 > MaxGetAdminStatePerMsg {
+		str := fmt.Sprintf("too many key ids for message [max %v]",
+			MaxGetAdminStatePerMsg)
+		return messageError("MsgGetAdminState.AddKeyID", str)
+	}
+
+	msg.KeyIDs = append(msg.KeyIDs, keyID)
+	return nil
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetAdminState) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("getadmstate message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetAdminState.BtcDecode", str)
+	}
+
+	// Read num key ids and limit to max.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > MaxGetAdminStatePerMsg {
+		str := fmt.Sprintf("too many key ids for message "+
+			"[count %v, max %v]", count, MaxGetAdminStatePerMsg)
+		return messageError("MsgGetAdminState.BtcDecode", str)
+	}
+
+	msg.KeyIDs = make([]btcec.KeyID, 0, count)
+	for i := uint64(0); i < count; i++ {
+		keyID, err := binarySerializer.Uint32(r, littleEndian)
+		if err != nil {
+			return err
+		}
+		msg.AddKeyID(btcec.KeyID(keyID))
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetAdminState) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("getadmstate message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetAdminState.BtcEncode", str)
+	}
+
+	// Limit to max key ids per message.
+	count := len(msg.KeyIDs)
+	if count > MaxGetAdminStatePerMsg {
+		str := fmt.Sprintf("too many key ids for message "+
+			"[count %v, max %v]", count, MaxGetAdminStatePerMsg)
+		return messageError("MsgGetAdminState.BtcEncode", str)
+	}
+
+	err := WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, keyID := range msg.KeyIDs {
+		err := binarySerializer.PutUint32(w, littleEndian, uint32(keyID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetAdminState) Command() string {
+	return CmdGetAdminState
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetAdminState) MaxPayloadLength(pver uint32) uint32 {
+	// Num key ids (varInt) + max allowed key ids of 4 bytes each.
+	return MaxVarIntPayload + (MaxGetAdminStatePerMsg * 4)
+}
+
+// NewMsgGetAdminState returns a new bitcoin getadmstate message that conforms
+// to the Message interface.  See MsgGetAdminState for details.
+func NewMsgGetAdminState() *MsgGetAdminState {
+	return &MsgGetAdminState{}
+}
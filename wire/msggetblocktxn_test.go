@@ -0,0 +1,63 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn wire encode and decode.
+func TestGetBlockTxnWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	hash := chainhash.Hash{0x01}
+	msg := NewMsgGetBlockTxn(&hash, []uint32{0, 1, 5, 300})
+	if cmd := msg.Command(); cmd != "getblocktxn" {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v",
+			cmd, "getblocktxn")
+	}
+
+	// The indexes are differentially encoded.
+	wantBuf := append(hash[:], 0x04, 0x00, 0x00, 0x03, 0xfd, 0x26, 0x01)
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, pver)
+	if err != nil {
+		t.Fatalf("encode of MsgGetBlockTxn failed %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Errorf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	var readmsg MsgGetBlockTxn
+	err = readmsg.BtcDecode(bytes.NewReader(wantBuf), pver)
+	if err != nil {
+		t.Fatalf("decode of MsgGetBlockTxn failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Indexes beyond 16 bits must be rejected.
+	tooLarge := append(hash[:], 0x01, 0xfe, 0x00, 0x00, 0x01, 0x00)
+	err = readmsg.BtcDecode(bytes.NewReader(tooLarge), pver)
+	if _, ok := err.(*MessageError); !ok {
+		t.Errorf("BtcDecode: expected MessageError for out of range "+
+			"index, got %v", err)
+	}
+
+	// Older protocol versions should fail encode since the message didn't
+	// exist yet.
+	if err := msg.BtcEncode(&buf, CompactBlocksVersion-1); err == nil {
+		t.Errorf("encode of MsgGetBlockTxn passed for old protocol " +
+			"version")
+	}
+}
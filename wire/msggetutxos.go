@@ -0,0 +1,147 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+// MaxGetUTXOsPerMsg is the maximum number of outpoints that can be queried in
+// a single bitcoin getutxos message.
+const MaxGetUTXOsPerMsg = 100
+
+// MsgGetUTXOs implements the Message interface and represents a bitcoin
+// getutxos message (BIP0064).  It is used by light clients to request the
+// unspent outputs for a list of outpoints from peers which advertise
+// SFNodeGetUTXO.  The response is a utxos message (MsgUTXOs) anchored to the
+// block the outputs were looked up at.
+//
+// Set the CheckMempool field to also take the transactions in the memory pool
+// of the remote peer into account and use AddOutPoint to build up the list of
+// outpoints.
+//
+// This message was not added until protocol versions starting with
+// StateQueryVersion.
+type MsgGetUTXOs struct {
+	CheckMempool bool
+	OutPoints    []*OutPoint
+}
+
+// AddOutPoint adds a new outpoint to the message.
+func (msg *MsgGetUTXOs) AddOutPoint(op *OutPoint) error {
+	if len(msg.OutPoints)+1 > MaxGetUTXOsPerMsg {
+		str := fmt.Sprintf("too many outpoints for message [max %v]",
+			MaxGetUTXOsPerMsg)
+		return messageError("MsgGetUTXOs.AddOutPoint", str)
+	}
+
+	msg.OutPoints = append(msg.OutPoints, op)
+	return nil
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetUTXOs) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("getutxos message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetUTXOs.BtcDecode", str)
+	}
+
+	err := readElement(r, &msg.CheckMempool)
+	if err != nil {
+		return err
+	}
+
+	// Read num outpoints and limit to max.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > MaxGetUTXOsPerMsg {
+		str := fmt.Sprintf("too many outpoints for message "+
+			"[count %v, max %v]", count, MaxGetUTXOsPerMsg)
+		return messageError("MsgGetUTXOs.BtcDecode", str)
+	}
+
+	// Create a contiguous slice of outpoints to deserialize into in order
+	// to reduce the number of allocations.
+	outPoints := make([]OutPoint, count)
+	msg.OutPoints = make([]*OutPoint, 0, count)
+	for i := uint64(0); i < count; i++ {
+		op := &outPoints[i]
+		err := readOutPoint(r, pver, 0, op)
+		if err != nil {
+			return err
+		}
+		msg.AddOutPoint(op)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetUTXOs) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("getutxos message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetUTXOs.BtcEncode", str)
+	}
+
+	// Limit to max outpoints per message.
+	count := len(msg.OutPoints)
+	if count > MaxGetUTXOsPerMsg {
+		str := fmt.Sprintf("too many outpoints for message "+
+			"[count %v, max %v]", count, MaxGetUTXOsPerMsg)
+		return messageError("MsgGetUTXOs.BtcEncode", str)
+	}
+
+	err := writeElement(w, msg.CheckMempool)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, op := range msg.OutPoints {
+		err := writeOutPoint(w, pver, 0, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetUTXOs) Command() string {
+	return CmdGetUTXOs
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetUTXOs) MaxPayloadLength(pver uint32) uint32 {
+	// Check mempool flag 1 byte + num outpoints (varInt) + max allowed
+	// outpoints of a hash and an index each.
+	return 1 + MaxVarIntPayload + (MaxGetUTXOsPerMsg *
+		(chainhash.HashSize + 4))
+}
+
+// NewMsgGetUTXOs returns a new bitcoin getutxos message that conforms to the
+// Message interface.  See MsgGetUTXOs for details.
+func NewMsgGetUTXOs(checkMempool bool) *MsgGetUTXOs {
+	return &MsgGetUTXOs{
+		CheckMempool: checkMempool,
+		OutPoints:    make([]*OutPoint, 0, MaxGetUTXOsPerMsg),
+	}
+}
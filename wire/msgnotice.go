@@ -0,0 +1,236 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+const (
+	// NoticeVersionCurrent is the version of the notice format this
+	// package produces.
+	NoticeVersionCurrent = 1
+
+	// MaxNoticeMessageLen is the maximum length in bytes of the text of a
+	// network notice.
+	MaxNoticeMessageLen = 1024
+
+	// MaxNoticeSignatures is the maximum number of signatures a network
+	// notice may carry.
+	MaxNoticeSignatures = 32
+
+	// NoticePubKeySize is the number of bytes of the compressed public key
+	// a notice signature is made with.
+	NoticePubKeySize = 33
+
+	// MaxNoticeSignatureSize is the maximum number of bytes of a DER
+	// encoded notice signature.
+	MaxNoticeSignatureSize = 72
+
+	// maxNoticePayload is the maximum number of bytes a network notice
+	// can be.  Version 4 bytes + key set 1 byte + timestamp 8 bytes +
+	// expiration 8 bytes + priority 4 bytes + message + signatures.
+	maxNoticePayload = 25 + MaxVarIntPayload + MaxNoticeMessageLen +
+		MaxVarIntPayload + MaxNoticeSignatures*(NoticePubKeySize+1+
+		MaxNoticeSignatureSize)
+)
+
+// NoticeSignature is a signature of a network notice along with the public key
+// it was made with.
+type NoticeSignature struct {
+	PubKey    [NoticePubKeySize]byte
+	Signature []byte
+}
+
+// MsgNotice implements the Message interface and represents a network notice
+// message.  It is used to broadcast announcements such as mandatory upgrade
+// warnings on behalf of the operators of the chain.  A notice is signed by a
+// quorum of the keys of an admin key set, so it is authenticated against the
+// admin state of the chain instead of a hardcoded key like the deprecated
+// alert message (MsgAlert).
+//
+// The signatures commit to all of the other fields, so more signatures can be
+// added to a notice without changing its identity (see NoticeHash).
+//
+// This message was not added until protocol versions starting with
+// NoticeVersion.
+type MsgNotice struct {
+	// Version is the version of the notice format.
+	Version int32
+
+	// KeySet is the admin key set (btcec.KeySetType) the notice is signed
+	// by.
+	KeySet uint8
+
+	// Timestamp is the time the notice was created and Expiration is the
+	// time after which it is no longer relayed or displayed.
+	Timestamp  time.Time
+	Expiration time.Time
+
+	// Priority is the priority of the notice.  Higher values indicate more
+	// important notices.
+	Priority uint32
+
+	// Message is the text of the notice.
+	Message string
+
+	// Signatures are the signatures of the notice by the admin keys.
+	Signatures []NoticeSignature
+}
+
+// encodeContents encodes all of the fields of the notice other than the
+// signatures to w.
+func (msg *MsgNotice) encodeContents(w io.Writer, pver uint32) error {
+	err := writeElements(w, msg.Version, msg.KeySet,
+		msg.Timestamp.Unix(), msg.Expiration.Unix(), msg.Priority)
+	if err != nil {
+		return err
+	}
+
+	return WriteVarString(w, pver, msg.Message)
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgNotice) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < NoticeVersion {
+		str := fmt.Sprintf("notice message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgNotice.BtcDecode", str)
+	}
+
+	err := readElements(r, &msg.Version, &msg.KeySet,
+		(*int64Time)(&msg.Timestamp), (*int64Time)(&msg.Expiration),
+		&msg.Priority)
+	if err != nil {
+		return err
+	}
+
+	message, err := ReadVarBytes(r, pver, MaxNoticeMessageLen,
+		"notice message")
+	if err != nil {
+		return err
+	}
+	msg.Message = string(message)
+
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count > MaxNoticeSignatures {
+		str := fmt.Sprintf("too many signatures for notice [count %d, "+
+			"max %d]", count, MaxNoticeSignatures)
+		return messageError("MsgNotice.BtcDecode", str)
+	}
+	msg.Signatures = make([]NoticeSignature, count)
+	for i := range msg.Signatures {
+		sig := &msg.Signatures[i]
+		if _, err := io.ReadFull(r, sig.PubKey[:]); err != nil {
+			return err
+		}
+		sig.Signature, err = ReadVarBytes(r, pver,
+			MaxNoticeSignatureSize, "notice signature")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgNotice) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < NoticeVersion {
+		str := fmt.Sprintf("notice message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgNotice.BtcEncode", str)
+	}
+	if len(msg.Message) > MaxNoticeMessageLen {
+		str := fmt.Sprintf("notice message is too long [len %d, max %d]",
+			len(msg.Message), MaxNoticeMessageLen)
+		return messageError("MsgNotice.BtcEncode", str)
+	}
+	if len(msg.Signatures) > MaxNoticeSignatures {
+		str := fmt.Sprintf("too many signatures for notice [count %d, "+
+			"max %d]", len(msg.Signatures), MaxNoticeSignatures)
+		return messageError("MsgNotice.BtcEncode", str)
+	}
+
+	if err := msg.encodeContents(w, pver); err != nil {
+		return err
+	}
+	err := WriteVarInt(w, pver, uint64(len(msg.Signatures)))
+	if err != nil {
+		return err
+	}
+	for i := range msg.Signatures {
+		sig := &msg.Signatures[i]
+		if _, err := w.Write(sig.PubKey[:]); err != nil {
+			return err
+		}
+		if err := WriteVarBytes(w, pver, sig.Signature); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgNotice) Command() string {
+	return CmdNotice
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgNotice) MaxPayloadLength(pver uint32) uint32 {
+	return maxNoticePayload
+}
+
+// NoticeHash returns the hash which identifies the notice.  It is the hash the
+// signatures are made over, so it does not depend on the signatures.
+func (msg *MsgNotice) NoticeHash() chainhash.Hash {
+	var buf bytes.Buffer
+	_ = msg.encodeContents(&buf, ProtocolVersion)
+	return chainhash.DoubleHashH(buf.Bytes())
+}
+
+// Sign signs the notice with the passed private key and adds the signature.
+func (msg *MsgNotice) Sign(key *btcec.PrivateKey) error {
+	hash := msg.NoticeHash()
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		return err
+	}
+
+	var sig NoticeSignature
+	copy(sig.PubKey[:], key.PubKey().SerializeCompressed())
+	sig.Signature = signature.Serialize()
+	msg.Signatures = append(msg.Signatures, sig)
+	return nil
+}
+
+// NewMsgNotice returns a new unsigned network notice message that conforms to
+// the Message interface.  See MsgNotice for details.
+func NewMsgNotice(keySet btcec.KeySetType, timestamp, expiration time.Time,
+	priority uint32, message string) *MsgNotice {
+
+	return &MsgNotice{
+		Version:    NoticeVersionCurrent,
+		KeySet:     uint8(keySet),
+		Timestamp:  time.Unix(timestamp.Unix(), 0),
+		Expiration: time.Unix(expiration.Unix(), 0),
+		Priority:   priority,
+		Message:    message,
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitgo/prova/btcec"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestNoticeWire tests the MsgNotice wire encode and decode.
+func TestNoticeWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgNotice(btcec.ProvisionKeySet, time.Unix(1500000000, 0),
+		time.Unix(1500086400, 0), 10, "please upgrade")
+	if cmd := msg.Command(); cmd != "notice" {
+		t.Errorf("NewMsgNotice: wrong command - got %v want %v", cmd,
+			"notice")
+	}
+
+	// Sign the notice with two keys and ensure the signatures do not
+	// change the notice hash.
+	hash := msg.NoticeHash()
+	for _, seed := range []byte{0x01, 0x02} {
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+			bytes.Repeat([]byte{seed}, 32))
+		if err := msg.Sign(key); err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		sig, err := btcec.ParseDERSignature(
+			msg.Signatures[len(msg.Signatures)-1].Signature,
+			btcec.S256())
+		if err != nil {
+			t.Fatalf("ParseDERSignature: unexpected error: %v", err)
+		}
+		if !sig.Verify(hash[:], key.PubKey()) {
+			t.Errorf("Sign: signature does not verify")
+		}
+	}
+	if msg.NoticeHash() != hash {
+		t.Errorf("NoticeHash: hash changed after signing")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgNotice failed %v", err)
+	}
+	if uint32(buf.Len()) > msg.MaxPayloadLength(pver) {
+		t.Errorf("BtcEncode: length %d exceeds max payload %d",
+			buf.Len(), msg.MaxPayloadLength(pver))
+	}
+
+	var readmsg MsgNotice
+	err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver)
+	if err != nil {
+		t.Fatalf("decode of MsgNotice failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), NoticeVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgNotice passed for old protocol version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, NoticeVersion-1); err == nil {
+		t.Errorf("encode of MsgNotice passed for old protocol version")
+	}
+
+	// Notices with a message that is too long must be rejected.
+	msg.Message = strings.Repeat("a", MaxNoticeMessageLen+1)
+	if err := msg.BtcEncode(&bytes.Buffer{}, pver); err == nil {
+		t.Errorf("encode of MsgNotice passed with a message that is " +
+			"too long")
+	}
+}
@@ -27,6 +27,13 @@ const (
 	RejectInsufficientFee RejectCode = 0x42
 	RejectCheckpoint      RejectCode = 0x43
 	RejectInvalidAdmin    RejectCode = 0x44
+	RejectUnknownKeyID    RejectCode = 0x45
+	RejectKeyIDRevoked    RejectCode = 0x46
+	RejectThreadOrder     RejectCode = 0x47
+	RejectSupplyUnderflow RejectCode = 0x48
+	RejectInvalidAdminOp  RejectCode = 0x49
+	RejectRateLimited     RejectCode = 0x4a
+	RejectHighFee         RejectCode = 0x4b
 )
 
 // Map of reject codes back strings for pretty printing.
@@ -40,6 +47,13 @@ var rejectCodeStrings = map[RejectCode]string{
 	RejectInsufficientFee: "REJECT_INSUFFICIENTFEE",
 	RejectCheckpoint:      "REJECT_CHECKPOINT",
 	RejectInvalidAdmin:    "REJECT_INVALID_ADMIN",
+	RejectUnknownKeyID:    "REJECT_UNKNOWN_KEYID",
+	RejectKeyIDRevoked:    "REJECT_KEYID_REVOKED",
+	RejectThreadOrder:     "REJECT_THREAD_ORDER",
+	RejectSupplyUnderflow: "REJECT_SUPPLY_UNDERFLOW",
+	RejectInvalidAdminOp:  "REJECT_INVALID_ADMIN_OP",
+	RejectRateLimited:     "REJECT_RATE_LIMITED",
+	RejectHighFee:         "REJECT_HIGHFEE",
 }
 
 // String returns the RejectCode in human-readable form.
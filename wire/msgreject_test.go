@@ -29,6 +29,13 @@ func TestRejectCodeStringer(t *testing.T) {
 		{RejectInsufficientFee, "REJECT_INSUFFICIENTFEE"},
 		{RejectCheckpoint, "REJECT_CHECKPOINT"},
 		{RejectInvalidAdmin, "REJECT_INVALID_ADMIN"},
+		{RejectUnknownKeyID, "REJECT_UNKNOWN_KEYID"},
+		{RejectKeyIDRevoked, "REJECT_KEYID_REVOKED"},
+		{RejectThreadOrder, "REJECT_THREAD_ORDER"},
+		{RejectSupplyUnderflow, "REJECT_SUPPLY_UNDERFLOW"},
+		{RejectInvalidAdminOp, "REJECT_INVALID_ADMIN_OP"},
+		{RejectRateLimited, "REJECT_RATE_LIMITED"},
+		{RejectHighFee, "REJECT_HIGHFEE"},
 		{0xff, "Unknown RejectCode (255)"},
 	}
 
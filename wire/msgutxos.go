@@ -0,0 +1,212 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+)
+
+const (
+	// MempoolHeight is the height reported in a bitcoin utxos message for
+	// the outputs of transactions which are only in the memory pool.
+	MempoolHeight = 0x7fffffff
+
+	// maxUTXOsBitmapLen is the maximum number of bytes of the bitmap of a
+	// bitcoin utxos message.
+	maxUTXOsBitmapLen = (MaxGetUTXOsPerMsg + 7) / 8
+)
+
+// UTXO defines an unspent transaction output returned in a bitcoin utxos
+// message along with the version of its transaction and the height of the
+// block which contains it.
+type UTXO struct {
+	TxVersion int32
+	Height    uint32
+	TxOut     TxOut
+}
+
+// MsgUTXOs implements the Message interface and represents a bitcoin utxos
+// message (BIP0064).  It is sent in response to a getutxos message (MsgGetUTXOs)
+// and anchored to the block at the given height and hash the outputs were
+// looked up at, so light clients can cross-check the responses of several
+// peers.
+//
+// Bit i of the bitmap is set when the i-th queried outpoint is unspent, and the
+// unspent outputs are listed in the order they were queried.  Use AddUTXO to
+// build up the response and IsUnspent to check the bitmap.
+//
+// This message was not added until protocol versions starting with
+// StateQueryVersion.
+type MsgUTXOs struct {
+	Height    uint32
+	BlockHash chainhash.Hash
+	Bitmap    []byte
+	UTXOs     []*UTXO
+}
+
+// AddUTXO marks the queried outpoint at the passed index as unspent and adds
+// its output to the message.  Outputs must be added in the order the
+// outpoints were queried.
+func (msg *MsgUTXOs) AddUTXO(index int, utxo *UTXO) error {
+	if index < 0 || index >= len(msg.Bitmap)*8 {
+		str := fmt.Sprintf("outpoint index %d is out of range [max %v]",
+			index, len(msg.Bitmap)*8-1)
+		return messageError("MsgUTXOs.AddUTXO", str)
+	}
+
+	msg.Bitmap[index/8] |= 1 << uint(index%8)
+	msg.UTXOs = append(msg.UTXOs, utxo)
+	return nil
+}
+
+// IsUnspent returns whether the queried outpoint at the passed index is
+// unspent.
+func (msg *MsgUTXOs) IsUnspent(index int) bool {
+	if index < 0 || index >= len(msg.Bitmap)*8 {
+		return false
+	}
+	return msg.Bitmap[index/8]&(1<<uint(index%8)) != 0
+}
+
+// numUnspent returns the number of unspent outpoints in the bitmap.
+func (msg *MsgUTXOs) numUnspent() int {
+	count := 0
+	for i := 0; i < len(msg.Bitmap)*8; i++ {
+		if msg.IsUnspent(i) {
+			count++
+		}
+	}
+	return count
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgUTXOs) BtcDecode(r io.Reader, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("utxos message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgUTXOs.BtcDecode", str)
+	}
+
+	err := readElements(r, &msg.Height, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	msg.Bitmap, err = ReadVarBytes(r, pver, maxUTXOsBitmapLen,
+		"utxos bitmap")
+	if err != nil {
+		return err
+	}
+
+	// Read num outputs and ensure it matches the bitmap.
+	count, err := ReadVarInt(r, pver)
+	if err != nil {
+		return err
+	}
+	if count != uint64(msg.numUnspent()) {
+		str := fmt.Sprintf("wrong number of outputs for message "+
+			"[count %v, unspent %v]", count, msg.numUnspent())
+		return messageError("MsgUTXOs.BtcDecode", str)
+	}
+
+	// Create a contiguous slice of outputs to deserialize into in order to
+	// reduce the number of allocations.
+	utxos := make([]UTXO, count)
+	msg.UTXOs = make([]*UTXO, 0, count)
+	for i := uint64(0); i < count; i++ {
+		utxo := &utxos[i]
+		err := readElements(r, &utxo.TxVersion, &utxo.Height)
+		if err != nil {
+			return err
+		}
+		err = readTxOut(r, pver, utxo.TxVersion, &utxo.TxOut)
+		if err != nil {
+			return err
+		}
+		msg.UTXOs = append(msg.UTXOs, utxo)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgUTXOs) BtcEncode(w io.Writer, pver uint32) error {
+	if pver < StateQueryVersion {
+		str := fmt.Sprintf("utxos message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgUTXOs.BtcEncode", str)
+	}
+
+	if len(msg.Bitmap) > maxUTXOsBitmapLen {
+		str := fmt.Sprintf("utxos bitmap is too large [len %v, max %v]",
+			len(msg.Bitmap), maxUTXOsBitmapLen)
+		return messageError("MsgUTXOs.BtcEncode", str)
+	}
+	count := len(msg.UTXOs)
+	if count != msg.numUnspent() {
+		str := fmt.Sprintf("wrong number of outputs for message "+
+			"[count %v, unspent %v]", count, msg.numUnspent())
+		return messageError("MsgUTXOs.BtcEncode", str)
+	}
+
+	err := writeElements(w, msg.Height, &msg.BlockHash)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarBytes(w, pver, msg.Bitmap)
+	if err != nil {
+		return err
+	}
+
+	err = WriteVarInt(w, pver, uint64(count))
+	if err != nil {
+		return err
+	}
+
+	for _, utxo := range msg.UTXOs {
+		err := writeElements(w, utxo.TxVersion, utxo.Height)
+		if err != nil {
+			return err
+		}
+		err = WriteTxOut(w, pver, utxo.TxVersion, &utxo.TxOut)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgUTXOs) Command() string {
+	return CmdUTXOs
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgUTXOs) MaxPayloadLength(pver uint32) uint32 {
+	// The outputs have scripts of variable length, so the limit is the
+	// max message payload.
+	return MaxMessagePayload
+}
+
+// NewMsgUTXOs returns a new bitcoin utxos message for the passed number of
+// queried outpoints anchored to the block at the passed height and hash that
+// conforms to the Message interface.  See MsgUTXOs for details.
+func NewMsgUTXOs(height uint32, blockHash *chainhash.Hash, numOutPoints int) *MsgUTXOs {
+	return &MsgUTXOs{
+		Height:    height,
+		BlockHash: *blockHash,
+		Bitmap:    make([]byte, (numOutPoints+7)/8),
+		UTXOs:     make([]*UTXO, 0, numOutPoints),
+	}
+}
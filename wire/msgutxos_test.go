@@ -0,0 +1,134 @@
+// Copyright (c) 2017 BitGo
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bitgo/prova/chaincfg/chainhash"
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetUTXOsWire tests the MsgGetUTXOs wire encode and decode.
+func TestGetUTXOsWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	msg := NewMsgGetUTXOs(true)
+	if cmd := msg.Command(); cmd != "getutxos" {
+		t.Errorf("NewMsgGetUTXOs: wrong command - got %v want %v", cmd,
+			"getutxos")
+	}
+	for i := 0; i < MaxGetUTXOsPerMsg; i++ {
+		op := NewOutPoint(&chainhash.Hash{byte(i)}, uint32(i))
+		if err := msg.AddOutPoint(op); err != nil {
+			t.Fatalf("AddOutPoint: unexpected error %v", err)
+		}
+	}
+	if err := msg.AddOutPoint(&OutPoint{}); err == nil {
+		t.Errorf("AddOutPoint: expected error on too many outpoints")
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgGetUTXOs failed %v", err)
+	}
+	if uint32(buf.Len()) > msg.MaxPayloadLength(pver) {
+		t.Errorf("BtcEncode: length %d exceeds max payload %d",
+			buf.Len(), msg.MaxPayloadLength(pver))
+	}
+	var readmsg MsgGetUTXOs
+	if err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver); err != nil {
+		t.Fatalf("decode of MsgGetUTXOs failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+		StateQueryVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgGetUTXOs passed for old protocol version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, StateQueryVersion-1); err == nil {
+		t.Errorf("encode of MsgGetUTXOs passed for old protocol version")
+	}
+}
+
+// TestUTXOsWire tests the MsgUTXOs wire encode and decode.
+func TestUTXOsWire(t *testing.T) {
+	pver := ProtocolVersion
+
+	blockHash := chainhash.Hash{0x01}
+	msg := NewMsgUTXOs(10, &blockHash, 10)
+	if cmd := msg.Command(); cmd != "utxos" {
+		t.Errorf("NewMsgUTXOs: wrong command - got %v want %v", cmd,
+			"utxos")
+	}
+	utxos := []*UTXO{{
+		TxVersion: 1,
+		Height:    5,
+		TxOut:     TxOut{Value: 1000, PkScript: []byte{0x51}},
+	}, {
+		TxVersion: 1,
+		Height:    MempoolHeight,
+		TxOut:     TxOut{Value: 2000, PkScript: []byte{0x52, 0x53}},
+	}}
+	if err := msg.AddUTXO(1, utxos[0]); err != nil {
+		t.Fatalf("AddUTXO: unexpected error %v", err)
+	}
+	if err := msg.AddUTXO(9, utxos[1]); err != nil {
+		t.Fatalf("AddUTXO: unexpected error %v", err)
+	}
+	if err := msg.AddUTXO(16, utxos[1]); err == nil {
+		t.Errorf("AddUTXO: expected error on out of range index")
+	}
+	for i := 0; i < 10; i++ {
+		if want := i == 1 || i == 9; msg.IsUnspent(i) != want {
+			t.Errorf("IsUnspent(%d): got %v, want %v", i,
+				msg.IsUnspent(i), want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, pver); err != nil {
+		t.Fatalf("encode of MsgUTXOs failed %v", err)
+	}
+	var readmsg MsgUTXOs
+	if err := readmsg.BtcDecode(bytes.NewReader(buf.Bytes()), pver); err != nil {
+		t.Fatalf("decode of MsgUTXOs failed %v", err)
+	}
+	if !reflect.DeepEqual(&readmsg, msg) {
+		t.Errorf("BtcDecode\n got: %s want: %s", spew.Sdump(readmsg),
+			spew.Sdump(msg))
+	}
+
+	// A bitmap which doesn't match the outputs must be rejected.
+	msg.Bitmap[0] |= 0x01
+	if err := msg.BtcEncode(&bytes.Buffer{}, pver); err == nil {
+		t.Errorf("encode of MsgUTXOs passed with mismatched bitmap")
+	}
+	encoded := buf.Bytes()
+	encoded[4+chainhash.HashSize+1] |= 0x01
+	err := readmsg.BtcDecode(bytes.NewReader(encoded), pver)
+	if err == nil {
+		t.Errorf("decode of MsgUTXOs passed with mismatched bitmap")
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	err = readmsg.BtcDecode(bytes.NewReader(buf.Bytes()),
+		StateQueryVersion-1)
+	if err == nil {
+		t.Errorf("decode of MsgUTXOs passed for old protocol version")
+	}
+	if err := msg.BtcEncode(&bytes.Buffer{}, StateQueryVersion-1); err == nil {
+		t.Errorf("encode of MsgUTXOs passed for old protocol version")
+	}
+}
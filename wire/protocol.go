@@ -13,7 +13,7 @@ import (
 
 const (
 	// ProtocolVersion is the latest protocol version this package supports.
-	ProtocolVersion uint32 = 70013
+	ProtocolVersion uint32 = 70019
 
 	// MultipleAddressVersion is the protocol version which added multiple
 	// addresses per message (pver >= MultipleAddressVersion).
@@ -51,6 +51,34 @@ const (
 	// FeeFilterVersion is the protocol version which added a new
 	// feefilter message.
 	FeeFilterVersion uint32 = 70013
+
+	// CompactBlocksVersion is the protocol version which added the
+	// sendcmpct, cmpctblock, getblocktxn and blocktxn messages (BIP0152).
+	CompactBlocksVersion uint32 = 70014
+
+	// NoticeVersion is the protocol version which added the notice message
+	// for admin signed network notices.
+	NoticeVersion uint32 = 70015
+
+	// NodeAuthVersion is the protocol version which added the authchal
+	// and authproof messages used to authenticate peers during the
+	// handshake.
+	NodeAuthVersion uint32 = 70016
+
+	// EncryptionVersion is the protocol version which added the encinit
+	// message used to negotiate an encrypted transport during the
+	// handshake.
+	EncryptionVersion uint32 = 70017
+
+	// StateQueryVersion is the protocol version which added the getutxos,
+	// utxos, getadmstate, and admstate messages used by light clients to
+	// query the chain state.
+	StateQueryVersion uint32 = 70018
+
+	// CFilterVersion is the protocol version which added the getcfilters,
+	// cfilter, getcfheaders, and cfheaders messages used by light clients
+	// to download committed block filters.
+	CFilterVersion uint32 = 70019
 )
 
 // ServiceFlag identifies services supported by a bitcoin peer.
@@ -67,13 +95,44 @@ const (
 	// SFNodeBloom is a flag used to indiciate a peer supports bloom
 	// filtering.
 	SFNodeBloom
+
+	// SFNodeCompactBlocks is a flag used to indicate a peer supports
+	// compact block relay (BIP0152).
+	SFNodeCompactBlocks
+
+	// SFNodeAuth is a flag used to indicate a peer wants to authenticate
+	// with the authchal and authproof messages during the handshake.
+	SFNodeAuth
+
+	// SFNodeEncrypt is a flag used to indicate a peer supports encrypting
+	// the connection after exchanging encinit messages during the
+	// handshake.
+	SFNodeEncrypt
+
+	// SFNodeAdminState is a flag used to indicate a peer supports the
+	// getadmstate and admstate commands.
+	SFNodeAdminState
+
+	// SFNodeCF is a flag used to indicate a peer supports the getcfilters
+	// and getcfheaders commands and serves committed block filters.
+	SFNodeCF
+
+	// SFNodeNetworkLimited is a flag used to indicate a peer is a pruned
+	// node which only serves the most recent blocks (BIP0159).
+	SFNodeNetworkLimited ServiceFlag = 1 << 10
 )
 
 // Map of service flags back to their constant names for pretty printing.
 var sfStrings = map[ServiceFlag]string{
-	SFNodeNetwork: "SFNodeNetwork",
-	SFNodeGetUTXO: "SFNodeGetUTXO",
-	SFNodeBloom:   "SFNodeBloom",
+	SFNodeNetwork:        "SFNodeNetwork",
+	SFNodeGetUTXO:        "SFNodeGetUTXO",
+	SFNodeBloom:          "SFNodeBloom",
+	SFNodeCompactBlocks:  "SFNodeCompactBlocks",
+	SFNodeAuth:           "SFNodeAuth",
+	SFNodeEncrypt:        "SFNodeEncrypt",
+	SFNodeAdminState:     "SFNodeAdminState",
+	SFNodeCF:             "SFNodeCF",
+	SFNodeNetworkLimited: "SFNodeNetworkLimited",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -82,6 +141,12 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeNetwork,
 	SFNodeGetUTXO,
 	SFNodeBloom,
+	SFNodeCompactBlocks,
+	SFNodeAuth,
+	SFNodeEncrypt,
+	SFNodeAdminState,
+	SFNodeCF,
+	SFNodeNetworkLimited,
 }
 
 // String returns the ServiceFlag in human-readable form.
@@ -17,7 +17,16 @@ func TestServiceFlagStringer(t *testing.T) {
 		{SFNodeNetwork, "SFNodeNetwork"},
 		{SFNodeGetUTXO, "SFNodeGetUTXO"},
 		{SFNodeBloom, "SFNodeBloom"},
-		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|0xfffffff8"},
+		{SFNodeCompactBlocks, "SFNodeCompactBlocks"},
+		{SFNodeAuth, "SFNodeAuth"},
+		{SFNodeEncrypt, "SFNodeEncrypt"},
+		{SFNodeAdminState, "SFNodeAdminState"},
+		{SFNodeCF, "SFNodeCF"},
+		{SFNodeNetworkLimited, "SFNodeNetworkLimited"},
+		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|" +
+			"SFNodeCompactBlocks|SFNodeAuth|SFNodeEncrypt|" +
+			"SFNodeAdminState|SFNodeCF|SFNodeNetworkLimited|" +
+			"0xfffffb00"},
 	}
 
 	t.Logf("Running %d tests", len(tests))